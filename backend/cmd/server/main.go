@@ -17,6 +17,7 @@ import (
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/config"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/handler"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/middleware"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/render"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
 )
@@ -54,10 +55,21 @@ func main() {
 	fileRepo := repository.NewPostgresFileRepository(db)
 	fileMetadataRepo := repository.NewPostgresFileMetadataRepository(db)
 	fileSourceRepo := repository.NewPostgresFileSourceRepository(db)
+	fileVersionRepo := repository.NewPostgresFileVersionRepository(db)
+	fileChangeRepo := repository.NewPostgresFileChangeRepository(db)
+	summaryRepo := repository.NewPostgresSummaryRepository(db)
+	usageRepo := repository.NewPostgresUsageRepository(db)
 	discoveryRepo := repository.NewPostgresDiscoveryRepository(db)
 	achievementRepo := repository.NewAchievementRepository(db)
 
-	// Initialize Claude service (real or mock)
+	// Initialize usage service (token and cost accounting)
+	modelPrices, err := service.LoadModelPrices(cfg.ModelPricesFile)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load model prices")
+	}
+	usageSvc := service.NewUsageService(modelPrices, usageRepo, logger)
+
+	// Initialize LLM provider (Anthropic, OpenAI-compatible, or mock)
 	var claudeService service.ClaudeMessenger
 	var claudeVision service.ClaudeVision
 	if os.Getenv("USE_MOCK_CLAUDE") == "true" {
@@ -69,19 +81,75 @@ func main() {
 		claudeVision = mockService // MockClaudeService also implements ClaudeVision
 		logger.Info().Msg("using MOCK Claude service (no API calls)")
 	} else {
-		realClaudeService := service.NewClaudeService(service.ClaudeConfig{
-			APIKey:    cfg.ClaudeAPIKey,
-			Model:     cfg.ClaudeModel,
-			MaxTokens: cfg.ClaudeMaxTokens,
+		retry := service.RetryConfig{
+			MaxRetries: cfg.ClaudeMaxRetries,
+			BaseDelay:  cfg.ClaudeRetryBaseDelay,
+			MaxDelay:   cfg.ClaudeRetryMaxDelay,
+		}
+		breaker := service.BreakerConfig{
+			FailureThreshold: cfg.ClaudeBreakerThreshold,
+			Cooldown:         cfg.ClaudeBreakerCooldown,
+		}
+		routes, err := service.LoadModelRoutes(cfg.ModelRoutesFile, cfg.ModelRoutes)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to load model routes")
+		}
+		if err := routes.Validate(); err != nil {
+			logger.Fatal().Err(err).Msg("invalid model routes")
+		}
+		var cassette *service.Cassette
+		if cfg.LLMCassetteMode != "" {
+			cassette, err = service.NewCassette(cfg.LLMCassetteDir, cfg.LLMCassetteMode, logger)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("failed to open LLM cassette")
+			}
+			logger.Info().
+				Str("mode", cfg.LLMCassetteMode).
+				Str("dir", cfg.LLMCassetteDir).
+				Msg("using LLM cassette")
+		}
+		provider, err := service.NewProvider(service.ProviderConfig{
+			Type:     cfg.LLMProvider,
+			Cassette: cassette,
+			Anthropic: service.ClaudeConfig{
+				APIKey:    cfg.ClaudeAPIKey,
+				Model:     cfg.ClaudeModel,
+				MaxTokens: cfg.ClaudeMaxTokens,
+				Retry:     retry,
+				Breaker:   breaker,
+				Routes:    routes,
+			},
+			OpenAI: service.OpenAIConfig{
+				APIKey:    cfg.OpenAIAPIKey,
+				Model:     cfg.OpenAIModel,
+				MaxTokens: cfg.OpenAIMaxTokens,
+				BaseURL:   cfg.OpenAIBaseURL,
+				Retry:     retry,
+				Breaker:   breaker,
+				Routes:    routes,
+			},
 		}, logger)
-		claudeService = realClaudeService
-		claudeVision = realClaudeService
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to initialize LLM provider")
+		}
+		provider.SetUsageRecorder(usageSvc) // Record token usage of every API call
+		claudeService = provider
+		claudeVision = provider
+		logger.Info().Str("provider", provider.Name()).Msg("using LLM provider")
 	}
 
 	// Initialize PRD repository and service
 	prdRepo := repository.NewPostgresPRDRepository(db)
 	prdService := service.NewPRDService(prdRepo, discoveryRepo, claudeService, logger)
 
+	// Initialize summary service (conversation compaction)
+	summarySvc := service.NewSummaryService(service.SummaryConfig{
+		TokenBudget:  cfg.SummaryTokenBudget,
+		MessageLimit: cfg.ContextMessageLimit, // Summarize history the chat would otherwise drop
+		KeepRecent:   cfg.SummaryKeepRecent,
+	}, summaryRepo, projectRepo, claudeService, logger)
+	prdService.SetConversationCompactor(summarySvc) // Summarize the conversation when a PRD is completed
+
 	// Initialize discovery service
 	discoveryService := service.NewDiscoveryService(discoveryRepo, projectRepo, logger)
 	discoveryService.SetPRDService(prdService)      // Wire PRD generation trigger
@@ -95,24 +163,72 @@ func main() {
 	achievementSvc := service.NewAchievementService(achievementRepo, logger)
 	nudgeSvc := service.NewNudgeService(achievementRepo, achievementSvc, logger)
 
+	// Initialize file history service (version every file write)
+	fileHistorySvc := service.NewFileHistoryService(fileRepo, fileVersionRepo, logger)
+
+	// Initialize change set service (per-turn diffs)
+	changeSetSvc := service.NewChangeSetService(fileChangeRepo, projectRepo, logger)
+
 	// Initialize chat service
 	chatService := service.NewChatService(service.ChatConfig{
 		ContextMessageLimit: cfg.ContextMessageLimit,
+		MaxToolIterations:   cfg.MaxToolIterations,
+		MaxRepairIterations: cfg.MaxRepairIterations,
+		TurnTimeout:         cfg.TurnTimeout,
 	}, claudeService, discoveryService, agentContextService, projectRepo, fileRepo, fileMetadataRepo, logger)
+	chatService.SetFileHistory(fileHistorySvc)
+	chatService.SetChangeSets(changeSetSvc)
+	chatService.SetSummaries(summarySvc)
+	chatService.SetUsage(usageSvc)
+	for _, tool := range prdService.Tools() { // Let the product manager agent edit PRDs
+		if err := chatService.Tools().Register(tool); err != nil {
+			logger.Fatal().Err(err).Msg("failed to register PRD tools")
+		}
+	}
 
 	// Initialize completeness checker
 	completenessChecker := service.NewCompletenessChecker(fileRepo, logger)
+	if cfg.RenderCheckNode != "" { // Load pages headlessly to catch runtime errors
+		runner, err := render.NewRunner(context.Background(), cfg.RenderCheckNode, cfg.RenderCheckTimeout)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to set up render check")
+		}
+		completenessChecker.SetRenderChecker(runner)
+	}
+	lintSeverities, err := service.LoadLintSeverities(cfg.LintSeverities)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load lint severities")
+	}
+	if err := lintSeverities.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid lint severities")
+	}
+	completenessChecker.SetLintSeverities(lintSeverities)
+	chatService.SetCompletenessChecker(completenessChecker)
+	completenessFixer := service.NewCompletenessFixer(completenessChecker, fileRepo, claudeService, agentContextService, logger)
+	completenessFixer.SetFileHistory(fileHistorySvc)
+	graphService := service.NewGraphService(completenessChecker, fileRepo, fileMetadataRepo, logger)
 
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler(db)
 	projectHandler := handler.NewProjectHandler(projectRepo)
 	fileHandler := handler.NewFileHandler(fileRepo, projectRepo, fileMetadataRepo)
 	uploadHandler := handler.NewUploadHandler(projectRepo, fileRepo, fileMetadataRepo, fileSourceRepo, claudeVision, logger)
+	uploadHandler.SetFileHistory(fileHistorySvc)
+	fileHistoryHandler := handler.NewFileHistoryHandler(fileHistorySvc, logger)
+	changeSetHandler := handler.NewChangeSetHandler(changeSetSvc, logger)
+	summaryHandler := handler.NewSummaryHandler(summarySvc, logger)
+	usageHandler := handler.NewUsageHandler(usageSvc, logger)
 	discoveryHandler := handler.NewDiscoveryHandler(discoveryService, logger)
 	prdHandler := handler.NewPRDHandler(prdService, logger)
 	achievementHandler := handler.NewAchievementHandler(achievementSvc, nudgeSvc, logger)
-	completenessHandler := handler.NewCompletenessHandler(completenessChecker, logger)
+	completenessHandler := handler.NewCompletenessHandler(completenessChecker, completenessFixer, logger)
+	graphHandler := handler.NewGraphHandler(graphService, logger)
 	wsHandler := handler.NewWebSocketHandler(chatService, logger)
+	fileHandler.SetEvents(wsHandler)
+	fileHistoryHandler.SetEvents(wsHandler)
+	completenessHandler.SetEvents(wsHandler)
+	uploadHandler.SetEvents(wsHandler)
+	previewHandler := handler.NewPreviewHandler(fileRepo, cfg.CORSOrigins, logger)
 
 	// Set up Gin
 	if cfg.LogLevel != "debug" {
@@ -142,6 +258,8 @@ func main() {
 			projects.PATCH("/:id", projectHandler.Update)
 			projects.DELETE("/:id", projectHandler.Delete)
 			projects.GET("/:id/files", fileHandler.ListFiles)
+			projects.GET("/:id/files/deleted", fileHistoryHandler.ListDeletedFiles)
+			projects.POST("/:id/files/deleted/restore", fileHistoryHandler.RestoreDeletedFile)
 			projects.GET("/:id/download", fileHandler.DownloadProjectZip)
 			projects.POST("/:id/upload", uploadHandler.Upload)
 
@@ -161,13 +279,36 @@ func main() {
 			projects.PUT("/:id/active-prd", prdHandler.SetActivePRD)
 			projects.DELETE("/:id/active-prd", prdHandler.ClearActivePRD)
 
-			// Completeness check route
+			// Completeness check routes
 			projects.GET("/:id/completeness", completenessHandler.GetCompleteness)
+			projects.POST("/:id/completeness/fix", completenessHandler.FixCompleteness)
+
+			// Dependency graph route
+			projects.GET("/:id/graph", graphHandler.GetGraph)
+
+			// Conversation summary route
+			projects.GET("/:id/summaries", summaryHandler.ListSummaries)
+
+			// Token usage and cost route
+			projects.GET("/:id/usage", usageHandler.GetProjectUsage)
 		}
 		files := api.Group("/files")
 		{
 			files.GET("/:id", fileHandler.GetFile)
+			files.PATCH("/:id", fileHandler.MoveFile)
+			files.DELETE("/:id", fileHandler.DeleteFile)
 			files.GET("/:id/download", fileHandler.DownloadFile)
+
+			// File version history routes
+			files.GET("/:id/versions", fileHistoryHandler.ListVersions)
+			files.GET("/:id/versions/:version", fileHistoryHandler.GetVersion)
+			files.POST("/:id/versions/:version/restore", fileHistoryHandler.RestoreVersion)
+		}
+
+		// Message routes
+		messages := api.Group("/messages")
+		{
+			messages.GET("/:id/changes", changeSetHandler.GetChanges)
 		}
 
 		// PRD routes (direct PRD access)
@@ -185,6 +326,9 @@ func main() {
 	// WebSocket endpoint
 	router.GET("/ws/chat", wsHandler.HandleConnection)
 
+	// Live preview of a project's files, served outside /api with a sandboxed CSP
+	router.GET("/preview/:projectId/*path", previewHandler.ServeFile)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -14,13 +14,56 @@ type Config struct {
 	// Database settings
 	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
 
-	// Claude API settings
-	ClaudeAPIKey    string `envconfig:"CLAUDE_API_KEY" required:"true"`
+	// LLM provider: "anthropic" or "openai" (any OpenAI-compatible chat completions server)
+	LLMProvider string `envconfig:"LLM_PROVIDER" default:"anthropic"`
+
+	// Claude API settings (required when LLM_PROVIDER is anthropic)
+	ClaudeAPIKey    string `envconfig:"CLAUDE_API_KEY"`
 	ClaudeModel     string `envconfig:"CLAUDE_MODEL" default:"claude-sonnet-4-20250514"`
 	ClaudeMaxTokens int    `envconfig:"CLAUDE_MAX_TOKENS" default:"4096"`
 
+	// OpenAI-compatible API settings (used when LLM_PROVIDER is openai)
+	OpenAIBaseURL   string `envconfig:"OPENAI_BASE_URL" default:"http://localhost:11434/v1"`
+	OpenAIAPIKey    string `envconfig:"OPENAI_API_KEY"`
+	OpenAIModel     string `envconfig:"OPENAI_MODEL"`
+	OpenAIMaxTokens int    `envconfig:"OPENAI_MAX_TOKENS" default:"4096"`
+
+	// Model routing: per-purpose/per-agent model, max tokens, temperature and stop sequences (JSON)
+	ModelRoutes     string `envconfig:"MODEL_ROUTES"`      // Inline JSON, overrides entries from the file
+	ModelRoutesFile string `envconfig:"MODEL_ROUTES_FILE"` // Optional JSON file
+
+	// LLM API resilience settings (apply to either provider)
+	ClaudeMaxRetries       int           `envconfig:"CLAUDE_MAX_RETRIES" default:"3"`
+	ClaudeRetryBaseDelay   time.Duration `envconfig:"CLAUDE_RETRY_BASE_DELAY" default:"500ms"`
+	ClaudeRetryMaxDelay    time.Duration `envconfig:"CLAUDE_RETRY_MAX_DELAY" default:"30s"`
+	ClaudeBreakerThreshold int           `envconfig:"CLAUDE_BREAKER_THRESHOLD" default:"5"`
+	ClaudeBreakerCooldown  time.Duration `envconfig:"CLAUDE_BREAKER_COOLDOWN" default:"30s"`
+
+	// LLM cassette settings: "record" saves every API exchange, "replay" serves them without network access
+	LLMCassetteMode string `envconfig:"LLM_CASSETTE_MODE"`
+	LLMCassetteDir  string `envconfig:"LLM_CASSETTE_DIR" default:"testdata/cassettes"`
+
+	// Cost accounting settings
+	ModelPricesFile string `envconfig:"MODEL_PRICES_FILE"` // Optional JSON price table overriding the defaults
+
 	// Context settings
 	ContextMessageLimit int `envconfig:"CONTEXT_MESSAGE_LIMIT" default:"20"`
+	SummaryTokenBudget  int `envconfig:"SUMMARY_TOKEN_BUDGET" default:"12000"`
+	SummaryKeepRecent   int `envconfig:"SUMMARY_KEEP_RECENT" default:"10"`
+
+	// Render check settings: Node runs each HTML page's scripts after turns that write files
+	RenderCheckNode    string        `envconfig:"RENDER_CHECK_NODE"` // Path to the node binary (Node 20 or later); empty disables the render check
+	RenderCheckTimeout time.Duration `envconfig:"RENDER_CHECK_TIMEOUT" default:"10s"`
+
+	// Accessibility and SEO lint settings: per-rule severity, "critical", "warning", "info" or "off" (JSON)
+	LintSeverities string `envconfig:"LINT_SEVERITIES"` // e.g. {"img-alt": "critical", "single-h1": "off"}
+
+	// Tool use settings
+	MaxToolIterations   int `envconfig:"MAX_TOOL_ITERATIONS" default:"10"`  // Rounds of tool calls per chat turn
+	MaxRepairIterations int `envconfig:"MAX_REPAIR_ITERATIONS" default:"2"` // Repair turns fixing critical completeness issues after a chat turn; 0 disables them
+
+	// Time a chat turn, and each of its repair turns, has to get Claude's response
+	TurnTimeout time.Duration `envconfig:"TURN_TIMEOUT" default:"180s"`
 
 	// Logging settings
 	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
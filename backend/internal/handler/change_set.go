@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+// ChangeSetHandler handles per-turn change set endpoints.
+type ChangeSetHandler struct {
+	changeSets *service.ChangeSetService
+	logger     zerolog.Logger
+}
+
+// NewChangeSetHandler creates a new ChangeSetHandler.
+func NewChangeSetHandler(changeSets *service.ChangeSetService, logger zerolog.Logger) *ChangeSetHandler {
+	return &ChangeSetHandler{
+		changeSets: changeSets,
+		logger:     logger,
+	}
+}
+
+// GetChanges returns the files changed during an assistant message's turn with unified diffs.
+// Pass ?format=patch to get the plain-text unified diff instead of JSON.
+// GET /api/messages/:id/changes
+func (h *ChangeSetHandler) GetChanges(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	changes, err := h.changeSets.GetChanges(c.Request.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, service.ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		h.logger.Error().Err(err).Str("messageId", messageID.String()).Msg("failed to get message changes")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get message changes"})
+		return
+	}
+
+	if c.Query("format") == "patch" {
+		var sb strings.Builder
+		for _, file := range changes.Files {
+			sb.WriteString(file.Diff)
+		}
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(sb.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
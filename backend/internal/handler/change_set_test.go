@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+// setupChangeSetTestRouter returns a router and the IDs of an assistant message with a
+// change set and of one without.
+func setupChangeSetTestRouter(t *testing.T) (*gin.Engine, uuid.UUID, uuid.UUID) {
+	ctx := context.Background()
+	projectRepo := repository.NewMockProjectRepository()
+	project, err := projectRepo.Create(ctx, "Test Project")
+	require.NoError(t, err)
+	changed, err := projectRepo.CreateMessage(ctx, project.ID, model.RoleAssistant, "Updated the page.")
+	require.NoError(t, err)
+	unchanged, err := projectRepo.CreateMessage(ctx, project.ID, model.RoleAssistant, "Nothing to change.")
+	require.NoError(t, err)
+
+	changeSets := service.NewChangeSetService(repository.NewMockFileChangeRepository(), projectRepo, zerolog.Nop())
+
+	before, after := "hello\nworld\n", "hello\nthere\n"
+	_, err = changeSets.Record(ctx, project.ID, changed.ID, []service.FileSnapshot{
+		{Path: "index.html", Before: &before, After: &after},
+	})
+	require.NoError(t, err)
+
+	handler := NewChangeSetHandler(changeSets, zerolog.Nop())
+	router := gin.New()
+	router.GET("/api/messages/:id/changes", handler.GetChanges)
+
+	return router, changed.ID, unchanged.ID
+}
+
+func TestChangeSetHandler_GetChanges(t *testing.T) {
+	t.Run("returns diffs as JSON", func(t *testing.T) {
+		router, messageID, _ := setupChangeSetTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/"+messageID.String()+"/changes", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.MessageChangesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, messageID, response.MessageID)
+		require.Len(t, response.Files, 1)
+		assert.Equal(t, model.FileChangeModified, response.Files[0].ChangeType)
+		require.Len(t, response.Files[0].Hunks, 1)
+		assert.Len(t, response.Files[0].Hunks[0].Lines, 3)
+	})
+
+	t.Run("returns unified diff text with format=patch", func(t *testing.T) {
+		router, messageID, _ := setupChangeSetTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/"+messageID.String()+"/changes?format=patch", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/x-diff")
+		assert.Equal(t, "--- a/index.html\n+++ b/index.html\n@@ -1,2 +1,2 @@\n hello\n-world\n+there\n", w.Body.String())
+	})
+
+	t.Run("returns empty change set for message without changes", func(t *testing.T) {
+		router, _, messageID := setupChangeSetTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/"+messageID.String()+"/changes", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.MessageChangesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.Files)
+	})
+
+	t.Run("returns 404 for unknown message", func(t *testing.T) {
+		router, _, _ := setupChangeSetTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/"+uuid.New().String()+"/changes", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns 400 for invalid message ID", func(t *testing.T) {
+		router, _, _ := setupChangeSetTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/messages/not-a-uuid/changes", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// streamRetention is how long a finished stream stays available for resume.
+const streamRetention = 5 * time.Minute
+
+// errSubscriberClosed is returned when sending to a subscriber whose connection closed.
+var errSubscriberClosed = errors.New("subscriber closed")
+
+// streamSubscriber is a client following one or more chat streams.
+type streamSubscriber struct {
+	send func(event interface{}) error
+	stop func() // Stops the subscriber's writer; nil if it has none
+}
+
+// close stops the subscriber's writer, dropping events it hasn't written yet.
+func (s *streamSubscriber) close() {
+	if s.stop != nil {
+		s.stop()
+	}
+}
+
+// newConnSubscriber creates a subscriber that writes events to a WebSocket connection,
+// serialized with other writers through mu.
+func newConnSubscriber(conn *websocket.Conn, mu *sync.Mutex) *streamSubscriber {
+	return newQueuedSubscriber(func(event interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return conn.WriteJSON(event)
+	})
+}
+
+// newQueuedSubscriber creates a subscriber that queues events and writes them in order
+// from its own goroutine, so a slow client holds up neither the stream publishing them
+// nor the other clients following it. Once a write fails, sends return its error.
+func newQueuedSubscriber(write func(event interface{}) error) *streamSubscriber {
+	q := &subscriberQueue{}
+	q.wake = sync.NewCond(&q.mu)
+	go q.run(write)
+	return &streamSubscriber{send: q.push, stop: q.close}
+}
+
+// subscriberQueue holds the events a queued subscriber has yet to write.
+type subscriberQueue struct {
+	mu     sync.Mutex
+	wake   *sync.Cond
+	events []interface{}
+	err    error // First write error
+	closed bool
+}
+
+// push queues an event without waiting for it to be written.
+func (q *subscriberQueue) push(event interface{}) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err != nil {
+		return q.err
+	}
+	if q.closed {
+		return errSubscriberClosed
+	}
+	q.events = append(q.events, event)
+	q.wake.Signal()
+	return nil
+}
+
+// run writes queued events until the queue is closed or a write fails.
+func (q *subscriberQueue) run(write func(event interface{}) error) {
+	for {
+		q.mu.Lock()
+		for len(q.events) == 0 && !q.closed {
+			q.wake.Wait()
+		}
+		if q.closed {
+			q.events = nil
+			q.mu.Unlock()
+			return
+		}
+		event := q.events[0]
+		q.events[0] = nil
+		q.events = q.events[1:]
+		q.mu.Unlock()
+
+		if err := write(event); err != nil {
+			q.mu.Lock()
+			q.err = err
+			q.events = nil
+			q.mu.Unlock()
+			return
+		}
+	}
+}
+
+// close stops the writer.
+func (q *subscriberQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.wake.Signal()
+}
+
+// chatStream buffers the events of one assistant response so clients can
+// reconnect and replay what they missed. Every event carries a sequence number.
+type chatStream struct {
+	messageID string
+	projectID uuid.UUID
+	cancel    context.CancelFunc // Cancels the generation's context
+
+	mu          sync.Mutex
+	events      []interface{} // events[i] has sequence number i+1
+	done        bool
+	subscribers map[*streamSubscriber]struct{}
+}
+
+// publish assigns the next sequence number, buffers the event, and fans it out to subscribers.
+// Subscribers whose connection fails are dropped; they can resume later. Connection
+// subscribers only queue the event here (see newQueuedSubscriber), so publishing doesn't
+// wait for the network.
+func (s *chatStream) publish(build func(seq int) interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := build(len(s.events) + 1)
+	s.events = append(s.events, event)
+
+	for sub := range s.subscribers {
+		if err := sub.send(event); err != nil {
+			delete(s.subscribers, sub)
+		}
+	}
+}
+
+// subscribe replays buffered events after afterSeq and, if the stream is still
+// running, follows live events. Returns the number of replayed events.
+func (s *chatStream) subscribe(sub *streamSubscriber, afterSeq int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if afterSeq < 0 {
+		afterSeq = 0
+	}
+
+	replayed := 0
+	for _, event := range s.events[min(afterSeq, len(s.events)):] {
+		if err := sub.send(event); err != nil {
+			return replayed
+		}
+		replayed++
+	}
+
+	if !s.done {
+		s.subscribers[sub] = struct{}{}
+	}
+	return replayed
+}
+
+// unsubscribe stops sending live events to a subscriber.
+func (s *chatStream) unsubscribe(sub *streamSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, sub)
+}
+
+// requestCancel stops a running generation. Returns false if it has already finished.
+func (s *chatStream) requestCancel() bool {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+
+	if done {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+// lastSeq returns the sequence number of the most recent event.
+func (s *chatStream) lastSeq() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// chatStreamRegistry tracks running and recently finished chat streams by message ID.
+type chatStreamRegistry struct {
+	mu        sync.Mutex
+	streams   map[string]*chatStream
+	active    map[uuid.UUID]*chatStream // projectID -> running stream
+	retention time.Duration
+}
+
+// newChatStreamRegistry creates a registry that keeps finished streams for the given duration.
+func newChatStreamRegistry(retention time.Duration) *chatStreamRegistry {
+	return &chatStreamRegistry{
+		streams:   make(map[string]*chatStream),
+		active:    make(map[uuid.UUID]*chatStream),
+		retention: retention,
+	}
+}
+
+// start registers a new stream for a project; cancel aborts its generation.
+// Returns false if the project already has a running stream.
+func (r *chatStreamRegistry) start(projectID uuid.UUID, cancel context.CancelFunc) (*chatStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, running := r.active[projectID]; running {
+		return nil, false
+	}
+
+	stream := &chatStream{
+		messageID:   uuid.New().String(),
+		projectID:   projectID,
+		cancel:      cancel,
+		subscribers: make(map[*streamSubscriber]struct{}),
+	}
+	r.streams[stream.messageID] = stream
+	r.active[projectID] = stream
+	return stream, true
+}
+
+// get returns a running or recently finished stream.
+func (r *chatStreamRegistry) get(messageID string) (*chatStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stream, ok := r.streams[messageID]
+	return stream, ok
+}
+
+// activeFor returns the running stream of a project, if any.
+func (r *chatStreamRegistry) activeFor(projectID uuid.UUID) (*chatStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stream, ok := r.active[projectID]
+	return stream, ok
+}
+
+// finish marks a stream as done after its final event was published,
+// and schedules its removal once the retention period expires.
+func (r *chatStreamRegistry) finish(stream *chatStream) {
+	stream.mu.Lock()
+	stream.done = true
+	stream.subscribers = make(map[*streamSubscriber]struct{})
+	stream.mu.Unlock()
+
+	r.mu.Lock()
+	if r.active[stream.projectID] == stream {
+		delete(r.active, stream.projectID)
+	}
+	r.mu.Unlock()
+
+	time.AfterFunc(r.retention, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.streams, stream.messageID)
+	})
+}
+
+// unsubscribeAll detaches a closed connection from every stream it follows.
+func (r *chatStreamRegistry) unsubscribeAll(sub *streamSubscriber) {
+	r.mu.Lock()
+	streams := make([]*chatStream, 0, len(r.streams))
+	for _, stream := range r.streams {
+		streams = append(streams, stream)
+	}
+	r.mu.Unlock()
+
+	for _, stream := range streams {
+		stream.unsubscribe(sub)
+	}
+}
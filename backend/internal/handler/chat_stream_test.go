@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSubscriber collects the sequence numbers of events it receives.
+func recordingSubscriber() (*streamSubscriber, *[]int) {
+	var seqs []int
+	return &streamSubscriber{
+		send: func(event interface{}) error {
+			seqs = append(seqs, event.(WebSocketMessage).Seq)
+			return nil
+		},
+	}, &seqs
+}
+
+func publishChunk(stream *chatStream, content string) {
+	stream.publish(func(seq int) interface{} {
+		return WebSocketMessage{Type: "message_chunk", Content: content, Seq: seq}
+	})
+}
+
+func TestChatStream_ResumeReplaysMissedEvents(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	stream, ok := registry.start(uuid.New(), func() {})
+	require.True(t, ok)
+
+	first, firstSeqs := recordingSubscriber()
+	stream.subscribe(first, 0)
+	publishChunk(stream, "a")
+	publishChunk(stream, "b")
+
+	// Connection drops after seeing seq 2
+	registry.unsubscribeAll(first)
+	publishChunk(stream, "c")
+	publishChunk(stream, "d")
+
+	// Reconnect and resume from seq 2
+	resumed, resumedSeqs := recordingSubscriber()
+	replayed := stream.subscribe(resumed, 2)
+	publishChunk(stream, "e")
+
+	assert.Equal(t, []int{1, 2}, *firstSeqs)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, []int{3, 4, 5}, *resumedSeqs, "resumed client should get missed events then live ones")
+}
+
+func TestChatStream_ResumeAfterFinish(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	projectID := uuid.New()
+	stream, _ := registry.start(projectID, func() {})
+	publishChunk(stream, "a")
+	publishChunk(stream, "b")
+	registry.finish(stream)
+
+	got, ok := registry.get(stream.messageID)
+	require.True(t, ok, "finished streams should be kept for the retention period")
+
+	sub, seqs := recordingSubscriber()
+	got.subscribe(sub, 1)
+	assert.Equal(t, []int{2}, *seqs)
+
+	_, active := registry.activeFor(projectID)
+	assert.False(t, active)
+}
+
+func TestChatStream_DropsFailingSubscriber(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	stream, _ := registry.start(uuid.New(), func() {})
+
+	calls := 0
+	failing := &streamSubscriber{send: func(interface{}) error {
+		calls++
+		return errors.New("connection closed")
+	}}
+	stream.subscribe(failing, 0)
+
+	publishChunk(stream, "a")
+	publishChunk(stream, "b")
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestChatStream_StalledSubscriberDoesNotBlockOthers(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	stream, _ := registry.start(uuid.New(), func() {})
+
+	release := make(chan struct{})
+	stalled := newQueuedSubscriber(func(interface{}) error {
+		<-release
+		return nil
+	})
+	defer stalled.close()
+	defer close(release)
+
+	var mu sync.Mutex
+	var seqs []int
+	live := newQueuedSubscriber(func(event interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seqs = append(seqs, event.(WebSocketMessage).Seq)
+		return nil
+	})
+	defer live.close()
+
+	stream.subscribe(stalled, 0)
+	stream.subscribe(live, 0)
+
+	published := make(chan struct{})
+	go func() {
+		publishChunk(stream, "a")
+		publishChunk(stream, "b")
+		publishChunk(stream, "c")
+		close(published)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a stalled subscriber")
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seqs) == 3
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{1, 2, 3}, seqs)
+}
+
+func TestQueuedSubscriber_RejectsAfterWriteError(t *testing.T) {
+	failed := make(chan struct{})
+	sub := newQueuedSubscriber(func(interface{}) error {
+		close(failed)
+		return errors.New("connection closed")
+	})
+	defer sub.close()
+
+	require.NoError(t, sub.send(WebSocketMessage{Seq: 1}))
+	<-failed
+	assert.Eventually(t, func() bool { return sub.send(WebSocketMessage{Seq: 2}) != nil }, time.Second, 5*time.Millisecond)
+}
+
+func TestChatStreamRegistry_OneActiveStreamPerProject(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	projectID := uuid.New()
+
+	stream, ok := registry.start(projectID, func() {})
+	require.True(t, ok)
+
+	_, ok = registry.start(projectID, func() {})
+	assert.False(t, ok)
+
+	_, ok = registry.start(uuid.New(), func() {})
+	assert.True(t, ok, "other projects can stream concurrently")
+
+	registry.finish(stream)
+	_, ok = registry.start(projectID, func() {})
+	assert.True(t, ok)
+}
+
+func TestChatStreamRegistry_ExpiresFinishedStreams(t *testing.T) {
+	registry := newChatStreamRegistry(10 * time.Millisecond)
+	stream, _ := registry.start(uuid.New(), func() {})
+	registry.finish(stream)
+
+	assert.Eventually(t, func() bool {
+		_, ok := registry.get(stream.messageID)
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestChatStream_RequestCancel(t *testing.T) {
+	registry := newChatStreamRegistry(time.Minute)
+	cancelled := 0
+	stream, _ := registry.start(uuid.New(), func() { cancelled++ })
+
+	assert.True(t, stream.requestCancel())
+	assert.Equal(t, 1, cancelled)
+
+	registry.finish(stream)
+	assert.False(t, stream.requestCancel(), "finished streams cannot be cancelled")
+	assert.Equal(t, 1, cancelled)
+}
@@ -1,28 +1,40 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
 )
 
 // CompletenessHandler handles completeness check HTTP endpoints.
 type CompletenessHandler struct {
 	checker *service.CompletenessChecker
+	fixer   *service.CompletenessFixer
+	events  FileEventPublisher
 	logger  zerolog.Logger
 }
 
 // NewCompletenessHandler creates a new CompletenessHandler.
-func NewCompletenessHandler(checker *service.CompletenessChecker, logger zerolog.Logger) *CompletenessHandler {
+func NewCompletenessHandler(checker *service.CompletenessChecker, fixer *service.CompletenessFixer, logger zerolog.Logger) *CompletenessHandler {
 	return &CompletenessHandler{
 		checker: checker,
+		fixer:   fixer,
 		logger:  logger,
 	}
 }
 
+// SetEvents sets the publisher used to notify connected clients of files created by a fix.
+// This is optional - if not set, clients see the change on their next refresh.
+func (h *CompletenessHandler) SetEvents(events FileEventPublisher) {
+	h.events = events
+}
+
 // GetCompleteness returns the completeness report for a project.
 // GET /api/projects/:id/completeness
 func (h *CompletenessHandler) GetCompleteness(c *gin.Context) {
@@ -42,3 +54,35 @@ func (h *CompletenessHandler) GetCompleteness(c *gin.Context) {
 
 	c.JSON(http.StatusOK, report)
 }
+
+// FixCompleteness generates the missing files of the requested issues, or of all
+// auto-fixable issues if none are given, and returns the result with a new report.
+// POST /api/projects/:id/completeness/fix
+func (h *CompletenessHandler) FixCompleteness(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	// The body is optional; without one every auto-fixable issue is fixed
+	var req model.CompletenessFixRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	response, err := h.fixer.Fix(c.Request.Context(), projectID, req.IssueIDs)
+	if err != nil {
+		h.logger.Error().Err(err).Str("projectId", projectIDStr).Msg("failed to fix completeness issues")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fix completeness issues"})
+		return
+	}
+
+	if h.events != nil && len(response.Files) > 0 {
+		h.events.PublishFilesUpdated(projectID, response.Files)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
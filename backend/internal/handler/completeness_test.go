@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+func setupCompletenessTestRouter() (*gin.Engine, *repository.MockFileRepository, *repository.MockProjectRepository, *recordingFileEvents) {
+	fileRepo := repository.NewMockFileRepository()
+	projectRepo := repository.NewMockProjectRepository()
+	agentContext := service.NewAgentContextService(repository.NewMockPRDRepository(), projectRepo, repository.NewMockDiscoveryRepository(), zerolog.Nop())
+	checker := service.NewCompletenessChecker(fileRepo, zerolog.Nop())
+	fixer := service.NewCompletenessFixer(checker, fileRepo, service.NewMockClaudeServiceSimple(), agentContext, zerolog.Nop())
+	events := &recordingFileEvents{}
+	handler := NewCompletenessHandler(checker, fixer, zerolog.Nop())
+	handler.SetEvents(events)
+
+	router := gin.New()
+	router.GET("/api/projects/:id/completeness", handler.GetCompleteness)
+	router.POST("/api/projects/:id/completeness/fix", handler.FixCompleteness)
+
+	return router, fileRepo, projectRepo, events
+}
+
+func TestCompletenessHandler_FixCompleteness(t *testing.T) {
+	t.Run("rejects an invalid project id", func(t *testing.T) {
+		router, _, _, _ := setupCompletenessTestRouter()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/projects/not-a-uuid/completeness/fix", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("rejects an invalid body", func(t *testing.T) {
+		router, _, _, _ := setupCompletenessTestRouter()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/projects/"+uuid.New().String()+"/completeness/fix", strings.NewReader("{"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("reports issues that can't be fixed automatically as failed", func(t *testing.T) {
+		router, fileRepo, projectRepo, events := setupCompletenessTestRouter()
+		project, _ := projectRepo.Create(context.Background(), "Test Project")
+		_, _ = fileRepo.SaveFile(context.Background(), project.ID, "index.html", "html", `<img src="logo.png" alt="Logo">`)
+
+		// Look up the issue ID from a report
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+project.ID.String()+"/completeness", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var report model.CompletenessReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		require.Len(t, report.Issues, 1)
+
+		body := `{"issueIds": ["` + report.Issues[0].ID + `"]}`
+		req = httptest.NewRequest(http.MethodPost, "/api/projects/"+project.ID.String()+"/completeness/fix", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.CompletenessFixResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.Fixed)
+		assert.Equal(t, []string{report.Issues[0].ID}, response.Failed)
+		require.NotNil(t, response.NewReport)
+		assert.Len(t, response.NewReport.Issues, 1)
+		assert.Empty(t, events.events)
+	})
+
+	t.Run("fixes nothing without a body when nothing is auto-fixable", func(t *testing.T) {
+		router, fileRepo, projectRepo, _ := setupCompletenessTestRouter()
+		project, _ := projectRepo.Create(context.Background(), "Test Project")
+		_, _ = fileRepo.SaveFile(context.Background(), project.ID, "index.html", "html", "<h1>Hi</h1>")
+
+		req := httptest.NewRequest(http.MethodPost, "/api/projects/"+project.ID.String()+"/completeness/fix", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.CompletenessFixResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.Fixed)
+		assert.Empty(t, response.Failed)
+		assert.Equal(t, model.StatusPass, response.NewReport.Status)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
 )
 
 // FileHandler handles file-related endpoints.
@@ -20,6 +21,7 @@ type FileHandler struct {
 	fileRepo         repository.FileRepository
 	projectRepo      repository.ProjectRepository
 	fileMetadataRepo repository.FileMetadataRepository
+	events           FileEventPublisher
 }
 
 // NewFileHandler creates a new FileHandler.
@@ -31,6 +33,12 @@ func NewFileHandler(fileRepo repository.FileRepository, projectRepo repository.P
 	}
 }
 
+// SetEvents sets the publisher used to notify connected clients of deleted and moved files.
+// This is optional - if not set, clients see the change on their next refresh.
+func (h *FileHandler) SetEvents(events FileEventPublisher) {
+	h.events = events
+}
+
 // ListFiles returns all files for a project with metadata.
 // GET /api/projects/:id/files
 func (h *FileHandler) ListFiles(c *gin.Context) {
@@ -110,10 +118,114 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		return
 	}
 
-	// Try to get metadata for the file
+	c.JSON(http.StatusOK, h.fileResponse(c, file))
+}
+
+// DeleteFile deletes a file with its metadata and source record.
+// DELETE /api/files/:id
+func (h *FileHandler) DeleteFile(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file id"})
+		return
+	}
+
+	file, err := h.fileRepo.GetFile(c.Request.Context(), id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get file"})
+		return
+	}
+
+	if err := h.fileRepo.DeleteFile(c.Request.Context(), id); err != nil {
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete file"})
+		return
+	}
+
+	if h.events != nil {
+		h.events.PublishFilesDeleted(file.ProjectID, []string{file.Path})
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MoveFile moves (renames) a file within its project.
+// PATCH /api/files/:id
+func (h *FileHandler) MoveFile(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file id"})
+		return
+	}
+
+	var req model.MoveFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if req.Path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	newPath, err := service.CleanFilePath(req.Path)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.fileRepo.GetFile(c.Request.Context(), id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get file"})
+		return
+	}
+
+	oldPath := file.Path
+	if newPath == oldPath {
+		c.JSON(http.StatusOK, h.fileResponse(c, file))
+		return
+	}
+
+	moved, err := h.fileRepo.MoveFile(c.Request.Context(), id, newPath)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		case repository.ErrPathExists:
+			c.JSON(http.StatusConflict, gin.H{"error": "a file already exists at that path"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to move file"})
+		}
+		return
+	}
+
+	if h.events != nil {
+		h.events.PublishFilesDeleted(moved.ProjectID, []string{oldPath})
+		h.events.PublishFilesUpdated(moved.ProjectID, []string{moved.Path})
+	}
+
+	c.JSON(http.StatusOK, h.fileResponse(c, moved))
+}
+
+// fileResponse builds the single-file response, including metadata when available.
+func (h *FileHandler) fileResponse(c *gin.Context, file *model.File) model.GetFileResponse {
 	var shortDesc, longDesc, funcGroup string
 	if h.fileMetadataRepo != nil {
-		metadata, err := h.fileMetadataRepo.GetByFileID(c.Request.Context(), id)
+		metadata, err := h.fileMetadataRepo.GetByFileID(c.Request.Context(), file.ID)
 		if err == nil && metadata != nil {
 			shortDesc = metadata.ShortDescription
 			longDesc = metadata.LongDescription
@@ -121,7 +233,7 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, model.GetFileResponse{
+	return model.GetFileResponse{
 		ID:               file.ID,
 		ProjectID:        file.ProjectID,
 		Path:             file.Path,
@@ -132,7 +244,7 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		LongDescription:  longDesc,
 		FunctionalGroup:  funcGroup,
 		CreatedAt:        file.CreatedAt,
-	})
+	}
 }
 
 // DownloadProjectZip creates and returns a zip archive of all project files.
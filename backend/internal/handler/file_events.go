@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileEventPublisher notifies a project's connected clients of file changes made
+// outside a chat turn, e.g. through the REST API.
+type FileEventPublisher interface {
+	PublishFilesUpdated(projectID uuid.UUID, paths []string)
+	PublishFilesDeleted(projectID uuid.UUID, paths []string)
+}
+
+// projectConnections tracks the open WebSocket connections of each project.
+type projectConnections struct {
+	mu    sync.Mutex
+	conns map[uuid.UUID]map[*streamSubscriber]struct{}
+}
+
+// newProjectConnections creates an empty connection set.
+func newProjectConnections() *projectConnections {
+	return &projectConnections{conns: make(map[uuid.UUID]map[*streamSubscriber]struct{})}
+}
+
+// add registers a connection for a project.
+func (p *projectConnections) add(projectID uuid.UUID, sub *streamSubscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns[projectID] == nil {
+		p.conns[projectID] = make(map[*streamSubscriber]struct{})
+	}
+	p.conns[projectID][sub] = struct{}{}
+}
+
+// remove unregisters a closed connection.
+func (p *projectConnections) remove(projectID uuid.UUID, sub *streamSubscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.conns[projectID], sub)
+	if len(p.conns[projectID]) == 0 {
+		delete(p.conns, projectID)
+	}
+}
+
+// broadcast sends an event to every connection of a project. Send failures are
+// ignored; the connection's read loop notices the close and removes it.
+func (p *projectConnections) broadcast(projectID uuid.UUID, event interface{}) {
+	p.mu.Lock()
+	subs := make([]*streamSubscriber, 0, len(p.conns[projectID]))
+	for sub := range p.conns[projectID] {
+		subs = append(subs, sub)
+	}
+	p.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.send(event)
+	}
+}
+
+// PublishFilesUpdated sends files_updated and preview_reload to all of a project's connections.
+func (h *WebSocketHandler) PublishFilesUpdated(projectID uuid.UUID, paths []string) {
+	h.connections.broadcast(projectID, FilesUpdatedResponse{
+		Type:      "files_updated",
+		FilePaths: paths,
+		Timestamp: time.Now().UTC(),
+	})
+	h.publishPreviewReload(projectID, paths)
+}
+
+// PublishFilesDeleted sends files_deleted and preview_reload to all of a project's connections.
+func (h *WebSocketHandler) PublishFilesDeleted(projectID uuid.UUID, paths []string) {
+	h.connections.broadcast(projectID, FilesUpdatedResponse{
+		Type:      "files_deleted",
+		FilePaths: paths,
+		Timestamp: time.Now().UTC(),
+	})
+	h.publishPreviewReload(projectID, paths)
+}
+
+// publishPreviewReload sends preview_reload to all of a project's connections. Every project
+// file is served by the preview, so any change can affect it. Unlike the chat stream's events
+// it isn't buffered for resume; a reconnecting preview loads the current files anyway.
+func (h *WebSocketHandler) publishPreviewReload(projectID uuid.UUID, paths []string) {
+	h.connections.broadcast(projectID, PreviewReloadResponse{
+		Type:      "preview_reload",
+		FilePaths: paths,
+		Timestamp: time.Now().UTC(),
+	})
+}
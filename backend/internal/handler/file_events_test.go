@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileEventSubscriber collects the files events it receives as "type:paths" entries.
+func fileEventSubscriber() (*streamSubscriber, *[]string) {
+	var received []string
+	return &streamSubscriber{
+		send: func(event interface{}) error {
+			if e, ok := event.(FilesUpdatedResponse); ok {
+				for _, p := range e.FilePaths {
+					received = append(received, e.Type+":"+p)
+				}
+			}
+			return nil
+		},
+	}, &received
+}
+
+func TestWebSocketHandler_PublishesFileEventsToProjectConnections(t *testing.T) {
+	h := NewWebSocketHandler(nil, zerolog.Nop())
+	projectID := uuid.New()
+
+	first, firstReceived := fileEventSubscriber()
+	second, secondReceived := fileEventSubscriber()
+	other, otherReceived := fileEventSubscriber()
+	h.connections.add(projectID, first)
+	h.connections.add(projectID, second)
+	h.connections.add(uuid.New(), other)
+
+	h.PublishFilesDeleted(projectID, []string{"about.html"})
+	h.PublishFilesUpdated(projectID, []string{"pages/about.html"})
+
+	expected := []string{"files_deleted:about.html", "files_updated:pages/about.html"}
+	assert.Equal(t, expected, *firstReceived)
+	assert.Equal(t, expected, *secondReceived)
+	assert.Empty(t, *otherReceived, "events are scoped to the project")
+
+	// Closed connections stop receiving events
+	h.connections.remove(projectID, second)
+	h.PublishFilesDeleted(projectID, []string{"pages/about.html"})
+	assert.Len(t, *firstReceived, 3)
+	assert.Len(t, *secondReceived, 2)
+}
+
+func TestWebSocketHandler_PublishesPreviewReload(t *testing.T) {
+	h := NewWebSocketHandler(nil, zerolog.Nop())
+	projectID := uuid.New()
+
+	var reloads [][]string
+	h.connections.add(projectID, &streamSubscriber{
+		send: func(event interface{}) error {
+			if e, ok := event.(PreviewReloadResponse); ok {
+				assert.Equal(t, "preview_reload", e.Type)
+				reloads = append(reloads, e.FilePaths)
+			}
+			return nil
+		},
+	})
+
+	h.PublishFilesUpdated(projectID, []string{"index.html", "style.css"})
+	h.PublishFilesDeleted(projectID, []string{"old.js"})
+
+	assert.Equal(t, [][]string{{"index.html", "style.css"}, {"old.js"}}, reloads)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+// FileHistoryHandler handles file version history endpoints.
+type FileHistoryHandler struct {
+	fileHistory *service.FileHistoryService
+	events      FileEventPublisher
+	logger      zerolog.Logger
+}
+
+// NewFileHistoryHandler creates a new FileHistoryHandler.
+func NewFileHistoryHandler(fileHistory *service.FileHistoryService, logger zerolog.Logger) *FileHistoryHandler {
+	return &FileHistoryHandler{
+		fileHistory: fileHistory,
+		logger:      logger,
+	}
+}
+
+// SetEvents sets the publisher used to notify connected clients of restored files.
+// This is optional - if not set, clients see the change on their next refresh.
+func (h *FileHistoryHandler) SetEvents(events FileEventPublisher) {
+	h.events = events
+}
+
+// ListVersions returns the version history of a file.
+// GET /api/files/:id/versions
+func (h *FileHistoryHandler) ListVersions(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file id"})
+		return
+	}
+
+	file, versions, err := h.fileHistory.ListVersions(c.Request.Context(), fileID)
+	if err != nil {
+		h.handleError(c, err, fileID, "failed to list file versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, model.ListFileVersionsResponse{
+		FileID:   file.ID,
+		Path:     file.Path,
+		Versions: versions,
+	})
+}
+
+// GetVersion returns a single version of a file, including its content.
+// GET /api/files/:id/versions/:version
+func (h *FileHistoryHandler) GetVersion(c *gin.Context) {
+	fileID, version, ok := h.parseVersionParams(c)
+	if !ok {
+		return
+	}
+
+	fileVersion, err := h.fileHistory.GetVersion(c.Request.Context(), fileID, version)
+	if err != nil {
+		h.handleError(c, err, fileID, "failed to get file version")
+		return
+	}
+
+	c.JSON(http.StatusOK, fileVersion)
+}
+
+// RestoreVersion overwrites a file with the content of an earlier version.
+// POST /api/files/:id/versions/:version/restore
+func (h *FileHistoryHandler) RestoreVersion(c *gin.Context) {
+	fileID, version, ok := h.parseVersionParams(c)
+	if !ok {
+		return
+	}
+
+	file, newVersion, err := h.fileHistory.RestoreVersion(c.Request.Context(), fileID, version)
+	if err != nil {
+		h.handleError(c, err, fileID, "failed to restore file version")
+		return
+	}
+
+	if h.events != nil {
+		h.events.PublishFilesUpdated(file.ProjectID, []string{file.Path})
+	}
+
+	c.JSON(http.StatusOK, restoreResponse(file, newVersion))
+}
+
+// ListDeletedFiles returns the deleted files of a project that can be restored.
+// GET /api/projects/:id/files/deleted
+func (h *FileHistoryHandler) ListDeletedFiles(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	versions, err := h.fileHistory.ListDeletedFiles(c.Request.Context(), projectID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("projectId", projectID.String()).Msg("failed to list deleted files")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deleted files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.ListDeletedFilesResponse{
+		ProjectID: projectID,
+		Files:     versions,
+	})
+}
+
+// RestoreDeletedFile recreates a deleted file from its latest version.
+// POST /api/projects/:id/files/deleted/restore
+func (h *FileHistoryHandler) RestoreDeletedFile(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	var req model.RestoreDeletedFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.Path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path is required"})
+		return
+	}
+
+	file, newVersion, err := h.fileHistory.RestoreDeletedFile(c.Request.Context(), projectID, req.Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFileVersionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "no deleted file at that path"})
+		case errors.Is(err, service.ErrFileExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "a file already exists at that path"})
+		default:
+			h.logger.Error().Err(err).Str("projectId", projectID.String()).Str("path", req.Path).Msg("failed to restore deleted file")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore deleted file"})
+		}
+		return
+	}
+
+	if h.events != nil {
+		h.events.PublishFilesUpdated(file.ProjectID, []string{file.Path})
+	}
+
+	c.JSON(http.StatusOK, restoreResponse(file, newVersion))
+}
+
+// restoreResponse builds the response for a restored file and its new version.
+func restoreResponse(file *model.File, version *model.FileVersion) model.RestoreFileVersionResponse {
+	return model.RestoreFileVersionResponse{
+		File: model.GetFileResponse{
+			ID:        file.ID,
+			ProjectID: file.ProjectID,
+			Path:      file.Path,
+			Filename:  file.Filename,
+			Language:  file.Language,
+			Content:   file.Content,
+			CreatedAt: file.CreatedAt,
+		},
+		Version: *version,
+	}
+}
+
+// parseVersionParams parses the file ID and version number from the URL.
+// It writes a 400 response and returns false if either is invalid.
+func (h *FileHistoryHandler) parseVersionParams(c *gin.Context) (uuid.UUID, int, bool) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file id"})
+		return uuid.Nil, 0, false
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return uuid.Nil, 0, false
+	}
+
+	return fileID, version, true
+}
+
+// handleError maps file history service errors to HTTP responses.
+func (h *FileHistoryHandler) handleError(c *gin.Context, err error, fileID uuid.UUID, msg string) {
+	switch {
+	case errors.Is(err, service.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+	case errors.Is(err, service.ErrFileVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+	default:
+		h.logger.Error().Err(err).Str("fileId", fileID.String()).Msg(msg)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
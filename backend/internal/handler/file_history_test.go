@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+func setupFileHistoryTestRouter() (*gin.Engine, *repository.MockFileRepository, *service.FileHistoryService) {
+	fileRepo := repository.NewMockFileRepository()
+	versionRepo := repository.NewMockFileVersionRepository()
+	fileHistory := service.NewFileHistoryService(fileRepo, versionRepo, zerolog.Nop())
+	handler := NewFileHistoryHandler(fileHistory, zerolog.Nop())
+
+	router := gin.New()
+	files := router.Group("/api/files")
+	{
+		files.GET("/:id/versions", handler.ListVersions)
+		files.GET("/:id/versions/:version", handler.GetVersion)
+		files.POST("/:id/versions/:version/restore", handler.RestoreVersion)
+	}
+
+	return router, fileRepo, fileHistory
+}
+
+// writeVersionedFile saves a file and records the write in history.
+func writeVersionedFile(t *testing.T, fileRepo *repository.MockFileRepository, fileHistory *service.FileHistoryService, projectID uuid.UUID, path, content string) *model.File {
+	ctx := context.Background()
+	file, err := fileRepo.SaveFile(ctx, projectID, path, "html", content)
+	require.NoError(t, err)
+	_, err = fileHistory.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+	require.NoError(t, err)
+	return file
+}
+
+func TestFileHistoryHandler_ListVersions(t *testing.T) {
+	t.Run("lists versions newest first", func(t *testing.T) {
+		router, fileRepo, fileHistory := setupFileHistoryTestRouter()
+		projectID := uuid.New()
+		writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "one")
+		file := writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "two")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/"+file.ID.String()+"/versions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ListFileVersionsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "index.html", response.Path)
+		require.Len(t, response.Versions, 2)
+		assert.Equal(t, 2, response.Versions[0].Version)
+		assert.Equal(t, 1, response.Versions[1].Version)
+	})
+
+	t.Run("returns 404 when file not found", func(t *testing.T) {
+		router, _, _ := setupFileHistoryTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/"+uuid.New().String()+"/versions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns 400 for invalid file ID", func(t *testing.T) {
+		router, _, _ := setupFileHistoryTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/not-a-uuid/versions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestFileHistoryHandler_GetVersion(t *testing.T) {
+	t.Run("returns version content", func(t *testing.T) {
+		router, fileRepo, fileHistory := setupFileHistoryTestRouter()
+		projectID := uuid.New()
+		writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "first draft")
+		file := writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "second draft")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/"+file.ID.String()+"/versions/1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var version model.FileVersion
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+		assert.Equal(t, 1, version.Version)
+		assert.Equal(t, "first draft", version.Content)
+	})
+
+	t.Run("returns 404 for unknown version", func(t *testing.T) {
+		router, fileRepo, fileHistory := setupFileHistoryTestRouter()
+		file := writeVersionedFile(t, fileRepo, fileHistory, uuid.New(), "index.html", "content")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/"+file.ID.String()+"/versions/9", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns 400 for invalid version", func(t *testing.T) {
+		router, _, _ := setupFileHistoryTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/files/"+uuid.New().String()+"/versions/zero", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestFileHistoryHandler_RestoreVersion(t *testing.T) {
+	router, fileRepo, fileHistory := setupFileHistoryTestRouter()
+	projectID := uuid.New()
+	writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "working page")
+	file := writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "broken page")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/files/"+file.ID.String()+"/versions/1/restore", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response model.RestoreFileVersionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "working page", response.File.Content)
+	assert.Equal(t, 3, response.Version.Version)
+	assert.Equal(t, model.FileVersionSourceRestore, response.Version.Source)
+
+	current, err := fileRepo.GetFile(context.Background(), file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "working page", current.Content)
+}
+
+func TestFileHistoryHandler_RestoreVersion_PublishesFilesUpdated(t *testing.T) {
+	fileRepo := repository.NewMockFileRepository()
+	fileHistory := service.NewFileHistoryService(fileRepo, repository.NewMockFileVersionRepository(), zerolog.Nop())
+	events := &recordingFileEvents{}
+	handler := NewFileHistoryHandler(fileHistory, zerolog.Nop())
+	handler.SetEvents(events)
+	router := gin.New()
+	router.POST("/api/files/:id/versions/:version/restore", handler.RestoreVersion)
+
+	projectID := uuid.New()
+	writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "working page")
+	file := writeVersionedFile(t, fileRepo, fileHistory, projectID, "index.html", "broken page")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/files/"+file.ID.String()+"/versions/1/restore", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"files_updated:index.html"}, events.events)
+}
+
+func TestFileHistoryHandler_RestoreDeletedFile(t *testing.T) {
+	fileRepo := repository.NewMockFileRepository()
+	versionRepo := repository.NewMockFileVersionRepository()
+	fileHistory := service.NewFileHistoryService(fileRepo, versionRepo, zerolog.Nop())
+	events := &recordingFileEvents{}
+	handler := NewFileHistoryHandler(fileHistory, zerolog.Nop())
+	handler.SetEvents(events)
+	router := gin.New()
+	router.GET("/api/projects/:id/files/deleted", handler.ListDeletedFiles)
+	router.POST("/api/projects/:id/files/deleted/restore", handler.RestoreDeletedFile)
+
+	projectID := uuid.New()
+	writeVersionedFile(t, fileRepo, fileHistory, projectID, "about.html", "first draft")
+	file := writeVersionedFile(t, fileRepo, fileHistory, projectID, "about.html", "about us")
+	require.NoError(t, fileRepo.DeleteFile(context.Background(), file.ID))
+	versionRepo.DetachFile(file.ID)
+
+	restore := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/projects/"+projectID.String()+"/files/deleted/restore", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("lists deleted files", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.String()+"/files/deleted", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.ListDeletedFilesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Files, 1)
+		assert.Equal(t, "about.html", response.Files[0].Path)
+		assert.Equal(t, 2, response.Files[0].Version)
+	})
+
+	t.Run("returns 404 for a path with no deleted file", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, restore(`{"path": "contact.html"}`).Code)
+	})
+
+	t.Run("returns 400 without a path", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, restore(`{}`).Code)
+	})
+
+	t.Run("restores the latest version", func(t *testing.T) {
+		w := restore(`{"path": "about.html"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.RestoreFileVersionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "about us", response.File.Content)
+		assert.Equal(t, 3, response.Version.Version)
+		assert.Equal(t, []string{"files_updated:about.html"}, events.events)
+	})
+
+	t.Run("returns 409 once the path is taken", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, restore(`{"path": "about.html"}`).Code)
+	})
+}
@@ -7,9 +7,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
@@ -314,6 +316,139 @@ func TestFileHandler_GetFile(t *testing.T) {
 	})
 }
 
+// recordingFileEvents records published file events as "type:path" entries.
+type recordingFileEvents struct {
+	events []string
+}
+
+func (r *recordingFileEvents) PublishFilesUpdated(projectID uuid.UUID, paths []string) {
+	for _, p := range paths {
+		r.events = append(r.events, "files_updated:"+p)
+	}
+}
+
+func (r *recordingFileEvents) PublishFilesDeleted(projectID uuid.UUID, paths []string) {
+	for _, p := range paths {
+		r.events = append(r.events, "files_deleted:"+p)
+	}
+}
+
+func TestFileHandler_DeleteFile(t *testing.T) {
+	t.Run("deletes file and publishes files_deleted", func(t *testing.T) {
+		// Arrange
+		projectRepo := repository.NewMockProjectRepository()
+		fileRepo := repository.NewMockFileRepository()
+		events := &recordingFileEvents{}
+
+		project, _ := projectRepo.Create(nil, "Test Project")
+		file, _ := fileRepo.SaveFile(nil, project.ID, "index-old.html", "html", "<html></html>")
+
+		handler := NewFileHandler(fileRepo, projectRepo, nil)
+		handler.SetEvents(events)
+		router := gin.New()
+		router.DELETE("/api/files/:id", handler.DeleteFile)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/files/"+file.ID.String(), nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		_, err := fileRepo.GetFile(nil, file.ID)
+		assert.Equal(t, repository.ErrNotFound, err)
+		assert.Equal(t, []string{"files_deleted:index-old.html"}, events.events)
+	})
+
+	t.Run("returns 404 for non-existent file", func(t *testing.T) {
+		// Arrange
+		handler := NewFileHandler(repository.NewMockFileRepository(), repository.NewMockProjectRepository(), nil)
+		router := gin.New()
+		router.DELETE("/api/files/:id", handler.DeleteFile)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/files/"+uuid.New().String(), nil)
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestFileHandler_MoveFile(t *testing.T) {
+	setup := func() (*repository.MockFileRepository, *recordingFileEvents, *gin.Engine, *model.File) {
+		projectRepo := repository.NewMockProjectRepository()
+		fileRepo := repository.NewMockFileRepository()
+		metadataRepo := repository.NewMockFileMetadataRepository()
+		events := &recordingFileEvents{}
+
+		project, _ := projectRepo.Create(nil, "Test Project")
+		file, _ := fileRepo.SaveFile(nil, project.ID, "about.html", "html", "<p>About</p>")
+		_, _ = fileRepo.SaveFile(nil, project.ID, "contact.html", "html", "<p>Contact</p>")
+		_, _ = metadataRepo.Upsert(nil, file.ID, "About page", "", "User Interface")
+
+		handler := NewFileHandler(fileRepo, projectRepo, metadataRepo)
+		handler.SetEvents(events)
+		router := gin.New()
+		router.PATCH("/api/files/:id", handler.MoveFile)
+		return fileRepo, events, router, file
+	}
+
+	patch := func(router *gin.Engine, id uuid.UUID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/files/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("moves file and keeps its metadata", func(t *testing.T) {
+		fileRepo, events, router, file := setup()
+
+		w := patch(router, file.ID, `{"path": "pages/about.html"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response model.GetFileResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, file.ID, response.ID)
+		assert.Equal(t, "pages/about.html", response.Path)
+		assert.Equal(t, "About page", response.ShortDescription)
+
+		_, err := fileRepo.GetFileByPath(nil, file.ProjectID, "about.html")
+		assert.Equal(t, repository.ErrNotFound, err)
+		assert.Equal(t, []string{"files_deleted:about.html", "files_updated:pages/about.html"}, events.events)
+	})
+
+	t.Run("returns 409 when the path is taken", func(t *testing.T) {
+		_, events, router, file := setup()
+
+		w := patch(router, file.ID, `{"path": "contact.html"}`)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Empty(t, events.events)
+	})
+
+	t.Run("returns 400 for invalid paths", func(t *testing.T) {
+		_, _, router, file := setup()
+
+		for _, body := range []string{`{}`, `{"path": "../secret.html"}`, `{"path": "/abs.html"}`, `not json`} {
+			w := patch(router, file.ID, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+
+	t.Run("returns 404 for non-existent file", func(t *testing.T) {
+		_, _, router, _ := setup()
+
+		w := patch(router, uuid.New(), `{"path": "x.html"}`)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
 func TestSanitizeFilename(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+// GraphHandler handles project dependency graph HTTP endpoints.
+type GraphHandler struct {
+	graph  *service.GraphService
+	logger zerolog.Logger
+}
+
+// NewGraphHandler creates a new GraphHandler.
+func NewGraphHandler(graph *service.GraphService, logger zerolog.Logger) *GraphHandler {
+	return &GraphHandler{
+		graph:  graph,
+		logger: logger,
+	}
+}
+
+// GetGraph returns the dependency graph of a project as JSON, or with ?format=dot or
+// ?format=mermaid as a Graphviz or Mermaid file download.
+// GET /api/projects/:id/graph
+func (h *GraphHandler) GetGraph(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	format := model.GraphFormat(c.DefaultQuery("format", string(model.GraphFormatJSON)))
+	if format != model.GraphFormatJSON && format != model.GraphFormatDOT && format != model.GraphFormatMermaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, dot or mermaid"})
+		return
+	}
+
+	graph, err := h.graph.Build(c.Request.Context(), projectID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("projectId", projectIDStr).Msg("failed to build project graph")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build project graph"})
+		return
+	}
+
+	var content, contentType, filename string
+	switch format {
+	case model.GraphFormatJSON:
+		c.JSON(http.StatusOK, graph)
+		return
+	case model.GraphFormatDOT:
+		content, contentType, filename = service.FormatGraphDOT(graph), "text/vnd.graphviz; charset=utf-8", "graph.dot"
+	case model.GraphFormatMermaid:
+		content, contentType, filename = service.FormatGraphMermaid(graph), "text/plain; charset=utf-8", "graph.mmd"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%s-%s"`, projectID.String()[:8], filename))
+	c.Data(http.StatusOK, contentType, []byte(content))
+}
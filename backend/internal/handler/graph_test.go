@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+func setupGraphTestRouter() (*gin.Engine, *repository.MockFileRepository) {
+	fileRepo := repository.NewMockFileRepository()
+	checker := service.NewCompletenessChecker(fileRepo, zerolog.Nop())
+	graph := service.NewGraphService(checker, fileRepo, repository.NewMockFileMetadataRepository(), zerolog.Nop())
+	handler := NewGraphHandler(graph, zerolog.Nop())
+
+	router := gin.New()
+	router.GET("/api/projects/:id/graph", handler.GetGraph)
+
+	return router, fileRepo
+}
+
+func TestGraphHandler_GetGraph(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rejects an invalid project id", func(t *testing.T) {
+		router, _ := setupGraphTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/not-a-uuid/graph", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("rejects an unknown format", func(t *testing.T) {
+		router, _ := setupGraphTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+uuid.New().String()+"/graph?format=svg", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("returns the graph as JSON", func(t *testing.T) {
+		router, fileRepo := setupGraphTestRouter()
+		projectID := uuid.New()
+		_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<script src="app.js"></script>`)
+		_, _ = fileRepo.SaveFile(ctx, projectID, "app.js", "javascript", "init();")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.String()+"/graph", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var graph model.ProjectGraph
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+		assert.Equal(t, projectID, graph.ProjectID)
+		assert.Len(t, graph.Nodes, 2)
+		assert.Equal(t, []model.GraphEdge{{From: "index.html", To: "app.js", Type: "script", Line: 1}}, graph.Edges)
+		assert.Empty(t, graph.Orphans)
+		assert.Empty(t, graph.Cycles)
+	})
+
+	t.Run("exports Graphviz DOT", func(t *testing.T) {
+		router, fileRepo := setupGraphTestRouter()
+		projectID := uuid.New()
+		_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<script src="app.js"></script>`)
+		_, _ = fileRepo.SaveFile(ctx, projectID, "app.js", "javascript", "init();")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.String()+"/graph?format=dot", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/vnd.graphviz; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".dot")
+		assert.Contains(t, w.Body.String(), `"index.html" -> "app.js" [label="script"];`)
+	})
+
+	t.Run("exports Mermaid", func(t *testing.T) {
+		router, _ := setupGraphTestRouter()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+uuid.New().String()+"/graph?format=mermaid", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".mmd")
+		assert.Equal(t, "flowchart LR\n", w.Body.String())
+	})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// previewIndex is the file served for a directory.
+const previewIndex = "index.html"
+
+// previewPolicy is the Content-Security-Policy of preview responses, without frame-ancestors.
+// The sandbox gives the page an opaque origin, so generated scripts run but can't reach the
+// app's cookies, storage or API; resources load from the project itself or inline.
+const previewPolicy = "sandbox allow-scripts allow-forms allow-modals allow-popups; " +
+	"default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: blob:; " +
+	"font-src 'self' data:; " +
+	"media-src 'self' data: blob:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'none'"
+
+// PreviewHandler serves a project's files as a static site, so the app can show what the
+// user is building in an iframe. Connected clients get preview_reload when files change.
+type PreviewHandler struct {
+	fileRepo       repository.FileRepository
+	frameAncestors string
+	logger         zerolog.Logger
+}
+
+// NewPreviewHandler creates a new PreviewHandler. allowOrigins is the comma-separated list of
+// origins that may embed the preview (the CORS origins), or "*" for any.
+func NewPreviewHandler(fileRepo repository.FileRepository, allowOrigins string, logger zerolog.Logger) *PreviewHandler {
+	return &PreviewHandler{
+		fileRepo:       fileRepo,
+		frameAncestors: frameAncestors(allowOrigins),
+		logger:         logger,
+	}
+}
+
+// ServeFile serves a project file. A directory serves its index.html, and a path without an
+// extension that matches no file falls back to the root index.html for client-side routing.
+// GET /preview/:projectId/*path
+func (h *PreviewHandler) ServeFile(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	// Clean against the root so the path can't climb out of the project
+	requested := c.Param("path")
+	filePath := strings.TrimPrefix(path.Clean("/"+requested), "/")
+	isDir := filePath == "" || strings.HasSuffix(requested, "/")
+
+	candidates := []string{filePath}
+	if isDir {
+		candidates = []string{path.Join(filePath, previewIndex)}
+	} else if path.Ext(filePath) == "" {
+		candidates = append(candidates, path.Join(filePath, previewIndex), previewIndex)
+	}
+
+	for _, candidate := range candidates {
+		file, err := h.fileRepo.GetFileByPath(c.Request.Context(), projectID, candidate)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			h.logger.Error().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("path", candidate).
+				Msg("failed to get preview file")
+			c.String(http.StatusInternalServerError, "failed to get file")
+			return
+		}
+
+		// Relative links in a directory's index resolve against the directory
+		if candidate == path.Join(filePath, previewIndex) && !isDir {
+			c.Redirect(http.StatusMovedPermanently, c.Request.URL.Path+"/")
+			return
+		}
+		h.serve(c, file)
+		return
+	}
+
+	h.setHeaders(c)
+	c.String(http.StatusNotFound, "file not found")
+}
+
+// serve writes a file with the preview headers.
+func (h *PreviewHandler) serve(c *gin.Context, file *model.File) {
+	h.setHeaders(c)
+	c.Data(http.StatusOK, getContentType(file.Path), []byte(file.Content))
+}
+
+// setHeaders sets the security and caching headers of a preview response.
+func (h *PreviewHandler) setHeaders(c *gin.Context) {
+	c.Header("Content-Security-Policy", previewPolicy+"; frame-ancestors "+h.frameAncestors)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-store") // Files change while the agent works
+}
+
+// frameAncestors converts a comma-separated origin list to a frame-ancestors source list.
+func frameAncestors(allowOrigins string) string {
+	var sources []string
+	for _, origin := range strings.Split(allowOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			sources = append(sources, origin)
+		}
+	}
+	if len(sources) == 0 {
+		return "'none'"
+	}
+	return strings.Join(sources, " ")
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// setupPreviewTestRouter serves a project with a small static site.
+func setupPreviewTestRouter(t *testing.T, allowOrigins string) (*gin.Engine, uuid.UUID) {
+	t.Helper()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	files := map[string]string{
+		"index.html":      "<h1>Home</h1>",
+		"css/style.css":   "h1 { color: red; }",
+		"js/app.js":       "console.log('hi')",
+		"docs/index.html": "<h1>Docs</h1>",
+	}
+	for path, content := range files {
+		_, err := fileRepo.SaveFile(context.Background(), projectID, path, "", content)
+		require.NoError(t, err)
+	}
+
+	handler := NewPreviewHandler(fileRepo, allowOrigins, zerolog.Nop())
+	router := gin.New()
+	router.GET("/preview/:projectId/*path", handler.ServeFile)
+	return router, projectID
+}
+
+func TestPreviewHandler_ServeFile(t *testing.T) {
+	router, projectID := setupPreviewTestRouter(t, "http://localhost:3000")
+	base := "/preview/" + projectID.String()
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		body        string
+		contentType string
+		location    string
+	}{
+		{"root serves index.html", "/", http.StatusOK, "<h1>Home</h1>", "text/html; charset=utf-8", ""},
+		{"stylesheet", "/css/style.css", http.StatusOK, "h1 { color: red; }", "text/css; charset=utf-8", ""},
+		{"script", "/js/app.js", http.StatusOK, "console.log('hi')", "text/javascript; charset=utf-8", ""},
+		{"directory index", "/docs/", http.StatusOK, "<h1>Docs</h1>", "text/html; charset=utf-8", ""},
+		{"directory without slash redirects", "/docs", http.StatusMovedPermanently, "", "", base + "/docs/"},
+		{"client-side route falls back to index.html", "/checkout/step-2", http.StatusOK, "<h1>Home</h1>", "text/html; charset=utf-8", ""},
+		{"missing asset", "/img/logo.png", http.StatusNotFound, "file not found", "", ""},
+		{"path can't leave the project", "/../../etc/passwd", http.StatusOK, "<h1>Home</h1>", "text/html; charset=utf-8", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, base+tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			if tt.location != "" {
+				assert.Equal(t, tt.location, w.Header().Get("Location"))
+				return
+			}
+			assert.Equal(t, tt.body, w.Body.String())
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestPreviewHandler_SecurityHeaders(t *testing.T) {
+	router, projectID := setupPreviewTestRouter(t, "http://localhost:3000, https://app.example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/preview/"+projectID.String()+"/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	csp := w.Header().Get("Content-Security-Policy")
+	assert.Contains(t, csp, "sandbox allow-scripts")
+	assert.NotContains(t, csp, "allow-same-origin", "the preview must not share the app's origin")
+	assert.Contains(t, csp, "frame-ancestors http://localhost:3000 https://app.example.com")
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestPreviewHandler_UnknownProject(t *testing.T) {
+	router, _ := setupPreviewTestRouter(t, "*")
+
+	req := httptest.NewRequest(http.MethodGet, "/preview/"+uuid.New().String()+"/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/preview/not-a-uuid/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+// SummaryHandler handles conversation summary HTTP endpoints.
+type SummaryHandler struct {
+	summaries *service.SummaryService
+	logger    zerolog.Logger
+}
+
+// NewSummaryHandler creates a new SummaryHandler.
+func NewSummaryHandler(summaries *service.SummaryService, logger zerolog.Logger) *SummaryHandler {
+	return &SummaryHandler{
+		summaries: summaries,
+		logger:    logger,
+	}
+}
+
+// ListSummaries returns the conversation summaries for a project, oldest first.
+// The last summary is the current recap of the whole conversation.
+// GET /api/projects/:id/summaries
+func (h *SummaryHandler) ListSummaries(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	summaries, err := h.summaries.ListSummaries(c.Request.Context(), projectID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("projectId", projectIDStr).Msg("failed to list summaries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list summaries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.ListSummariesResponse{Summaries: summaries})
+}
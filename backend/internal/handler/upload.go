@@ -51,6 +51,8 @@ type UploadHandler struct {
 	fileMetadataRepo repository.FileMetadataRepository
 	fileSourceRepo   repository.FileSourceRepository
 	claudeVision     service.ClaudeVision
+	fileHistory      *service.FileHistoryService
+	events           FileEventPublisher
 	logger           zerolog.Logger
 }
 
@@ -73,6 +75,17 @@ func NewUploadHandler(
 	}
 }
 
+// SetFileHistory sets the file history service used to version uploaded files.
+func (h *UploadHandler) SetFileHistory(fileHistory *service.FileHistoryService) {
+	h.fileHistory = fileHistory
+}
+
+// SetEvents sets the publisher used to notify connected clients of uploaded files.
+// This is optional - if not set, clients see the file on their next refresh.
+func (h *UploadHandler) SetEvents(events FileEventPublisher) {
+	h.events = events
+}
+
 // Upload handles multipart file uploads.
 // POST /api/projects/:id/upload
 func (h *UploadHandler) Upload(c *gin.Context) {
@@ -149,7 +162,8 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		Msg("processing image upload")
 
 	// Call Claude Vision to analyze the image
-	visionResponse, err := h.claudeVision.AnalyzeImage(c.Request.Context(), imageData, mimeType, VisionPrompt)
+	visionCtx := service.WithUsageScope(c.Request.Context(), projectID, nil, model.UsageSourceVision)
+	visionResponse, err := h.claudeVision.AnalyzeImage(visionCtx, imageData, mimeType, VisionPrompt)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to analyze image with Claude Vision")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to analyze image"})
@@ -179,6 +193,14 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	// Record the upload in file history
+	if h.fileHistory != nil {
+		if _, err := h.fileHistory.RecordWrite(c.Request.Context(), savedFile, model.FileVersionSourceUpload, nil); err != nil {
+			h.logger.Warn().Err(err).Msg("failed to record file version")
+			// Continue even if history recording fails
+		}
+	}
+
 	// Create a short description from the first line of markdown content
 	shortDesc := extractShortDescription(markdownContent)
 
@@ -196,6 +218,10 @@ func (h *UploadHandler) Upload(c *gin.Context) {
 		// Continue even if source record save fails
 	}
 
+	if h.events != nil {
+		h.events.PublishFilesUpdated(projectID, []string{savedFile.Path})
+	}
+
 	h.logger.Info().
 		Str("projectId", projectID.String()).
 		Str("fileId", savedFile.ID.String()).
@@ -86,6 +86,38 @@ This is a screenshot of a web application.
 		assert.Contains(t, prompt, "FILENAME:")
 	})
 
+	t.Run("publishes files_updated for the converted file", func(t *testing.T) {
+		projectRepo := repository.NewMockProjectRepository()
+		mockVision := service.NewMockClaudeVision()
+		mockVision.SetDefaultResponse("FILENAME: menu-photo\n\n## Menu")
+		project, _ := projectRepo.Create(nil, "Test Project")
+
+		events := &recordingFileEvents{}
+		handler := NewUploadHandler(projectRepo, repository.NewMockFileRepository(), repository.NewMockFileMetadataRepository(), repository.NewMockFileSourceRepository(), mockVision, logger)
+		handler.SetEvents(events)
+		router := gin.New()
+		router.POST("/api/projects/:id/upload", handler.Upload)
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreatePart(map[string][]string{
+			"Content-Disposition": {`form-data; name="file"; filename="menu.png"`},
+			"Content-Type":        {"image/png"},
+		})
+		part.Write([]byte("fake PNG data"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/projects/"+project.ID.String()+"/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response model.UploadResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []string{"files_updated:" + response.File.Path}, events.events)
+	})
+
 	t.Run("falls back to default filename when vision response has no FILENAME prefix", func(t *testing.T) {
 		// Arrange
 		projectRepo := repository.NewMockProjectRepository()
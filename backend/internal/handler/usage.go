@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 365
+)
+
+// UsageHandler handles token usage and cost HTTP endpoints.
+type UsageHandler struct {
+	usage  *service.UsageService
+	logger zerolog.Logger
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(usage *service.UsageService, logger zerolog.Logger) *UsageHandler {
+	return &UsageHandler{
+		usage:  usage,
+		logger: logger,
+	}
+}
+
+// GetProjectUsage returns a project's token usage and cost, totalled per agent, per source, and per day.
+// The optional days query parameter sets the window (default 30, max 365).
+// GET /api/projects/:id/usage
+func (h *UsageHandler) GetProjectUsage(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	days := defaultUsageDays
+	if daysStr := c.Query("days"); daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > maxUsageDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+	}
+
+	usage, err := h.usage.GetProjectUsage(c.Request.Context(), projectID, days)
+	if err != nil {
+		h.logger.Error().Err(err).Str("projectId", projectIDStr).Msg("failed to get project usage")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get project usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/service"
+)
+
+func setupUsageTestRouter(t *testing.T) (*gin.Engine, uuid.UUID) {
+	usage := service.NewUsageService(nil, repository.NewMockUsageRepository(), zerolog.Nop())
+
+	projectID := uuid.New()
+	agentType := "developer"
+	ctx := service.WithUsageScope(context.Background(), projectID, &agentType, model.UsageSourceChat)
+	usage.RecordUsage(ctx, "claude-sonnet-4-20250514", model.TokenUsage{InputTokens: 1000, OutputTokens: 100})
+
+	handler := NewUsageHandler(usage, zerolog.Nop())
+	router := gin.New()
+	router.GET("/api/projects/:id/usage", handler.GetProjectUsage)
+
+	return router, projectID
+}
+
+func TestUsageHandler_GetProjectUsage(t *testing.T) {
+	t.Run("returns totals and rollups", func(t *testing.T) {
+		router, projectID := setupUsageTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.String()+"/usage", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ProjectUsageResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, projectID, response.ProjectID)
+		assert.Equal(t, 1, response.Total.Calls)
+		assert.Equal(t, 1000, response.Total.InputTokens)
+		assert.InDelta(t, 0.0045, response.Total.CostUSD, 1e-9)
+		require.Len(t, response.ByAgent, 1)
+		assert.Equal(t, "developer", response.ByAgent[0].AgentType)
+		assert.Len(t, response.Daily, 1)
+	})
+
+	t.Run("rejects invalid days", func(t *testing.T) {
+		router, projectID := setupUsageTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID.String()+"/usage?days=0", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("rejects invalid project id", func(t *testing.T) {
+		router, _ := setupUsageTestRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/projects/not-a-uuid/usage", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,7 +29,11 @@ type WebSocketMessage struct {
 	Type      string    `json:"type"`
 	Content   string    `json:"content,omitempty"`
 	MessageID string    `json:"messageId,omitempty"`
+	Seq       int       `json:"seq,omitempty"`     // Sequence number of a stream event
+	LastSeq   int       `json:"lastSeq,omitempty"` // Last sequence number seen by the client (resume)
 	Timestamp time.Time `json:"timestamp"`
+
+	ClarificationID string `json:"clarificationId,omitempty"` // Question being answered (clarification_response)
 }
 
 // MessageCompleteResponse is sent when a message stream is complete.
@@ -39,6 +44,10 @@ type MessageCompleteResponse struct {
 	CodeBlocks         []model.CodeBlock         `json:"codeBlocks"`
 	AgentType          *string                   `json:"agentType,omitempty"`
 	CompletenessReport *model.CompletenessReport `json:"completenessReport,omitempty"`
+	Changes            *model.ChangeSummary      `json:"changes,omitempty"`
+	ToolLimitReached   bool                      `json:"toolLimitReached,omitempty"` // The response stopped at the tool iteration limit and saved no file changes
+	RepairIterations   int                       `json:"repairIterations,omitempty"` // Repair turns run before the completeness report
+	Seq                int                       `json:"seq"`
 	Timestamp          time.Time                 `json:"timestamp"`
 }
 
@@ -48,19 +57,89 @@ type ErrorResponse struct {
 	Error     string    `json:"error"`
 	Code      string    `json:"code,omitempty"`
 	MessageID string    `json:"messageId,omitempty"`
+	Seq       int       `json:"seq,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// FilesUpdatedResponse is sent when files are created or updated via tool use.
+// FilesUpdatedResponse is sent when files are created or updated (files_updated)
+// or deleted (files_deleted), via tool use or the REST API. A move sends both.
 type FilesUpdatedResponse struct {
 	Type      string    `json:"type"`
 	FilePaths []string  `json:"filePaths"`
+	MessageID string    `json:"messageId,omitempty"`
+	Seq       int       `json:"seq,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PreviewReloadResponse is sent to all of a project's connections when files it serves at
+// /preview change (preview_reload), so open previews reload.
+type PreviewReloadResponse struct {
+	Type      string    `json:"type"`
+	FilePaths []string  `json:"filePaths"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PRDUpdatedResponse is sent when the product manager agent changes a PRD via tool use (prd_updated).
+type PRDUpdatedResponse struct {
+	Type      string    `json:"type"`
+	PRDID     string    `json:"prdId"`
+	MessageID string    `json:"messageId,omitempty"`
+	Seq       int       `json:"seq,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ClarificationRequestResponse is sent when the assistant asks the user a question and waits
+// for a clarification_response (clarification_request). It ends the message's stream; the
+// answer continues the response in a new one. Sent again on connect while unanswered.
+type ClarificationRequestResponse struct {
+	Type            string    `json:"type"`
+	ClarificationID string    `json:"clarificationId"`
+	Question        string    `json:"question"`
+	Options         []string  `json:"options,omitempty"`
+	MessageID       string    `json:"messageId,omitempty"`
+	Seq             int       `json:"seq,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// RepairIterationResponse is sent when a repair turn, fixing the critical completeness issues
+// a response left, starts and when it ends (repair_iteration).
+type RepairIterationResponse struct {
+	Type               string                    `json:"type"`
+	MessageID          string                    `json:"messageId"`
+	Iteration          int                       `json:"iteration"`
+	MaxIterations      int                       `json:"maxIterations"`
+	Status             string                    `json:"status"` // "started", "finished" or "failed"
+	Issues             []model.CompletenessIssue `json:"issues"` // Critical issues the iteration works on
+	FilePaths          []string                  `json:"filePaths,omitempty"`
+	CompletenessReport *model.CompletenessReport `json:"completenessReport,omitempty"` // Report after the iteration
+	Seq                int                       `json:"seq"`
+	Timestamp          time.Time                 `json:"timestamp"`
+}
+
+// MessageCancelledResponse is sent when the user cancels a response mid-generation.
+type MessageCancelledResponse struct {
+	Type           string               `json:"type"`
+	MessageID      string               `json:"messageId"`
+	PartialContent string               `json:"partialContent"`
+	Changes        *model.ChangeSummary `json:"changes,omitempty"` // Files written before the cancel
+	Seq            int                  `json:"seq"`
+	Timestamp      time.Time            `json:"timestamp"`
+}
+
+// StreamActiveResponse is sent on connect when the project has a response still being generated,
+// so a refreshed client can resume it.
+type StreamActiveResponse struct {
+	Type      string    `json:"type"`
+	MessageID string    `json:"messageId"`
+	LastSeq   int       `json:"lastSeq"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
 // WebSocketHandler handles WebSocket connections.
 type WebSocketHandler struct {
 	chatService *service.ChatService
+	streams     *chatStreamRegistry
+	connections *projectConnections
 	logger      zerolog.Logger
 }
 
@@ -68,6 +147,8 @@ type WebSocketHandler struct {
 func NewWebSocketHandler(chatService *service.ChatService, logger zerolog.Logger) *WebSocketHandler {
 	return &WebSocketHandler{
 		chatService: chatService,
+		streams:     newChatStreamRegistry(streamRetention),
+		connections: newProjectConnections(),
 		logger:      logger,
 	}
 }
@@ -98,6 +179,18 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 	// Create a mutex for thread-safe writes to the WebSocket
 	var writeMu sync.Mutex
 
+	// Streams outlive the connection; detach from them when it closes
+	sub := newConnSubscriber(conn, &writeMu)
+	defer sub.close()
+	defer h.streams.unsubscribeAll(sub)
+
+	// Receive file events from outside chat turns (REST edits)
+	h.connections.add(projectID, sub)
+	defer h.connections.remove(projectID, sub)
+
+	h.sendStreamActive(conn, &writeMu, projectID)
+	h.sendPendingClarification(conn, &writeMu, projectID)
+
 	for {
 		var msg WebSocketMessage
 		err := conn.ReadJSON(&msg)
@@ -118,7 +211,13 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		case "ping":
 			h.sendPong(conn, &writeMu)
 		case "chat_message":
-			h.handleChatMessage(c.Request.Context(), conn, &writeMu, projectID, msg)
+			h.handleChatMessage(conn, &writeMu, sub, projectID, msg)
+		case "resume":
+			h.handleResume(conn, &writeMu, sub, projectID, msg)
+		case "cancel":
+			h.handleCancel(conn, &writeMu, projectID, msg)
+		case "clarification_response":
+			h.handleClarificationResponse(conn, &writeMu, sub, projectID, msg)
 		default:
 			h.sendError(conn, &writeMu, "unknown message type", "UNKNOWN_TYPE", "")
 		}
@@ -138,77 +237,329 @@ func (h *WebSocketHandler) sendPong(conn *websocket.Conn, mu *sync.Mutex) {
 	conn.WriteJSON(response)
 }
 
-func (h *WebSocketHandler) handleChatMessage(ctx context.Context, conn *websocket.Conn, mu *sync.Mutex, projectID uuid.UUID, msg WebSocketMessage) {
-	messageID := uuid.New().String()
+// handleChatMessage starts generating a response in the background and subscribes the
+// connection to its stream. Generation continues if the connection drops.
+func (h *WebSocketHandler) handleChatMessage(conn *websocket.Conn, mu *sync.Mutex, sub *streamSubscriber, projectID uuid.UUID, msg WebSocketMessage) {
+	// The chat service bounds the time to get the response; this context is for cancelling it
+	chatCtx, cancel := context.WithCancel(context.Background())
 
-	// Send message_start
-	h.sendMessageStart(conn, mu, messageID)
+	stream, ok := h.streams.start(projectID, cancel)
+	if !ok {
+		cancel()
+		h.sendError(conn, mu, "A response is already being generated for this project.", "STREAM_ACTIVE", "")
+		return
+	}
 
-	// Process message through chat service with streaming
-	onChunk := func(chunk string) {
-		mu.Lock()
-		defer mu.Unlock()
+	stream.subscribe(sub, 0)
+
+	go h.runChatStream(chatCtx, stream, func(ctx context.Context, callbacks service.ChatCallbacks) (*service.ChatResult, error) {
+		return h.chatService.ProcessMessageWithCallbacks(ctx, projectID, msg.Content, callbacks)
+	})
+}
+
+// handleClarificationResponse answers the question the project's paused response is
+// waiting on, and continues the response in a new stream.
+func (h *WebSocketHandler) handleClarificationResponse(conn *websocket.Conn, mu *sync.Mutex, sub *streamSubscriber, projectID uuid.UUID, msg WebSocketMessage) {
+	clarificationID, err := uuid.Parse(msg.ClarificationID)
+	if err != nil {
+		h.sendError(conn, mu, "invalid clarificationId", "INVALID_CLARIFICATION", "")
+		return
+	}
+	if strings.TrimSpace(msg.Content) == "" {
+		h.sendError(conn, mu, "an answer is required", "INVALID_CLARIFICATION", "")
+		return
+	}
+	if pending, ok := h.chatService.PendingClarification(projectID); !ok || pending.ID != clarificationID {
+		h.sendError(conn, mu, "The question is no longer waiting for an answer. Please send it as a new message.", "CLARIFICATION_NOT_FOUND", "")
+		return
+	}
+
+	chatCtx, cancel := context.WithCancel(context.Background())
+
+	stream, ok := h.streams.start(projectID, cancel)
+	if !ok {
+		cancel()
+		h.sendError(conn, mu, "A response is already being generated for this project.", "STREAM_ACTIVE", "")
+		return
+	}
+
+	stream.subscribe(sub, 0)
+
+	go h.runChatStream(chatCtx, stream, func(ctx context.Context, callbacks service.ChatCallbacks) (*service.ChatResult, error) {
+		return h.chatService.AnswerClarification(ctx, projectID, clarificationID, msg.Content, callbacks)
+	})
+}
+
+// chatProcessor generates a response with the given callbacks: a new message or an answer.
+type chatProcessor func(ctx context.Context, callbacks service.ChatCallbacks) (*service.ChatResult, error)
+
+// runChatStream processes a message detached from any connection, publishing every
+// event to the stream so subscribers (including reconnecting ones) receive it.
+func (h *WebSocketHandler) runChatStream(ctx context.Context, stream *chatStream, process chatProcessor) {
+	defer h.streams.finish(stream)
+	defer stream.cancel()
+
+	messageID := stream.messageID
 
-		chunkMsg := WebSocketMessage{
-			Type:      "message_chunk",
+	// Send message_start
+	stream.publish(func(seq int) interface{} {
+		return WebSocketMessage{
+			Type:      "message_start",
 			MessageID: messageID,
-			Content:   chunk,
+			Seq:       seq,
 			Timestamp: time.Now().UTC(),
 		}
-		if err := conn.WriteJSON(chunkMsg); err != nil {
-			h.logger.Error().Err(err).Msg("failed to write chunk to WebSocket")
-		}
+	})
+
+	// Process message through chat service with streaming
+	onChunk := func(chunk string) {
+		stream.publish(func(seq int) interface{} {
+			return WebSocketMessage{
+				Type:      "message_chunk",
+				MessageID: messageID,
+				Content:   chunk,
+				Seq:       seq,
+				Timestamp: time.Now().UTC(),
+			}
+		})
 	}
 
 	// Callback for file creation events - send immediately when files are created
 	onFileCreated := func(filePath string) {
-		h.sendFilesUpdated(conn, mu, []string{filePath})
+		stream.publish(func(seq int) interface{} {
+			return FilesUpdatedResponse{
+				Type:      "files_updated",
+				FilePaths: []string{filePath},
+				MessageID: messageID,
+				Seq:       seq,
+				Timestamp: time.Now().UTC(),
+			}
+		})
+		h.publishPreviewReload(stream.projectID, []string{filePath})
 	}
 
-	// Create a context with timeout for the Claude API call
-	// 180 seconds allows for longer responses (detailed code comments, multiple files)
-	chatCtx, cancel := context.WithTimeout(ctx, 180*time.Second)
-	defer cancel()
+	onFileDeleted := func(filePath string) {
+		stream.publish(func(seq int) interface{} {
+			return FilesUpdatedResponse{
+				Type:      "files_deleted",
+				FilePaths: []string{filePath},
+				MessageID: messageID,
+				Seq:       seq,
+				Timestamp: time.Now().UTC(),
+			}
+		})
+		h.publishPreviewReload(stream.projectID, []string{filePath})
+	}
+
+	onPRDUpdated := func(prdID uuid.UUID) {
+		stream.publish(func(seq int) interface{} {
+			return PRDUpdatedResponse{
+				Type:      "prd_updated",
+				PRDID:     prdID.String(),
+				MessageID: messageID,
+				Seq:       seq,
+				Timestamp: time.Now().UTC(),
+			}
+		})
+	}
+
+	// The question ends this stream; the answer continues the response in a new one
+	onClarification := func(clarification service.Clarification) {
+		stream.publish(func(seq int) interface{} {
+			return ClarificationRequestResponse{
+				Type:            "clarification_request",
+				ClarificationID: clarification.ID.String(),
+				Question:        clarification.Question,
+				Options:         clarification.Options,
+				MessageID:       messageID,
+				Seq:             seq,
+				Timestamp:       time.Now().UTC(),
+			}
+		})
+	}
 
-	result, err := h.chatService.ProcessMessage(chatCtx, projectID, msg.Content, onChunk, onFileCreated)
+	onRepairIteration := func(iteration service.RepairIteration) {
+		stream.publish(func(seq int) interface{} {
+			return RepairIterationResponse{
+				Type:               "repair_iteration",
+				MessageID:          messageID,
+				Iteration:          iteration.Iteration,
+				MaxIterations:      iteration.MaxIterations,
+				Status:             string(iteration.Status),
+				Issues:             iteration.Issues,
+				FilePaths:          iteration.Files,
+				CompletenessReport: iteration.Report,
+				Seq:                seq,
+				Timestamp:          time.Now().UTC(),
+			}
+		})
+	}
+
+	result, err := process(ctx, service.ChatCallbacks{
+		OnChunk:           onChunk,
+		OnFileCreated:     onFileCreated,
+		OnFileDeleted:     onFileDeleted,
+		OnPRDUpdated:      onPRDUpdated,
+		OnClarification:   onClarification,
+		OnRepairIteration: onRepairIteration,
+	})
 	if err != nil {
 		h.logger.Error().Err(err).
-			Str("projectId", projectID.String()).
+			Str("projectId", stream.projectID.String()).
 			Msg("failed to process message")
-		h.sendError(conn, mu, "Failed to generate response. Please try a simpler request.", "AI_ERROR", messageID)
+		code := service.ClaudeErrorCode(err)
+		stream.publish(func(seq int) interface{} {
+			return ErrorResponse{
+				Type:      "error",
+				Error:     chatErrorMessage(code),
+				Code:      code,
+				MessageID: messageID,
+				Seq:       seq,
+				Timestamp: time.Now().UTC(),
+			}
+		})
+		return
+	}
+
+	if result.Clarification != nil {
+		return
+	}
+
+	if result.Cancelled {
+		stream.publish(func(seq int) interface{} {
+			return MessageCancelledResponse{
+				Type:           "message_cancelled",
+				MessageID:      messageID,
+				PartialContent: result.Content,
+				Changes:        result.Changes,
+				Seq:            seq,
+				Timestamp:      time.Now().UTC(),
+			}
+		})
 		return
 	}
 
-	// Send message_complete with code blocks, agent type, and completeness report
-	h.sendMessageComplete(conn, mu, messageID, result.Content, result.CodeBlocks, result.AgentType, result.CompletenessReport)
+	// Send message_complete with code blocks, agent type, completeness report, and change summary
+	stream.publish(func(seq int) interface{} {
+		return MessageCompleteResponse{
+			Type:               "message_complete",
+			MessageID:          messageID,
+			FullContent:        result.Content,
+			CodeBlocks:         result.CodeBlocks,
+			AgentType:          result.AgentType,
+			CompletenessReport: result.CompletenessReport,
+			Changes:            result.Changes,
+			ToolLimitReached:   result.ToolLimitReached,
+			RepairIterations:   result.RepairIterations,
+			Seq:                seq,
+			Timestamp:          time.Now().UTC(),
+		}
+	})
+}
+
+// chatErrorMessage returns the user-facing message for a chat error code.
+func chatErrorMessage(code string) string {
+	switch code {
+	case service.ErrorCodeRateLimited:
+		return "Too many requests right now. Please wait a moment and try again."
+	case service.ErrorCodeOverloaded:
+		return "The AI service is overloaded. Please try again in a minute."
+	case service.ErrorCodeUpstreamDown:
+		return "The AI service is currently unavailable. Please try again shortly."
+	case service.ErrorCodeTimeout:
+		return "The response took too long. Please try again or split the request into smaller steps."
+	case service.ErrorCodeInvalidRequest:
+		return "The request could not be processed. Try a shorter message or start a new project."
+	case service.ErrorCodeAuth:
+		return "The AI service is misconfigured. Please contact support."
+	default:
+		return "Failed to generate response. Please try a simpler request."
+	}
+}
+
+// handleResume replays the events a reconnecting client missed after lastSeq,
+// then keeps it following the live stream if generation is still running.
+func (h *WebSocketHandler) handleResume(conn *websocket.Conn, mu *sync.Mutex, sub *streamSubscriber, projectID uuid.UUID, msg WebSocketMessage) {
+	stream, ok := h.streams.get(msg.MessageID)
+	if !ok || stream.projectID != projectID {
+		h.sendError(conn, mu, "stream not found or expired", "STREAM_NOT_FOUND", msg.MessageID)
+		return
+	}
+
+	replayed := stream.subscribe(sub, msg.LastSeq)
+
+	h.logger.Debug().
+		Str("projectId", projectID.String()).
+		Str("messageId", msg.MessageID).
+		Int("lastSeq", msg.LastSeq).
+		Int("replayed", replayed).
+		Msg("resumed chat stream")
 }
 
-func (h *WebSocketHandler) sendMessageStart(conn *websocket.Conn, mu *sync.Mutex, messageID string) {
+// handleCancel aborts a response being generated. Without a messageId it cancels
+// the project's running stream. Subscribers receive message_cancelled once the
+// partial response has been saved.
+func (h *WebSocketHandler) handleCancel(conn *websocket.Conn, mu *sync.Mutex, projectID uuid.UUID, msg WebSocketMessage) {
+	var stream *chatStream
+	var ok bool
+	if msg.MessageID != "" {
+		stream, ok = h.streams.get(msg.MessageID)
+	} else {
+		stream, ok = h.streams.activeFor(projectID)
+	}
+
+	if !ok || stream.projectID != projectID || !stream.requestCancel() {
+		h.sendError(conn, mu, "no response in progress", "STREAM_NOT_FOUND", msg.MessageID)
+		return
+	}
+
+	h.logger.Info().
+		Str("projectId", projectID.String()).
+		Str("messageId", stream.messageID).
+		Msg("cancel requested")
+}
+
+// sendStreamActive tells a newly connected client about a response still being generated.
+func (h *WebSocketHandler) sendStreamActive(conn *websocket.Conn, mu *sync.Mutex, projectID uuid.UUID) {
+	stream, ok := h.streams.activeFor(projectID)
+	if !ok {
+		return
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
-	startMsg := WebSocketMessage{
-		Type:      "message_start",
-		MessageID: messageID,
+	response := StreamActiveResponse{
+		Type:      "stream_active",
+		MessageID: stream.messageID,
+		LastSeq:   stream.lastSeq(),
 		Timestamp: time.Now().UTC(),
 	}
-	conn.WriteJSON(startMsg)
+	conn.WriteJSON(response)
 }
 
-func (h *WebSocketHandler) sendMessageComplete(conn *websocket.Conn, mu *sync.Mutex, messageID string, content string, codeBlocks []model.CodeBlock, agentType *string, completenessReport *model.CompletenessReport) {
+// sendPendingClarification tells a newly connected client about a question still waiting
+// for an answer.
+func (h *WebSocketHandler) sendPendingClarification(conn *websocket.Conn, mu *sync.Mutex, projectID uuid.UUID) {
+	if _, running := h.streams.activeFor(projectID); running {
+		return
+	}
+	clarification, ok := h.chatService.PendingClarification(projectID)
+	if !ok {
+		return
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
-	completeMsg := MessageCompleteResponse{
-		Type:               "message_complete",
-		MessageID:          messageID,
-		FullContent:        content,
-		CodeBlocks:         codeBlocks,
-		AgentType:          agentType,
-		CompletenessReport: completenessReport,
-		Timestamp:          time.Now().UTC(),
+	response := ClarificationRequestResponse{
+		Type:            "clarification_request",
+		ClarificationID: clarification.ID.String(),
+		Question:        clarification.Question,
+		Options:         clarification.Options,
+		Timestamp:       time.Now().UTC(),
 	}
-	conn.WriteJSON(completeMsg)
+	conn.WriteJSON(response)
 }
 
 func (h *WebSocketHandler) sendError(conn *websocket.Conn, mu *sync.Mutex, errorMsg string, code string, messageID string) {
@@ -224,21 +575,3 @@ func (h *WebSocketHandler) sendError(conn *websocket.Conn, mu *sync.Mutex, error
 	}
 	conn.WriteJSON(response)
 }
-
-func (h *WebSocketHandler) sendFilesUpdated(conn *websocket.Conn, mu *sync.Mutex, filePaths []string) {
-	if len(filePaths) == 0 {
-		return
-	}
-
-	mu.Lock()
-	defer mu.Unlock()
-
-	response := FilesUpdatedResponse{
-		Type:      "files_updated",
-		FilePaths: filePaths,
-		Timestamp: time.Now().UTC(),
-	}
-	if err := conn.WriteJSON(response); err != nil {
-		h.logger.Error().Err(err).Msg("failed to send files_updated event")
-	}
-}
@@ -32,18 +32,31 @@ type CompletenessReport struct {
 	Issues       []CompletenessIssue `json:"issues"`
 	FilesChecked int                 `json:"filesChecked"`
 	AutoFixable  int                 `json:"autoFixable"`
+	LintScore    *LintScore          `json:"lintScore,omitempty"` // Nil if the project has no HTML pages
+}
+
+// LintScore rates a project's HTML pages from 0 to 100 by their accessibility and SEO
+// issues. Each page starts at 100 and loses points for each issue by its severity; the
+// project's score is the average of its pages'.
+type LintScore struct {
+	Accessibility int `json:"accessibility"`
+	SEO           int `json:"seo"`
+	PagesChecked  int `json:"pagesChecked"`
 }
 
 // CompletenessIssue represents a single issue found during completeness check.
 type CompletenessIssue struct {
 	ID            string   `json:"id"`
 	Severity      Severity `json:"severity"`
-	Type          string   `json:"type"` // "missing_file", "syntax_error", "broken_reference"
+	Type          string   `json:"type"` // "missing_file", "syntax_error", "broken_reference", "runtime_error", "accessibility", "seo"
 	MissingFile   string   `json:"missingFile,omitempty"`
 	ReferencedBy  string   `json:"referencedBy,omitempty"`
-	ReferenceType string   `json:"referenceType,omitempty"` // "script", "stylesheet", "import", "image"
+	ReferenceType string   `json:"referenceType,omitempty"` // "script", "stylesheet", "import", "image", "font", "media", "manifest", "entry", "link"; for runtime errors "exception", "console_error", "asset_load"; for syntax errors the file's language; for accessibility and SEO issues the lint rule's ID
 	LineNumber    int      `json:"lineNumber,omitempty"`
-	Context       string   `json:"context,omitempty"`
+	Column        int      `json:"column,omitempty"`     // Column of a syntax error, if known
+	Context       string   `json:"context,omitempty"`    // The referencing line, or a runtime or syntax error's or lint rule's message
+	WCAG          string   `json:"wcag,omitempty"`       // WCAG success criterion an accessibility issue fails, e.g. "1.1.1 Non-text Content"
+	Suggestion    string   `json:"suggestion,omitempty"` // How to fix an accessibility or SEO issue
 	AutoFixable   bool     `json:"autoFixable"`
 	FixApplied    bool     `json:"fixApplied"`
 }
@@ -77,6 +90,28 @@ func (r *CompletenessReport) GetMissingFiles() []string {
 	return missing
 }
 
+// GetSyntaxErrors returns only syntax_error issues.
+func (r *CompletenessReport) GetSyntaxErrors() []CompletenessIssue {
+	var errs []CompletenessIssue
+	for _, issue := range r.Issues {
+		if issue.Type == "syntax_error" {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// GetLintIssues returns only accessibility and seo issues.
+func (r *CompletenessReport) GetLintIssues() []CompletenessIssue {
+	var lint []CompletenessIssue
+	for _, issue := range r.Issues {
+		if issue.Type == "accessibility" || issue.Type == "seo" {
+			lint = append(lint, issue)
+		}
+	}
+	return lint
+}
+
 // CompletenessFixRequest represents a request to fix completeness issues.
 type CompletenessFixRequest struct {
 	IssueIDs []string `json:"issueIds,omitempty"` // If empty, fix all auto-fixable
@@ -86,5 +121,6 @@ type CompletenessFixRequest struct {
 type CompletenessFixResponse struct {
 	Fixed     []string            `json:"fixed"`
 	Failed    []string            `json:"failed"`
+	Files     []string            `json:"files"` // Paths of the files written by the fix
 	NewReport *CompletenessReport `json:"newReport"`
 }
@@ -61,3 +61,27 @@ type GetFileResponse struct {
 	FunctionalGroup  string    `json:"functionalGroup,omitempty"`
 	CreatedAt        time.Time `json:"createdAt"`
 }
+
+// MoveFileRequest represents the request body for moving (renaming) a file.
+type MoveFileRequest struct {
+	Path string `json:"path"`
+}
+
+// FileOperationType identifies a staged file change.
+type FileOperationType string
+
+const (
+	FileOperationSave   FileOperationType = "save"   // Create or overwrite Path
+	FileOperationDelete FileOperationType = "delete" // Delete Path
+	FileOperationMove   FileOperationType = "move"   // Rename Path to NewPath
+)
+
+// FileOperation is one file change of a chat turn, applied with the turn's other
+// changes in a single transaction.
+type FileOperation struct {
+	Type     FileOperationType
+	Path     string
+	NewPath  string // Move only
+	Language string // Save only
+	Content  string // Save only
+}
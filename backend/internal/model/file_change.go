@@ -0,0 +1,85 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileChangeType describes the net effect of a turn on a file.
+type FileChangeType string
+
+const (
+	FileChangeAdded    FileChangeType = "added"
+	FileChangeModified FileChangeType = "modified"
+	FileChangeDeleted  FileChangeType = "deleted"
+)
+
+// FileChange records the before/after content of a file touched during an assistant turn.
+type FileChange struct {
+	ID            uuid.UUID      `db:"id" json:"id"`
+	MessageID     uuid.UUID      `db:"message_id" json:"messageId"`
+	ProjectID     uuid.UUID      `db:"project_id" json:"projectId"`
+	Path          string         `db:"path" json:"path"`
+	ChangeType    FileChangeType `db:"change_type" json:"changeType"`
+	BeforeContent *string        `db:"before_content" json:"beforeContent,omitempty"` // nil if the file was added
+	AfterContent  *string        `db:"after_content" json:"afterContent,omitempty"`   // nil if the file was deleted
+	Additions     int            `db:"additions" json:"additions"`
+	Deletions     int            `db:"deletions" json:"deletions"`
+	CreatedAt     time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// FileChangeStat is a path with its line counts, used in change summaries.
+type FileChangeStat struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// ChangeSummary summarizes the files changed during an assistant turn.
+type ChangeSummary struct {
+	MessageID uuid.UUID        `json:"messageId"` // Persisted assistant message ID, for GET /api/messages/:id/changes
+	Added     []FileChangeStat `json:"added"`
+	Modified  []FileChangeStat `json:"modified"`
+	Deleted   []FileChangeStat `json:"deleted"`
+	Additions int              `json:"additions"`
+	Deletions int              `json:"deletions"`
+}
+
+// IsEmpty returns true if no files were changed.
+func (s *ChangeSummary) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Modified) == 0 && len(s.Deleted) == 0
+}
+
+// DiffLine is a single line of a unified diff hunk.
+type DiffLine struct {
+	Type    string `json:"type"` // "context", "add", or "delete"
+	Content string `json:"content"`
+}
+
+// DiffHunk is a contiguous region of changes in a file diff.
+type DiffHunk struct {
+	Header   string     `json:"header"`
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// FileDiff is the diff of a single file within a change set.
+type FileDiff struct {
+	Path       string         `json:"path"`
+	ChangeType FileChangeType `json:"changeType"`
+	Additions  int            `json:"additions"`
+	Deletions  int            `json:"deletions"`
+	Diff       string         `json:"diff"` // Unified diff text
+	Hunks      []DiffHunk     `json:"hunks"`
+}
+
+// MessageChangesResponse represents the response for a message's change set.
+type MessageChangesResponse struct {
+	MessageID uuid.UUID     `json:"messageId"`
+	Summary   ChangeSummary `json:"summary"`
+	Files     []FileDiff    `json:"files"`
+}
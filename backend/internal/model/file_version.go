@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileVersionSource identifies what caused a file write.
+type FileVersionSource string
+
+const (
+	FileVersionSourceTool      FileVersionSource = "tool"       // write_file or edit_file tool call
+	FileVersionSourceCodeBlock FileVersionSource = "code_block" // Extracted markdown code block
+	FileVersionSourceUpload    FileVersionSource = "upload"     // Converted user upload
+	FileVersionSourceRestore   FileVersionSource = "restore"    // Rollback to an earlier version
+	FileVersionSourceFix       FileVersionSource = "fix"        // Missing file generated by a completeness fix
+)
+
+// FileVersion represents an immutable snapshot of a file's content after a write.
+type FileVersion struct {
+	ID           uuid.UUID         `db:"id" json:"id"`
+	FileID       uuid.UUID         `db:"file_id" json:"fileId"` // uuid.Nil once the file is deleted
+	ProjectID    uuid.UUID         `db:"project_id" json:"projectId"`
+	Version      int               `db:"version" json:"version"`
+	Path         string            `db:"path" json:"path"`
+	Language     string            `db:"language" json:"language,omitempty"`
+	Content      string            `db:"content" json:"content"`
+	Source       FileVersionSource `db:"source" json:"source"`
+	MessageID    *uuid.UUID        `db:"message_id" json:"messageId,omitempty"`
+	AgentType    *string           `db:"agent_type" json:"agentType,omitempty"`
+	RestoredFrom *int              `db:"restored_from" json:"restoredFrom,omitempty"`
+	CreatedAt    time.Time         `db:"created_at" json:"createdAt"`
+}
+
+// FileVersionListItem represents a file version in list view (without content).
+type FileVersionListItem struct {
+	ID           uuid.UUID         `db:"id" json:"id"`
+	Version      int               `db:"version" json:"version"`
+	Path         string            `db:"path" json:"path"`
+	Source       FileVersionSource `db:"source" json:"source"`
+	MessageID    *uuid.UUID        `db:"message_id" json:"messageId,omitempty"`
+	AgentType    *string           `db:"agent_type" json:"agentType,omitempty"`
+	RestoredFrom *int              `db:"restored_from" json:"restoredFrom,omitempty"`
+	Size         int               `db:"size" json:"size"`
+	CreatedAt    time.Time         `db:"created_at" json:"createdAt"`
+}
+
+// ListFileVersionsResponse represents the response for listing a file's versions.
+type ListFileVersionsResponse struct {
+	FileID   uuid.UUID             `json:"fileId"`
+	Path     string                `json:"path"`
+	Versions []FileVersionListItem `json:"versions"`
+}
+
+// ListDeletedFilesResponse represents the response for listing a project's deleted files.
+type ListDeletedFilesResponse struct {
+	ProjectID uuid.UUID             `json:"projectId"`
+	Files     []FileVersionListItem `json:"files"` // Latest version of each deleted file
+}
+
+// RestoreDeletedFileRequest represents the request body for restoring a deleted file.
+type RestoreDeletedFileRequest struct {
+	Path string `json:"path"`
+}
+
+// RestoreFileVersionResponse represents the response after restoring a file version
+// or a deleted file.
+type RestoreFileVersionResponse struct {
+	File    GetFileResponse `json:"file"`
+	Version FileVersion     `json:"version"`
+}
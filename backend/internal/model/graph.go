@@ -0,0 +1,39 @@
+package model
+
+import "github.com/google/uuid"
+
+// GraphFormat is an export format of a project's dependency graph.
+type GraphFormat string
+
+const (
+	GraphFormatJSON    GraphFormat = "json"
+	GraphFormatDOT     GraphFormat = "dot"     // Graphviz
+	GraphFormatMermaid GraphFormat = "mermaid" // Mermaid flowchart
+)
+
+// ProjectGraph is the dependency graph of a project: how its files reference each other.
+type ProjectGraph struct {
+	ProjectID uuid.UUID   `json:"projectId"`
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+	Orphans   []string    `json:"orphans"` // Paths of files that neither reference nor are referenced by another file
+	Cycles    [][]string  `json:"cycles"`  // Paths of each cycle, starting with its smallest path; the last file references the first
+}
+
+// GraphNode is a file of a project graph.
+type GraphNode struct {
+	ID               uuid.UUID `json:"id"`
+	Path             string    `json:"path"`
+	Filename         string    `json:"filename"`
+	Language         string    `json:"language,omitempty"`
+	FunctionalGroup  string    `json:"functionalGroup,omitempty"`
+	ShortDescription string    `json:"shortDescription,omitempty"`
+}
+
+// GraphEdge is a reference from one file of a project graph to another, by path.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"` // "script", "stylesheet", "import", "image", "font", "media", "manifest", "entry" or "link"
+	Line int    `json:"line"` // Line of the first reference in From
+}
@@ -21,6 +21,7 @@ type Message struct {
 	Role       Role        `db:"role" json:"role"`
 	Content    string      `db:"content" json:"content"`
 	AgentType  *string     `db:"agent_type" json:"agentType,omitempty"` // "product_manager", "designer", "developer", or null for user messages
+	Cancelled  bool        `db:"cancelled" json:"cancelled,omitempty"`  // True if the response was stopped before it finished
 	CreatedAt  time.Time   `db:"created_at" json:"createdAt"`
 	CodeBlocks []CodeBlock `db:"-" json:"codeBlocks,omitempty"`
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SummaryTrigger identifies what caused a conversation to be compacted.
+type SummaryTrigger string
+
+const (
+	SummaryTriggerTokenBudget  SummaryTrigger = "token_budget"  // Unsummarized history exceeded the token budget
+	SummaryTriggerMessageLimit SummaryTrigger = "message_limit" // Unsummarized history exceeded the message limit
+	SummaryTriggerPRDComplete  SummaryTrigger = "prd_complete"  // A PRD was marked complete
+)
+
+// ConversationSummary is a rolling recap of a project's conversation.
+// Each summary supersedes the previous one and covers every message up to ThroughMessageID,
+// or up to ThroughCreatedAt once that message has been deleted.
+type ConversationSummary struct {
+	ID               uuid.UUID      `db:"id" json:"id"`
+	ProjectID        uuid.UUID      `db:"project_id" json:"projectId"`
+	Content          string         `db:"content" json:"content"`
+	ThroughMessageID *uuid.UUID     `db:"through_message_id" json:"throughMessageId,omitempty"`
+	ThroughCreatedAt *time.Time     `db:"through_created_at" json:"throughCreatedAt,omitempty"`
+	MessageCount     int            `db:"message_count" json:"messageCount"`
+	Trigger          SummaryTrigger `db:"trigger" json:"trigger"`
+	PRDID            *uuid.UUID     `db:"prd_id" json:"prdId,omitempty"`
+	CreatedAt        time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// ListSummariesResponse represents the response for listing a project's summaries.
+type ListSummariesResponse struct {
+	Summaries []ConversationSummary `json:"summaries"`
+}
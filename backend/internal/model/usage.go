@@ -0,0 +1,118 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageSource identifies what made a Claude API call.
+type UsageSource string
+
+const (
+	UsageSourceChat      UsageSource = "chat"      // Chat response, including tool-use continuations
+	UsageSourceDiscovery UsageSource = "discovery" // Chat response while the project is in discovery
+	UsageSourcePRD       UsageSource = "prd"       // PRD generation
+	UsageSourceWelcome   UsageSource = "welcome"   // Discovery welcome message
+	UsageSourceSummary   UsageSource = "summary"   // Conversation compaction
+	UsageSourceVision    UsageSource = "vision"    // Image analysis of an upload
+	UsageSourceFix       UsageSource = "fix"       // Completeness fix generating a missing file
+	UsageSourceOther     UsageSource = "other"     // Call made without a usage scope
+)
+
+// TokenUsage holds the token counts reported by the Claude API for one call.
+type TokenUsage struct {
+	InputTokens              int `db:"input_tokens" json:"inputTokens"`
+	OutputTokens             int `db:"output_tokens" json:"outputTokens"`
+	CacheCreationInputTokens int `db:"cache_creation_input_tokens" json:"cacheCreationInputTokens"`
+	CacheReadInputTokens     int `db:"cache_read_input_tokens" json:"cacheReadInputTokens"`
+}
+
+// Add returns the sum of two usages.
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:              u.InputTokens + other.InputTokens,
+		OutputTokens:             u.OutputTokens + other.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens + other.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens + other.CacheReadInputTokens,
+	}
+}
+
+// IsZero returns true if no tokens were reported.
+func (u TokenUsage) IsZero() bool {
+	return u == TokenUsage{}
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cacheWrite"`
+	CacheRead  float64 `json:"cacheRead"`
+}
+
+// UsageRecord is the token usage and cost of one Claude API call.
+type UsageRecord struct {
+	ID        uuid.UUID   `db:"id" json:"id"`
+	ProjectID *uuid.UUID  `db:"project_id" json:"projectId,omitempty"`
+	MessageID *uuid.UUID  `db:"message_id" json:"messageId,omitempty"`
+	AgentType *string     `db:"agent_type" json:"agentType,omitempty"`
+	Source    UsageSource `db:"source" json:"source"`
+	Model     string      `db:"model" json:"model"`
+	Route     *string     `db:"route" json:"route,omitempty"` // Model route key the call was made with
+	TokenUsage
+	CostUSD         float64   `db:"cost_usd" json:"costUsd"`
+	CacheSavingsUSD float64   `db:"cache_savings_usd" json:"cacheSavingsUsd"`
+	CreatedAt       time.Time `db:"created_at" json:"createdAt"`
+}
+
+// UsageTotals aggregates the usage of several calls.
+type UsageTotals struct {
+	Calls int `json:"calls"`
+	TokenUsage
+	CostUSD         float64 `json:"costUsd"`
+	CacheSavingsUSD float64 `json:"cacheSavingsUsd"`
+}
+
+// AddRecord adds a call to the totals.
+func (t *UsageTotals) AddRecord(record UsageRecord) {
+	t.Calls++
+	t.TokenUsage = t.TokenUsage.Add(record.TokenUsage)
+	t.CostUSD += record.CostUSD
+	t.CacheSavingsUSD += record.CacheSavingsUSD
+}
+
+// AgentUsage is the usage attributed to one agent type ("none" for calls without an agent).
+type AgentUsage struct {
+	AgentType string `json:"agentType"`
+	UsageTotals
+}
+
+// SourceUsage is the usage of one kind of call.
+type SourceUsage struct {
+	Source UsageSource `json:"source"`
+	UsageTotals
+}
+
+// ModelUsage is the usage of one model.
+type ModelUsage struct {
+	Model string `json:"model"`
+	UsageTotals
+}
+
+// DailyUsage is the usage of one UTC day.
+type DailyUsage struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	UsageTotals
+}
+
+// ProjectUsageResponse represents the response for a project's token usage and cost.
+type ProjectUsageResponse struct {
+	ProjectID uuid.UUID     `json:"projectId"`
+	Since     time.Time     `json:"since"`
+	Total     UsageTotals   `json:"total"`
+	ByAgent   []AgentUsage  `json:"byAgent"`
+	BySource  []SourceUsage `json:"bySource"`
+	ByModel   []ModelUsage  `json:"byModel"`
+	Daily     []DailyUsage  `json:"daily"`
+}
@@ -0,0 +1,316 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// maxEditDistance bounds the work done by the Myers search. Inputs that differ
+// by more edits than this are diffed as a single replacement of the changed region.
+const maxEditDistance = 2000
+
+// LineKind identifies how a line participates in a diff.
+type LineKind string
+
+const (
+	LineContext LineKind = "context" // Unchanged line
+	LineAdded   LineKind = "add"     // Line only present in the new content
+	LineDeleted LineKind = "delete"  // Line only present in the old content
+)
+
+// Line is a single line of a diff.
+type Line struct {
+	Kind    LineKind `json:"type"`
+	Content string   `json:"content"`
+}
+
+// Hunk is a contiguous region of changes with surrounding context.
+// Start positions are 1-based line numbers, following unified diff conventions.
+type Hunk struct {
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Header returns the unified diff hunk header, e.g. "@@ -1,3 +1,4 @@".
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", rangeString(h.OldStart, h.OldLines), rangeString(h.NewStart, h.NewLines))
+}
+
+// Compute diffs two texts line by line and groups the result into hunks
+// with the given number of context lines.
+func Compute(before, after string, context int) []Hunk {
+	return buildHunks(diffLines(splitLines(before), splitLines(after)), context)
+}
+
+// Stats counts added and deleted lines across hunks.
+func Stats(hunks []Hunk) (additions, deletions int) {
+	for _, h := range hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded:
+				additions++
+			case LineDeleted:
+				deletions++
+			}
+		}
+	}
+	return additions, deletions
+}
+
+// Unified renders hunks as unified diff text. Use "/dev/null" as a name
+// for a file that did not exist on that side.
+func Unified(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case LineAdded:
+				sb.WriteByte('+')
+			case LineDeleted:
+				sb.WriteByte('-')
+			default:
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(l.Content)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines. A trailing newline does not produce an extra empty line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// rangeString formats a hunk range. Empty ranges point at the line before the change.
+func rangeString(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	if count == 0 {
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// diffLines returns the full edit script turning a into b.
+func diffLines(a, b []string) []Line {
+	// Trim common prefix and suffix so the search only covers the changed region
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, s := range a[:prefix] {
+		lines = append(lines, Line{Kind: LineContext, Content: s})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, s := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Kind: LineContext, Content: s})
+	}
+	return lines
+}
+
+// myers computes a shortest edit script using Myers' O(ND) algorithm.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	limit := n + m
+	if limit > maxEditDistance {
+		limit = maxEditDistance
+	}
+
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds v[-d..d] as it was before step d, for backtracking
+	var trace [][]int
+	found := false
+
+	for d := 0; d <= limit && !found; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return replaceAll(a, b)
+	}
+
+	// Backtrack from (n, m) to (0, 0), collecting lines in reverse
+	var reversed []Line
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Kind: LineContext, Content: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Kind: LineAdded, Content: b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Kind: LineDeleted, Content: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, l := range reversed {
+		lines[len(reversed)-1-i] = l
+	}
+	return lines
+}
+
+// replaceAll is the fallback edit script: delete every old line, then add every new line.
+func replaceAll(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, s := range a {
+		lines = append(lines, Line{Kind: LineDeleted, Content: s})
+	}
+	for _, s := range b {
+		lines = append(lines, Line{Kind: LineAdded, Content: s})
+	}
+	return lines
+}
+
+// buildHunks groups an edit script into hunks, merging changes separated
+// by no more than 2*context unchanged lines.
+func buildHunks(lines []Line, context int) []Hunk {
+	if context < 0 {
+		context = 0
+	}
+
+	// Line numbers (1-based) in the old and new text at each position of the script
+	oldAt := make([]int, len(lines)+1)
+	newAt := make([]int, len(lines)+1)
+	oldLine, newLine := 1, 1
+	for i, l := range lines {
+		oldAt[i], newAt[i] = oldLine, newLine
+		switch l.Kind {
+		case LineContext:
+			oldLine++
+			newLine++
+		case LineDeleted:
+			oldLine++
+		case LineAdded:
+			newLine++
+		}
+	}
+	oldAt[len(lines)], newAt[len(lines)] = oldLine, newLine
+
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		if lines[i].Kind == LineContext {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend over changes and short runs of context between them
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != LineContext {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Kind == LineContext {
+				run++
+			}
+			if run < len(lines) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			break
+		}
+		end += context
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		hunk := Hunk{
+			OldStart: oldAt[start],
+			NewStart: newAt[start],
+			Lines:    lines[start:end],
+		}
+		for _, l := range hunk.Lines {
+			if l.Kind != LineAdded {
+				hunk.OldLines++
+			}
+			if l.Kind != LineDeleted {
+				hunk.NewLines++
+			}
+		}
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
@@ -0,0 +1,155 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompute_NoChanges(t *testing.T) {
+	hunks := Compute("a\nb\nc\n", "a\nb\nc\n", DefaultContext)
+
+	if len(hunks) != 0 {
+		t.Errorf("expected 0 hunks, got %d", len(hunks))
+	}
+}
+
+func TestCompute_AddedFile(t *testing.T) {
+	hunks := Compute("", "one\ntwo\n", DefaultContext)
+
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+	if got := hunks[0].Header(); got != "@@ -0,0 +1,2 @@" {
+		t.Errorf("expected header '@@ -0,0 +1,2 @@', got '%s'", got)
+	}
+
+	additions, deletions := Stats(hunks)
+	if additions != 2 || deletions != 0 {
+		t.Errorf("expected +2 -0, got +%d -%d", additions, deletions)
+	}
+}
+
+func TestCompute_ModifiedLine(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	after := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+
+	hunks := Compute(before, after, DefaultContext)
+
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+	if got := hunks[0].Header(); got != "@@ -2,7 +2,7 @@" {
+		t.Errorf("expected header '@@ -2,7 +2,7 @@', got '%s'", got)
+	}
+
+	additions, deletions := Stats(hunks)
+	if additions != 1 || deletions != 1 {
+		t.Errorf("expected +1 -1, got +%d -%d", additions, deletions)
+	}
+}
+
+func TestCompute_SeparateHunks(t *testing.T) {
+	var beforeLines, afterLines []string
+	for i := 0; i < 30; i++ {
+		line := strings.Repeat("x", i+1)
+		beforeLines = append(beforeLines, line)
+		afterLines = append(afterLines, line)
+	}
+	afterLines[2] = "changed near top"
+	afterLines[27] = "changed near bottom"
+
+	hunks := Compute(strings.Join(beforeLines, "\n"), strings.Join(afterLines, "\n"), DefaultContext)
+
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	if hunks[0].OldStart != 1 {
+		t.Errorf("expected first hunk to start at line 1, got %d", hunks[0].OldStart)
+	}
+	if hunks[1].OldStart != 25 {
+		t.Errorf("expected second hunk to start at line 25, got %d", hunks[1].OldStart)
+	}
+}
+
+func TestCompute_MergesNearbyChanges(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	after := "A\nb\nc\nd\ne\nf\ng\nH\n"
+
+	hunks := Compute(before, after, DefaultContext)
+
+	if len(hunks) != 1 {
+		t.Fatalf("expected changes within 2*context lines to merge into 1 hunk, got %d", len(hunks))
+	}
+}
+
+func TestCompute_InsertionInMiddle(t *testing.T) {
+	hunks := Compute("a\nb\nc\n", "a\nb\nnew\nc\n", DefaultContext)
+
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+
+	var kinds []LineKind
+	for _, l := range hunks[0].Lines {
+		kinds = append(kinds, l.Kind)
+	}
+	expected := []LineKind{LineContext, LineContext, LineAdded, LineContext}
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(kinds))
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("line %d: expected %s, got %s", i, expected[i], kinds[i])
+		}
+	}
+}
+
+func TestMyers_ShortestEditScript(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+
+	lines := myers(a, b)
+
+	var edits int
+	var oldOut, newOut []string
+	for _, l := range lines {
+		switch l.Kind {
+		case LineContext:
+			oldOut = append(oldOut, l.Content)
+			newOut = append(newOut, l.Content)
+		case LineDeleted:
+			oldOut = append(oldOut, l.Content)
+			edits++
+		case LineAdded:
+			newOut = append(newOut, l.Content)
+			edits++
+		}
+	}
+
+	if edits != 5 {
+		t.Errorf("expected 5 edits, got %d", edits)
+	}
+	if strings.Join(oldOut, " ") != strings.Join(a, " ") {
+		t.Errorf("edit script does not reproduce old text: %v", oldOut)
+	}
+	if strings.Join(newOut, " ") != strings.Join(b, " ") {
+		t.Errorf("edit script does not reproduce new text: %v", newOut)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	hunks := Compute("hello\nworld\n", "hello\nthere\n", DefaultContext)
+
+	got := Unified("a/index.html", "b/index.html", hunks)
+	expected := "--- a/index.html\n+++ b/index.html\n@@ -1,2 +1,2 @@\n hello\n-world\n+there\n"
+
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestUnified_NoHunks(t *testing.T) {
+	if got := Unified("a/x", "b/x", nil); got != "" {
+		t.Errorf("expected empty diff, got %q", got)
+	}
+}
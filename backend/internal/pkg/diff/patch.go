@@ -0,0 +1,280 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoMatch means a search block or hunk does not match the current content.
+	ErrNoMatch = errors.New("no match in current content")
+	// ErrAmbiguousMatch means a search block matches more than one place.
+	ErrAmbiguousMatch = errors.New("matches more than one place")
+	// ErrMalformedPatch means a unified diff could not be parsed.
+	ErrMalformedPatch = errors.New("malformed patch")
+)
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Replacement replaces the single exact occurrence of Search with Replace.
+type Replacement struct {
+	Search  string
+	Replace string
+}
+
+// LineRange is a 1-based, inclusive range of lines in the edited content.
+// A range with End < Start marks lines removed just before line Start.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// String formats the range as "12", "12-15", or "deleted before 12".
+func (r LineRange) String() string {
+	switch {
+	case r.End < r.Start:
+		return fmt.Sprintf("deleted before %d", r.Start)
+	case r.End == r.Start:
+		return strconv.Itoa(r.Start)
+	default:
+		return fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+}
+
+// ApplyReplacements applies search/replace edits in order. Each search text must
+// occur exactly once in the content as it is when that edit is applied, so an
+// edit never lands somewhere the author did not intend. Nothing is applied if
+// any edit fails.
+func ApplyReplacements(content string, edits []Replacement) (string, error) {
+	for i, edit := range edits {
+		if edit.Search == "" {
+			return "", fmt.Errorf("edit %d: search text is empty", i+1)
+		}
+
+		switch count := strings.Count(content, edit.Search); count {
+		case 0:
+			return "", fmt.Errorf("edit %d: %w%s", i+1, ErrNoMatch, nearestLineHint(content, edit.Search))
+		case 1:
+			content = strings.Replace(content, edit.Search, edit.Replace, 1)
+		default:
+			return "", fmt.Errorf("edit %d: search text %w (%d occurrences); include more surrounding lines", i+1, ErrAmbiguousMatch, count)
+		}
+	}
+	return content, nil
+}
+
+// nearestLineHint points at a line that matches the first line of search apart from
+// surrounding whitespace, the usual reason an exact match fails.
+func nearestLineHint(content, search string) string {
+	first := ""
+	for _, line := range strings.Split(search, "\n") {
+		if strings.TrimSpace(line) != "" {
+			first = strings.TrimSpace(line)
+			break
+		}
+	}
+	if first == "" {
+		return ""
+	}
+
+	for i, line := range splitLines(content) {
+		if strings.TrimSpace(line) == first {
+			return fmt.Sprintf(" (line %d has the same text with different whitespace or the lines around it differ)", i+1)
+		}
+	}
+	return ""
+}
+
+// patchHunk is a parsed unified diff hunk.
+type patchHunk struct {
+	header   string   // Header line, for error messages
+	oldStart int      // 1-based line the hunk claims to start at
+	oldCount int      // Old line count from the header
+	newCount int      // New line count from the header
+	old      []string // Context and deleted lines
+	new      []string // Context and added lines
+}
+
+// complete reports whether the hunk has as many lines as its header announced.
+func (h *patchHunk) complete() bool {
+	return len(h.old) >= h.oldCount && len(h.new) >= h.newCount
+}
+
+// ApplyUnified applies the hunks of a unified diff to content. A hunk is applied at
+// the line its header names if its context and deleted lines match there; otherwise
+// at the nearest place after the previous hunk where they match exactly. A hunk that
+// matches nowhere is a conflict and nothing is applied.
+func ApplyUnified(content, patch string) (string, error) {
+	hunks, err := parseUnified(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := splitLines(content)
+	offset := 0   // Lines added minus lines removed by earlier hunks
+	minStart := 0 // Hunks apply in order and may not overlap
+
+	for i, h := range hunks {
+		expected := h.oldStart - 1 + offset
+		if len(h.old) == 0 && h.oldStart > 0 {
+			expected++ // "-N,0" inserts after line N
+		}
+		if expected < minStart {
+			expected = minStart
+		}
+		if expected > len(lines) {
+			expected = len(lines)
+		}
+
+		at := expected
+		if !linesMatch(lines, at, h.old) {
+			at = nearestMatch(lines, minStart, expected, h.old)
+			if at < 0 {
+				return "", fmt.Errorf("hunk %d (%s): %w%s", i+1, h.header, ErrNoMatch, mismatchDetail(lines, expected, h.old))
+			}
+		}
+
+		updated := make([]string, 0, len(lines)-len(h.old)+len(h.new))
+		updated = append(updated, lines[:at]...)
+		updated = append(updated, h.new...)
+		updated = append(updated, lines[at+len(h.old):]...)
+		lines = updated
+
+		offset += len(h.new) - len(h.old)
+		minStart = at + len(h.new)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	result := strings.Join(lines, "\n")
+	if content == "" || strings.HasSuffix(content, "\n") {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// parseUnified parses the hunks of a unified diff. File headers ("---", "+++", "diff")
+// and commentary between hunks are skipped. Header line counts are only used to tell
+// a "---" file header from a deleted "--" line, since models often get them wrong.
+// A blank line inside a hunk is read as an empty context line, since models often
+// drop the leading space.
+func parseUnified(patch string) ([]patchHunk, error) {
+	var hunks []patchHunk
+	var current *patchHunk
+
+	for _, line := range strings.Split(strings.TrimSuffix(patch, "\n"), "\n") {
+		if strings.HasPrefix(line, "@@") {
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("%w: bad hunk header %q", ErrMalformedPatch, line)
+			}
+			h := patchHunk{header: strings.TrimSpace(line), oldCount: 1, newCount: 1}
+			h.oldStart, _ = strconv.Atoi(m[1])
+			if m[2] != "" {
+				h.oldCount, _ = strconv.Atoi(m[2])
+			}
+			if m[4] != "" {
+				h.newCount, _ = strconv.Atoi(m[4])
+			}
+			hunks = append(hunks, h)
+			current = &hunks[len(hunks)-1]
+			continue
+		}
+
+		isFileHeader := strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "diff ")
+		if current != nil && current.complete() && (isFileHeader || line == "") {
+			current = nil
+		}
+		if current == nil {
+			// Headers and commentary are skipped, but a stray diff line would be silently lost
+			if !isFileHeader && line != "" && strings.ContainsRune(" +-", rune(line[0])) {
+				return nil, fmt.Errorf("%w: line outside a hunk: %q (hunk line counts may be wrong)", ErrMalformedPatch, line)
+			}
+			continue
+		}
+
+		switch {
+		case line == "":
+			current.old = append(current.old, "")
+			current.new = append(current.new, "")
+		case line[0] == ' ':
+			current.old = append(current.old, line[1:])
+			current.new = append(current.new, line[1:])
+		case line[0] == '-':
+			current.old = append(current.old, line[1:])
+		case line[0] == '+':
+			current.new = append(current.new, line[1:])
+		case line[0] == '\\':
+			// "\ No newline at end of file"
+		case current.complete():
+			current = nil // Commentary after a hunk
+		default:
+			return nil, fmt.Errorf("%w: unexpected line in hunk %d: %q", ErrMalformedPatch, len(hunks), line)
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("%w: no hunks found (expected lines starting with @@)", ErrMalformedPatch)
+	}
+	return hunks, nil
+}
+
+// linesMatch reports whether want appears in lines starting at index at.
+func linesMatch(lines []string, at int, want []string) bool {
+	if at < 0 || at+len(want) > len(lines) {
+		return false
+	}
+	for i, w := range want {
+		if lines[at+i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// nearestMatch finds the match of want at or after minStart closest to expected, or -1.
+func nearestMatch(lines []string, minStart, expected int, want []string) int {
+	best := -1
+	for at := minStart; at+len(want) <= len(lines); at++ {
+		if !linesMatch(lines, at, want) {
+			continue
+		}
+		if best < 0 || abs(at-expected) < abs(best-expected) {
+			best = at
+		}
+	}
+	return best
+}
+
+// mismatchDetail describes the first line of a hunk that differs from the content at its expected position.
+func mismatchDetail(lines []string, at int, want []string) string {
+	for i, w := range want {
+		if at+i >= len(lines) {
+			return fmt.Sprintf("; expected %q at line %d but the file has only %d lines", w, at+i+1, len(lines))
+		}
+		if lines[at+i] != w {
+			return fmt.Sprintf("; expected %q at line %d, found %q", w, at+i+1, lines[at+i])
+		}
+	}
+	return ""
+}
+
+// ChangedLines returns the line ranges of after that differ from before.
+func ChangedLines(before, after string) []LineRange {
+	var ranges []LineRange
+	for _, h := range Compute(before, after, 0) {
+		ranges = append(ranges, LineRange{Start: h.NewStart, End: h.NewStart + h.NewLines - 1})
+	}
+	return ranges
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const page = `<html>
+<head>
+  <title>Old</title>
+</head>
+<body>
+  <h1>Hello</h1>
+  <p>One</p>
+  <p>Two</p>
+</body>
+</html>
+`
+
+func TestApplyReplacements(t *testing.T) {
+	got, err := ApplyReplacements(page, []Replacement{
+		{Search: "<title>Old</title>", Replace: "<title>New</title>"},
+		{Search: "  <p>Two</p>\n", Replace: ""},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(got, "<title>New</title>") {
+		t.Errorf("expected title to be replaced, got:\n%s", got)
+	}
+	if strings.Contains(got, "<p>Two</p>") {
+		t.Errorf("expected paragraph to be removed, got:\n%s", got)
+	}
+}
+
+func TestApplyReplacements_NoMatch(t *testing.T) {
+	_, err := ApplyReplacements(page, []Replacement{
+		{Search: "<title>Old</title>", Replace: "<title>New</title>"},
+		{Search: "<h1>Hello</h1>\n<p>One</p>", Replace: "x"}, // Indentation differs
+	})
+
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "edit 2") {
+		t.Errorf("expected error to name the failing edit, got %q", err)
+	}
+	if !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("expected a hint pointing at line 6, got %q", err)
+	}
+}
+
+func TestApplyReplacements_Ambiguous(t *testing.T) {
+	_, err := ApplyReplacements(page, []Replacement{{Search: "<p>", Replace: "<p class=\"x\">"}})
+
+	if !errors.Is(err, ErrAmbiguousMatch) {
+		t.Fatalf("expected ErrAmbiguousMatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "2 occurrences") {
+		t.Errorf("expected occurrence count in error, got %q", err)
+	}
+}
+
+func TestApplyUnified(t *testing.T) {
+	patch := `--- a/index.html
++++ b/index.html
+@@ -2,3 +2,3 @@
+ <head>
+-  <title>Old</title>
++  <title>New</title>
+ </head>
+@@ -7,2 +7,3 @@
+   <p>One</p>
++  <p>One and a half</p>
+   <p>Two</p>
+`
+
+	got, err := ApplyUnified(page, patch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := strings.Replace(page, "<title>Old</title>", "<title>New</title>", 1)
+	want = strings.Replace(want, "  <p>Two</p>", "  <p>One and a half</p>\n  <p>Two</p>", 1)
+	if got != want {
+		t.Errorf("unexpected result:\n%s", got)
+	}
+}
+
+func TestApplyUnified_WrongLineNumbers(t *testing.T) {
+	// Header says line 40, but the context only matches at line 6
+	patch := `@@ -40,2 +40,2 @@
+-  <h1>Hello</h1>
++  <h1>Welcome</h1>
+   <p>One</p>
+`
+
+	got, err := ApplyUnified(page, patch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "<h1>Welcome</h1>") {
+		t.Errorf("expected hunk to be applied where it matches, got:\n%s", got)
+	}
+}
+
+func TestApplyUnified_Conflict(t *testing.T) {
+	patch := `@@ -6,2 +6,2 @@
+-  <h1>Goodbye</h1>
++  <h1>Welcome</h1>
+   <p>One</p>
+`
+
+	_, err := ApplyUnified(page, patch)
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected ErrNoMatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `expected "  <h1>Goodbye</h1>" at line 6, found "  <h1>Hello</h1>"`) {
+		t.Errorf("expected error to show the mismatching line, got %q", err)
+	}
+}
+
+func TestApplyUnified_DeletesDashLines(t *testing.T) {
+	// A deleted line "-- note" looks like a file header but belongs to the hunk
+	content := "a\n-- note\nb\n"
+	patch := "@@ -1,3 +1,2 @@\n a\n--- note\n b\n"
+
+	got, err := ApplyUnified(content, patch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "a\nb\n" {
+		t.Errorf("expected 'a\\nb\\n', got %q", got)
+	}
+}
+
+func TestApplyUnified_Malformed(t *testing.T) {
+	tests := map[string]string{
+		"no hunks":        "just some text\n",
+		"bad header":      "@@ -x +y @@\n a\n",
+		"stray diff line": "@@ -1 +1 @@\n-a\n+b\n\n+c\n",
+	}
+
+	for name, patch := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ApplyUnified("a\n", patch); !errors.Is(err, ErrMalformedPatch) {
+				t.Errorf("expected ErrMalformedPatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n"
+	after := "1\ntwo\n3\n4\n6\n7\n8\n"
+
+	ranges := ChangedLines(before, after)
+
+	var got []string
+	for _, r := range ranges {
+		got = append(got, r.String())
+	}
+	want := []string{"2", "deleted before 5", "6-7"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
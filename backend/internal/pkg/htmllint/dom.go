@@ -0,0 +1,181 @@
+package htmllint
+
+import (
+	"html"
+	"sort"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/htmltoken"
+)
+
+// closedBy maps elements whose end tag is optional to the start tags that close them.
+var closedBy = map[string]map[string]bool{
+	"p":      {"p": true, "div": true, "ul": true, "ol": true, "table": true, "section": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "form": true, "header": true, "footer": true, "nav": true},
+	"li":     {"li": true},
+	"dt":     {"dt": true, "dd": true},
+	"dd":     {"dt": true, "dd": true},
+	"option": {"option": true, "optgroup": true},
+	"tr":     {"tr": true},
+	"td":     {"td": true, "th": true, "tr": true},
+	"th":     {"td": true, "th": true, "tr": true},
+}
+
+// attr is an attribute of an element.
+type attr struct {
+	name  string // Lowercase
+	value string // Unescaped
+}
+
+// node is an element or a run of text. The document's root is a node named "#document".
+type node struct {
+	name     string // Lowercase element name, or "#text"
+	attrs    []attr
+	text     string // Unescaped text of a text node, or the content of a raw text element
+	line     int    // 1-based line of the start tag or text
+	parent   *node
+	children []*node
+}
+
+// attr returns the value of the element's attribute with a name, and whether it has it.
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// walk calls fn for the node and each element inside it, in document order.
+func (n *node) walk(fn func(*node)) {
+	if n.name == "#text" {
+		return
+	}
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// find returns the elements with a name inside the node, in document order.
+func (n *node) find(name string) []*node {
+	var found []*node
+	n.walk(func(el *node) {
+		if el.name == name {
+			found = append(found, el)
+		}
+	})
+	return found
+}
+
+// within reports whether the node is inside an element with a name.
+func (n *node) within(name string) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent returns the node's text with whitespace collapsed, including the text
+// alternatives of images inside it.
+func (n *node) textContent() string {
+	var b strings.Builder
+	var collect func(*node)
+	collect = func(el *node) {
+		switch {
+		case el.name == "#text":
+			b.WriteString(el.text)
+			b.WriteByte(' ')
+		case el.name == "img":
+			alt, _ := el.attr("alt")
+			b.WriteString(alt)
+			b.WriteByte(' ')
+		case el.name == "script" || el.name == "style" || el.name == "template":
+		default:
+			for _, child := range el.children {
+				collect(child)
+			}
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// document is a parsed HTML page.
+type document struct {
+	root    *node
+	doctype bool // The page has a <!DOCTYPE>
+}
+
+// isPage reports whether the document is a full page rather than a fragment, such as a
+// template partial: it has a doctype or an <html> element. Page-level rules only apply
+// to full pages.
+func (d *document) isPage() bool {
+	return d.doctype || len(d.root.find("html")) > 0
+}
+
+// parse builds the element tree of an HTML page. Like browsers it recovers from broken
+// markup: end tags without a matching open element are ignored, and an end tag closes
+// the elements left open inside it.
+func parse(content string) *document {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	lineAt := func(offset int) int {
+		return sort.Search(len(lines), func(i int) bool { return lines[i] > offset })
+	}
+
+	doc := &document{root: &node{name: "#document", line: 1}}
+	open := []*node{doc.root}
+	top := func() *node { return open[len(open)-1] }
+
+	for _, tok := range htmltoken.Tokenize(content) {
+		switch tok.Kind {
+		case htmltoken.Declaration:
+			if tok.Name == "doctype" {
+				doc.doctype = true
+			}
+		case htmltoken.Text:
+			text := content[tok.Start:tok.End]
+			if tok.Raw { // Content of the raw text element just opened
+				el := top().children[len(top().children)-1]
+				el.text = text
+				if el.name == "title" || el.name == "textarea" {
+					el.text = html.UnescapeString(text)
+				}
+				continue
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			parent := top()
+			parent.children = append(parent.children, &node{name: "#text", text: html.UnescapeString(text), line: lineAt(tok.Start), parent: parent})
+		case htmltoken.EndTag:
+			for k := len(open) - 1; k > 0; k-- {
+				if open[k].name == tok.Name {
+					open = open[:k]
+					break
+				}
+			}
+		case htmltoken.StartTag:
+			el := &node{name: tok.Name, line: lineAt(tok.Start)}
+			for _, a := range tok.Attrs {
+				el.attrs = append(el.attrs, attr{name: a.Name, value: a.Value})
+			}
+			for len(open) > 1 && closedBy[top().name][el.name] {
+				open = open[:len(open)-1]
+			}
+			el.parent = top()
+			el.parent.children = append(el.parent.children, el)
+			if !htmltoken.IsVoid(el.name) && !tok.SelfClosing && !htmltoken.IsRawText(el.name) {
+				open = append(open, el)
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,89 @@
+package htmllint
+
+import (
+	"strings"
+	"testing"
+)
+
+// outline returns the element names of a tree, nested in parentheses, e.g. "html(head body(p))".
+func outline(n *node) string {
+	var parts []string
+	for _, child := range n.children {
+		if child.name == "#text" {
+			continue
+		}
+		if inner := outline(child); inner != "" {
+			parts = append(parts, child.name+"("+inner+")")
+		} else {
+			parts = append(parts, child.name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParse_Tree(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html lang="en">
+<head><title>A &amp; B</title><meta charset="utf-8"></head>
+<body>
+  <!-- <aside> in a comment -->
+  <ul><li>One<li>Two</ul>
+  <p>First<p>Second<div>Block</div>
+  <script>if (a < b) { document.write("<section>"); }</script>
+  <img src="a.png"/><span>x</b></span>
+</body>
+</html>`
+	d := parse(content)
+
+	want := "html(head(title meta) body(ul(li li) p p div script img span))"
+	if got := outline(d.root); got != want {
+		t.Errorf("expected tree %q, got %q", want, got)
+	}
+	if !d.doctype || !d.isPage() {
+		t.Errorf("expected a page with a doctype")
+	}
+	if title := d.root.find("title")[0]; title.text != "A & B" || title.line != 3 {
+		t.Errorf("expected title %q on line 3, got %q on line %d", "A & B", title.text, title.line)
+	}
+	if img := d.root.find("img")[0]; img.line != 9 {
+		t.Errorf("expected img on line 9, got %d", img.line)
+	}
+}
+
+func TestParse_Attributes(t *testing.T) {
+	d := parse(`<input type=email id='mail' required aria-label="E &quot;mail&quot;">`)
+
+	input := d.root.find("input")[0]
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"type", "email", true},
+		{"id", "mail", true},
+		{"required", "", true},
+		{"aria-label", `E "mail"`, true},
+		{"alt", "", false},
+	}
+	for _, tt := range tests {
+		if value, ok := input.attr(tt.name); value != tt.value || ok != tt.ok {
+			t.Errorf("attr(%q): expected (%q, %v), got (%q, %v)", tt.name, tt.value, tt.ok, value, ok)
+		}
+	}
+}
+
+func TestParse_Fragment(t *testing.T) {
+	d := parse(`<div class="card"><h2>Title</h2></div>`)
+
+	if d.isPage() {
+		t.Errorf("expected a fragment, not a page")
+	}
+}
+
+func TestNode_TextContent(t *testing.T) {
+	d := parse("<a href=\"/\">\n  <img src=\"home.png\" alt=\"Home\">\n  <span>page</span><style>a{}</style>\n</a>")
+
+	if got := d.root.find("a")[0].textContent(); got != "Home page" {
+		t.Errorf("expected %q, got %q", "Home page", got)
+	}
+}
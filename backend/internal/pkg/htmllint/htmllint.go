@@ -0,0 +1,73 @@
+// Package htmllint checks HTML pages for accessibility and SEO problems, such as images
+// without alt text, form controls without labels and pages without a meta description.
+// Each rule names the WCAG success criterion it checks, where there is one, and how to
+// fix what it finds. Rules about the page as a whole, such as its language and title,
+// only apply to full pages, not to fragments like template partials.
+package htmllint
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Rule categories.
+const (
+	CategoryAccessibility = "accessibility"
+	CategorySEO           = "seo"
+)
+
+// Rule severities, from most to least severe.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Rule is a check of HTML pages.
+type Rule struct {
+	ID       string // e.g. "img-alt"
+	Category string // CategoryAccessibility or CategorySEO
+	WCAG     string // WCAG 2.1 success criterion, e.g. "1.1.1 Non-text Content"; "" for SEO rules
+	Severity string // Default severity of the rule's findings
+	Fix      string // How to fix a finding
+	check    func(d *document, report reporter)
+}
+
+// reporter reports a finding of a rule at a node.
+type reporter func(n *node, format string, args ...interface{})
+
+// Finding is a problem a rule found in a page.
+type Finding struct {
+	Rule    *Rule
+	Line    int // 1-based
+	Message string
+}
+
+// Lint checks an HTML page against all rules and returns the findings, sorted by line.
+func Lint(content string) []Finding {
+	d := parse(content)
+	var findings []Finding
+	for _, rule := range rules {
+		rule := rule
+		rule.check(d, func(n *node, format string, args ...interface{}) {
+			findings = append(findings, Finding{Rule: rule, Line: n.line, Message: fmt.Sprintf(format, args...)})
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings
+}
+
+// Rules returns all rules, accessibility rules first.
+func Rules() []*Rule {
+	return append([]*Rule(nil), rules...)
+}
+
+// Lookup returns the rule with an ID.
+func Lookup(id string) (*Rule, bool) {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package htmllint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format joins findings as "line rule: message" with "; ".
+func format(findings []Finding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%d %s: %s", f.Line, f.Rule.ID, f.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestLint_CleanPage(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="description" content="Fresh bread, baked daily.">
+  <title>Bakery</title>
+</head>
+<body>
+  <h1>Bakery</h1>
+  <img src="img/loaf.jpg" alt="A sourdough loaf">
+  <h2>Order</h2>
+  <form>
+    <label for="name">Name</label>
+    <input id="name" type="text">
+    <label>Email <input type="email"></label>
+    <button type="submit">Send</button>
+  </form>
+  <a href="about.html">About us</a>
+</body>
+</html>`
+	if findings := Lint(content); findings != nil {
+		t.Errorf("expected no findings, got %q", format(findings))
+	}
+}
+
+func TestLint_SortedByLine(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html>
+<head><title>Menu</title></head>
+<body>
+<h1>Menu</h1>
+<img src="a.png">
+</body>
+</html>`
+	got := format(Lint(content))
+	want := `2 html-lang: the page has no lang attribute on its <html> element; ` +
+		`3 meta-description: the page has no meta description; ` +
+		`3 meta-viewport: the page has no viewport meta tag; ` +
+		`6 img-alt: <img src="a.png"> has no alt attribute`
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRules(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, rule := range Rules() {
+		if seen[rule.ID] {
+			t.Errorf("duplicate rule %q", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Category != CategoryAccessibility && rule.Category != CategorySEO {
+			t.Errorf("rule %q: unknown category %q", rule.ID, rule.Category)
+		}
+		if rule.Category == CategoryAccessibility && rule.WCAG == "" {
+			t.Errorf("rule %q: accessibility rule without a WCAG reference", rule.ID)
+		}
+		if rule.Severity != SeverityCritical && rule.Severity != SeverityWarning && rule.Severity != SeverityInfo {
+			t.Errorf("rule %q: unknown severity %q", rule.ID, rule.Severity)
+		}
+		if rule.Fix == "" {
+			t.Errorf("rule %q: no fix", rule.ID)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if rule, ok := Lookup("img-alt"); !ok || rule.WCAG != "1.1.1 Non-text Content" {
+		t.Errorf("expected the img-alt rule, got %v, %v", rule, ok)
+	}
+	if _, ok := Lookup("no-such-rule"); ok {
+		t.Errorf("expected no rule")
+	}
+}
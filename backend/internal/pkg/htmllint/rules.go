@@ -0,0 +1,371 @@
+package htmllint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// rules are the rules Lint checks, accessibility rules first.
+var rules = []*Rule{
+	{
+		ID:       "img-alt",
+		Category: CategoryAccessibility,
+		WCAG:     "1.1.1 Non-text Content",
+		Severity: SeverityWarning,
+		Fix:      `Add an alt attribute describing the image, or alt="" if it is only decorative.`,
+		check:    checkImageAlt,
+	},
+	{
+		ID:       "html-lang",
+		Category: CategoryAccessibility,
+		WCAG:     "3.1.1 Language of Page",
+		Severity: SeverityWarning,
+		Fix:      `Add the page's language to the <html> element, e.g. <html lang="en">.`,
+		check:    checkLang,
+	},
+	{
+		ID:       "document-title",
+		Category: CategoryAccessibility,
+		WCAG:     "2.4.2 Page Titled",
+		Severity: SeverityWarning,
+		Fix:      "Add a <title> to the <head> that describes the page.",
+		check:    checkTitle,
+	},
+	{
+		ID:       "form-label",
+		Category: CategoryAccessibility,
+		WCAG:     "4.1.2 Name, Role, Value",
+		Severity: SeverityWarning,
+		Fix:      `Add a <label for="..."> naming the control's id, wrap the control in a <label>, or give it an aria-label.`,
+		check:    checkFormLabels,
+	},
+	{
+		ID:       "button-name",
+		Category: CategoryAccessibility,
+		WCAG:     "4.1.2 Name, Role, Value",
+		Severity: SeverityWarning,
+		Fix:      "Give the button text, or an aria-label if it only shows an icon.",
+		check:    checkButtonNames,
+	},
+	{
+		ID:       "link-name",
+		Category: CategoryAccessibility,
+		WCAG:     "2.4.4 Link Purpose (In Context)",
+		Severity: SeverityWarning,
+		Fix:      "Give the link text saying where it goes, or an aria-label if it only shows an icon.",
+		check:    checkLinkNames,
+	},
+	{
+		ID:       "heading-order",
+		Category: CategoryAccessibility,
+		WCAG:     "1.3.1 Info and Relationships",
+		Severity: SeverityInfo,
+		Fix:      "Use heading levels in order, going down one level at a time, e.g. <h2> after <h1>.",
+		check:    checkHeadingOrder,
+	},
+	{
+		ID:       "viewport-zoom",
+		Category: CategoryAccessibility,
+		WCAG:     "1.4.4 Resize Text",
+		Severity: SeverityWarning,
+		Fix:      "Remove user-scalable=no and any maximum-scale below 2 from the viewport meta tag.",
+		check:    checkViewportZoom,
+	},
+	{
+		ID:       "duplicate-id",
+		Category: CategoryAccessibility,
+		WCAG:     "4.1.1 Parsing",
+		Severity: SeverityInfo,
+		Fix:      "Give each element a unique id, and update the labels and scripts that refer to it.",
+		check:    checkDuplicateIDs,
+	},
+	{
+		ID:       "meta-description",
+		Category: CategorySEO,
+		Severity: SeverityWarning,
+		Fix:      `Add <meta name="description" content="..."> to the <head> with a one or two sentence summary of the page.`,
+		check:    checkMetaDescription,
+	},
+	{
+		ID:       "meta-viewport",
+		Category: CategorySEO,
+		Severity: SeverityWarning,
+		Fix:      `Add <meta name="viewport" content="width=device-width, initial-scale=1"> to the <head>.`,
+		check:    checkMetaViewport,
+	},
+	{
+		ID:       "single-h1",
+		Category: CategorySEO,
+		Severity: SeverityInfo,
+		Fix:      "Give the page exactly one <h1> stating its main topic, and use <h2> to <h6> for sections.",
+		check:    checkSingleH1,
+	},
+}
+
+// unlabelledInputTypes are the input types that don't need a label: they are hidden or
+// named by their value or alt text.
+var unlabelledInputTypes = map[string]bool{"hidden": true, "submit": true, "reset": true, "button": true, "image": true}
+
+// checkImageAlt reports images, image inputs and image map areas without alt text.
+func checkImageAlt(d *document, report reporter) {
+	d.root.walk(func(n *node) {
+		switch {
+		case n.name == "img":
+		case n.name == "input" && inputType(n) == "image":
+		case n.name == "area":
+			if _, ok := n.attr("href"); !ok {
+				return
+			}
+		default:
+			return
+		}
+		if _, ok := n.attr("alt"); ok || hasARIALabel(n) {
+			return
+		}
+		report(n, "%s has no alt attribute", describe(n))
+	})
+}
+
+// checkLang reports a page whose <html> element has no language.
+func checkLang(d *document, report reporter) {
+	if !d.isPage() {
+		return
+	}
+	for _, n := range d.root.find("html") {
+		if lang, _ := n.attr("lang"); strings.TrimSpace(lang) != "" {
+			return
+		}
+	}
+	report(d.pageNode("html"), "the page has no lang attribute on its <html> element")
+}
+
+// checkTitle reports a page without a title, or with an empty one.
+func checkTitle(d *document, report reporter) {
+	if !d.isPage() {
+		return
+	}
+	titles := d.root.find("title")
+	if len(titles) == 0 {
+		report(d.pageNode("head"), "the page has no <title>")
+		return
+	}
+	if strings.TrimSpace(titles[0].text) == "" {
+		report(titles[0], "the page's <title> is empty")
+	}
+}
+
+// checkFormLabels reports form controls without a label: they aren't named by a
+// <label for>, inside a <label>, or labelled with ARIA or a title.
+func checkFormLabels(d *document, report reporter) {
+	labelled := make(map[string]bool)
+	for _, label := range d.root.find("label") {
+		if id, ok := label.attr("for"); ok {
+			labelled[id] = true
+		}
+	}
+
+	d.root.walk(func(n *node) {
+		switch n.name {
+		case "input":
+			if unlabelledInputTypes[inputType(n)] {
+				return
+			}
+		case "select", "textarea":
+		default:
+			return
+		}
+		if id, _ := n.attr("id"); id != "" && labelled[id] {
+			return
+		}
+		if n.within("label") || hasARIALabel(n) {
+			return
+		}
+		report(n, "%s has no label", describe(n))
+	})
+}
+
+// checkButtonNames reports buttons without text or an ARIA label.
+func checkButtonNames(d *document, report reporter) {
+	d.root.walk(func(n *node) {
+		if n.name == "button" && n.textContent() == "" && !hasARIALabel(n) {
+			report(n, "%s has no text", describe(n))
+		}
+	})
+}
+
+// checkLinkNames reports links without text or an ARIA label.
+func checkLinkNames(d *document, report reporter) {
+	d.root.walk(func(n *node) {
+		if n.name != "a" {
+			return
+		}
+		if _, ok := n.attr("href"); ok && n.textContent() == "" && !hasARIALabel(n) {
+			report(n, "%s has no text", describe(n))
+		}
+	})
+}
+
+// checkHeadingOrder reports headings that skip levels on the way down, e.g. an <h4>
+// after an <h2>.
+func checkHeadingOrder(d *document, report reporter) {
+	previous := 0
+	d.root.walk(func(n *node) {
+		level := headingLevel(n)
+		if level == 0 {
+			return
+		}
+		if previous > 0 && level > previous+1 {
+			report(n, "<h%d> follows <h%d>, skipping a heading level", level, previous)
+		}
+		previous = level
+	})
+}
+
+// checkViewportZoom reports a viewport meta tag that stops users zooming in.
+func checkViewportZoom(d *document, report reporter) {
+	for _, n := range d.root.find("meta") {
+		if name, _ := n.attr("name"); !strings.EqualFold(name, "viewport") {
+			continue
+		}
+		content, _ := n.attr("content")
+		for _, param := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' }) {
+			key, value, _ := strings.Cut(param, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.ToLower(strings.TrimSpace(value))
+			switch key {
+			case "user-scalable":
+				if value == "no" || value == "0" {
+					report(n, "the viewport disables zooming with user-scalable=%s", value)
+				}
+			case "maximum-scale":
+				if scale, err := strconv.ParseFloat(value, 64); err == nil && scale < 2 {
+					report(n, "the viewport limits zooming with maximum-scale=%s", value)
+				}
+			}
+		}
+	}
+}
+
+// checkDuplicateIDs reports elements whose id an earlier element already has.
+func checkDuplicateIDs(d *document, report reporter) {
+	seen := make(map[string]int) // Line of the first element with each id
+	d.root.walk(func(n *node) {
+		id, _ := n.attr("id")
+		if id == "" {
+			return
+		}
+		if line, ok := seen[id]; ok {
+			report(n, "id %q is already used on line %d", id, line)
+			return
+		}
+		seen[id] = n.line
+	})
+}
+
+// checkMetaDescription reports a page without a meta description, or with an empty one.
+func checkMetaDescription(d *document, report reporter) {
+	if !d.isPage() {
+		return
+	}
+	meta := d.meta("description")
+	if meta == nil {
+		report(d.pageNode("head"), "the page has no meta description")
+		return
+	}
+	if content, _ := meta.attr("content"); strings.TrimSpace(content) == "" {
+		report(meta, "the page's meta description is empty")
+	}
+}
+
+// checkMetaViewport reports a page without a viewport meta tag, which search engines
+// take to mean it isn't mobile friendly.
+func checkMetaViewport(d *document, report reporter) {
+	if d.isPage() && d.meta("viewport") == nil {
+		report(d.pageNode("head"), "the page has no viewport meta tag")
+	}
+}
+
+// checkSingleH1 reports a page without an <h1>, or with more than one.
+func checkSingleH1(d *document, report reporter) {
+	if !d.isPage() {
+		return
+	}
+	h1s := d.root.find("h1")
+	switch {
+	case len(h1s) == 0:
+		report(d.pageNode("body"), "the page has no <h1>")
+	case len(h1s) > 1:
+		report(h1s[1], "the page has %d <h1> headings; the first is on line %d", len(h1s), h1s[0].line)
+	}
+}
+
+// pageNode returns the first element with a name, falling back to the <html> element
+// and then the document, to report page-level findings at.
+func (d *document) pageNode(name string) *node {
+	for _, candidate := range []string{name, "html"} {
+		if found := d.root.find(candidate); len(found) > 0 {
+			return found[0]
+		}
+	}
+	return d.root
+}
+
+// meta returns the page's first meta tag with a name, or nil.
+func (d *document) meta(name string) *node {
+	for _, n := range d.root.find("meta") {
+		if value, _ := n.attr("name"); strings.EqualFold(value, name) {
+			return n
+		}
+	}
+	return nil
+}
+
+// inputType returns the lowercase type of an input element, "text" by default.
+func inputType(n *node) string {
+	t, _ := n.attr("type")
+	if t = strings.ToLower(strings.TrimSpace(t)); t == "" {
+		return "text"
+	}
+	return t
+}
+
+// hasARIALabel reports whether an element is named by aria-label, aria-labelledby or title.
+func hasARIALabel(n *node) bool {
+	for _, name := range []string{"aria-label", "aria-labelledby", "title"} {
+		if value, _ := n.attr(name); strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// headingLevel returns the level of an <h1> to <h6> element, or 0.
+func headingLevel(n *node) int {
+	if len(n.name) == 2 && n.name[0] == 'h' && '1' <= n.name[1] && n.name[1] <= '6' {
+		return int(n.name[1] - '0')
+	}
+	return 0
+}
+
+// describedAttrs are the attributes describe shows, which usually identify an element.
+var describedAttrs = []string{"type", "id", "name", "src", "href", "class"}
+
+// describe returns an element's start tag with the attributes that identify it, e.g.
+// `<input type="email" name="email">`.
+func describe(n *node) string {
+	var b strings.Builder
+	b.WriteString("<" + n.name)
+	for _, name := range describedAttrs {
+		value, ok := n.attr(name)
+		if !ok {
+			continue
+		}
+		if runes := []rune(value); len(runes) > 40 {
+			value = string(runes[:37]) + "..."
+		}
+		fmt.Fprintf(&b, " %s=%q", name, value)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package htmllint
+
+import (
+	"fmt"
+	"testing"
+)
+
+// lintRule returns the findings of one rule for content, formatted like format.
+func lintRule(t *testing.T, id, content string) string {
+	t.Helper()
+	rule, ok := Lookup(id)
+	if !ok {
+		t.Fatalf("no rule %q", id)
+	}
+	var findings []Finding
+	rule.check(parse(content), func(n *node, format string, args ...interface{}) {
+		findings = append(findings, Finding{Rule: rule, Line: n.line, Message: fmt.Sprintf(format, args...)})
+	})
+	return format(findings)
+}
+
+func TestRules_Findings(t *testing.T) {
+	tests := []struct {
+		rule    string
+		content string
+		want    string
+	}{
+		{"img-alt", `<img src="logo.png"><img src="line.png" alt=""><img src="x.png" aria-label="X">`,
+			`1 img-alt: <img src="logo.png"> has no alt attribute`},
+		{"img-alt", "<input type=image src=go.png>\n<map><area href=a.html><area></map>",
+			`1 img-alt: <input type="image" src="go.png"> has no alt attribute; 2 img-alt: <area href="a.html"> has no alt attribute`},
+		{"html-lang", "<!DOCTYPE html>\n<html lang=\"\"><body></body></html>",
+			"2 html-lang: the page has no lang attribute on its <html> element"},
+		{"html-lang", `<div>fragment</div>`, ""},
+		{"document-title", "<html>\n<head></head></html>", "2 document-title: the page has no <title>"},
+		{"document-title", "<html><head>\n<title> </title></head></html>", "2 document-title: the page's <title> is empty"},
+		{"form-label", "<label for=a>A</label><input id=a>\n<input name=q placeholder=Search>\n<select id=s></select>\n" +
+			"<textarea title=Notes></textarea><input type=hidden><input type=submit>",
+			`2 form-label: <input name="q"> has no label; 3 form-label: <select id="s"> has no label`},
+		{"button-name", "<button>Save</button>\n<button class=\"icon\"><i class=\"x\"></i></button>\n<button aria-label=Close></button>",
+			`2 button-name: <button class="icon"> has no text`},
+		{"link-name", "<a href=\"/\"><img src=home.png alt=Home></a>\n<a href=\"/cart\"><svg></svg></a>\n<a name=top></a>",
+			`2 link-name: <a href="/cart"> has no text`},
+		{"heading-order", "<h1>A</h1>\n<h2>B</h2>\n<h4>C</h4>\n<h2>D</h2>\n<h3>E</h3>",
+			"3 heading-order: <h4> follows <h2>, skipping a heading level"},
+		{"viewport-zoom", "<meta name=\"viewport\" content=\"width=device-width, user-scalable=no, maximum-scale=1.0\">",
+			"1 viewport-zoom: the viewport disables zooming with user-scalable=no; 1 viewport-zoom: the viewport limits zooming with maximum-scale=1.0"},
+		{"viewport-zoom", `<meta name="viewport" content="width=device-width, maximum-scale=5">`, ""},
+		{"duplicate-id", "<div id=main></div>\n<p id=intro></p>\n<section id=main></section>",
+			`3 duplicate-id: id "main" is already used on line 1`},
+		{"meta-description", "<html>\n<head><title>T</title></head></html>", "2 meta-description: the page has no meta description"},
+		{"meta-description", "<html><head>\n<meta name=Description content=\"\"></head></html>", "2 meta-description: the page's meta description is empty"},
+		{"meta-viewport", "<!DOCTYPE html>\n<html><head>\n</head></html>", "2 meta-viewport: the page has no viewport meta tag"},
+		{"single-h1", "<html>\n<body><h2>A</h2></body></html>", "2 single-h1: the page has no <h1>"},
+		{"single-h1", "<html><body><h1>A</h1>\n<h1>B</h1></body></html>", "2 single-h1: the page has 2 <h1> headings; the first is on line 1"},
+	}
+	for _, tt := range tests {
+		if got := lintRule(t, tt.rule, tt.content); got != tt.want {
+			t.Errorf("%s on %q: expected %q, got %q", tt.rule, tt.content, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package htmltoken splits HTML into tokens: text, comments, declarations, start tags and
+// end tags, with their offsets in the source. Like browsers it never fails: markup cut off
+// by the end of the source is returned as far as it goes and marked unclosed, and a "<"
+// that doesn't start markup is text.
+package htmltoken
+
+import (
+	"html"
+	"strings"
+)
+
+// Kind is the kind of a token.
+type Kind int
+
+// Token kinds.
+const (
+	Text        Kind = iota // A run of text, or the content of a raw text element
+	Comment                 // <!-- ... -->
+	Declaration             // A doctype, CDATA section or processing instruction
+	StartTag
+	EndTag
+)
+
+// voidElements have no content and no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text rather than markup up to their end tag.
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true, "xmp": true}
+
+// IsVoid reports whether an element, by lowercase name, has no content and no end tag.
+func IsVoid(name string) bool {
+	return voidElements[name]
+}
+
+// IsRawText reports whether an element, by lowercase name, holds text rather than markup
+// up to its end tag.
+func IsRawText(name string) bool {
+	return rawTextElements[name]
+}
+
+// Attr is an attribute of a start tag.
+type Attr struct {
+	Name       string // Lowercase
+	Value      string // Unescaped
+	ValueStart int    // Offset of the raw value in the source; the end of the name without a value
+	ValueEnd   int
+	Unclosed   bool // The quoted value runs to the end of the source
+}
+
+// Token is a token of an HTML source.
+type Token struct {
+	Kind        Kind
+	Name        string // Lowercase name of a start or end tag, or "doctype" for a doctype declaration
+	Attrs       []Attr
+	SelfClosing bool // The start tag ends with "/>"
+	Raw         bool // Text that is the content of a raw text element, such as a script
+	Start, End  int  // Offsets of the token in the source
+	Unclosed    bool // The token runs to the end of the source without its closing delimiter
+}
+
+// Attr returns the first attribute of the token with a lowercase name.
+func (t *Token) Attr(name string) (Attr, bool) {
+	for _, a := range t.Attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attr{}, false
+}
+
+// Tokenize returns the tokens of an HTML source in order. Runs of text between markup are
+// one token each, and the start tag of a raw text element, such as a script, that isn't
+// self-closing is always followed by a raw text token with its content, which may be empty.
+func Tokenize(content string) []Token {
+	var tokens []Token
+	text := 0 // Start of the text not yet in a token
+	addText := func(end int) {
+		if end > text {
+			tokens = append(tokens, Token{Kind: Text, Start: text, End: end})
+		}
+	}
+
+	for i := 0; i < len(content); {
+		lt := strings.IndexByte(content[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt
+
+		tok, ok := markup(content, i)
+		if !ok {
+			i++
+			continue
+		}
+		addText(i)
+		tokens = append(tokens, tok)
+		i, text = tok.End, tok.End
+
+		if tok.Kind == StartTag && rawTextElements[tok.Name] && !tok.SelfClosing {
+			raw := Token{Kind: Text, Raw: true, Start: i, End: len(content), Unclosed: true}
+			if closing := indexFold(content[i:], "</"+tok.Name); closing >= 0 {
+				raw.End, raw.Unclosed = i+closing, false
+			}
+			tokens = append(tokens, raw)
+			i, text = raw.End, raw.End
+		}
+	}
+	addText(len(content))
+	return tokens
+}
+
+// markup returns the markup token at content[i], a "<", or false if it is text.
+func markup(content string, i int) (Token, bool) {
+	switch {
+	case strings.HasPrefix(content[i:], "<!--"):
+		return closeAt(Token{Kind: Comment, Start: i}, content, i+4, "-->"), true
+	case i+1 < len(content) && (content[i+1] == '!' || content[i+1] == '?'):
+		tok := Token{Kind: Declaration, Start: i}
+		if hasPrefixFold(content[i+2:], "doctype") {
+			tok.Name = "doctype"
+		}
+		return closeAt(tok, content, i+2, ">"), true
+	case i+2 < len(content) && content[i+1] == '/' && isLetter(content[i+2]):
+		name, end := tagName(content, i+2)
+		return closeAt(Token{Kind: EndTag, Name: name, Start: i}, content, end, ">"), true
+	case i+1 < len(content) && isLetter(content[i+1]):
+		return startTag(content, i), true
+	}
+	return Token{}, false
+}
+
+// closeAt ends a token after the first instance of its closing delimiter from content[i],
+// or at the end of the source if there is none.
+func closeAt(tok Token, content string, i int, delimiter string) Token {
+	if closing := strings.Index(content[i:], delimiter); closing >= 0 {
+		tok.End = i + closing + len(delimiter)
+	} else {
+		tok.End, tok.Unclosed = len(content), true
+	}
+	return tok
+}
+
+// startTag parses the start tag at content[i].
+func startTag(content string, i int) Token {
+	name, j := tagName(content, i+1)
+	tok := Token{Kind: StartTag, Name: name, Start: i}
+
+	for {
+		for j < len(content) && isSpace(content[j]) {
+			j++
+		}
+		switch {
+		case j >= len(content):
+			tok.End, tok.Unclosed = j, true
+			return tok
+		case content[j] == '>':
+			tok.End = j + 1
+			return tok
+		case strings.HasPrefix(content[j:], "/>"):
+			tok.End, tok.SelfClosing = j+2, true
+			return tok
+		case content[j] == '/':
+			j++
+			continue
+		}
+
+		// Attribute name
+		k := j
+		for k < len(content) && !isSpace(content[k]) && content[k] != '=' && content[k] != '>' && (content[k] != '/' || k == j) {
+			k++
+		}
+		a := Attr{Name: strings.ToLower(content[j:k]), ValueStart: k, ValueEnd: k}
+		for j = k; j < len(content) && isSpace(content[j]); j++ {
+		}
+
+		// Attribute value
+		if j < len(content) && content[j] == '=' {
+			for j++; j < len(content) && isSpace(content[j]); j++ {
+			}
+			if j < len(content) && (content[j] == '"' || content[j] == '\'') {
+				a.ValueStart, a.ValueEnd = j+1, len(content)
+				if closing := strings.IndexByte(content[j+1:], content[j]); closing >= 0 {
+					a.ValueEnd = j + 1 + closing
+				} else {
+					a.Unclosed = true
+				}
+				j = min(a.ValueEnd+1, len(content))
+			} else {
+				k = j
+				for k < len(content) && !isSpace(content[k]) && content[k] != '>' {
+					k++
+				}
+				a.ValueStart, a.ValueEnd = j, k
+				j = k
+			}
+			a.Value = html.UnescapeString(content[a.ValueStart:a.ValueEnd])
+		}
+		tok.Attrs = append(tok.Attrs, a)
+	}
+}
+
+// tagName reads the lowercase tag name starting at content[i] and returns it with the offset after it.
+func tagName(content string, i int) (string, int) {
+	j := i
+	for j < len(content) && isNameByte(content[j]) {
+		j++
+	}
+	return strings.ToLower(content[i:j]), j
+}
+
+// indexFold returns the index of the first instance of substr in s, ignoring ASCII case, or -1.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasPrefixFold reports whether s begins with prefix, ignoring ASCII case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isSpace reports whether c is HTML whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isNameByte reports whether c can be part of a tag name.
+func isNameByte(c byte) bool {
+	return isLetter(c) || '0' <= c && c <= '9' || c == '-' || c == ':' || c == '_'
+}
@@ -0,0 +1,96 @@
+package htmltoken
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format renders tokens as "kind name source" entries for compact comparison, with "!"
+// marking unclosed tokens and "raw" raw text.
+func format(content string, tokens []Token) string {
+	kinds := map[Kind]string{Text: "text", Comment: "comment", Declaration: "decl", StartTag: "start", EndTag: "end"}
+	var parts []string
+	for _, tok := range tokens {
+		kind := kinds[tok.Kind]
+		if tok.Raw {
+			kind = "raw"
+		}
+		if tok.Unclosed {
+			kind += "!"
+		}
+		if tok.Name != "" {
+			kind += " " + tok.Name
+		}
+		parts = append(parts, fmt.Sprintf("%s %q", kind, content[tok.Start:tok.End]))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestTokenize(t *testing.T) {
+	content := `<!DOCTYPE html><P Class="a">x < y<!-- <b> --><br/><SCRIPT>if (a</b) {}</Script><xmp><i></xmp></p>`
+
+	want := strings.Join([]string{
+		`decl doctype "<!DOCTYPE html>"`,
+		`start p "<P Class=\"a\">"`,
+		`text "x < y"`,
+		`comment "<!-- <b> -->"`,
+		`start br "<br/>"`,
+		`start script "<SCRIPT>"`,
+		`raw "if (a</b) {}"`,
+		`end script "</Script>"`,
+		`start xmp "<xmp>"`,
+		`raw "<i>"`,
+		`end xmp "</xmp>"`,
+		`end p "</p>"`,
+	}, "\n")
+	if got := format(content, Tokenize(content)); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTokenize_Attributes(t *testing.T) {
+	content := `<img SRC = "a&amp;b.png" alt='say "hi"' hidden data-x=1 / >`
+	tokens := Tokenize(content)
+	if len(tokens) != 1 || tokens[0].Kind != StartTag || tokens[0].Unclosed {
+		t.Fatalf("expected one start tag, got:\n%s", format(content, tokens))
+	}
+
+	var attrs []string
+	for _, a := range tokens[0].Attrs {
+		attrs = append(attrs, fmt.Sprintf("%s=%q@%q", a.Name, a.Value, content[a.ValueStart:a.ValueEnd]))
+	}
+	want := `src="a&b.png"@"a&amp;b.png"; alt="say \"hi\""@"say \"hi\""; hidden=""@""; data-x="1"@"1"`
+	if got := strings.Join(attrs, "; "); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if a, ok := tokens[0].Attr("alt"); !ok || a.Value != `say "hi"` {
+		t.Errorf("expected the alt attribute, got %+v", a)
+	}
+}
+
+func TestTokenize_Unclosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"comment", "a<!-- b", "text \"a\"\ncomment! \"<!-- b\""},
+		{"end tag", "</div", "end! div \"</div\""},
+		{"start tag", "<div\n  class=\"a\"", "start! div \"<div\\n  class=\\\"a\\\"\""},
+		{"raw text", "<script>let a", "start script \"<script>\"\nraw! \"let a\""},
+		{"self-closing raw text", "<script/>a", "start script \"<script/>\"\ntext \"a\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(tt.content, Tokenize(tt.content)); got != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+
+	tokens := Tokenize(`<div title="x>`)
+	if a := tokens[0].Attrs[0]; !a.Unclosed || a.ValueStart != 12 {
+		t.Errorf("expected an unclosed value at 12, got %+v", a)
+	}
+}
@@ -0,0 +1,209 @@
+// Package outline extracts the top-level structure of source files: headings,
+// functions, classes, selectors and keys, with their line numbers.
+package outline
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Symbol is one entry in a file outline.
+type Symbol struct {
+	Kind  string `json:"kind"`  // e.g. "h1", "function", "class", "selector", "key"
+	Name  string `json:"name"`  // Heading text, identifier, selector or key
+	Line  int    `json:"line"`  // 1-based line number
+	Level int    `json:"level"` // Nesting level for headings (1-6); 0 otherwise
+}
+
+// maxNameLength truncates long headings and selectors.
+const maxNameLength = 80
+
+// linePattern maps a regular expression over a single line to a symbol kind.
+// The expression's last non-empty submatch is the symbol name.
+type linePattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+var (
+	scriptPatterns = []linePattern{
+		{"function", regexp.MustCompile(`^(?:export\s+(?:default\s+)?)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)},
+		{"class", regexp.MustCompile(`^(?:export\s+(?:default\s+)?)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)},
+		{"interface", regexp.MustCompile(`^(?:export\s+)?interface\s+([A-Za-z_$][\w$]*)`)},
+		{"type", regexp.MustCompile(`^(?:export\s+)?type\s+([A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`)},
+		{"enum", regexp.MustCompile(`^(?:export\s+)?(?:const\s+)?enum\s+([A-Za-z_$][\w$]*)`)},
+		{"variable", regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)`)},
+	}
+	goPatterns = []linePattern{
+		{"function", regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`)},
+		{"type", regexp.MustCompile(`^type\s+([A-Za-z_]\w*)`)},
+	}
+	pythonPatterns = []linePattern{
+		{"function", regexp.MustCompile(`^(?:async\s+)?def\s+([A-Za-z_]\w*)`)},
+		{"class", regexp.MustCompile(`^class\s+([A-Za-z_]\w*)`)},
+	}
+
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	htmlHeading     = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]>`)
+	htmlLandmark    = regexp.MustCompile(`(?i)<(header|nav|main|section|article|aside|footer|form)\b([^>]*)>`)
+	htmlID          = regexp.MustCompile(`(?i)\bid\s*=\s*["']([^"']+)["']`)
+	htmlTag         = regexp.MustCompile(`<[^>]*>`)
+	cssComment      = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// Extract returns the outline of content for a language as used by the file store
+// ("html", "css", "javascript", "typescript", "tsx", "jsx", "go", "python", "markdown", "json").
+// Other languages return nil.
+func Extract(language, content string) []Symbol {
+	switch language {
+	case "html", "htm":
+		return extractHTML(content)
+	case "css", "scss", "less":
+		return extractCSS(content)
+	case "javascript", "typescript", "tsx", "jsx":
+		return extractLines(content, scriptPatterns)
+	case "go":
+		return extractLines(content, goPatterns)
+	case "python":
+		return extractLines(content, pythonPatterns)
+	case "markdown":
+		return extractMarkdown(content)
+	case "json":
+		return extractJSON(content)
+	default:
+		return nil
+	}
+}
+
+// extractLines matches top-level declarations: lines that start at column 0.
+func extractLines(content string, patterns []linePattern) []Symbol {
+	var symbols []Symbol
+	for i, line := range strings.Split(content, "\n") {
+		for _, p := range patterns {
+			if m := p.pattern.FindStringSubmatch(line); m != nil {
+				symbols = append(symbols, Symbol{Kind: p.kind, Name: m[len(m)-1], Line: i + 1})
+				break
+			}
+		}
+	}
+	return symbols
+}
+
+// extractMarkdown returns headings, skipping fenced code blocks.
+func extractMarkdown(content string) []Symbol {
+	var symbols []Symbol
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			symbols = append(symbols, Symbol{Kind: "h" + string(rune('0'+len(m[1]))), Name: truncate(m[2]), Line: i + 1, Level: len(m[1])})
+		}
+	}
+	return symbols
+}
+
+// extractHTML returns headings and landmark elements (with their id, if any), in document order.
+func extractHTML(content string) []Symbol {
+	var symbols []Symbol
+
+	for _, m := range htmlHeading.FindAllStringSubmatchIndex(content, -1) {
+		level := int(content[m[2]] - '0')
+		text := strings.Join(strings.Fields(htmlTag.ReplaceAllString(content[m[4]:m[5]], "")), " ")
+		if text == "" {
+			continue
+		}
+		symbols = append(symbols, Symbol{Kind: "h" + content[m[2]:m[3]], Name: truncate(text), Line: lineAt(content, m[0]), Level: level})
+	}
+
+	for _, m := range htmlLandmark.FindAllStringSubmatchIndex(content, -1) {
+		name := strings.ToLower(content[m[2]:m[3]])
+		if id := htmlID.FindStringSubmatch(content[m[4]:m[5]]); id != nil {
+			name += "#" + id[1]
+		}
+		symbols = append(symbols, Symbol{Kind: "element", Name: name, Line: lineAt(content, m[0])})
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].Line < symbols[j].Line })
+	return symbols
+}
+
+// extractCSS returns top-level selectors and at-rules (e.g. @media).
+func extractCSS(content string) []Symbol {
+	// Blank out comments but keep newlines so line numbers stay correct
+	content = cssComment.ReplaceAllStringFunc(content, func(c string) string {
+		return strings.Repeat("\n", strings.Count(c, "\n"))
+	})
+
+	var symbols []Symbol
+	depth := 0
+	start := 0 // Offset where the current prelude (text before "{") starts
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '{':
+			if depth == 0 {
+				prelude := strings.TrimSpace(content[start:i])
+				if prelude != "" {
+					kind := "selector"
+					if strings.HasPrefix(prelude, "@") {
+						kind = "at-rule"
+					}
+					offset := start + strings.Index(content[start:i], prelude)
+					symbols = append(symbols, Symbol{Kind: kind, Name: truncate(strings.Join(strings.Fields(prelude), " ")), Line: lineAt(content, offset)})
+				}
+			}
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 {
+				start = i + 1
+			}
+		case ';':
+			if depth == 0 {
+				start = i + 1 // e.g. @import
+			}
+		}
+	}
+	return symbols
+}
+
+// extractJSON returns the top-level keys of an object, in file order.
+func extractJSON(content string) []Symbol {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &object); err != nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	for key := range object {
+		quoted, _ := json.Marshal(key)
+		offset := strings.Index(content, string(quoted))
+		if offset < 0 {
+			continue
+		}
+		symbols = append(symbols, Symbol{Kind: "key", Name: key, Line: lineAt(content, offset)})
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Line < symbols[j].Line })
+	return symbols
+}
+
+// lineAt returns the 1-based line number of a byte offset.
+func lineAt(content string, offset int) int {
+	return strings.Count(content[:offset], "\n") + 1
+}
+
+func truncate(s string) string {
+	if len(s) <= maxNameLength {
+		return s
+	}
+	return s[:maxNameLength-3] + "..."
+}
@@ -0,0 +1,124 @@
+package outline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format renders symbols as "line kind name" entries for compact comparison.
+func format(symbols []Symbol) string {
+	var parts []string
+	for _, s := range symbols {
+		parts = append(parts, fmt.Sprintf("%d %s %s", s.Line, s.Kind, s.Name))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestExtract_HTML(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html>
+<body>
+  <header id="top">
+    <h1>Cake <em>Orders</em></h1>
+  </header>
+  <main>
+    <section id="menu">
+      <h2 class="title">Menu</h2>
+    </section>
+  </main>
+</body>
+</html>`
+
+	got := format(Extract("html", content))
+	want := "4 element header#top; 5 h1 Cake Orders; 7 element main; 8 element section#menu; 9 h2 Menu"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtract_CSS(t *testing.T) {
+	content := `/* Base { styles } */
+body {
+  margin: 0;
+}
+
+.card,
+.card:hover { color: red; }
+
+@media (max-width: 600px) {
+  .card { display: block; }
+}`
+
+	got := format(Extract("css", content))
+	want := "2 selector body; 6 selector .card, .card:hover; 9 at-rule @media (max-width: 600px)"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtract_JavaScript(t *testing.T) {
+	content := `import { api } from './api.js';
+
+export const API_URL = '/api';
+
+export default async function loadOrders() {
+  const local = 1;
+  function nested() {}
+}
+
+class OrderList {
+}
+
+export interface Order {}
+type Status = 'open' | 'done';`
+
+	got := format(Extract("typescript", content))
+	want := "3 variable API_URL; 5 function loadOrders; 10 class OrderList; 13 interface Order; 14 type Status"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtract_Markdown(t *testing.T) {
+	content := "# Title\n\nText\n\n```bash\n# not a heading\n```\n\n## Usage ##\n"
+
+	symbols := Extract("markdown", content)
+
+	if got, want := format(symbols), "1 h1 Title; 9 h2 Usage"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if symbols[1].Level != 2 {
+		t.Errorf("expected level 2, got %d", symbols[1].Level)
+	}
+}
+
+func TestExtract_GoAndPython(t *testing.T) {
+	goSource := "package main\n\ntype Server struct{}\n\nfunc (s *Server) Run() {}\n\nfunc main() {}\n"
+	if got, want := format(Extract("go", goSource)), "3 type Server; 5 function Run; 7 function main"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	pySource := "class App:\n    def method(self):\n        pass\n\nasync def main():\n    pass\n"
+	if got, want := format(Extract("python", pySource)), "1 class App; 5 function main"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtract_JSON(t *testing.T) {
+	content := "{\n  \"name\": \"app\",\n  \"scripts\": {\"start\": \"x\"},\n  \"version\": \"1.0.0\"\n}"
+
+	if got, want := format(Extract("json", content)), "2 key name; 3 key scripts; 4 key version"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if symbols := Extract("json", "[1, 2]"); symbols != nil {
+		t.Errorf("expected nil for a non-object, got %v", symbols)
+	}
+}
+
+func TestExtract_UnknownLanguage(t *testing.T) {
+	if symbols := Extract("cobol", "IDENTIFICATION DIVISION."); symbols != nil {
+		t.Errorf("expected nil, got %v", symbols)
+	}
+}
@@ -0,0 +1,136 @@
+package refs
+
+import (
+	"path"
+	"strings"
+)
+
+// fontExtensions are the extensions of fonts loaded through url().
+var fontExtensions = map[string]bool{".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true}
+
+// extractCSS returns the @import and url() references of a stylesheet.
+func extractCSS(filePath, content string, project *Project) []Reference {
+	return scanCSS(newSource(filePath, content), 0, len(content))
+}
+
+// scanCSS returns the @import and url() references in s.content[start:end], skipping
+// comments and strings. It also scans style elements and attributes of HTML pages.
+func scanCSS(s *source, start, end int) []Reference {
+	var refs []Reference
+	text := s.content
+
+	add := func(offset int, value, kind string) {
+		if ref, ok := s.urlRef(offset, value, kind); ok {
+			refs = append(refs, ref)
+		}
+	}
+
+	for i := start; i < end; {
+		switch c := text[i]; {
+		case c == '/' && i+1 < end && text[i+1] == '*':
+			closing := strings.Index(text[i+2:end], "*/")
+			if closing < 0 {
+				return refs
+			}
+			i += closing + 4
+		case c == '"' || c == '\'':
+			_, i = quotedString(text, i, end)
+		case c == '@' && hasPrefixFold(text[i:end], "@import"):
+			j := skipSpace(text, i+len("@import"), end)
+			switch {
+			case j < end && (text[j] == '"' || text[j] == '\''):
+				value, next := quotedString(text, j, end)
+				add(j, value, "stylesheet")
+				i = next
+			case hasPrefixFold(text[j:end], "url("):
+				value, next := cssURL(text, j, end)
+				add(j, value, "stylesheet")
+				i = next
+			default:
+				i = j
+			}
+		case (c == 'u' || c == 'U') && hasPrefixFold(text[i:end], "url(") && (i == start || !isNameByte(text[i-1])):
+			value, next := cssURL(text, i, end)
+			kind := "image"
+			if resolved, ok := Resolve(s.path, value); ok && fontExtensions[strings.ToLower(path.Ext(resolved))] {
+				kind = "font"
+			}
+			add(i, value, kind)
+			i = next
+		default:
+			i++
+		}
+	}
+	return refs
+}
+
+// quotedString reads the CSS or JavaScript string starting at text[i] and returns its
+// value and the offset after it. An unterminated string ends at the end of its line.
+func quotedString(text string, i, end int) (string, int) {
+	quote := text[i]
+	var value strings.Builder
+	for j := i + 1; j < end; j++ {
+		switch text[j] {
+		case quote:
+			return value.String(), j + 1
+		case '\n':
+			return value.String(), j
+		case '\\':
+			if j+1 < end {
+				j++
+				value.WriteByte(text[j])
+			}
+		default:
+			value.WriteByte(text[j])
+		}
+	}
+	return value.String(), end
+}
+
+// cssURL reads the url() starting at text[i] and returns its value and the offset after it.
+func cssURL(text string, i, end int) (string, int) {
+	j := skipSpace(text, i+len("url("), end)
+	var value string
+	if j < end && (text[j] == '"' || text[j] == '\'') {
+		value, j = quotedString(text, j, end)
+	} else {
+		k := j
+		for k < end && text[k] != ')' && !isSpace(text[k]) {
+			k++
+		}
+		value, j = text[j:k], k
+	}
+	if closing := strings.IndexByte(text[j:end], ')'); closing >= 0 {
+		j += closing + 1
+	}
+	return strings.TrimSpace(value), j
+}
+
+// hasPrefixFold reports whether s begins with prefix, ignoring ASCII case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// skipSpace returns the offset of the first non-space byte of text[i:end], or end.
+func skipSpace(text string, i, end int) int {
+	for i < end && isSpace(text[i]) {
+		i++
+	}
+	return i
+}
+
+// isSpace reports whether c is ASCII white space.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isNameByte reports whether c can be part of an identifier or tag name.
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || c == '$' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,30 @@
+package refs
+
+import "testing"
+
+func TestExtractCSS(t *testing.T) {
+	content := `@import "base.css";
+@import url('theme/dark.css') screen;
+/* url(commented.png) */
+body {
+  background: url(../img/bg.png) no-repeat;
+  content: "url(not-a-url.png)";
+}
+@font-face {
+  font-family: Brand;
+  src: URL("/fonts/brand.woff2?v=1") format("woff2"),
+       url(https://cdn.example.com/brand.ttf);
+}
+.logo { mask: url(data:image/svg+xml;base64,AAAA); }
+.x { background-image: image-set(url(a.png) 1x); }`
+
+	got := format(extract("css/site.css", content, nil))
+	want := "1 stylesheet base.css -> css/base.css; " +
+		"2 stylesheet theme/dark.css -> css/theme/dark.css; " +
+		"5 image ../img/bg.png -> img/bg.png; " +
+		"10 font /fonts/brand.woff2?v=1 -> fonts/brand.woff2; " +
+		"14 image a.png -> css/a.png"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,128 @@
+package refs
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goModulePattern matches the module directive of a go.mod file.
+var goModulePattern = regexp.MustCompile(`(?m)^\s*module\s+"?([^\s"]+)"?`)
+
+// extractGo returns the imports of a Go file that name packages of its own module, found
+// through the nearest go.mod. Each must be a directory of the project holding files.
+func extractGo(filePath, content string, project *Project) []Reference {
+	moduleDir, modulePath, ok := goModule(filePath, project)
+	if !ok {
+		return nil
+	}
+
+	s := newSource(filePath, content)
+	var refs []Reference
+	for _, imp := range goImports(content) {
+		if imp.path != modulePath && !strings.HasPrefix(imp.path, modulePath+"/") {
+			continue
+		}
+		dir := path.Join(moduleDir, strings.TrimPrefix(imp.path, modulePath))
+		refs = append(refs, s.ref(imp.offset, imp.path, "import", dir+"/"))
+	}
+	return refs
+}
+
+// goModule returns the directory and module path of the go.mod closest to filePath.
+func goModule(filePath string, project *Project) (dir, modulePath string, ok bool) {
+	for dir = path.Dir(filePath); ; dir = path.Dir(dir) {
+		if content, found := project.Content(path.Join(dir, "go.mod")); found {
+			if m := goModulePattern.FindStringSubmatch(content); m != nil {
+				return dir, m[1], true
+			}
+			return "", "", false
+		}
+		if dir == "." || dir == "/" {
+			return "", "", false
+		}
+	}
+}
+
+// goImport is an import path of a Go file.
+type goImport struct {
+	path   string
+	offset int
+}
+
+// goImports returns the import paths of Go source, from single imports and import
+// blocks, skipping comments.
+func goImports(text string) []goImport {
+	var imports []goImport
+	inImport, inBlock, inPackage := false, false, false
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '/' && i+1 < len(text) && text[i+1] == '/':
+			i = lineEnd(text, i, len(text))
+		case c == '/' && i+1 < len(text) && text[i+1] == '*':
+			closing := strings.Index(text[i+2:], "*/")
+			if closing < 0 {
+				return imports
+			}
+			i += closing + 4
+		case c == '"' || c == '`':
+			end := goStringEnd(text, i)
+			if inImport {
+				if value, err := strconv.Unquote(text[i:end]); err == nil {
+					imports = append(imports, goImport{path: value, offset: i})
+				}
+				inImport = inBlock
+			}
+			i = end
+		case c == '\'':
+			i = goStringEnd(text, i)
+		case c == '(' && inImport && !inBlock:
+			inBlock = true
+			i++
+		case c == ')' && inBlock:
+			inImport, inBlock = false, false
+			i++
+		case isLetter(c) || c == '_':
+			j := i
+			for j < len(text) && isNameByte(text[j]) && text[j] != '-' && text[j] != '$' {
+				j++
+			}
+			switch word := text[i:j]; {
+			case word == "import":
+				inImport = true
+			case word == "package":
+				inPackage = true
+			case inPackage:
+				inPackage = false
+			case inImport:
+				// Import name such as "_" or an alias
+			default:
+				// Imports come before all declarations
+				return imports
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	return imports
+}
+
+// goStringEnd returns the offset after the string, raw string or rune literal at text[i].
+func goStringEnd(text string, i int) int {
+	quote := text[i]
+	for j := i + 1; j < len(text); j++ {
+		switch {
+		case text[j] == '\\' && quote != '`':
+			j++
+		case text[j] == quote:
+			return j + 1
+		case text[j] == '\n' && quote != '`':
+			return j
+		}
+	}
+	return len(text)
+}
@@ -0,0 +1,39 @@
+package refs
+
+import "testing"
+
+func TestExtractGo(t *testing.T) {
+	content := `// Package main runs the server.
+package main
+
+import "fmt"
+
+import (
+	"net/http"
+
+	// "example.com/shop/commented"
+	api "example.com/shop/internal/api"
+	_ ` + "`example.com/shop/internal/db`" + `
+	"example.com/shopping/other"
+)
+
+func main() {
+	fmt.Println("import \"example.com/shop/after\"")
+}`
+
+	others := map[string]string{"go.mod": "module example.com/shop\n\ngo 1.22\n"}
+	got := format(extract("cmd/server/main.go", content, others))
+	want := "10 import example.com/shop/internal/api -> internal/api/; " +
+		"11 import example.com/shop/internal/db -> internal/db/"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractGo_WithoutModule(t *testing.T) {
+	content := "package main\n\nimport \"example.com/shop/internal/api\"\n"
+
+	if got := extract("main.go", content, nil); len(got) != 0 {
+		t.Errorf("expected no references without a go.mod, got %s", format(got))
+	}
+}
@@ -0,0 +1,164 @@
+package refs
+
+import (
+	"path"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/htmltoken"
+)
+
+// scriptTypes are the script element types whose content is JavaScript.
+var scriptTypes = map[string]bool{"": true, "module": true, "text/javascript": true, "application/javascript": true}
+
+// extractHTML returns the references of an HTML page: scripts, stylesheets, icons and the
+// manifest, images (including srcset candidates), media, frames and links to other pages,
+// plus the references of inline styles and inline scripts.
+func extractHTML(filePath, content string, project *Project) []Reference {
+	s := newSource(filePath, content)
+	var refs []Reference
+	var element htmltoken.Token // Start tag of the raw text element a raw text token is the content of
+
+	for _, tok := range htmltoken.Tokenize(content) {
+		switch {
+		case tok.Kind == htmltoken.StartTag:
+			refs = append(refs, s.tagRefs(tok)...)
+			element = tok
+		case tok.Raw && element.Name == "style":
+			refs = append(refs, scanCSS(s, tok.Start, tok.End)...)
+		case tok.Raw && element.Name == "script":
+			scriptType, _ := element.Attr("type")
+			if _, hasSrc := element.Attr("src"); !hasSrc && scriptTypes[strings.ToLower(strings.TrimSpace(scriptType.Value))] {
+				refs = append(refs, scanJavaScript(s, tok.Start, tok.End)...)
+			}
+		}
+	}
+	return refs
+}
+
+// tagRefs returns the references made by the attributes of a start tag.
+func (s *source) tagRefs(tag htmltoken.Token) []Reference {
+	var refs []Reference
+	add := func(name, kind string) {
+		if a, ok := tag.Attr(name); ok {
+			if ref, ok := s.urlRef(a.ValueStart, a.Value, kind); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	switch tag.Name {
+	case "script":
+		add("src", "script")
+	case "link":
+		if kind := linkKind(tag); kind != "" {
+			add("href", kind)
+		}
+	case "img":
+		add("src", "image")
+		refs = append(refs, s.srcsetRefs(tag)...)
+	case "source":
+		add("src", "media")
+		refs = append(refs, s.srcsetRefs(tag)...)
+	case "video":
+		add("src", "media")
+		add("poster", "image")
+	case "audio", "track", "embed":
+		add("src", "media")
+	case "input":
+		if t, _ := tag.Attr("type"); strings.EqualFold(t.Value, "image") {
+			add("src", "image")
+		}
+	case "iframe", "a", "area":
+		name := "href"
+		if tag.Name == "iframe" {
+			name = "src"
+		}
+		if a, ok := tag.Attr(name); ok {
+			if ref, ok := s.pageRef(a.ValueStart, a.Value); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	if a, ok := tag.Attr("style"); ok {
+		refs = append(refs, scanCSS(s, a.ValueStart, a.ValueEnd)...)
+	}
+	return refs
+}
+
+// linkKind returns the reference type of a link element's href, or "" for links that
+// don't load a file, such as preconnect hints or alternate pages.
+func linkKind(tag htmltoken.Token) string {
+	rel, _ := tag.Attr("rel")
+	as, _ := tag.Attr("as")
+	for _, token := range strings.Fields(strings.ToLower(rel.Value)) {
+		switch token {
+		case "stylesheet":
+			return "stylesheet"
+		case "icon", "apple-touch-icon", "mask-icon":
+			return "image"
+		case "manifest":
+			return "manifest"
+		case "modulepreload":
+			return "script"
+		case "preload":
+			switch strings.ToLower(as.Value) {
+			case "script":
+				return "script"
+			case "style":
+				return "stylesheet"
+			case "font":
+				return "font"
+			case "image":
+				return "image"
+			}
+		}
+	}
+
+	// Stylesheets are recognized by their extension without a rel
+	if href, ok := tag.Attr("href"); ok && rel.Value == "" {
+		if resolved, ok := Resolve("", href.Value); ok && strings.EqualFold(path.Ext(resolved), ".css") {
+			return "stylesheet"
+		}
+	}
+	return ""
+}
+
+// srcsetRefs returns the image references of a tag's srcset candidates.
+func (s *source) srcsetRefs(tag htmltoken.Token) []Reference {
+	a, ok := tag.Attr("srcset")
+	if !ok || strings.HasPrefix(strings.TrimSpace(a.Value), "data:") {
+		return nil
+	}
+
+	var refs []Reference
+	for _, candidate := range strings.Split(a.Value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if ref, ok := s.urlRef(a.ValueStart, fields[0], "image"); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// pageRef creates a link reference to a page. Like the preview server, a link without
+// an extension may name an HTML file or a directory with an index.html.
+func (s *source) pageRef(offset int, written string) (Reference, bool) {
+	ref, ok := s.urlRef(offset, written, "link")
+	if !ok {
+		return Reference{}, false
+	}
+
+	resolved := ref.Targets[0]
+	written = strings.SplitN(strings.SplitN(written, "#", 2)[0], "?", 2)[0]
+	switch {
+	case resolved == "." || resolved == "" || strings.HasSuffix(written, "/"):
+		ref.Targets = []string{path.Join(resolved, "index.html")}
+	case path.Ext(resolved) == "":
+		ref.Targets = []string{resolved + ".html", path.Join(resolved, "index.html"), resolved}
+	}
+	return ref, true
+}
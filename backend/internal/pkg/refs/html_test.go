@@ -0,0 +1,65 @@
+package refs
+
+import "testing"
+
+func TestExtractHTML(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html>
+<head>
+  <link
+    rel="stylesheet"
+    href="css/site.css">
+  <link rel="icon" href="/favicon.ico">
+  <link rel="preconnect" href="https://fonts.example.com">
+  <link rel="manifest" href="manifest.json">
+  <style>
+    body { background: url('img/bg.png'); }
+  </style>
+  <!-- <script src="commented.js"></script> -->
+</head>
+<body style="background-image: url(img/body.png)">
+  <img src="img/logo.png" srcset="img/logo@2x.png 2x, img/logo@3x.png 3x" alt="Logo">
+  <a href="about">About</a>
+  <a href="#top">Top</a>
+  <a href="https://example.com">External</a>
+  <img src="{{ avatar }}">
+  <video src="media/intro.mp4" poster="img/poster.jpg"></video>
+  <script type="module">
+    import { start } from "./js/main.js";
+    const html = "<script src='in-string.js'></script>";
+  </script>
+  <script src="js/app.js" defer></script>
+  <textarea><img src="not-markup.png"></textarea>
+</body>
+</html>`
+
+	got := format(extract("index.html", content, nil))
+	want := "6 stylesheet css/site.css -> css/site.css; " +
+		"7 image /favicon.ico -> favicon.ico; " +
+		"9 manifest manifest.json -> manifest.json; " +
+		"11 image img/bg.png -> img/bg.png; " +
+		"15 image img/body.png -> img/body.png; " +
+		"16 image img/logo.png -> img/logo.png; " +
+		"16 image img/logo@2x.png -> img/logo@2x.png; " +
+		"16 image img/logo@3x.png -> img/logo@3x.png; " +
+		"17 link about -> about.html|about/index.html|about; " +
+		"21 media media/intro.mp4 -> media/intro.mp4; " +
+		"21 image img/poster.jpg -> img/poster.jpg; " +
+		"23 import ./js/main.js -> js/main.js; " +
+		"26 script js/app.js -> js/app.js"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractHTML_Subdirectory(t *testing.T) {
+	content := `<LINK HREF="../css/site.css"><a href="./">Home</a><IMG SRC=../img/a.png>`
+
+	got := format(extract("pages/about.html", content, nil))
+	want := "1 stylesheet ../css/site.css -> css/site.css; " +
+		"1 link ./ -> pages/index.html; " +
+		"1 image ../img/a.png -> img/a.png"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
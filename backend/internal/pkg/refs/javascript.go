@@ -0,0 +1,282 @@
+package refs
+
+import (
+	"path"
+	"strings"
+)
+
+// Extensions tried, in order, for an import without one; TypeScript files prefer TypeScript.
+var (
+	scriptExtensions     = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"}
+	typeScriptExtensions = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs"}
+)
+
+// regexPrecedingKeywords are the keywords after which a slash starts a regular expression.
+var regexPrecedingKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// jsTokenKind is the kind of a JavaScript token.
+type jsTokenKind int
+
+const (
+	jsName    jsTokenKind = iota // Identifier, keyword or number
+	jsString                     // String literal; value is its content
+	jsPunct                      // Punctuator, one byte
+	jsLiteral                    // Template literal or regular expression; value is empty
+)
+
+// jsToken is a JavaScript token. Comments are skipped.
+type jsToken struct {
+	kind   jsTokenKind
+	value  string
+	offset int
+}
+
+// is reports whether the token is a name or punctuator with the given value.
+func (t jsToken) is(value string) bool {
+	return t.kind != jsString && t.value == value
+}
+
+// extractJavaScript returns the relative imports of a JavaScript or TypeScript module:
+// static and dynamic imports, re-exports, and require calls.
+func extractJavaScript(filePath, content string, project *Project) []Reference {
+	return scanJavaScript(newSource(filePath, content), 0, len(content))
+}
+
+// scanJavaScript returns the relative imports in s.content[start:end]. It also scans
+// inline scripts of HTML pages.
+func scanJavaScript(s *source, start, end int) []Reference {
+	tokens := jsTokens(s.content, start, end)
+	var refs []Reference
+
+	add := func(t jsToken) {
+		if ref, ok := s.moduleRef(t.offset, t.value); ok {
+			refs = append(refs, ref)
+		}
+	}
+
+	at := func(i int) jsToken {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return jsToken{kind: jsPunct}
+	}
+
+	for i, t := range tokens {
+		if t.kind != jsName || (i > 0 && tokens[i-1].is(".")) {
+			continue
+		}
+		switch t.value {
+		case "import":
+			switch next := at(i + 1); {
+			case next.is("("): // import("./module")
+				if at(i+2).kind == jsString && (at(i+3).is(")") || at(i+3).is(",")) {
+					add(at(i + 2))
+				}
+			case next.kind == jsString: // import "./module"
+				add(next)
+			default: // import x, { y } from "./module"
+				if from, ok := findFrom(tokens, i+1); ok {
+					add(from)
+				}
+			}
+		case "export": // export * from "./module", export { x } from "./module"
+			if next := at(i + 1); next.is("*") || next.is("{") {
+				if from, ok := findFrom(tokens, i+1); ok {
+					add(from)
+				}
+			}
+		case "require":
+			if at(i+1).is("(") && at(i+2).kind == jsString && at(i+3).is(")") {
+				add(at(i + 2))
+			}
+		}
+	}
+	return refs
+}
+
+// findFrom returns the module string of an import or export clause starting at tokens[i]:
+// names, "*", "as", commas and braced lists up to "from" and a string.
+func findFrom(tokens []jsToken, i int) (jsToken, bool) {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.is("{"):
+			depth++
+		case t.is("}"):
+			depth--
+		case depth > 0:
+			// Names, commas and string names inside braces
+		case t.is("from") && i+1 < len(tokens) && tokens[i+1].kind == jsString:
+			return tokens[i+1], true
+		case t.kind == jsName || t.is("*") || t.is(","):
+		default:
+			return jsToken{}, false
+		}
+	}
+	return jsToken{}, false
+}
+
+// moduleRef creates an import reference to a relative module specifier, or returns false
+// for packages and URLs. The targets try the usual extensions and index files.
+func (s *source) moduleRef(offset int, specifier string) (Reference, bool) {
+	if !strings.HasPrefix(specifier, "./") && !strings.HasPrefix(specifier, "../") && specifier != "." && specifier != ".." {
+		return Reference{}, false
+	}
+	resolved := path.Join(path.Dir(s.path), strings.SplitN(specifier, "?", 2)[0])
+
+	extensions := scriptExtensions
+	typeScript := path.Ext(s.path) == ".ts" || path.Ext(s.path) == ".tsx"
+	if typeScript {
+		extensions = typeScriptExtensions
+	}
+
+	var targets []string
+	switch ext := path.Ext(resolved); {
+	case ext == ".js" && typeScript:
+		// TypeScript imports compiled names: "./util.js" is util.ts
+		base := strings.TrimSuffix(resolved, ext)
+		targets = append(targets, resolved, base+".ts", base+".tsx")
+	case ext != "" && !strings.HasSuffix(specifier, "/"):
+		targets = append(targets, resolved)
+	default:
+		for _, ext := range extensions {
+			targets = append(targets, resolved+ext)
+		}
+		for _, ext := range extensions {
+			targets = append(targets, path.Join(resolved, "index"+ext))
+		}
+	}
+	return s.ref(offset, specifier, "import", targets...), true
+}
+
+// jsTokens splits text[start:end] into tokens, skipping white space and comments.
+// Template literals and regular expressions are single tokens, except that expressions
+// inside template literals are tokenized.
+// Unterminated strings end at the end of their line, which keeps JSX text with quotes
+// from swallowing the rest of the file.
+func jsTokens(text string, start, end int) []jsToken {
+	var tokens []jsToken
+	var templates []int // Brace depth at each open template substitution
+	depth := 0
+
+	regexAllowed := func() bool {
+		if len(tokens) == 0 {
+			return true
+		}
+		last := tokens[len(tokens)-1]
+		switch last.kind {
+		case jsName:
+			return regexPrecedingKeywords[last.value]
+		case jsPunct:
+			return last.value != ")" && last.value != "]" && last.value != "}"
+		}
+		return false // After a string or literal
+	}
+
+	for i := start; i < end; {
+		c := text[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '/' && i+1 < end && text[i+1] == '/':
+			i = lineEnd(text, i, end)
+		case c == '/' && i+1 < end && text[i+1] == '*':
+			closing := strings.Index(text[i+2:end], "*/")
+			if closing < 0 {
+				return tokens
+			}
+			i += closing + 4
+		case c == '"' || c == '\'':
+			value, next := quotedString(text, i, end)
+			tokens = append(tokens, jsToken{kind: jsString, value: value, offset: i})
+			i = next
+		case c == '`':
+			tokens = append(tokens, jsToken{kind: jsLiteral, offset: i})
+			i = skipTemplate(text, i+1, end, &templates, depth)
+		case c == '}' && len(templates) > 0 && templates[len(templates)-1] == depth:
+			// End of a template substitution; continue the template literal
+			templates = templates[:len(templates)-1]
+			i = skipTemplate(text, i+1, end, &templates, depth)
+		case c == '/' && regexAllowed():
+			tokens = append(tokens, jsToken{kind: jsLiteral, offset: i})
+			i = skipRegex(text, i+1, end)
+		case isNameByte(c) && c != '-':
+			j := i
+			for j < end && isNameByte(text[j]) && text[j] != '-' {
+				j++
+			}
+			tokens = append(tokens, jsToken{kind: jsName, value: text[i:j], offset: i})
+			i = j
+		default:
+			switch c {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			tokens = append(tokens, jsToken{kind: jsPunct, value: text[i : i+1], offset: i})
+			i++
+		}
+	}
+	return tokens
+}
+
+// skipTemplate skips template literal text starting at text[i] and returns the offset
+// after its closing backtick, or after "${" when a substitution starts, which is pushed
+// onto templates with the current brace depth.
+func skipTemplate(text string, i, end int, templates *[]int, depth int) int {
+	for ; i < end; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1
+		case '$':
+			if i+1 < end && text[i+1] == '{' {
+				*templates = append(*templates, depth)
+				return i + 2
+			}
+		}
+	}
+	return end
+}
+
+// skipRegex skips a regular expression literal whose body starts at text[i] and returns
+// the offset after its flags. A regular expression ends at the end of its line at the latest.
+func skipRegex(text string, i, end int) int {
+	inClass := false
+	for ; i < end; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return i
+		case '/':
+			if !inClass {
+				i++
+				for i < end && isNameByte(text[i]) {
+					i++
+				}
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// lineEnd returns the offset of the newline ending the line at text[i], or end.
+func lineEnd(text string, i, end int) int {
+	if n := strings.IndexByte(text[i:end], '\n'); n >= 0 {
+		return i + n
+	}
+	return end
+}
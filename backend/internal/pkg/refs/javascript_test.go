@@ -0,0 +1,64 @@
+package refs
+
+import "testing"
+
+func TestExtractJavaScript(t *testing.T) {
+	content := `import React from "react";
+import {
+  formatPrice,
+  formatDate as date,
+} from "./utils/format.js";
+import * as api from '../api';
+import "./polyfills.js";
+export { default as Cart } from "./cart";
+export * from "./types.js";
+const lazy = () => import("./pages/lazy.js");
+const config = require("./config.json");
+// import "./commented.js";
+const text = "import './in-string.js'";
+const tpl = ` + "`${items.map(i => `<li>${i}</li>`).join('')} import \"./in-template.js\"`" + `;
+const re = /import "\.\/in-regex.js"/;
+const url = new URL("./worker.js", import.meta.url);
+obj.require("./method.js");`
+
+	got := format(extract("src/app.js", content, nil))
+	want := "5 import ./utils/format.js -> src/utils/format.js; " +
+		"6 import ../api -> api.js|api.jsx|api.mjs|api.cjs|api.ts|api.tsx|api/index.js|api/index.jsx|api/index.mjs|api/index.cjs|api/index.ts|api/index.tsx; " +
+		"7 import ./polyfills.js -> src/polyfills.js; " +
+		"8 import ./cart -> src/cart.js|src/cart.jsx|src/cart.mjs|src/cart.cjs|src/cart.ts|src/cart.tsx|src/cart/index.js|src/cart/index.jsx|src/cart/index.mjs|src/cart/index.cjs|src/cart/index.ts|src/cart/index.tsx; " +
+		"9 import ./types.js -> src/types.js; " +
+		"10 import ./pages/lazy.js -> src/pages/lazy.js; " +
+		"11 import ./config.json -> src/config.json"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractJavaScript_TypeScript(t *testing.T) {
+	content := `import type { User } from "./models/user.js";
+import { Button } from "./components";`
+
+	refs := extract("src/main.ts", content, nil)
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 references, got %d: %s", len(refs), format(refs))
+	}
+	if got, want := refs[0].Targets, []string{"src/models/user.js", "src/models/user.ts", "src/models/user.tsx"}; !equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := refs[1].Targets[0]; got != "src/components.ts" {
+		t.Errorf("expected TypeScript extensions first, got %q", got)
+	}
+}
+
+// equal reports whether two string slices are equal.
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package refs
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// packageEntryFields are the package.json fields naming a file of the package.
+var packageEntryFields = []string{"main", "module", "browser", "types", "typings"}
+
+// extractPackageJSON returns the entry points of a package.json: main, module, browser,
+// types and typings, bin, and exports. Export patterns with wildcards are skipped.
+func extractPackageJSON(filePath, content string, project *Project) []Reference {
+	var manifest map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return nil
+	}
+
+	var entries []string
+	for _, field := range packageEntryFields {
+		if value, ok := manifest[field].(string); ok {
+			entries = append(entries, value)
+		}
+	}
+	entries = append(entries, jsonStrings(manifest["bin"])...)
+	entries = append(entries, jsonStrings(manifest["exports"])...)
+
+	s := newSource(filePath, content)
+	var refs []Reference
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if strings.Contains(entry, "*") {
+			continue
+		}
+		// The same file is often named by several fields, spelled differently
+		if ref, ok := s.urlRef(s.jsonOffset(entry), entry, "entry"); ok && !seen[ref.Targets[0]] {
+			seen[ref.Targets[0]] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// extractWebManifest returns the icons and screenshots of a web app manifest.
+func extractWebManifest(filePath, content string, project *Project) []Reference {
+	var manifest struct {
+		Icons       []struct{ Src string } `json:"icons"`
+		Screenshots []struct{ Src string } `json:"screenshots"`
+	}
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return nil
+	}
+
+	s := newSource(filePath, content)
+	var refs []Reference
+	for _, image := range append(manifest.Icons, manifest.Screenshots...) {
+		if ref, ok := s.urlRef(s.jsonOffset(image.Src), image.Src, "image"); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// jsonStrings returns the strings of a JSON value: the value itself, or the values of an
+// object (in key order) or array, recursively.
+func jsonStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			values = append(values, jsonStrings(item)...)
+		}
+		return values
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var values []string
+		for _, key := range keys {
+			values = append(values, jsonStrings(v[key])...)
+		}
+		return values
+	}
+	return nil
+}
+
+// jsonOffset returns the offset of a string value in the source, or 0 if it isn't found
+// as written, e.g. because it contains escapes.
+func (s *source) jsonOffset(value string) int {
+	if i := strings.Index(s.content, strconv.Quote(value)); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package refs
+
+import "testing"
+
+func TestExtractPackageJSON(t *testing.T) {
+	content := `{
+  "name": "shop",
+  "main": "dist/index.js",
+  "types": "./dist/index.d.ts",
+  "bin": { "shop": "bin/cli.js" },
+  "exports": {
+    ".": { "import": "./dist/index.mjs", "require": "./dist/index.js" },
+    "./features/*": "./dist/features/*.js"
+  },
+  "scripts": { "build": "tsc" }
+}`
+
+	got := format(extract("packages/shop/package.json", content, nil))
+	want := "3 entry dist/index.js -> packages/shop/dist/index.js; " +
+		"4 entry ./dist/index.d.ts -> packages/shop/dist/index.d.ts; " +
+		"5 entry bin/cli.js -> packages/shop/bin/cli.js; " +
+		"7 entry ./dist/index.mjs -> packages/shop/dist/index.mjs"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractWebManifest(t *testing.T) {
+	content := `{
+  "name": "Shop",
+  "icons": [
+    { "src": "icons/192.png", "sizes": "192x192" },
+    { "src": "https://cdn.example.com/512.png", "sizes": "512x512" }
+  ],
+  "screenshots": [{ "src": "/screens/home.png" }]
+}`
+
+	got := format(extract("static/app.webmanifest", content, nil))
+	want := "4 image icons/192.png -> static/icons/192.png; 7 image /screens/home.png -> screens/home.png"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if got := extract("manifest.json", "{ not json", nil); len(got) != 0 {
+		t.Errorf("expected no references for invalid JSON, got %s", format(got))
+	}
+}
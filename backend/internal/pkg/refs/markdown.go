@@ -0,0 +1,142 @@
+package refs
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// markdownDefinitionPattern matches a link reference definition such as "[docs]: ./docs.md".
+var markdownDefinitionPattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:[ \t]*(<[^>]*>|\S+)`)
+
+// extractMarkdown returns the links and images of a Markdown document, from inline links
+// and link reference definitions. Code blocks and code spans are skipped.
+func extractMarkdown(filePath, content string, project *Project) []Reference {
+	s := newSource(filePath, content)
+	var refs []Reference
+	fence := ""
+
+	for start := 0; start < len(content); {
+		end := lineEnd(content, start, len(content))
+		line := content[start:end]
+		trimmed := strings.TrimLeft(line, " ")
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]+" \t\r") == "" {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = markdownFence(trimmed)
+		default:
+			if m := markdownDefinitionPattern.FindStringSubmatchIndex(line); m != nil {
+				if ref, ok := s.markdownRef(start+m[2], line[m[2]:m[3]], "link"); ok {
+					refs = append(refs, ref)
+				}
+			} else {
+				refs = append(refs, s.markdownLinks(start, end)...)
+			}
+		}
+		start = end + 1
+	}
+	return refs
+}
+
+// markdownFence returns the opening code fence of a line, e.g. "````".
+func markdownFence(line string) string {
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	return line[:n]
+}
+
+// markdownLinks returns the inline links and images in s.content[start:end].
+func (s *source) markdownLinks(start, end int) []Reference {
+	text := s.content
+	var refs []Reference
+	var open []int // Offsets of the unclosed "[" of link texts
+
+	for i := start; i < end; i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '`':
+			// A code span ends at the next run of as many backticks
+			n := len(markdownFence(text[i:end]))
+			closing := strings.Index(text[i+n:end], text[i:i+n])
+			if closing < 0 {
+				i += n - 1
+				continue
+			}
+			i += n + closing + n - 1
+		case '[':
+			open = append(open, i)
+		case ']':
+			if len(open) == 0 {
+				continue
+			}
+			bracket := open[len(open)-1]
+			open = open[:len(open)-1]
+			if i+1 >= end || text[i+1] != '(' {
+				continue
+			}
+			destStart, destEnd := markdownDestination(text, i+2, end)
+			kind := "link"
+			if bracket > start && text[bracket-1] == '!' {
+				kind = "image"
+			}
+			if ref, ok := s.markdownRef(destStart, text[destStart:destEnd], kind); ok {
+				refs = append(refs, ref)
+			}
+			i = destEnd - 1
+		}
+	}
+	return refs
+}
+
+// markdownDestination returns the bounds of the link destination starting at text[i],
+// which ends at white space (before a title) or an unbalanced closing parenthesis.
+func markdownDestination(text string, i, end int) (int, int) {
+	i = skipSpace(text, i, end)
+	if i < end && text[i] == '<' {
+		if closing := strings.IndexByte(text[i:end], '>'); closing >= 0 {
+			return i, i + closing + 1
+		}
+	}
+	depth := 0
+	j := i
+	for ; j < end && !isSpace(text[j]); j++ {
+		if text[j] == '(' {
+			depth++
+		} else if text[j] == ')' {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+	}
+	return i, j
+}
+
+// markdownRef creates a link or image reference. A link without an extension may name a
+// document or a directory, as repository hosts render both.
+func (s *source) markdownRef(offset int, written, kind string) (Reference, bool) {
+	written = strings.TrimSuffix(strings.TrimPrefix(written, "<"), ">")
+	ref, ok := s.urlRef(offset, written, kind)
+	if !ok {
+		return Reference{}, false
+	}
+
+	resolved := ref.Targets[0]
+	written = strings.SplitN(strings.SplitN(written, "#", 2)[0], "?", 2)[0]
+	switch {
+	case resolved == "." || resolved == "":
+		return Reference{}, false
+	case strings.HasSuffix(written, "/"):
+		ref.Targets = []string{resolved + "/"}
+	case kind == "link" && path.Ext(resolved) == "":
+		ref.Targets = []string{resolved, resolved + "/"}
+	}
+	return ref, true
+}
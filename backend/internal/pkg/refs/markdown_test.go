@@ -0,0 +1,28 @@
+package refs
+
+import "testing"
+
+func TestExtractMarkdown(t *testing.T) {
+	content := "# Shop\n" +
+		"\n" +
+		"See the [setup guide](docs/setup.md \"Setup\") and [API](docs/api).\n" +
+		"[![Build](badges/build.svg)](https://ci.example.com)\n" +
+		"Use `[not](a-link.md)` or the [examples](examples/).\n" +
+		"\n" +
+		"```md\n" +
+		"[fenced](fenced.md)\n" +
+		"```\n" +
+		"\n" +
+		"[license]: <LICENSE.txt>\n" +
+		"[Top](#shop) and [site](https://example.com).\n"
+
+	got := format(extract("README.md", content, nil))
+	want := "3 link docs/setup.md -> docs/setup.md; " +
+		"3 link docs/api -> docs/api|docs/api/; " +
+		"4 image badges/build.svg -> badges/build.svg; " +
+		"5 link examples/ -> examples/; " +
+		"11 link LICENSE.txt -> LICENSE.txt"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,233 @@
+package refs
+
+import (
+	"path"
+	"strings"
+)
+
+// pyToken is a token of a Python logical line: a name, or punctuation such as "." or ",".
+// Strings and comments are skipped.
+type pyToken struct {
+	value  string
+	offset int
+}
+
+// extractPython returns the imports of a Python module that name modules of the project.
+// Relative imports are always the project's; an absolute import is when its top-level
+// package or module exists at the project root or next to the importing file, so the
+// standard library and installed packages are skipped.
+func extractPython(filePath, content string, project *Project) []Reference {
+	s := newSource(filePath, content)
+	var refs []Reference
+
+	for _, statement := range pyStatements(content) {
+		if len(statement) == 0 {
+			continue
+		}
+		switch statement[0].value {
+		case "import": // import a.b as c, d
+			for _, name := range splitPyNames(statement[1:]) {
+				if ref, ok := s.pyAbsoluteRef(name, project); ok {
+					refs = append(refs, ref)
+				}
+			}
+		case "from": // from .a import b, from a.b import c
+			refs = append(refs, s.pyFromRefs(statement, project)...)
+		}
+	}
+	return refs
+}
+
+// pyFromRefs returns the references of a from-import statement.
+func (s *source) pyFromRefs(statement []pyToken, project *Project) []Reference {
+	i := 1
+	dots := 0
+	for ; i < len(statement) && statement[i].value == "."; i++ {
+		dots++
+	}
+	var module []string
+	for ; i < len(statement) && statement[i].value != "import"; i++ {
+		if statement[i].value != "." {
+			module = append(module, statement[i].value)
+		}
+	}
+	if i == len(statement) {
+		return nil
+	}
+
+	if dots == 0 {
+		if ref, ok := s.pyAbsoluteRef(pyName{parts: module, offset: statement[0].offset}, project); ok {
+			return []Reference{ref}
+		}
+		return nil
+	}
+
+	written := strings.Repeat(".", dots)
+	dir := path.Dir(s.path)
+	for ; dots > 1; dots-- {
+		dir = path.Dir(dir)
+	}
+
+	// from .a.b import c needs the module a.b
+	if len(module) > 0 {
+		return []Reference{s.ref(statement[0].offset, written+strings.Join(module, "."), "import", pyModuleTargets(dir, module)...)}
+	}
+
+	// from . import a, b needs modules a and b, or names defined by the package
+	var refs []Reference
+	for _, name := range splitPyNames(statement[i+1:]) {
+		if len(name.parts) != 1 || name.parts[0] == "*" {
+			continue
+		}
+		targets := append(pyModuleTargets(dir, name.parts), path.Join(dir, "__init__.py"))
+		refs = append(refs, s.ref(name.offset, written+name.parts[0], "import", targets...))
+	}
+	return refs
+}
+
+// pyAbsoluteRef creates the reference of an absolute import, or returns false if the
+// module isn't the project's or is only a top-level name the project already has.
+func (s *source) pyAbsoluteRef(name pyName, project *Project) (Reference, bool) {
+	if len(name.parts) == 0 {
+		return Reference{}, false
+	}
+	for _, root := range []string{".", path.Dir(s.path)} {
+		top := path.Join(root, name.parts[0])
+		if !project.Exists(top+".py") && !project.HasDir(top) {
+			continue
+		}
+		if len(name.parts) == 1 {
+			return Reference{}, false
+		}
+		return s.ref(name.offset, strings.Join(name.parts, "."), "import", pyModuleTargets(root, name.parts)...), true
+	}
+	return Reference{}, false
+}
+
+// pyModuleTargets returns the files that may hold a dotted module under dir: a module
+// file, a package, or a namespace package directory.
+func pyModuleTargets(dir string, parts []string) []string {
+	base := path.Join(append([]string{dir}, parts...)...)
+	return []string{base + ".py", path.Join(base, "__init__.py"), base + "/"}
+}
+
+// pyName is a dotted name in an import statement.
+type pyName struct {
+	parts  []string
+	offset int
+}
+
+// splitPyNames splits the names of an import list such as "a.b as c, (d, e)", dropping aliases.
+func splitPyNames(tokens []pyToken) []pyName {
+	var names []pyName
+	var current *pyName
+	alias := false
+	for _, t := range tokens {
+		switch t.value {
+		case "(", ")":
+		case ",":
+			current, alias = nil, false
+		case "as":
+			alias = true
+		case ".":
+		default:
+			if alias {
+				continue
+			}
+			if current == nil {
+				names = append(names, pyName{offset: t.offset})
+				current = &names[len(names)-1]
+			}
+			current.parts = append(current.parts, t.value)
+		}
+	}
+	return names
+}
+
+// pyStatements splits Python source into simple statements of tokens. Logical lines
+// continue inside brackets and after a backslash; semicolons separate statements.
+// Statements after a colon on the same line, as in "if x: import y", are not split out.
+func pyStatements(text string) [][]pyToken {
+	var statements [][]pyToken
+	var current []pyToken
+	depth := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			statements = append(statements, current)
+			current = nil
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '#':
+			i = lineEnd(text, i, len(text))
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			i += 2
+		case c == '\n':
+			if depth == 0 {
+				flush()
+			}
+			i++
+		case c == ';' && depth == 0:
+			flush()
+			i++
+		case c == '"' || c == '\'':
+			i = skipPyString(text, i)
+			current = append(current, pyToken{value: `""`, offset: i})
+		case isSpace(c):
+			i++
+		case isNameByte(c) && c != '-' && c != '$':
+			j := i
+			for j < len(text) && isNameByte(text[j]) && text[j] != '-' && text[j] != '$' {
+				j++
+			}
+			// A string prefix such as r, b or f
+			if j < len(text) && (text[j] == '"' || text[j] == '\'') && j-i <= 2 && strings.Trim(strings.ToLower(text[i:j]), "rbuf") == "" {
+				i = j
+				continue
+			}
+			current = append(current, pyToken{value: text[i:j], offset: i})
+			i = j
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			current = append(current, pyToken{value: text[i : i+1], offset: i})
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// skipPyString returns the offset after the string literal starting at text[i],
+// including triple-quoted strings. A single-quoted string ends at its line's end at the latest.
+func skipPyString(text string, i int) int {
+	quote := text[i : i+1]
+	if strings.HasPrefix(text[i:], strings.Repeat(quote, 3)) {
+		closing := strings.Index(text[i+3:], strings.Repeat(quote, 3))
+		if closing < 0 {
+			return len(text)
+		}
+		return i + 3 + closing + 3
+	}
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '\n':
+			return j
+		case quote[0]:
+			return j + 1
+		}
+	}
+	return len(text)
+}
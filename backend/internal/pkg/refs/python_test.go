@@ -0,0 +1,49 @@
+package refs
+
+import "testing"
+
+func TestExtractPython(t *testing.T) {
+	content := `"""Order service.
+
+import not_an_import
+"""
+import os, sys
+import app.models.order as order
+from app.services import (
+    billing,
+    shipping,
+)
+from . import helpers, views as v
+from .utils.money import format_price
+from .. import settings
+import requests  # third party
+x = "from .strings import nothing"; from .db import session
+`
+
+	others := map[string]string{
+		"app/__init__.py":        "",
+		"app/models/__init__.py": "",
+		"app/api/helpers.py":     "",
+	}
+	got := format(extract("app/api/orders.py", content, others))
+	want := "6 import app.models.order -> app/models/order.py|app/models/order/__init__.py|app/models/order/; " +
+		"7 import app.services -> app/services.py|app/services/__init__.py|app/services/; " +
+		"11 import .helpers -> app/api/helpers.py|app/api/helpers/__init__.py|app/api/helpers/|app/api/__init__.py; " +
+		"11 import .views -> app/api/views.py|app/api/views/__init__.py|app/api/views/|app/api/__init__.py; " +
+		"12 import .utils.money -> app/api/utils/money.py|app/api/utils/money/__init__.py|app/api/utils/money/; " +
+		"13 import ..settings -> app/settings.py|app/settings/__init__.py|app/settings/|app/__init__.py; " +
+		"15 import .db -> app/api/db.py|app/api/db/__init__.py|app/api/db/"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExtractPython_SiblingModule(t *testing.T) {
+	content := "import utils\nfrom utils.dates import today\nfrom flask import Flask\n"
+
+	got := format(extract("main.py", content, map[string]string{"utils/__init__.py": ""}))
+	want := "2 import utils.dates -> utils/dates.py|utils/dates/__init__.py|utils/dates/"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
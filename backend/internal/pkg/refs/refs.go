@@ -0,0 +1,257 @@
+// Package refs finds the files that source files reference, such as scripts loaded by an
+// HTML page, modules imported by JavaScript or Python, or entry points of a package.json.
+// Each language has an Extractor; a Registry picks the extractor for a file by its name.
+package refs
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reference is a file reference found in a source file.
+type Reference struct {
+	Path    string // The reference as written, e.g. "./utils", "css/site.css" or "app.models"
+	Type    string // "script", "stylesheet", "import", "image", "font", "media", "manifest", "entry" or "link"
+	Line    int    // 1-based line of the reference
+	Context string // The referencing line, trimmed
+
+	// Targets are the project paths that satisfy the reference, the one to create first.
+	// A target with a trailing slash is satisfied by any file directly in that directory.
+	Targets []string
+}
+
+// Extractor finds the references of a file. Targets are resolved against the project,
+// which also tells local modules apart from third-party ones where a language needs it.
+type Extractor interface {
+	Extract(path, content string, project *Project) []Reference
+}
+
+// ExtractorFunc adapts a function to the Extractor interface.
+type ExtractorFunc func(path, content string, project *Project) []Reference
+
+// Extract calls f.
+func (f ExtractorFunc) Extract(path, content string, project *Project) []Reference {
+	return f(path, content, project)
+}
+
+// Registry maps file names to extractors. Safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{extractors: make(map[string]Extractor)}
+}
+
+// DefaultRegistry creates a Registry with the extractors for HTML, CSS, JavaScript and
+// TypeScript, Python, Go, package.json and web app manifests, and Markdown.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, ext := range []string{".html", ".htm"} {
+		r.Register(ext, ExtractorFunc(extractHTML))
+	}
+	for _, ext := range []string{".css", ".scss", ".sass", ".less"} {
+		r.Register(ext, ExtractorFunc(extractCSS))
+	}
+	for _, ext := range []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs"} {
+		r.Register(ext, ExtractorFunc(extractJavaScript))
+	}
+	r.Register(".py", ExtractorFunc(extractPython))
+	r.Register(".go", ExtractorFunc(extractGo))
+	r.Register("package.json", ExtractorFunc(extractPackageJSON))
+	r.Register("manifest.json", ExtractorFunc(extractWebManifest))
+	r.Register(".webmanifest", ExtractorFunc(extractWebManifest))
+	for _, ext := range []string{".md", ".markdown"} {
+		r.Register(ext, ExtractorFunc(extractMarkdown))
+	}
+	return r
+}
+
+// Register sets the extractor for files matching pattern: an extension such as ".py",
+// or a file name such as "package.json", which takes precedence over the extension.
+// It replaces an extractor registered for the same pattern.
+func (r *Registry) Register(pattern string, extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors[strings.ToLower(pattern)] = extractor
+}
+
+// Extract returns the references of the file at filePath, or nil if no extractor handles it.
+// References without targets are dropped.
+func (r *Registry) Extract(filePath, content string, project *Project) []Reference {
+	r.mu.RLock()
+	name := strings.ToLower(path.Base(filePath))
+	extractor, ok := r.extractors[name]
+	if !ok {
+		extractor, ok = r.extractors[path.Ext(name)]
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	var references []Reference
+	for _, ref := range extractor.Extract(strings.TrimPrefix(filePath, "/"), content, project) {
+		if len(ref.Targets) > 0 {
+			references = append(references, ref)
+		}
+	}
+	return references
+}
+
+// Project is the set of files that references resolve against.
+type Project struct {
+	files map[string]string
+	dirs  map[string]bool // Directories containing a file at any depth, with a trailing slash
+	flat  map[string]bool // Directories directly containing a file, with a trailing slash
+}
+
+// NewProject creates a Project from file paths to contents.
+func NewProject(files map[string]string) *Project {
+	p := &Project{
+		files: make(map[string]string, len(files)),
+		dirs:  make(map[string]bool),
+		flat:  make(map[string]bool),
+	}
+	for filePath, content := range files {
+		filePath = strings.TrimPrefix(filePath, "/")
+		p.files[filePath] = content
+
+		dir := path.Dir(filePath)
+		if dir != "." {
+			p.flat[dir+"/"] = true
+		}
+		for ; dir != "." && dir != "/"; dir = path.Dir(dir) {
+			p.dirs[dir+"/"] = true
+		}
+	}
+	return p
+}
+
+// Content returns the content of the file at filePath.
+func (p *Project) Content(filePath string) (string, bool) {
+	content, ok := p.files[filePath]
+	return content, ok
+}
+
+// Exists reports whether a target exists: a file, or for a target with a trailing slash,
+// a directory directly containing a file. The empty target is the project root.
+func (p *Project) Exists(target string) bool {
+	if target == "" {
+		return true
+	}
+	if strings.HasSuffix(target, "/") {
+		return p.flat[target]
+	}
+	_, ok := p.files[target]
+	return ok
+}
+
+// HasDir reports whether a directory contains a file at any depth.
+func (p *Project) HasDir(dir string) bool {
+	return p.dirs[strings.TrimSuffix(dir, "/")+"/"]
+}
+
+// Resolves reports whether any target of a reference exists.
+func (p *Project) Resolves(ref Reference) bool {
+	for _, target := range ref.Targets {
+		if p.Exists(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemePattern matches a URL scheme such as "https:", "mailto:" or "data:".
+var schemePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// Resolve resolves a URL-style reference made by the file at from to a project path,
+// dropping any query or fragment. Root-relative references resolve against the project
+// root. It returns false for remote references, other schemes and fragment-only references.
+func Resolve(from, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if ref == "" || IsRemote(ref) {
+		return "", false
+	}
+	if strings.HasPrefix(ref, "/") {
+		return strings.TrimPrefix(path.Clean(ref), "/"), true
+	}
+	return path.Join(path.Dir(from), ref), true
+}
+
+// IsRemote reports whether a reference has a scheme or is protocol-relative.
+func IsRemote(ref string) bool {
+	return strings.HasPrefix(ref, "//") || schemePattern.MatchString(ref)
+}
+
+// maxContextLength truncates the context of references on long (e.g. minified) lines.
+const maxContextLength = 200
+
+// source is a file being scanned, with its line starts for locating offsets.
+type source struct {
+	path    string
+	content string
+	lines   []int // Offset of the first byte of each line
+}
+
+// newSource creates a source for the file at filePath.
+func newSource(filePath, content string) *source {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &source{path: filePath, content: content, lines: lines}
+}
+
+// line returns the 1-based line of an offset.
+func (s *source) line(offset int) int {
+	return sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset })
+}
+
+// context returns the trimmed line of an offset.
+func (s *source) context(offset int) string {
+	start := s.lines[s.line(offset)-1]
+	end := strings.IndexByte(s.content[start:], '\n')
+	if end < 0 {
+		end = len(s.content) - start
+	}
+	text := strings.TrimSpace(s.content[start : start+end])
+	if len(text) > maxContextLength {
+		text = strings.ToValidUTF8(text[:maxContextLength], "") + "…"
+	}
+	return text
+}
+
+// ref creates a reference written at offset.
+func (s *source) ref(offset int, written, kind string, targets ...string) Reference {
+	return Reference{
+		Path:    written,
+		Type:    kind,
+		Line:    s.line(offset),
+		Context: s.context(offset),
+		Targets: targets,
+	}
+}
+
+// urlRef creates a reference to a URL-style path written at offset, or returns false if
+// the URL doesn't point into the project or is a template placeholder such as "{{ url }}".
+func (s *source) urlRef(offset int, written, kind string) (Reference, bool) {
+	if strings.ContainsAny(written, "{}<>") {
+		return Reference{}, false
+	}
+	resolved, ok := Resolve(s.path, written)
+	if !ok {
+		return Reference{}, false
+	}
+	return s.ref(offset, written, kind, resolved), true
+}
@@ -0,0 +1,117 @@
+package refs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format renders references as "line type path -> targets" entries for compact comparison.
+func format(refs []Reference) string {
+	var parts []string
+	for _, r := range refs {
+		parts = append(parts, fmt.Sprintf("%d %s %s -> %s", r.Line, r.Type, r.Path, strings.Join(r.Targets, "|")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// extract extracts the references of one file with the default registry.
+func extract(filePath, content string, others map[string]string) []Reference {
+	files := map[string]string{filePath: content}
+	for p, c := range others {
+		files[p] = c
+	}
+	return DefaultRegistry().Extract(filePath, content, NewProject(files))
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		from, ref string
+		want      string
+		ok        bool
+	}{
+		{"index.html", "app.js", "app.js", true},
+		{"pages/about.html", "../css/site.css?v=2", "css/site.css", true},
+		{"pages/about.html", "/img/logo.png#top", "img/logo.png", true},
+		{"pages/about.html", "./", "pages", true},
+		{"index.html", "https://cdn.example.com/lib.js", "", false},
+		{"index.html", "//cdn.example.com/lib.js", "", false},
+		{"index.html", "mailto:team@example.com", "", false},
+		{"index.html", "data:image/png;base64,AAAA", "", false},
+		{"index.html", "#section", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Resolve(tt.from, tt.ref)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q, %q): expected (%q, %v), got (%q, %v)", tt.from, tt.ref, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestProject_Exists(t *testing.T) {
+	project := NewProject(map[string]string{
+		"/index.html":         "",
+		"src/app/models/a.py": "",
+		"assets/img/logo.png": "",
+	})
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"index.html", true},
+		{"", true},
+		{"src/app/models/a.py", true},
+		{"src/app/models/", true},
+		{"src/app/", false},
+		{"missing.js", false},
+	}
+	for _, tt := range tests {
+		if got := project.Exists(tt.target); got != tt.want {
+			t.Errorf("Exists(%q): expected %v, got %v", tt.target, tt.want, got)
+		}
+	}
+
+	if !project.HasDir("src/app") || project.HasDir("src/lib") {
+		t.Errorf("expected HasDir to report directories with files at any depth")
+	}
+}
+
+func TestRegistry_Extract(t *testing.T) {
+	r := NewRegistry()
+	r.Register(".txt", ExtractorFunc(func(p, content string, project *Project) []Reference {
+		return []Reference{
+			{Path: content, Type: "link", Line: 1, Targets: []string{content}},
+			{Path: "ignored", Type: "link", Line: 1},
+		}
+	}))
+	r.Register("README.TXT", ExtractorFunc(func(p, content string, project *Project) []Reference {
+		return []Reference{{Path: "readme", Type: "link", Line: 1, Targets: []string{p}}}
+	}))
+	project := NewProject(nil)
+
+	if got, want := format(r.Extract("/notes.txt", "other.txt", project)), "1 link other.txt -> other.txt"; got != want {
+		t.Errorf("extension: expected %q, got %q", want, got)
+	}
+	if got, want := format(r.Extract("/docs/readme.txt", "", project)), "1 link readme -> docs/readme.txt"; got != want {
+		t.Errorf("file name: expected %q, got %q", want, got)
+	}
+	if got := r.Extract("main.rs", "mod util;", project); got != nil {
+		t.Errorf("expected no references for an unregistered extension, got %v", got)
+	}
+}
+
+func TestSource_Context(t *testing.T) {
+	s := newSource("app.js", "first\n   second line  \n"+strings.Repeat("x", 300))
+
+	if got := s.context(8); got != "second line" {
+		t.Errorf("expected trimmed line, got %q", got)
+	}
+	if got := s.line(8); got != 2 {
+		t.Errorf("expected line 2, got %d", got)
+	}
+	if got := s.context(len(s.content) - 1); len(got) > maxContextLength+len("…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("expected truncated context, got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,206 @@
+// Package render loads a generated HTML page in a headless JavaScript environment, without
+// network access, and reports what goes wrong: uncaught exceptions, console errors and
+// assets that fail to load.
+package render
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Problem kinds.
+const (
+	ProblemException    = "exception"     // Uncaught exception, syntax error or unhandled rejection
+	ProblemConsoleError = "console_error" // console.error or a failed console.assert
+	ProblemAssetLoad    = "asset_load"    // A project file the page loads doesn't exist
+	ProblemUnchecked    = "unchecked"     // A script the check can't run, so its errors aren't reported
+)
+
+// Problem is something that went wrong while loading a page.
+type Problem struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`  // Project file the problem is in, if known
+	Line    int    `json:"line,omitempty"`  // 1-based line in File; 0 if unknown
+	Asset   string `json:"asset,omitempty"` // Project path of the asset that failed to load (ProblemAssetLoad)
+}
+
+// Page is the structure of an HTML page that the headless environment needs.
+type Page struct {
+	Path     string    `json:"path"`
+	Scripts  []Script  `json:"scripts"`
+	Elements []Element `json:"elements"`
+	Assets   []Asset   `json:"-"`
+}
+
+// Script is a script of a page, in document order.
+type Script struct {
+	Src    string `json:"src,omitempty"`    // Project path of an external script; empty for inline
+	Code   string `json:"code,omitempty"`   // Inline source
+	Line   int    `json:"line,omitempty"`   // Line of the page the inline source, or an external script's element, starts on
+	Remote bool   `json:"remote,omitempty"` // Loaded from another origin, so not run
+	Module bool   `json:"module,omitempty"` // type="module", which isn't run and is reported as unchecked
+}
+
+// Element is an element of the page's initial DOM.
+type Element struct {
+	Tag     string   `json:"tag"`
+	ID      string   `json:"id,omitempty"`
+	Classes []string `json:"classes,omitempty"`
+}
+
+// Asset is a project file the page's markup loads.
+type Asset struct {
+	Path string // Project path
+	Kind string // "script", "stylesheet", "icon", "image" or "media"
+	Line int
+}
+
+var (
+	// Comments, raw text elements and start tags, in document order
+	markupPattern    = regexp.MustCompile(`(?is)<!--.*?-->|<(script)\b([^>]*)>(.*?)</script\s*>|<(style)\b([^>]*)>.*?</style\s*>|<([a-z][a-z0-9-]*)\b([^>]*)>`)
+	attributePattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	schemePattern    = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// ParsePage extracts the scripts, elements and assets of an HTML page at a project path.
+func ParsePage(pagePath, content string) *Page {
+	page := &Page{Path: pagePath}
+	lines := lineStarts(content)
+
+	for _, m := range markupPattern.FindAllStringSubmatchIndex(content, -1) {
+		var tag, attrs string
+		switch {
+		case m[2] >= 0:
+			tag, attrs = "script", content[m[4]:m[5]]
+		case m[8] >= 0:
+			tag, attrs = "style", content[m[10]:m[11]]
+		case m[12] >= 0:
+			tag, attrs = strings.ToLower(content[m[12]:m[13]]), content[m[14]:m[15]]
+		default:
+			continue // Comment
+		}
+
+		attributes := parseAttributes(attrs)
+		line := lineAt(lines, m[0])
+		page.Elements = append(page.Elements, Element{Tag: tag, ID: attributes["id"], Classes: strings.Fields(attributes["class"])})
+
+		switch {
+		case m[2] >= 0:
+			page.addScript(attributes, content[m[6]:m[7]], lineAt(lines, m[6]), line)
+		case tag != "script": // An unclosed script is never run
+			page.addAssets(tag, attributes, line)
+		}
+	}
+	return page
+}
+
+// addScript adds a script element; an external one is also an asset.
+func (p *Page) addScript(attributes map[string]string, code string, codeLine, line int) {
+	script := Script{Module: strings.EqualFold(attributes["type"], "module")}
+	if kind := strings.ToLower(attributes["type"]); kind != "" && kind != "module" && !strings.Contains(kind, "javascript") {
+		return // Data block, e.g. application/json or a template
+	}
+
+	src, hasSrc := attributes["src"]
+	switch {
+	case !hasSrc:
+		script.Code, script.Line = code, codeLine
+	case isRemote(src):
+		script.Remote = true
+	default:
+		resolved, ok := Resolve(p.Path, src)
+		if !ok {
+			return
+		}
+		script.Src, script.Line = resolved, line
+		p.Assets = append(p.Assets, Asset{Path: resolved, Kind: "script", Line: line})
+	}
+	p.Scripts = append(p.Scripts, script)
+}
+
+// addAssets adds the project files an element loads.
+func (p *Page) addAssets(tag string, attributes map[string]string, line int) {
+	add := func(ref, kind string) {
+		if resolved, ok := Resolve(p.Path, ref); ok {
+			p.Assets = append(p.Assets, Asset{Path: resolved, Kind: kind, Line: line})
+		}
+	}
+
+	switch tag {
+	case "link":
+		rel := strings.Fields(strings.ToLower(attributes["rel"]))
+		if contains(rel, "stylesheet") {
+			add(attributes["href"], "stylesheet")
+		} else if contains(rel, "icon") {
+			add(attributes["href"], "icon")
+		}
+	case "img":
+		add(attributes["src"], "image")
+	case "audio", "video", "source":
+		add(attributes["src"], "media")
+		if tag == "video" {
+			add(attributes["poster"], "image")
+		}
+	}
+}
+
+// Resolve returns the project path a reference from a page points to. It returns false for
+// references that don't load a project file: empty ones, fragments and other origins.
+func Resolve(pagePath, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if ref == "" || isRemote(ref) {
+		return "", false
+	}
+	if strings.HasPrefix(ref, "/") {
+		return strings.TrimPrefix(path.Clean(ref), "/"), true
+	}
+	return path.Join(path.Dir(pagePath), ref), true
+}
+
+// isRemote reports whether a reference has a scheme or is protocol-relative.
+func isRemote(ref string) bool {
+	return strings.HasPrefix(ref, "//") || schemePattern.MatchString(ref)
+}
+
+// parseAttributes returns the attributes of a start tag, with lowercase names.
+func parseAttributes(s string) map[string]string {
+	attributes := make(map[string]string)
+	for _, m := range attributePattern.FindAllStringSubmatch(s, -1) {
+		name := strings.ToLower(m[1])
+		if _, seen := attributes[name]; !seen {
+			attributes[name] = m[2] + m[3] + m[4]
+		}
+	}
+	return attributes
+}
+
+// lineStarts returns the offset of the first byte of each line.
+func lineStarts(content string) []int {
+	starts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineAt returns the 1-based line of an offset.
+func lineAt(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
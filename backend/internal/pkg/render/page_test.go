@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html>
+<head>
+  <link rel="stylesheet" href="css/style.css?v=2">
+  <link rel="icon" href="/favicon.ico">
+  <link rel="preconnect" href="https://fonts.example.com">
+  <script src="https://cdn.example.com/lib.js"></script>
+  <!-- <script src="old.js"></script> -->
+</head>
+<body class="dark wide">
+  <div id="app"><img src="../logo.png"></div>
+  <script type="application/json" id="data">{"a": 1}</script>
+  <script type="module" src="main.js"></script>
+  <script src="js/app.js"></script>
+  <script>
+    init();
+  </script>
+</body>
+</html>`
+
+	page := ParsePage("pages/index.html", content)
+
+	var assets []string
+	for _, a := range page.Assets {
+		assets = append(assets, fmt.Sprintf("%d %s %s", a.Line, a.Kind, a.Path))
+	}
+	want := "4 stylesheet pages/css/style.css; 5 icon favicon.ico; 11 image logo.png; 13 script pages/main.js; 14 script pages/js/app.js"
+	if got := strings.Join(assets, "; "); got != want {
+		t.Errorf("assets: expected %q, got %q", want, got)
+	}
+
+	if len(page.Scripts) != 4 {
+		t.Fatalf("expected 4 scripts, got %+v", page.Scripts)
+	}
+	if !page.Scripts[0].Remote || !page.Scripts[1].Module || page.Scripts[2].Src != "pages/js/app.js" {
+		t.Errorf("unexpected scripts: %+v", page.Scripts[:3])
+	}
+	if inline := page.Scripts[3]; inline.Line != 15 || strings.TrimSpace(inline.Code) != "init();" {
+		t.Errorf("inline script: expected line 15 and init();, got %+v", inline)
+	}
+
+	var body, app *Element
+	for i, e := range page.Elements {
+		switch {
+		case e.Tag == "body":
+			body = &page.Elements[i]
+		case e.ID == "app":
+			app = &page.Elements[i]
+		}
+	}
+	if body == nil || strings.Join(body.Classes, " ") != "dark wide" {
+		t.Errorf("expected body with classes dark wide, got %+v", body)
+	}
+	if app == nil || app.Tag != "div" {
+		t.Errorf("expected div#app, got %+v", app)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		page, ref, want string
+		ok              bool
+	}{
+		{"index.html", "js/app.js", "js/app.js", true},
+		{"docs/index.html", "../css/site.css", "css/site.css", true},
+		{"docs/index.html", "/img/a.png#top", "img/a.png", true},
+		{"index.html", "https://example.com/a.js", "", false},
+		{"index.html", "//cdn.example.com/a.js", "", false},
+		{"index.html", "data:image/png;base64,AAAA", "", false},
+		{"index.html", "#section", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Resolve(tt.page, tt.ref)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q, %q) = %q, %v; expected %q, %v", tt.page, tt.ref, got, ok, tt.want, tt.ok)
+		}
+	}
+}
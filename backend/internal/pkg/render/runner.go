@@ -0,0 +1,175 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sandboxScript is run by Node with the page and project files on stdin.
+//
+//go:embed sandbox.js
+var sandboxScript string
+
+// DefaultTimeout bounds the wall-clock time of checking one page.
+const DefaultTimeout = 10 * time.Second
+
+// permissionFlags are the flags that enable Node's permission model, newest first: Node
+// 22.13 and 23.5 renamed --experimental-permission, which Node 20 needs, to --permission.
+var permissionFlags = []string{"--permission", "--experimental-permission"}
+
+// sandboxFlags are the Node flags the sandbox runs with besides the permission flag. Code
+// generation from strings is allowed in the page's context but not the sandbox's own, so
+// nothing of the sandbox's realm that a page might get hold of can compile code; vm modules
+// let the sandbox reject the page's dynamic imports, which would otherwise load Node modules.
+var sandboxFlags = []string{"--max-old-space-size=256", "--disallow-code-generation-from-strings", "--experimental-vm-modules"}
+
+// Runner checks pages by running their scripts in Node 20 or later. Pages run in a vm context
+// whose globals, a minimal DOM without require, process or network, are all created inside
+// it, and their dynamic imports are rejected. Node's permission model additionally denies file
+// system and child process access, but not the network, so the context is what keeps pages
+// off it. Module scripts aren't run and are reported as unchecked.
+type Runner struct {
+	nodePath       string
+	permissionFlag string
+	timeout        time.Duration
+}
+
+// NewRunner creates a Runner using the Node binary at nodePath (e.g. "node"). It fails if
+// the binary can't be run or doesn't support the permission model.
+// A timeout of zero uses DefaultTimeout.
+func NewRunner(ctx context.Context, nodePath string, timeout time.Duration) (*Runner, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	flag, err := detectPermissionFlag(ctx, nodePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{nodePath: nodePath, permissionFlag: flag, timeout: timeout}, nil
+}
+
+// detectPermissionFlag returns the flag the Node binary at nodePath enables its permission
+// model with, by running an empty script with each in turn.
+func detectPermissionFlag(ctx context.Context, nodePath string) (string, error) {
+	var lastErr error
+	for _, flag := range permissionFlags {
+		probeCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+		cmd := exec.CommandContext(probeCtx, nodePath, flag, "-e", "")
+		cmd.Env = []string{}
+		output, err := cmd.CombinedOutput()
+		cancel()
+		if err == nil {
+			return flag, nil
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("run %s: %w", nodePath, err)
+		}
+		lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return "", fmt.Errorf("%s doesn't support the permission model; the render check needs Node 20 or later: %w", nodePath, lastErr)
+}
+
+// Check loads the page at pagePath with a project's files (path to content) and returns
+// its problems: local assets that don't exist, module scripts that weren't run, then what
+// went wrong running its other scripts.
+func (r *Runner) Check(ctx context.Context, pagePath string, files map[string]string) ([]Problem, error) {
+	content, ok := files[pagePath]
+	if !ok {
+		return nil, fmt.Errorf("page %s not found", pagePath)
+	}
+	page := ParsePage(pagePath, content)
+
+	var problems []Problem
+	for _, asset := range page.Assets {
+		if _, ok := files[asset.Path]; !ok {
+			problems = append(problems, Problem{
+				Kind:    ProblemAssetLoad,
+				Message: fmt.Sprintf("Failed to load %s %s", asset.Kind, asset.Path),
+				File:    pagePath,
+				Line:    asset.Line,
+				Asset:   asset.Path,
+			})
+		}
+	}
+
+	for _, script := range page.Scripts {
+		if script.Module {
+			name := "An inline module script"
+			if script.Src != "" {
+				name = "Module script " + script.Src
+			}
+			problems = append(problems, Problem{
+				Kind:    ProblemUnchecked,
+				Message: name + ` wasn't checked: scripts with type="module" aren't run by the render check`,
+				File:    pagePath,
+				Line:    script.Line,
+			})
+		}
+	}
+
+	if !page.hasRunnableScripts() {
+		return problems, nil
+	}
+
+	scriptProblems, err := r.run(ctx, page, files)
+	if err != nil {
+		return nil, err
+	}
+	return append(problems, scriptProblems...), nil
+}
+
+// run runs the page's scripts in Node.
+func (r *Runner) run(parent context.Context, page *Page, files map[string]string) ([]Problem, error) {
+	input, err := json.Marshal(map[string]interface{}{"page": page, "files": files})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, r.timeout)
+	defer cancel()
+
+	args := append([]string{r.permissionFlag}, sandboxFlags...)
+	cmd := exec.CommandContext(ctx, r.nodePath, append(args, "-e", sandboxScript)...)
+	cmd.Env = []string{} // Don't pass the server's secrets to the page
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return []Problem{{
+				Kind:    ProblemException,
+				Message: fmt.Sprintf("The page was still running after %s; a script may be stuck in a loop", r.timeout),
+				File:    page.Path,
+			}}, nil
+		}
+		return nil, fmt.Errorf("run %s: %w: %s", r.nodePath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output struct {
+		Problems []Problem `json:"problems"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("parse render output: %w", err)
+	}
+	return output.Problems, nil
+}
+
+// hasRunnableScripts reports whether the page has a script the sandbox would run.
+func (p *Page) hasRunnableScripts() bool {
+	for _, script := range p.Scripts {
+		if !script.Remote && !script.Module {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRunner returns a Runner using the node on PATH, skipping the test without one.
+func newTestRunner(t *testing.T, timeout time.Duration) *Runner {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	runner, err := NewRunner(context.Background(), node, timeout)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	return runner
+}
+
+// format renders problems as "kind file:line message" entries for compact comparison.
+func format(problems []Problem) string {
+	var parts []string
+	for _, p := range problems {
+		parts = append(parts, fmt.Sprintf("%s %s:%d %s", p.Kind, p.File, p.Line, p.Message))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestRunner_WorkingPage(t *testing.T) {
+	runner := newTestRunner(t, 0)
+	files := map[string]string{
+		"index.html": `<!DOCTYPE html>
+<html>
+<head><link rel="stylesheet" href="style.css"></head>
+<body>
+  <form id="todo-form"><input id="todo-input"><button type="submit">Add</button></form>
+  <ul id="todo-list" class="list"></ul>
+  <canvas id="chart"></canvas>
+  <script src="app.js"></script>
+</body>
+</html>`,
+		"style.css":  "body { margin: 0; }",
+		"todos.json": `[{"title": "Buy milk", "done": false}]`,
+		"app.js": `const form = document.getElementById('todo-form');
+const list = document.querySelector('#todo-list');
+const todos = JSON.parse(localStorage.getItem('todos') || '[]');
+
+function render() {
+  list.innerHTML = todos.map((t, i) => '<li class="todo" data-index="' + i + '">' + t.title + '</li>').join('');
+  document.querySelectorAll('.todo').forEach(li => li.addEventListener('click', () => li.classList.toggle('done')));
+}
+
+form.addEventListener('submit', e => {
+  e.preventDefault();
+  todos.push({ title: document.getElementById('todo-input').value });
+  localStorage.setItem('todos', JSON.stringify(todos));
+  render();
+});
+
+document.addEventListener('DOMContentLoaded', async () => {
+  const response = await fetch('todos.json');
+  todos.push(...await response.json());
+  render();
+  const ctx = document.getElementById('chart').getContext('2d');
+  ctx.fillRect(0, 0, 10, 10);
+  new IntersectionObserver(() => {}).observe(list);
+  requestAnimationFrame(function tick() { requestAnimationFrame(tick); });
+});`,
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("expected no problems, got:\n%s", format(problems))
+	}
+}
+
+func TestRunner_ReportsProblems(t *testing.T) {
+	runner := newTestRunner(t, 0)
+	files := map[string]string{
+		"index.html": `<!DOCTYPE html>
+<html>
+<head><link rel="stylesheet" href="css/missing.css"></head>
+<body>
+  <div id="app"></div>
+  <script src="js/app.js"></script>
+  <script src="js/broken.js"></script>
+  <script>
+    document.getElementById('start').addEventListener('click', start);
+  </script>
+</body>
+</html>`,
+		"js/app.js": `console.error('Failed to init', 42);
+setTimeout(() => updateScore(), 500);
+fetch('data/levels.json').then(r => r.json());
+`,
+		"js/broken.js": "function () {\n",
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"asset_load index.html:3 Failed to load stylesheet css/missing.css",
+		"console_error js/app.js:1 Failed to init 42",
+		"asset_load js/app.js:3 Failed to load resource data/levels.json",
+		"exception js/app.js:3 Uncaught (in promise) SyntaxError: Unexpected token '<', \"<!DOCTYPE \"... is not valid JSON",
+		"exception js/broken.js:1 Uncaught SyntaxError: Function statements require a function name",
+		"exception index.html:9 Uncaught TypeError: Cannot read properties of null (reading 'addEventListener')",
+		"exception js/app.js:2 Uncaught ReferenceError: updateScore is not defined",
+	}, "\n")
+	if got := format(problems); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+	if problems[0].Asset != "css/missing.css" || problems[2].Asset != "data/levels.json" {
+		t.Errorf("expected the missing assets to be set, got %q and %q", problems[0].Asset, problems[2].Asset)
+	}
+}
+
+func TestRunner_RemoteLibrariesDefineUnknownGlobals(t *testing.T) {
+	runner := newTestRunner(t, 0)
+	files := map[string]string{
+		"index.html": `<script src="https://cdn.example.com/chart.js"></script>
+<script>new Chart(document.getElementById('c'), {});</script>`,
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("expected no problems without the library, got:\n%s", format(problems))
+	}
+}
+
+func TestRunner_StopsInfiniteLoops(t *testing.T) {
+	runner := newTestRunner(t, 0)
+	files := map[string]string{
+		"index.html": "<script>\nsetInterval(() => { for (;;) {} }, 100);\n</script>",
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := format(problems); got != "exception index.html:0 A script ran for more than 1000ms; it may be stuck in an infinite loop" {
+		t.Errorf("unexpected problems: %s", got)
+	}
+
+	// A page that outlives the runner's timeout is reported as stuck too
+	runner.timeout = 50 * time.Millisecond
+	problems, err = runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := format(problems); got != "exception index.html:0 The page was still running after 50ms; a script may be stuck in a loop" {
+		t.Errorf("unexpected problems: %s", got)
+	}
+}
+
+func TestRunner_PagesCantReachNode(t *testing.T) {
+	runner := newTestRunner(t, 0)
+	files := map[string]string{
+		"index.html": "<script src=\"escape.js\"></script>",
+		"escape.js": `const attempts = {
+  global: () => this.constructor.constructor('return typeof process')(),
+  prototype: () => Object.getPrototypeOf(globalThis).constructor.constructor('return typeof process')(),
+  url: () => URL.constructor('return typeof process')(),
+  crypto: () => crypto.randomUUID.constructor('return typeof process')(),
+  listener: () => document.addEventListener.constructor('return typeof process')(),
+  control: () => __render.hold.constructor('return typeof process')(),
+  eval: () => eval('typeof process'),
+};
+for (const [name, attempt] of Object.entries(attempts)) {
+  if (attempt() !== 'undefined') {
+    console.error('Reached process through ' + name);
+  }
+}
+import('net').then(
+  () => console.error('Imported net'),
+  err => err.constructor.constructor('return typeof process')() !== 'undefined' && console.error('Reached process through import'),
+);`,
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("expected no problems, got:\n%s", format(problems))
+	}
+}
+
+func TestRunner_NoScripts(t *testing.T) {
+	runner := &Runner{nodePath: "/nonexistent/node", timeout: DefaultTimeout}
+	files := map[string]string{"index.html": `<img src="logo.png"><script src="https://cdn.example.com/a.js"></script>`}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got := format(problems); got != "asset_load index.html:1 Failed to load image logo.png" {
+		t.Errorf("unexpected problems: %s", got)
+	}
+}
+
+func TestRunner_ReportsModuleScriptsAsUnchecked(t *testing.T) {
+	runner := &Runner{nodePath: "/nonexistent/node", timeout: DefaultTimeout}
+	files := map[string]string{
+		"index.html": `<script type="module" src="js/main.js"></script>
+<script type="module">
+  import { start } from './js/main.js';
+</script>`,
+		"js/main.js": "export function start() {}",
+	}
+
+	problems, err := runner.Check(context.Background(), "index.html", files)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	want := strings.Join([]string{
+		`unchecked index.html:1 Module script js/main.js wasn't checked: scripts with type="module" aren't run by the render check`,
+		`unchecked index.html:2 An inline module script wasn't checked: scripts with type="module" aren't run by the render check`,
+	}, "\n")
+	if got := format(problems); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestNewRunner_DetectsPermissionFlag(t *testing.T) {
+	// A Node 20 stand-in that only knows the experimental flag
+	node := filepath.Join(t.TempDir(), "node")
+	script := "#!/bin/sh\n[ \"$1\" = --experimental-permission ] || { echo \"bad option: $1\" >&2; exit 9; }\n"
+	if err := os.WriteFile(node, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := NewRunner(context.Background(), node, 0)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	if runner.permissionFlag != "--experimental-permission" {
+		t.Errorf("expected --experimental-permission, got %q", runner.permissionFlag)
+	}
+}
+
+func TestNewRunner_RejectsNodeWithoutPermissionModel(t *testing.T) {
+	node := filepath.Join(t.TempDir(), "node")
+	if err := os.WriteFile(node, []byte("#!/bin/sh\necho \"bad option: $1\" >&2\nexit 9\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewRunner(context.Background(), node, 0)
+	if err == nil || !strings.Contains(err.Error(), "Node 20 or later") || !strings.Contains(err.Error(), "bad option: --experimental-permission") {
+		t.Errorf("expected an unsupported Node error, got %v", err)
+	}
+
+	if _, err := NewRunner(context.Background(), "/nonexistent/node", 0); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+}
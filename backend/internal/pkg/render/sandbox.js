@@ -0,0 +1,1144 @@
+// Loads a page in a minimal DOM, runs its scripts and reports problems as JSON on stdout.
+// Input on stdin: {"page": Page, "files": {path: content}}. There is no network: fetch and
+// XMLHttpRequest serve project files, and requests to other origins never complete.
+//
+// The DOM is permissive: APIs it doesn't model return inert stubs instead of failing, so
+// problems come from the page's own code (syntax errors, undefined variables, elements that
+// don't exist, thrown errors) rather than from gaps in the environment.
+'use strict';
+
+const util = require('util');
+const vm = require('vm');
+
+const input = require('fs').readFileSync(0, 'utf8');
+const { page, files } = JSON.parse(input);
+
+const SCRIPT_TIMEOUT_MS = 1000; // Per script or callback, to stop infinite loops
+const MAX_TIMER_CALLS = 500;
+
+// environment creates the page's globals. It's never called here: its source is run in the
+// page's context, so every object and function the page can reach belongs to that context's
+// realm. An object from this realm would hand the page this realm's Function constructor,
+// and through it process.
+function environment(input) {
+  'use strict';
+
+  const { page, files } = JSON.parse(input);
+
+  const MAX_PROBLEMS = 20;
+  const VIRTUAL_TIME_MS = 3000; // How long the page runs for; timers later than this don't fire
+  const MAX_TIMER_CALLS = 500;
+
+  const problems = [];
+  const reported = new Set();
+  const scriptFiles = new Set([page.path]);
+  let currentFile = page.path;
+  let remoteScripts = false; // Globals of remote libraries are unknown, so undefined variables aren't reported
+
+  function report(kind, message, where, asset) {
+    const key = [kind, message, where.file, where.line].join('|');
+    if (problems.length >= MAX_PROBLEMS || reported.has(key)) {
+      return;
+    }
+    reported.add(key);
+    problems.push({ kind, message: String(message).slice(0, 300), file: where.file, line: where.line, asset });
+  }
+
+  // locate returns the first project file and line in a stack trace.
+  function locate(stack) {
+    for (const line of String(stack || '').split('\n')) {
+      const m = /([^\s()]+?):(\d+)(?::\d+)?\)?\s*$/.exec(line);
+      if (m && scriptFiles.has(m[1])) {
+        return { file: m[1], line: Number(m[2]) };
+      }
+    }
+    return { file: currentFile };
+  }
+
+  function describe(value) {
+    if (value && typeof value === 'object' && 'message' in value) {
+      return `${value.name || 'Error'}: ${value.message}`;
+    }
+    if (typeof value === 'object') {
+      try {
+        return JSON.stringify(value);
+      } catch (e) {
+        return String(value);
+      }
+    }
+    return String(value);
+  }
+
+  function reportException(err, prefix) {
+    const message = describe(err);
+    if (remoteScripts && err && err.name === 'ReferenceError' && / is not defined$/.test(err.message)) {
+      return;
+    }
+    report('exception', (prefix || 'Uncaught ') + message, locate(err && err.stack));
+  }
+
+  // invoke calls a page function, reporting what it throws. The host's time limit covers
+  // everything a call to __render runs.
+  function invoke(fn, args, thisArg) {
+    try {
+      fn.apply(thisArg, args);
+    } catch (err) {
+      reportException(err);
+    }
+  }
+
+  // resolve returns the project path of a URL requested by the page, or null for other origins.
+  function resolve(url) {
+    let ref = String(url).trim().split(/[?#]/)[0];
+    if (ref === '' || ref.startsWith('//') || /^[a-z][a-z0-9+.-]*:/i.test(ref)) {
+      return null;
+    }
+    const parts = ref.startsWith('/') ? [] : page.path.split('/').slice(0, -1);
+    for (const part of ref.split('/')) {
+      if (part === '..') {
+        parts.pop();
+      } else if (part !== '.' && part !== '') {
+        parts.push(part);
+      }
+    }
+    return parts.join('/');
+  }
+
+  // load reports a failed load of a project file and returns its content, or null.
+  function load(url, kind) {
+    const resolved = resolve(url);
+    if (resolved === null) {
+      return null;
+    }
+    if (!(resolved in files)) {
+      report('asset_load', `Failed to load ${kind} ${resolved}`, locate(new Error().stack), resolved);
+      return null;
+    }
+    return files[resolved];
+  }
+
+  // stub is an inert value for APIs the environment doesn't model: every property is another
+  // stub and calling or constructing one returns a stub.
+  function stub() {
+    const props = new Map();
+    return new Proxy(function () {}, {
+      get(target, prop) {
+        if (prop === Symbol.toPrimitive) {
+          return () => '';
+        }
+        if (prop === Symbol.iterator) {
+          return function* () {};
+        }
+        if (typeof prop === 'symbol' || prop === 'then') {
+          return undefined;
+        }
+        if (!props.has(prop)) {
+          props.set(prop, stub());
+        }
+        return props.get(prop);
+      },
+      set(target, prop, value) {
+        props.set(prop, value);
+        return true;
+      },
+      apply: () => stub(),
+      construct: () => stub(),
+    });
+  }
+
+  // permissive wraps an object so that missing properties are stubs.
+  function permissive(obj) {
+    const stubs = new Map();
+    return new Proxy(obj, {
+      get(target, prop, receiver) {
+        if (prop in target || typeof prop === 'symbol' || prop === 'then' || prop === 'toJSON') {
+          return Reflect.get(target, prop, receiver);
+        }
+        if (!stubs.has(prop)) {
+          stubs.set(prop, stub());
+        }
+        return stubs.get(prop);
+      },
+    });
+  }
+
+  // Events
+
+  const listeners = new WeakMap();
+
+  function eventTarget(obj) {
+    listeners.set(obj, {});
+    obj.addEventListener = function (type, fn) {
+      const byType = listeners.get(obj);
+      (byType[type] = byType[type] || []).push(fn);
+    };
+    obj.removeEventListener = function (type, fn) {
+      const byType = listeners.get(obj);
+      byType[type] = (byType[type] || []).filter(f => f !== fn);
+    };
+    obj.dispatchEvent = function (event) {
+      const fns = [...(listeners.get(obj)[event.type] || [])];
+      const handler = obj['on' + event.type];
+      if (typeof handler === 'function') {
+        fns.push(handler);
+      }
+      for (const fn of fns) {
+        if (typeof fn === 'function') {
+          invoke(fn, [event], obj);
+        } else if (fn && typeof fn.handleEvent === 'function') {
+          invoke(fn.handleEvent, [event], fn);
+        }
+      }
+      return true;
+    };
+    return obj;
+  }
+
+  class Event {
+    constructor(type, init) {
+      Object.assign(this, init);
+      this.type = type;
+      this.defaultPrevented = false;
+    }
+    preventDefault() {
+      this.defaultPrevented = true;
+    }
+    stopPropagation() {}
+    stopImmediatePropagation() {}
+  }
+
+  class CustomEvent extends Event {}
+
+  // DOM
+
+  const elements = []; // Every element created, in creation order
+
+  function styleDeclaration() {
+    const values = {};
+    return new Proxy(values, {
+      get(target, prop) {
+        if (prop === 'setProperty') {
+          return (name, value) => { target[name] = value; };
+        }
+        if (prop === 'getPropertyValue') {
+          return name => target[name] || '';
+        }
+        if (prop === 'removeProperty') {
+          return name => { delete target[name]; };
+        }
+        return typeof prop === 'symbol' ? undefined : target[prop] || '';
+      },
+    });
+  }
+
+  function createElement(tag, id, classes) {
+    const attributes = {};
+    let classList = [...(classes || [])];
+    const el = eventTarget({
+      tagName: tag.toUpperCase(),
+      nodeName: tag.toUpperCase(),
+      nodeType: 1,
+      style: styleDeclaration(),
+      dataset: {},
+      children: [],
+      childNodes: [],
+      parentNode: null,
+      parentElement: null,
+      textContent: '',
+      innerText: '',
+      value: '',
+      checked: false,
+      disabled: false,
+      hidden: false,
+      offsetWidth: 0,
+      offsetHeight: 0,
+      scrollTop: 0,
+      scrollHeight: 0,
+      classList: {
+        add: (...names) => { classList = [...new Set([...classList, ...names])]; },
+        remove: (...names) => { classList = classList.filter(c => !names.includes(c)); },
+        toggle(name, force) {
+          const on = force === undefined ? !classList.includes(name) : Boolean(force);
+          on ? this.add(name) : this.remove(name);
+          return on;
+        },
+        contains: name => classList.includes(name),
+        replace(from, to) {
+          this.remove(from);
+          this.add(to);
+        },
+        forEach: fn => classList.forEach(fn),
+        get length() {
+          return classList.length;
+        },
+      },
+      get id() {
+        return attributes.id || '';
+      },
+      set id(value) {
+        attributes.id = String(value);
+      },
+      get className() {
+        return classList.join(' ');
+      },
+      set className(value) {
+        classList = String(value).split(/\s+/).filter(Boolean);
+      },
+      get innerHTML() {
+        return '';
+      },
+      set innerHTML(html) {
+        // Elements created from markup can be looked up by id and class afterwards
+        const tagPattern = /<([a-z][a-z0-9-]*)\b([^>]*)>/gi;
+        let m;
+        while ((m = tagPattern.exec(String(html)))) {
+          const id = /\bid\s*=\s*["']?([^"'\s>]+)/i.exec(m[2]);
+          const cls = /\bclass\s*=\s*["']([^"']*)["']/i.exec(m[2]);
+          el.appendChild(createElement(m[1], id && id[1], cls && cls[1].split(/\s+/).filter(Boolean)));
+        }
+      },
+      get src() {
+        return attributes.src || '';
+      },
+      set src(value) {
+        attributes.src = String(value);
+        if (['IMG', 'SCRIPT', 'AUDIO', 'VIDEO', 'SOURCE'].includes(el.tagName)) {
+          load(value, el.tagName === 'IMG' ? 'image' : el.tagName.toLowerCase());
+        }
+      },
+      getAttribute: name => (name === 'class' ? el.className : name in attributes ? attributes[name] : null),
+      setAttribute(name, value) {
+        if (name === 'class') {
+          el.className = value;
+        } else if (name === 'src') {
+          el.src = value;
+        } else {
+          attributes[name] = String(value);
+        }
+      },
+      removeAttribute: name => { delete attributes[name]; },
+      hasAttribute: name => name in attributes,
+      appendChild(child) {
+        if (child && typeof child === 'object') {
+          el.children.push(child);
+          el.childNodes.push(child);
+          child.parentNode = el;
+          child.parentElement = el;
+        }
+        return child;
+      },
+      append: (...nodes) => nodes.forEach(n => el.appendChild(n)),
+      prepend: (...nodes) => nodes.forEach(n => el.appendChild(n)),
+      insertBefore: child => el.appendChild(child),
+      insertAdjacentHTML(position, html) {
+        el.innerHTML = html;
+      },
+      replaceChildren(...nodes) {
+        el.children.length = 0;
+        el.childNodes.length = 0;
+        el.append(...nodes);
+      },
+      removeChild(child) {
+        const i = el.children.indexOf(child);
+        if (i >= 0) {
+          el.children.splice(i, 1);
+          el.childNodes.splice(el.childNodes.indexOf(child), 1);
+        }
+        return child;
+      },
+      remove() {
+        if (el.parentNode) {
+          el.parentNode.removeChild(proxy);
+        }
+      },
+      cloneNode: () => createElement(tag, undefined, classList),
+      contains: () => false,
+      matches: selector => matches(proxy, selector),
+      closest: selector => (matches(proxy, selector) ? proxy : createElement('div')),
+      querySelector: selector => query(selector, true),
+      querySelectorAll: selector => query(selector, false),
+      getElementsByClassName: name => query('.' + name, false),
+      getElementsByTagName: name => query(name, false),
+      getBoundingClientRect: () => ({ top: 0, left: 0, right: 0, bottom: 0, width: 0, height: 0, x: 0, y: 0 }),
+      click: () => el.dispatchEvent(new Event('click')),
+      focus() {},
+      blur() {},
+      scrollIntoView() {},
+      scrollTo() {},
+    });
+    if (id) {
+      attributes.id = id;
+    }
+    const proxy = permissive(el);
+    elements.push(proxy);
+    return proxy;
+  }
+
+  const simpleSelector = /^([a-z][a-z0-9-]*|\*)?(#[\w-]+)?((?:\.[\w-]+)*)$/i;
+
+  function matches(el, selector) {
+    const m = simpleSelector.exec(String(selector).trim());
+    if (!m) {
+      return false;
+    }
+    const [, tag, id, classes] = m;
+    return (!tag || tag === '*' || el.tagName === tag.toUpperCase()) &&
+      (!id || el.id === id.slice(1)) &&
+      classes.split('.').filter(Boolean).every(c => el.classList.contains(c));
+  }
+
+  // query finds elements by a simple selector (tag, #id, .class or a compound of them).
+  // Complex selectors aren't modelled: they match a detached element, or nothing for lists.
+  function query(selector, first) {
+    if (!simpleSelector.test(String(selector).trim())) {
+      return first ? createElement('div') : [];
+    }
+    const found = elements.filter(el => el.tagName !== '#TEXT' && matches(el, selector));
+    if (first) {
+      return found[0] || null;
+    }
+    return found;
+  }
+
+  function byTag(tag) {
+    return elements.find(el => el.tagName === tag.toUpperCase()) || createElement(tag);
+  }
+
+  for (const element of page.elements || []) {
+    createElement(element.tag, element.id, element.classes);
+  }
+
+  const document = permissive(eventTarget({
+    readyState: 'loading',
+    title: '',
+    cookie: '',
+    body: byTag('body'),
+    head: byTag('head'),
+    documentElement: byTag('html'),
+    currentScript: null,
+    getElementById: id => elements.find(el => el.id === String(id)) || null,
+    querySelector: selector => query(selector, true),
+    querySelectorAll: selector => query(selector, false),
+    getElementsByClassName: name => query('.' + name, false),
+    getElementsByTagName: name => query(name, false),
+    createElement: tag => createElement(String(tag)),
+    createTextNode: text => Object.assign(createElement('#text'), { textContent: String(text) }),
+    createDocumentFragment: () => createElement('#document-fragment'),
+  }));
+
+  // Network
+
+  function response(url, content) {
+    const ok = content !== null;
+    const body = ok ? content : '<!DOCTYPE html><html><body>Not Found</body></html>';
+    return {
+      ok,
+      status: ok ? 200 : 404,
+      statusText: ok ? 'OK' : 'Not Found',
+      url: String(url),
+      headers: { get: () => null },
+      text: async () => body,
+      json: async () => JSON.parse(body),
+      blob: async () => stub(),
+      arrayBuffer: async () => new ArrayBuffer(0),
+      clone() {
+        return response(url, content);
+      },
+    };
+  }
+
+  function fetch(input) {
+    const url = typeof input === 'object' && input && input.url ? input.url : input;
+    if (resolve(url) === null) {
+      return new Promise(() => {}); // No network
+    }
+    const content = load(url, 'resource');
+    return Promise.resolve(response(url, content));
+  }
+
+  class XMLHttpRequest {
+    constructor() {
+      eventTarget(this);
+      this.readyState = 0;
+      this.status = 0;
+      this.responseText = '';
+    }
+    open(method, url) {
+      this.url = url;
+      this.readyState = 1;
+    }
+    setRequestHeader() {}
+    abort() {}
+    send() {
+      if (resolve(this.url) === null) {
+        return; // No network
+      }
+      const content = load(this.url, 'resource');
+      timers.set(this, () => {
+        this.readyState = 4;
+        this.status = content === null ? 404 : 200;
+        this.responseText = content === null ? '' : content;
+        this.response = this.responseText;
+        this.dispatchEvent(new Event('readystatechange'));
+        this.dispatchEvent(new Event('load'));
+        this.dispatchEvent(new Event('loadend'));
+      }, 0);
+    }
+  }
+
+  // Timers run on a virtual clock, so the page's first few seconds pass instantly.
+
+  const timers = {
+    now: 0,
+    nextId: 1,
+    queue: [],
+    set(owner, fn, delay, args, repeat) {
+      const id = this.nextId++;
+      const wait = Math.max(0, Number(delay) || 0);
+      this.queue.push({ id, at: this.now + wait, fn, args: args || [], repeat: repeat ? Math.max(wait, 10) : 0 });
+      return id;
+    },
+    clear(id) {
+      this.queue = this.queue.filter(t => t.id !== id);
+    },
+    next() {
+      this.queue.sort((a, b) => a.at - b.at || a.id - b.id);
+      const timer = this.queue[0];
+      if (!timer || timer.at > VIRTUAL_TIME_MS) {
+        return null;
+      }
+      this.now = timer.at;
+      if (timer.repeat) {
+        timer.at += timer.repeat;
+      } else {
+        this.queue.shift();
+      }
+      return timer;
+    },
+  };
+
+  function storage() {
+    const items = new Map();
+    return {
+      getItem: key => (items.has(String(key)) ? items.get(String(key)) : null),
+      setItem: (key, value) => { items.set(String(key), String(value)); },
+      removeItem: key => { items.delete(String(key)); },
+      clear: () => items.clear(),
+      key: i => [...items.keys()][i] || null,
+      get length() {
+        return items.size;
+      },
+    };
+  }
+
+  class Observer {
+    observe() {}
+    unobserve() {}
+    disconnect() {}
+    takeRecords() {
+      return [];
+    }
+  }
+
+  function pageConsole() {
+    const quiet = () => {};
+    const error = (...args) => report('console_error', args.map(describe).join(' '), locate(new Error().stack));
+    return {
+      log: quiet,
+      info: quiet,
+      debug: quiet,
+      warn: quiet,
+      trace: quiet,
+      table: quiet,
+      group: quiet,
+      groupCollapsed: quiet,
+      groupEnd: quiet,
+      time: quiet,
+      timeEnd: quiet,
+      count: quiet,
+      error,
+      assert: (condition, ...args) => {
+        if (!condition) {
+          error('Assertion failed:', ...args);
+        }
+      },
+    };
+  }
+
+  // Web APIs that aren't part of JavaScript itself, kept to what pages commonly use.
+
+  class URLSearchParams {
+    #list = [];
+
+    constructor(init) {
+      if (typeof init === 'string') {
+        for (const pair of init.replace(/^\?/, '').split('&')) {
+          if (pair !== '') {
+            const i = pair.indexOf('=');
+            this.append(decodeQuery(i < 0 ? pair : pair.slice(0, i)), i < 0 ? '' : decodeQuery(pair.slice(i + 1)));
+          }
+        }
+      } else if (init && typeof init[Symbol.iterator] === 'function') {
+        for (const [name, value] of init) {
+          this.append(name, value);
+        }
+      } else if (init && typeof init === 'object') {
+        for (const name of Object.keys(init)) {
+          this.append(name, init[name]);
+        }
+      }
+    }
+    get size() {
+      return this.#list.length;
+    }
+    append(name, value) {
+      this.#list.push([String(name), String(value)]);
+    }
+    delete(name) {
+      this.#list = this.#list.filter(([n]) => n !== String(name));
+    }
+    get(name) {
+      const entry = this.#list.find(([n]) => n === String(name));
+      return entry ? entry[1] : null;
+    }
+    getAll(name) {
+      return this.#list.filter(([n]) => n === String(name)).map(([, v]) => v);
+    }
+    has(name) {
+      return this.#list.some(([n]) => n === String(name));
+    }
+    set(name, value) {
+      const i = this.#list.findIndex(([n]) => n === String(name));
+      if (i < 0) {
+        this.append(name, value);
+        return;
+      }
+      this.#list[i] = [String(name), String(value)];
+      this.#list = this.#list.filter(([n], j) => j <= i || n !== String(name));
+    }
+    sort() {
+      this.#list.sort(([a], [b]) => (a < b ? -1 : a > b ? 1 : 0));
+    }
+    forEach(fn, thisArg) {
+      for (const [name, value] of this.#list) {
+        fn.call(thisArg, value, name, this);
+      }
+    }
+    keys() {
+      return this.#list.map(([n]) => n)[Symbol.iterator]();
+    }
+    values() {
+      return this.#list.map(([, v]) => v)[Symbol.iterator]();
+    }
+    entries() {
+      return this.#list.map(([n, v]) => [n, v])[Symbol.iterator]();
+    }
+    [Symbol.iterator]() {
+      return this.entries();
+    }
+    toString() {
+      return this.#list.map(([n, v]) => encodeQuery(n) + '=' + encodeQuery(v)).join('&');
+    }
+  }
+
+  function decodeQuery(s) {
+    try {
+      return decodeURIComponent(s.replace(/\+/g, ' '));
+    } catch (e) {
+      return s;
+    }
+  }
+
+  function encodeQuery(s) {
+    return encodeURIComponent(s).replace(/%20/g, '+');
+  }
+
+  // removeDotSegments resolves "." and ".." in a URL path.
+  function removeDotSegments(path) {
+    const out = [];
+    const parts = path.split('/');
+    parts.forEach((part, i) => {
+      if (part === '..') {
+        if (out.length > 1) {
+          out.pop();
+        }
+      } else if (part !== '.') {
+        out.push(part);
+      }
+      if ((part === '.' || part === '..') && i === parts.length - 1) {
+        out.push('');
+      }
+    });
+    return out.join('/');
+  }
+
+  const absoluteURL = /^([a-z][a-z0-9+.-]*:)(?:\/\/([^/?#]*))?([^?#]*)(\?[^#]*)?(#.*)?$/i;
+
+  class URL {
+    #params = new URLSearchParams();
+
+    constructor(url, base) {
+      let ref = String(url).trim();
+      if (!absoluteURL.test(ref)) {
+        if (base === undefined) {
+          throw new TypeError(`Invalid URL: ${ref}`);
+        }
+        const b = new URL(base);
+        if (ref.startsWith('//')) {
+          ref = b.protocol + ref;
+        } else if (ref.startsWith('/')) {
+          ref = b.origin + ref;
+        } else if (ref.startsWith('?')) {
+          ref = b.origin + b.pathname + ref;
+        } else if (ref.startsWith('#')) {
+          ref = b.origin + b.pathname + b.search + ref;
+        } else if (ref === '') {
+          ref = b.origin + b.pathname + b.search;
+        } else {
+          ref = b.origin + b.pathname.replace(/[^/]*$/, '') + ref;
+        }
+      }
+      const [, protocol, host, path, search, hash] = absoluteURL.exec(ref);
+      this.protocol = protocol.toLowerCase();
+      this.host = (host || '').toLowerCase();
+      this.pathname = host === undefined ? path : removeDotSegments(path || '/');
+      this.search = search || '';
+      this.hash = hash || '';
+    }
+    static canParse(url, base) {
+      try {
+        new URL(url, base);
+        return true;
+      } catch (e) {
+        return false;
+      }
+    }
+    static createObjectURL() {
+      return 'blob:http://preview.local/' + randomUUID();
+    }
+    static revokeObjectURL() {}
+    get hostname() {
+      return this.host.replace(/:\d*$/, '');
+    }
+    get port() {
+      const m = /:(\d+)$/.exec(this.host);
+      return m ? m[1] : '';
+    }
+    get origin() {
+      return this.host ? this.protocol + '//' + this.host : 'null';
+    }
+    get search() {
+      const query = this.#params.toString();
+      return query ? '?' + query : '';
+    }
+    set search(value) {
+      for (const name of [...this.#params.keys()]) {
+        this.#params.delete(name);
+      }
+      new URLSearchParams(String(value)).forEach((v, n) => this.#params.append(n, v));
+    }
+    get searchParams() {
+      return this.#params;
+    }
+    get href() {
+      return (this.host || this.protocol === 'file:' ? this.protocol + '//' + this.host : this.protocol) + this.pathname + this.search + this.hash;
+    }
+    toString() {
+      return this.href;
+    }
+    toJSON() {
+      return this.href;
+    }
+  }
+
+  function randomUUID() {
+    const hex = [...Array(32)].map(() => Math.floor(Math.random() * 16).toString(16));
+    hex[12] = '4';
+    hex[16] = '89ab'[Math.floor(Math.random() * 4)];
+    return hex.join('').replace(/^(.{8})(.{4})(.{4})(.{4})/, '$1-$2-$3-$4-');
+  }
+
+  const loneSurrogate = /[\uD800-\uDBFF](?![\uDC00-\uDFFF])|(?<![\uD800-\uDBFF])[\uDC00-\uDFFF]/g;
+
+  class TextEncoder {
+    get encoding() {
+      return 'utf-8';
+    }
+    encode(text) {
+      const bytes = unescape(encodeURIComponent(String(text === undefined ? '' : text).replace(loneSurrogate, '\uFFFD')));
+      return Uint8Array.from(bytes, c => c.charCodeAt(0));
+    }
+  }
+
+  class TextDecoder {
+    get encoding() {
+      return 'utf-8';
+    }
+    decode(buffer) {
+      if (buffer === undefined) {
+        return '';
+      }
+      const bytes = ArrayBuffer.isView(buffer) ? new Uint8Array(buffer.buffer, buffer.byteOffset, buffer.byteLength) : new Uint8Array(buffer);
+      const binary = Array.from(bytes, b => String.fromCharCode(b)).join('');
+      try {
+        return decodeURIComponent(escape(binary));
+      } catch (e) {
+        return binary;
+      }
+    }
+  }
+
+  const base64 = 'ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/';
+
+  function btoa(data) {
+    const s = String(data);
+    if (/[^\x00-\xFF]/.test(s)) {
+      throw new DOMException('Invalid character', 'InvalidCharacterError');
+    }
+    let out = '';
+    for (let i = 0; i < s.length; i += 3) {
+      const n = (s.charCodeAt(i) << 16) | (s.charCodeAt(i + 1) << 8) | s.charCodeAt(i + 2);
+      out += base64[n >> 18 & 63] + base64[n >> 12 & 63] +
+        (i + 1 < s.length ? base64[n >> 6 & 63] : '=') + (i + 2 < s.length ? base64[n & 63] : '=');
+    }
+    return out;
+  }
+
+  function atob(data) {
+    const s = String(data).replace(/[\t\n\f\r ]/g, '').replace(/==?$/, '');
+    if (s.length % 4 === 1 || /[^A-Za-z0-9+/]/.test(s)) {
+      throw new DOMException('The string to be decoded is not correctly encoded.', 'InvalidCharacterError');
+    }
+    let out = '';
+    let bits = 0;
+    let n = 0;
+    for (const c of s) {
+      n = (n << 6) | base64.indexOf(c);
+      bits += 6;
+      if (bits >= 8) {
+        bits -= 8;
+        out += String.fromCharCode(n >> bits & 255);
+      }
+    }
+    return out;
+  }
+
+  class DOMException extends Error {
+    constructor(message, name) {
+      super(message);
+      this.name = name || 'Error';
+    }
+  }
+
+  class AbortController {
+    constructor() {
+      this.signal = eventTarget({
+        aborted: false,
+        reason: undefined,
+        throwIfAborted() {
+          if (this.aborted) {
+            throw this.reason;
+          }
+        },
+      });
+    }
+    abort(reason) {
+      if (!this.signal.aborted) {
+        this.signal.aborted = true;
+        this.signal.reason = reason === undefined ? new DOMException('This operation was aborted', 'AbortError') : reason;
+        this.signal.dispatchEvent(new Event('abort'));
+      }
+    }
+  }
+
+  function structuredClone(value) {
+    return clone(value, new Map());
+  }
+
+  function clone(value, seen) {
+    if (value === null || typeof value !== 'object') {
+      if (typeof value === 'function' || typeof value === 'symbol') {
+        throw new DOMException(`${String(value)} could not be cloned.`, 'DataCloneError');
+      }
+      return value;
+    }
+    if (seen.has(value)) {
+      return seen.get(value);
+    }
+    let copy;
+    if (value instanceof Date) {
+      copy = new Date(value.getTime());
+    } else if (value instanceof RegExp) {
+      copy = new RegExp(value.source, value.flags);
+    } else if (ArrayBuffer.isView(value)) {
+      copy = value.slice();
+    } else if (value instanceof ArrayBuffer) {
+      copy = value.slice(0);
+    } else if (value instanceof Map) {
+      copy = new Map();
+      seen.set(value, copy);
+      value.forEach((v, k) => copy.set(clone(k, seen), clone(v, seen)));
+    } else if (value instanceof Set) {
+      copy = new Set();
+      seen.set(value, copy);
+      value.forEach(v => copy.add(clone(v, seen)));
+    } else {
+      copy = Array.isArray(value) ? [] : {};
+      seen.set(value, copy);
+      for (const key of Object.keys(value)) {
+        copy[key] = clone(value[key], seen);
+      }
+    }
+    seen.set(value, copy);
+    return copy;
+  }
+
+  const window = eventTarget(globalThis);
+
+  Object.assign(window, {
+    console: pageConsole(),
+    document,
+    navigator: permissive({ userAgent: 'Mozilla/5.0 (headless)', language: 'en-US', languages: ['en-US'], onLine: false }),
+    location: permissive({ href: 'http://preview.local/' + page.path, origin: 'http://preview.local', protocol: 'http:', host: 'preview.local', hostname: 'preview.local', pathname: '/' + page.path, search: '', hash: '', reload() {}, assign() {}, replace() {} }),
+    history: permissive({ length: 1, state: null, pushState() {}, replaceState() {}, back() {}, forward() {}, go() {} }),
+    localStorage: storage(),
+    sessionStorage: storage(),
+    innerWidth: 1280,
+    innerHeight: 800,
+    devicePixelRatio: 1,
+    scrollX: 0,
+    scrollY: 0,
+    pageYOffset: 0,
+    setTimeout: (fn, delay, ...args) => timers.set(null, fn, delay, args, false),
+    setInterval: (fn, delay, ...args) => timers.set(null, fn, delay, args, true),
+    clearTimeout: id => timers.clear(id),
+    clearInterval: id => timers.clear(id),
+    requestAnimationFrame: fn => timers.set(null, fn, 16, [timers.now + 16], false),
+    cancelAnimationFrame: id => timers.clear(id),
+    requestIdleCallback: fn => timers.set(null, fn, 1, [stub()], false),
+    queueMicrotask: fn => Promise.resolve().then(fn),
+    alert() {},
+    confirm: () => true,
+    prompt: () => '',
+    open: () => null,
+    print() {},
+    scrollTo() {},
+    scrollBy() {},
+    matchMedia: query => eventTarget({ matches: false, media: String(query), addListener() {}, removeListener() {} }),
+    getComputedStyle: () => styleDeclaration(),
+    fetch,
+    XMLHttpRequest,
+    Event,
+    CustomEvent,
+    Image: function Image() {
+      return createElement('img');
+    },
+    Audio: function Audio() {
+      return createElement('audio');
+    },
+    IntersectionObserver: Observer,
+    ResizeObserver: Observer,
+    MutationObserver: Observer,
+    HTMLElement: class {},
+    customElements: { define() {}, get() {}, whenDefined: () => new Promise(() => {}) },
+    performance: { now: () => timers.now, mark() {}, measure() {} },
+    crypto: {
+      randomUUID,
+      getRandomValues(array) {
+        for (let i = 0; i < array.length; i++) {
+          array[i] = Math.floor(Math.random() * 256);
+        }
+        return array;
+      },
+    },
+    URL,
+    URLSearchParams,
+    TextEncoder,
+    TextDecoder,
+    AbortController,
+    DOMException,
+    structuredClone,
+    atob,
+    btoa,
+    WebSocket: stub(),
+    FormData: stub(),
+    Blob: stub(),
+    FileReader: stub(),
+    Notification: stub(),
+    speechSynthesis: stub(),
+  });
+  window.window = window.self = window.top = window.parent = window;
+
+  // __render is how the host drives the page. It passes and gets back only strings, numbers
+  // and booleans, except for hold, which takes back a value the page threw.
+  let thrown;
+  let thrownPrefix;
+  const render = Object.freeze({
+    enter(file) {
+      scriptFiles.add(file);
+      currentFile = file;
+    },
+    skipRemote() {
+      remoteScripts = true;
+    },
+    hold(value, prefix) {
+      thrown = value;
+      thrownPrefix = prefix;
+    },
+    reportThrown() {
+      const value = thrown;
+      thrown = undefined;
+      try {
+        reportException(value, thrownPrefix);
+      } catch (err) {
+        report('exception', thrownPrefix + 'exception', { file: currentFile }); // The value couldn't be described
+      }
+    },
+    reportSyntaxError(message, stack) {
+      report('exception', 'Uncaught ' + message, locate(stack));
+    },
+    hang(timeoutMs) {
+      report('exception', `A script ran for more than ${timeoutMs}ms; it may be stuck in an infinite loop`, { file: currentFile });
+    },
+    importError: specifier => new TypeError(`Failed to fetch dynamically imported module: ${specifier}; the render check doesn't load modules`),
+    ready() {
+      currentFile = page.path;
+      document.readyState = 'interactive';
+      document.dispatchEvent(new Event('DOMContentLoaded'));
+    },
+    load() {
+      document.readyState = 'complete';
+      window.dispatchEvent(new Event('load'));
+    },
+    nextTimer() {
+      const timer = timers.next();
+      if (!timer) {
+        return false;
+      }
+      if (typeof timer.fn === 'function') {
+        invoke(timer.fn, timer.args, window);
+      }
+      return true;
+    },
+    result: () => JSON.stringify({ problems }),
+  });
+  Object.defineProperty(globalThis, '__render', { value: render });
+  return render;
+}
+
+// The context's global object is created in its own realm where Node supports it; otherwise
+// an object without a prototype leaves nothing to climb from.
+const context = vm.createContext(vm.constants && vm.constants.DONT_CONTEXTIFY ? vm.constants.DONT_CONTEXTIFY : Object.create(null));
+
+// denyImport rejects the page's dynamic imports with an error of its own realm; without it
+// they would load this realm's modules.
+const denyImport = specifier => {
+  throw render.importError(String(specifier));
+};
+const options = { timeout: SCRIPT_TIMEOUT_MS, importModuleDynamically: denyImport };
+
+const render = vm.runInContext(`(${environment})(${JSON.stringify(input)})`, context, options);
+
+let hung = false; // A script timed out
+
+// isTimeout reports whether err is the error vm throws when a script runs out of time. It
+// only reads an own data property, since err may be a page object with getters or a Proxy.
+function isTimeout(err) {
+  if (err === null || typeof err !== 'object' || util.types.isProxy(err)) {
+    return false;
+  }
+  const code = Object.getOwnPropertyDescriptor(err, 'code');
+  return code !== undefined && code.value === 'ERR_SCRIPT_EXECUTION_TIMEOUT';
+}
+
+// reportThrown reports a value the page threw. Describing it may run page code (a getter,
+// say), so that happens in the page with a time limit.
+const reportThrownScript = new vm.Script('__render.reportThrown()');
+function reportThrown(err, prefix) {
+  if (isTimeout(err)) {
+    hung = true; // A browser tab would be frozen now, so nothing else runs
+    render.hang(SCRIPT_TIMEOUT_MS);
+    return;
+  }
+  render.hold(err, prefix || 'Uncaught ');
+  try {
+    reportThrownScript.runInContext(context, options);
+  } catch (e) {
+    if (isTimeout(e)) {
+      hung = true;
+      render.hang(SCRIPT_TIMEOUT_MS);
+    }
+  }
+}
+
+// runInPage runs a script in the page with a time limit, reporting what it throws.
+function runInPage(script) {
+  try {
+    return script.runInContext(context, options);
+  } catch (err) {
+    reportThrown(err);
+  }
+}
+
+process.on('unhandledRejection', reason => reportThrown(reason, 'Uncaught (in promise) '));
+
+const settle = () => new Promise(resolve => setImmediate(resolve));
+
+async function run() {
+  for (const script of page.scripts || []) {
+    if (hung) {
+      return;
+    }
+    if (script.remote) {
+      render.skipRemote();
+      continue;
+    }
+    if (script.module) {
+      continue; // Reported as unchecked by the runner
+    }
+    const filename = script.src || page.path;
+    const code = script.src ? files[script.src] : script.code;
+    if (typeof code !== 'string') {
+      continue; // Reported as a missing asset
+    }
+    render.enter(filename);
+
+    let compiled;
+    try {
+      compiled = new vm.Script(code, { filename, lineOffset: script.src ? 0 : (script.line || 1) - 1, importModuleDynamically: denyImport });
+    } catch (err) {
+      render.reportSyntaxError(`${err.name}: ${err.message}`, String(err.stack));
+      continue;
+    }
+    runInPage(compiled);
+    await settle();
+  }
+
+  if (hung) {
+    return;
+  }
+  runInPage(new vm.Script('__render.ready()'));
+  await settle();
+  runInPage(new vm.Script('__render.load()'));
+  await settle();
+
+  const nextTimer = new vm.Script('__render.nextTimer()');
+  for (let calls = 0; calls < MAX_TIMER_CALLS && !hung; calls++) {
+    if (runInPage(nextTimer) !== true) {
+      break;
+    }
+    await settle();
+  }
+}
+
+run().then(
+  () => {
+    const output = runInPage(new vm.Script('__render.result()'));
+    process.stdout.write(typeof output === 'string' ? output : '{"problems":[]}');
+    process.exit(0);
+  },
+  err => {
+    process.stderr.write(String(err && err.stack));
+    process.exit(1);
+  },
+);
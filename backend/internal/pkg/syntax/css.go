@@ -0,0 +1,60 @@
+package syntax
+
+import "strings"
+
+// checkCSS checks that a stylesheet's comments and strings are closed and its braces,
+// parentheses and brackets are balanced.
+func checkCSS(content string) []Error {
+	p := newPositioner(content)
+	b := &brackets{p: p}
+
+	for i := 0; i < len(content); {
+		switch c := content[i]; c {
+		case '/':
+			if strings.HasPrefix(content[i:], "/*") {
+				closing := strings.Index(content[i+2:], "*/")
+				if closing < 0 {
+					b.errors = append(b.errors, p.errorAt(i, "comment is never closed"))
+					return b.finish()
+				}
+				i += closing + 4
+				continue
+			}
+			i++
+		case '"', '\'':
+			end, closed := skipString(content, i)
+			if !closed {
+				b.errors = append(b.errors, p.errorAt(i, "string is never closed"))
+			}
+			i = end
+		case '\\':
+			i += 2
+		case '(', '[', '{':
+			b.push(i)
+			i++
+		case ')', ']', '}':
+			b.close(i)
+			i++
+		default:
+			i++
+		}
+	}
+	return b.finish()
+}
+
+// skipString returns the offset after the quoted string starting at content[i] and
+// whether it is closed. An unclosed string ends at the end of its line.
+func skipString(content string, i int) (int, bool) {
+	quote := content[i]
+	for j := i + 1; j < len(content); j++ {
+		switch content[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, true
+		case '\n':
+			return j, false
+		}
+	}
+	return len(content), false
+}
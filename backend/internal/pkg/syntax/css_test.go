@@ -0,0 +1,24 @@
+package syntax
+
+import "testing"
+
+func TestCheckCSS(t *testing.T) {
+	valid := `@media (min-width: 600px) {
+  .a::before { content: "}"; }
+  .b { width: calc(100% - (2px * 3)); }
+  a[href$='.pdf'] { background: url(icon\)s.png); }
+}
+/* { */`
+	if errs := checkCSS(valid); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+
+	content := "body {\n  color: red;\n  content: \"x;\n}\n.a { width: calc(100% - (2px); }\n/* never"
+	got := format(checkCSS(content))
+	want := "3:12: string is never closed; " +
+		"5:32: unexpected '}', expected ')' to close '(' from line 5; " +
+		"6:1: comment is never closed"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
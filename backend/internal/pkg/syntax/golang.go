@@ -0,0 +1,27 @@
+package syntax
+
+import (
+	"errors"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+)
+
+// checkGo parses content as a Go source file.
+func checkGo(content string) []Error {
+	_, err := parser.ParseFile(token.NewFileSet(), "", content, parser.AllErrors|parser.SkipObjectResolution)
+	if err == nil {
+		return nil
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return nil
+	}
+	p := newPositioner(content)
+	errs := make([]Error, 0, len(list))
+	for _, e := range list {
+		errs = append(errs, p.errorAt(e.Pos.Offset, "%s", e.Msg))
+	}
+	return errs
+}
@@ -0,0 +1,16 @@
+package syntax
+
+import "testing"
+
+func TestCheckGo(t *testing.T) {
+	valid := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	if errs := checkGo(valid); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+
+	got := format(Check("main.go", "package main\n\nfunc main() {\n\tx := \n}\n"))
+	want := "5:1: expected operand, found '}'; 6:1: expected ';', found 'EOF'; 6:1: expected '}', found 'EOF'"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
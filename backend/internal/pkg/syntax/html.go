@@ -0,0 +1,124 @@
+package syntax
+
+import "gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/htmltoken"
+
+// optionalEndElements may be left unclosed; the parser closes them implicitly.
+var optionalEndElements = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "tr": true, "td": true, "th": true, "thead": true,
+	"tbody": true, "tfoot": true, "colgroup": true, "caption": true, "rb": true, "rt": true,
+	"rtc": true, "rp": true,
+}
+
+// htmlElement is an open element.
+type htmlElement struct {
+	name   string
+	offset int
+}
+
+// checkHTML checks that an HTML document is well formed: comments, tags and attribute
+// values are closed, and elements are closed in order, allowing for void elements and
+// elements whose end tag is optional.
+func checkHTML(content string) []Error {
+	p := newPositioner(content)
+	var errs []Error
+	var open []htmlElement
+
+	for _, tok := range htmltoken.Tokenize(content) {
+		switch tok.Kind {
+		case htmltoken.Comment:
+			if tok.Unclosed {
+				return append(errs, p.errorAt(tok.Start, "comment is never closed"))
+			}
+		case htmltoken.Declaration:
+			if tok.Unclosed {
+				return append(errs, p.errorAt(tok.Start, "declaration is never closed"))
+			}
+		case htmltoken.EndTag:
+			if tok.Unclosed {
+				return append(errs, p.errorAt(tok.Start, "end tag </%s> is never closed", tok.Name))
+			}
+			open, errs = closeElement(p, open, errs, tok.Name, tok.Start)
+		case htmltoken.StartTag:
+			for _, a := range tok.Attrs {
+				if a.Unclosed {
+					return append(errs, p.errorAt(a.ValueStart-1, "attribute value is never closed"))
+				}
+			}
+			if tok.Unclosed {
+				return append(errs, p.errorAt(tok.Start, "tag is never closed"))
+			}
+			if !htmltoken.IsVoid(tok.Name) && !tok.SelfClosing {
+				open = openElement(open, tok.Name, tok.Start)
+			}
+		case htmltoken.Text:
+			if tok.Raw && tok.Unclosed {
+				return append(errs, p.errorAt(open[len(open)-1].offset, "<%s> is never closed", open[len(open)-1].name))
+			}
+		}
+	}
+
+	for _, e := range open {
+		if !optionalEndElements[e.name] {
+			errs = append(errs, p.errorAt(e.offset, "<%s> is never closed", e.name))
+		}
+	}
+	return errs
+}
+
+// impliedEnds maps elements to the open elements their start tag implicitly closes, such
+// as a list item closing the previous one.
+var impliedEnds = map[string][]string{
+	"li":       {"li"},
+	"dt":       {"dt", "dd"},
+	"dd":       {"dt", "dd"},
+	"p":        {"p"},
+	"option":   {"option"},
+	"optgroup": {"optgroup", "option"},
+	"tr":       {"tr", "td", "th"},
+	"td":       {"td", "th"},
+	"th":       {"td", "th"},
+	"thead":    {"tbody", "tfoot"},
+	"tbody":    {"thead", "tbody", "tfoot"},
+	"tfoot":    {"thead", "tbody"},
+}
+
+// openElement pushes an element opened at offset, first closing the open elements its
+// start tag implies the end of.
+func openElement(open []htmlElement, name string, offset int) []htmlElement {
+	for len(open) > 0 {
+		top := open[len(open)-1].name
+		implied := false
+		for _, end := range impliedEnds[name] {
+			if top == end {
+				implied = true
+			}
+		}
+		if !implied {
+			break
+		}
+		open = open[:len(open)-1]
+	}
+	return append(open, htmlElement{name: name, offset: offset})
+}
+
+// closeElement handles the end tag of name at offset: it closes the innermost open element
+// with that name, reporting open elements inside it that need an end tag, or reports the
+// end tag if no such element is open.
+func closeElement(p *positioner, open []htmlElement, errs []Error, name string, offset int) ([]htmlElement, []Error) {
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i].name != name {
+			continue
+		}
+		for j := len(open) - 1; j > i; j-- {
+			if !optionalEndElements[open[j].name] {
+				errs = append(errs, p.errorAt(open[j].offset, "<%s> is not closed before </%s> on line %d", open[j].name, name, p.line(offset)))
+			}
+		}
+		return open[:i], errs
+	}
+	if htmltoken.IsVoid(name) {
+		return open, append(errs, p.errorAt(offset, "<%s> is a void element and has no end tag", name))
+	}
+	return open, append(errs, p.errorAt(offset, "unexpected end tag </%s>", name))
+}
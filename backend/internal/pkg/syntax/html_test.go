@@ -0,0 +1,68 @@
+package syntax
+
+import "testing"
+
+func TestCheckHTML_Valid(t *testing.T) {
+	content := `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Home & <away></title>
+  <link rel="stylesheet" href="css/site.css" />
+  <!-- <div> in a comment -->
+</head>
+<body>
+  <ul><li>One<li>Two</ul>
+  <p>First<p>Second
+  <table><tr><td>a<td>b<tr><td>c</table>
+  <img src="a.png" alt='say "hi"'>
+  <script>if (a < b && "</div>") { go(); }</script>
+  <xmp><div> is shown as text</xmp>
+</body>
+</html>`
+	if errs := checkHTML(content); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+}
+
+func TestCheckHTML_Errors(t *testing.T) {
+	content := `<html>
+<body>
+<div class="a">
+<span>x</div>
+<br></br>
+</section>
+<main>
+</body>
+</html>
+`
+	got := format(checkHTML(content))
+	want := "4:1: <span> is not closed before </div> on line 4; " +
+		"5:5: <br> is a void element and has no end tag; " +
+		"6:1: unexpected end tag </section>; " +
+		"7:1: <main> is not closed before </body> on line 8"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCheckHTML_Unclosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"element", "<div>\n<section>", "1:1: <div> is never closed; 2:1: <section> is never closed"},
+		{"comment", "<p>a</p>\n<!-- b", "2:1: comment is never closed"},
+		{"tag", "<div\n  class=\"a\"", "1:1: tag is never closed"},
+		{"attribute value", `<div title="x>`, "1:12: attribute value is never closed"},
+		{"script", "<script>\nlet a = 1;", "1:1: <script> is never closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(checkHTML(tt.content)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package syntax
+
+import "github.com/evanw/esbuild/pkg/api"
+
+// scriptChecker returns a checker that parses content as JavaScript or TypeScript with
+// esbuild's parser, using loader to choose the dialect. Modules, top-level await and
+// syntax newer than any browser supports are accepted; types aren't checked.
+func scriptChecker(loader api.Loader) checker {
+	return func(content string) []Error {
+		result := api.Transform(content, api.TransformOptions{
+			Loader:   loader,
+			Target:   api.ESNext,
+			LogLevel: api.LogLevelSilent,
+		})
+		if len(result.Errors) == 0 {
+			return nil
+		}
+
+		p := newPositioner(content)
+		errs := make([]Error, 0, len(result.Errors))
+		for _, m := range result.Errors {
+			offset := len(content)
+			if loc := m.Location; loc != nil && loc.Line >= 1 && loc.Line <= len(p.lines) {
+				// esbuild reports 1-based lines and 0-based byte columns
+				offset = p.lines[loc.Line-1] + loc.Column
+			}
+			errs = append(errs, p.errorAt(offset, "%s", m.Text))
+		}
+		return errs
+	}
+}
@@ -0,0 +1,59 @@
+package syntax
+
+import "testing"
+
+func TestCheckJavaScript_Valid(t *testing.T) {
+	content := "#!/usr/bin/env node\n" +
+		"import { api } from './api.js';\n" +
+		"const re = /[/]}/g, half = a / b / c;\n" +
+		"const s = `a ${ {b: 1}.b } c ${`nested ${x}`}`;\n" +
+		"const data = await api.get('/items');\n" +
+		"data.count ||= 0;\n" +
+		"const o = { f() { return '}' + \"{\"; } }; // }\n" +
+		"/* ( */ export const t = (x) => x[0] / 2;\n"
+	if errs := Check("app.mjs", content); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+}
+
+func TestCheckJavaScript_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"grammar", "const = ;", `1:7: Expected identifier but found "="`},
+		{"missing parenthesis", "if x {}", `1:4: Expected "(" but found "x"`},
+		{"mismatched bracket", "function f() {\n  return (1;\n}\n", `2:12: Expected ")" but found ";"`},
+		{"unclosed string", "const t = 'oops\nf();", "1:16: Unterminated string literal"},
+		{"unclosed comment", "f();\n/* todo", `2:8: Expected "*/" to terminate multi-line comment`},
+		{"truncated", "export function f(a) {\n  if (a) {\n    g(", "3:7: Unexpected end of file"},
+		{"column in characters", "const é = 'ü'; const = 1;", `1:22: Expected identifier but found "="`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(Check("app.js", tt.content)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheck_TypeScript(t *testing.T) {
+	content := "interface User { name: string; tags: Array<string> }\nenum Role { Admin }\nconst u: User = { name: 'a', tags: [] };\n"
+	if errs := Check("src/user.ts", content); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+	if errs := Check("src/user.ts", "const u = { name: 'a';\n"); len(errs) != 1 || errs[0].Line != 1 || errs[0].Column != 22 {
+		t.Errorf("expected an error at 1:22, got %q", format(errs))
+	}
+}
+
+func TestCheck_JSX(t *testing.T) {
+	if errs := Check("src/App.tsx", "export const App = (p: { n: number }) => <div>{p.n} items</div>;\n"); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+	if errs := Check("src/App.jsx", "export const App = () => <div>items</span>;\n"); len(errs) == 0 {
+		t.Error("expected a mismatched closing tag")
+	}
+}
@@ -0,0 +1,26 @@
+package syntax
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// checkJSON parses content as a JSON document.
+func checkJSON(content string) []Error {
+	var value interface{}
+	err := json.Unmarshal([]byte(content), &value)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return nil
+	}
+	// The error occurred after reading Offset bytes, at the last one read
+	offset := int(syntaxErr.Offset) - 1
+	if offset < 0 || syntaxErr.Offset >= int64(len(content)) {
+		offset = int(syntaxErr.Offset)
+	}
+	return []Error{newPositioner(content).errorAt(offset, "%s", syntaxErr.Error())}
+}
@@ -0,0 +1,22 @@
+package syntax
+
+import "testing"
+
+func TestCheckJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"valid", `{"name": "app", "files": [1, 2]}`, ""},
+		{"trailing comma", "{\n  \"a\": 1,\n  \"b\": [1, 2,]\n}", "3:14: invalid character ']' looking for beginning of value"},
+		{"truncated", `{"a": 1`, "1:8: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(checkJSON(tt.content)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
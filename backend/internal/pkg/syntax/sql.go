@@ -0,0 +1,149 @@
+package syntax
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sqlStatementKeywords are the keywords a SQL statement can start with, across the
+// common dialects. Statements inside BEGIN ... END blocks are split out too.
+var sqlStatementKeywords = map[string]bool{
+	"ABORT": true, "ALTER": true, "ANALYZE": true, "ATTACH": true, "BEGIN": true, "CALL": true,
+	"CHECKPOINT": true, "CLOSE": true, "CLUSTER": true, "COMMENT": true, "COMMIT": true,
+	"COPY": true, "CREATE": true, "DEALLOCATE": true, "DECLARE": true, "DELETE": true,
+	"DELIMITER": true, "DETACH": true, "DISCARD": true, "DO": true, "DROP": true, "ELSE": true,
+	"ELSEIF": true, "END": true, "EXEC": true, "EXECUTE": true, "EXPLAIN": true, "FETCH": true,
+	"GRANT": true, "IF": true, "IMPORT": true, "INSERT": true, "LISTEN": true, "LOAD": true,
+	"LOCK": true, "LOOP": true, "MERGE": true, "MOVE": true, "NOTIFY": true, "OPEN": true,
+	"PRAGMA": true, "PREPARE": true, "REFRESH": true, "REINDEX": true, "RELEASE": true,
+	"RENAME": true, "REPLACE": true, "RESET": true, "RETURN": true, "REVOKE": true,
+	"ROLLBACK": true, "SAVEPOINT": true, "SECURITY": true, "SELECT": true, "SET": true,
+	"SHOW": true, "START": true, "TABLE": true, "TRUNCATE": true, "UNLISTEN": true,
+	"UPDATE": true, "USE": true, "VACUUM": true, "VALUES": true, "WHILE": true, "WITH": true,
+}
+
+// sqlDollarQuotePattern matches the opening of a PostgreSQL dollar-quoted string, e.g. "$body$".
+var sqlDollarQuotePattern = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// checkSQL checks that SQL has closed comments, strings and quoted identifiers, balanced
+// parentheses, and statements that start with a statement keyword.
+func checkSQL(content string) []Error {
+	p := newPositioner(content)
+	b := &brackets{p: p}
+	statementStart := true // Expecting the first word of a statement
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isSpace(c):
+			i++
+			continue
+		case strings.HasPrefix(content[i:], "--"):
+			i = lineEnd(content, i)
+			continue
+		case strings.HasPrefix(content[i:], "/*"):
+			closing := strings.Index(content[i+2:], "*/")
+			if closing < 0 {
+				b.errors = append(b.errors, p.errorAt(i, "comment is never closed"))
+				return b.finish()
+			}
+			i += closing + 4
+			continue
+		}
+
+		if statementStart && c != ';' {
+			statementStart = false
+			if c != '(' && !sqlStatementKeywords[strings.ToUpper(sqlWord(content, i))] {
+				b.errors = append(b.errors, p.errorAt(i, "statement starts with %q, not a SQL keyword", sqlWord(content, i)))
+			}
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			closing := sqlQuoteEnd(content, i)
+			if closing < 0 {
+				kind := "string"
+				if c != '\'' {
+					kind = "quoted identifier"
+				}
+				b.errors = append(b.errors, p.errorAt(i, "%s is never closed", kind))
+				return b.finish()
+			}
+			i = closing
+		case c == '$' && sqlDollarQuotePattern.MatchString(content[i:]):
+			tag := sqlDollarQuotePattern.FindString(content[i:])
+			closing := strings.Index(content[i+len(tag):], tag)
+			if closing < 0 {
+				b.errors = append(b.errors, p.errorAt(i, "dollar-quoted string %s is never closed", tag))
+				return b.finish()
+			}
+			i += len(tag) + closing + len(tag)
+		case c == '(':
+			b.push(i)
+			i++
+		case c == ')':
+			b.close(i)
+			i++
+		case c == ';':
+			if len(b.open) == 0 {
+				statementStart = true
+			}
+			i++
+		case isWordByte(c):
+			for i < len(content) && isWordByte(content[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return b.finish()
+}
+
+// sqlQuoteEnd returns the offset after the string or quoted identifier starting at
+// content[i], or -1 if it is never closed. A doubled quote is an escaped quote.
+func sqlQuoteEnd(content string, i int) int {
+	quote := content[i]
+	for j := i + 1; j < len(content); j++ {
+		if content[j] != quote {
+			continue
+		}
+		if j+1 < len(content) && content[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return -1
+}
+
+// sqlWord returns the word starting at content[i], or its first character if it isn't one.
+func sqlWord(content string, i int) string {
+	j := i
+	for j < len(content) && isWordByte(content[j]) {
+		j++
+	}
+	if j == i {
+		return content[i : i+1]
+	}
+	return content[i:j]
+}
+
+// isSpace reports whether c is ASCII white space.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// lineEnd returns the offset of the newline ending the line at content[i], or the content's end.
+func lineEnd(content string, i int) int {
+	if n := strings.IndexByte(content[i:], '\n'); n >= 0 {
+		return i + n
+	}
+	return len(content)
+}
+
+// isWordByte reports whether c can be part of an identifier, keyword or number.
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
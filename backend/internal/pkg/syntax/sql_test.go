@@ -0,0 +1,43 @@
+package syntax
+
+import "testing"
+
+func TestCheckSQL_Valid(t *testing.T) {
+	content := `-- Schema; with a semicolon
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  "name" TEXT NOT NULL DEFAULT 'it''s',
+  ` + "`tag`" + ` TEXT
+);
+/* ) */
+INSERT INTO users (id, "name") VALUES (1, 'a;b');
+CREATE FUNCTION f() RETURNS void AS $body$ BEGIN; ( $body$ LANGUAGE plpgsql;
+(SELECT 1) UNION (SELECT 2);
+`
+	if errs := checkSQL(content); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+}
+
+func TestCheckSQL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown statement", "SELECT 1;\nfoo bar;\n", `2:1: statement starts with "foo", not a SQL keyword`},
+		{"unclosed parenthesis", "SELECT (1;\n", "1:8: '(' is never closed"},
+		{"unexpected parenthesis", "SELECT 1);\n", "1:9: unexpected ')'"},
+		{"unclosed string", "SELECT 'x;\nSELECT 2;", "1:8: string is never closed"},
+		{"unclosed identifier", `SELECT "x FROM t;`, "1:8: quoted identifier is never closed"},
+		{"unclosed dollar quote", "DO $$ BEGIN", "1:4: dollar-quoted string $$ is never closed"},
+		{"unclosed comment", "SELECT 1; /* x", "1:11: comment is never closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(checkSQL(tt.content)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Package syntax checks that source files parse, reporting errors with their line and
+// column. Each language has a checker chosen by file extension. JSON, YAML, Go,
+// JavaScript and TypeScript are parsed fully; HTML, CSS and SQL are checked for the
+// mistakes generated code makes in practice, such as truncated output, unclosed tags,
+// strings and comments, and unbalanced brackets, rather than against their full grammar.
+package syntax
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// maxErrors limits the errors reported for one file; later errors usually follow from the first.
+const maxErrors = 10
+
+// Error is a syntax error in a file.
+type Error struct {
+	Line    int // 1-based
+	Column  int // 1-based, in characters; 0 if the parser doesn't report it
+	Message string
+}
+
+// Error formats the error as "line:column: message".
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// checker returns the syntax errors of a file's content.
+type checker func(content string) []Error
+
+// language is a checked language with its checker.
+type language struct {
+	name  string
+	check checker
+}
+
+// languages maps file extensions to the languages checked for them.
+var languages = map[string]language{
+	".json": {"json", checkJSON},
+	".yaml": {"yaml", checkYAML},
+	".yml":  {"yaml", checkYAML},
+	".html": {"html", checkHTML},
+	".htm":  {"html", checkHTML},
+	".css":  {"css", checkCSS},
+	".js":   {"javascript", scriptChecker(api.LoaderJS)},
+	".mjs":  {"javascript", scriptChecker(api.LoaderJS)},
+	".cjs":  {"javascript", scriptChecker(api.LoaderJS)},
+	".jsx":  {"javascript", scriptChecker(api.LoaderJSX)},
+	".ts":   {"typescript", scriptChecker(api.LoaderTS)},
+	".mts":  {"typescript", scriptChecker(api.LoaderTS)},
+	".cts":  {"typescript", scriptChecker(api.LoaderTS)},
+	".tsx":  {"typescript", scriptChecker(api.LoaderTSX)},
+	".go":   {"go", checkGo},
+	".sql":  {"sql", checkSQL},
+}
+
+// Language returns the language whose syntax is checked for a file, or "" if none is.
+func Language(filePath string) string {
+	lang, ok := lookup(filePath)
+	if !ok {
+		return ""
+	}
+	return lang.name
+}
+
+// Check returns the syntax errors of a file, or nil if it parses or its language isn't
+// checked. Duplicates are dropped and at most maxErrors are returned, sorted by position.
+func Check(filePath, content string) []Error {
+	lang, ok := lookup(filePath)
+	if !ok {
+		return nil
+	}
+
+	errs := lang.check(content)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	unique := errs[:0]
+	for _, e := range errs {
+		if len(unique) == 0 || e != unique[len(unique)-1] {
+			unique = append(unique, e)
+		}
+	}
+	errs = unique
+	if len(errs) > maxErrors {
+		errs = errs[:maxErrors]
+	}
+	return errs
+}
+
+// lookup returns the language checked for a file. JSON files that allow comments, such
+// as tsconfig.json, aren't checked.
+func lookup(filePath string) (language, bool) {
+	base := strings.ToLower(path.Base(filePath))
+	if strings.HasPrefix(base, "tsconfig") || strings.HasPrefix(base, "jsconfig") {
+		return language{}, false
+	}
+	lang, ok := languages[path.Ext(base)]
+	return lang, ok
+}
+
+// positioner converts byte offsets of content to lines and columns.
+type positioner struct {
+	content string
+	lines   []int // Offset of the first byte of each line
+}
+
+// newPositioner creates a positioner for content.
+func newPositioner(content string) *positioner {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &positioner{content: content, lines: lines}
+}
+
+// errorAt creates an error at a byte offset.
+func (p *positioner) errorAt(offset int, format string, args ...interface{}) Error {
+	offset = max(0, min(offset, len(p.content)))
+	line := sort.Search(len(p.lines), func(i int) bool { return p.lines[i] > offset })
+	start := p.lines[line-1]
+	return Error{
+		Line:    line,
+		Column:  utf8.RuneCountInString(p.content[start:offset]) + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// line returns the 1-based line of a byte offset.
+func (p *positioner) line(offset int) int {
+	return p.errorAt(offset, "").Line
+}
+
+// brackets matches opening and closing brackets, reporting those that are unbalanced.
+type brackets struct {
+	p      *positioner
+	open   []bracket
+	errors []Error
+}
+
+// bracket is an open bracket.
+type bracket struct {
+	char   byte // '(', '[' or '{'
+	offset int
+}
+
+// closers maps opening brackets to their closing ones.
+var closers = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+// push opens the bracket at offset.
+func (b *brackets) push(offset int) {
+	b.open = append(b.open, bracket{char: b.p.content[offset], offset: offset})
+}
+
+// top returns the innermost open bracket.
+func (b *brackets) top() (bracket, bool) {
+	if len(b.open) == 0 {
+		return bracket{}, false
+	}
+	return b.open[len(b.open)-1], true
+}
+
+// close closes the innermost bracket with the closing bracket at offset. A closing bracket
+// that matches an outer bracket also closes the ones inside it; one that matches none is
+// skipped.
+func (b *brackets) close(offset int) {
+	c := b.p.content[offset]
+	top, ok := b.top()
+	if !ok {
+		b.errors = append(b.errors, b.p.errorAt(offset, "unexpected %q", c))
+		return
+	}
+	if closers[top.char] == c {
+		b.open = b.open[:len(b.open)-1]
+		return
+	}
+
+	b.errors = append(b.errors, b.p.errorAt(offset, "unexpected %q, expected %q to close %q from line %d",
+		c, closers[top.char], top.char, b.p.line(top.offset)))
+	for i := len(b.open) - 2; i >= 0; i-- {
+		if closers[b.open[i].char] == c {
+			b.open = b.open[:i]
+			return
+		}
+	}
+}
+
+// finish reports the brackets still open at the end of the content.
+func (b *brackets) finish() []Error {
+	for i := len(b.open) - 1; i >= 0; i-- {
+		b.errors = append(b.errors, b.p.errorAt(b.open[i].offset, "%q is never closed", b.open[i].char))
+	}
+	return b.errors
+}
@@ -0,0 +1,83 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+// format renders errors as "line:column: message" joined by "; ".
+func format(errs []Error) string {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, e.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestLanguage(t *testing.T) {
+	tests := map[string]string{
+		"data/config.json": "json",
+		"compose.yml":      "yaml",
+		"index.HTML":       "html",
+		"css/site.css":     "css",
+		"js/app.mjs":       "javascript",
+		"src/main.ts":      "typescript",
+		"main.go":          "go",
+		"schema.sql":       "sql",
+		"tsconfig.json":    "",
+		"src/App.tsx":      "typescript",
+		"README.md":        "",
+	}
+	for filePath, want := range tests {
+		if got := Language(filePath); got != want {
+			t.Errorf("Language(%q): expected %q, got %q", filePath, want, got)
+		}
+	}
+}
+
+func TestCheck_UncheckedLanguage(t *testing.T) {
+	if errs := Check("notes.txt", "{{{"); errs != nil {
+		t.Errorf("expected no errors, got %q", format(errs))
+	}
+}
+
+func TestCheck_SortsAndLimitsErrors(t *testing.T) {
+	content := strings.Repeat(")\n", maxErrors+5) + "(\n"
+
+	errs := Check("style.css", content)
+	if len(errs) != maxErrors {
+		t.Fatalf("expected %d errors, got %d", maxErrors, len(errs))
+	}
+	for i, e := range errs {
+		if e.Line != i+1 {
+			t.Errorf("expected error %d on line %d, got %d", i, i+1, e.Line)
+		}
+	}
+}
+
+func TestPositioner_ColumnCountsCharacters(t *testing.T) {
+	p := newPositioner("ab\n€x")
+	e := p.errorAt(len("ab\n€"), "here")
+	if e.Line != 2 || e.Column != 2 {
+		t.Errorf("expected 2:2, got %d:%d", e.Line, e.Column)
+	}
+}
+
+func TestBrackets_Recovery(t *testing.T) {
+	p := newPositioner("{ ( ] }")
+	b := &brackets{p: p}
+	for i := 0; i < len(p.content); i++ {
+		switch p.content[i] {
+		case '(', '[', '{':
+			b.push(i)
+		case ')', ']', '}':
+			b.close(i)
+		}
+	}
+
+	got := format(b.finish())
+	want := `1:5: unexpected ']', expected ')' to close '(' from line 1; 1:7: unexpected '}', expected ')' to close '(' from line 1`
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,34 @@
+package syntax
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorPattern matches the line of a YAML parser error, e.g. "yaml: line 3: could not find expected ':'".
+var yamlErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// checkYAML parses content as a stream of YAML documents. The YAML parser reports lines
+// but not columns.
+func checkYAML(content string) []Error {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if m := yamlErrorPattern.FindStringSubmatch(err.Error()); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				return []Error{{Line: line, Message: m[2]}}
+			}
+			return []Error{{Line: 1, Message: strings.TrimPrefix(err.Error(), "yaml: ")}}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package syntax
+
+import "testing"
+
+func TestCheckYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"valid", "a: 1\nb:\n  - x\n  - y\n", ""},
+		{"several documents", "a: 1\n---\nb: 2\n", ""},
+		{"bad indentation", "a: 1\nb:\n  - x\n - y\n", "3:0: did not find expected key"},
+		{"unclosed flow sequence", "a: [1, 2\n", "1:0: did not find expected ',' or ']'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(checkYAML(tt.content)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
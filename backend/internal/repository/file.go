@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"path/filepath"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
 )
 
@@ -18,8 +20,14 @@ type FileRepository interface {
 	GetFilesWithContentByProject(ctx context.Context, projectID uuid.UUID) ([]model.File, error)
 	GetFile(ctx context.Context, id uuid.UUID) (*model.File, error)
 	GetFileByPath(ctx context.Context, projectID uuid.UUID, path string) (*model.File, error)
+	DeleteFile(ctx context.Context, id uuid.UUID) error
+	MoveFile(ctx context.Context, id uuid.UUID, newPath string) (*model.File, error)
+	ApplyOperations(ctx context.Context, projectID uuid.UUID, ops []model.FileOperation) error
 }
 
+// ErrPathExists is returned by MoveFile when the project already has a file at the new path.
+var ErrPathExists = errors.New("path already exists")
+
 // PostgresFileRepository implements FileRepository using PostgreSQL.
 type PostgresFileRepository struct {
 	db *sqlx.DB
@@ -32,25 +40,7 @@ func NewPostgresFileRepository(db *sqlx.DB) *PostgresFileRepository {
 
 // SaveFile saves or updates a file for a project (upsert by project_id + path).
 func (r *PostgresFileRepository) SaveFile(ctx context.Context, projectID uuid.UUID, path, language, content string) (*model.File, error) {
-	filename := filepath.Base(path)
-
-	query := `
-		INSERT INTO files (project_id, path, filename, language, content)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (project_id, path)
-		DO UPDATE SET
-			language = EXCLUDED.language,
-			content = EXCLUDED.content,
-			created_at = NOW()
-		RETURNING id, project_id, path, filename, language, content, created_at
-	`
-
-	var file model.File
-	if err := r.db.GetContext(ctx, &file, query, projectID, path, filename, language, content); err != nil {
-		return nil, err
-	}
-
-	return &file, nil
+	return saveFile(ctx, r.db, projectID, path, language, content)
 }
 
 // GetFilesByProject returns all files for a project (without content).
@@ -124,3 +114,162 @@ func (r *PostgresFileRepository) GetFileByPath(ctx context.Context, projectID uu
 
 	return &file, nil
 }
+
+// DeleteFile deletes a file together with its metadata and source record.
+// Its versions are kept, detached by the files foreign key, so it can be restored by path.
+func (r *PostgresFileRepository) DeleteFile(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteFile(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit file deletion: %w", err)
+	}
+
+	return nil
+}
+
+// MoveFile renames a file within its project. Metadata, source records and versions
+// reference the file by ID and stay attached. Returns ErrPathExists if newPath is taken.
+func (r *PostgresFileRepository) MoveFile(ctx context.Context, id uuid.UUID, newPath string) (*model.File, error) {
+	return moveFile(ctx, r.db, id, newPath)
+}
+
+// ApplyOperations applies a chat turn's file changes in order, in one transaction:
+// either all of them take effect or none do.
+func (r *PostgresFileRepository) ApplyOperations(ctx context.Context, projectID uuid.UUID, ops []model.FileOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, op := range ops {
+		if err := applyOperation(ctx, tx, projectID, op); err != nil {
+			return fmt.Errorf("operation %d (%s %s): %w", i+1, op.Type, op.Path, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit file operations: %w", err)
+	}
+
+	return nil
+}
+
+// applyOperation applies one file operation within a transaction.
+func applyOperation(ctx context.Context, tx *sqlx.Tx, projectID uuid.UUID, op model.FileOperation) error {
+	switch op.Type {
+	case model.FileOperationSave:
+		_, err := saveFile(ctx, tx, projectID, op.Path, op.Language, op.Content)
+		return err
+	case model.FileOperationDelete:
+		id, err := fileIDByPath(ctx, tx, projectID, op.Path)
+		if err != nil {
+			return err
+		}
+		return deleteFile(ctx, tx, id)
+	case model.FileOperationMove:
+		id, err := fileIDByPath(ctx, tx, projectID, op.Path)
+		if err != nil {
+			return err
+		}
+		_, err = moveFile(ctx, tx, id, op.NewPath)
+		return err
+	default:
+		return fmt.Errorf("unknown file operation %q", op.Type)
+	}
+}
+
+// saveFile upserts a file by project_id + path.
+func saveFile(ctx context.Context, q sqlx.QueryerContext, projectID uuid.UUID, path, language, content string) (*model.File, error) {
+	filename := filepath.Base(path)
+
+	query := `
+		INSERT INTO files (project_id, path, filename, language, content)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id, path)
+		DO UPDATE SET
+			language = EXCLUDED.language,
+			content = EXCLUDED.content,
+			created_at = NOW()
+		RETURNING id, project_id, path, filename, language, content, created_at
+	`
+
+	var file model.File
+	if err := sqlx.GetContext(ctx, q, &file, query, projectID, path, filename, language, content); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// deleteFile deletes a file with its metadata and source record; tx must be a transaction.
+func deleteFile(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM file_metadata WHERE file_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM file_sources WHERE file_id = $1`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM files WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// moveFile updates a file's path and filename.
+func moveFile(ctx context.Context, q sqlx.QueryerContext, id uuid.UUID, newPath string) (*model.File, error) {
+	query := `
+		UPDATE files
+		SET path = $2, filename = $3
+		WHERE id = $1
+		RETURNING id, project_id, path, filename, language, content, created_at
+	`
+
+	var file model.File
+	if err := sqlx.GetContext(ctx, q, &file, query, id, newPath, filepath.Base(newPath)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrPathExists
+		}
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// fileIDByPath looks up a file's ID by project and path.
+func fileIDByPath(ctx context.Context, q sqlx.QueryerContext, projectID uuid.UUID, path string) (uuid.UUID, error) {
+	var id uuid.UUID
+	if err := sqlx.GetContext(ctx, q, &id, `SELECT id FROM files WHERE project_id = $1 AND path = $2`, projectID, path); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+	return id, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// FileChangeRepository defines the interface for per-turn change set data access.
+type FileChangeRepository interface {
+	// CreateBatch records all file changes for a turn atomically.
+	CreateBatch(ctx context.Context, changes []model.FileChange) ([]model.FileChange, error)
+
+	// ListByMessageID returns the file changes made during an assistant message's turn, ordered by path.
+	ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]model.FileChange, error)
+}
+
+// PostgresFileChangeRepository implements FileChangeRepository using PostgreSQL.
+type PostgresFileChangeRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresFileChangeRepository creates a new PostgresFileChangeRepository.
+func NewPostgresFileChangeRepository(db *sqlx.DB) *PostgresFileChangeRepository {
+	return &PostgresFileChangeRepository{db: db}
+}
+
+// CreateBatch records all file changes for a turn atomically.
+func (r *PostgresFileChangeRepository) CreateBatch(ctx context.Context, changes []model.FileChange) ([]model.FileChange, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO file_changes (message_id, project_id, path, change_type, before_content, after_content, additions, deletions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, message_id, project_id, path, change_type, before_content, after_content, additions, deletions, created_at
+	`
+
+	created := make([]model.FileChange, 0, len(changes))
+	for _, change := range changes {
+		var fc model.FileChange
+		if err := tx.GetContext(ctx, &fc, query,
+			change.MessageID,
+			change.ProjectID,
+			change.Path,
+			change.ChangeType,
+			change.BeforeContent,
+			change.AfterContent,
+			change.Additions,
+			change.Deletions,
+		); err != nil {
+			return nil, err
+		}
+		created = append(created, fc)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file changes: %w", err)
+	}
+
+	return created, nil
+}
+
+// ListByMessageID returns the file changes made during an assistant message's turn, ordered by path.
+func (r *PostgresFileChangeRepository) ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]model.FileChange, error) {
+	query := `
+		SELECT id, message_id, project_id, path, change_type, before_content, after_content, additions, deletions, created_at
+		FROM file_changes
+		WHERE message_id = $1
+		ORDER BY path
+	`
+
+	var changes []model.FileChange
+	if err := r.db.SelectContext(ctx, &changes, query, messageID); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
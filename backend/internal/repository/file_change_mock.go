@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// MockFileChangeRepository implements FileChangeRepository for testing.
+type MockFileChangeRepository struct {
+	mu        sync.RWMutex
+	byMessage map[uuid.UUID][]model.FileChange
+}
+
+// NewMockFileChangeRepository creates a new MockFileChangeRepository.
+func NewMockFileChangeRepository() *MockFileChangeRepository {
+	return &MockFileChangeRepository{
+		byMessage: make(map[uuid.UUID][]model.FileChange),
+	}
+}
+
+// CreateBatch records all file changes for a turn atomically.
+func (r *MockFileChangeRepository) CreateBatch(ctx context.Context, changes []model.FileChange) ([]model.FileChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := make([]model.FileChange, 0, len(changes))
+	now := time.Now().UTC()
+	for _, change := range changes {
+		change.ID = uuid.New()
+		change.CreatedAt = now
+		r.byMessage[change.MessageID] = append(r.byMessage[change.MessageID], change)
+		created = append(created, change)
+	}
+
+	return created, nil
+}
+
+// ListByMessageID returns the file changes made during an assistant message's turn, ordered by path.
+func (r *MockFileChangeRepository) ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]model.FileChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := append([]model.FileChange(nil), r.byMessage[messageID]...)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// FileVersionRepository defines the interface for file version history data access.
+type FileVersionRepository interface {
+	// Create records a new version of a file, assigning the next version number. The first
+	// version of a file adopts the versions of deleted files at the same path, renumbered
+	// in the order they were written, so the numbering continues from them.
+	Create(ctx context.Context, version *model.FileVersion) (*model.FileVersion, error)
+
+	// ListByFileID returns all versions of a file (without content), newest first.
+	ListByFileID(ctx context.Context, fileID uuid.UUID) ([]model.FileVersionListItem, error)
+
+	// GetByVersion retrieves a specific version of a file.
+	GetByVersion(ctx context.Context, fileID uuid.UUID, version int) (*model.FileVersion, error)
+
+	// AttachMessage links versions written during a chat turn to the resulting assistant message.
+	AttachMessage(ctx context.Context, versionIDs []uuid.UUID, messageID uuid.UUID) error
+
+	// ListDeleted returns the latest version (without content) of each deleted file in a
+	// project whose path no file has taken since, ordered by path.
+	ListDeleted(ctx context.Context, projectID uuid.UUID) ([]model.FileVersionListItem, error)
+
+	// GetLatestDeleted retrieves the latest version of the deleted file at a path.
+	GetLatestDeleted(ctx context.Context, projectID uuid.UUID, path string) (*model.FileVersion, error)
+}
+
+// PostgresFileVersionRepository implements FileVersionRepository using PostgreSQL.
+type PostgresFileVersionRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresFileVersionRepository creates a new PostgresFileVersionRepository.
+func NewPostgresFileVersionRepository(db *sqlx.DB) *PostgresFileVersionRepository {
+	return &PostgresFileVersionRepository{db: db}
+}
+
+// Create records a new version of a file, assigning the next version number. The file's
+// row is locked while the number is assigned, so concurrent writes to the same file get
+// consecutive versions instead of colliding on unique_file_version. A file without
+// versions first adopts those left at its path by deleted files, renumbered from 1 in the
+// order they were written, since several deleted files may each have left a version 1.
+func (r *PostgresFileVersionRepository) Create(ctx context.Context, version *model.FileVersion) (*model.FileVersion, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fileID uuid.UUID
+	if err := tx.GetContext(ctx, &fileID, `SELECT id FROM files WHERE id = $1 FOR UPDATE`, version.FileID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	adopt := `
+		UPDATE file_versions v
+		SET file_id = $1, version = adopted.version
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, version, id) AS version
+			FROM file_versions
+			WHERE file_id IS NULL AND project_id = $2 AND path = $3
+		) adopted
+		WHERE v.id = adopted.id
+			AND NOT EXISTS (SELECT 1 FROM file_versions WHERE file_id = $1)
+	`
+	if _, err := tx.ExecContext(ctx, adopt, version.FileID, version.ProjectID, version.Path); err != nil {
+		return nil, fmt.Errorf("failed to adopt deleted file versions: %w", err)
+	}
+
+	query := `
+		INSERT INTO file_versions (file_id, project_id, version, path, language, content, source, message_id, agent_type, restored_from)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5, $6, $7, $8, $9
+		FROM file_versions
+		WHERE file_id = $1
+		RETURNING id, file_id, project_id, version, path, language, content, source, message_id, agent_type, restored_from, created_at
+	`
+
+	var created model.FileVersion
+	if err := tx.GetContext(ctx, &created, query,
+		version.FileID,
+		version.ProjectID,
+		version.Path,
+		version.Language,
+		version.Content,
+		version.Source,
+		version.MessageID,
+		version.AgentType,
+		version.RestoredFrom,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file version: %w", err)
+	}
+
+	return &created, nil
+}
+
+// ListByFileID returns all versions of a file (without content), newest first.
+func (r *PostgresFileVersionRepository) ListByFileID(ctx context.Context, fileID uuid.UUID) ([]model.FileVersionListItem, error) {
+	query := `
+		SELECT id, version, path, source, message_id, agent_type, restored_from, LENGTH(content) as size, created_at
+		FROM file_versions
+		WHERE file_id = $1
+		ORDER BY version DESC
+	`
+
+	var versions []model.FileVersionListItem
+	if err := r.db.SelectContext(ctx, &versions, query, fileID); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetByVersion retrieves a specific version of a file.
+func (r *PostgresFileVersionRepository) GetByVersion(ctx context.Context, fileID uuid.UUID, version int) (*model.FileVersion, error) {
+	query := `
+		SELECT id, file_id, project_id, version, path, language, content, source, message_id, agent_type, restored_from, created_at
+		FROM file_versions
+		WHERE file_id = $1 AND version = $2
+	`
+
+	var fileVersion model.FileVersion
+	if err := r.db.GetContext(ctx, &fileVersion, query, fileID, version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &fileVersion, nil
+}
+
+// AttachMessage links versions written during a chat turn to the resulting assistant message.
+func (r *PostgresFileVersionRepository) AttachMessage(ctx context.Context, versionIDs []uuid.UUID, messageID uuid.UUID) error {
+	if len(versionIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(versionIDs))
+	for i, id := range versionIDs {
+		ids[i] = id.String()
+	}
+
+	query := `UPDATE file_versions SET message_id = $1 WHERE id = ANY($2::uuid[])`
+
+	_, err := r.db.ExecContext(ctx, query, messageID, pq.Array(ids))
+	return err
+}
+
+// ListDeleted returns the latest version (without content) of each deleted file in a
+// project whose path no file has taken since, ordered by path.
+func (r *PostgresFileVersionRepository) ListDeleted(ctx context.Context, projectID uuid.UUID) ([]model.FileVersionListItem, error) {
+	query := `
+		SELECT DISTINCT ON (v.path) v.id, v.version, v.path, v.source, v.message_id, v.agent_type, v.restored_from, LENGTH(v.content) as size, v.created_at
+		FROM file_versions v
+		WHERE v.project_id = $1 AND v.file_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM files f WHERE f.project_id = v.project_id AND f.path = v.path)
+		ORDER BY v.path, v.version DESC
+	`
+
+	var versions []model.FileVersionListItem
+	if err := r.db.SelectContext(ctx, &versions, query, projectID); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetLatestDeleted retrieves the latest version of the deleted file at a path.
+func (r *PostgresFileVersionRepository) GetLatestDeleted(ctx context.Context, projectID uuid.UUID, path string) (*model.FileVersion, error) {
+	query := `
+		SELECT id, file_id, project_id, version, path, language, content, source, message_id, agent_type, restored_from, created_at
+		FROM file_versions
+		WHERE project_id = $1 AND path = $2 AND file_id IS NULL
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	var fileVersion model.FileVersion
+	if err := r.db.GetContext(ctx, &fileVersion, query, projectID, path); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &fileVersion, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// MockFileVersionRepository implements FileVersionRepository for testing.
+type MockFileVersionRepository struct {
+	mu       sync.RWMutex
+	versions map[uuid.UUID]*model.FileVersion // keyed by version ID
+	byFileID map[uuid.UUID][]uuid.UUID        // fileID -> version IDs in creation order
+	deleted  []uuid.UUID                      // Version IDs of deleted files in creation order
+}
+
+// NewMockFileVersionRepository creates a new MockFileVersionRepository.
+func NewMockFileVersionRepository() *MockFileVersionRepository {
+	return &MockFileVersionRepository{
+		versions: make(map[uuid.UUID]*model.FileVersion),
+		byFileID: make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+// Create records a new version of a file, assigning the next version number.
+func (r *MockFileVersionRepository) Create(ctx context.Context, version *model.FileVersion) (*model.FileVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.byFileID[version.FileID]) == 0 {
+		r.adopt(version.FileID, version.ProjectID, version.Path)
+	}
+
+	created := *version
+	created.ID = uuid.New()
+	created.Version = 1
+	if ids := r.byFileID[version.FileID]; len(ids) > 0 {
+		created.Version = r.versions[ids[len(ids)-1]].Version + 1
+	}
+	created.CreatedAt = time.Now().UTC()
+
+	r.versions[created.ID] = &created
+	r.byFileID[created.FileID] = append(r.byFileID[created.FileID], created.ID)
+
+	result := created
+	return &result, nil
+}
+
+// adopt moves the versions of deleted files at path to fileID, renumbered from 1 in the
+// order they were written. Callers must hold r.mu.
+func (r *MockFileVersionRepository) adopt(fileID, projectID uuid.UUID, path string) {
+	var adopted, kept []uuid.UUID
+	for _, id := range r.deleted {
+		if v := r.versions[id]; v.ProjectID == projectID && v.Path == path {
+			adopted = append(adopted, id)
+		} else {
+			kept = append(kept, id)
+		}
+	}
+	r.deleted = kept
+
+	sort.SliceStable(adopted, func(i, j int) bool {
+		a, b := r.versions[adopted[i]], r.versions[adopted[j]]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.Version < b.Version
+	})
+	for i, id := range adopted {
+		v := r.versions[id]
+		v.FileID = fileID
+		v.Version = i + 1
+	}
+	r.byFileID[fileID] = adopted
+}
+
+// DetachFile keeps the versions of a deleted file without it, as the files foreign key
+// does in PostgreSQL. Tests call it after deleting a file.
+func (r *MockFileVersionRepository) DetachFile(fileID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.byFileID[fileID] {
+		r.versions[id].FileID = uuid.Nil
+		r.deleted = append(r.deleted, id)
+	}
+	delete(r.byFileID, fileID)
+}
+
+// ListByFileID returns all versions of a file (without content), newest first.
+func (r *MockFileVersionRepository) ListByFileID(ctx context.Context, fileID uuid.UUID) ([]model.FileVersionListItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []model.FileVersionListItem
+	for _, id := range r.byFileID[fileID] {
+		result = append(result, versionListItem(r.versions[id]))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version > result[j].Version
+	})
+
+	return result, nil
+}
+
+// GetByVersion retrieves a specific version of a file.
+func (r *MockFileVersionRepository) GetByVersion(ctx context.Context, fileID uuid.UUID, version int) (*model.FileVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, id := range r.byFileID[fileID] {
+		if v := r.versions[id]; v.Version == version {
+			result := *v
+			return &result, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// AttachMessage links versions written during a chat turn to the resulting assistant message.
+func (r *MockFileVersionRepository) AttachMessage(ctx context.Context, versionIDs []uuid.UUID, messageID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range versionIDs {
+		if v, ok := r.versions[id]; ok {
+			msgID := messageID
+			v.MessageID = &msgID
+		}
+	}
+
+	return nil
+}
+
+// ListDeleted returns the latest version (without content) of each deleted file in a
+// project, ordered by path. A path stops being listed once a new file there records a version.
+func (r *MockFileVersionRepository) ListDeleted(ctx context.Context, projectID uuid.UUID) ([]model.FileVersionListItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	latest := make(map[string]*model.FileVersion)
+	for _, id := range r.deleted {
+		v := r.versions[id]
+		if v.ProjectID == projectID && (latest[v.Path] == nil || v.Version > latest[v.Path].Version) {
+			latest[v.Path] = v
+		}
+	}
+
+	var result []model.FileVersionListItem
+	for _, v := range latest {
+		result = append(result, versionListItem(v))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	return result, nil
+}
+
+// GetLatestDeleted retrieves the latest version of the deleted file at a path.
+func (r *MockFileVersionRepository) GetLatestDeleted(ctx context.Context, projectID uuid.UUID, path string) (*model.FileVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *model.FileVersion
+	for _, id := range r.deleted {
+		v := r.versions[id]
+		if v.ProjectID == projectID && v.Path == path && (latest == nil || v.Version > latest.Version) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+
+	result := *latest
+	return &result, nil
+}
+
+// versionListItem returns a version without its content.
+func versionListItem(v *model.FileVersion) model.FileVersionListItem {
+	return model.FileVersionListItem{
+		ID:           v.ID,
+		Version:      v.Version,
+		Path:         v.Path,
+		Source:       v.Source,
+		MessageID:    v.MessageID,
+		AgentType:    v.AgentType,
+		RestoredFrom: v.RestoredFrom,
+		Size:         len(v.Content),
+		CreatedAt:    v.CreatedAt,
+	}
+}
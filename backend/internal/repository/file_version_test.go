@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+func TestMockFileVersionRepository_Create_AdoptsDeletedVersions(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	write := func(t *testing.T, repo *MockFileVersionRepository, fileID uuid.UUID, path, content string) *model.FileVersion {
+		t.Helper()
+		v, err := repo.Create(ctx, &model.FileVersion{FileID: fileID, ProjectID: projectID, Path: path, Content: content, Source: model.FileVersionSourceTool})
+		require.NoError(t, err)
+		return v
+	}
+	contents := func(t *testing.T, repo *MockFileVersionRepository, fileID uuid.UUID) []string {
+		t.Helper()
+		versions, err := repo.ListByFileID(ctx, fileID)
+		require.NoError(t, err)
+		var result []string
+		for i, v := range versions {
+			assert.Equal(t, len(versions)-i, v.Version)
+			version, err := repo.GetByVersion(ctx, fileID, v.Version)
+			require.NoError(t, err)
+			result = append([]string{version.Content}, result...)
+		}
+		return result
+	}
+
+	t.Run("delete, recreate, delete and recreate at one path", func(t *testing.T) {
+		repo := NewMockFileVersionRepository()
+
+		first := uuid.New()
+		write(t, repo, first, "index.html", "a1")
+		write(t, repo, first, "index.html", "a2")
+		repo.DetachFile(first)
+
+		second := uuid.New()
+		assert.Equal(t, 3, write(t, repo, second, "index.html", "b1").Version)
+		repo.DetachFile(second)
+
+		third := uuid.New()
+		assert.Equal(t, 4, write(t, repo, third, "index.html", "c1").Version)
+		assert.Equal(t, []string{"a1", "a2", "b1", "c1"}, contents(t, repo, third))
+	})
+
+	t.Run("two deleted files with overlapping version numbers", func(t *testing.T) {
+		repo := NewMockFileVersionRepository()
+
+		first := uuid.New()
+		write(t, repo, first, "index.html", "a1")
+		write(t, repo, first, "index.html", "a2")
+		repo.DetachFile(first)
+
+		// A file moved to the path after its first version, then deleted, leaves a
+		// second version 2 at the path.
+		second := uuid.New()
+		write(t, repo, second, "draft.html", "b1")
+		write(t, repo, second, "index.html", "b2")
+		repo.DetachFile(second)
+
+		third := uuid.New()
+		assert.Equal(t, 4, write(t, repo, third, "index.html", "c1").Version)
+		assert.Equal(t, []string{"a1", "a2", "b2", "c1"}, contents(t, repo, third))
+
+		deleted, err := repo.ListDeleted(ctx, projectID)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, "draft.html", deleted[0].Path)
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -134,6 +135,22 @@ func (r *MockProjectRepository) GetMessages(ctx context.Context, projectID uuid.
 	return messages, nil
 }
 
+// GetMessage retrieves a message by ID.
+func (r *MockProjectRepository) GetMessage(ctx context.Context, id uuid.UUID) (*model.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, messages := range r.messages {
+		for _, message := range messages {
+			if message.ID == id {
+				return &message, nil
+			}
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
 // CreateMessage creates a new message without agent type.
 func (r *MockProjectRepository) CreateMessage(ctx context.Context, projectID uuid.UUID, role model.Role, content string) (*model.Message, error) {
 	return r.CreateMessageWithAgent(ctx, projectID, role, content, nil)
@@ -163,6 +180,23 @@ func (r *MockProjectRepository) CreateMessageWithAgent(ctx context.Context, proj
 	return &message, nil
 }
 
+// MarkMessageCancelled flags a message as a partial response that the user cancelled.
+func (r *MockProjectRepository) MarkMessageCancelled(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for projectID, messages := range r.messages {
+		for i := range messages {
+			if messages[i].ID == id {
+				r.messages[projectID][i].Cancelled = true
+				return nil
+			}
+		}
+	}
+
+	return ErrNotFound
+}
+
 // MockFileRepository implements FileRepository for testing.
 type MockFileRepository struct {
 	mu    sync.RWMutex
@@ -188,6 +222,11 @@ func (r *MockFileRepository) SaveFile(ctx context.Context, projectID uuid.UUID,
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	return r.saveLocked(projectID, path, language, content), nil
+}
+
+// saveLocked upserts a file; the caller holds the lock.
+func (r *MockFileRepository) saveLocked(projectID uuid.UUID, path, language, content string) *model.File {
 	pathKey := makePathKey(projectID, path)
 	now := time.Now().UTC()
 
@@ -197,7 +236,7 @@ func (r *MockFileRepository) SaveFile(ctx context.Context, projectID uuid.UUID,
 		file.Language = language
 		file.Content = content
 		file.CreatedAt = now // matches upsert behavior in real repo
-		return file, nil
+		return file
 	}
 
 	// Create new file
@@ -214,7 +253,7 @@ func (r *MockFileRepository) SaveFile(ctx context.Context, projectID uuid.UUID,
 	r.files[file.ID] = file
 	r.byPath[pathKey] = file.ID
 
-	return file, nil
+	return file
 }
 
 // GetFilesByProject returns all files for a project (without content).
@@ -284,3 +323,102 @@ func (r *MockFileRepository) GetFileByPath(ctx context.Context, projectID uuid.U
 
 	return file, nil
 }
+
+// DeleteFile deletes a file.
+func (r *MockFileRepository) DeleteFile(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, ok := r.files[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(r.byPath, makePathKey(file.ProjectID, file.Path))
+	delete(r.files, id)
+	return nil
+}
+
+// MoveFile renames a file within its project.
+func (r *MockFileRepository) MoveFile(ctx context.Context, id uuid.UUID, newPath string) (*model.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, ok := r.files[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	newKey := makePathKey(file.ProjectID, newPath)
+	if otherID, exists := r.byPath[newKey]; exists && otherID != id {
+		return nil, ErrPathExists
+	}
+
+	delete(r.byPath, makePathKey(file.ProjectID, file.Path))
+	file.Path = newPath
+	file.Filename = newPath // simplified for mock
+	r.byPath[newKey] = id
+	return file, nil
+}
+
+// ApplyOperations applies file operations in order. Like the transactional real
+// implementation, nothing is changed if any operation would fail.
+func (r *MockFileRepository) ApplyOperations(ctx context.Context, projectID uuid.UUID, ops []model.FileOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Validate against the resulting set of paths before changing anything
+	exists := func(path string) bool {
+		_, ok := r.byPath[makePathKey(projectID, path)]
+		return ok
+	}
+	overlay := make(map[string]bool)
+	present := func(path string) bool {
+		if v, ok := overlay[path]; ok {
+			return v
+		}
+		return exists(path)
+	}
+	for i, op := range ops {
+		switch op.Type {
+		case model.FileOperationSave:
+			overlay[op.Path] = true
+		case model.FileOperationDelete:
+			if !present(op.Path) {
+				return fmt.Errorf("operation %d (%s %s): %w", i+1, op.Type, op.Path, ErrNotFound)
+			}
+			overlay[op.Path] = false
+		case model.FileOperationMove:
+			if !present(op.Path) {
+				return fmt.Errorf("operation %d (%s %s): %w", i+1, op.Type, op.Path, ErrNotFound)
+			}
+			if present(op.NewPath) {
+				return fmt.Errorf("operation %d (%s %s): %w", i+1, op.Type, op.Path, ErrPathExists)
+			}
+			overlay[op.Path] = false
+			overlay[op.NewPath] = true
+		default:
+			return fmt.Errorf("unknown file operation %q", op.Type)
+		}
+	}
+
+	for _, op := range ops {
+		switch op.Type {
+		case model.FileOperationSave:
+			r.saveLocked(projectID, op.Path, op.Language, op.Content)
+		case model.FileOperationDelete:
+			key := makePathKey(projectID, op.Path)
+			delete(r.files, r.byPath[key])
+			delete(r.byPath, key)
+		case model.FileOperationMove:
+			key := makePathKey(projectID, op.Path)
+			id := r.byPath[key]
+			delete(r.byPath, key)
+			r.files[id].Path = op.NewPath
+			r.files[id].Filename = op.NewPath // simplified for mock
+			r.byPath[makePathKey(projectID, op.NewPath)] = id
+		}
+	}
+
+	return nil
+}
@@ -116,3 +116,116 @@ func TestMockProjectRepository_Messages(t *testing.T) {
 		assert.Equal(t, msg2.ID, messages[1].ID)
 	})
 }
+
+func TestMockFileRepository_DeleteFile(t *testing.T) {
+	t.Run("deletes existing file", func(t *testing.T) {
+		repo := NewMockFileRepository()
+		ctx := context.Background()
+		projectID := uuid.New()
+		file, _ := repo.SaveFile(ctx, projectID, "index-old.html", "html", "<html></html>")
+
+		err := repo.DeleteFile(ctx, file.ID)
+
+		require.NoError(t, err)
+		_, err = repo.GetFile(ctx, file.ID)
+		assert.Equal(t, ErrNotFound, err)
+		_, err = repo.GetFileByPath(ctx, projectID, "index-old.html")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("returns error when not found", func(t *testing.T) {
+		repo := NewMockFileRepository()
+
+		err := repo.DeleteFile(context.Background(), uuid.New())
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestMockFileRepository_MoveFile(t *testing.T) {
+	t.Run("moves file to a new path", func(t *testing.T) {
+		repo := NewMockFileRepository()
+		ctx := context.Background()
+		projectID := uuid.New()
+		file, _ := repo.SaveFile(ctx, projectID, "about.html", "html", "<p>About</p>")
+
+		moved, err := repo.MoveFile(ctx, file.ID, "pages/about.html")
+
+		require.NoError(t, err)
+		assert.Equal(t, file.ID, moved.ID)
+		assert.Equal(t, "pages/about.html", moved.Path)
+		assert.Equal(t, "<p>About</p>", moved.Content)
+		_, err = repo.GetFileByPath(ctx, projectID, "about.html")
+		assert.Equal(t, ErrNotFound, err)
+		found, err := repo.GetFileByPath(ctx, projectID, "pages/about.html")
+		require.NoError(t, err)
+		assert.Equal(t, file.ID, found.ID)
+	})
+
+	t.Run("returns error when the new path is taken", func(t *testing.T) {
+		repo := NewMockFileRepository()
+		ctx := context.Background()
+		projectID := uuid.New()
+		file, _ := repo.SaveFile(ctx, projectID, "a.css", "css", "a")
+		_, _ = repo.SaveFile(ctx, projectID, "b.css", "css", "b")
+
+		_, err := repo.MoveFile(ctx, file.ID, "b.css")
+
+		assert.Equal(t, ErrPathExists, err)
+		found, _ := repo.GetFileByPath(ctx, projectID, "a.css")
+		assert.Equal(t, "a", found.Content)
+	})
+
+	t.Run("returns error when not found", func(t *testing.T) {
+		repo := NewMockFileRepository()
+
+		_, err := repo.MoveFile(context.Background(), uuid.New(), "x.css")
+
+		assert.Equal(t, ErrNotFound, err)
+	})
+}
+
+func TestMockFileRepository_ApplyOperations(t *testing.T) {
+	t.Run("applies operations in order", func(t *testing.T) {
+		repo := NewMockFileRepository()
+		ctx := context.Background()
+		projectID := uuid.New()
+		index, _ := repo.SaveFile(ctx, projectID, "index.html", "html", "<h1>Hi</h1>")
+		_, _ = repo.SaveFile(ctx, projectID, "old.js", "javascript", "old()")
+
+		err := repo.ApplyOperations(ctx, projectID, []model.FileOperation{
+			{Type: model.FileOperationSave, Path: "app.js", Language: "javascript", Content: "app()"},
+			{Type: model.FileOperationDelete, Path: "old.js"},
+			{Type: model.FileOperationMove, Path: "index.html", NewPath: "home.html"},
+			{Type: model.FileOperationSave, Path: "home.html", Language: "html", Content: "<h1>Home</h1>"},
+		})
+
+		require.NoError(t, err)
+		home, err := repo.GetFileByPath(ctx, projectID, "home.html")
+		require.NoError(t, err)
+		assert.Equal(t, index.ID, home.ID)
+		assert.Equal(t, "<h1>Home</h1>", home.Content)
+		_, err = repo.GetFileByPath(ctx, projectID, "old.js")
+		assert.Equal(t, ErrNotFound, err)
+		_, err = repo.GetFileByPath(ctx, projectID, "app.js")
+		assert.NoError(t, err)
+	})
+
+	t.Run("changes nothing when an operation fails", func(t *testing.T) {
+		repo := NewMockFileRepository()
+		ctx := context.Background()
+		projectID := uuid.New()
+		_, _ = repo.SaveFile(ctx, projectID, "a.css", "css", "a")
+
+		err := repo.ApplyOperations(ctx, projectID, []model.FileOperation{
+			{Type: model.FileOperationSave, Path: "b.css", Language: "css", Content: "b"},
+			{Type: model.FileOperationDelete, Path: "a.css"},
+			{Type: model.FileOperationDelete, Path: "a.css"},
+		})
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		files, _ := repo.GetFilesByProject(ctx, projectID)
+		require.Len(t, files, 1)
+		assert.Equal(t, "a.css", files[0].Path)
+	})
+}
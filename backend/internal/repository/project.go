@@ -23,8 +23,10 @@ type ProjectRepository interface {
 	UpdateTimestamp(ctx context.Context, id uuid.UUID, timestamp time.Time) error
 	UpdateTitle(ctx context.Context, id uuid.UUID, title string) (*model.Project, error)
 	GetMessages(ctx context.Context, projectID uuid.UUID) ([]model.Message, error)
+	GetMessage(ctx context.Context, id uuid.UUID) (*model.Message, error)
 	CreateMessage(ctx context.Context, projectID uuid.UUID, role model.Role, content string) (*model.Message, error)
 	CreateMessageWithAgent(ctx context.Context, projectID uuid.UUID, role model.Role, content string, agentType *string) (*model.Message, error)
+	MarkMessageCancelled(ctx context.Context, id uuid.UUID) error
 }
 
 // PostgresProjectRepository implements ProjectRepository using PostgreSQL.
@@ -156,7 +158,7 @@ func (r *PostgresProjectRepository) UpdateTitle(ctx context.Context, id uuid.UUI
 // GetMessages returns all messages for a project.
 func (r *PostgresProjectRepository) GetMessages(ctx context.Context, projectID uuid.UUID) ([]model.Message, error) {
 	query := `
-		SELECT id, project_id, role, content, agent_type, created_at
+		SELECT id, project_id, role, content, agent_type, cancelled, created_at
 		FROM messages
 		WHERE project_id = $1
 		ORDER BY created_at ASC
@@ -170,6 +172,25 @@ func (r *PostgresProjectRepository) GetMessages(ctx context.Context, projectID u
 	return messages, nil
 }
 
+// GetMessage retrieves a message by ID.
+func (r *PostgresProjectRepository) GetMessage(ctx context.Context, id uuid.UUID) (*model.Message, error) {
+	query := `
+		SELECT id, project_id, role, content, agent_type, cancelled, created_at
+		FROM messages
+		WHERE id = $1
+	`
+
+	var message model.Message
+	if err := r.db.GetContext(ctx, &message, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &message, nil
+}
+
 // CreateMessage creates a new message without agent type (for backwards compatibility).
 func (r *PostgresProjectRepository) CreateMessage(ctx context.Context, projectID uuid.UUID, role model.Role, content string) (*model.Message, error) {
 	return r.CreateMessageWithAgent(ctx, projectID, role, content, nil)
@@ -180,7 +201,7 @@ func (r *PostgresProjectRepository) CreateMessageWithAgent(ctx context.Context,
 	query := `
 		INSERT INTO messages (project_id, role, content, agent_type)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, project_id, role, content, agent_type, created_at
+		RETURNING id, project_id, role, content, agent_type, cancelled, created_at
 	`
 
 	var message model.Message
@@ -190,3 +211,22 @@ func (r *PostgresProjectRepository) CreateMessageWithAgent(ctx context.Context,
 
 	return &message, nil
 }
+
+// MarkMessageCancelled flags a message as a partial response that the user cancelled.
+func (r *PostgresProjectRepository) MarkMessageCancelled(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE messages SET cancelled = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
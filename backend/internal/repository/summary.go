@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// SummaryRepository defines the interface for conversation summary data access.
+type SummaryRepository interface {
+	// Create stores a new conversation summary.
+	Create(ctx context.Context, summary *model.ConversationSummary) (*model.ConversationSummary, error)
+
+	// ListByProject returns all summaries for a project, oldest first.
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ConversationSummary, error)
+
+	// GetLatest returns the most recent summary for a project.
+	GetLatest(ctx context.Context, projectID uuid.UUID) (*model.ConversationSummary, error)
+}
+
+// PostgresSummaryRepository implements SummaryRepository using PostgreSQL.
+type PostgresSummaryRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresSummaryRepository creates a new PostgresSummaryRepository.
+func NewPostgresSummaryRepository(db *sqlx.DB) *PostgresSummaryRepository {
+	return &PostgresSummaryRepository{db: db}
+}
+
+// Create stores a new conversation summary.
+func (r *PostgresSummaryRepository) Create(ctx context.Context, summary *model.ConversationSummary) (*model.ConversationSummary, error) {
+	query := `
+		INSERT INTO conversation_summaries (project_id, content, through_message_id, through_created_at, message_count, trigger, prd_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, project_id, content, through_message_id, through_created_at, message_count, trigger, prd_id, created_at
+	`
+
+	var created model.ConversationSummary
+	if err := r.db.GetContext(ctx, &created, query,
+		summary.ProjectID,
+		summary.Content,
+		summary.ThroughMessageID,
+		summary.ThroughCreatedAt,
+		summary.MessageCount,
+		summary.Trigger,
+		summary.PRDID,
+	); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// ListByProject returns all summaries for a project, oldest first.
+func (r *PostgresSummaryRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ConversationSummary, error) {
+	query := `
+		SELECT id, project_id, content, through_message_id, through_created_at, message_count, trigger, prd_id, created_at
+		FROM conversation_summaries
+		WHERE project_id = $1
+		ORDER BY created_at ASC
+	`
+
+	var summaries []model.ConversationSummary
+	if err := r.db.SelectContext(ctx, &summaries, query, projectID); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// GetLatest returns the most recent summary for a project.
+func (r *PostgresSummaryRepository) GetLatest(ctx context.Context, projectID uuid.UUID) (*model.ConversationSummary, error) {
+	query := `
+		SELECT id, project_id, content, through_message_id, through_created_at, message_count, trigger, prd_id, created_at
+		FROM conversation_summaries
+		WHERE project_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var summary model.ConversationSummary
+	if err := r.db.GetContext(ctx, &summary, query, projectID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &summary, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// MockSummaryRepository implements SummaryRepository for testing.
+type MockSummaryRepository struct {
+	mu        sync.RWMutex
+	summaries map[uuid.UUID][]model.ConversationSummary // projectID -> summaries in creation order
+}
+
+// NewMockSummaryRepository creates a new MockSummaryRepository.
+func NewMockSummaryRepository() *MockSummaryRepository {
+	return &MockSummaryRepository{
+		summaries: make(map[uuid.UUID][]model.ConversationSummary),
+	}
+}
+
+// Create stores a new conversation summary.
+func (r *MockSummaryRepository) Create(ctx context.Context, summary *model.ConversationSummary) (*model.ConversationSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := *summary
+	created.ID = uuid.New()
+	created.CreatedAt = time.Now().UTC()
+	r.summaries[created.ProjectID] = append(r.summaries[created.ProjectID], created)
+
+	return &created, nil
+}
+
+// ListByProject returns all summaries for a project, oldest first.
+func (r *MockSummaryRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]model.ConversationSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]model.ConversationSummary(nil), r.summaries[projectID]...), nil
+}
+
+// GetLatest returns the most recent summary for a project.
+func (r *MockSummaryRepository) GetLatest(ctx context.Context, projectID uuid.UUID) (*model.ConversationSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	summaries := r.summaries[projectID]
+	if len(summaries) == 0 {
+		return nil, ErrNotFound
+	}
+
+	latest := summaries[len(summaries)-1]
+	return &latest, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// UsageRepository defines the interface for token usage data access.
+type UsageRepository interface {
+	// Create records the usage of one Claude API call.
+	Create(ctx context.Context, record *model.UsageRecord) (*model.UsageRecord, error)
+
+	// AttachMessage links usage recorded during a chat turn to the resulting assistant message.
+	AttachMessage(ctx context.Context, recordIDs []uuid.UUID, messageID uuid.UUID) error
+
+	// ListByProject returns a project's usage records created at or after since, oldest first.
+	ListByProject(ctx context.Context, projectID uuid.UUID, since time.Time) ([]model.UsageRecord, error)
+}
+
+// PostgresUsageRepository implements UsageRepository using PostgreSQL.
+type PostgresUsageRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresUsageRepository creates a new PostgresUsageRepository.
+func NewPostgresUsageRepository(db *sqlx.DB) *PostgresUsageRepository {
+	return &PostgresUsageRepository{db: db}
+}
+
+// Create records the usage of one Claude API call.
+func (r *PostgresUsageRepository) Create(ctx context.Context, record *model.UsageRecord) (*model.UsageRecord, error) {
+	query := `
+		INSERT INTO token_usage (project_id, message_id, agent_type, source, model, route,
+			input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens,
+			cost_usd, cache_savings_usd)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, project_id, message_id, agent_type, source, model, route,
+			input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens,
+			cost_usd, cache_savings_usd, created_at
+	`
+
+	var created model.UsageRecord
+	if err := r.db.GetContext(ctx, &created, query,
+		record.ProjectID,
+		record.MessageID,
+		record.AgentType,
+		record.Source,
+		record.Model,
+		record.Route,
+		record.InputTokens,
+		record.OutputTokens,
+		record.CacheCreationInputTokens,
+		record.CacheReadInputTokens,
+		record.CostUSD,
+		record.CacheSavingsUSD,
+	); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// AttachMessage links usage recorded during a chat turn to the resulting assistant message.
+func (r *PostgresUsageRepository) AttachMessage(ctx context.Context, recordIDs []uuid.UUID, messageID uuid.UUID) error {
+	if len(recordIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(recordIDs))
+	for i, id := range recordIDs {
+		ids[i] = id.String()
+	}
+
+	query := `UPDATE token_usage SET message_id = $1 WHERE id = ANY($2::uuid[])`
+
+	_, err := r.db.ExecContext(ctx, query, messageID, pq.Array(ids))
+	return err
+}
+
+// ListByProject returns a project's usage records created at or after since, oldest first.
+func (r *PostgresUsageRepository) ListByProject(ctx context.Context, projectID uuid.UUID, since time.Time) ([]model.UsageRecord, error) {
+	query := `
+		SELECT id, project_id, message_id, agent_type, source, model, route,
+			input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens,
+			cost_usd, cache_savings_usd, created_at
+		FROM token_usage
+		WHERE project_id = $1 AND created_at >= $2
+		ORDER BY created_at ASC
+	`
+
+	var records []model.UsageRecord
+	if err := r.db.SelectContext(ctx, &records, query, projectID, since); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
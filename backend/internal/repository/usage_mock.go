@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// MockUsageRepository implements UsageRepository for testing.
+type MockUsageRepository struct {
+	mu      sync.RWMutex
+	records []*model.UsageRecord // In creation order
+}
+
+// NewMockUsageRepository creates a new MockUsageRepository.
+func NewMockUsageRepository() *MockUsageRepository {
+	return &MockUsageRepository{}
+}
+
+// Create records the usage of one Claude API call.
+func (r *MockUsageRepository) Create(ctx context.Context, record *model.UsageRecord) (*model.UsageRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := *record
+	created.ID = uuid.New()
+	if created.CreatedAt.IsZero() {
+		created.CreatedAt = time.Now().UTC()
+	}
+	r.records = append(r.records, &created)
+
+	result := created
+	return &result, nil
+}
+
+// AttachMessage links usage recorded during a chat turn to the resulting assistant message.
+func (r *MockUsageRepository) AttachMessage(ctx context.Context, recordIDs []uuid.UUID, messageID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range recordIDs {
+		for _, record := range r.records {
+			if record.ID == id {
+				msgID := messageID
+				record.MessageID = &msgID
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListByProject returns a project's usage records created at or after since, oldest first.
+func (r *MockUsageRepository) ListByProject(ctx context.Context, projectID uuid.UUID, since time.Time) ([]model.UsageRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []model.UsageRecord
+	for _, record := range r.records {
+		if record.ProjectID != nil && *record.ProjectID == projectID && !record.CreatedAt.Before(since) {
+			result = append(result, *record)
+		}
+	}
+
+	return result, nil
+}
+
+// All returns every recorded usage, for assertions in tests.
+func (r *MockUsageRepository) All() []model.UsageRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]model.UsageRecord, len(r.records))
+	for i, record := range r.records {
+		result[i] = *record
+	}
+	return result
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -158,8 +159,8 @@ func (m *mockProjectRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Pro
 	return project, nil
 }
 
-func (m *mockProjectRepo) Create(ctx context.Context, userID uuid.UUID) (*model.Project, error) {
-	project := &model.Project{ID: uuid.New()}
+func (m *mockProjectRepo) Create(ctx context.Context, title string) (*model.Project, error) {
+	project := &model.Project{ID: uuid.New(), Title: title}
 	m.projects[project.ID] = project
 	return project, nil
 }
@@ -169,7 +170,31 @@ func (m *mockProjectRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func (m *mockProjectRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]model.Project, error) {
+func (m *mockProjectRepo) List(ctx context.Context) ([]model.ProjectListItem, error) {
+	return nil, nil
+}
+
+func (m *mockProjectRepo) UpdateTimestamp(ctx context.Context, id uuid.UUID, timestamp time.Time) error {
+	return nil
+}
+
+func (m *mockProjectRepo) GetMessages(ctx context.Context, projectID uuid.UUID) ([]model.Message, error) {
+	return nil, nil
+}
+
+func (m *mockProjectRepo) GetMessage(ctx context.Context, id uuid.UUID) (*model.Message, error) {
+	return nil, nil
+}
+
+func (m *mockProjectRepo) CreateMessage(ctx context.Context, projectID uuid.UUID, role model.Role, content string) (*model.Message, error) {
+	return nil, nil
+}
+
+func (m *mockProjectRepo) MarkMessageCancelled(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (m *mockProjectRepo) CreateMessageWithAgent(ctx context.Context, projectID uuid.UUID, role model.Role, content string, agentType *string) (*model.Message, error) {
 	return nil, nil
 }
 
@@ -260,6 +285,38 @@ func (m *mockDiscoveryRepoForAgent) GetFutureFeatures(ctx context.Context, disco
 	return nil, nil
 }
 
+func (m *mockDiscoveryRepoForAgent) UpdateUser(ctx context.Context, user *model.DiscoveryUser) (*model.DiscoveryUser, error) {
+	return nil, nil
+}
+
+func (m *mockDiscoveryRepoForAgent) ClearUsers(ctx context.Context, discoveryID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockDiscoveryRepoForAgent) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockDiscoveryRepoForAgent) GetFeatures(ctx context.Context, discoveryID uuid.UUID) ([]model.DiscoveryFeature, error) {
+	return nil, nil
+}
+
+func (m *mockDiscoveryRepoForAgent) UpdateFeature(ctx context.Context, feature *model.DiscoveryFeature) (*model.DiscoveryFeature, error) {
+	return nil, nil
+}
+
+func (m *mockDiscoveryRepoForAgent) DeleteFeature(ctx context.Context, featureID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockDiscoveryRepoForAgent) AddEditHistory(ctx context.Context, history *model.DiscoveryEditHistory) (*model.DiscoveryEditHistory, error) {
+	return nil, nil
+}
+
+func (m *mockDiscoveryRepoForAgent) GetEditHistory(ctx context.Context, discoveryID uuid.UUID) ([]model.DiscoveryEditHistory, error) {
+	return nil, nil
+}
+
 // Helper to create a test service
 func newTestAgentContextService() (*AgentContextService, *mockPRDRepo, *mockProjectRepo, *mockDiscoveryRepoForAgent) {
 	prdRepo := newMockPRDRepo()
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Cassette modes.
+const (
+	CassetteRecord = "record" // Call the real API and save every exchange
+	CassetteReplay = "replay" // Serve saved exchanges; never touch the network
+)
+
+// ErrCassetteMiss is returned in replay mode when no recorded exchange matches a request.
+var ErrCassetteMiss = errors.New("no recorded response matches request")
+
+var (
+	cassetteUUIDPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	cassetteTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+// cassetteFile is the on-disk form of a cassette entry: one normalized request and the
+// responses it received, in order. The request is stored so that re-recording after a
+// prompt change shows up as a reviewable diff.
+type cassetteFile struct {
+	Method    string             `json:"method"`
+	Path      string             `json:"path"`
+	Request   json.RawMessage    `json:"request"`
+	Responses []cassetteResponse `json:"responses"`
+}
+
+// cassetteResponse is one recorded response. Body holds the raw bytes, e.g. an SSE stream.
+type cassetteResponse struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	RetryAfter  string `json:"retry_after,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Cassette records LLM API exchanges to files and replays them, so end-to-end tests run
+// deterministically without network access.
+//
+// Requests are matched by a hash of their normalized body (see normalizeCassetteRequest),
+// so IDs and timestamps embedded in prompts do not break matching but any change to a
+// prompt, the message history or the tool definitions does. Identical requests are
+// answered in the order they were recorded; once those run out the last one repeats.
+// Request headers, including API keys, are never stored.
+type Cassette struct {
+	dir    string
+	mode   string
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*cassetteFile // Loaded or recorded entries by hash
+	played  map[string]int           // Responses served per hash in this run
+}
+
+// NewCassette creates a cassette stored in dir. Mode is CassetteRecord or CassetteReplay.
+func NewCassette(dir, mode string, logger zerolog.Logger) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q (expected %q or %q)", mode, CassetteRecord, CassetteReplay)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("cassette directory is required")
+	}
+	if mode == CassetteRecord {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+
+	return &Cassette{
+		dir:     dir,
+		mode:    mode,
+		logger:  logger,
+		entries: make(map[string]*cassetteFile),
+		played:  make(map[string]int),
+	}, nil
+}
+
+// Mode returns the cassette mode.
+func (c *Cassette) Mode() string {
+	return c.mode
+}
+
+// Wrap returns a transport that records through next or replays from disk.
+// In replay mode next is never called.
+func (c *Cassette) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &cassetteTransport{cassette: c, next: next}
+}
+
+// cassetteTransport is the http.RoundTripper returned by Cassette.Wrap.
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	normalized := normalizeCassetteRequest(body)
+	hash := cassetteHash(req.Method, req.URL.Path, normalized)
+
+	if t.cassette.mode == CassetteReplay {
+		return t.cassette.replay(req, hash)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stream the body through to the caller and save it once it has been read
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onClose: func(recorded []byte) {
+			t.cassette.record(req, hash, normalized, cassetteResponse{
+				StatusCode:  resp.StatusCode,
+				ContentType: resp.Header.Get("Content-Type"),
+				RetryAfter:  resp.Header.Get("Retry-After"),
+				Body:        string(recorded),
+			})
+		},
+	}
+	return resp, nil
+}
+
+// replay serves the next recorded response for hash.
+func (c *Cassette) replay(req *http.Request, hash string) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, err := c.load(hash)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || len(entry.Responses) == 0 {
+		return nil, fmt.Errorf("%w: %s %s (hash %s) in %s", ErrCassetteMiss, req.Method, req.URL.Path, hash, c.dir)
+	}
+
+	i := c.played[hash]
+	if i >= len(entry.Responses) {
+		i = len(entry.Responses) - 1
+	}
+	c.played[hash]++
+	recorded := entry.Responses[i]
+
+	c.logger.Debug().
+		Str("hash", hash).
+		Int("response", i).
+		Msg("replaying cassette response")
+
+	header := make(http.Header)
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+	if recorded.RetryAfter != "" {
+		header.Set("Retry-After", recorded.RetryAfter)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record appends a response to the entry for hash and writes it to disk.
+// The first recording of a hash in a run replaces whatever was on disk.
+func (c *Cassette) record(req *http.Request, hash string, normalized []byte, response cassetteResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[hash]
+	if !ok {
+		entry = &cassetteFile{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Request: cassetteRequestJSON(normalized),
+		}
+		c.entries[hash] = entry
+	}
+	entry.Responses = append(entry.Responses, response)
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		c.logger.Error().Err(err).Str("hash", hash).Msg("failed to encode cassette")
+		return
+	}
+	if err := os.WriteFile(c.path(hash), append(data, '\n'), 0o644); err != nil {
+		c.logger.Error().Err(err).Str("hash", hash).Msg("failed to write cassette")
+		return
+	}
+
+	c.logger.Debug().
+		Str("hash", hash).
+		Int("status", response.StatusCode).
+		Msg("recorded cassette response")
+}
+
+// load returns the entry for hash, reading it from disk on first use.
+// It returns nil if there is no recording. Callers must hold c.mu.
+func (c *Cassette) load(hash string) (*cassetteFile, error) {
+	if entry, ok := c.entries[hash]; ok {
+		return entry, nil
+	}
+
+	data, err := os.ReadFile(c.path(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var entry cassetteFile
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", c.path(hash), err)
+	}
+	c.entries[hash] = &entry
+	return &entry, nil
+}
+
+// path returns the file that stores the entry for hash.
+func (c *Cassette) path(hash string) string {
+	return filepath.Join(c.dir, hash+".json")
+}
+
+// recordingBody copies everything read from a response body and hands it to onClose.
+type recordingBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	onClose func([]byte)
+	once    sync.Once
+}
+
+// Read implements io.Reader.
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() { b.onClose(b.buf.Bytes()) })
+}
+
+// normalizeCassetteRequest returns a canonical form of a request body used for matching.
+// JSON is re-encoded with sorted keys, and UUIDs and RFC 3339 timestamps inside strings
+// are replaced with placeholders, since they differ between runs.
+func normalizeCassetteRequest(body []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+
+	normalized, err := json.MarshalIndent(normalizeCassetteValue(v), "", "  ")
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+func normalizeCassetteValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		s := cassetteUUIDPattern.ReplaceAllString(v, "<uuid>")
+		return cassetteTimePattern.ReplaceAllString(s, "<time>")
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = normalizeCassetteValue(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeCassetteValue(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// cassetteHash identifies a request by method, path and normalized body.
+func cassetteHash(method, path string, normalized []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(normalized)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// cassetteRequestJSON stores a normalized body as JSON, falling back to a JSON string.
+func cassetteRequestJSON(normalized []byte) json.RawMessage {
+	if json.Valid(normalized) {
+		return json.RawMessage(normalized)
+	}
+	quoted, _ := json.Marshal(string(normalized))
+	return quoted
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// newCassetteClaudeService returns a Claude service pointed at baseURL that uses a cassette in dir.
+func newCassetteClaudeService(t *testing.T, baseURL, dir, mode string) *ClaudeService {
+	t.Helper()
+	cassette, err := NewCassette(dir, mode, zerolog.Nop())
+	require.NoError(t, err)
+
+	svc := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-test",
+		MaxTokens: 1024,
+		BaseURL:   baseURL,
+		Retry:     RetryConfig{MaxRetries: 0},
+	}, zerolog.Nop())
+	svc.UseCassette(cassette)
+	return svc
+}
+
+// runCassetteChat sends one chat message for a fresh project and returns the reply and the written file.
+func runCassetteChat(t *testing.T, claude ClaudeMessenger) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	project, _ := repo.Create(ctx, "Cassette Project")
+
+	chatService := NewChatService(ChatConfig{}, claude, nil, nil, repo, fileRepo, nil, zerolog.Nop())
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Make a landing page", func(string) {}, func(string) {})
+	require.NoError(t, err)
+
+	file, err := fileRepo.GetFileByPath(ctx, project.ID, "index.html")
+	require.NoError(t, err)
+	return result.Content, file.Content
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cassettes")
+
+	// Record a tool-use turn followed by a text turn against a live server
+	server := newToolUseTestServer(t, "index.html", "<h1>Recorded</h1>")
+	recorder := newCassetteClaudeService(t, server.URL, dir, CassetteRecord)
+	recordedReply, recordedFile := runCassetteChat(t, recorder)
+	server.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "one cassette per distinct request")
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "test-key", "API keys must not be recorded")
+	}
+
+	// Replay with the server gone; a new project gets a new ID, which must not affect matching
+	replayer := newCassetteClaudeService(t, server.URL, dir, CassetteReplay)
+	replayedReply, replayedFile := runCassetteChat(t, replayer)
+
+	assert.Equal(t, recordedReply, replayedReply)
+	assert.Equal(t, "<h1>Recorded</h1>", recordedFile)
+	assert.Equal(t, recordedFile, replayedFile)
+}
+
+func TestCassette_ReplayIsByteForByte(t *testing.T) {
+	dir := t.TempDir()
+	events := toolUseTurnEvents("toolu_1", "write_file", map[string]interface{}{"path": "a.txt", "content": "A"})
+	server, _ := newScriptedClaudeServer(t, events)
+	defer server.Close()
+
+	recorder := newCassetteClaudeService(t, server.URL, dir, CassetteRecord)
+	stream, err := recorder.SendMessage(context.Background(), "sys", []ClaudeMessage{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	var entry cassetteFile
+	require.NoError(t, json.Unmarshal(data, &entry))
+	require.Len(t, entry.Responses, 1)
+	assert.Equal(t, 200, entry.Responses[0].StatusCode)
+	assert.Equal(t, "text/event-stream", entry.Responses[0].ContentType)
+	assert.Equal(t, strings.Join(events, ""), entry.Responses[0].Body)
+	assert.Contains(t, string(entry.Request), `"content": "Hi"`, "the normalized request is stored for review")
+
+	replayer := newCassetteClaudeService(t, server.URL, dir, CassetteReplay)
+	replayed, err := replayer.SendMessage(context.Background(), "sys", []ClaudeMessage{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	for range replayed.Chunks() {
+	}
+	replayed.Close()
+
+	assert.Equal(t, StopReasonToolUse, replayed.StopReason())
+	assert.Equal(t, stream.ToolUses(), replayed.ToolUses())
+}
+
+func TestCassette_ReplaysRepeatedRequestsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	server, _ := newFlakyClaudeServer(t, overloadedResponse)
+	defer server.Close()
+
+	// With retries the same request is sent twice: first overloaded, then successful
+	record := func(mode string) (string, error) {
+		svc := newCassetteClaudeService(t, server.URL, dir, mode)
+		svc.retrier.config.MaxRetries = 1
+		svc.retrier.sleep = func(context.Context, time.Duration) error { return nil }
+		stream, err := sendHello(svc)
+		if err != nil {
+			return "", err
+		}
+		defer stream.Close()
+		var content strings.Builder
+		for chunk := range stream.Chunks() {
+			content.WriteString(chunk)
+		}
+		return content.String(), stream.Err()
+	}
+
+	recorded, err := record(CassetteRecord)
+	require.NoError(t, err)
+
+	server.Close()
+	replayed, err := record(CassetteReplay)
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+}
+
+func TestCassette_ReplayMiss(t *testing.T) {
+	svc := newCassetteClaudeService(t, "http://127.0.0.1:1", t.TempDir(), CassetteReplay)
+
+	_, err := sendHello(svc)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCassetteMiss))
+}
+
+func TestNewCassette_InvalidMode(t *testing.T) {
+	_, err := NewCassette(t.TempDir(), "rewind", zerolog.Nop())
+	assert.Error(t, err)
+
+	_, err = NewCassette("", CassetteReplay, zerolog.Nop())
+	assert.Error(t, err)
+}
+
+func TestNormalizeCassetteRequest(t *testing.T) {
+	a := normalizeCassetteRequest([]byte(`{"system":"Project 3f2c1a9e-1b2c-4d5e-8f90-123456789abc at 2026-01-02T03:04:05Z","model":"m"}`))
+	b := normalizeCassetteRequest([]byte(`{"model":"m","system":"Project 0a1b2c3d-0000-4000-8000-abcdefabcdef at 2026-05-06T07:08:09.123+02:00"}`))
+	assert.Equal(t, string(a), string(b), "IDs, timestamps and key order must not affect matching")
+
+	c := normalizeCassetteRequest([]byte(`{"model":"m","system":"Project <uuid> at <time>, now with a new instruction"}`))
+	assert.NotEqual(t, string(a), string(c), "prompt changes must change the request")
+
+	assert.Equal(t, "not json", string(normalizeCassetteRequest([]byte("not json"))))
+}
+
+func TestNewProvider_ReplayCassetteNeedsNoAPIKey(t *testing.T) {
+	cassette, err := NewCassette(t.TempDir(), CassetteReplay, zerolog.Nop())
+	require.NoError(t, err)
+
+	provider, err := NewProvider(ProviderConfig{Cassette: cassette}, zerolog.Nop())
+	require.NoError(t, err)
+	assert.Equal(t, ProviderAnthropic, provider.Name())
+}
+
+// discoveryToBuildTurns are the messages the user sends during discovery, one per stage
+// up to the summary, which is confirmed separately.
+var discoveryToBuildTurns = []string{
+	"I run a small bakery in town",
+	"Customers keep calling to ask what we have, because our menu isn't online",
+	"Just me, the owner",
+	"A menu page listing our breads and cakes with prices",
+	"Yes, that's everything",
+}
+
+// notifyingPRDGenerator closes done once GenerateAllPRDs returns.
+type notifyingPRDGenerator struct {
+	PRDGenerator
+	done chan struct{}
+}
+
+func (g *notifyingPRDGenerator) GenerateAllPRDs(ctx context.Context, discoveryID uuid.UUID) error {
+	defer close(g.done)
+	return g.PRDGenerator.GenerateAllPRDs(ctx, discoveryID)
+}
+
+func TestCassette_ReplayDiscoveryToBuild(t *testing.T) {
+	// The server is unreachable; every response comes from the recording
+	claude := newCassetteClaudeService(t, "http://127.0.0.1:1", "../../testdata/cassettes/discovery_to_build", CassetteReplay)
+
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	projectRepo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	discoveryRepo := repository.NewMockDiscoveryRepository()
+	prdRepo := repository.NewMockPRDRepository()
+
+	generator := &notifyingPRDGenerator{PRDGenerator: NewPRDService(prdRepo, discoveryRepo, claude, logger), done: make(chan struct{})}
+	discoveryService := NewDiscoveryService(discoveryRepo, projectRepo, logger)
+	discoveryService.SetPRDService(generator)
+	agentContext := NewAgentContextService(prdRepo, projectRepo, discoveryRepo, logger)
+	chatService := NewChatService(ChatConfig{}, claude, discoveryService, agentContext, projectRepo, fileRepo, nil, logger)
+
+	project, err := projectRepo.Create(ctx, "New Project")
+	require.NoError(t, err)
+
+	// Discovery
+	for _, content := range discoveryToBuildTurns {
+		_, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, content, ChatCallbacks{OnChunk: func(string) {}})
+		require.NoError(t, err, "discovery turn %q", content)
+	}
+	discovery, err := discoveryService.GetDiscovery(ctx, project.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.StageSummary, discovery.Stage)
+	discovery, err = discoveryService.ConfirmDiscovery(ctx, discovery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.StageComplete, discovery.Stage)
+
+	// PRDs are generated in the background once discovery is confirmed
+	select {
+	case <-generator.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PRDs were not generated")
+	}
+	prds, err := prdRepo.GetByProjectID(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, prds, 1)
+	assert.Equal(t, "Menu page", prds[0].Title)
+	assert.Equal(t, model.PRDStatusDraft, prds[0].Status)
+	assert.NotEmpty(t, prds[0].Overview)
+	stories, err := prds[0].UserStories()
+	require.NoError(t, err)
+	assert.NotEmpty(t, stories)
+
+	// Build
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Add the menu page", ChatCallbacks{OnChunk: func(string) {}})
+	require.NoError(t, err)
+	require.NotNil(t, result.AgentType)
+	assert.Equal(t, string(model.AgentDeveloper), *result.AgentType)
+
+	project, err = projectRepo.GetByID(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Crumb & Co Menu", project.Title)
+
+	files, err := fileRepo.GetFilesByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	page, err := fileRepo.GetFileByPath(ctx, project.ID, "index.html")
+	require.NoError(t, err)
+	assert.Contains(t, page.Content, "Sourdough loaf")
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/diff"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// ErrMessageNotFound is returned when a message does not exist.
+var ErrMessageNotFound = errors.New("message not found")
+
+// FileSnapshot holds the content of a file before and after a turn.
+// A nil Before means the file did not exist; a nil After means it was deleted.
+type FileSnapshot struct {
+	Path   string
+	Before *string
+	After  *string
+}
+
+// ChangeSetService persists the files changed during each assistant turn and renders their diffs.
+type ChangeSetService struct {
+	changeRepo  repository.FileChangeRepository
+	projectRepo repository.ProjectRepository
+	logger      zerolog.Logger
+}
+
+// NewChangeSetService creates a new ChangeSetService.
+func NewChangeSetService(changeRepo repository.FileChangeRepository, projectRepo repository.ProjectRepository, logger zerolog.Logger) *ChangeSetService {
+	return &ChangeSetService{
+		changeRepo:  changeRepo,
+		projectRepo: projectRepo,
+		logger:      logger.With().Str("component", "change_set").Logger(),
+	}
+}
+
+// Record persists the net changes of a turn against the assistant message.
+// Files whose content ended up unchanged are skipped. Returns nil if nothing changed.
+func (s *ChangeSetService) Record(ctx context.Context, projectID, messageID uuid.UUID, snapshots []FileSnapshot) (*model.ChangeSummary, error) {
+	var changes []model.FileChange
+	for _, snap := range snapshots {
+		changeType, ok := classifyChange(snap)
+		if !ok {
+			continue
+		}
+
+		additions, deletions := diff.Stats(diff.Compute(derefString(snap.Before), derefString(snap.After), diff.DefaultContext))
+		changes = append(changes, model.FileChange{
+			MessageID:     messageID,
+			ProjectID:     projectID,
+			Path:          snap.Path,
+			ChangeType:    changeType,
+			BeforeContent: snap.Before,
+			AfterContent:  snap.After,
+			Additions:     additions,
+			Deletions:     deletions,
+		})
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	created, err := s.changeRepo.CreateBatch(ctx, changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record file changes: %w", err)
+	}
+
+	summary := buildChangeSummary(messageID, created)
+
+	s.logger.Debug().
+		Str("projectId", projectID.String()).
+		Str("messageId", messageID.String()).
+		Int("files", len(created)).
+		Int("additions", summary.Additions).
+		Int("deletions", summary.Deletions).
+		Msg("recorded change set")
+
+	return summary, nil
+}
+
+// GetChanges returns the change set of an assistant message with unified diffs for every file.
+// Returns ErrMessageNotFound if the message does not exist.
+func (s *ChangeSetService) GetChanges(ctx context.Context, messageID uuid.UUID) (*model.MessageChangesResponse, error) {
+	if _, err := s.projectRepo.GetMessage(ctx, messageID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	changes, err := s.changeRepo.ListByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file changes: %w", err)
+	}
+
+	files := make([]model.FileDiff, 0, len(changes))
+	for _, change := range changes {
+		files = append(files, buildFileDiff(change))
+	}
+
+	return &model.MessageChangesResponse{
+		MessageID: messageID,
+		Summary:   *buildChangeSummary(messageID, changes),
+		Files:     files,
+	}, nil
+}
+
+// classifyChange determines the change type of a snapshot.
+// Returns false if the file's content is the same before and after.
+func classifyChange(snap FileSnapshot) (model.FileChangeType, bool) {
+	switch {
+	case snap.Before == nil && snap.After == nil:
+		return "", false
+	case snap.Before == nil:
+		return model.FileChangeAdded, true
+	case snap.After == nil:
+		return model.FileChangeDeleted, true
+	case *snap.Before == *snap.After:
+		return "", false
+	default:
+		return model.FileChangeModified, true
+	}
+}
+
+// buildChangeSummary groups file changes by type with their line counts.
+func buildChangeSummary(messageID uuid.UUID, changes []model.FileChange) *model.ChangeSummary {
+	summary := &model.ChangeSummary{
+		MessageID: messageID,
+		Added:     []model.FileChangeStat{},
+		Modified:  []model.FileChangeStat{},
+		Deleted:   []model.FileChangeStat{},
+	}
+
+	for _, change := range changes {
+		stat := model.FileChangeStat{
+			Path:      change.Path,
+			Additions: change.Additions,
+			Deletions: change.Deletions,
+		}
+		switch change.ChangeType {
+		case model.FileChangeAdded:
+			summary.Added = append(summary.Added, stat)
+		case model.FileChangeModified:
+			summary.Modified = append(summary.Modified, stat)
+		case model.FileChangeDeleted:
+			summary.Deleted = append(summary.Deleted, stat)
+		}
+		summary.Additions += change.Additions
+		summary.Deletions += change.Deletions
+	}
+
+	return summary
+}
+
+// buildFileDiff renders a file change as unified diff text and structured hunks.
+func buildFileDiff(change model.FileChange) model.FileDiff {
+	hunks := diff.Compute(derefString(change.BeforeContent), derefString(change.AfterContent), diff.DefaultContext)
+
+	oldName, newName := "a/"+change.Path, "b/"+change.Path
+	if change.BeforeContent == nil {
+		oldName = "/dev/null"
+	}
+	if change.AfterContent == nil {
+		newName = "/dev/null"
+	}
+
+	diffHunks := make([]model.DiffHunk, len(hunks))
+	for i, h := range hunks {
+		lines := make([]model.DiffLine, len(h.Lines))
+		for j, l := range h.Lines {
+			lines[j] = model.DiffLine{Type: string(l.Kind), Content: l.Content}
+		}
+		diffHunks[i] = model.DiffHunk{
+			Header:   h.Header(),
+			OldStart: h.OldStart,
+			OldLines: h.OldLines,
+			NewStart: h.NewStart,
+			NewLines: h.NewLines,
+			Lines:    lines,
+		}
+	}
+
+	return model.FileDiff{
+		Path:       change.Path,
+		ChangeType: change.ChangeType,
+		Additions:  change.Additions,
+		Deletions:  change.Deletions,
+		Diff:       diff.Unified(oldName, newName, hunks),
+		Hunks:      diffHunks,
+	}
+}
+
+// changeTracker accumulates the net before/after content of files touched during a turn.
+// The first write to a path captures its original content; later writes only update After.
+type changeTracker struct {
+	order []string
+	files map[string]*FileSnapshot
+}
+
+// tracking returns true if the path has already been captured this turn.
+func (t *changeTracker) tracking(path string) bool {
+	_, ok := t.files[path]
+	return ok
+}
+
+// begin captures the content of a path before its first write in the turn.
+func (t *changeTracker) begin(path string, before *string) {
+	if t.tracking(path) {
+		return
+	}
+	if t.files == nil {
+		t.files = make(map[string]*FileSnapshot)
+	}
+	before = copyString(before)
+	t.files[path] = &FileSnapshot{Path: path, Before: before, After: before}
+	t.order = append(t.order, path)
+}
+
+// record updates the content of a path after a write (nil after a delete).
+func (t *changeTracker) record(path string, after *string) {
+	if snap, ok := t.files[path]; ok {
+		snap.After = copyString(after)
+	}
+}
+
+// merge adds the changes of a later part of the turn, tracked separately, keeping the
+// original content of paths this tracker already captured.
+func (t *changeTracker) merge(other changeTracker) {
+	for _, snap := range other.snapshots() {
+		t.begin(snap.Path, snap.Before)
+		t.record(snap.Path, snap.After)
+	}
+}
+
+// snapshots returns the tracked files in the order they were first touched.
+func (t *changeTracker) snapshots() []FileSnapshot {
+	result := make([]FileSnapshot, 0, len(t.order))
+	for _, path := range t.order {
+		result = append(result, *t.files[path])
+	}
+	return result
+}
+
+// copyString returns a pointer to a copy of the string, so later writes to the
+// source (e.g. a cached *model.File) do not change the captured content.
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+// derefString returns the string pointed to, or "" for nil.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
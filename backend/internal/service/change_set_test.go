@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestChangeSetService_Record(t *testing.T) {
+	svc := NewChangeSetService(repository.NewMockFileChangeRepository(), repository.NewMockProjectRepository(), zerolog.Nop())
+	ctx := context.Background()
+	messageID := uuid.New()
+
+	summary, err := svc.Record(ctx, uuid.New(), messageID, []FileSnapshot{
+		{Path: "index.html", Before: nil, After: strPtr("<h1>Hi</h1>\n<p>new</p>\n")},
+		{Path: "style.css", Before: strPtr("a {}\nb {}\n"), After: strPtr("a {}\nb { color: red; }\n")},
+		{Path: "old.js", Before: strPtr("console.log(1)\n"), After: nil},
+		{Path: "same.txt", Before: strPtr("unchanged\n"), After: strPtr("unchanged\n")},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, messageID, summary.MessageID)
+	assert.Equal(t, []model.FileChangeStat{{Path: "index.html", Additions: 2}}, summary.Added)
+	assert.Equal(t, []model.FileChangeStat{{Path: "style.css", Additions: 1, Deletions: 1}}, summary.Modified)
+	assert.Equal(t, []model.FileChangeStat{{Path: "old.js", Deletions: 1}}, summary.Deleted)
+	assert.Equal(t, 3, summary.Additions)
+	assert.Equal(t, 2, summary.Deletions)
+}
+
+func TestChangeSetService_Record_NoChanges(t *testing.T) {
+	svc := NewChangeSetService(repository.NewMockFileChangeRepository(), repository.NewMockProjectRepository(), zerolog.Nop())
+
+	summary, err := svc.Record(context.Background(), uuid.New(), uuid.New(), []FileSnapshot{
+		{Path: "same.txt", Before: strPtr("x"), After: strPtr("x")},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+}
+
+// newChangeSetTestService returns a change set service and the ID of an assistant message in a new project.
+func newChangeSetTestService(t *testing.T) (*ChangeSetService, uuid.UUID) {
+	repo := repository.NewMockProjectRepository()
+	project, err := repo.Create(context.Background(), "Test Project")
+	require.NoError(t, err)
+	message, err := repo.CreateMessage(context.Background(), project.ID, model.RoleAssistant, "Done.")
+	require.NoError(t, err)
+	return NewChangeSetService(repository.NewMockFileChangeRepository(), repo, zerolog.Nop()), message.ID
+}
+
+func TestChangeSetService_GetChanges(t *testing.T) {
+	svc, messageID := newChangeSetTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.Record(ctx, uuid.New(), messageID, []FileSnapshot{
+		{Path: "index.html", Before: strPtr("hello\nworld\n"), After: strPtr("hello\nthere\n")},
+		{Path: "app.js", Before: nil, After: strPtr("run()\n")},
+	})
+	require.NoError(t, err)
+
+	changes, err := svc.GetChanges(ctx, messageID)
+	require.NoError(t, err)
+	require.Len(t, changes.Files, 2)
+
+	// Files are ordered by path
+	added := changes.Files[0]
+	assert.Equal(t, "app.js", added.Path)
+	assert.Equal(t, model.FileChangeAdded, added.ChangeType)
+	assert.True(t, strings.HasPrefix(added.Diff, "--- /dev/null\n+++ b/app.js\n"))
+
+	modified := changes.Files[1]
+	assert.Equal(t, "--- a/index.html\n+++ b/index.html\n@@ -1,2 +1,2 @@\n hello\n-world\n+there\n", modified.Diff)
+	require.Len(t, modified.Hunks, 1)
+	assert.Equal(t, "@@ -1,2 +1,2 @@", modified.Hunks[0].Header)
+	assert.Equal(t, []model.DiffLine{
+		{Type: "context", Content: "hello"},
+		{Type: "delete", Content: "world"},
+		{Type: "add", Content: "there"},
+	}, modified.Hunks[0].Lines)
+
+	assert.Len(t, changes.Summary.Added, 1)
+	assert.Len(t, changes.Summary.Modified, 1)
+}
+
+func TestChangeSetService_GetChanges_Empty(t *testing.T) {
+	svc, messageID := newChangeSetTestService(t)
+
+	changes, err := svc.GetChanges(context.Background(), messageID)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Files)
+	assert.True(t, changes.Summary.IsEmpty())
+}
+
+func TestChangeSetService_GetChanges_UnknownMessage(t *testing.T) {
+	svc, _ := newChangeSetTestService(t)
+
+	_, err := svc.GetChanges(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func TestChangeTracker_KeepsOriginalBefore(t *testing.T) {
+	var tracker changeTracker
+	tracker.begin("index.html", strPtr("v1"))
+	tracker.record("index.html", strPtr("v2"))
+	tracker.begin("index.html", strPtr("v2")) // second write in the same turn
+	tracker.record("index.html", strPtr("v3"))
+
+	snapshots := tracker.snapshots()
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "v1", *snapshots[0].Before)
+	assert.Equal(t, "v3", *snapshots[0].After)
+}
+
+func TestChangeTracker_Merge(t *testing.T) {
+	var tracker changeTracker
+	tracker.begin("index.html", nil)
+	tracker.record("index.html", strPtr("v1"))
+
+	var later changeTracker
+	later.begin("index.html", strPtr("v1"))
+	later.record("index.html", strPtr("v2"))
+	later.begin("js/app.js", nil)
+	later.record("js/app.js", strPtr("init()"))
+
+	tracker.merge(later)
+
+	snapshots := tracker.snapshots()
+	require.Len(t, snapshots, 2)
+	assert.Nil(t, snapshots[0].Before, "the original content is kept")
+	assert.Equal(t, "v2", *snapshots[0].After)
+	assert.Equal(t, "js/app.js", snapshots[1].Path)
+	assert.Equal(t, "init()", *snapshots[1].After)
+}
+
+func TestChatService_ProcessMessage_RecordsChangeSet(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_1", "write_file", map[string]interface{}{"path": "index.html", "content": "<h1>Draft</h1>\n"}),
+		toolUseTurnEvents("toolu_2", "write_file", map[string]interface{}{"path": "index.html", "content": "<h1>Final</h1>\n"}),
+		textTurnEvents("Updated the page."),
+	)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+	}, logger)
+
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+	_, err := fileRepo.SaveFile(ctx, project.ID, "index.html", "html", "<h1>Original</h1>\n")
+	require.NoError(t, err)
+
+	changeSets := NewChangeSetService(repository.NewMockFileChangeRepository(), repo, logger)
+	chatService := NewChatService(ChatConfig{}, claudeService, nil, nil, repo, fileRepo, nil, logger)
+	chatService.SetChangeSets(changeSets)
+
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Update the heading", func(string) {}, nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Changes)
+	assert.Equal(t, result.Message.ID, result.Changes.MessageID)
+	assert.Empty(t, result.Changes.Added)
+	assert.Equal(t, []model.FileChangeStat{{Path: "index.html", Additions: 1, Deletions: 1}}, result.Changes.Modified)
+
+	changes, err := changeSets.GetChanges(ctx, result.Message.ID)
+	require.NoError(t, err)
+	require.Len(t, changes.Files, 1)
+	assert.Contains(t, changes.Files[0].Diff, "-<h1>Original</h1>\n+<h1>Final</h1>\n")
+}
@@ -2,14 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/diff"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/markdown"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
 )
@@ -65,6 +69,9 @@ func inferFunctionalGroup(filename string) string {
 // ChatConfig holds configuration for the chat service.
 type ChatConfig struct {
 	ContextMessageLimit int
+	MaxToolIterations   int // Rounds of tool calls per turn; reaching it stops the turn and discards its file changes
+	MaxRepairIterations int // Repair turns run after a turn leaves critical completeness issues; 0 disables repairs
+	TurnTimeout         time.Duration // Time a turn, and each of its repair turns, has to get Claude's response; 0 uses 180s
 }
 
 // ChatService orchestrates chat interactions between WebSocket, Claude, and database.
@@ -77,7 +84,19 @@ type ChatService struct {
 	repo                 repository.ProjectRepository
 	fileRepo             repository.FileRepository
 	fileMetadataRepo     repository.FileMetadataRepository
+	fileHistory          *FileHistoryService
+	changeSets           *ChangeSetService
+	summaries            *SummaryService
+	usage                *UsageService
+	tools                *ToolRegistry
 	logger               zerolog.Logger
+
+	pausedMu         sync.Mutex
+	paused           map[uuid.UUID]*pausedTurn // projectID -> turn waiting for a clarification answer
+	clarificationTTL time.Duration             // How long a paused turn waits for its answer
+
+	syntaxMu     sync.Mutex
+	syntaxErrors map[uuid.UUID][]model.CompletenessIssue // projectID -> syntax errors left by the last turn
 }
 
 // NewChatService creates a new chat service.
@@ -94,11 +113,20 @@ func NewChatService(
 	if config.ContextMessageLimit <= 0 {
 		config.ContextMessageLimit = 20
 	}
+	if config.MaxToolIterations <= 0 {
+		config.MaxToolIterations = 10
+	}
+	if config.TurnTimeout <= 0 {
+		config.TurnTimeout = 180 * time.Second
+	}
 
-	// Create completeness checker
-	completenessChecker := NewCompletenessChecker(fileRepo, logger)
+	// Create completeness checker (requires file access)
+	var completenessChecker *CompletenessChecker
+	if fileRepo != nil {
+		completenessChecker = NewCompletenessChecker(fileRepo, logger)
+	}
 
-	return &ChatService{
+	s := &ChatService{
 		config:               config,
 		claudeService:        claudeService,
 		discoveryService:     discoveryService,
@@ -107,10 +135,86 @@ func NewChatService(
 		repo:                 repo,
 		fileRepo:             fileRepo,
 		fileMetadataRepo:     fileMetadataRepo,
+		tools:                NewToolRegistry(),
 		logger:               logger,
+		paused:               make(map[uuid.UUID]*pausedTurn),
+		clarificationTTL:     clarificationTTL,
+		syntaxErrors:         make(map[uuid.UUID][]model.CompletenessIssue),
+	}
+	s.registerFileTools()
+	s.registerAskUserTool()
+	return s
+}
+
+// Tools returns the registry of tools offered to Claude during chat turns.
+// Services contribute their own tools by registering them here.
+func (s *ChatService) Tools() *ToolRegistry {
+	return s.tools
+}
+
+// SetFileHistory sets the file history service used to version files written during a turn.
+// This is optional - if not set, files are overwritten without keeping history.
+func (s *ChatService) SetFileHistory(fileHistory *FileHistoryService) {
+	s.fileHistory = fileHistory
+}
+
+// SetChangeSets sets the change set service used to record per-turn file diffs.
+// This is optional - if not set, no change summary is produced.
+func (s *ChatService) SetChangeSets(changeSets *ChangeSetService) {
+	s.changeSets = changeSets
+}
+
+// SetSummaries sets the summary service used to compact older conversation history.
+// This is optional - if not set, history is truncated to ContextMessageLimit.
+func (s *ChatService) SetSummaries(summaries *SummaryService) {
+	s.summaries = summaries
+}
+
+// SetUsage sets the usage service used to link a turn's token usage to its assistant message.
+// This is optional - if not set, usage is still recorded but not linked to messages.
+func (s *ChatService) SetUsage(usage *UsageService) {
+	s.usage = usage
+}
+
+// SetCompletenessChecker replaces the completeness checker run after turns that write files,
+// e.g. with one that also render checks pages.
+func (s *ChatService) SetCompletenessChecker(checker *CompletenessChecker) {
+	s.completenessChecker = checker
+}
+
+// chatTurn tracks state accumulated while processing a single user message.
+type chatTurn struct {
+	projectID   uuid.UUID
+	agentType   *string
+	userMessage string                  // The message being answered
+	discovery   *model.ProjectDiscovery // Discovery state when the message arrived (nil without a discovery service)
+	prdID       uuid.UUID               // PRD the turn's agent is working on (uuid.Nil if none)
+	files       *fileStage              // File changes staged until the turn completes (nil without a file repository)
+	permissions map[ToolPermission]bool // Capabilities granted to the turn's tools
+	versions    []model.FileVersion     // File versions written during this turn
+	changes     changeTracker           // Before/after content of files touched during this turn
+	pending     *pendingClarification   // Tool loop paused on ask_user (nil unless the turn is waiting for an answer)
+	syntaxFed   bool                    // The system prompt included the project's recorded syntax errors
+}
+
+// newTurn starts the state of a turn.
+func (s *ChatService) newTurn(projectID uuid.UUID, agentType *string) *chatTurn {
+	turn := &chatTurn{projectID: projectID, agentType: agentType, permissions: make(map[ToolPermission]bool)}
+	if s.fileRepo != nil {
+		turn.files = newFileStage(projectID, s.fileRepo)
+		turn.permissions[ToolPermissionReadFiles] = true
+		turn.permissions[ToolPermissionWriteFiles] = true
 	}
+	return turn
 }
 
+// ErrResponseCancelled is returned by processStreamWithTools when the turn's context is cancelled.
+var ErrResponseCancelled = errors.New("response cancelled")
+
+// ErrToolLimitReached is returned by processStreamWithTools when Claude still wants to use
+// tools after MaxToolIterations rounds.
+var ErrToolLimitReached = errors.New("tool iteration limit reached")
+
 // ChatResult contains the result of processing a chat message.
 type ChatResult struct {
 	Message             *model.Message
@@ -119,12 +223,33 @@ type ChatResult struct {
 	CodeBlocks          []model.CodeBlock
 	AgentType           *string                   // "product_manager", "designer", "developer", or nil
 	CompletenessReport  *model.CompletenessReport // Report of missing files/broken references
+	Changes             *model.ChangeSummary      // Files added/modified/deleted during this turn, or nil
+	Cancelled           bool                      // True if the user cancelled; Content holds the partial response
+	ToolLimitReached    bool                      // True if the turn was stopped at MaxToolIterations; Content ends with a notice
+	RepairIterations    int                       // Repair turns run to fix critical completeness issues; CompletenessReport is the report after them
+	Clarification       *Clarification            // Question the turn is paused on; nothing is saved until it is answered
+}
+
+// ChatCallbacks receives events while a message is processed. Any callback may be nil.
+type ChatCallbacks struct {
+	OnChunk       func(chunk string)    // Called for each streaming chunk received
+	OnFileCreated func(filePath string) // Called when a file is created or updated via tool use
+	OnFileDeleted func(filePath string) // Called when a file is deleted, or moved away, via tool use
+	OnPRDUpdated  func(prdID uuid.UUID) // Called when a PRD is changed via tool use
+
+	// OnRepairIteration is called when a repair turn starts and when it ends.
+	OnRepairIteration func(iteration RepairIteration)
+
+	// OnClarification is called when the turn pauses on a question for the user, which is
+	// answered with AnswerClarification. Setting it makes the ask_user tool available.
+	OnClarification func(clarification Clarification)
 }
 
 // ProcessMessage handles a user message and streams the AI response.
 // It saves both the user message and assistant response to the database.
 // The onChunk callback is called for each streaming chunk received.
 // The onFileCreated callback is called when a file is created/updated via tool use.
+// Cancelling ctx stops generation; the partial response is saved and returned with Cancelled set.
 func (s *ChatService) ProcessMessage(
 	ctx context.Context,
 	projectID uuid.UUID,
@@ -132,6 +257,19 @@ func (s *ChatService) ProcessMessage(
 	onChunk func(chunk string),
 	onFileCreated func(filePath string),
 ) (*ChatResult, error) {
+	return s.ProcessMessageWithCallbacks(ctx, projectID, content, ChatCallbacks{
+		OnChunk:       onChunk,
+		OnFileCreated: onFileCreated,
+	})
+}
+
+// ProcessMessageWithCallbacks is ProcessMessage with the full set of event callbacks.
+func (s *ChatService) ProcessMessageWithCallbacks(ctx context.Context, projectID uuid.UUID, content string, callbacks ChatCallbacks) (*ChatResult, error) {
+	// Getting the response has TurnTimeout; finishing the turn stops only if the caller cancels
+	caller := ctx
+	ctx, cancelTurn := context.WithTimeout(ctx, s.config.TurnTimeout)
+	defer cancelTurn()
+
 	// Verify project exists
 	_, err := s.repo.GetByID(ctx, projectID)
 	if err != nil {
@@ -151,6 +289,9 @@ func (s *ChatService) ProcessMessage(
 		}
 	}
 
+	// A new message instead of an answer leaves the paused turn's question unanswered
+	s.abandonPausedTurn(ctx, projectID)
+
 	// Save user message first
 	_, err = s.repo.CreateMessage(ctx, projectID, model.RoleUser, content)
 	if err != nil {
@@ -163,8 +304,8 @@ func (s *ChatService) ProcessMessage(
 		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
 
-	// Convert to Claude messages format with limit
-	claudeMessages := s.buildClaudeMessages(messages)
+	// Replace history covered by a summary with the summary itself, compacting if over budget
+	summary, claudeMessages := s.conversationContext(ctx, projectID, messages)
 
 	// Determine agent context and system prompt
 	var agentType *string
@@ -191,6 +332,18 @@ func (s *ChatService) ProcessMessage(
 
 	// Get appropriate system prompt (discovery-aware, agent-specific, or default)
 	systemPrompt := s.getSystemPrompt(ctx, projectID, discovery, agentContext)
+	if summary != nil {
+		systemPrompt = withConversationSummary(systemPrompt, summary)
+	}
+
+	// Syntax errors left by earlier turns go to the developer agent to fix
+	syntaxFed := false
+	if agentContext != nil && agentContext.Agent == model.AgentDeveloper {
+		if issues := s.syntaxErrorsFor(projectID); len(issues) > 0 {
+			systemPrompt = withSyntaxErrors(systemPrompt, issues)
+			syntaxFed = true
+		}
+	}
 
 	s.logger.Debug().
 		Str("projectId", projectID.String()).
@@ -198,12 +351,87 @@ func (s *ChatService) ProcessMessage(
 		Bool("discoveryMode", discovery != nil && !discovery.Stage.IsComplete()).
 		Msg("sending message to Claude")
 
+	turn := s.newTurn(projectID, agentType)
+	turn.userMessage = content
+	turn.discovery = discovery
+	if agentContext != nil && agentContext.PRD != nil {
+		turn.prdID = agentContext.PRD.ID
+	}
+	turn.permissions[ToolPermissionAskUser] = callbacks.OnClarification != nil
+	turn.syntaxFed = syntaxFed
+
+	// Attribute token usage of this turn's Claude calls to the project and agent;
+	// the source also selects the model route (see ModelRoutes)
+	source := model.UsageSourceChat
+	if discovery != nil && !discovery.Stage.IsComplete() {
+		source = model.UsageSourceDiscovery
+	}
+	ctx = WithUsageScope(ctx, projectID, agentType, source)
+
 	// Send to Claude and handle tool use loop
-	responseContent, err := s.processStreamWithTools(ctx, projectID, systemPrompt, claudeMessages, onChunk, onFileCreated)
-	if err != nil {
+	responseContent, err := s.processStreamWithTools(ctx, turn, systemPrompt, claudeMessages, callbacks)
+	finishCtx, cancelFinish := finishContext(ctx, caller)
+	defer cancelFinish()
+	return s.finishTurn(finishCtx, turn, responseContent, err, callbacks)
+}
+
+// finishContext returns a context with the values of a turn's context, such as its usage
+// scope, that is cancelled with the caller's context, which the turn's was derived from, but
+// has no deadline. Finishing a turn uses it, so saving the response and repair turns, which
+// have deadlines of their own, don't fail because getting the response took most of the
+// turn's time.
+func finishContext(turnCtx, caller context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(turnCtx))
+	stop := context.AfterFunc(caller, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// finishTurn completes a turn once its tool loop has ended: it applies or discards the
+// turn's file changes, saves the assistant response and builds the result. A loop that
+// paused on ask_user is kept until the question is answered instead.
+func (s *ChatService) finishTurn(ctx context.Context, turn *chatTurn, responseContent string, err error, callbacks ChatCallbacks) (*ChatResult, error) {
+	projectID, agentType, discovery, content := turn.projectID, turn.agentType, turn.discovery, turn.userMessage
+
+	if errors.Is(err, ErrClarificationNeeded) {
+		clarification := s.pauseTurn(ctx, turn)
+		if callbacks.OnClarification != nil {
+			callbacks.OnClarification(clarification)
+		}
+		return &ChatResult{
+			Role:          model.RoleAssistant,
+			Content:       responseContent,
+			AgentType:     agentType,
+			Clarification: &clarification,
+		}, nil
+	}
+
+	cancelled := errors.Is(err, ErrResponseCancelled)
+	limitReached := errors.Is(err, ErrToolLimitReached)
+	if err != nil && !cancelled && !limitReached {
+		s.discardTurnFiles(turn)
 		return nil, err
 	}
 
+	if cancelled {
+		s.logger.Info().
+			Str("projectId", projectID.String()).
+			Int("partialLength", len(responseContent)).
+			Msg("response cancelled by user")
+		// The turn's context is done; persist what was produced with one that is not
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	// A cancelled or unfinished turn leaves the project files as they were
+	if cancelled || limitReached {
+		s.discardTurnFiles(turn)
+	}
+	if limitReached {
+		responseContent += toolLimitNotice(s.config.MaxToolIterations)
+	}
+
 	// If in discovery mode, extract and save discovery data from response
 	if discovery != nil && !discovery.Stage.IsComplete() {
 		if err := s.discoveryService.ExtractAndSaveData(ctx, discovery.ID, responseContent); err != nil {
@@ -244,74 +472,20 @@ func (s *ChatService) ProcessMessage(
 			Msg("extracted code block")
 	}
 
-	// Save files extracted from code blocks (only those with filenames)
-	if s.fileRepo != nil {
-		for _, block := range markdownBlocks {
-			if block.Filename != "" {
-				file, err := s.fileRepo.SaveFile(ctx, projectID, block.Filename, block.Language, block.Code)
-				if err != nil {
-					s.logger.Warn().
-						Err(err).
-						Str("projectId", projectID.String()).
-						Str("filename", block.Filename).
-						Msg("failed to save extracted file")
-					continue
-				}
-
-				s.logger.Info().
-					Str("projectId", projectID.String()).
-					Str("filename", block.Filename).
-					Msg("saved extracted file")
-
-				// Save metadata if repository is configured
-				if s.fileMetadataRepo != nil {
-					shortDesc := ""
-					longDesc := ""
-					funcGroup := ""
-
-					// Use explicit metadata if available
-					if block.Metadata != nil {
-						shortDesc = block.Metadata.ShortDescription
-						longDesc = block.Metadata.LongDescription
-						funcGroup = block.Metadata.FunctionalGroup
-					}
+	// Stage files extracted from code blocks (only those with filenames).
+	// A cancelled response may end mid-block, so its code blocks are not saved.
+	var savedBlocks []markdown.CodeBlockWithMetadata
+	if !cancelled && !limitReached {
+		savedBlocks = s.stageCodeBlocks(ctx, turn, markdownBlocks)
+	}
 
-					// Infer functional group from filename if not explicitly set
-					if funcGroup == "" {
-						funcGroup = inferFunctionalGroup(block.Filename)
-					}
+	// Apply all of the turn's file changes in one transaction
+	if err := s.commitTurnFiles(ctx, turn, callbacks); err != nil {
+		return nil, err
+	}
 
-					// Only save if we have something to save
-					if shortDesc != "" || longDesc != "" || funcGroup != "" {
-						_, err := s.fileMetadataRepo.Upsert(
-							ctx,
-							file.ID,
-							shortDesc,
-							longDesc,
-							funcGroup,
-						)
-						if err != nil {
-							s.logger.Warn().
-								Err(err).
-								Str("projectId", projectID.String()).
-								Str("filename", block.Filename).
-								Msg("failed to save file metadata")
-						} else {
-							s.logger.Info().
-								Str("projectId", projectID.String()).
-								Str("filename", block.Filename).
-								Str("functionalGroup", funcGroup).
-								Msg("saved file metadata")
-						}
-					}
-				}
-			} else {
-				s.logger.Info().
-					Str("projectId", projectID.String()).
-					Str("language", block.Language).
-					Msg("code block has no filename - not saving")
-			}
-		}
+	for _, block := range savedBlocks {
+		s.saveCodeBlockMetadata(ctx, projectID, block)
 	}
 
 	// Save assistant response with agent type
@@ -320,20 +494,21 @@ func (s *ChatService) ProcessMessage(
 		return nil, fmt.Errorf("failed to save assistant message: %w", err)
 	}
 
-	s.logger.Debug().
-		Str("projectId", projectID.String()).
-		Int("responseLength", len(responseContent)).
-		Int("codeBlocks", len(codeBlocks)).
-		Msg("completed message processing")
+	if cancelled {
+		s.markMessageCancelled(ctx, projectID, assistantMsg)
+	}
 
-	// Run completeness check if any files were created
+	// Run completeness check if any files were created or changed, by code blocks or tools
 	var completenessReport *model.CompletenessReport
-	if len(codeBlocks) > 0 && s.completenessChecker != nil {
+	filesChanged := turn.files != nil && turn.files.pending() > 0
+	checked := (len(codeBlocks) > 0 || filesChanged) && s.completenessChecker != nil && !cancelled
+	if checked {
 		report, err := s.completenessChecker.Check(ctx, projectID)
 		if err != nil {
 			s.logger.Warn().Err(err).Msg("failed to run completeness check")
 		} else {
 			completenessReport = report
+			s.recordSyntaxErrors(projectID, report.GetSyntaxErrors())
 			if report.HasCriticalIssues() {
 				s.logger.Warn().
 					Str("projectId", projectID.String()).
@@ -344,6 +519,71 @@ func (s *ChatService) ProcessMessage(
 		}
 	}
 
+	// A completed turn that was shown the syntax errors and changed no files is done with
+	// them; one that changed files had them replaced by its check above
+	if turn.syntaxFed && !checked && !cancelled && !limitReached {
+		s.recordSyntaxErrors(projectID, nil)
+	}
+
+	// Let the developer agent fix critical issues before the turn's changes are recorded,
+	// so the change set and file versions include the repairs
+	repairs := 0
+	if completenessReport != nil && completenessReport.HasCriticalIssues() && !limitReached && s.config.MaxRepairIterations > 0 {
+		completenessReport, repairs = s.repair(ctx, turn, completenessReport, callbacks)
+	}
+
+	// Cancelling during repairs cancels the message; the repairs finished so far are kept
+	if !cancelled && isCancelled(ctx) {
+		s.logger.Info().
+			Str("projectId", projectID.String()).
+			Int("repairIterations", repairs).
+			Msg("response cancelled by user during repairs")
+		cancelled = true
+		ctx = context.WithoutCancel(ctx)
+		s.markMessageCancelled(ctx, projectID, assistantMsg)
+	}
+
+	// Link file versions written during this turn to the assistant message
+	if s.fileHistory != nil && len(turn.versions) > 0 {
+		if err := s.fileHistory.AttachMessage(ctx, turn.versions, assistantMsg.ID); err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("messageId", assistantMsg.ID.String()).
+				Msg("failed to link file versions to message")
+		}
+	}
+
+	// Link the turn's token usage to the assistant message
+	if s.usage != nil {
+		if err := s.usage.AttachMessage(ctx, assistantMsg.ID); err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("messageId", assistantMsg.ID.String()).
+				Msg("failed to link token usage to message")
+		}
+	}
+
+	// Persist the turn's change set against the assistant message
+	var changes *model.ChangeSummary
+	if s.changeSets != nil {
+		changes, err = s.changeSets.Record(ctx, projectID, assistantMsg.ID, turn.changes.snapshots())
+		if err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("messageId", assistantMsg.ID.String()).
+				Msg("failed to record change set")
+		}
+	}
+
+	s.logger.Debug().
+		Str("projectId", projectID.String()).
+		Int("responseLength", len(responseContent)).
+		Int("codeBlocks", len(codeBlocks)).
+		Msg("completed message processing")
+
 	return &ChatResult{
 		Message:            assistantMsg,
 		Role:               model.RoleAssistant,
@@ -351,26 +591,220 @@ func (s *ChatService) ProcessMessage(
 		CodeBlocks:         codeBlocks,
 		AgentType:          agentType,
 		CompletenessReport: completenessReport,
+		Changes:            changes,
+		Cancelled:          cancelled,
+		ToolLimitReached:   limitReached,
+		RepairIterations:   repairs,
 	}, nil
 }
 
+// markMessageCancelled marks a saved assistant message as cancelled.
+func (s *ChatService) markMessageCancelled(ctx context.Context, projectID uuid.UUID, msg *model.Message) {
+	if err := s.repo.MarkMessageCancelled(ctx, msg.ID); err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", projectID.String()).
+			Str("messageId", msg.ID.String()).
+			Msg("failed to mark message as cancelled")
+		return
+	}
+	msg.Cancelled = true
+}
+
+// stageCodeBlocks stages the files of a response's code blocks that name one, and returns
+// those blocks.
+func (s *ChatService) stageCodeBlocks(ctx context.Context, turn *chatTurn, blocks []markdown.CodeBlockWithMetadata) []markdown.CodeBlockWithMetadata {
+	if turn.files == nil {
+		return nil
+	}
+
+	var saved []markdown.CodeBlockWithMetadata
+	for _, block := range blocks {
+		if block.Filename != "" {
+			s.trackFileBefore(ctx, turn, block.Filename)
+			file := turn.files.SaveFile(ctx, block.Filename, block.Language, block.Code, model.FileVersionSourceCodeBlock)
+			turn.changes.record(block.Filename, &file.Content)
+			saved = append(saved, block)
+		} else {
+			s.logger.Info().
+				Str("projectId", turn.projectID.String()).
+				Str("language", block.Language).
+				Msg("code block has no filename - not saving")
+		}
+	}
+	return saved
+}
+
+// commitTurnFiles applies the file changes staged during a turn in one transaction, then
+// records versions of the written files and notifies the callbacks. If the transaction
+// fails, none of the changes are applied and the turn fails.
+func (s *ChatService) commitTurnFiles(ctx context.Context, turn *chatTurn, callbacks ChatCallbacks) error {
+	if turn.files == nil || turn.files.pending() == 0 {
+		return nil
+	}
+
+	if err := turn.files.commit(ctx); err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("projectId", turn.projectID.String()).
+			Int("operations", turn.files.pending()).
+			Msg("failed to commit turn file changes, none were applied")
+		return fmt.Errorf("failed to save file changes: %w", err)
+	}
+
+	changed := turn.files.changedPaths()
+	s.logger.Info().
+		Str("projectId", turn.projectID.String()).
+		Int("operations", turn.files.pending()).
+		Int("files", len(changed)).
+		Msg("committed turn file changes")
+
+	for _, staged := range changed {
+		if staged.Deleted {
+			if callbacks.OnFileDeleted != nil {
+				callbacks.OnFileDeleted(staged.Path)
+			}
+			continue
+		}
+
+		if staged.Source != "" && s.fileHistory != nil {
+			file, err := s.fileRepo.GetFileByPath(ctx, turn.projectID, staged.Path)
+			if err != nil {
+				s.logger.Warn().
+					Err(err).
+					Str("projectId", turn.projectID.String()).
+					Str("path", staged.Path).
+					Msg("failed to read committed file for versioning")
+			} else {
+				s.recordFileVersion(ctx, turn, file, staged.Source)
+			}
+		}
+		if callbacks.OnFileCreated != nil {
+			callbacks.OnFileCreated(staged.Path)
+		}
+	}
+
+	return nil
+}
+
+// discardTurnFiles drops the file changes staged during a turn that did not complete.
+func (s *ChatService) discardTurnFiles(turn *chatTurn) {
+	if turn.files != nil && turn.files.pending() > 0 {
+		s.logger.Info().
+			Str("projectId", turn.projectID.String()).
+			Int("operations", turn.files.pending()).
+			Msg("discarded staged file changes of unfinished turn")
+	}
+	if turn.files != nil {
+		turn.files = newFileStage(turn.projectID, s.fileRepo)
+	}
+	turn.changes = changeTracker{}
+}
+
+// toolLimitNotice tells the user that the turn was stopped at the tool iteration limit.
+func toolLimitNotice(limit int) string {
+	return fmt.Sprintf("\n\n---\n**Stopped:** this response used the maximum of %d rounds of tool calls before it was finished, "+
+		"so none of its file changes were saved. Ask me to continue, ideally with a smaller step.", limit)
+}
+
+// saveCodeBlockMetadata stores the description and functional group of a file saved from
+// a code block. The file must already be committed.
+func (s *ChatService) saveCodeBlockMetadata(ctx context.Context, projectID uuid.UUID, block markdown.CodeBlockWithMetadata) {
+	s.logger.Info().
+		Str("projectId", projectID.String()).
+		Str("filename", block.Filename).
+		Msg("saved extracted file")
+
+	if s.fileMetadataRepo == nil {
+		return
+	}
+
+	shortDesc := ""
+	longDesc := ""
+	funcGroup := ""
+
+	// Use explicit metadata if available
+	if block.Metadata != nil {
+		shortDesc = block.Metadata.ShortDescription
+		longDesc = block.Metadata.LongDescription
+		funcGroup = block.Metadata.FunctionalGroup
+	}
+
+	// Infer functional group from filename if not explicitly set
+	if funcGroup == "" {
+		funcGroup = inferFunctionalGroup(block.Filename)
+	}
+
+	// Only save if we have something to save
+	if shortDesc == "" && longDesc == "" && funcGroup == "" {
+		return
+	}
+
+	file, err := s.fileRepo.GetFileByPath(ctx, projectID, block.Filename)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", projectID.String()).
+			Str("filename", block.Filename).
+			Msg("failed to read saved file for metadata")
+		return
+	}
+
+	if _, err := s.fileMetadataRepo.Upsert(ctx, file.ID, shortDesc, longDesc, funcGroup); err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", projectID.String()).
+			Str("filename", block.Filename).
+			Msg("failed to save file metadata")
+	} else {
+		s.logger.Info().
+			Str("projectId", projectID.String()).
+			Str("filename", block.Filename).
+			Str("functionalGroup", funcGroup).
+			Msg("saved file metadata")
+	}
+}
+
+// conversationContext returns the summary of a project's earlier messages, if any, and
+// the messages it doesn't cover in Claude API format. The summary service compacts
+// history over the message limit, so nothing is dropped unsummarized; without it, or if
+// it fails, history is truncated to ContextMessageLimit.
+func (s *ChatService) conversationContext(ctx context.Context, projectID uuid.UUID, messages []model.Message) (*model.ConversationSummary, []ClaudeMessage) {
+	if s.summaries != nil {
+		summary, recent, err := s.summaries.PrepareContext(ctx, projectID, messages)
+		if err == nil {
+			return summary, toClaudeMessages(recent)
+		}
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", projectID.String()).
+			Msg("failed to prepare conversation summary, falling back to truncation")
+	}
+	return nil, s.buildClaudeMessages(messages)
+}
+
 // buildClaudeMessages converts database messages to Claude API format.
 // It applies the context message limit, keeping the most recent messages.
 func (s *ChatService) buildClaudeMessages(messages []model.Message) []ClaudeMessage {
-	// Apply context limit - keep the most recent messages
-	startIdx := 0
 	if len(messages) > s.config.ContextMessageLimit {
-		startIdx = len(messages) - s.config.ContextMessageLimit
+		messages = messages[len(messages)-s.config.ContextMessageLimit:]
 	}
+	return toClaudeMessages(messages)
+}
 
-	claudeMessages := make([]ClaudeMessage, 0, len(messages)-startIdx)
-	for _, msg := range messages[startIdx:] {
+// toClaudeMessages converts database messages to Claude API format.
+func toClaudeMessages(messages []model.Message) []ClaudeMessage {
+	claudeMessages := make([]ClaudeMessage, 0, len(messages))
+	for _, msg := range messages {
+		// Responses cancelled before any text was produced are empty; Claude rejects empty content
+		if msg.Content == "" {
+			continue
+		}
 		claudeMessages = append(claudeMessages, ClaudeMessage{
 			Role:    string(msg.Role),
 			Content: msg.Content,
 		})
 	}
-
 	return claudeMessages
 }
 
@@ -420,34 +854,94 @@ func (s *ChatService) getSystemPrompt(ctx context.Context, projectID uuid.UUID,
 
 // processStreamWithTools handles streaming from Claude, executing tools, and continuing
 // the conversation until Claude returns a final response (not a tool_use).
+// If ctx is cancelled it stops between chunks or tool calls and returns the partial
+// response with ErrResponseCancelled. If Claude asks the user a question it returns
+// ErrClarificationNeeded with the loop's state saved in turn.pending.
 func (s *ChatService) processStreamWithTools(
 	ctx context.Context,
-	projectID uuid.UUID,
+	turn *chatTurn,
 	systemPrompt string,
 	claudeMessages []ClaudeMessage,
-	onChunk func(chunk string),
-	onFileCreated func(filePath string),
+	callbacks ChatCallbacks,
 ) (string, error) {
+	// Offer the tools the turn's agent and permissions allow
+	ctx = WithTools(ctx, s.tools.Definitions(turn.agentType, turn.permissions))
+
 	// Initial request
 	stream, err := s.claudeService.SendMessage(ctx, systemPrompt, claudeMessages)
 	if err != nil {
+		if isCancelled(ctx) {
+			return "", ErrResponseCancelled
+		}
 		return "", fmt.Errorf("failed to send message to Claude: %w", err)
 	}
 
+	return s.streamToolLoop(ctx, turn, &toolLoop{systemPrompt: systemPrompt, claudeMessages: claudeMessages}, stream, callbacks)
+}
+
+// resumeStreamWithTools continues a tool loop that paused on ask_user, sending Claude the
+// paused round's tool results with the user's answer filled in.
+func (s *ChatService) resumeStreamWithTools(ctx context.Context, turn *chatTurn, loop *toolLoop, callbacks ChatCallbacks) (string, error) {
+	ctx = WithTools(ctx, s.tools.Definitions(turn.agentType, turn.permissions))
+
+	stream, err := s.claudeService.SendMessageWithToolResults(ctx, loop.systemPrompt, loop.claudeMessages, loop.assistantContent, loop.toolResults)
+	if err != nil {
+		if isCancelled(ctx) {
+			return loop.response, ErrResponseCancelled
+		}
+		return "", fmt.Errorf("failed to continue with tool results: %w", err)
+	}
+
+	return s.streamToolLoop(ctx, turn, loop, stream, callbacks)
+}
+
+// toolLoop is the state of a turn's conversation with Claude between rounds of tool calls.
+type toolLoop struct {
+	systemPrompt     string
+	claudeMessages   []ClaudeMessage
+	response         string         // Text streamed in earlier rounds
+	round            int            // Rounds of tool calls completed
+	assistantContent []ContentBlock // The last round's text and tool_use blocks
+	toolResults      []ToolResult   // The last round's tool results
+}
+
+// streamToolLoop reads Claude's response from stream, runs the tools it calls and sends
+// their results back, round after round, until Claude finishes.
+func (s *ChatService) streamToolLoop(ctx context.Context, turn *chatTurn, loop *toolLoop, stream *ClaudeStream, callbacks ChatCallbacks) (string, error) {
 	var fullResponse strings.Builder
-	maxIterations := 10 // Prevent infinite loops
+	fullResponse.WriteString(loop.response)
 
-	for iteration := 0; iteration < maxIterations; iteration++ {
+	for round := loop.round; ; round++ {
 		// Collect response while streaming
 		var iterationResponse strings.Builder
-		for chunk := range stream.Chunks() {
-			iterationResponse.WriteString(chunk)
-			fullResponse.WriteString(chunk)
-			if onChunk != nil {
-				onChunk(chunk)
+	receive:
+		for {
+			select {
+			case chunk, ok := <-stream.Chunks():
+				if !ok {
+					break receive
+				}
+				iterationResponse.WriteString(chunk)
+				fullResponse.WriteString(chunk)
+				if callbacks.OnChunk != nil {
+					callbacks.OnChunk(chunk)
+				}
+			case <-ctx.Done():
+				break receive
 			}
 		}
 
+		if isCancelled(ctx) {
+			abandonStream(stream)
+			return fullResponse.String(), ErrResponseCancelled
+		}
+
+		// The turn's deadline passed mid-stream; the response is incomplete
+		if err := ctx.Err(); err != nil {
+			abandonStream(stream)
+			return "", fmt.Errorf("stream read error: %w", err)
+		}
+
 		if err := stream.Err(); err != nil {
 			stream.Close()
 			return "", fmt.Errorf("stream error: %w", err)
@@ -459,13 +953,23 @@ func (s *ChatService) processStreamWithTools(
 		stream.Close()
 
 		// If no tool uses or stop reason is not tool_use, we're done
-		if len(toolUses) == 0 || stopReason != "tool_use" {
+		if len(toolUses) == 0 || stopReason != StopReasonToolUse {
 			break
 		}
 
+		// Prevent infinite loops; the caller tells the user the turn did not finish
+		if round >= s.config.MaxToolIterations {
+			s.logger.Warn().
+				Int("maxToolIterations", s.config.MaxToolIterations).
+				Int("requestedTools", len(toolUses)).
+				Str("projectId", turn.projectID.String()).
+				Msg("tool iteration limit reached")
+			return fullResponse.String(), ErrToolLimitReached
+		}
+
 		s.logger.Debug().
 			Int("toolCount", len(toolUses)).
-			Str("projectId", projectID.String()).
+			Str("projectId", turn.projectID.String()).
 			Msg("executing tools")
 
 		// Execute tools and collect results
@@ -480,7 +984,7 @@ func (s *ChatService) processStreamWithTools(
 			})
 		}
 
-		// Add tool_use blocks and execute them
+		// Add tool_use blocks
 		for _, toolUse := range toolUses {
 			assistantContent = append(assistantContent, ContentBlock{
 				Type:  "tool_use",
@@ -488,31 +992,50 @@ func (s *ChatService) processStreamWithTools(
 				Name:  toolUse.Name,
 				Input: toolUse.Input,
 			})
+		}
 
-			execResult := s.executeTool(ctx, projectID, toolUse)
+		execResults, err := s.executeTools(ctx, turn, toolUses)
+		if err != nil {
+			return fullResponse.String(), err
+		}
+		for i, execResult := range execResults {
 			toolResults = append(toolResults, execResult.Result)
 
 			s.logger.Debug().
-				Str("toolName", toolUse.Name).
-				Str("toolID", toolUse.ID).
+				Str("toolName", toolUses[i].Name).
+				Str("toolID", toolUses[i].ID).
 				Bool("isError", execResult.Result.IsError).
 				Msg("tool executed")
 
-			// Notify about file creation if callback is provided
-			if execResult.CreatedFile != "" && onFileCreated != nil {
-				onFileCreated(execResult.CreatedFile)
+			// PRD changes are saved by the tool itself, so report them right away
+			if execResult.UpdatedPRD != uuid.Nil && callbacks.OnPRDUpdated != nil {
+				callbacks.OnPRDUpdated(execResult.UpdatedPRD)
 			}
 		}
 
+		loop.response = fullResponse.String()
+		loop.round = round + 1
+		loop.assistantContent = assistantContent
+		loop.toolResults = toolResults
+
+		// Wait for the user's answer; AnswerClarification resumes the loop from here
+		if pending := newPendingClarification(loop, execResults); pending != nil {
+			turn.pending = pending
+			return fullResponse.String(), ErrClarificationNeeded
+		}
+
 		// Continue conversation with tool results
 		stream, err = s.claudeService.SendMessageWithToolResults(
 			ctx,
-			systemPrompt,
-			claudeMessages,
+			loop.systemPrompt,
+			loop.claudeMessages,
 			assistantContent,
 			toolResults,
 		)
 		if err != nil {
+			if isCancelled(ctx) {
+				return fullResponse.String(), ErrResponseCancelled
+			}
 			return "", fmt.Errorf("failed to continue with tool results: %w", err)
 		}
 	}
@@ -520,99 +1043,192 @@ func (s *ChatService) processStreamWithTools(
 	return fullResponse.String(), nil
 }
 
-// ToolExecutionResult contains the result of executing a tool.
-type ToolExecutionResult struct {
-	Result        ToolResult
-	CreatedFile   string // Path of file created (empty if none)
+// isCancelled returns true if ctx was cancelled (as opposed to timing out).
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
 }
 
-// executeTool executes a single tool and returns the result.
-func (s *ChatService) executeTool(ctx context.Context, projectID uuid.UUID, toolUse ToolUseBlock) ToolExecutionResult {
-	result := ToolResult{
-		Type:      "tool_result",
-		ToolUseID: toolUse.ID,
-	}
-	execResult := ToolExecutionResult{Result: result}
+// abandonStream closes a stream that is no longer being read and drains it in the
+// background so its reader goroutine is not left blocked on a full channel.
+func abandonStream(stream *ClaudeStream) {
+	stream.Close()
+	go func() {
+		for range stream.Chunks() {
+		}
+	}()
+}
 
-	switch toolUse.Name {
-	case "write_file":
-		path, _ := toolUse.Input["path"].(string)
-		content, _ := toolUse.Input["content"].(string)
+// executeTools runs one round of tool calls and returns their results in call order.
+// Consecutive read-only calls run concurrently; any other call runs on its own after
+// the calls before it, so every call sees the effects of the calls that precede it.
+// Returns ErrResponseCancelled if ctx is cancelled between calls.
+func (s *ChatService) executeTools(ctx context.Context, turn *chatTurn, toolUses []ToolUseBlock) ([]ToolExecutionResult, error) {
+	results := make([]ToolExecutionResult, len(toolUses))
 
-		if path == "" {
-			execResult.Result.Content = "Error: path is required"
-			execResult.Result.IsError = true
-			return execResult
+	for start := 0; start < len(toolUses); {
+		if isCancelled(ctx) {
+			return nil, ErrResponseCancelled
 		}
 
-		if s.fileRepo == nil {
-			execResult.Result.Content = "Error: file operations not available"
-			execResult.Result.IsError = true
-			return execResult
+		end := start + 1
+		if s.readOnlyTool(toolUses[start].Name) {
+			for end < len(toolUses) && s.readOnlyTool(toolUses[end].Name) {
+				end++
+			}
 		}
 
-		// Infer language from file extension
-		language := inferLanguageFromPath(path)
-
-		_, err := s.fileRepo.SaveFile(ctx, projectID, path, language, content)
-		if err != nil {
-			execResult.Result.Content = fmt.Sprintf("Error writing file: %v", err)
-			execResult.Result.IsError = true
-			s.logger.Warn().
-				Err(err).
-				Str("path", path).
-				Str("projectId", projectID.String()).
-				Msg("failed to write file via tool")
+		if end-start == 1 {
+			results[start] = s.executeTool(ctx, turn, toolUses[start])
 		} else {
-			execResult.Result.Content = fmt.Sprintf("File written successfully: %s", path)
-			execResult.CreatedFile = path
-			s.logger.Info().
-				Str("path", path).
-				Str("projectId", projectID.String()).
-				Msg("wrote file via tool")
+			var wg sync.WaitGroup
+			for i := start; i < end; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i] = s.executeTool(ctx, turn, toolUses[i])
+				}(i)
+			}
+			wg.Wait()
 		}
 
-	case "read_file":
-		path, _ := toolUse.Input["path"].(string)
+		start = end
+	}
 
-		if path == "" {
-			execResult.Result.Content = "Error: path is required"
-			execResult.Result.IsError = true
-			return execResult
-		}
+	return results, nil
+}
 
-		if s.fileRepo == nil {
-			execResult.Result.Content = "Error: file operations not available"
-			execResult.Result.IsError = true
-			return execResult
-		}
+// readOnlyTool reports whether a tool is registered as read-only.
+func (s *ChatService) readOnlyTool(name string) bool {
+	tool, ok := s.tools.Get(name)
+	return ok && tool.ReadOnly
+}
 
-		file, err := s.fileRepo.GetFileByPath(ctx, projectID, path)
-		if err != nil {
-			execResult.Result.Content = fmt.Sprintf("Error reading file: %v", err)
-			execResult.Result.IsError = true
-			s.logger.Warn().
-				Err(err).
-				Str("path", path).
-				Str("projectId", projectID.String()).
-				Msg("failed to read file via tool")
-		} else {
-			execResult.Result.Content = file.Content
-			s.logger.Debug().
-				Str("path", path).
-				Str("projectId", projectID.String()).
-				Int("contentLength", len(file.Content)).
-				Msg("read file via tool")
+// ToolExecutionResult contains the result of executing a tool.
+type ToolExecutionResult struct {
+	Result        ToolResult
+	CreatedFile   string         // Path of file created or updated, once the turn commits (empty if none)
+	DeletedFile   string         // Path of file deleted or moved away, once the turn commits (empty if none)
+	UpdatedPRD    uuid.UUID      // PRD changed by the tool (uuid.Nil if none)
+	Clarification *Clarification // Question for the user; the turn pauses until it is answered
+}
+
+// executeTool executes a single tool call through the tool registry and returns the result.
+func (s *ChatService) executeTool(ctx context.Context, turn *chatTurn, toolUse ToolUseBlock) ToolExecutionResult {
+	return s.tools.Execute(ctx, turn, toolUse)
+}
+
+// executeEditFile applies an edit_file tool call: search/replace blocks or a unified diff
+// against the file's current content. Edits that don't match the content are rejected as a
+// whole with an error that tells the model what to fix; on success the result reports the
+// changed line ranges.
+func (s *ChatService) executeEditFile(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*editFileInput)
+	projectID := turn.projectID
+	path := in.Path
+
+	file, err := turn.files.GetFileByPath(ctx, path)
+	if errors.Is(err, repository.ErrNotFound) {
+		return toolError(execResult, "file not found: %s (use write_file to create it)", path)
+	}
+	if err != nil {
+		return toolError(execResult, "reading file: %v", err)
+	}
+
+	before := file.Content
+	var updated string
+	if in.Patch != "" {
+		updated, err = diff.ApplyUnified(before, in.Patch)
+	} else {
+		edits := make([]diff.Replacement, len(in.Edits))
+		for i, edit := range in.Edits {
+			edits[i] = diff.Replacement{Search: *edit.Search, Replace: edit.Replace}
 		}
+		updated, err = diff.ApplyReplacements(before, edits)
+	}
+	if err != nil {
+		s.logger.Debug().
+			Err(err).
+			Str("path", path).
+			Str("projectId", projectID.String()).
+			Msg("edit_file did not apply")
+		return toolError(execResult, "%v. No changes were made to %s; read_file it and retry with text copied exactly", err, path)
+	}
 
-	default:
-		execResult.Result.Content = fmt.Sprintf("Unknown tool: %s", toolUse.Name)
-		execResult.Result.IsError = true
+	if updated == before {
+		execResult.Result.Content = fmt.Sprintf("No changes: the edits leave %s unchanged", path)
+		return execResult
 	}
 
+	language := file.Language
+	if language == "" {
+		language = inferLanguageFromPath(path)
+	}
+
+	s.trackFileBefore(ctx, turn, path)
+	saved := turn.files.SaveFile(ctx, path, language, updated, model.FileVersionSourceTool)
+
+	ranges := diff.ChangedLines(before, updated)
+	described := make([]string, len(ranges))
+	for i, r := range ranges {
+		described[i] = r.String()
+	}
+
+	execResult.Result.Content = fmt.Sprintf("File edited successfully: %s (changed lines: %s; now %d lines)",
+		path, strings.Join(described, ", "), len(strings.Split(strings.TrimSuffix(updated, "\n"), "\n")))
+	execResult.CreatedFile = path
+	s.logger.Info().
+		Str("path", path).
+		Str("projectId", projectID.String()).
+		Int("changedRanges", len(ranges)).
+		Msg("edited file via tool")
+
+	turn.changes.record(path, &saved.Content)
+
 	return execResult
 }
 
+// recordFileVersion snapshots a file written during the turn if file history is configured.
+// Failures are logged but never fail the write itself.
+func (s *ChatService) recordFileVersion(ctx context.Context, turn *chatTurn, file *model.File, source model.FileVersionSource) {
+	if s.fileHistory == nil || file == nil {
+		return
+	}
+
+	version, err := s.fileHistory.RecordWrite(ctx, file, source, turn.agentType)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", turn.projectID.String()).
+			Str("path", file.Path).
+			Msg("failed to record file version")
+		return
+	}
+
+	turn.versions = append(turn.versions, *version)
+}
+
+// trackFileBefore captures a file's current content before its first write in the turn,
+// so the turn's change set can diff against it. No-op if change sets are not configured.
+func (s *ChatService) trackFileBefore(ctx context.Context, turn *chatTurn, path string) {
+	if s.changeSets == nil || s.fileRepo == nil || turn.changes.tracking(path) {
+		return
+	}
+
+	var before *string
+	existing, err := s.fileRepo.GetFileByPath(ctx, turn.projectID, path)
+	if err == nil {
+		before = &existing.Content
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", turn.projectID.String()).
+			Str("path", path).
+			Msg("failed to read file before write, diff will treat it as new")
+	}
+
+	turn.changes.begin(path, before)
+}
+
 // inferLanguageFromPath determines the programming language from a file path.
 func inferLanguageFromPath(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// newStallingClaudeServer streams one text chunk and then holds the response open
+// until the client goes away, like a long generation.
+func newStallingClaudeServer(t *testing.T, text string) *httptest.Server {
+	t.Helper()
+	events := textTurnEvents(text)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		// message_start and the first text delta only
+		for _, event := range events[:2] {
+			w.Write([]byte(event))
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		<-r.Context().Done()
+	}))
+}
+
+func newCancelTestChatService(t *testing.T, serverURL string) (*ChatService, *repository.MockProjectRepository, *repository.MockFileRepository) {
+	t.Helper()
+	logger := zerolog.Nop()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   serverURL,
+	}, logger)
+
+	return NewChatService(ChatConfig{}, claudeService, nil, nil, repo, fileRepo, nil, logger), repo, fileRepo
+}
+
+func TestChatService_ProcessMessage_CancelMidStream(t *testing.T) {
+	server := newStallingClaudeServer(t, "Here is the start of")
+	defer server.Close()
+
+	chatService, repo, _ := newCancelTestChatService(t, server.URL)
+	project, _ := repo.Create(context.Background(), "Test Project")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel as soon as the first chunk arrives
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Build a page", func(string) { cancel() }, nil)
+	require.NoError(t, err)
+
+	assert.True(t, result.Cancelled)
+	assert.Equal(t, "Here is the start of", result.Content)
+	assert.True(t, result.Message.Cancelled)
+
+	messages, _ := repo.GetMessages(context.Background(), project.ID)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Here is the start of", messages[1].Content)
+	assert.True(t, messages[1].Cancelled, "partial response should be persisted with the cancelled marker")
+}
+
+func TestChatService_ProcessMessage_CancelBetweenToolCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first request writes a file; the user cancels while the tool results are sent back
+	writeTurn := toolUseTurnEvents("toolu_write", "write_file", map[string]interface{}{"path": "index.html", "content": "<h1>Hello</h1>"})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			cancel()
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range writeTurn {
+			w.Write([]byte(event))
+		}
+	}))
+	defer server.Close()
+
+	chatService, repo, fileRepo := newCancelTestChatService(t, server.URL)
+	project, _ := repo.Create(context.Background(), "Test Project")
+
+	var created []string
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Create index.html", func(string) {}, func(path string) { created = append(created, path) })
+	require.NoError(t, err)
+
+	assert.True(t, result.Cancelled)
+	assert.Nil(t, result.Changes)
+	assert.Empty(t, created)
+
+	_, err = fileRepo.GetFileByPath(context.Background(), project.ID, "index.html")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "staged writes of a cancelled turn are rolled back")
+}
+
+// stallingContinuationMessenger replays scripted responses to new messages, but answers
+// tool results with a stream that sends one chunk and then stays open, with no error, until
+// the test ends.
+type stallingContinuationMessenger struct {
+	*scriptedClaudeMessenger
+	t    *testing.T
+	text string
+}
+
+func (m *stallingContinuationMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	stream := &ClaudeStream{chunks: make(chan string, 1), done: make(chan struct{})}
+	stream.chunks <- m.text
+	m.t.Cleanup(func() { close(stream.chunks) })
+	return stream, nil
+}
+
+func TestChatService_ProcessMessage_DeadlineMidStream(t *testing.T) {
+	// The turn writes a file; the response to its tool results stalls past the turn's deadline
+	claude := &stallingContinuationMessenger{
+		scriptedClaudeMessenger: &scriptedClaudeMessenger{responses: []scriptedResponse{
+			{toolUses: []ToolUseBlock{writeFileCall("index.html", "<h1>Hello</h1>")}},
+		}},
+		t:    t,
+		text: "Still working on",
+	}
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	chatService := NewChatService(ChatConfig{TurnTimeout: 100 * time.Millisecond}, claude, nil, nil, repo, fileRepo, nil, zerolog.Nop())
+	project, _ := repo.Create(context.Background(), "Test Project")
+
+	var chunks []string
+	result, err := chatService.ProcessMessage(context.Background(), project.ID, "Create index.html", func(chunk string) { chunks = append(chunks, chunk) }, nil)
+	require.Error(t, err)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ErrorCodeTimeout, ClaudeErrorCode(err))
+	assert.Equal(t, []string{"Still working on"}, chunks, "the deadline passed after the response started")
+
+	messages, _ := repo.GetMessages(context.Background(), project.ID)
+	require.Len(t, messages, 1, "the partial response is not saved")
+	assert.Equal(t, model.RoleUser, messages[0].Role)
+
+	_, err = fileRepo.GetFileByPath(context.Background(), project.ID, "index.html")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "staged writes of a timed-out turn are discarded")
+}
+
+func TestChatService_BuildClaudeMessages_SkipsEmptyCancelledResponses(t *testing.T) {
+	chatService, _, _ := newCancelTestChatService(t, "http://unused")
+
+	claudeMessages := chatService.buildClaudeMessages([]model.Message{
+		{Role: model.RoleUser, Content: "Build a page"},
+		{Role: model.RoleAssistant, Content: "", Cancelled: true},
+		{Role: model.RoleUser, Content: "Try again"},
+	})
+
+	require.Len(t, claudeMessages, 2)
+	assert.Equal(t, "Try again", claudeMessages[1].Content)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+const editTestPage = `<html>
+<body>
+  <h1>Hello</h1>
+  <p>Welcome</p>
+</body>
+</html>
+`
+
+// newEditTestChatService returns a chat service with one project containing index.html.
+func newEditTestChatService(t *testing.T) (*ChatService, *repository.MockFileRepository, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	project, _ := repo.Create(ctx, "Edit Project")
+
+	_, err := fileRepo.SaveFile(ctx, project.ID, "index.html", "html", editTestPage)
+	require.NoError(t, err)
+
+	return NewChatService(ChatConfig{}, nil, nil, nil, repo, fileRepo, nil, zerolog.Nop()), fileRepo, project.ID
+}
+
+func editFile(s *ChatService, projectID uuid.UUID, input map[string]interface{}) ToolExecutionResult {
+	ctx := context.Background()
+	turn := s.newTurn(projectID, nil)
+	result := s.executeTool(ctx, turn, ToolUseBlock{Type: "tool_use", ID: "toolu_edit", Name: "edit_file", Input: input})
+	if err := s.commitTurnFiles(ctx, turn, ChatCallbacks{}); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestChatService_EditFile_SearchReplace(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+
+	result := editFile(s, projectID, map[string]interface{}{
+		"path": "index.html",
+		"edits": []interface{}{
+			map[string]interface{}{"search": "<h1>Hello</h1>", "replace": "<h1>Hi there</h1>"},
+			map[string]interface{}{"search": "  <p>Welcome</p>\n", "replace": "  <p>Welcome</p>\n  <p>Enjoy</p>\n"},
+		},
+	})
+
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "File edited successfully: index.html (changed lines: 3, 5; now 7 lines)", result.Result.Content)
+	assert.Equal(t, "index.html", result.CreatedFile)
+
+	file, err := fileRepo.GetFileByPath(context.Background(), projectID, "index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<html>\n<body>\n  <h1>Hi there</h1>\n  <p>Welcome</p>\n  <p>Enjoy</p>\n</body>\n</html>\n", file.Content)
+	assert.Equal(t, "html", file.Language)
+}
+
+func TestChatService_EditFile_Patch(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+
+	result := editFile(s, projectID, map[string]interface{}{
+		"path":  "index.html",
+		"patch": "--- a/index.html\n+++ b/index.html\n@@ -3,2 +3,2 @@\n   <h1>Hello</h1>\n-  <p>Welcome</p>\n+  <p>Welcome back</p>\n",
+	})
+
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Contains(t, result.Result.Content, "changed lines: 4")
+
+	file, _ := fileRepo.GetFileByPath(context.Background(), projectID, "index.html")
+	assert.Contains(t, file.Content, "<p>Welcome back</p>")
+}
+
+func TestChatService_EditFile_AnchorNotFound(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+
+	result := editFile(s, projectID, map[string]interface{}{
+		"path": "index.html",
+		"edits": []interface{}{
+			map[string]interface{}{"search": "<h1>Hello</h1>", "replace": "<h1>Hi</h1>"},
+			map[string]interface{}{"search": "<p>Goodbye</p>", "replace": "<p>Bye</p>"},
+		},
+	})
+
+	assert.True(t, result.Result.IsError)
+	assert.Contains(t, result.Result.Content, "edit 2: no match in current content")
+	assert.Contains(t, result.Result.Content, "No changes were made to index.html")
+	assert.Empty(t, result.CreatedFile)
+
+	file, _ := fileRepo.GetFileByPath(context.Background(), projectID, "index.html")
+	assert.Equal(t, editTestPage, file.Content, "a failed edit applies nothing, not even the edits that matched")
+}
+
+func TestChatService_EditFile_InvalidInput(t *testing.T) {
+	s, _, projectID := newEditTestChatService(t)
+	edits := []interface{}{map[string]interface{}{"search": "Hello", "replace": "Hi"}}
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+	}{
+		{"missing path", map[string]interface{}{"edits": edits}, "Error: path is required"},
+		{"neither edits nor patch", map[string]interface{}{"path": "index.html"}, "Error: provide either edits or patch"},
+		{"both edits and patch", map[string]interface{}{"path": "index.html", "edits": edits, "patch": "@@ -1 +1 @@\n-a\n+b\n"}, "Error: provide either edits or patch"},
+		{"missing file", map[string]interface{}{"path": "about.html", "edits": edits}, "Error: file not found: about.html (use write_file to create it)"},
+		{"missing search", map[string]interface{}{"path": "index.html", "edits": []interface{}{map[string]interface{}{"replace": "x"}}}, "Error: edit 1: search is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := editFile(s, projectID, tt.input)
+			assert.True(t, result.Result.IsError)
+			assert.Equal(t, tt.expected, result.Result.Content)
+		})
+	}
+}
+
+func TestChatService_ProcessMessage_ToolUseEditFile(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_edit", "edit_file", map[string]interface{}{
+			"path":  "index.html",
+			"edits": []interface{}{map[string]interface{}{"search": "<h1>Hello</h1>", "replace": "<h1>Edited</h1>"}},
+		}),
+		textTurnEvents("Updated the heading."),
+	)
+	defer server.Close()
+
+	s, fileRepo, projectID := newEditTestChatService(t)
+	s.claudeService = NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, zerolog.Nop())
+
+	var updatedFiles []string
+	result, err := s.ProcessMessage(context.Background(), projectID, "Change the heading", func(string) {}, func(path string) {
+		updatedFiles = append(updatedFiles, path)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Updated the heading.", result.Content)
+	assert.Equal(t, []string{"index.html"}, updatedFiles)
+	file, _ := fileRepo.GetFileByPath(context.Background(), projectID, "index.html")
+	assert.Contains(t, file.Content, "<h1>Edited</h1>")
+}
+
+func TestFileTools_IncludesEditFile(t *testing.T) {
+	var names []string
+	for _, tool := range fileToolDefinitions() {
+		names = append(names, tool.Name)
+	}
+	assert.Contains(t, names, "edit_file")
+}
@@ -71,7 +71,7 @@ func TestChatService_ProcessMessage_Success(t *testing.T) {
 	}
 
 	// Process message
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Hi there!", onChunk)
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Hi there!", onChunk, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -156,7 +156,7 @@ func TestChatService_ProcessMessage_WithContext(t *testing.T) {
 	}, claudeService, nil, nil, repo, nil, nil, logger)
 
 	// Process a new message
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Second message", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Second message", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -215,7 +215,7 @@ func TestChatService_ProcessMessage_ContextLimit(t *testing.T) {
 		ContextMessageLimit: 10,
 	}, claudeService, nil, nil, repo, nil, nil, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "New message", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "New message", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -247,7 +247,7 @@ func TestChatService_ProcessMessage_ProjectNotFound(t *testing.T) {
 	ctx := context.Background()
 	nonExistentID := uuid.New()
 
-	_, err := chatService.ProcessMessage(ctx, nonExistentID, "Hello", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, nonExistentID, "Hello", func(string) {}, nil)
 	if err == nil {
 		t.Fatal("expected error for non-existent project")
 	}
@@ -287,7 +287,7 @@ func TestChatService_ProcessMessage_CodeBlockExtraction(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, nil, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Show me code", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Show me code", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -331,7 +331,7 @@ func TestChatService_ProcessMessage_Timeout(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, nil, nil, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {}, nil)
 	if err == nil {
 		t.Fatal("expected timeout error")
 	}
@@ -405,7 +405,7 @@ h1 { color: blue; }
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, fileMetadataRepo, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a homepage", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a homepage", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -503,7 +503,7 @@ console.log('Hello World');
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, fileMetadataRepo, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a script", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a script", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -578,7 +578,7 @@ func TestChatService_ProcessMessage_DiscoveryMode(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, discoveryService, nil, projectRepo, nil, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -643,7 +643,7 @@ func TestChatService_ProcessMessage_DiscoveryModeAdvancesStage(t *testing.T) {
 	}, claudeService, discoveryService, nil, projectRepo, nil, nil, logger)
 
 	// Process message - should create discovery in welcome stage and advance to problem stage
-	_, err := chatService.ProcessMessage(ctx, project.ID, "I run a bakery", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "I run a bakery", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -708,7 +708,7 @@ func TestChatService_ProcessMessage_WithoutDiscoveryService(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, projectRepo, nil, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -765,7 +765,7 @@ func TestChatService_ProcessMessage_DiscoveryCompleteUsesDefaultPrompt(t *testin
 		ContextMessageLimit: 20,
 	}, claudeService, discoveryService, nil, projectRepo, nil, nil, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a homepage", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a homepage", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -820,7 +820,7 @@ func TestChatService_ProcessMessage_AgentTypeTracking(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, discoveryService, agentContextService, projectRepo, nil, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Build me a homepage", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Build me a homepage", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -88,7 +89,7 @@ func TestChatService_ProcessMessage_ToolUseWriteFile(t *testing.T) {
 		chunks = append(chunks, chunk)
 	}
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Create an index.html file", onChunk)
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Create an index.html file", onChunk, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -204,7 +205,7 @@ func TestChatService_ProcessMessage_ToolUseReadFile(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "What's in config.json?", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "What's in config.json?", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -288,7 +289,7 @@ func TestChatService_ProcessMessage_MultipleToolUses(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, nil, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Create HTML and CSS files", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Create HTML and CSS files", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -391,7 +392,7 @@ func TestChatService_ProcessMessage_ToolError(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, nil, logger)
 
-	result, err := chatService.ProcessMessage(ctx, project.ID, "Read nonexistent.txt", func(string) {})
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Read nonexistent.txt", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -464,7 +465,7 @@ func TestChatService_ProcessMessage_FallbackToMarkdown(t *testing.T) {
 		ContextMessageLimit: 20,
 	}, claudeService, nil, nil, repo, fileRepo, nil, logger)
 
-	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a file", func(string) {})
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Create a file", func(string) {}, nil)
 	if err != nil {
 		t.Fatalf("ProcessMessage failed: %v", err)
 	}
@@ -483,3 +484,119 @@ func TestChatService_ProcessMessage_FallbackToMarkdown(t *testing.T) {
 		t.Errorf("expected file path 'index.html', got '%s'", files[0].Path)
 	}
 }
+
+// sseEvent formats a single Claude SSE event.
+func sseEvent(eventType, data string) string {
+	return "event: " + eventType + "\n" + "data: " + data + "\n\n"
+}
+
+// textTurnEvents returns SSE events for an assistant turn that only streams text.
+func textTurnEvents(text string) []string {
+	textJSON, _ := json.Marshal(text)
+	return []string{
+		sseEvent("message_start", `{"type":"message_start","message":{"id":"msg_text","role":"assistant"}}`),
+		sseEvent("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":`+string(textJSON)+`}}`),
+		sseEvent("message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`),
+		sseEvent("message_stop", `{"type":"message_stop"}`),
+	}
+}
+
+// toolUseTurnEvents returns SSE events for an assistant turn that calls a single tool.
+func toolUseTurnEvents(toolID, toolName string, input map[string]interface{}) []string {
+	inputJSON, _ := json.Marshal(input)
+	partialJSON, _ := json.Marshal(string(inputJSON))
+	return []string{
+		sseEvent("message_start", `{"type":"message_start","message":{"id":"msg_tool","role":"assistant"}}`),
+		sseEvent("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"`+toolID+`","name":"`+toolName+`","input":{}}}`),
+		sseEvent("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":`+string(partialJSON)+`}}`),
+		sseEvent("content_block_stop", `{"type":"content_block_stop","index":0}`),
+		sseEvent("message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`),
+		sseEvent("message_stop", `{"type":"message_stop"}`),
+	}
+}
+
+// newScriptedClaudeServer returns a test server that replays one scripted turn per request,
+// and a function returning the request bodies it has received. Requests beyond the script
+// replay the last turn.
+func newScriptedClaudeServer(t *testing.T, turns ...[]string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		idx := min(len(bodies), len(turns)-1)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range turns[idx] {
+			w.Write([]byte(event))
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+// newToolUseTestServer returns a test server where Claude writes one file and then finishes.
+func newToolUseTestServer(t *testing.T, path, content string) *httptest.Server {
+	t.Helper()
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_write", "write_file", map[string]interface{}{"path": path, "content": content}),
+		textTurnEvents("Done."),
+	)
+	return server
+}
+
+func TestChatService_ProcessMessage_ToolLimitReached(t *testing.T) {
+	// Claude writes a file and then keeps calling tools without finishing
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_write", "write_file", map[string]interface{}{"path": "index.html", "content": "<h1>Hi</h1>"}),
+		toolUseTurnEvents("toolu_list", "list_files", map[string]interface{}{}),
+	)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	claudeService := NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, logger)
+	chatService := NewChatService(ChatConfig{MaxToolIterations: 3}, claudeService, nil, nil, repo, fileRepo, nil, logger)
+
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var created []string
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Build a page", func(string) {}, func(path string) { created = append(created, path) })
+	if err != nil {
+		t.Fatalf("ProcessMessage failed: %v", err)
+	}
+
+	if !result.ToolLimitReached {
+		t.Error("expected ToolLimitReached to be set")
+	}
+	if !strings.Contains(result.Content, "maximum of 3 rounds of tool calls") {
+		t.Errorf("expected the response to tell the user about the limit, got: %q", result.Content)
+	}
+	if result.Changes != nil || len(created) != 0 {
+		t.Errorf("expected no file changes, got changes %v and created %v", result.Changes, created)
+	}
+
+	// File changes of the unfinished turn are discarded
+	files, _ := fileRepo.GetFilesByProject(ctx, project.ID)
+	if len(files) != 0 {
+		t.Errorf("expected no files, got %d", len(files))
+	}
+
+	messages, _ := repo.GetMessages(ctx, project.ID)
+	if len(messages) != 2 || !strings.Contains(messages[1].Content, "maximum of 3 rounds of tool calls") {
+		t.Errorf("expected the saved response to include the limit notice, got %v", messages)
+	}
+}
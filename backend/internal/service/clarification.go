@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// clarificationTTL is how long a paused turn waits for its answer. After that the turn
+// is abandoned and its question saved as unanswered.
+const clarificationTTL = 30 * time.Minute
+
+// maxClarificationOptions limits the multiple-choice options of a question.
+const maxClarificationOptions = 6
+
+// ErrClarificationNeeded is returned by processStreamWithTools when Claude asks the user a question.
+var ErrClarificationNeeded = errors.New("clarification needed")
+
+// ErrNoClarification is returned by AnswerClarification when the project has no question
+// with the given ID waiting for an answer, e.g. because it expired.
+var ErrNoClarification = errors.New("no question is waiting for an answer")
+
+// Clarification is a question Claude asked the user with ask_user.
+type Clarification struct {
+	ID       uuid.UUID
+	Question string
+	Options  []string // Suggested answers; the user may also answer freely
+}
+
+// pendingClarification is a tool loop paused on an ask_user call.
+type pendingClarification struct {
+	Clarification
+	loop        *toolLoop
+	answerIndex int // Index in loop.toolResults of the ask_user call, whose result is the answer
+}
+
+// newPendingClarification returns the question asked in a round of tool calls, or nil if
+// none was. Claude gets one answer at a time, so further questions in the round fail.
+func newPendingClarification(loop *toolLoop, results []ToolExecutionResult) *pendingClarification {
+	var pending *pendingClarification
+	for i, result := range results {
+		if result.Clarification == nil {
+			continue
+		}
+		if pending == nil {
+			pending = &pendingClarification{Clarification: *result.Clarification, loop: loop, answerIndex: i}
+			continue
+		}
+		loop.toolResults[i].Content = "Error: ask one question at a time; ask again after the user answers"
+		loop.toolResults[i].IsError = true
+	}
+	return pending
+}
+
+// pausedTurn is a turn waiting for the user to answer its question. Paused turns are kept
+// in memory, so a question survives reconnects but not a server restart.
+type pausedTurn struct {
+	turn      *chatTurn
+	usage     *usageScope // Collects the token usage of the turn's Claude calls, before and after the pause
+	expiresAt time.Time
+	timer     *time.Timer // Abandons the turn at expiresAt
+}
+
+func (p *pausedTurn) expired() bool {
+	return !time.Now().Before(p.expiresAt)
+}
+
+// askUserInput is the input of ask_user.
+type askUserInput struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+}
+
+func (in *askUserInput) Validate() error {
+	if strings.TrimSpace(in.Question) == "" {
+		return errors.New("question is required")
+	}
+	if len(in.Options) > maxClarificationOptions {
+		return fmt.Errorf("offer at most %d options", maxClarificationOptions)
+	}
+	for _, option := range in.Options {
+		if strings.TrimSpace(option) == "" {
+			return errors.New("options must not be empty")
+		}
+	}
+	return nil
+}
+
+// registerAskUserTool registers ask_user, which is offered to turns whose caller can
+// relay the question to the user (see ChatCallbacks.OnClarification).
+func (s *ChatService) registerAskUserTool() {
+	err := s.tools.Register(Tool{
+		Name: "ask_user",
+		Description: "Ask the user a question when their request is ambiguous and a wrong guess would waste their time. " +
+			"The conversation pauses until they answer, and the answer is this tool's result. " +
+			"Offer options when the likely answers are known. Don't ask about details you can reasonably decide yourself.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "One short, specific question",
+				},
+				"options": map[string]interface{}{
+					"type":        "array",
+					"description": "Optional answers to choose from (at most 6); the user can also answer in their own words",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+			},
+			"required": []string{"question"},
+		},
+		NewInput:    func() ToolInput { return &askUserInput{} },
+		Handler:     s.executeAskUser,
+		Permissions: []ToolPermission{ToolPermissionAskUser},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// executeAskUser records the question; the tool loop pauses after the round and the
+// result is filled in with the answer when the user replies.
+func (s *ChatService) executeAskUser(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*askUserInput)
+	execResult.Clarification = &Clarification{ID: uuid.New(), Question: in.Question, Options: in.Options}
+	return execResult
+}
+
+// pauseTurn keeps a turn whose tool loop stopped on a question until the user answers,
+// or abandons it once the question expires.
+func (s *ChatService) pauseTurn(ctx context.Context, turn *chatTurn) Clarification {
+	paused := &pausedTurn{turn: turn, usage: usageScopeFrom(ctx), expiresAt: time.Now().Add(s.clarificationTTL)}
+	paused.timer = time.AfterFunc(s.clarificationTTL, func() { s.expirePausedTurn(paused) })
+
+	s.pausedMu.Lock()
+	s.paused[turn.projectID] = paused
+	s.pausedMu.Unlock()
+
+	s.logger.Info().
+		Str("projectId", turn.projectID.String()).
+		Str("clarificationId", turn.pending.ID.String()).
+		Msg("turn paused for clarification")
+	return turn.pending.Clarification
+}
+
+// PendingClarification returns the question a project's paused turn is waiting on, if any.
+func (s *ChatService) PendingClarification(projectID uuid.UUID) (*Clarification, bool) {
+	s.pausedMu.Lock()
+	defer s.pausedMu.Unlock()
+
+	paused, ok := s.paused[projectID]
+	if !ok || paused.expired() {
+		return nil, false
+	}
+	clarification := paused.turn.pending.Clarification
+	return &clarification, true
+}
+
+// AnswerClarification resumes the turn paused on a question with the user's answer as the
+// ask_user result, and completes it like ProcessMessageWithCallbacks. The question and
+// answer become part of the response. Returns ErrNoClarification if the project has no
+// such question waiting.
+func (s *ChatService) AnswerClarification(ctx context.Context, projectID, clarificationID uuid.UUID, answer string, callbacks ChatCallbacks) (*ChatResult, error) {
+	if strings.TrimSpace(answer) == "" {
+		return nil, errors.New("answer is required")
+	}
+
+	// As for a new message, getting the response has TurnTimeout
+	caller := ctx
+	ctx, cancelTurn := context.WithTimeout(ctx, s.config.TurnTimeout)
+	defer cancelTurn()
+
+	s.pausedMu.Lock()
+	paused, ok := s.paused[projectID]
+	if !ok || paused.expired() || paused.turn.pending.ID != clarificationID {
+		s.pausedMu.Unlock()
+		return nil, ErrNoClarification
+	}
+	delete(s.paused, projectID)
+	s.pausedMu.Unlock()
+	paused.timer.Stop()
+
+	turn := paused.turn
+	pending := turn.pending
+	turn.pending = nil
+	turn.permissions[ToolPermissionAskUser] = callbacks.OnClarification != nil
+
+	loop := pending.loop
+	loop.toolResults[pending.answerIndex].Content = answer
+	exchange := formatClarification(loop.response, pending.Clarification, answer)
+	loop.response += exchange
+	if callbacks.OnChunk != nil {
+		callbacks.OnChunk(exchange)
+	}
+
+	if paused.usage != nil {
+		ctx = context.WithValue(ctx, usageScopeKey{}, paused.usage)
+	}
+
+	s.logger.Info().
+		Str("projectId", projectID.String()).
+		Str("clarificationId", clarificationID.String()).
+		Msg("resuming turn with clarification answer")
+
+	responseContent, err := s.resumeStreamWithTools(ctx, turn, loop, callbacks)
+	finishCtx, cancelFinish := finishContext(ctx, caller)
+	defer cancelFinish()
+	return s.finishTurn(finishCtx, turn, responseContent, err, callbacks)
+}
+
+// abandonPausedTurn ends a project's paused turn without an answer: its file changes are
+// discarded and the response so far is saved with the unanswered question, so the
+// conversation history shows what Claude asked.
+func (s *ChatService) abandonPausedTurn(ctx context.Context, projectID uuid.UUID) {
+	s.pausedMu.Lock()
+	paused, ok := s.paused[projectID]
+	delete(s.paused, projectID)
+	s.pausedMu.Unlock()
+	if !ok {
+		return
+	}
+	paused.timer.Stop()
+	s.abandonTurn(ctx, paused)
+}
+
+// expirePausedTurn abandons a paused turn whose question was not answered in time,
+// unless it has already been answered or abandoned.
+func (s *ChatService) expirePausedTurn(paused *pausedTurn) {
+	projectID := paused.turn.projectID
+	s.pausedMu.Lock()
+	if s.paused[projectID] != paused {
+		s.pausedMu.Unlock()
+		return
+	}
+	delete(s.paused, projectID)
+	s.pausedMu.Unlock()
+
+	s.logger.Info().
+		Str("projectId", projectID.String()).
+		Str("clarificationId", paused.turn.pending.ID.String()).
+		Msg("clarification expired")
+	s.abandonTurn(context.Background(), paused)
+}
+
+// abandonTurn discards a paused turn's file changes and saves its response with the
+// unanswered question.
+func (s *ChatService) abandonTurn(ctx context.Context, paused *pausedTurn) {
+	projectID := paused.turn.projectID
+	turn := paused.turn
+	s.discardTurnFiles(turn)
+
+	content := turn.pending.loop.response
+	if turn.discovery != nil && !turn.discovery.Stage.IsComplete() {
+		content = StripMetadata(content)
+	}
+	content += formatClarification(content, turn.pending.Clarification, "")
+
+	if paused.usage != nil {
+		ctx = context.WithValue(ctx, usageScopeKey{}, paused.usage)
+	}
+	msg, err := s.repo.CreateMessageWithAgent(ctx, projectID, model.RoleAssistant, content, turn.agentType)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", projectID.String()).
+			Msg("failed to save unanswered clarification")
+		return
+	}
+	if s.usage != nil {
+		if err := s.usage.AttachMessage(ctx, msg.ID); err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("messageId", msg.ID.String()).
+				Msg("failed to link token usage to message")
+		}
+	}
+}
+
+// formatClarification renders a question, its options and the answer (if any) as a
+// Markdown quote to append to a response.
+func formatClarification(response string, clarification Clarification, answer string) string {
+	var sb strings.Builder
+	if response != "" {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("> **" + clarification.Question + "**\n")
+	for _, option := range clarification.Options {
+		sb.WriteString("> - " + option + "\n")
+	}
+	if answer != "" {
+		sb.WriteString(">\n")
+		for _, line := range strings.Split(strings.TrimSpace(answer), "\n") {
+			sb.WriteString("> " + line + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// scriptedResponse is one scripted Claude response: streamed text, then optional tool calls.
+type scriptedResponse struct {
+	text     string
+	toolUses []ToolUseBlock
+}
+
+// scriptedClaudeMessenger replays one scripted response per call and records what it was sent.
+type scriptedClaudeMessenger struct {
+	mu          sync.Mutex
+	responses   []scriptedResponse
+	calls       int
+	offered     [][]string     // Names of the tools offered with each call
+	toolResults [][]ToolResult // Tool results sent with each continuation
+}
+
+func (m *scriptedClaudeMessenger) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for _, tool := range toolsFrom(ctx) {
+		names = append(names, tool.Name)
+	}
+	m.offered = append(m.offered, names)
+
+	response := m.responses[min(m.calls, len(m.responses)-1)]
+	m.calls++
+
+	stream := &ClaudeStream{chunks: make(chan string, 1), done: make(chan struct{}), toolUses: response.toolUses, stopReason: "end_turn"}
+	if len(response.toolUses) > 0 {
+		stream.stopReason = StopReasonToolUse
+	}
+	if response.text != "" {
+		stream.chunks <- response.text
+	}
+	close(stream.chunks)
+	close(stream.done)
+	return stream, nil
+}
+
+func (m *scriptedClaudeMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	m.mu.Lock()
+	m.toolResults = append(m.toolResults, toolResults)
+	m.mu.Unlock()
+	return m.SendMessage(ctx, systemPrompt, messages)
+}
+
+func askUserCall(question string, options ...string) ToolUseBlock {
+	input := map[string]interface{}{"question": question}
+	if len(options) > 0 {
+		input["options"] = options
+	}
+	return ToolUseBlock{Type: "tool_use", ID: "toolu_ask", Name: "ask_user", Input: input}
+}
+
+func writeFileCall(path, content string) ToolUseBlock {
+	return ToolUseBlock{Type: "tool_use", ID: "toolu_write", Name: "write_file", Input: map[string]interface{}{"path": path, "content": content}}
+}
+
+func newClarificationTestChatService(t *testing.T, responses ...scriptedResponse) (*ChatService, *scriptedClaudeMessenger, *repository.MockProjectRepository, *repository.MockFileRepository, uuid.UUID) {
+	t.Helper()
+	claude := &scriptedClaudeMessenger{responses: responses}
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	s := NewChatService(ChatConfig{}, claude, nil, nil, repo, fileRepo, nil, zerolog.Nop())
+	project, err := repo.Create(context.Background(), "Clarification Project")
+	require.NoError(t, err)
+	return s, claude, repo, fileRepo, project.ID
+}
+
+func TestChatService_AskUser_PausesUntilAnswered(t *testing.T) {
+	s, claude, repo, fileRepo, projectID := newClarificationTestChatService(t,
+		scriptedResponse{text: "Let me set that up.", toolUses: []ToolUseBlock{writeFileCall("index.html", "<h1>Hi</h1>"), askUserCall("Which colour?", "Blue", "Green")}},
+		scriptedResponse{text: "Blue it is."},
+	)
+	ctx := context.Background()
+
+	var asked []Clarification
+	var created []string
+	callbacks := ChatCallbacks{
+		OnFileCreated:   func(path string) { created = append(created, path) },
+		OnClarification: func(c Clarification) { asked = append(asked, c) },
+	}
+
+	result, err := s.ProcessMessageWithCallbacks(ctx, projectID, "Build a page", callbacks)
+	require.NoError(t, err)
+	require.NotNil(t, result.Clarification)
+	assert.Equal(t, "Which colour?", result.Clarification.Question)
+	assert.Equal(t, []string{"Blue", "Green"}, result.Clarification.Options)
+	assert.Equal(t, []Clarification{*result.Clarification}, asked)
+	assert.Equal(t, "Let me set that up.", result.Content)
+	assert.Contains(t, claude.offered[0], "ask_user")
+
+	// Nothing is saved while the turn waits
+	assert.Nil(t, result.Message)
+	assert.Empty(t, created)
+	files, _ := fileRepo.GetFilesByProject(ctx, projectID)
+	assert.Empty(t, files)
+	messages, _ := repo.GetMessages(ctx, projectID)
+	assert.Len(t, messages, 1)
+
+	pending, ok := s.PendingClarification(projectID)
+	require.True(t, ok)
+	assert.Equal(t, result.Clarification, pending)
+
+	_, err = s.AnswerClarification(ctx, projectID, uuid.New(), "Blue", callbacks)
+	assert.ErrorIs(t, err, ErrNoClarification)
+
+	var chunks []string
+	callbacks.OnChunk = func(chunk string) { chunks = append(chunks, chunk) }
+	result, err = s.AnswerClarification(ctx, projectID, pending.ID, "Blue", callbacks)
+	require.NoError(t, err)
+
+	// The answer is the ask_user result; the other results of the round are kept
+	require.Len(t, claude.toolResults, 1)
+	require.Len(t, claude.toolResults[0], 2)
+	assert.False(t, claude.toolResults[0][0].IsError, claude.toolResults[0][0].Content)
+	assert.Equal(t, ToolResult{Type: "tool_result", ToolUseID: "toolu_ask", Content: "Blue"}, claude.toolResults[0][1])
+
+	expected := "Let me set that up.\n\n> **Which colour?**\n> - Blue\n> - Green\n>\n> Blue\n\nBlue it is."
+	assert.Equal(t, expected, result.Content)
+	assert.Equal(t, []string{"\n\n> **Which colour?**\n> - Blue\n> - Green\n>\n> Blue\n\n", "Blue it is."}, chunks)
+	assert.Nil(t, result.Clarification)
+	require.NotNil(t, result.Message)
+	assert.Equal(t, []string{"index.html"}, created)
+
+	messages, _ = repo.GetMessages(ctx, projectID)
+	require.Len(t, messages, 2)
+	assert.Equal(t, expected, messages[1].Content)
+
+	_, ok = s.PendingClarification(projectID)
+	assert.False(t, ok)
+}
+
+func TestChatService_AskUser_NewMessageLeavesQuestionUnanswered(t *testing.T) {
+	s, _, repo, fileRepo, projectID := newClarificationTestChatService(t,
+		scriptedResponse{text: "Sure.", toolUses: []ToolUseBlock{writeFileCall("index.html", "<h1>Hi</h1>"), askUserCall("Which colour?")}},
+		scriptedResponse{text: "Red it is."},
+	)
+	ctx := context.Background()
+	callbacks := ChatCallbacks{OnClarification: func(Clarification) {}}
+
+	first, err := s.ProcessMessageWithCallbacks(ctx, projectID, "Build a page", callbacks)
+	require.NoError(t, err)
+	require.NotNil(t, first.Clarification)
+
+	_, err = s.ProcessMessageWithCallbacks(ctx, projectID, "Never mind, make it red", callbacks)
+	require.NoError(t, err)
+
+	messages, _ := repo.GetMessages(ctx, projectID)
+	var contents []string
+	for _, message := range messages {
+		contents = append(contents, message.Content)
+	}
+	assert.Equal(t, []string{"Build a page", "Sure.\n\n> **Which colour?**\n\n", "Never mind, make it red", "Red it is."}, contents)
+
+	// The unfinished turn's file changes were discarded
+	files, _ := fileRepo.GetFilesByProject(ctx, projectID)
+	assert.Empty(t, files)
+
+	_, err = s.AnswerClarification(ctx, projectID, first.Clarification.ID, "Blue", callbacks)
+	assert.ErrorIs(t, err, ErrNoClarification)
+}
+
+func TestChatService_AskUser_ExpiredQuestionIsLeftUnanswered(t *testing.T) {
+	s, _, repo, fileRepo, projectID := newClarificationTestChatService(t,
+		scriptedResponse{text: "Sure.", toolUses: []ToolUseBlock{writeFileCall("index.html", "<h1>Hi</h1>"), askUserCall("Which colour?")}},
+	)
+	s.clarificationTTL = 20 * time.Millisecond
+	ctx := context.Background()
+	callbacks := ChatCallbacks{OnClarification: func(Clarification) {}}
+
+	result, err := s.ProcessMessageWithCallbacks(ctx, projectID, "Build a page", callbacks)
+	require.NoError(t, err)
+	require.NotNil(t, result.Clarification)
+
+	// The turn is abandoned without another message from the project
+	require.Eventually(t, func() bool {
+		messages, _ := repo.GetMessages(ctx, projectID)
+		return len(messages) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	messages, _ := repo.GetMessages(ctx, projectID)
+	assert.Equal(t, "Sure.\n\n> **Which colour?**\n\n", messages[1].Content)
+	files, _ := fileRepo.GetFilesByProject(ctx, projectID)
+	assert.Empty(t, files)
+
+	_, ok := s.PendingClarification(projectID)
+	assert.False(t, ok)
+	_, err = s.AnswerClarification(ctx, projectID, result.Clarification.ID, "Blue", callbacks)
+	assert.ErrorIs(t, err, ErrNoClarification)
+}
+
+func TestChatService_AskUser_OnlyWhenTheCallerCanAsk(t *testing.T) {
+	s, claude, _, _, projectID := newClarificationTestChatService(t, scriptedResponse{text: "Hi!"})
+
+	_, err := s.ProcessMessage(context.Background(), projectID, "Hello", func(string) {}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, claude.offered[0], "ask_user")
+
+	turn := s.newTurn(projectID, nil)
+	result := s.executeTool(context.Background(), turn, askUserCall("Which colour?"))
+	assert.Equal(t, "Error: ask_user is not available in this conversation", result.Result.Content)
+	assert.Nil(t, result.Clarification)
+}
+
+func TestAskUserInput_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    askUserInput
+		expected string
+	}{
+		{"valid", askUserInput{Question: "Which colour?", Options: []string{"Blue"}}, ""},
+		{"no question", askUserInput{Question: " "}, "question is required"},
+		{"empty option", askUserInput{Question: "Which colour?", Options: []string{"Blue", ""}}, "options must not be empty"},
+		{"too many options", askUserInput{Question: "Which?", Options: []string{"1", "2", "3", "4", "5", "6", "7"}}, "offer at most 6 options"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewPendingClarification_OneQuestionPerRound(t *testing.T) {
+	loop := &toolLoop{toolResults: []ToolResult{{ToolUseID: "toolu_1"}, {ToolUseID: "toolu_2"}, {ToolUseID: "toolu_3"}}}
+	results := []ToolExecutionResult{
+		{Result: loop.toolResults[0]},
+		{Result: loop.toolResults[1], Clarification: &Clarification{Question: "First?"}},
+		{Result: loop.toolResults[2], Clarification: &Clarification{Question: "Second?"}},
+	}
+
+	pending := newPendingClarification(loop, results)
+	require.NotNil(t, pending)
+	assert.Equal(t, "First?", pending.Question)
+	assert.Equal(t, 1, pending.answerIndex)
+	assert.True(t, loop.toolResults[2].IsError)
+	assert.False(t, loop.toolResults[0].IsError)
+
+	assert.Nil(t, newPendingClarification(&toolLoop{}, []ToolExecutionResult{{}}))
+}
@@ -12,8 +12,10 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
 )
 
 // ClaudeMessenger is the interface for sending messages to Claude (real or mock).
@@ -39,6 +41,12 @@ type ToolResult struct {
 	IsError   bool   `json:"is_error,omitempty"`
 }
 
+// UsageRecorder records the token usage of each Claude API call.
+// Attribution (project, agent, source) is read from the call's context; see WithUsageScope.
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, modelName string, usage model.TokenUsage)
+}
+
 // ClaudeVision is the interface for image analysis with Claude Vision.
 type ClaudeVision interface {
 	AnalyzeImage(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error)
@@ -50,7 +58,8 @@ const (
 	defaultSystemPrompt = `You are Go Chat. You create files for users.
 
 IMPORTANT: When creating files, ALWAYS use the write_file tool. Do not output code blocks with filenames - use the tool instead.
-When reading existing files, use the read_file tool.
+When changing an existing file, use the edit_file tool instead of rewriting the whole file. Use delete_file and move_file to remove or rename files instead of leaving old copies behind.
+When reading existing files, use the read_file tool. To find your way around a larger project, use list_files, search_files and file_outline instead of guessing paths.
 
 For each file you create with write_file, provide a brief explanation of what the file does.
 
@@ -71,10 +80,14 @@ Be concise. Generate working code.`
 
 // ClaudeConfig holds configuration for the Claude service.
 type ClaudeConfig struct {
-	APIKey    string
-	Model     string
-	MaxTokens int
-	BaseURL   string // Optional, for testing
+	APIKey                string
+	Model                 string
+	MaxTokens             int
+	BaseURL               string        // Optional, for testing
+	ResponseHeaderTimeout time.Duration // How long to wait for response headers (streams may run longer)
+	Retry                 RetryConfig
+	Breaker               BreakerConfig
+	Routes                ModelRoutes // Optional per-purpose and per-agent generation parameters
 }
 
 // ClaudeMessage represents a message in the Claude conversation.
@@ -102,6 +115,9 @@ type claudeRequest struct {
 	Messages  []ClaudeMessage `json:"messages"`
 	Stream    bool            `json:"stream"`
 	Tools     []ClaudeTool    `json:"tools,omitempty"`
+
+	Temperature   *float64 `json:"temperature,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
 }
 
 // claudeRequestWithContent is the request body when messages include content arrays (for tool results).
@@ -112,6 +128,9 @@ type claudeRequestWithContent struct {
 	Messages  []map[string]interface{} `json:"messages"`
 	Stream    bool                     `json:"stream"`
 	Tools     []ClaudeTool             `json:"tools,omitempty"`
+
+	Temperature   *float64 `json:"temperature,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
 }
 
 // ClaudeTool represents a tool definition for the Claude API.
@@ -129,49 +148,14 @@ type ToolUseBlock struct {
 	Input map[string]interface{} `json:"input"`
 }
 
-// getFileTools returns the tool definitions for file operations.
-func getFileTools() []ClaudeTool {
-	return []ClaudeTool{
-		{
-			Name:        "write_file",
-			Description: "Create or overwrite a file in the project. Use this for ALL file creation.",
-			InputSchema: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"path": map[string]interface{}{
-						"type":        "string",
-						"description": "File path relative to project root (e.g., 'src/index.html', 'styles/main.css')",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": "Complete file content to write",
-					},
-				},
-				"required": []string{"path", "content"},
-			},
-		},
-		{
-			Name:        "read_file",
-			Description: "Read the contents of a file in the project",
-			InputSchema: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"path": map[string]interface{}{
-						"type":        "string",
-						"description": "File path relative to project root",
-					},
-				},
-				"required": []string{"path"},
-			},
-		},
-	}
-}
-
 // claudeVisionRequest is the request body for the Claude Vision API.
 type claudeVisionRequest struct {
 	Model     string                `json:"model"`
 	MaxTokens int                   `json:"max_tokens"`
 	Messages  []claudeVisionMessage `json:"messages"`
+
+	Temperature   *float64 `json:"temperature,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
 }
 
 // claudeVisionMessage represents a message with multimodal content.
@@ -203,7 +187,26 @@ type claudeVisionResponse struct {
 		Type string `json:"type"`
 		Text string `json:"text"`
 	} `json:"content"`
-	StopReason string `json:"stop_reason"`
+	StopReason string      `json:"stop_reason"`
+	Usage      claudeUsage `json:"usage"`
+}
+
+// claudeUsage represents the token counts reported by the Claude API.
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toTokenUsage converts API usage to the model type.
+func (u claudeUsage) toTokenUsage() model.TokenUsage {
+	return model.TokenUsage{
+		InputTokens:              u.InputTokens,
+		OutputTokens:             u.OutputTokens,
+		CacheCreationInputTokens: u.CacheCreationInputTokens,
+		CacheReadInputTokens:     u.CacheReadInputTokens,
+	}
 }
 
 // claudeErrorResponse represents an error from the Claude API.
@@ -233,9 +236,15 @@ type claudeStreamEvent struct {
 		StopReason  string `json:"stop_reason,omitempty"`  // For message_delta
 	} `json:"delta,omitempty"`
 	Message *struct {
-		ID   string `json:"id"`
-		Role string `json:"role"`
+		ID    string       `json:"id"`
+		Role  string       `json:"role"`
+		Usage *claudeUsage `json:"usage,omitempty"` // Input and cache tokens (message_start)
 	} `json:"message,omitempty"`
+	Usage *claudeUsage `json:"usage,omitempty"` // Cumulative output tokens (message_delta)
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"` // For error events
 }
 
 // ClaudeStream represents a streaming response from Claude.
@@ -246,6 +255,8 @@ type ClaudeStream struct {
 	resp       *http.Response
 	toolUses   []ToolUseBlock
 	stopReason string
+	usage      model.TokenUsage
+	model      string // Model that produced the stream, for usage records
 	mu         sync.Mutex
 }
 
@@ -273,6 +284,13 @@ func (s *ClaudeStream) StopReason() string {
 	return s.stopReason
 }
 
+// Usage returns the token usage reported so far. It is final once Chunks is closed.
+func (s *ClaudeStream) Usage() model.TokenUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
 // Close closes the stream and releases resources.
 func (s *ClaudeStream) Close() error {
 	if s.resp != nil && s.resp.Body != nil {
@@ -283,9 +301,11 @@ func (s *ClaudeStream) Close() error {
 
 // ClaudeService handles communication with the Claude API.
 type ClaudeService struct {
-	config ClaudeConfig
-	client *http.Client
-	logger zerolog.Logger
+	config        ClaudeConfig
+	client        *http.Client
+	retrier       *retrier
+	usageRecorder UsageRecorder
+	logger        zerolog.Logger
 }
 
 // NewClaudeService creates a new Claude service.
@@ -293,13 +313,62 @@ func NewClaudeService(config ClaudeConfig, logger zerolog.Logger) *ClaudeService
 	if config.BaseURL == "" {
 		config.BaseURL = defaultBaseURL
 	}
+	if config.ResponseHeaderTimeout <= 0 {
+		config.ResponseHeaderTimeout = 60 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+
 	return &ClaudeService{
-		config: config,
-		client: &http.Client{},
-		logger: logger,
+		config:  config,
+		client:  &http.Client{Transport: transport},
+		retrier: newRetrier("Claude API", config.Retry, config.Breaker, logger),
+		logger:  logger,
 	}
 }
 
+// Name returns the provider name.
+func (s *ClaudeService) Name() string {
+	return ProviderAnthropic
+}
+
+// UseCassette routes API calls through a cassette, which records them or replays
+// earlier recordings without touching the network.
+func (s *ClaudeService) UseCassette(cassette *Cassette) {
+	s.client.Transport = cassette.Wrap(s.client.Transport)
+}
+
+// SetUsageRecorder sets the recorder that receives the token usage of every API call.
+// This is optional - if not set, usage is only logged.
+func (s *ClaudeService) SetUsageRecorder(recorder UsageRecorder) {
+	s.usageRecorder = recorder
+}
+
+// recordUsage logs the usage of a call and passes it to the usage recorder.
+// The call's context may already be cancelled, so the recorder gets one that is not.
+func (s *ClaudeService) recordUsage(ctx context.Context, modelName string, usage model.TokenUsage) {
+	s.logger.Debug().
+		Str("model", modelName).
+		Str("route", modelRouteFrom(ctx)).
+		Int("inputTokens", usage.InputTokens).
+		Int("outputTokens", usage.OutputTokens).
+		Int("cacheCreationInputTokens", usage.CacheCreationInputTokens).
+		Int("cacheReadInputTokens", usage.CacheReadInputTokens).
+		Msg("Claude API usage")
+
+	if s.usageRecorder != nil && !usage.IsZero() {
+		s.usageRecorder.RecordUsage(context.WithoutCancel(ctx), modelName, usage)
+	}
+}
+
+// route resolves the generation parameters of a call from the routing table.
+// The returned context carries the matched route key for usage records.
+func (s *ClaudeService) route(ctx context.Context) (context.Context, GenerationParams) {
+	params, key := s.config.Routes.Resolve(ctx, GenerationParams{Model: s.config.Model, MaxTokens: s.config.MaxTokens})
+	return withModelRoute(ctx, key), params
+}
+
 // DefaultSystemPrompt returns the default system prompt for Go Chat.
 func DefaultSystemPrompt() string {
 	return defaultSystemPrompt
@@ -314,14 +383,18 @@ func (s *ClaudeService) SendMessage(ctx context.Context, systemPrompt string, me
 		}
 	}
 
-	// Build request with file tools
+	ctx, params := s.route(ctx)
+
+	// Build request with the tools the caller offers
 	reqBody := claudeRequest{
-		Model:     s.config.Model,
-		MaxTokens: s.config.MaxTokens,
-		System:    systemPrompt,
-		Messages:  messages,
-		Stream:    true,
-		Tools:     getFileTools(),
+		Model:         params.Model,
+		MaxTokens:     params.MaxTokens,
+		System:        systemPrompt,
+		Messages:      messages,
+		Stream:        true,
+		Tools:         toolsFrom(ctx),
+		Temperature:   params.Temperature,
+		StopSequences: params.StopSequences,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -329,36 +402,15 @@ func (s *ClaudeService) SendMessage(ctx context.Context, systemPrompt string, me
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.config.APIKey)
-	req.Header.Set("anthropic-version", anthropicVersion)
-
 	s.logger.Debug().
-		Str("model", s.config.Model).
+		Str("model", params.Model).
 		Int("messageCount", len(messages)).
 		Msg("sending request to Claude API")
 
-	// Send request
-	resp, err := s.client.Do(req)
+	// Send request, retrying transient failures
+	resp, err := s.post(ctx, jsonBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-
-	// Check for error response
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-
-		var errResp claudeErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("Claude API error: %s", errResp.Error.Message)
-		}
-		return nil, fmt.Errorf("Claude API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	// Start streaming
@@ -366,9 +418,10 @@ func (s *ClaudeService) SendMessage(ctx context.Context, systemPrompt string, me
 		chunks: make(chan string, 100),
 		done:   make(chan struct{}),
 		resp:   resp,
+		model:  params.Model,
 	}
 
-	go s.processStream(resp, stream)
+	go s.processStream(ctx, resp, stream)
 
 	return stream, nil
 }
@@ -433,14 +486,18 @@ func (s *ClaudeService) SendMessageWithToolResults(
 		})
 	}
 
-	// Build request with file tools
+	ctx, params := s.route(ctx)
+
+	// Build request with the tools the caller offers
 	reqBody := claudeRequestWithContent{
-		Model:     s.config.Model,
-		MaxTokens: s.config.MaxTokens,
-		System:    systemPrompt,
-		Messages:  msgArray,
-		Stream:    true,
-		Tools:     getFileTools(),
+		Model:         params.Model,
+		MaxTokens:     params.MaxTokens,
+		System:        systemPrompt,
+		Messages:      msgArray,
+		Stream:        true,
+		Tools:         toolsFrom(ctx),
+		Temperature:   params.Temperature,
+		StopSequences: params.StopSequences,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -448,37 +505,16 @@ func (s *ClaudeService) SendMessageWithToolResults(
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.config.APIKey)
-	req.Header.Set("anthropic-version", anthropicVersion)
-
 	s.logger.Debug().
-		Str("model", s.config.Model).
+		Str("model", params.Model).
 		Int("messageCount", len(msgArray)).
 		Int("toolResults", len(toolResults)).
 		Msg("sending request to Claude API with tool results")
 
-	// Send request
-	resp, err := s.client.Do(req)
+	// Send request, retrying transient failures
+	resp, err := s.post(ctx, jsonBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-
-	// Check for error response
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-
-		var errResp claudeErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("Claude API error: %s", errResp.Error.Message)
-		}
-		return nil, fmt.Errorf("Claude API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	// Start streaming
@@ -486,17 +522,65 @@ func (s *ClaudeService) SendMessageWithToolResults(
 		chunks: make(chan string, 100),
 		done:   make(chan struct{}),
 		resp:   resp,
+		model:  params.Model,
 	}
 
-	go s.processStream(resp, stream)
+	go s.processStream(ctx, resp, stream)
 
 	return stream, nil
 }
 
+// post sends a request body to the Claude API and returns the successful response.
+// Transient failures are retried with backoff, honouring retry-after; while the API
+// is down the circuit breaker fails requests fast with ErrCircuitOpen.
+func (s *ClaudeService) post(ctx context.Context, body []byte) (*http.Response, error) {
+	return s.retrier.do(ctx, func() (*http.Response, error) {
+		return s.doRequest(ctx, body)
+	})
+}
+
+// doRequest makes a single attempt. Non-200 responses are returned as *ClaudeAPIError.
+func (s *ClaudeService) doRequest(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", s.config.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	apiErr := &ClaudeAPIError{
+		StatusCode: resp.StatusCode,
+		Message:    string(respBody),
+		RetryAfter: parseRetryAfter(resp.Header.Get("retry-after"), time.Now()),
+	}
+	var errResp claudeErrorResponse
+	if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+	}
+	return nil, apiErr
+}
+
 // processStream reads SSE events from the response and sends text chunks.
-func (s *ClaudeService) processStream(resp *http.Response, stream *ClaudeStream) {
+// The call's token usage is recorded before Chunks is closed.
+func (s *ClaudeService) processStream(ctx context.Context, resp *http.Response, stream *ClaudeStream) {
 	defer close(stream.chunks)
 	defer close(stream.done)
+	defer func() { s.recordUsage(ctx, stream.model, stream.Usage()) }()
 
 	scanner := bufio.NewScanner(resp.Body)
 
@@ -602,6 +686,19 @@ func (s *ClaudeService) processStream(resp *http.Response, stream *ClaudeStream)
 				}
 			}
 
+			// Capture token usage: message_start reports input and cache tokens,
+			// message_delta reports the cumulative output tokens
+			if event.Type == "message_start" && event.Message != nil && event.Message.Usage != nil {
+				stream.mu.Lock()
+				stream.usage = event.Message.Usage.toTokenUsage()
+				stream.mu.Unlock()
+			}
+			if event.Type == "message_delta" && event.Usage != nil {
+				stream.mu.Lock()
+				stream.usage.OutputTokens = event.Usage.OutputTokens
+				stream.mu.Unlock()
+			}
+
 			// Handle message_delta for stop_reason
 			if event.Type == "message_delta" && event.Delta != nil && event.Delta.StopReason != "" {
 				stream.mu.Lock()
@@ -609,9 +706,12 @@ func (s *ClaudeService) processStream(resp *http.Response, stream *ClaudeStream)
 				stream.mu.Unlock()
 			}
 
-			// Log other event types for debugging
+			// An error event ends the stream (e.g. overloaded mid-response)
 			if event.Type == "error" {
 				s.logger.Error().Str("event", currentEvent).Msg("received error event from Claude")
+				if event.Error != nil {
+					stream.err = &ClaudeAPIError{Type: event.Error.Type, Message: event.Error.Message}
+				}
 			}
 		}
 	}
@@ -626,10 +726,14 @@ func (s *ClaudeService) AnalyzeImage(ctx context.Context, imageData []byte, mime
 	// Encode image data to base64
 	base64Data := base64.StdEncoding.EncodeToString(imageData)
 
+	ctx, params := s.route(ctx)
+
 	// Build the multimodal request
 	reqBody := claudeVisionRequest{
-		Model:     s.config.Model,
-		MaxTokens: s.config.MaxTokens,
+		Model:         params.Model,
+		MaxTokens:     params.MaxTokens,
+		Temperature:   params.Temperature,
+		StopSequences: params.StopSequences,
 		Messages: []claudeVisionMessage{
 			{
 				Role: "user",
@@ -656,25 +760,16 @@ func (s *ClaudeService) AnalyzeImage(ctx context.Context, imageData []byte, mime
 		return "", fmt.Errorf("failed to marshal vision request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL, bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", fmt.Errorf("failed to create vision request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.config.APIKey)
-	req.Header.Set("anthropic-version", anthropicVersion)
-
 	s.logger.Debug().
-		Str("model", s.config.Model).
+		Str("model", params.Model).
 		Str("mimeType", mimeType).
 		Int("imageSize", len(imageData)).
 		Msg("sending vision request to Claude API")
 
-	// Send request
-	resp, err := s.client.Do(req)
+	// Send request, retrying transient failures
+	resp, err := s.post(ctx, jsonBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to send vision request: %w", err)
+		return "", fmt.Errorf("vision request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -683,21 +778,14 @@ func (s *ClaudeService) AnalyzeImage(ctx context.Context, imageData []byte, mime
 		return "", fmt.Errorf("failed to read vision response: %w", err)
 	}
 
-	// Check for error response
-	if resp.StatusCode != http.StatusOK {
-		var errResp claudeErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
-			return "", fmt.Errorf("Claude Vision API error: %s", errResp.Error.Message)
-		}
-		return "", fmt.Errorf("Claude Vision API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
 	// Parse the response
 	var visionResp claudeVisionResponse
 	if err := json.Unmarshal(body, &visionResp); err != nil {
 		return "", fmt.Errorf("failed to parse vision response: %w", err)
 	}
 
+	s.recordUsage(ctx, params.Model, visionResp.Usage.toTokenUsage())
+
 	// Extract text from the response
 	var result strings.Builder
 	for _, content := range visionResp.Content {
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Error codes surfaced to clients for failed Claude calls.
+const (
+	ErrorCodeRateLimited    = "RATE_LIMITED"        // Too many requests; retry later
+	ErrorCodeOverloaded     = "UPSTREAM_OVERLOADED" // Claude is temporarily overloaded
+	ErrorCodeUpstreamDown   = "UPSTREAM_DOWN"       // Claude is unreachable or failing; circuit may be open
+	ErrorCodeTimeout        = "UPSTREAM_TIMEOUT"    // The request did not finish in time
+	ErrorCodeInvalidRequest = "INVALID_REQUEST"     // The request was rejected (e.g. too large)
+	ErrorCodeAuth           = "AUTH_ERROR"          // API key is invalid or lacks permission
+	ErrorCodeAI             = "AI_ERROR"            // Any other failure
+)
+
+// ErrCircuitOpen is returned without calling the API while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("Claude API circuit breaker is open")
+
+// ClaudeAPIError is an error response from the LLM API, either an HTTP error
+// status or an error event in the middle of a stream.
+type ClaudeAPIError struct {
+	API        string        // Name of the API for messages; empty means the Claude API
+	StatusCode int           // HTTP status, or 0 for a mid-stream error event
+	Type       string        // Error type, e.g. "rate_limit_error", "overloaded_error"
+	Message    string        // Error message from the API, or the raw body
+	RetryAfter time.Duration // Value of the retry-after header, if any
+}
+
+// Error implements the error interface.
+func (e *ClaudeAPIError) Error() string {
+	api := e.API
+	if api == "" {
+		api = "Claude API"
+	}
+	if e.Type != "" && e.Message != "" {
+		return fmt.Sprintf("%s error: %s", api, e.Message)
+	}
+	return fmt.Sprintf("%s returned status %d: %s", api, e.StatusCode, e.Message)
+}
+
+// Retryable returns true if the same request may succeed later.
+func (e *ClaudeAPIError) Retryable() bool {
+	switch e.Type {
+	case "rate_limit_error", "overloaded_error", "api_error":
+		return true
+	case "invalid_request_error", "authentication_error", "permission_error", "not_found_error", "request_too_large":
+		return false
+	}
+
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	}
+	return false
+}
+
+// code returns the client-facing error code for the API error.
+func (e *ClaudeAPIError) code() string {
+	switch {
+	case e.Type == "rate_limit_error" || e.StatusCode == http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	case e.Type == "overloaded_error" || e.StatusCode == 529:
+		return ErrorCodeOverloaded
+	case e.Type == "authentication_error" || e.Type == "permission_error" ||
+		e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrorCodeAuth
+	case e.Type == "invalid_request_error" || e.Type == "request_too_large" ||
+		e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrorCodeInvalidRequest
+	case e.Retryable():
+		return ErrorCodeUpstreamDown
+	}
+	return ErrorCodeAI
+}
+
+// ClaudeErrorCode classifies an error from a Claude call (possibly wrapped) into a client-facing code.
+func ClaudeErrorCode(err error) string {
+	var apiErr *ClaudeAPIError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &apiErr):
+		return apiErr.code()
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorCodeUpstreamDown
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	}
+	return ErrorCodeAI
+}
+
+// isRetryable returns true if a failed attempt should be retried.
+// Network errors are retried; cancellation and deadline errors are not.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *ClaudeAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	return true
+}
+
+// countsAsOutage returns true if a failure suggests the API is down and should trip the breaker.
+// Rate limiting and rejected requests mean the API is up.
+func countsAsOutage(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *ClaudeAPIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.code()
+		return code == ErrorCodeOverloaded || code == ErrorCodeUpstreamDown
+	}
+	return true
+}
+
+// parseRetryAfter parses a retry-after header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// RetryConfig controls retries of failed Claude requests.
+type RetryConfig struct {
+	MaxRetries int           // Retries after the first attempt (0 disables)
+	BaseDelay  time.Duration // Backoff before the first retry, doubled each attempt
+	MaxDelay   time.Duration // Upper bound for a single backoff
+}
+
+// withDefaults fills unset retry settings.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = 500 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 30 * time.Second
+	}
+	return c
+}
+
+// backoff returns the delay before retry attempt (0-based). A retry-after from the API
+// takes precedence; otherwise it uses exponential backoff with full jitter.
+func (c RetryConfig) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	ceiling := c.BaseDelay << attempt
+	if ceiling <= 0 || ceiling > c.MaxDelay {
+		ceiling = c.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling))) + 1
+}
+
+// BreakerConfig controls the circuit breaker that fails fast while the Claude API is down.
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failed requests that open the circuit
+	Cooldown         time.Duration // How long the circuit stays open before a trial request
+}
+
+// circuitState is the state of a circuit breaker.
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // Requests flow normally
+	circuitOpen                         // Requests fail fast until the cooldown expires
+	circuitHalfOpen                     // One trial request decides whether to close or reopen
+)
+
+// circuitBreaker opens after consecutive outage failures and lets a single trial request
+// through once the cooldown has passed.
+type circuitBreaker struct {
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial request is in flight
+}
+
+// newCircuitBreaker creates a closed circuit breaker.
+func newCircuitBreaker(config BreakerConfig) *circuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	return &circuitBreaker{config: config, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen if a request must not be sent.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.config.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		b.trial = true
+		return nil
+	case circuitHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen
+		}
+		b.trial = true
+		return nil
+	}
+	return nil
+}
+
+// success records a request that reached the API, closing the circuit.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = circuitClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// failure records a request that failed because the API is down.
+// Returns true if this opened the circuit.
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == circuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		opened := b.state != circuitOpen
+		b.state = circuitOpen
+		b.openedAt = b.now()
+		return opened
+	}
+	return false
+}
+
+// release ends a request that neither proved nor disproved an outage (e.g. cancelled or rate limited).
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// retrier sends requests to an LLM API with retries and a circuit breaker.
+type retrier struct {
+	api     string // API name for logs, e.g. "Claude API"
+	config  RetryConfig
+	breaker *circuitBreaker
+	sleep   func(ctx context.Context, d time.Duration) error // Waits between retries; replaced in tests
+	logger  zerolog.Logger
+}
+
+// newRetrier creates a retrier with its own circuit breaker.
+func newRetrier(api string, retry RetryConfig, breaker BreakerConfig, logger zerolog.Logger) *retrier {
+	return &retrier{
+		api:     api,
+		config:  retry.withDefaults(),
+		breaker: newCircuitBreaker(breaker),
+		sleep:   sleepContext,
+		logger:  logger,
+	}
+}
+
+// do makes attempts until one succeeds, the error is not retryable, or retries run out.
+// Delays honour retry-after; while the API is down the breaker fails requests fast with ErrCircuitOpen.
+func (r *retrier) do(ctx context.Context, attempt func() (*http.Response, error)) (*http.Response, error) {
+	var lastErr error
+	for n := 0; ; n++ {
+		if err := r.breaker.allow(); err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		resp, err := attempt()
+		if err == nil {
+			r.breaker.success()
+			return resp, nil
+		}
+		lastErr = err
+
+		if countsAsOutage(ctx, err) {
+			if r.breaker.failure() {
+				r.logger.Error().Err(err).Str("api", r.api).Msg("circuit breaker opened")
+			}
+		} else {
+			r.breaker.release()
+		}
+
+		if n >= r.config.MaxRetries || !isRetryable(ctx, err) {
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		var apiErr *ClaudeAPIError
+		if errors.As(err, &apiErr) {
+			retryAfter = apiErr.RetryAfter
+		}
+		delay := r.config.backoff(n, retryAfter)
+		if delay > r.config.MaxDelay {
+			// The API asked us to wait longer than we are willing to hold the user
+			return nil, err
+		}
+
+		r.logger.Warn().
+			Err(err).
+			Str("api", r.api).
+			Int("attempt", n+1).
+			Dur("delay", delay).
+			Msg("request failed, retrying")
+
+		if err := r.sleep(ctx, delay); err != nil {
+			return nil, lastErr
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
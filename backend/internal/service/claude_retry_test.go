@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyResponse is one scripted HTTP response of a flaky Claude server.
+type flakyResponse struct {
+	status     int
+	body       string
+	retryAfter string
+}
+
+// newFlakyClaudeServer replays scripted error responses, then streams a successful turn.
+// It returns the server and a function reporting how many requests it received.
+func newFlakyClaudeServer(t *testing.T, failures ...flakyResponse) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		idx := requests
+		requests++
+		mu.Unlock()
+
+		if idx < len(failures) {
+			f := failures[idx]
+			if f.retryAfter != "" {
+				w.Header().Set("retry-after", f.retryAfter)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(f.status)
+			w.Write([]byte(f.body))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range textTurnEvents("OK") {
+			w.Write([]byte(event))
+		}
+	}))
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+// newRetryTestClaudeService creates a ClaudeService whose retry sleeps are recorded instead of waited.
+func newRetryTestClaudeService(baseURL string, retry RetryConfig, breaker BreakerConfig) (*ClaudeService, *[]time.Duration) {
+	svc := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   baseURL,
+		Retry:     retry,
+		Breaker:   breaker,
+	}, zerolog.Nop())
+
+	var sleeps []time.Duration
+	svc.retrier.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return svc, &sleeps
+}
+
+var (
+	overloadedResponse  = flakyResponse{status: 529, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`}
+	serverErrorResponse = flakyResponse{status: http.StatusInternalServerError, body: `{"type":"error","error":{"type":"api_error","message":"Internal error"}}`}
+)
+
+func sendHello(svc *ClaudeService) (*ClaudeStream, error) {
+	return svc.SendMessage(context.Background(), "system", []ClaudeMessage{{Role: "user", Content: "Hello"}})
+}
+
+func TestClaudeService_RetriesTransientErrors(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, overloadedResponse, serverErrorResponse)
+	defer server.Close()
+
+	svc, sleeps := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, BreakerConfig{})
+
+	stream, err := sendHello(svc)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var content string
+	for chunk := range stream.Chunks() {
+		content += chunk
+	}
+	assert.Equal(t, "OK", content)
+	assert.Equal(t, 3, requests())
+
+	require.Len(t, *sleeps, 2)
+	assert.LessOrEqual(t, (*sleeps)[0], 100*time.Millisecond, "first backoff is jittered up to the base delay")
+	assert.LessOrEqual(t, (*sleeps)[1], 200*time.Millisecond, "backoff ceiling doubles each attempt")
+}
+
+func TestClaudeService_HonoursRetryAfter(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, flakyResponse{
+		status:     http.StatusTooManyRequests,
+		body:       `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limited"}}`,
+		retryAfter: "2",
+	})
+	defer server.Close()
+
+	svc, sleeps := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 1, MaxDelay: 10 * time.Second}, BreakerConfig{})
+
+	stream, err := sendHello(svc)
+	require.NoError(t, err)
+	stream.Close()
+
+	assert.Equal(t, 2, requests())
+	assert.Equal(t, []time.Duration{2 * time.Second}, *sleeps)
+}
+
+func TestClaudeService_GivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, flakyResponse{
+		status:     http.StatusTooManyRequests,
+		body:       `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limited"}}`,
+		retryAfter: "120",
+	})
+	defer server.Close()
+
+	svc, sleeps := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 1, MaxDelay: 10 * time.Second}, BreakerConfig{})
+
+	_, err := sendHello(svc)
+	require.Error(t, err)
+
+	assert.Equal(t, 1, requests())
+	assert.Empty(t, *sleeps)
+	assert.Equal(t, ErrorCodeRateLimited, ClaudeErrorCode(err))
+}
+
+func TestClaudeService_DoesNotRetryFatalErrors(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, flakyResponse{
+		status: http.StatusBadRequest,
+		body:   `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long"}}`,
+	})
+	defer server.Close()
+
+	svc, sleeps := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 3}, BreakerConfig{})
+
+	_, err := sendHello(svc)
+	require.Error(t, err)
+
+	assert.Equal(t, 1, requests())
+	assert.Empty(t, *sleeps)
+	assert.Contains(t, err.Error(), "prompt is too long")
+	assert.Equal(t, ErrorCodeInvalidRequest, ClaudeErrorCode(err))
+}
+
+func TestClaudeService_ReturnsTypedErrorAfterRetries(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, overloadedResponse, overloadedResponse, overloadedResponse)
+	defer server.Close()
+
+	svc, _ := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 2}, BreakerConfig{FailureThreshold: 10})
+
+	_, err := sendHello(svc)
+	require.Error(t, err)
+
+	assert.Equal(t, 3, requests())
+	var apiErr *ClaudeAPIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 529, apiErr.StatusCode)
+	assert.Equal(t, ErrorCodeOverloaded, ClaudeErrorCode(fmt.Errorf("failed to send message to Claude: %w", err)))
+}
+
+func TestClaudeService_CircuitBreaker(t *testing.T) {
+	server, requests := newFlakyClaudeServer(t, serverErrorResponse, serverErrorResponse)
+	defer server.Close()
+
+	svc, _ := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 0}, BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Now()
+	svc.retrier.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := sendHello(svc)
+		require.Error(t, err)
+	}
+	assert.Equal(t, 2, requests())
+
+	// Open: fails fast without calling the API
+	_, err := sendHello(svc)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, ErrorCodeUpstreamDown, ClaudeErrorCode(err))
+	assert.Equal(t, 2, requests())
+
+	// After the cooldown a trial request goes through and closes the circuit
+	now = now.Add(time.Minute)
+	stream, err := sendHello(svc)
+	require.NoError(t, err)
+	stream.Close()
+	assert.Equal(t, 3, requests())
+
+	stream, err = sendHello(svc)
+	require.NoError(t, err)
+	stream.Close()
+	assert.Equal(t, 4, requests())
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	breaker := newCircuitBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Second})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	require.NoError(t, breaker.allow())
+	assert.True(t, breaker.failure(), "reaching the threshold opens the circuit")
+	assert.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+
+	now = now.Add(time.Second)
+	require.NoError(t, breaker.allow(), "one trial request is allowed after the cooldown")
+	assert.ErrorIs(t, breaker.allow(), ErrCircuitOpen, "only one trial at a time")
+
+	breaker.failure()
+	assert.ErrorIs(t, breaker.allow(), ErrCircuitOpen, "a failed trial reopens the circuit")
+
+	now = now.Add(time.Second)
+	require.NoError(t, breaker.allow())
+	breaker.success()
+	assert.NoError(t, breaker.allow())
+	assert.NoError(t, breaker.allow(), "a successful trial closes the circuit")
+}
+
+func TestClaudeService_StreamErrorEvent(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t, []string{
+		sseEvent("message_start", `{"type":"message_start","message":{"id":"msg","role":"assistant"}}`),
+		sseEvent("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Partial"}}`),
+		sseEvent("error", `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`),
+	})
+	defer server.Close()
+
+	svc, _ := newRetryTestClaudeService(server.URL, RetryConfig{MaxRetries: 3}, BreakerConfig{})
+
+	stream, err := sendHello(svc)
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	require.Error(t, stream.Err())
+	assert.Equal(t, ErrorCodeOverloaded, ClaudeErrorCode(stream.Err()))
+}
+
+func TestClaudeErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"rate limit", &ClaudeAPIError{StatusCode: 429, Type: "rate_limit_error"}, ErrorCodeRateLimited},
+		{"overloaded", &ClaudeAPIError{StatusCode: 529, Type: "overloaded_error"}, ErrorCodeOverloaded},
+		{"server error", &ClaudeAPIError{StatusCode: 502, Message: "bad gateway"}, ErrorCodeUpstreamDown},
+		{"auth", &ClaudeAPIError{StatusCode: 401, Type: "authentication_error"}, ErrorCodeAuth},
+		{"too large", &ClaudeAPIError{StatusCode: 413, Type: "request_too_large"}, ErrorCodeInvalidRequest},
+		{"circuit open", fmt.Errorf("wrapped: %w", ErrCircuitOpen), ErrorCodeUpstreamDown},
+		{"timeout", fmt.Errorf("stream error: %w", context.DeadlineExceeded), ErrorCodeTimeout},
+		{"other", errors.New("boom"), ErrorCodeAI},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, ClaudeErrorCode(tt.err))
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
@@ -54,7 +54,7 @@ func TestClaudeService_ToolDefinitions(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	stream, err := service.SendMessage(ctx, "You are a helpful assistant", messages)
+	stream, err := service.SendMessage(WithTools(ctx, fileToolDefinitions()), "You are a helpful assistant", messages)
 	if err != nil {
 		t.Fatalf("SendMessage failed: %v", err)
 	}
@@ -107,7 +107,7 @@ func TestClaudeService_WriteFileToolSchema(t *testing.T) {
 		BaseURL:   server.URL,
 	}, logger)
 
-	ctx := context.Background()
+	ctx := WithTools(context.Background(), fileToolDefinitions())
 	stream, _ := service.SendMessage(ctx, "test", []ClaudeMessage{{Role: "user", Content: "test"}})
 	defer stream.Close()
 	for range stream.Chunks() {
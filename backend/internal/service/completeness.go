@@ -2,113 +2,106 @@ package service
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/refs"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/render"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/syntax"
 	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
 )
 
+// maxRenderPages limits how many HTML pages of a project the render check loads.
+const maxRenderPages = 10
+
+// RenderChecker loads an HTML page with a project's files (path to content) and reports
+// what goes wrong, such as uncaught exceptions. See render.Runner.
+type RenderChecker interface {
+	Check(ctx context.Context, pagePath string, files map[string]string) ([]render.Problem, error)
+}
+
 // CompletenessChecker validates that all file references in a project resolve correctly.
 type CompletenessChecker struct {
-	fileRepo repository.FileRepository
-	logger   zerolog.Logger
+	fileRepo   repository.FileRepository
+	extractors *refs.Registry
+	renderer   RenderChecker
+	logger     zerolog.Logger
+
+	lintSeverities LintSeverities
 }
 
 // NewCompletenessChecker creates a new completeness checker.
 func NewCompletenessChecker(fileRepo repository.FileRepository, logger zerolog.Logger) *CompletenessChecker {
 	return &CompletenessChecker{
-		fileRepo: fileRepo,
-		logger:   logger.With().Str("component", "completeness_checker").Logger(),
+		fileRepo:   fileRepo,
+		extractors: refs.DefaultRegistry(),
+		logger:     logger.With().Str("component", "completeness_checker").Logger(),
 	}
 }
 
-// Regex patterns for extracting file references
-var (
-	// HTML patterns
-	htmlScriptPattern = regexp.MustCompile(`<script[^>]+src=["']([^"']+)["']`)
-	htmlLinkPattern   = regexp.MustCompile(`<link[^>]+href=["']([^"']+\.css)["']`)
-	htmlImgPattern    = regexp.MustCompile(`<img[^>]+src=["']([^"']+)["']`)
+// Extractors returns the registry of reference extractors, keyed by file extension or name.
+// Support for another language is added by registering its extractor here.
+func (c *CompletenessChecker) Extractors() *refs.Registry {
+	return c.extractors
+}
 
-	// JavaScript/TypeScript patterns
-	jsImportPattern  = regexp.MustCompile(`import\s+(?:.*\s+from\s+)?["']([^"']+)["']`)
-	jsRequirePattern = regexp.MustCompile(`require\s*\(\s*["']([^"']+)["']\s*\)`)
+// SetRenderChecker enables the render check, which loads each HTML page and reports its
+// runtime errors. This is optional - if not set, only file references are checked.
+func (c *CompletenessChecker) SetRenderChecker(renderer RenderChecker) {
+	c.renderer = renderer
+}
 
-	// CSS patterns
-	cssImportPattern = regexp.MustCompile(`@import\s+(?:url\s*\(\s*)?["']?([^"')]+)["']?\s*\)?`)
-	cssUrlPattern    = regexp.MustCompile(`url\s*\(\s*["']?([^"')]+)["']?\s*\)`)
-)
+// SetLintSeverities overrides the severity of accessibility and SEO lint rules, or turns
+// them off. This is optional - if not set, every rule reports at its default severity.
+func (c *CompletenessChecker) SetLintSeverities(severities LintSeverities) {
+	c.lintSeverities = severities
+}
 
 // Check validates all files in a project and returns a completeness report.
 func (c *CompletenessChecker) Check(ctx context.Context, projectID uuid.UUID) (*model.CompletenessReport, error) {
 	c.logger.Debug().Str("projectId", projectID.String()).Msg("starting completeness check")
 
 	// Get all files in project
-	files, err := c.fileRepo.GetByProject(ctx, projectID)
+	files, err := c.fileRepo.GetFilesWithContentByProject(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build a set of existing file paths for quick lookup
-	existingFiles := make(map[string]bool)
-	for _, f := range files {
-		// Store both with and without leading slash for flexible matching
-		existingFiles[f.Path] = true
-		existingFiles[strings.TrimPrefix(f.Path, "/")] = true
-		existingFiles[f.Filename] = true
-	}
-
 	var issues []model.CompletenessIssue
-	issueID := 0
-
-	// Check each file for references
-	for _, file := range files {
-		// Get file content
-		fileWithContent, err := c.fileRepo.GetByID(ctx, file.ID)
-		if err != nil {
-			c.logger.Warn().Err(err).Str("fileId", file.ID.String()).Msg("failed to get file content")
-			continue
-		}
+	missing := make(map[string]bool) // Referencing path + "\x00" + resolved path of each missing file
+
+	for _, m := range c.findMissingReferences(files) {
+		severity := c.getSeverity(m.ref, m.file.Filename)
+		missing[m.file.Path+"\x00"+m.resolved] = true
+
+		issues = append(issues, model.CompletenessIssue{
+			ID:            m.issueID(),
+			Severity:      severity,
+			Type:          "missing_file",
+			MissingFile:   m.ref.Path,
+			ReferencedBy:  m.file.Filename,
+			ReferenceType: m.ref.Type,
+			LineNumber:    m.ref.Line,
+			Context:       m.ref.Context,
+			AutoFixable:   m.autoFixable(severity),
+		})
+	}
 
-		content := fileWithContent.Content
-		if content == "" {
-			continue
-		}
+	issues = append(issues, syntaxIssues(files)...)
 
-		// Extract and validate references based on file type
-		refs := c.extractReferences(file.Filename, content)
+	lint, lintScore := c.lintIssues(files)
+	issues = append(issues, lint...)
 
-		for _, ref := range refs {
-			// Skip external URLs
-			if isExternalURL(ref.Path) {
-				continue
-			}
-
-			// Resolve relative path
-			resolvedPath := c.resolvePath(file.Path, ref.Path)
-
-			// Check if file exists
-			if !c.fileExists(existingFiles, resolvedPath, ref.Path) {
-				issueID++
-				severity := c.getSeverity(ref.Path, file.Filename, ref.Type)
-
-				issues = append(issues, model.CompletenessIssue{
-					ID:            generateIssueID(issueID),
-					Severity:      severity,
-					Type:          "missing_file",
-					MissingFile:   ref.Path,
-					ReferencedBy:  file.Filename,
-					ReferenceType: ref.Type,
-					LineNumber:    ref.LineNumber,
-					Context:       ref.Context,
-					AutoFixable:   severity == model.SeverityCritical,
-				})
-			}
-		}
+	if c.renderer != nil {
+		issues = append(issues, c.renderIssues(ctx, projectID, files, missing)...)
 	}
 
 	// Determine overall status
@@ -132,6 +125,7 @@ func (c *CompletenessChecker) Check(ctx context.Context, projectID uuid.UUID) (*
 		Issues:       issues,
 		FilesChecked: len(files),
 		AutoFixable:  autoFixable,
+		LintScore:    lintScore,
 	}
 
 	c.logger.Info().
@@ -144,206 +138,153 @@ func (c *CompletenessChecker) Check(ctx context.Context, projectID uuid.UUID) (*
 	return report, nil
 }
 
-// FileReference represents a reference found in a file.
-type FileReference struct {
-	Path       string
-	Type       string // "script", "stylesheet", "import", "image"
-	LineNumber int
-	Context    string
+// missingReference is a file reference that doesn't resolve to a project file.
+type missingReference struct {
+	file     *model.File
+	ref      refs.Reference
+	resolved string // Project path the reference points to, its first target
 }
 
-// extractReferences extracts file references from content based on file type.
-func (c *CompletenessChecker) extractReferences(filename, content string) []FileReference {
-	ext := strings.ToLower(filepath.Ext(filename))
-	var refs []FileReference
-
-	switch ext {
-	case ".html", ".htm":
-		refs = append(refs, c.extractHTMLReferences(content)...)
-	case ".js", ".jsx", ".ts", ".tsx", ".mjs":
-		refs = append(refs, c.extractJSReferences(content)...)
-	case ".css", ".scss", ".sass", ".less":
-		refs = append(refs, c.extractCSSReferences(content)...)
-	}
-
-	return refs
+// issueID returns the ID of the missing_file issue reporting the reference. IDs are
+// stable across checks, so a fix request can name issues of an earlier report.
+func (m missingReference) issueID() string {
+	return generateIssueID("missing_file", m.file.Path, m.resolved, strconv.Itoa(m.ref.Line))
 }
 
-// extractHTMLReferences extracts script, stylesheet, and image references from HTML.
-func (c *CompletenessChecker) extractHTMLReferences(content string) []FileReference {
-	var refs []FileReference
-	lines := strings.Split(content, "\n")
-
-	for lineNum, line := range lines {
-		// Scripts
-		for _, match := range htmlScriptPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				refs = append(refs, FileReference{
-					Path:       match[1],
-					Type:       "script",
-					LineNumber: lineNum + 1,
-					Context:    strings.TrimSpace(line),
-				})
-			}
-		}
+// autoFixable reports whether the developer agent can create the missing file: the
+// reference is critical and names a file rather than any file of a directory.
+func (m missingReference) autoFixable(severity model.Severity) bool {
+	return severity == model.SeverityCritical && !strings.HasSuffix(m.resolved, "/")
+}
 
-		// Stylesheets
-		for _, match := range htmlLinkPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				refs = append(refs, FileReference{
-					Path:       match[1],
-					Type:       "stylesheet",
-					LineNumber: lineNum + 1,
-					Context:    strings.TrimSpace(line),
-				})
-			}
-		}
+// fileReference is a reference made by a project file.
+type fileReference struct {
+	file *model.File
+	ref  refs.Reference
+}
 
-		// Images (warning level only)
-		for _, match := range htmlImgPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				refs = append(refs, FileReference{
-					Path:       match[1],
-					Type:       "image",
-					LineNumber: lineNum + 1,
-					Context:    strings.TrimSpace(line),
-				})
-			}
-		}
+// references returns the references made by files, with the project they resolve against.
+func (c *CompletenessChecker) references(files []model.File) (*refs.Project, []fileReference) {
+	contents := make(map[string]string, len(files))
+	for _, f := range files {
+		contents[f.Path] = f.Content
 	}
+	project := refs.NewProject(contents)
 
-	return refs
-}
-
-// extractJSReferences extracts import and require references from JavaScript.
-func (c *CompletenessChecker) extractJSReferences(content string) []FileReference {
-	var refs []FileReference
-	lines := strings.Split(content, "\n")
-
-	for lineNum, line := range lines {
-		// ES6 imports
-		for _, match := range jsImportPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				path := match[1]
-				// Only check relative imports
-				if strings.HasPrefix(path, ".") {
-					refs = append(refs, FileReference{
-						Path:       path,
-						Type:       "import",
-						LineNumber: lineNum + 1,
-						Context:    strings.TrimSpace(line),
-					})
-				}
-			}
+	var references []fileReference
+	for i := range files {
+		file := &files[i]
+		if file.Content == "" {
+			continue
 		}
-
-		// CommonJS require
-		for _, match := range jsRequirePattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				path := match[1]
-				// Only check relative requires
-				if strings.HasPrefix(path, ".") {
-					refs = append(refs, FileReference{
-						Path:       path,
-						Type:       "import",
-						LineNumber: lineNum + 1,
-						Context:    strings.TrimSpace(line),
-					})
-				}
-			}
+		for _, ref := range c.extractors.Extract(file.Path, file.Content, project) {
+			references = append(references, fileReference{file: file, ref: ref})
 		}
 	}
-
-	return refs
+	return project, references
 }
 
-// extractCSSReferences extracts @import and url() references from CSS.
-func (c *CompletenessChecker) extractCSSReferences(content string) []FileReference {
-	var refs []FileReference
-	lines := strings.Split(content, "\n")
-
-	for lineNum, line := range lines {
-		// @import
-		for _, match := range cssImportPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 && !isExternalURL(match[1]) {
-				refs = append(refs, FileReference{
-					Path:       match[1],
-					Type:       "stylesheet",
-					LineNumber: lineNum + 1,
-					Context:    strings.TrimSpace(line),
-				})
-			}
-		}
+// findMissingReferences returns the references of files that don't resolve to any of them.
+func (c *CompletenessChecker) findMissingReferences(files []model.File) []missingReference {
+	// References may also name a file as written, e.g. by its file name alone
+	existingFiles := make(map[string]bool)
+	for _, f := range files {
+		existingFiles[f.Path] = true
+		existingFiles[strings.TrimPrefix(f.Path, "/")] = true
+		existingFiles[f.Filename] = true
+	}
 
-		// url() - but not for data: URIs or external URLs
-		for _, match := range cssUrlPattern.FindAllStringSubmatch(line, -1) {
-			if len(match) >= 2 {
-				path := match[1]
-				if !isExternalURL(path) && !strings.HasPrefix(path, "data:") {
-					refs = append(refs, FileReference{
-						Path:       path,
-						Type:       "image",
-						LineNumber: lineNum + 1,
-						Context:    strings.TrimSpace(line),
-					})
-				}
-			}
+	var missing []missingReference
+	project, references := c.references(files)
+	for _, r := range references {
+		if project.Resolves(r.ref) || existingFiles[r.ref.Path] {
+			continue
 		}
+		missing = append(missing, missingReference{file: r.file, ref: r.ref, resolved: r.ref.Targets[0]})
 	}
 
-	return refs
+	return missing
 }
 
-// resolvePath resolves a relative path against a base file path.
-func (c *CompletenessChecker) resolvePath(basePath, refPath string) string {
-	if filepath.IsAbs(refPath) || strings.HasPrefix(refPath, "/") {
-		return strings.TrimPrefix(refPath, "/")
+// syntaxIssues returns the syntax errors of files as critical syntax_error issues.
+func syntaxIssues(files []model.File) []model.CompletenessIssue {
+	var issues []model.CompletenessIssue
+	for _, f := range files {
+		for _, e := range syntax.Check(f.Path, f.Content) {
+			issues = append(issues, model.CompletenessIssue{
+				ID:            generateIssueID("syntax_error", f.Path, strconv.Itoa(e.Line), strconv.Itoa(e.Column), e.Message),
+				Severity:      model.SeverityCritical,
+				Type:          "syntax_error",
+				ReferencedBy:  f.Path,
+				ReferenceType: syntax.Language(f.Path),
+				LineNumber:    e.Line,
+				Column:        e.Column,
+				Context:       e.Message,
+			})
+		}
 	}
-
-	baseDir := filepath.Dir(basePath)
-	resolved := filepath.Join(baseDir, refPath)
-	return filepath.Clean(resolved)
+	return issues
 }
 
-// fileExists checks if a file exists in the project.
-func (c *CompletenessChecker) fileExists(existingFiles map[string]bool, resolvedPath, originalPath string) bool {
-	// Try resolved path
-	if existingFiles[resolvedPath] {
-		return true
+// renderIssues loads each HTML page of a project and returns its runtime problems as
+// runtime_error issues. Assets already reported as missing files are skipped.
+func (c *CompletenessChecker) renderIssues(ctx context.Context, projectID uuid.UUID, files []model.File, missing map[string]bool) []model.CompletenessIssue {
+	contents := make(map[string]string, len(files))
+	var pages []string
+	for _, f := range files {
+		contents[f.Path] = f.Content
+		ext := strings.ToLower(filepath.Ext(f.Path))
+		if (ext == ".html" || ext == ".htm") && len(pages) < maxRenderPages {
+			pages = append(pages, f.Path)
+		}
 	}
 
-	// Try original path
-	if existingFiles[originalPath] {
-		return true
-	}
+	var issues []model.CompletenessIssue
+	for _, page := range pages {
+		problems, err := c.renderer.Check(ctx, page, contents)
+		if err != nil {
+			c.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("page", page).
+				Msg("failed to render check page")
+			continue
+		}
 
-	// Try with common extensions for JS imports without extension
-	if !strings.Contains(originalPath, ".") {
-		extensions := []string{".js", ".jsx", ".ts", ".tsx", ".mjs"}
-		for _, ext := range extensions {
-			if existingFiles[resolvedPath+ext] || existingFiles[originalPath+ext] {
-				return true
+		for _, problem := range problems {
+			if problem.Kind == render.ProblemAssetLoad && missing[problem.File+"\x00"+problem.Asset] {
+				continue
 			}
-		}
-		// Try index files
-		for _, ext := range extensions {
-			indexPath := filepath.Join(resolvedPath, "index"+ext)
-			if existingFiles[indexPath] {
-				return true
+			severity := model.SeverityWarning
+			switch problem.Kind {
+			case render.ProblemException:
+				severity = model.SeverityCritical
+			case render.ProblemUnchecked:
+				severity = model.SeverityInfo
 			}
+			issues = append(issues, model.CompletenessIssue{
+				ID:            generateIssueID("runtime_error", problem.File, problem.Kind, strconv.Itoa(problem.Line), problem.Message),
+				Severity:      severity,
+				Type:          "runtime_error",
+				MissingFile:   problem.Asset,
+				ReferencedBy:  problem.File,
+				ReferenceType: problem.Kind,
+				LineNumber:    problem.Line,
+				Context:       problem.Message,
+			})
 		}
 	}
-
-	return false
+	return issues
 }
 
-// getSeverity determines the severity of a missing file.
-func (c *CompletenessChecker) getSeverity(missingFile, referencedBy, refType string) model.Severity {
-	ext := strings.ToLower(filepath.Ext(missingFile))
+// getSeverity determines the severity of a missing file from the path the reference
+// points to and the file making it.
+func (c *CompletenessChecker) getSeverity(ref refs.Reference, referencedBy string) model.Severity {
+	ext := strings.ToLower(path.Ext(ref.Targets[0]))
 	refExt := strings.ToLower(filepath.Ext(referencedBy))
 
 	// Critical: Missing JS/TS referenced by HTML (app won't work)
-	if (ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx") && (refExt == ".html" || refExt == ".htm") {
+	if (ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" || ext == ".mjs") && (refExt == ".html" || refExt == ".htm") {
 		return model.SeverityCritical
 	}
 
@@ -352,33 +293,32 @@ func (c *CompletenessChecker) getSeverity(missingFile, referencedBy, refType str
 		return model.SeverityCritical
 	}
 
-	// Critical: Missing JS module imports (app won't work)
-	if refType == "import" && (ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" || ext == "") {
+	// Critical: Missing module imports (app won't work); Go packages have no extension
+	if ref.Type == "import" && (ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" || ext == ".mjs" || ext == ".cjs" || ext == ".py" || ext == "") {
 		return model.SeverityCritical
 	}
 
-	// Warning: Missing images
-	if refType == "image" {
+	// Critical: Missing package entry points (package won't load)
+	if ref.Type == "entry" {
+		return model.SeverityCritical
+	}
+
+	// Warning: Missing images, fonts, media and manifests
+	if ref.Type == "image" || ref.Type == "font" || ref.Type == "media" || ref.Type == "manifest" {
 		return model.SeverityWarning
 	}
 
 	// Warning: Missing CSS imports
-	if refType == "stylesheet" && ext == ".css" {
+	if ref.Type == "stylesheet" && ext == ".css" {
 		return model.SeverityWarning
 	}
 
+	// Info: Broken links between pages and documents, and everything else
 	return model.SeverityInfo
 }
 
-// isExternalURL checks if a path is an external URL.
-func isExternalURL(path string) bool {
-	return strings.HasPrefix(path, "http://") ||
-		strings.HasPrefix(path, "https://") ||
-		strings.HasPrefix(path, "//") ||
-		strings.HasPrefix(path, "data:")
-}
-
-// generateIssueID generates a unique issue ID.
-func generateIssueID(num int) string {
-	return "issue-" + uuid.New().String()[:8]
+// generateIssueID derives an issue ID from the fields that identify the issue.
+func generateIssueID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return "issue-" + hex.EncodeToString(sum[:4])
 }
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/markdown"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// completenessFixInstructions is appended to the developer prompt when generating a missing file.
+const completenessFixInstructions = `
+
+## Completeness Fix
+A file of this project references a file that doesn't exist. Write the missing file so the
+referencing file works as intended. Respond with exactly one code block for the missing file,
+using its full path (e.g. ` + "```javascript:js/app.js" + `), and nothing else.`
+
+// CompletenessFixer generates files that completeness issues report as missing.
+type CompletenessFixer struct {
+	checker      *CompletenessChecker
+	fileRepo     repository.FileRepository
+	claude       ClaudeMessenger
+	agentContext *AgentContextService
+	fileHistory  *FileHistoryService
+	logger       zerolog.Logger
+}
+
+// NewCompletenessFixer creates a new CompletenessFixer.
+func NewCompletenessFixer(
+	checker *CompletenessChecker,
+	fileRepo repository.FileRepository,
+	claude ClaudeMessenger,
+	agentContext *AgentContextService,
+	logger zerolog.Logger,
+) *CompletenessFixer {
+	return &CompletenessFixer{
+		checker:      checker,
+		fileRepo:     fileRepo,
+		claude:       claude,
+		agentContext: agentContext,
+		logger:       logger.With().Str("component", "completeness_fixer").Logger(),
+	}
+}
+
+// SetFileHistory sets the file history service used to version generated files.
+// This is optional - if not set, generated files are saved without a version.
+func (f *CompletenessFixer) SetFileHistory(fileHistory *FileHistoryService) {
+	f.fileHistory = fileHistory
+}
+
+// Fix generates the missing files of the given issues, or of all auto-fixable issues if
+// issueIDs is empty, and returns which issues were fixed along with a new report.
+// Requested issues that no longer exist or can't be fixed automatically are reported as failed.
+func (f *CompletenessFixer) Fix(ctx context.Context, projectID uuid.UUID, issueIDs []string) (*model.CompletenessFixResponse, error) {
+	files, err := f.fileRepo.GetFilesWithContentByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the auto-fixable issues; their IDs are stable, so they match an earlier report
+	var fixable []missingReference
+	fixableByID := make(map[string]missingReference)
+	for _, m := range f.checker.findMissingReferences(files) {
+		if m.autoFixable(f.checker.getSeverity(m.ref, m.file.Filename)) {
+			fixable = append(fixable, m)
+			fixableByID[m.issueID()] = m
+		}
+	}
+
+	response := &model.CompletenessFixResponse{
+		Fixed:  []string{},
+		Failed: []string{},
+		Files:  []string{},
+	}
+
+	// Group the issues to fix by the path of the file they miss, so a file
+	// referenced from several places is generated once
+	var paths []string
+	issuesByPath := make(map[string][]missingReference)
+	addIssue := func(m missingReference) {
+		if _, ok := issuesByPath[m.resolved]; !ok {
+			paths = append(paths, m.resolved)
+		}
+		issuesByPath[m.resolved] = append(issuesByPath[m.resolved], m)
+	}
+
+	if len(issueIDs) == 0 {
+		for _, m := range fixable {
+			addIssue(m)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, id := range issueIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if m, ok := fixableByID[id]; ok {
+				addIssue(m)
+			} else {
+				response.Failed = append(response.Failed, id)
+			}
+		}
+	}
+
+	agentType := string(model.AgentDeveloper)
+	ctx = WithUsageScope(ctx, projectID, &agentType, model.UsageSourceFix)
+
+	for _, path := range paths {
+		refs := issuesByPath[path]
+		var ids []string
+		for _, m := range refs {
+			ids = append(ids, m.issueID())
+		}
+
+		file, err := f.generate(ctx, projectID, path, refs[0], files, &agentType)
+		if err != nil {
+			f.logger.Warn().
+				Err(err).
+				Str("projectId", projectID.String()).
+				Str("path", path).
+				Msg("failed to generate missing file")
+			response.Failed = append(response.Failed, ids...)
+			continue
+		}
+
+		response.Fixed = append(response.Fixed, ids...)
+		response.Files = append(response.Files, file.Path)
+	}
+
+	report, err := f.checker.Check(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	response.NewReport = report
+
+	f.logger.Info().
+		Str("projectId", projectID.String()).
+		Int("fixed", len(response.Fixed)).
+		Int("failed", len(response.Failed)).
+		Msg("completeness fix completed")
+
+	return response, nil
+}
+
+// generate asks the developer agent for the missing file at path, using the file that
+// references it as context, and saves it.
+func (f *CompletenessFixer) generate(ctx context.Context, projectID uuid.UUID, path string, m missingReference, files []model.File, agentType *string) (*model.File, error) {
+	existing := make([]string, 0, len(files))
+	for _, file := range files {
+		existing = append(existing, file.Path)
+	}
+
+	request := fmt.Sprintf(
+		"%s references %s on line %d:\n\n%s\n\nbut %s doesn't exist. Create %s.\n\nProject files that exist: %s\n\nContents of %s:\n\n```\n%s\n```",
+		m.file.Path, m.ref.Path, m.ref.Line, m.ref.Context, path, path, strings.Join(existing, ", "), m.file.Path, m.file.Content,
+	)
+
+	agentContext, err := f.agentContext.GetContextForMessage(ctx, projectID, request)
+	if err != nil {
+		return nil, err
+	}
+	agentContext.Agent = model.AgentDeveloper
+
+	systemPrompt, err := f.agentContext.GetSystemPrompt(ctx, agentContext)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := f.claude.SendMessage(ctx, systemPrompt+completenessFixInstructions, []ClaudeMessage{
+		{Role: "user", Content: request},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send fix request to Claude: %w", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for chunk := range stream.Chunks() {
+		content.WriteString(chunk)
+	}
+
+	if stream.Err() != nil {
+		return nil, fmt.Errorf("stream error: %w", stream.Err())
+	}
+
+	block, ok := fixBlock(markdown.ExtractCodeBlocksWithMetadata(content.String()), path)
+	if !ok {
+		return nil, errors.New("response has no code block for the missing file")
+	}
+
+	language := block.Language
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	file, err := f.fileRepo.SaveFile(ctx, projectID, path, language, block.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+
+	if f.fileHistory != nil {
+		if _, err := f.fileHistory.RecordWrite(ctx, file, model.FileVersionSourceFix, agentType); err != nil {
+			f.logger.Warn().
+				Err(err).
+				Str("path", path).
+				Msg("failed to record file version")
+		}
+	}
+
+	return file, nil
+}
+
+// fixBlock picks the code block holding the file at path: the block named after it,
+// or the only block of the response if it has no name.
+func fixBlock(blocks []markdown.CodeBlockWithMetadata, path string) (markdown.CodeBlockWithMetadata, bool) {
+	for _, block := range blocks {
+		if strings.TrimPrefix(block.Filename, "/") == path {
+			return block, true
+		}
+	}
+	if len(blocks) == 1 && blocks[0].Filename == "" && strings.TrimSpace(blocks[0].Code) != "" {
+		return blocks[0], true
+	}
+	return markdown.CodeBlockWithMetadata{}, false
+}
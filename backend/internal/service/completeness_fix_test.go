@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// fakeFixMessenger answers a fix request with the response for the file it asks to create.
+type fakeFixMessenger struct {
+	responses map[string]string // Missing path to response
+	requests  []string
+	prompts   []string
+}
+
+func (m *fakeFixMessenger) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	request := messages[len(messages)-1].Content
+	m.requests = append(m.requests, request)
+	m.prompts = append(m.prompts, systemPrompt)
+
+	response := ""
+	for path, r := range m.responses {
+		if strings.Contains(request, "Create "+path+".") {
+			response = r
+		}
+	}
+	return (&MockClaudeMessengerForPRD{Response: response}).SendMessage(ctx, systemPrompt, messages)
+}
+
+func (m *fakeFixMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	return m.SendMessage(ctx, systemPrompt, messages)
+}
+
+func newTestCompletenessFixer(t *testing.T, responses map[string]string) (*CompletenessFixer, *repository.MockFileRepository, *fakeFixMessenger, uuid.UUID) {
+	t.Helper()
+	fileRepo := repository.NewMockFileRepository()
+	projectRepo := repository.NewMockProjectRepository()
+	project, err := projectRepo.Create(context.Background(), "Test Project")
+	require.NoError(t, err)
+
+	agentContext := NewAgentContextService(repository.NewMockPRDRepository(), projectRepo, repository.NewMockDiscoveryRepository(), zerolog.Nop())
+	claude := &fakeFixMessenger{responses: responses}
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	return NewCompletenessFixer(checker, fileRepo, claude, agentContext, zerolog.Nop()), fileRepo, claude, project.ID
+}
+
+func TestCompletenessChecker_StableIssueIDs(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<script src="app.js"></script>`+"\n"+`<script src="util.js"></script>`)
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	first, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	second, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+
+	require.Len(t, first.Issues, 2)
+	assert.NotEqual(t, first.Issues[0].ID, first.Issues[1].ID)
+	assert.Equal(t, first.Issues[0].ID, second.Issues[0].ID)
+	assert.Equal(t, first.Issues[1].ID, second.Issues[1].ID)
+}
+
+func TestCompletenessFixer_FixAll(t *testing.T) {
+	ctx := context.Background()
+	fixer, fileRepo, claude, projectID := newTestCompletenessFixer(t, map[string]string{
+		"js/app.js": "Here it is:\n\n```javascript:js/app.js\n---\nshort_description: App logic\n---\nconsole.log('hi');\n```",
+	})
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<script src="js/app.js"></script>`+"\n"+`<img src="logo.png" alt="Logo">`)
+	_, _ = fileRepo.SaveFile(ctx, projectID, "about.html", "html", `<script src="js/app.js"></script>`)
+
+	before, err := fixer.checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	require.Len(t, before.Issues, 3)
+
+	response, err := fixer.Fix(ctx, projectID, nil)
+	require.NoError(t, err)
+
+	// Both references to js/app.js are fixed by generating it once; the image isn't auto-fixable
+	assert.Len(t, claude.requests, 1)
+	assert.Contains(t, claude.prompts[0], "Completeness Fix")
+	var scriptIssues []string
+	for _, issue := range before.Issues {
+		if issue.MissingFile == "js/app.js" {
+			scriptIssues = append(scriptIssues, issue.ID)
+		}
+	}
+	assert.ElementsMatch(t, scriptIssues, response.Fixed)
+	assert.Empty(t, response.Failed)
+	assert.Equal(t, []string{"js/app.js"}, response.Files)
+
+	file, err := fileRepo.GetFileByPath(ctx, projectID, "js/app.js")
+	require.NoError(t, err)
+	assert.Equal(t, "console.log('hi');", file.Content)
+
+	require.NotNil(t, response.NewReport)
+	require.Len(t, response.NewReport.Issues, 1)
+	assert.Equal(t, "logo.png", response.NewReport.Issues[0].MissingFile)
+}
+
+func TestCompletenessFixer_FixSelectedIssues(t *testing.T) {
+	ctx := context.Background()
+	fixer, fileRepo, claude, projectID := newTestCompletenessFixer(t, map[string]string{
+		"app.js":    "```javascript:app.js\ninit();\n```",
+		"style.css": "Sorry, I can't help with that.",
+	})
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html",
+		"<link rel=\"stylesheet\" href=\"style.css\">\n<script src=\"app.js\"></script>\n<script src=\"extra.js\"></script>\n<img src=\"logo.png\" alt=\"Logo\">")
+
+	report, err := fixer.checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 4)
+	styleID, appID, imageID := report.Issues[0].ID, report.Issues[1].ID, report.Issues[3].ID
+
+	response, err := fixer.Fix(ctx, projectID, []string{styleID, appID, imageID, "issue-unknown"})
+	require.NoError(t, err)
+
+	// extra.js wasn't requested, the image isn't auto-fixable and the stylesheet response had no code
+	assert.Len(t, claude.requests, 2)
+	assert.Equal(t, []string{appID}, response.Fixed)
+	assert.Equal(t, []string{imageID, "issue-unknown", styleID}, response.Failed)
+	assert.Equal(t, []string{"app.js"}, response.Files)
+	assert.Len(t, response.NewReport.Issues, 3)
+
+	_, err = fileRepo.GetFileByPath(ctx, projectID, "style.css")
+	assert.Error(t, err)
+}
+
+func TestCompletenessFixer_RecordsFileHistory(t *testing.T) {
+	ctx := context.Background()
+	fixer, fileRepo, _, projectID := newTestCompletenessFixer(t, map[string]string{
+		"app.js": "```javascript\ninit();\n```",
+	})
+	versionRepo := repository.NewMockFileVersionRepository()
+	fixer.SetFileHistory(NewFileHistoryService(fileRepo, versionRepo, zerolog.Nop()))
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<script src="app.js"></script>`)
+
+	response, err := fixer.Fix(ctx, projectID, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"app.js"}, response.Files)
+
+	file, err := fileRepo.GetFileByPath(ctx, projectID, "app.js")
+	require.NoError(t, err)
+	version, err := versionRepo.GetByVersion(ctx, file.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.FileVersionSourceFix, version.Source)
+}
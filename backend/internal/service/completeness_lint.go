@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/htmllint"
+)
+
+// LintSeverityOff is the LintSeverities value that disables a rule.
+const LintSeverityOff = "off"
+
+// lintPenalties are the points a page's accessibility or SEO score loses per issue.
+var lintPenalties = map[model.Severity]int{
+	model.SeverityCritical: 10,
+	model.SeverityWarning:  5,
+	model.SeverityInfo:     2,
+}
+
+// LintSeverities maps accessibility and SEO lint rule IDs (see htmllint.Rules) to the
+// severity of their issues: "critical", "warning", "info", or LintSeverityOff. Rules not
+// listed keep their default severity.
+type LintSeverities map[string]string
+
+// LoadLintSeverities parses severities from inline JSON, which is optional, e.g.
+//
+//	{"img-alt": "critical", "single-h1": "off"}
+func LoadLintSeverities(inline string) (LintSeverities, error) {
+	severities := make(LintSeverities)
+	if inline == "" {
+		return severities, nil
+	}
+	if err := json.Unmarshal([]byte(inline), &severities); err != nil {
+		return nil, fmt.Errorf("failed to parse lint severities: %w", err)
+	}
+	return severities, nil
+}
+
+// Validate checks that every entry names a lint rule and a known severity.
+func (s LintSeverities) Validate() error {
+	for id, severity := range s {
+		if _, ok := htmllint.Lookup(id); !ok {
+			return fmt.Errorf("unknown lint rule %q", id)
+		}
+		switch severity {
+		case string(model.SeverityCritical), string(model.SeverityWarning), string(model.SeverityInfo), LintSeverityOff:
+		default:
+			return fmt.Errorf("lint rule %q: unknown severity %q", id, severity)
+		}
+	}
+	return nil
+}
+
+// severity returns the severity of a rule's issues, and false if the rule is off.
+func (s LintSeverities) severity(rule *htmllint.Rule) (model.Severity, bool) {
+	severity, ok := s[rule.ID]
+	if !ok {
+		severity = rule.Severity
+	}
+	return model.Severity(severity), severity != LintSeverityOff
+}
+
+// lintIssues checks the HTML pages of files for accessibility and SEO problems, and
+// returns them as accessibility and seo issues with the project's score, or a nil
+// score if it has no pages.
+func (c *CompletenessChecker) lintIssues(files []model.File) ([]model.CompletenessIssue, *model.LintScore) {
+	var issues []model.CompletenessIssue
+	pages := 0
+	accessibility, seo := 0, 0 // Sums of the pages' scores
+
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Path))
+		if (ext != ".html" && ext != ".htm") || f.Content == "" {
+			continue
+		}
+		pages++
+
+		penalties := map[string]int{} // By category
+		for _, finding := range htmllint.Lint(f.Content) {
+			severity, ok := c.lintSeverities.severity(finding.Rule)
+			if !ok {
+				continue
+			}
+			penalties[finding.Rule.Category] += lintPenalties[severity]
+			issues = append(issues, model.CompletenessIssue{
+				ID:            generateIssueID(finding.Rule.Category, f.Path, finding.Rule.ID, strconv.Itoa(finding.Line), finding.Message),
+				Severity:      severity,
+				Type:          finding.Rule.Category,
+				ReferencedBy:  f.Path,
+				ReferenceType: finding.Rule.ID,
+				LineNumber:    finding.Line,
+				Context:       finding.Message,
+				WCAG:          finding.Rule.WCAG,
+				Suggestion:    finding.Rule.Fix,
+			})
+		}
+		accessibility += max(0, 100-penalties[htmllint.CategoryAccessibility])
+		seo += max(0, 100-penalties[htmllint.CategorySEO])
+	}
+
+	if pages == 0 {
+		return issues, nil
+	}
+	return issues, &model.LintScore{
+		Accessibility: int(math.Round(float64(accessibility) / float64(pages))),
+		SEO:           int(math.Round(float64(seo) / float64(pages))),
+		PagesChecked:  pages,
+	}
+}
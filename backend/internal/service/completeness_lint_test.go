@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// newLintTestChecker returns a checker for a project with a page that has accessibility
+// and SEO issues and a page that has none.
+func newLintTestChecker(t *testing.T) (*CompletenessChecker, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<!DOCTYPE html>\n<html>\n<head>\n<title>Bakery</title>\n"+
+		"<meta name=\"viewport\" content=\"width=device-width\">\n</head>\n<body>\n<h1>Bakery</h1>\n<img src=\"data:,\">\n</body>\n</html>")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "about.html", "html", "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<title>About</title>\n"+
+		"<meta name=\"description\" content=\"Who we are.\">\n<meta name=\"viewport\" content=\"width=device-width\">\n</head>\n"+
+		"<body>\n<h1>About us</h1>\n</body>\n</html>")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "css/site.css", "css", "img { max-width: 100%; }")
+	return NewCompletenessChecker(fileRepo, zerolog.Nop()), projectID
+}
+
+func TestCompletenessChecker_LintIssues(t *testing.T) {
+	checker, projectID := newLintTestChecker(t)
+
+	report, err := checker.Check(context.Background(), projectID)
+	require.NoError(t, err)
+
+	issues := make(map[string]model.CompletenessIssue) // By rule
+	for _, issue := range report.GetLintIssues() {
+		assert.Equal(t, "index.html", issue.ReferencedBy)
+		issues[issue.ReferenceType] = issue
+	}
+	require.Len(t, issues, 3)
+
+	alt := issues["img-alt"]
+	assert.Equal(t, "accessibility", alt.Type)
+	assert.Equal(t, model.SeverityWarning, alt.Severity)
+	assert.Equal(t, 9, alt.LineNumber)
+	assert.Equal(t, `<img src="data:,"> has no alt attribute`, alt.Context)
+	assert.Equal(t, "1.1.1 Non-text Content", alt.WCAG)
+	assert.Contains(t, alt.Suggestion, "alt attribute")
+	assert.False(t, alt.AutoFixable)
+
+	assert.Equal(t, "accessibility", issues["html-lang"].Type)
+	assert.Equal(t, "3.1.1 Language of Page", issues["html-lang"].WCAG)
+
+	description := issues["meta-description"]
+	assert.Equal(t, "seo", description.Type)
+	assert.Equal(t, 3, description.LineNumber)
+	assert.Empty(t, description.WCAG)
+	assert.NotEmpty(t, description.Suggestion)
+
+	assert.Equal(t, model.StatusWarning, report.Status)
+	require.NotNil(t, report.LintScore)
+	assert.Equal(t, model.LintScore{Accessibility: 95, SEO: 98, PagesChecked: 2}, *report.LintScore)
+}
+
+func TestCompletenessChecker_LintSeverities(t *testing.T) {
+	checker, projectID := newLintTestChecker(t)
+	checker.SetLintSeverities(LintSeverities{"img-alt": "critical", "meta-description": LintSeverityOff})
+
+	report, err := checker.Check(context.Background(), projectID)
+	require.NoError(t, err)
+
+	var rules []string
+	for _, issue := range report.GetLintIssues() {
+		rules = append(rules, issue.ReferenceType)
+	}
+	assert.ElementsMatch(t, []string{"img-alt", "html-lang"}, rules)
+
+	critical := report.GetCriticalIssues()
+	require.Len(t, critical, 1)
+	assert.Equal(t, "img-alt", critical[0].ReferenceType)
+	assert.Equal(t, model.StatusCritical, report.Status)
+	assert.Equal(t, model.LintScore{Accessibility: 93, SEO: 100, PagesChecked: 2}, *report.LintScore)
+}
+
+func TestCompletenessChecker_LintScoreWithoutPages(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "main.go", "go", "package main\n\nfunc main() {}\n")
+
+	report, err := NewCompletenessChecker(fileRepo, zerolog.Nop()).Check(ctx, projectID)
+	require.NoError(t, err)
+	assert.Nil(t, report.LintScore)
+}
+
+func TestLoadLintSeverities(t *testing.T) {
+	severities, err := LoadLintSeverities("")
+	require.NoError(t, err)
+	assert.Empty(t, severities)
+
+	severities, err = LoadLintSeverities(`{"img-alt": "critical", "single-h1": "off"}`)
+	require.NoError(t, err)
+	assert.Equal(t, LintSeverities{"img-alt": "critical", "single-h1": "off"}, severities)
+
+	_, err = LoadLintSeverities("{not json")
+	assert.Error(t, err)
+}
+
+func TestLintSeverities_Validate(t *testing.T) {
+	assert.NoError(t, LintSeverities{"img-alt": "critical", "meta-viewport": "info", "single-h1": "off"}.Validate())
+	assert.Error(t, LintSeverities{"img-alts": "critical"}.Validate())
+	assert.Error(t, LintSeverities{"img-alt": "error"}.Validate())
+}
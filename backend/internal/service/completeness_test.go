@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/refs"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/render"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// fakeRenderChecker returns canned problems per page and records the pages it loaded.
+type fakeRenderChecker struct {
+	problems map[string][]render.Problem
+	err      error
+	pages    []string
+}
+
+func (f *fakeRenderChecker) Check(ctx context.Context, pagePath string, files map[string]string) ([]render.Problem, error) {
+	f.pages = append(f.pages, pagePath)
+	return f.problems[pagePath], f.err
+}
+
+func TestCompletenessChecker_RenderCheck(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", `<link rel="stylesheet" href="style.css"><script src="app.js"></script>`)
+	_, _ = fileRepo.SaveFile(ctx, projectID, "app.js", "javascript", "init();")
+
+	renderer := &fakeRenderChecker{problems: map[string][]render.Problem{
+		"index.html": {
+			{Kind: render.ProblemAssetLoad, Message: "Failed to load stylesheet style.css", File: "index.html", Line: 1, Asset: "style.css"},
+			{Kind: render.ProblemException, Message: "Uncaught ReferenceError: init is not defined", File: "app.js", Line: 1},
+			{Kind: render.ProblemConsoleError, Message: "Chart failed", File: "app.js", Line: 3},
+			{Kind: render.ProblemUnchecked, Message: "An inline module script wasn't checked", File: "index.html", Line: 1},
+		},
+	}}
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	checker.SetRenderChecker(renderer)
+
+	report, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"index.html"}, renderer.pages)
+	assert.Equal(t, model.StatusCritical, report.Status)
+
+	// The missing stylesheet is reported once, by the reference check
+	require.Len(t, report.Issues, 4)
+	assert.Equal(t, "missing_file", report.Issues[0].Type)
+	assert.Equal(t, "style.css", report.Issues[0].MissingFile)
+
+	assert.Equal(t, "runtime_error", report.Issues[1].Type)
+	assert.Equal(t, model.SeverityCritical, report.Issues[1].Severity)
+	assert.Equal(t, "app.js", report.Issues[1].ReferencedBy)
+	assert.Equal(t, 1, report.Issues[1].LineNumber)
+	assert.Equal(t, "exception", report.Issues[1].ReferenceType)
+	assert.Equal(t, "Uncaught ReferenceError: init is not defined", report.Issues[1].Context)
+	assert.False(t, report.Issues[1].AutoFixable)
+
+	assert.Equal(t, model.SeverityWarning, report.Issues[2].Severity)
+	assert.Equal(t, "console_error", report.Issues[2].ReferenceType)
+	assert.Equal(t, model.SeverityInfo, report.Issues[3].Severity)
+	assert.Equal(t, "unchecked", report.Issues[3].ReferenceType)
+	assert.Equal(t, []string{"style.css"}, report.GetMissingFiles())
+}
+
+func TestCompletenessChecker_RenderCheckFailureKeepsReport(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>Hi</h1>")
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	checker.SetRenderChecker(&fakeRenderChecker{err: errors.New("node not found")})
+
+	report, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusPass, report.Status)
+	assert.Empty(t, report.Issues)
+}
+
+func TestCompletenessChecker_MultiLanguageReferences(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<!DOCTYPE html>\n<script\n  type=\"module\"\n  src=\"js/main.js\"></script>\n<a href=\"about.html\">About</a>")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "js/main.js", "javascript", "const page = await import('./pages/home.js');")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "app/__init__.py", "python", "")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "app/main.py", "python", "import os\nfrom .models import Order\n")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "README.md", "markdown", "See [the guide](docs/guide.md).\n\n```\n[not checked](x.md)\n```")
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	report, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+
+	issues := make(map[string]model.CompletenessIssue)
+	for _, issue := range report.Issues {
+		if issue.Type == "missing_file" {
+			issues[issue.MissingFile] = issue
+		}
+	}
+	require.Len(t, issues, 4)
+
+	assert.Equal(t, model.SeverityCritical, issues["./pages/home.js"].Severity)
+	assert.Equal(t, "js/main.js", issues["./pages/home.js"].ReferencedBy)
+	assert.True(t, issues["./pages/home.js"].AutoFixable)
+
+	assert.Equal(t, model.SeverityCritical, issues[".models"].Severity)
+	assert.Equal(t, "import", issues[".models"].ReferenceType)
+	assert.Equal(t, 2, issues[".models"].LineNumber)
+	assert.Equal(t, "from .models import Order", issues[".models"].Context)
+
+	assert.Equal(t, model.SeverityInfo, issues["about.html"].Severity)
+	assert.Equal(t, "link", issues["about.html"].ReferenceType)
+	assert.Equal(t, model.SeverityInfo, issues["docs/guide.md"].Severity)
+	assert.False(t, issues["docs/guide.md"].AutoFixable)
+}
+
+func TestCompletenessChecker_CustomExtractor(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "main.rs", "rust", "mod util;")
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	checker.Extractors().Register(".rs", refs.ExtractorFunc(func(path, content string, project *refs.Project) []refs.Reference {
+		return []refs.Reference{{Path: "util", Type: "import", Line: 1, Context: content, Targets: []string{"util.rs", "util/mod.rs"}}}
+	}))
+
+	report, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, "util", report.Issues[0].MissingFile)
+	assert.Equal(t, model.SeverityInfo, report.Issues[0].Severity)
+}
+
+func TestCompletenessChecker_SyntaxErrors(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<!DOCTYPE html>\n<main>\n  <p>Hi</p>\n")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "data/menu.json", "json", `{"items": [1, 2,]}`)
+	_, _ = fileRepo.SaveFile(ctx, projectID, "css/site.css", "css", "body { color: red; }\n")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "notes.txt", "text", "{ not checked")
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	report, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+
+	issues := make(map[string]model.CompletenessIssue)
+	for _, issue := range report.GetSyntaxErrors() {
+		issues[issue.ReferencedBy] = issue
+	}
+	require.Len(t, issues, 2)
+	assert.Equal(t, model.StatusCritical, report.Status)
+
+	html := issues["index.html"]
+	assert.Equal(t, model.SeverityCritical, html.Severity)
+	assert.Equal(t, "html", html.ReferenceType)
+	assert.Equal(t, 2, html.LineNumber)
+	assert.Equal(t, 1, html.Column)
+	assert.Equal(t, "<main> is never closed", html.Context)
+	assert.False(t, html.AutoFixable)
+
+	json := issues["data/menu.json"]
+	assert.Equal(t, "json", json.ReferenceType)
+	assert.Equal(t, 1, json.LineNumber)
+	assert.Equal(t, 17, json.Column)
+
+	// Issue IDs are stable across checks
+	again, err := checker.Check(ctx, projectID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, report.GetSyntaxErrors(), again.GetSyntaxErrors())
+}
@@ -950,7 +950,8 @@ func (s *DiscoveryService) GenerateWelcomeMessage(ctx context.Context, projectID
 	}
 
 	// Call Claude to generate the welcome message
-	stream, err := s.claudeService.SendMessage(ctx, systemPrompt, triggerMessages)
+	usageCtx := WithUsageScope(ctx, projectID, nil, model.UsageSourceWelcome)
+	stream, err := s.claudeService.SendMessage(usageCtx, systemPrompt, triggerMessages)
 	if err != nil {
 		return nil, err
 	}
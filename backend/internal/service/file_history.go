@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// File history errors
+var (
+	ErrFileNotFound        = errors.New("file not found")
+	ErrFileVersionNotFound = errors.New("file version not found")
+	ErrFileExists          = errors.New("file already exists")
+)
+
+// FileHistoryService records every write to a project file and supports rollback.
+type FileHistoryService struct {
+	fileRepo    repository.FileRepository
+	versionRepo repository.FileVersionRepository
+	logger      zerolog.Logger
+}
+
+// NewFileHistoryService creates a new FileHistoryService.
+func NewFileHistoryService(
+	fileRepo repository.FileRepository,
+	versionRepo repository.FileVersionRepository,
+	logger zerolog.Logger,
+) *FileHistoryService {
+	return &FileHistoryService{
+		fileRepo:    fileRepo,
+		versionRepo: versionRepo,
+		logger:      logger.With().Str("component", "file_history").Logger(),
+	}
+}
+
+// RecordWrite snapshots the current content of a file after it has been saved.
+// The agent type is optional; the message ID is attached later via AttachMessage
+// because the assistant message is only persisted once the turn completes.
+func (s *FileHistoryService) RecordWrite(ctx context.Context, file *model.File, source model.FileVersionSource, agentType *string) (*model.FileVersion, error) {
+	version, err := s.versionRepo.Create(ctx, &model.FileVersion{
+		FileID:    file.ID,
+		ProjectID: file.ProjectID,
+		Path:      file.Path,
+		Language:  file.Language,
+		Content:   file.Content,
+		Source:    source,
+		AgentType: agentType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record file version: %w", err)
+	}
+
+	s.logger.Debug().
+		Str("fileId", file.ID.String()).
+		Str("path", file.Path).
+		Int("version", version.Version).
+		Str("source", string(source)).
+		Msg("recorded file version")
+
+	return version, nil
+}
+
+// AttachMessage links the versions written during a chat turn to the assistant message.
+func (s *FileHistoryService) AttachMessage(ctx context.Context, versions []model.FileVersion, messageID uuid.UUID) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(versions))
+	for i, v := range versions {
+		ids[i] = v.ID
+	}
+
+	if err := s.versionRepo.AttachMessage(ctx, ids, messageID); err != nil {
+		return fmt.Errorf("failed to attach message to file versions: %w", err)
+	}
+
+	return nil
+}
+
+// ListVersions returns the file and its version history, newest first.
+func (s *FileHistoryService) ListVersions(ctx context.Context, fileID uuid.UUID) (*model.File, []model.FileVersionListItem, error) {
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions, err := s.versionRepo.ListByFileID(ctx, fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list file versions: %w", err)
+	}
+
+	if versions == nil {
+		versions = []model.FileVersionListItem{}
+	}
+
+	return file, versions, nil
+}
+
+// GetVersion returns a specific version of a file, including its content.
+func (s *FileHistoryService) GetVersion(ctx context.Context, fileID uuid.UUID, version int) (*model.FileVersion, error) {
+	if _, err := s.getFile(ctx, fileID); err != nil {
+		return nil, err
+	}
+
+	fileVersion, err := s.versionRepo.GetByVersion(ctx, fileID, version)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrFileVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to get file version: %w", err)
+	}
+
+	return fileVersion, nil
+}
+
+// RestoreVersion overwrites the file with the content of an earlier version.
+// The restore itself is recorded as a new version so it can be undone.
+func (s *FileHistoryService) RestoreVersion(ctx context.Context, fileID uuid.UUID, version int) (*model.File, *model.FileVersion, error) {
+	file, err := s.getFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := s.GetVersion(ctx, fileID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restored, err := s.fileRepo.SaveFile(ctx, file.ProjectID, file.Path, target.Language, target.Content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	restoredFrom := target.Version
+	newVersion, err := s.versionRepo.Create(ctx, &model.FileVersion{
+		FileID:       restored.ID,
+		ProjectID:    restored.ProjectID,
+		Path:         restored.Path,
+		Language:     restored.Language,
+		Content:      restored.Content,
+		Source:       model.FileVersionSourceRestore,
+		RestoredFrom: &restoredFrom,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record restored version: %w", err)
+	}
+
+	s.logger.Info().
+		Str("fileId", fileID.String()).
+		Str("path", restored.Path).
+		Int("restoredFrom", restoredFrom).
+		Int("version", newVersion.Version).
+		Msg("restored file version")
+
+	return restored, newVersion, nil
+}
+
+// ListDeletedFiles returns the latest version of each deleted file in a project that can
+// be restored, ordered by path.
+func (s *FileHistoryService) ListDeletedFiles(ctx context.Context, projectID uuid.UUID) ([]model.FileVersionListItem, error) {
+	versions, err := s.versionRepo.ListDeleted(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted files: %w", err)
+	}
+
+	if versions == nil {
+		versions = []model.FileVersionListItem{}
+	}
+
+	return versions, nil
+}
+
+// RestoreDeletedFile recreates a deleted file at path with the content of its latest
+// version. The new file takes over the deleted file's history, and the restore is
+// recorded as a new version. Returns ErrFileExists if a file has since taken the path.
+func (s *FileHistoryService) RestoreDeletedFile(ctx context.Context, projectID uuid.UUID, path string) (*model.File, *model.FileVersion, error) {
+	if _, err := s.fileRepo.GetFileByPath(ctx, projectID, path); err == nil {
+		return nil, nil, ErrFileExists
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, fmt.Errorf("failed to get file: %w", err)
+	}
+
+	target, err := s.versionRepo.GetLatestDeleted(ctx, projectID, path)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrFileVersionNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get deleted file version: %w", err)
+	}
+
+	restored, err := s.fileRepo.SaveFile(ctx, projectID, path, target.Language, target.Content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	restoredFrom := target.Version
+	newVersion, err := s.versionRepo.Create(ctx, &model.FileVersion{
+		FileID:       restored.ID,
+		ProjectID:    restored.ProjectID,
+		Path:         restored.Path,
+		Language:     restored.Language,
+		Content:      restored.Content,
+		Source:       model.FileVersionSourceRestore,
+		RestoredFrom: &restoredFrom,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record restored version: %w", err)
+	}
+
+	s.logger.Info().
+		Str("fileId", restored.ID.String()).
+		Str("path", restored.Path).
+		Int("restoredFrom", restoredFrom).
+		Int("version", newVersion.Version).
+		Msg("restored deleted file")
+
+	return restored, newVersion, nil
+}
+
+// getFile loads a file, mapping repository not-found errors to ErrFileNotFound.
+func (s *FileHistoryService) getFile(ctx context.Context, fileID uuid.UUID) (*model.File, error) {
+	file, err := s.fileRepo.GetFile(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func newTestFileHistoryService() (*FileHistoryService, *repository.MockFileRepository, *repository.MockFileVersionRepository) {
+	fileRepo := repository.NewMockFileRepository()
+	versionRepo := repository.NewMockFileVersionRepository()
+	return NewFileHistoryService(fileRepo, versionRepo, zerolog.Nop()), fileRepo, versionRepo
+}
+
+func TestFileHistoryService_RecordWrite(t *testing.T) {
+	svc, fileRepo, _ := newTestFileHistoryService()
+	ctx := context.Background()
+	projectID := uuid.New()
+	agent := string(model.AgentDeveloper)
+
+	file, err := fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>v1</h1>")
+	require.NoError(t, err)
+	v1, err := svc.RecordWrite(ctx, file, model.FileVersionSourceTool, &agent)
+	require.NoError(t, err)
+
+	file, err = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>v2</h1>")
+	require.NoError(t, err)
+	v2, err := svc.RecordWrite(ctx, file, model.FileVersionSourceCodeBlock, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, v1.Version)
+	assert.Equal(t, "<h1>v1</h1>", v1.Content)
+	assert.Equal(t, &agent, v1.AgentType)
+	assert.Equal(t, 2, v2.Version)
+	assert.Equal(t, model.FileVersionSourceCodeBlock, v2.Source)
+
+	_, versions, err := svc.ListVersions(ctx, file.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest version should be listed first")
+	assert.Equal(t, len("<h1>v2</h1>"), versions[0].Size)
+}
+
+func TestFileHistoryService_AttachMessage(t *testing.T) {
+	svc, fileRepo, _ := newTestFileHistoryService()
+	ctx := context.Background()
+
+	file, _ := fileRepo.SaveFile(ctx, uuid.New(), "app.js", "javascript", "console.log(1)")
+	version, err := svc.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+	require.NoError(t, err)
+
+	messageID := uuid.New()
+	require.NoError(t, svc.AttachMessage(ctx, []model.FileVersion{*version}, messageID))
+
+	got, err := svc.GetVersion(ctx, file.ID, version.Version)
+	require.NoError(t, err)
+	require.NotNil(t, got.MessageID)
+	assert.Equal(t, messageID, *got.MessageID)
+}
+
+func TestFileHistoryService_RestoreVersion(t *testing.T) {
+	svc, fileRepo, _ := newTestFileHistoryService()
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	file, _ := fileRepo.SaveFile(ctx, projectID, "index.html", "html", "working page")
+	_, _ = svc.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+	file, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "broken page")
+	_, _ = svc.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+
+	restored, newVersion, err := svc.RestoreVersion(ctx, file.ID, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, "working page", restored.Content)
+	assert.Equal(t, 3, newVersion.Version)
+	assert.Equal(t, model.FileVersionSourceRestore, newVersion.Source)
+	require.NotNil(t, newVersion.RestoredFrom)
+	assert.Equal(t, 1, *newVersion.RestoredFrom)
+
+	current, err := fileRepo.GetFileByPath(ctx, projectID, "index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "working page", current.Content)
+}
+
+func TestFileHistoryService_NotFound(t *testing.T) {
+	svc, fileRepo, _ := newTestFileHistoryService()
+	ctx := context.Background()
+
+	_, _, err := svc.ListVersions(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	file, _ := fileRepo.SaveFile(ctx, uuid.New(), "index.html", "html", "content")
+	_, err = svc.GetVersion(ctx, file.ID, 7)
+	assert.ErrorIs(t, err, ErrFileVersionNotFound)
+
+	_, _, err = svc.RestoreVersion(ctx, file.ID, 7)
+	assert.ErrorIs(t, err, ErrFileVersionNotFound)
+}
+
+func TestChatService_ProcessMessage_RecordsFileVersions(t *testing.T) {
+	server := newToolUseTestServer(t, "index.html", "<h1>Hello</h1>")
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	versionRepo := repository.NewMockFileVersionRepository()
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+	}, logger)
+
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	chatService := NewChatService(ChatConfig{}, claudeService, nil, nil, repo, fileRepo, nil, logger)
+	chatService.SetFileHistory(NewFileHistoryService(fileRepo, versionRepo, logger))
+
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Create index.html", func(string) {}, nil)
+	require.NoError(t, err)
+
+	file, err := fileRepo.GetFileByPath(ctx, project.ID, "index.html")
+	require.NoError(t, err)
+
+	versions, err := versionRepo.ListByFileID(ctx, file.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, model.FileVersionSourceTool, versions[0].Source)
+	require.NotNil(t, versions[0].MessageID)
+	assert.Equal(t, result.Message.ID, *versions[0].MessageID)
+}
+
+func TestFileHistoryService_RestoreDeletedFile(t *testing.T) {
+	svc, fileRepo, versionRepo := newTestFileHistoryService()
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	file, _ := fileRepo.SaveFile(ctx, projectID, "about.html", "html", "first draft")
+	_, _ = svc.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+	file, _ = fileRepo.SaveFile(ctx, projectID, "about.html", "html", "about us")
+	_, _ = svc.RecordWrite(ctx, file, model.FileVersionSourceTool, nil)
+	require.NoError(t, fileRepo.DeleteFile(ctx, file.ID))
+	versionRepo.DetachFile(file.ID)
+
+	deleted, err := svc.ListDeletedFiles(ctx, projectID)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, "about.html", deleted[0].Path)
+	assert.Equal(t, 2, deleted[0].Version)
+
+	restored, newVersion, err := svc.RestoreDeletedFile(ctx, projectID, "about.html")
+	require.NoError(t, err)
+	assert.Equal(t, "about us", restored.Content)
+	assert.Equal(t, 3, newVersion.Version, "the restored file continues the deleted file's history")
+	require.NotNil(t, newVersion.RestoredFrom)
+	assert.Equal(t, 2, *newVersion.RestoredFrom)
+
+	_, versions, err := svc.ListVersions(ctx, restored.ID)
+	require.NoError(t, err)
+	assert.Len(t, versions, 3)
+
+	deleted, err = svc.ListDeletedFiles(ctx, projectID)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	_, _, err = svc.RestoreDeletedFile(ctx, projectID, "about.html")
+	assert.ErrorIs(t, err, ErrFileExists)
+	_, _, err = svc.RestoreDeletedFile(ctx, projectID, "contact.html")
+	assert.ErrorIs(t, err, ErrFileVersionNotFound)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// fileStage holds a chat turn's file changes until the turn completes. Tools read and
+// write through it, so they see the turn's own pending changes while the project files
+// stay untouched; commit applies all changes in one transaction, and a turn that fails
+// or is cancelled simply drops the stage.
+// Safe for concurrent use.
+type fileStage struct {
+	projectID uuid.UUID
+	base      repository.FileRepository
+
+	mu      sync.RWMutex
+	ops     []model.FileOperation
+	files   map[string]*model.File             // Staged state by path; nil means deleted
+	order   []string                           // Paths in the order they were first changed
+	sources map[string]model.FileVersionSource // How each saved path was last written
+}
+
+// newFileStage creates an empty stage over the project's committed files.
+func newFileStage(projectID uuid.UUID, base repository.FileRepository) *fileStage {
+	return &fileStage{
+		projectID: projectID,
+		base:      base,
+		files:     make(map[string]*model.File),
+		sources:   make(map[string]model.FileVersionSource),
+	}
+}
+
+// GetFileByPath returns a copy of the file at path as the turn currently sees it.
+func (f *fileStage) GetFileByPath(ctx context.Context, path string) (*model.File, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.getLocked(ctx, path)
+}
+
+func (f *fileStage) getLocked(ctx context.Context, path string) (*model.File, error) {
+	if staged, ok := f.files[path]; ok {
+		if staged == nil {
+			return nil, repository.ErrNotFound
+		}
+		file := *staged
+		return &file, nil
+	}
+
+	committed, err := f.base.GetFileByPath(ctx, f.projectID, path)
+	if err != nil {
+		return nil, err
+	}
+	file := *committed // Never hand out the repository's own copy
+	return &file, nil
+}
+
+// GetFilesWithContentByProject returns the project's files as the turn currently sees them.
+func (f *fileStage) GetFilesWithContentByProject(ctx context.Context) ([]model.File, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	committed, err := f.base.GetFilesWithContentByProject(ctx, f.projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []model.File
+	for _, file := range committed {
+		if _, staged := f.files[file.Path]; !staged {
+			files = append(files, file)
+		}
+	}
+	for _, path := range f.order {
+		if staged := f.files[path]; staged != nil {
+			files = append(files, *staged)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// GetFilesByProject returns the project's files without content as the turn currently sees them.
+func (f *fileStage) GetFilesByProject(ctx context.Context) ([]model.FileListItem, error) {
+	files, err := f.GetFilesWithContentByProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.FileListItem, len(files))
+	for i, file := range files {
+		items[i] = model.FileListItem{ID: file.ID, Path: file.Path, Filename: file.Filename, Language: file.Language, CreatedAt: file.CreatedAt}
+	}
+	return items, nil
+}
+
+// SaveFile stages creating or overwriting a file and returns its staged state.
+func (f *fileStage) SaveFile(ctx context.Context, path, language, content string, source model.FileVersionSource) *model.File {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file := &model.File{ID: uuid.New(), ProjectID: f.projectID, Path: path, Filename: filepath.Base(path)}
+	if existing, err := f.getLocked(ctx, path); err == nil {
+		file = existing // Keep the ID, so a later move of the same file is recognized
+	}
+	file.Language = language
+	file.Content = content
+
+	f.stageLocked(path, file)
+	f.sources[path] = source
+	f.ops = append(f.ops, model.FileOperation{Type: model.FileOperationSave, Path: path, Language: language, Content: content})
+
+	staged := *file
+	return &staged
+}
+
+// DeleteFile stages deleting the file at path.
+func (f *fileStage) DeleteFile(ctx context.Context, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.getLocked(ctx, path); err != nil {
+		return err
+	}
+
+	f.stageLocked(path, nil)
+	delete(f.sources, path)
+	f.ops = append(f.ops, model.FileOperation{Type: model.FileOperationDelete, Path: path})
+	return nil
+}
+
+// MoveFile stages renaming a file and returns its staged state.
+// Returns ErrPathExists if the turn already sees a file at newPath.
+func (f *fileStage) MoveFile(ctx context.Context, path, newPath string) (*model.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := f.getLocked(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.getLocked(ctx, newPath); err == nil {
+		return nil, repository.ErrPathExists
+	}
+
+	file.Path = newPath
+	file.Filename = filepath.Base(newPath)
+	f.stageLocked(path, nil)
+	f.stageLocked(newPath, file)
+	if source, ok := f.sources[path]; ok {
+		f.sources[newPath] = source
+		delete(f.sources, path)
+	}
+	f.ops = append(f.ops, model.FileOperation{Type: model.FileOperationMove, Path: path, NewPath: newPath})
+
+	moved := *file
+	return &moved, nil
+}
+
+func (f *fileStage) stageLocked(path string, file *model.File) {
+	if _, ok := f.files[path]; !ok {
+		f.order = append(f.order, path)
+	}
+	f.files[path] = file
+}
+
+// pending returns the number of staged operations.
+func (f *fileStage) pending() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.ops)
+}
+
+// stagedPath is the final state of a path changed during the turn.
+type stagedPath struct {
+	Path    string
+	Deleted bool
+	Source  model.FileVersionSource // Empty if the content was not written this turn (e.g. only moved)
+}
+
+// changedPaths returns every changed path with its final state, in the order first changed.
+func (f *fileStage) changedPaths() []stagedPath {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	paths := make([]stagedPath, len(f.order))
+	for i, path := range f.order {
+		paths[i] = stagedPath{Path: path, Deleted: f.files[path] == nil, Source: f.sources[path]}
+	}
+	return paths
+}
+
+// commit applies the staged operations to the project in one transaction.
+func (f *fileStage) commit(ctx context.Context) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.base.ApplyOperations(ctx, f.projectID, f.ops)
+}
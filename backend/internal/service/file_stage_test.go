@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// failingCommitFileRepository fails every ApplyOperations call.
+type failingCommitFileRepository struct {
+	*repository.MockFileRepository
+}
+
+func (r failingCommitFileRepository) ApplyOperations(context.Context, uuid.UUID, []model.FileOperation) error {
+	return errors.New("connection reset")
+}
+
+func TestFileStage_OverlaysCommittedFiles(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	index, _ := fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>Hi</h1>")
+	_, _ = fileRepo.SaveFile(ctx, projectID, "old.js", "javascript", "old()")
+	stage := newFileStage(projectID, fileRepo)
+
+	stage.SaveFile(ctx, "app.js", "javascript", "app()", model.FileVersionSourceTool)
+	require.NoError(t, stage.DeleteFile(ctx, "old.js"))
+	moved, err := stage.MoveFile(ctx, "index.html", "home.html")
+	require.NoError(t, err)
+	assert.Equal(t, index.ID, moved.ID, "a moved file keeps its ID")
+
+	files, err := stage.GetFilesByProject(ctx)
+	require.NoError(t, err)
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Equal(t, []string{"app.js", "home.html"}, paths)
+
+	_, err = stage.GetFileByPath(ctx, "old.js")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, stage.DeleteFile(ctx, "old.js"), repository.ErrNotFound)
+	_, err = stage.MoveFile(ctx, "app.js", "home.html")
+	assert.ErrorIs(t, err, repository.ErrPathExists)
+
+	// Nothing reaches the project before the commit
+	committed, _ := fileRepo.GetFilesByProject(ctx, projectID)
+	assert.Len(t, committed, 2)
+
+	require.NoError(t, stage.commit(ctx))
+	committed, _ = fileRepo.GetFilesByProject(ctx, projectID)
+	paths = nil
+	for _, file := range committed {
+		paths = append(paths, file.Path)
+	}
+	assert.ElementsMatch(t, []string{"app.js", "home.html"}, paths)
+	assert.Equal(t, []stagedPath{
+		{Path: "app.js", Source: model.FileVersionSourceTool},
+		{Path: "old.js", Deleted: true},
+		{Path: "index.html", Deleted: true},
+		{Path: "home.html"},
+	}, stage.changedPaths())
+}
+
+func TestFileStage_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>Hi</h1>")
+	stage := newFileStage(projectID, fileRepo)
+
+	file, err := stage.GetFileByPath(ctx, "index.html")
+	require.NoError(t, err)
+	file.Content = "changed"
+
+	committed, _ := fileRepo.GetFileByPath(ctx, projectID, "index.html")
+	assert.Equal(t, "<h1>Hi</h1>", committed.Content)
+}
+
+func TestChatService_CommitTurnFiles_FailureAppliesNothing(t *testing.T) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	projectID := uuid.New()
+	_, _ = fileRepo.SaveFile(ctx, projectID, "index.html", "html", "<h1>Hi</h1>")
+	s := NewChatService(ChatConfig{}, nil, nil, nil, repository.NewMockProjectRepository(), failingCommitFileRepository{fileRepo}, nil, zerolog.Nop())
+
+	turn := s.newTurn(projectID, nil)
+	s.executeTool(ctx, turn, ToolUseBlock{Type: "tool_use", ID: "toolu_1", Name: "write_file", Input: map[string]interface{}{"path": "app.js", "content": "app()"}})
+	s.executeTool(ctx, turn, ToolUseBlock{Type: "tool_use", ID: "toolu_2", Name: "delete_file", Input: map[string]interface{}{"path": "index.html"}})
+
+	var created []string
+	err := s.commitTurnFiles(ctx, turn, ChatCallbacks{OnFileCreated: func(path string) { created = append(created, path) }})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to save file changes")
+	assert.Empty(t, created, "no events for changes that were not applied")
+
+	files, _ := fileRepo.GetFilesByProject(ctx, projectID)
+	require.Len(t, files, 1)
+	assert.Equal(t, "index.html", files[0].Path)
+}
+
+func TestChatService_ExecuteTools_KeepsCallOrder(t *testing.T) {
+	s, projectID := newNavigationTestChatService(t)
+	ctx := context.Background()
+	turn := s.newTurn(projectID, nil)
+
+	toolUses := []ToolUseBlock{
+		{ID: "toolu_1", Name: "read_file", Input: map[string]interface{}{"path": "index.html"}},
+		{ID: "toolu_2", Name: "list_files", Input: map[string]interface{}{"glob": "*.css"}},
+		{ID: "toolu_3", Name: "write_file", Input: map[string]interface{}{"path": "js/new.js", "content": "newer()"}},
+		{ID: "toolu_4", Name: "read_file", Input: map[string]interface{}{"path": "js/new.js"}},
+		{ID: "toolu_5", Name: "file_outline", Input: map[string]interface{}{"path": "js/new.js"}},
+	}
+
+	results, err := s.executeTools(ctx, turn, toolUses)
+	require.NoError(t, err)
+	require.Len(t, results, len(toolUses))
+	for i, result := range results {
+		assert.Equal(t, toolUses[i].ID, result.Result.ToolUseID)
+		assert.False(t, result.Result.IsError, result.Result.Content)
+	}
+	assert.Contains(t, results[0].Result.Content, "<h1>Orders</h1>")
+	assert.Equal(t, "1 files:\ncss/styles.css [User Interface] - Site styles\n", results[1].Result.Content)
+	assert.Equal(t, "newer()", results[3].Result.Content, "reads after a write see the staged file")
+}
+
+func TestChatService_ExecuteTools_Cancelled(t *testing.T) {
+	s, projectID := newNavigationTestChatService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.executeTools(ctx, s.newTurn(projectID, nil), []ToolUseBlock{{ID: "toolu_1", Name: "list_files", Input: map[string]interface{}{}}})
+	assert.ErrorIs(t, err, ErrResponseCancelled)
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// registerFileTools registers the tools for reading, navigating and changing project files.
+// The definitions are static, so a registration error is a programming mistake.
+func (s *ChatService) registerFileTools() {
+	for _, tool := range s.fileTools() {
+		if err := s.tools.Register(tool); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// fileTools returns the file tools in the order they are offered to Claude.
+func (s *ChatService) fileTools() []Tool {
+	return []Tool{
+		{
+			Name:        "write_file",
+			Description: "Create or overwrite a file in the project. Use this for ALL file creation.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File path relative to project root (e.g., 'src/index.html', 'styles/main.css')",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "Complete file content to write",
+					},
+				},
+				"required": []string{"path", "content"},
+			},
+			NewInput:    func() ToolInput { return &writeFileInput{} },
+			Handler:     s.executeWriteFile,
+			Permissions: []ToolPermission{ToolPermissionReadFiles, ToolPermissionWriteFiles},
+		},
+		{
+			Name: "edit_file",
+			Description: "Make targeted changes to an existing file without rewriting it. " +
+				"Give either 'edits' (exact search/replace blocks, applied in order; each search text must appear exactly once) " +
+				"or 'patch' (unified diff hunks). Prefer this over write_file for changes to existing files. " +
+				"If an edit does not match, read_file the current content and try again.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File path relative to project root",
+					},
+					"edits": map[string]interface{}{
+						"type":        "array",
+						"description": "Search/replace blocks. Include enough surrounding lines to make each search text unique.",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"search": map[string]interface{}{
+									"type":        "string",
+									"description": "Exact text to find, including whitespace and indentation",
+								},
+								"replace": map[string]interface{}{
+									"type":        "string",
+									"description": "Text to replace it with (empty to delete)",
+								},
+							},
+							"required": []string{"search", "replace"},
+						},
+					},
+					"patch": map[string]interface{}{
+						"type":        "string",
+						"description": "Unified diff with @@ hunk headers and ' ', '-', '+' line prefixes",
+					},
+				},
+				"required": []string{"path"},
+			},
+			NewInput:    func() ToolInput { return &editFileInput{} },
+			Handler:     s.executeEditFile,
+			Permissions: []ToolPermission{ToolPermissionReadFiles, ToolPermissionWriteFiles},
+		},
+		{
+			Name:        "read_file",
+			Description: "Read the contents of a file in the project",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File path relative to project root",
+					},
+				},
+				"required": []string{"path"},
+			},
+			NewInput:    func() ToolInput { return &readFileInput{} },
+			Handler:     s.executeReadFile,
+			Permissions: []ToolPermission{ToolPermissionReadFiles},
+			ReadOnly:    true,
+		},
+		{
+			Name:        "list_files",
+			Description: "List the files in the project with their functional group and a short description. Use this to find out what exists before reading or changing files.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"glob": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional path filter, e.g. '*.css', 'src/**/*.js'. Patterns without '/' match the file name.",
+					},
+					"functional_group": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional functional group filter, e.g. 'User Interface'",
+					},
+				},
+			},
+			NewInput:    func() ToolInput { return &listFilesInput{} },
+			Handler:     s.executeListFiles,
+			Permissions: []ToolPermission{ToolPermissionReadFiles},
+			ReadOnly:    true,
+		},
+		{
+			Name:        "search_files",
+			Description: "Search file contents and return matching lines as path:line: text. Use this to find where something is defined or used.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":        "string",
+						"description": "Text to search for (literal unless regex is true)",
+					},
+					"regex": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Treat query as a regular expression (RE2 syntax)",
+					},
+					"ignore_case": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Match case-insensitively",
+					},
+					"glob": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional path filter, e.g. '*.html'",
+					},
+					"context_lines": map[string]interface{}{
+						"type":        "integer",
+						"description": "Lines of context to show around each match (0-5)",
+					},
+				},
+				"required": []string{"query"},
+			},
+			NewInput:    func() ToolInput { return &searchFilesInput{} },
+			Handler:     s.executeSearchFiles,
+			Permissions: []ToolPermission{ToolPermissionReadFiles},
+			ReadOnly:    true,
+		},
+		{
+			Name:        "file_outline",
+			Description: "Show a file's structure with line numbers: headings and landmarks for HTML and Markdown, selectors for CSS, top-level functions, classes and variables for code, keys for JSON.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File path relative to project root",
+					},
+				},
+				"required": []string{"path"},
+			},
+			NewInput:    func() ToolInput { return &fileOutlineInput{} },
+			Handler:     s.executeFileOutline,
+			Permissions: []ToolPermission{ToolPermissionReadFiles},
+			ReadOnly:    true,
+		},
+		{
+			Name:        "delete_file",
+			Description: "Delete a file from the project. Use this to remove obsolete drafts or files that are no longer referenced.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File path relative to project root",
+					},
+				},
+				"required": []string{"path"},
+			},
+			NewInput:    func() ToolInput { return &deleteFileInput{} },
+			Handler:     s.executeDeleteFile,
+			Permissions: []ToolPermission{ToolPermissionReadFiles, ToolPermissionWriteFiles},
+		},
+		{
+			Name:        "move_file",
+			Description: "Move or rename a project file, keeping its content and history. Update references to the old path afterwards.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "Current file path relative to project root",
+					},
+					"new_path": map[string]interface{}{
+						"type":        "string",
+						"description": "New file path relative to project root",
+					},
+				},
+				"required": []string{"path", "new_path"},
+			},
+			NewInput:    func() ToolInput { return &moveFileInput{} },
+			Handler:     s.executeMoveFile,
+			Permissions: []ToolPermission{ToolPermissionReadFiles, ToolPermissionWriteFiles},
+		},
+	}
+}
+
+// writeFileInput is the input of write_file.
+type writeFileInput struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+func (in *writeFileInput) Validate() error {
+	if in.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+// editFileInput is the input of edit_file: either search/replace edits or a unified diff.
+type editFileInput struct {
+	Path  string `json:"path"`
+	Edits []struct {
+		Search  *string `json:"search"`
+		Replace string  `json:"replace"`
+	} `json:"edits"`
+	Patch string `json:"patch"`
+}
+
+func (in *editFileInput) Validate() error {
+	if in.Path == "" {
+		return errors.New("path is required")
+	}
+	if (in.Patch == "") == (len(in.Edits) == 0) {
+		return errors.New("provide either edits or patch")
+	}
+	for i, edit := range in.Edits {
+		if edit.Search == nil {
+			return fmt.Errorf("edit %d: search is required", i+1)
+		}
+	}
+	return nil
+}
+
+// readFileInput is the input of read_file.
+type readFileInput struct {
+	Path string `json:"path"`
+}
+
+func (in *readFileInput) Validate() error {
+	if in.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+// deleteFileInput is the input of delete_file.
+type deleteFileInput struct {
+	Path string `json:"path"`
+}
+
+func (in *deleteFileInput) Validate() error {
+	if in.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+// moveFileInput is the input of move_file.
+type moveFileInput struct {
+	Path    string `json:"path"`
+	NewPath string `json:"new_path"`
+}
+
+func (in *moveFileInput) Validate() error {
+	if in.Path == "" || in.NewPath == "" {
+		return errors.New("path and new_path are required")
+	}
+	return nil
+}
+
+// executeWriteFile creates or overwrites a project file.
+func (s *ChatService) executeWriteFile(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*writeFileInput)
+
+	// Infer language from file extension
+	language := inferLanguageFromPath(in.Path)
+
+	s.trackFileBefore(ctx, turn, in.Path)
+	file := turn.files.SaveFile(ctx, in.Path, language, in.Content, model.FileVersionSourceTool)
+	execResult.Result.Content = fmt.Sprintf("File written successfully: %s", in.Path)
+	execResult.CreatedFile = in.Path
+	s.logger.Info().
+		Str("path", in.Path).
+		Str("projectId", turn.projectID.String()).
+		Msg("wrote file via tool")
+
+	turn.changes.record(in.Path, &file.Content)
+	return execResult
+}
+
+// executeReadFile returns the content of a project file.
+func (s *ChatService) executeReadFile(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*readFileInput)
+
+	file, err := turn.files.GetFileByPath(ctx, in.Path)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("path", in.Path).
+			Str("projectId", turn.projectID.String()).
+			Msg("failed to read file via tool")
+		execResult.Result.Content = fmt.Sprintf("Error reading file: %v", err)
+		execResult.Result.IsError = true
+		return execResult
+	}
+
+	execResult.Result.Content = file.Content
+	s.logger.Debug().
+		Str("path", in.Path).
+		Str("projectId", turn.projectID.String()).
+		Int("contentLength", len(file.Content)).
+		Msg("read file via tool")
+	return execResult
+}
+
+// executeDeleteFile removes a project file. The turn's change set records it as deleted
+// with its last content, so the deletion can be reviewed and undone from history.
+func (s *ChatService) executeDeleteFile(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	filePath := input.(*deleteFileInput).Path
+
+	s.trackFileBefore(ctx, turn, filePath)
+	err := turn.files.DeleteFile(ctx, filePath)
+	if errors.Is(err, repository.ErrNotFound) {
+		return toolError(execResult, "file not found: %s", filePath)
+	}
+	if err != nil {
+		return toolError(execResult, "deleting file: %v", err)
+	}
+
+	execResult.Result.Content = fmt.Sprintf("File deleted: %s", filePath)
+	execResult.DeletedFile = filePath
+	s.logger.Info().
+		Str("path", filePath).
+		Str("projectId", turn.projectID.String()).
+		Msg("deleted file via tool")
+
+	turn.changes.record(filePath, nil)
+	return execResult
+}
+
+// executeMoveFile renames a project file, keeping its metadata and history. The turn's
+// change set records the old path as deleted and the new path as added.
+func (s *ChatService) executeMoveFile(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*moveFileInput)
+	from := in.Path
+
+	to, err := CleanFilePath(in.NewPath)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	if to == from {
+		return toolError(execResult, "new_path is the same as path")
+	}
+
+	s.trackFileBefore(ctx, turn, from)
+	s.trackFileBefore(ctx, turn, to)
+	moved, err := turn.files.MoveFile(ctx, from, to)
+	if errors.Is(err, repository.ErrNotFound) {
+		return toolError(execResult, "file not found: %s", from)
+	}
+	if errors.Is(err, repository.ErrPathExists) {
+		return toolError(execResult, "%s already exists; delete_file it first or choose another new_path", to)
+	}
+	if err != nil {
+		return toolError(execResult, "moving file: %v", err)
+	}
+
+	execResult.Result.Content = fmt.Sprintf("File moved: %s -> %s (update any references to the old path)", from, to)
+	execResult.CreatedFile = to
+	execResult.DeletedFile = from
+	s.logger.Info().
+		Str("path", from).
+		Str("newPath", to).
+		Str("projectId", turn.projectID.String()).
+		Msg("moved file via tool")
+
+	turn.changes.record(from, nil)
+	turn.changes.record(to, &moved.Content)
+	return execResult
+}
+
+// CleanFilePath normalizes a project-relative file path and rejects paths that
+// are absolute, escape the project, or name a directory.
+func CleanFilePath(filePath string) (string, error) {
+	cleaned := path.Clean(strings.TrimSpace(filePath))
+	switch {
+	case cleaned == "." || cleaned == "" || strings.HasSuffix(filePath, "/"):
+		return "", fmt.Errorf("invalid path %q: must name a file", filePath)
+	case strings.HasPrefix(cleaned, "/"):
+		return "", fmt.Errorf("invalid path %q: must be relative to the project root", filePath)
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "", fmt.Errorf("invalid path %q: must stay inside the project", filePath)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func TestChatService_DeleteFile(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+
+	result := runTool(s, projectID, "delete_file", map[string]interface{}{"path": "index.html"})
+
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "File deleted: index.html", result.Result.Content)
+	assert.Equal(t, "index.html", result.DeletedFile)
+	assert.Empty(t, result.CreatedFile)
+	_, err := fileRepo.GetFileByPath(context.Background(), projectID, "index.html")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	result = runTool(s, projectID, "delete_file", map[string]interface{}{"path": "index.html"})
+	assert.True(t, result.Result.IsError)
+	assert.Equal(t, "Error: file not found: index.html", result.Result.Content)
+}
+
+func TestChatService_MoveFile(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+	ctx := context.Background()
+	original, _ := fileRepo.GetFileByPath(ctx, projectID, "index.html")
+
+	result := runTool(s, projectID, "move_file", map[string]interface{}{"path": "index.html", "new_path": "./pages/home.html"})
+
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "pages/home.html", result.CreatedFile)
+	assert.Equal(t, "index.html", result.DeletedFile)
+	moved, err := fileRepo.GetFileByPath(ctx, projectID, "pages/home.html")
+	require.NoError(t, err)
+	assert.Equal(t, original.ID, moved.ID)
+	assert.Equal(t, editTestPage, moved.Content)
+	_, err = fileRepo.GetFileByPath(ctx, projectID, "index.html")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestChatService_MoveFile_Errors(t *testing.T) {
+	s, fileRepo, projectID := newEditTestChatService(t)
+	_, err := fileRepo.SaveFile(context.Background(), projectID, "about.html", "html", "<p>About</p>")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+	}{
+		{"missing new_path", map[string]interface{}{"path": "index.html"}, "Error: path and new_path are required"},
+		{"target exists", map[string]interface{}{"path": "index.html", "new_path": "about.html"}, "Error: about.html already exists; delete_file it first or choose another new_path"},
+		{"same path", map[string]interface{}{"path": "index.html", "new_path": "index.html"}, "Error: new_path is the same as path"},
+		{"escapes project", map[string]interface{}{"path": "index.html", "new_path": "../index.html"}, `Error: invalid path "../index.html": must stay inside the project`},
+		{"missing file", map[string]interface{}{"path": "contact.html", "new_path": "pages/contact.html"}, "Error: file not found: contact.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runTool(s, projectID, "move_file", tt.input)
+			assert.True(t, result.Result.IsError)
+			assert.Equal(t, tt.expected, result.Result.Content)
+		})
+	}
+}
+
+func TestChatService_ProcessMessage_DeleteAndMoveChangeSet(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_del", "delete_file", map[string]interface{}{"path": "index-old.html"}),
+		toolUseTurnEvents("toolu_mv", "move_file", map[string]interface{}{"path": "index.html", "new_path": "home.html"}),
+		textTurnEvents("Cleaned up."),
+	)
+	defer server.Close()
+
+	s, fileRepo, projectID := newEditTestChatService(t)
+	ctx := context.Background()
+	_, err := fileRepo.SaveFile(ctx, projectID, "index-old.html", "html", "<p>old</p>\n")
+	require.NoError(t, err)
+	s.claudeService = NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, zerolog.Nop())
+	s.SetChangeSets(NewChangeSetService(repository.NewMockFileChangeRepository(), s.repo, zerolog.Nop()))
+
+	var created, deleted []string
+	result, err := s.ProcessMessageWithCallbacks(ctx, projectID, "Remove the old page and rename index", ChatCallbacks{
+		OnFileCreated: func(path string) { created = append(created, path) },
+		OnFileDeleted: func(path string) { deleted = append(deleted, path) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"home.html"}, created)
+	assert.Equal(t, []string{"index-old.html", "index.html"}, deleted)
+	require.NotNil(t, result.Changes)
+	assert.Equal(t, []model.FileChangeStat{{Path: "home.html", Additions: 6}}, result.Changes.Added)
+	assert.Equal(t, []model.FileChangeStat{{Path: "index-old.html", Deletions: 1}, {Path: "index.html", Deletions: 6}}, result.Changes.Deleted)
+}
+
+func TestCleanFilePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"pages/about.html", "pages/about.html", false},
+		{" ./css//main.css ", "css/main.css", false},
+		{"a/../b.js", "b.js", false},
+		{"/etc/passwd", "", true},
+		{"../outside.txt", "", true},
+		{"pages/", "", true},
+		{".", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := CleanFilePath(tt.input)
+		if tt.wantErr {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, got)
+	}
+}
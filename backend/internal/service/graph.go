@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// GraphService builds the dependency graph of a project from the file references that
+// the completeness checker extracts.
+type GraphService struct {
+	checker      *CompletenessChecker
+	fileRepo     repository.FileRepository
+	metadataRepo repository.FileMetadataRepository
+	logger       zerolog.Logger
+}
+
+// NewGraphService creates a new GraphService.
+func NewGraphService(checker *CompletenessChecker, fileRepo repository.FileRepository, metadataRepo repository.FileMetadataRepository, logger zerolog.Logger) *GraphService {
+	return &GraphService{
+		checker:      checker,
+		fileRepo:     fileRepo,
+		metadataRepo: metadataRepo,
+		logger:       logger.With().Str("component", "graph_service").Logger(),
+	}
+}
+
+// Build returns the dependency graph of a project. Nodes are its files with their App Map
+// metadata, sorted by path; edges are the references between them. References to missing
+// files are left out, as the completeness report covers those.
+func (s *GraphService) Build(ctx context.Context, projectID uuid.UUID) (*model.ProjectGraph, error) {
+	files, err := s.fileRepo.GetFilesWithContentByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := s.metadataRepo.GetFilesWithMetadata(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	metadataByID := make(map[uuid.UUID]model.FileWithMetadata, len(metadata))
+	for _, m := range metadata {
+		metadataByID[m.ID] = m
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	graph := &model.ProjectGraph{
+		ProjectID: projectID,
+		Nodes:     make([]model.GraphNode, 0, len(files)),
+		Edges:     []model.GraphEdge{},
+		Orphans:   []string{},
+		Cycles:    [][]string{},
+	}
+
+	// Reference targets are project paths without a leading slash
+	pathOf := make(map[string]string, len(files))
+	dirFiles := make(map[string][]string) // Directory with a trailing slash -> paths of its files
+	for _, f := range files {
+		target := strings.TrimPrefix(f.Path, "/")
+		pathOf[target] = f.Path
+		if dir := path.Dir(target); dir != "." {
+			dirFiles[dir+"/"] = append(dirFiles[dir+"/"], f.Path)
+		}
+
+		meta := metadataByID[f.ID]
+		graph.Nodes = append(graph.Nodes, model.GraphNode{
+			ID:               f.ID,
+			Path:             f.Path,
+			Filename:         f.Filename,
+			Language:         f.Language,
+			FunctionalGroup:  meta.FunctionalGroup,
+			ShortDescription: meta.ShortDescription,
+		})
+	}
+
+	// A file referencing another several times gets one edge, for its first reference
+	seen := make(map[string]bool)
+	_, references := s.checker.references(files)
+	for _, r := range references {
+		for _, to := range referencedFiles(r.ref.Targets, pathOf, dirFiles) {
+			key := r.file.Path + "\x00" + to
+			if to == r.file.Path || seen[key] {
+				continue
+			}
+			seen[key] = true
+			graph.Edges = append(graph.Edges, model.GraphEdge{From: r.file.Path, To: to, Type: r.ref.Type, Line: r.ref.Line})
+		}
+	}
+
+	connected := make(map[string]bool)
+	for _, e := range graph.Edges {
+		connected[e.From] = true
+		connected[e.To] = true
+	}
+	for _, n := range graph.Nodes {
+		if !connected[n.Path] {
+			graph.Orphans = append(graph.Orphans, n.Path)
+		}
+	}
+
+	graph.Cycles = findCycles(graph)
+
+	s.logger.Debug().
+		Str("projectId", projectID.String()).
+		Int("nodes", len(graph.Nodes)).
+		Int("edges", len(graph.Edges)).
+		Int("cycles", len(graph.Cycles)).
+		Msg("built project graph")
+
+	return graph, nil
+}
+
+// referencedFiles returns the files a reference points to: the first of its targets that
+// exists, or for a directory target (a Go package), every file directly in the directory.
+func referencedFiles(targets []string, pathOf map[string]string, dirFiles map[string][]string) []string {
+	for _, target := range targets {
+		if strings.HasSuffix(target, "/") {
+			if files := dirFiles[target]; len(files) > 0 {
+				return files
+			}
+		} else if p, ok := pathOf[target]; ok {
+			return []string{p}
+		}
+	}
+	return nil
+}
+
+// findCycles returns one cycle through each group of files that reference each other
+// (each strongly connected component of more than one file), found with Tarjan's
+// algorithm. Each cycle is a shortest one starting at the group's smallest path.
+func findCycles(graph *model.ProjectGraph) [][]string {
+	adjacent := make(map[string][]string)
+	for _, e := range graph.Edges {
+		adjacent[e.From] = append(adjacent[e.From], e.To)
+	}
+
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(node string)
+	visit = func(node string) {
+		index[node] = len(index)
+		lowLink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, next := range adjacent[node] {
+			if _, visited := index[next]; !visited {
+				visit(next)
+				lowLink[node] = min(lowLink[node], lowLink[next])
+			} else if onStack[next] {
+				lowLink[node] = min(lowLink[node], index[next])
+			}
+		}
+
+		if lowLink[node] == index[node] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			if len(component) > 1 {
+				components = append(components, component)
+			}
+		}
+	}
+
+	for _, n := range graph.Nodes {
+		if _, visited := index[n.Path]; !visited {
+			visit(n.Path)
+		}
+	}
+
+	cycles := [][]string{}
+	for _, component := range components {
+		sort.Strings(component)
+		cycles = append(cycles, shortestCycle(component, adjacent))
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// shortestCycle returns the shortest cycle from the first file of a strongly connected
+// component back to itself, staying inside the component.
+func shortestCycle(component []string, adjacent map[string][]string) []string {
+	start := component[0]
+	inComponent := make(map[string]bool, len(component))
+	for _, n := range component {
+		inComponent[n] = true
+	}
+
+	parent := map[string]string{start: ""}
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacent[node] {
+			if next == start {
+				var cycle []string
+				for n := node; n != ""; n = parent[n] {
+					cycle = append([]string{n}, cycle...)
+				}
+				return cycle
+			}
+			if _, seen := parent[next]; !seen && inComponent[next] {
+				parent[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+	return component // Unreachable for a strongly connected component
+}
+
+// FormatGraphDOT renders a project graph in the Graphviz DOT language. Files are boxes
+// labeled with their short description and clustered by functional group; orphans are
+// dashed and references that form cycles are red.
+func FormatGraphDOT(graph *model.ProjectGraph) string {
+	orphans, cycleEdges := graphHighlights(graph)
+
+	var b strings.Builder
+	b.WriteString("digraph project {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	writeNode := func(indent string, n model.GraphNode) {
+		label := n.Path
+		if n.ShortDescription != "" {
+			label += "\n" + n.ShortDescription
+		}
+		fmt.Fprintf(&b, "%s%s [label=%s", indent, dotQuote(n.Path), dotQuote(label))
+		if orphans[n.Path] {
+			b.WriteString(", style=dashed")
+		}
+		b.WriteString("];\n")
+	}
+
+	groups, ungrouped := groupNodes(graph.Nodes)
+	for _, n := range ungrouped {
+		writeNode("  ", n)
+	}
+	for i, group := range groups {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(group.name))
+		for _, n := range group.nodes {
+			writeNode("    ", n)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Type))
+		if cycleEdges[e.From+"\x00"+e.To] {
+			b.WriteString(", color=red")
+		}
+		b.WriteString("];\n")
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// FormatGraphMermaid renders a project graph as a Mermaid flowchart, with files grouped
+// into subgraphs by functional group. Orphans are dashed and references that form
+// cycles are red.
+func FormatGraphMermaid(graph *model.ProjectGraph) string {
+	orphans, cycleEdges := graphHighlights(graph)
+
+	// Mermaid IDs can't hold paths, so nodes are numbered in path order
+	ids := make(map[string]string, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		ids[n.Path] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	writeNode := func(indent string, n model.GraphNode) {
+		label := n.Path
+		if n.ShortDescription != "" {
+			label += "<br/>" + n.ShortDescription
+		}
+		fmt.Fprintf(&b, "%s%s[%s]\n", indent, ids[n.Path], mermaidQuote(label))
+	}
+
+	groups, ungrouped := groupNodes(graph.Nodes)
+	for _, n := range ungrouped {
+		writeNode("  ", n)
+	}
+	for i, group := range groups {
+		fmt.Fprintf(&b, "  subgraph g%d[%s]\n", i, mermaidQuote(group.name))
+		for _, n := range group.nodes {
+			writeNode("    ", n)
+		}
+		b.WriteString("  end\n")
+	}
+
+	for i, e := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], e.Type, ids[e.To])
+		if cycleEdges[e.From+"\x00"+e.To] {
+			fmt.Fprintf(&b, "  linkStyle %d stroke:red\n", i)
+		}
+	}
+
+	for _, n := range graph.Nodes {
+		if orphans[n.Path] {
+			fmt.Fprintf(&b, "  style %s stroke-dasharray: 5 5\n", ids[n.Path])
+		}
+	}
+	return b.String()
+}
+
+// nodeGroup is the files of a functional group.
+type nodeGroup struct {
+	name  string
+	nodes []model.GraphNode
+}
+
+// groupNodes splits nodes by functional group, sorted by name, and returns the nodes
+// without a group separately.
+func groupNodes(nodes []model.GraphNode) ([]nodeGroup, []model.GraphNode) {
+	var ungrouped []model.GraphNode
+	indexes := make(map[string]int)
+	var groups []nodeGroup
+	for _, n := range nodes {
+		if n.FunctionalGroup == "" {
+			ungrouped = append(ungrouped, n)
+			continue
+		}
+		i, ok := indexes[n.FunctionalGroup]
+		if !ok {
+			i = len(groups)
+			indexes[n.FunctionalGroup] = i
+			groups = append(groups, nodeGroup{name: n.FunctionalGroup})
+		}
+		groups[i].nodes = append(groups[i].nodes, n)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	return groups, ungrouped
+}
+
+// graphHighlights returns the orphans of a graph and its edges that are part of a cycle,
+// keyed by from + "\x00" + to.
+func graphHighlights(graph *model.ProjectGraph) (map[string]bool, map[string]bool) {
+	orphans := make(map[string]bool, len(graph.Orphans))
+	for _, p := range graph.Orphans {
+		orphans[p] = true
+	}
+	cycleEdges := make(map[string]bool)
+	for _, cycle := range graph.Cycles {
+		for i, from := range cycle {
+			cycleEdges[from+"\x00"+cycle[(i+1)%len(cycle)]] = true
+		}
+	}
+	return orphans, cycleEdges
+}
+
+// dotQuote quotes a string as a DOT ID, with newlines as label line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidQuote quotes a string as a Mermaid label.
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return `"` + s + `"`
+}
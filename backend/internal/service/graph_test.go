@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// setupGraphTest saves files (path to content) in a new project and returns a GraphService over them.
+func setupGraphTest(t *testing.T, files map[string]string) (*GraphService, *repository.MockFileMetadataRepository, map[string]*model.File, uuid.UUID) {
+	ctx := context.Background()
+	fileRepo := repository.NewMockFileRepository()
+	metadataRepo := repository.NewMockFileMetadataRepository()
+	projectID := uuid.New()
+
+	saved := make(map[string]*model.File)
+	for path, content := range files {
+		file, err := fileRepo.SaveFile(ctx, projectID, path, "", content)
+		require.NoError(t, err)
+		metadataRepo.AddFile(file)
+		saved[path] = file
+	}
+
+	checker := NewCompletenessChecker(fileRepo, zerolog.Nop())
+	return NewGraphService(checker, fileRepo, metadataRepo, zerolog.Nop()), metadataRepo, saved, projectID
+}
+
+func TestGraphService_Build(t *testing.T) {
+	ctx := context.Background()
+	svc, metadataRepo, files, projectID := setupGraphTest(t, map[string]string{
+		"index.html":    `<link rel="stylesheet" href="style.css"><script src="app.js"></script><img src="missing.png">`,
+		"style.css":     "body { margin: 0; }",
+		"app.js":        "import { cart } from './cart.js';\nimport { format } from './format.js';\nimport './cart.js';",
+		"cart.js":       "import { format } from './format.js';",
+		"format.js":     "export function format() {}",
+		"notes.txt":     "todo",
+		"cmd/main.go":   "package main\n\nimport \"example.com/shop/store\"\n",
+		"store/a.go":    "package store",
+		"store/b.go":    "package store",
+		"go.mod":        "module example.com/shop\n",
+		"docs/guide.md": "",
+	})
+	_, err := metadataRepo.Upsert(ctx, files["app.js"].ID, "Starts the shop", "", "Logic")
+	require.NoError(t, err)
+
+	graph, err := svc.Build(ctx, projectID)
+	require.NoError(t, err)
+
+	require.Len(t, graph.Nodes, 11)
+	assert.Equal(t, "app.js", graph.Nodes[0].Path)
+	assert.Equal(t, files["app.js"].ID, graph.Nodes[0].ID)
+	assert.Equal(t, "Logic", graph.Nodes[0].FunctionalGroup)
+	assert.Equal(t, "Starts the shop", graph.Nodes[0].ShortDescription)
+
+	assert.Equal(t, []model.GraphEdge{
+		{From: "app.js", To: "cart.js", Type: "import", Line: 1},
+		{From: "app.js", To: "format.js", Type: "import", Line: 2},
+		{From: "cart.js", To: "format.js", Type: "import", Line: 1},
+		{From: "cmd/main.go", To: "store/a.go", Type: "import", Line: 3},
+		{From: "cmd/main.go", To: "store/b.go", Type: "import", Line: 3},
+		{From: "index.html", To: "style.css", Type: "stylesheet", Line: 1},
+		{From: "index.html", To: "app.js", Type: "script", Line: 1},
+	}, graph.Edges)
+
+	assert.Equal(t, []string{"docs/guide.md", "go.mod", "notes.txt"}, graph.Orphans)
+	assert.Empty(t, graph.Cycles)
+}
+
+func TestGraphService_BuildFindsCycles(t *testing.T) {
+	svc, _, _, projectID := setupGraphTest(t, map[string]string{
+		"a.js": "import './b.js';",
+		"b.js": "import './c.js';\nimport './a.js';",
+		"c.js": "import './b.js';",
+		"d.js": "import './e.js';",
+		"e.js": "import './d.js';",
+		"f.js": "import './a.js';",
+	})
+
+	graph, err := svc.Build(context.Background(), projectID)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{{"a.js", "b.js"}, {"d.js", "e.js"}}, graph.Cycles)
+	assert.Empty(t, graph.Orphans)
+}
+
+func TestFormatGraph(t *testing.T) {
+	graph := &model.ProjectGraph{
+		Nodes: []model.GraphNode{
+			{Path: "a.js", FunctionalGroup: "Logic", ShortDescription: `Says "hi"`},
+			{Path: "b.js", FunctionalGroup: "Logic"},
+			{Path: "index.html"},
+			{Path: "notes.txt"},
+		},
+		Edges: []model.GraphEdge{
+			{From: "a.js", To: "b.js", Type: "import", Line: 1},
+			{From: "b.js", To: "a.js", Type: "import", Line: 1},
+			{From: "index.html", To: "a.js", Type: "script", Line: 3},
+		},
+		Orphans: []string{"notes.txt"},
+		Cycles:  [][]string{{"a.js", "b.js"}},
+	}
+
+	assert.Equal(t, `digraph project {
+  rankdir=LR;
+  node [shape=box];
+  "index.html" [label="index.html"];
+  "notes.txt" [label="notes.txt", style=dashed];
+  subgraph cluster_0 {
+    label="Logic";
+    "a.js" [label="a.js\nSays \"hi\""];
+    "b.js" [label="b.js"];
+  }
+  "a.js" -> "b.js" [label="import", color=red];
+  "b.js" -> "a.js" [label="import", color=red];
+  "index.html" -> "a.js" [label="script"];
+}
+`, FormatGraphDOT(graph))
+
+	assert.Equal(t, `flowchart LR
+  n2["index.html"]
+  n3["notes.txt"]
+  subgraph g0["Logic"]
+    n0["a.js<br/>Says #quot;hi#quot;"]
+    n1["b.js"]
+  end
+  n0 -->|import| n1
+  linkStyle 0 stroke:red
+  n1 -->|import| n0
+  linkStyle 1 stroke:red
+  n2 -->|script| n0
+  style n3 stroke-dasharray: 5 5
+`, FormatGraphMermaid(graph))
+}
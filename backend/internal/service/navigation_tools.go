@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/outline"
+)
+
+// Limits on navigation tool output, so a large project can't flood the context.
+const (
+	maxListedFiles     = 300
+	maxSearchMatches   = 100
+	maxSearchContext   = 5
+	maxSearchLineChars = 200
+)
+
+// listFilesInput is the input of list_files.
+type listFilesInput struct {
+	Glob            string `json:"glob"`
+	FunctionalGroup string `json:"functional_group"`
+}
+
+func (in *listFilesInput) Validate() error {
+	return nil
+}
+
+// searchFilesInput is the input of search_files.
+type searchFilesInput struct {
+	Query        string `json:"query"`
+	Regex        bool   `json:"regex"`
+	IgnoreCase   bool   `json:"ignore_case"`
+	Glob         string `json:"glob"`
+	ContextLines int    `json:"context_lines"`
+}
+
+func (in *searchFilesInput) Validate() error {
+	if in.Query == "" {
+		return errors.New("query is required")
+	}
+	return nil
+}
+
+// fileOutlineInput is the input of file_outline.
+type fileOutlineInput struct {
+	Path string `json:"path"`
+}
+
+func (in *fileOutlineInput) Validate() error {
+	if in.Path == "" {
+		return errors.New("path is required")
+	}
+	return nil
+}
+
+// executeListFiles lists project files with their functional group and short description,
+// optionally filtered by a glob on the path and by functional group.
+func (s *ChatService) executeListFiles(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*listFilesInput)
+	glob, group := in.Glob, in.FunctionalGroup
+
+	files, err := s.listProjectFiles(ctx, turn)
+	if err != nil {
+		return toolError(execResult, "listing files: %v", err)
+	}
+
+	var sb strings.Builder
+	count := 0
+	for _, file := range files {
+		if glob != "" && !matchGlob(glob, file.Path) {
+			continue
+		}
+		if group != "" && !strings.EqualFold(file.FunctionalGroup, group) {
+			continue
+		}
+		count++
+		if count > maxListedFiles {
+			continue
+		}
+
+		sb.WriteString(file.Path)
+		if file.FunctionalGroup != "" {
+			fmt.Fprintf(&sb, " [%s]", file.FunctionalGroup)
+		}
+		if file.ShortDescription != "" {
+			fmt.Fprintf(&sb, " - %s", file.ShortDescription)
+		}
+		sb.WriteByte('\n')
+	}
+
+	switch {
+	case count == 0 && len(files) == 0:
+		execResult.Result.Content = "The project has no files yet."
+	case count == 0:
+		execResult.Result.Content = fmt.Sprintf("No files match (the project has %d files).", len(files))
+	case count > maxListedFiles:
+		execResult.Result.Content = fmt.Sprintf("%d files (showing the first %d; narrow with glob or functional_group):\n%s", count, maxListedFiles, sb.String())
+	default:
+		execResult.Result.Content = fmt.Sprintf("%d files:\n%s", count, sb.String())
+	}
+	return execResult
+}
+
+// listProjectFiles returns the project's files as the turn sees them, sorted by path,
+// with metadata when available.
+func (s *ChatService) listProjectFiles(ctx context.Context, turn *chatTurn) ([]model.FileWithMetadata, error) {
+	items, err := turn.files.GetFilesByProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Metadata is keyed by file ID, which a moved file keeps
+	metadata := make(map[uuid.UUID]model.FileWithMetadata)
+	if s.fileMetadataRepo != nil {
+		withMetadata, err := s.fileMetadataRepo.GetFilesWithMetadata(ctx, turn.projectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range withMetadata {
+			metadata[file.ID] = file
+		}
+	}
+
+	files := make([]model.FileWithMetadata, len(items))
+	for i, item := range items {
+		meta := metadata[item.ID]
+		files[i] = model.FileWithMetadata{
+			ID:               item.ID,
+			ProjectID:        turn.projectID,
+			Path:             item.Path,
+			Filename:         item.Filename,
+			Language:         item.Language,
+			ShortDescription: meta.ShortDescription,
+			LongDescription:  meta.LongDescription,
+			FunctionalGroup:  meta.FunctionalGroup,
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// executeSearchFiles greps file contents for a literal string or regular expression and
+// returns grep-style "path:line: text" matches with optional context lines.
+func (s *ChatService) executeSearchFiles(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*searchFilesInput)
+	query, glob, contextLines := in.Query, in.Glob, in.ContextLines
+
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	if contextLines > maxSearchContext {
+		contextLines = maxSearchContext
+	}
+
+	expr := query
+	if !in.Regex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if in.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return toolError(execResult, "invalid regular expression: %v", err)
+	}
+
+	files, err := turn.files.GetFilesWithContentByProject(ctx)
+	if err != nil {
+		return toolError(execResult, "searching files: %v", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	var sb strings.Builder
+	matches, matchedFiles := 0, 0
+	for _, file := range files {
+		if glob != "" && !matchGlob(glob, file.Path) {
+			continue
+		}
+
+		lines := strings.Split(file.Content, "\n")
+		lastPrinted := -1 // Index of the last line written for this file
+		fileMatched := false
+		for i, line := range lines {
+			if !pattern.MatchString(line) {
+				continue
+			}
+			matches++
+			if matches > maxSearchMatches {
+				continue
+			}
+			if !fileMatched {
+				fileMatched = true
+				matchedFiles++
+			}
+
+			from := i - contextLines
+			if from <= lastPrinted {
+				from = lastPrinted + 1
+			} else if contextLines > 0 && sb.Len() > 0 {
+				sb.WriteString("--\n")
+			}
+			to := i + contextLines
+			if to >= len(lines) {
+				to = len(lines) - 1
+			}
+			for j := from; j <= to; j++ {
+				sep := "-"
+				if pattern.MatchString(lines[j]) {
+					sep = ":"
+				}
+				fmt.Fprintf(&sb, "%s%s%d%s %s\n", file.Path, sep, j+1, sep, truncateLine(lines[j]))
+			}
+			lastPrinted = to
+		}
+	}
+
+	switch {
+	case matches == 0:
+		execResult.Result.Content = fmt.Sprintf("No matches for %q.", query)
+	case matches > maxSearchMatches:
+		execResult.Result.Content = fmt.Sprintf("%d matches (showing the first %d; narrow the query or glob):\n%s", matches, maxSearchMatches, sb.String())
+	default:
+		execResult.Result.Content = fmt.Sprintf("%d matches in %d files:\n%s", matches, matchedFiles, sb.String())
+	}
+	return execResult
+}
+
+// executeFileOutline returns a file's top-level symbols or headings with line numbers.
+func (s *ChatService) executeFileOutline(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	filePath := input.(*fileOutlineInput).Path
+
+	file, err := turn.files.GetFileByPath(ctx, filePath)
+	if err != nil {
+		return toolError(execResult, "reading file: %v", err)
+	}
+
+	language := file.Language
+	if language == "" {
+		language = inferLanguageFromPath(filePath)
+	}
+	lineCount := len(strings.Split(strings.TrimSuffix(file.Content, "\n"), "\n"))
+
+	symbols := outline.Extract(language, file.Content)
+	if len(symbols) == 0 {
+		execResult.Result.Content = fmt.Sprintf("%s (%s, %d lines): no outline available; use read_file", filePath, language, lineCount)
+		return execResult
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s, %d lines):\n", filePath, language, lineCount)
+	for _, symbol := range symbols {
+		indent := ""
+		if symbol.Level > 1 {
+			indent = strings.Repeat("  ", symbol.Level-1)
+		}
+		fmt.Fprintf(&sb, "%5d  %s%s %s\n", symbol.Line, indent, symbol.Kind, symbol.Name)
+	}
+	execResult.Result.Content = sb.String()
+	return execResult
+}
+
+// toolError marks a tool result as failed with a formatted message.
+func toolError(execResult ToolExecutionResult, format string, args ...interface{}) ToolExecutionResult {
+	execResult.Result.Content = "Error: " + fmt.Sprintf(format, args...)
+	execResult.Result.IsError = true
+	return execResult
+}
+
+// matchGlob matches a slash-separated path against a glob pattern. "**" matches any
+// number of directories, and a pattern without "/" is matched against the file name
+// alone, so "*.css" finds stylesheets anywhere.
+func matchGlob(pattern, filePath string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(filePath))
+		return ok
+	}
+	if !strings.Contains(pattern, "**") {
+		ok, _ := path.Match(pattern, filePath)
+		return ok
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	ok, _ := regexp.MatchString(expr.String(), filePath)
+	return ok
+}
+
+// truncateLine shortens long lines (e.g. minified code) in search results.
+func truncateLine(line string) string {
+	if len(line) <= maxSearchLineChars {
+		return line
+	}
+	return line[:maxSearchLineChars] + "..."
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// newNavigationTestChatService returns a chat service with a small project whose files have metadata.
+func newNavigationTestChatService(t *testing.T) (*ChatService, uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	metadataRepo := repository.NewMockFileMetadataRepository()
+	project, _ := repo.Create(ctx, "Navigation Project")
+
+	files := []struct {
+		path, language, content, description, group string
+	}{
+		{"index.html", "html", "<html>\n<body>\n  <nav id=\"top\"></nav>\n  <h1>Orders</h1>\n  <script src=\"js/app.js\"></script>\n</body>\n</html>\n", "Main page", "User Interface"},
+		{"css/styles.css", "css", "body {\n  margin: 0;\n}\n\n.order { color: red; }\n", "Site styles", "User Interface"},
+		{"js/app.js", "javascript", "const API = '/api/orders';\n\nfunction loadOrders() {\n  return fetch(API);\n}\n\nfunction renderOrder(order) {\n  return `<div class=\"order\">${order.name}</div>`;\n}\n", "", "Application Logic"},
+	}
+	for _, f := range files {
+		file, err := fileRepo.SaveFile(ctx, project.ID, f.path, f.language, f.content)
+		require.NoError(t, err)
+		metadataRepo.AddFile(file)
+		if f.description != "" || f.group != "" {
+			_, err = metadataRepo.Upsert(ctx, file.ID, f.description, "", f.group)
+			require.NoError(t, err)
+		}
+	}
+
+	return NewChatService(ChatConfig{}, nil, nil, nil, repo, fileRepo, metadataRepo, zerolog.Nop()), project.ID
+}
+
+func runTool(s *ChatService, projectID uuid.UUID, name string, input map[string]interface{}) ToolExecutionResult {
+	ctx := context.Background()
+	turn := s.newTurn(projectID, nil)
+	result := s.executeTool(ctx, turn, ToolUseBlock{Type: "tool_use", ID: "toolu_nav", Name: name, Input: input})
+	if err := s.commitTurnFiles(ctx, turn, ChatCallbacks{}); err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestChatService_ListFiles(t *testing.T) {
+	s, projectID := newNavigationTestChatService(t)
+
+	result := runTool(s, projectID, "list_files", map[string]interface{}{})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "3 files:\n"+
+		"css/styles.css [User Interface] - Site styles\n"+
+		"index.html [User Interface] - Main page\n"+
+		"js/app.js [Application Logic]\n", result.Result.Content)
+
+	result = runTool(s, projectID, "list_files", map[string]interface{}{"glob": "*.css"})
+	assert.Equal(t, "1 files:\ncss/styles.css [User Interface] - Site styles\n", result.Result.Content)
+
+	result = runTool(s, projectID, "list_files", map[string]interface{}{"functional_group": "application logic"})
+	assert.Equal(t, "1 files:\njs/app.js [Application Logic]\n", result.Result.Content)
+
+	result = runTool(s, projectID, "list_files", map[string]interface{}{"glob": "*.py"})
+	assert.Equal(t, "No files match (the project has 3 files).", result.Result.Content)
+}
+
+func TestChatService_SearchFiles(t *testing.T) {
+	s, projectID := newNavigationTestChatService(t)
+
+	result := runTool(s, projectID, "search_files", map[string]interface{}{"query": "order"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "4 matches in 2 files:\n"+
+		"css/styles.css:5: .order { color: red; }\n"+
+		"js/app.js:1: const API = '/api/orders';\n"+
+		"js/app.js:7: function renderOrder(order) {\n"+
+		"js/app.js:8:   return `<div class=\"order\">${order.name}</div>`;\n", result.Result.Content)
+
+	result = runTool(s, projectID, "search_files", map[string]interface{}{"query": `^function \w+`, "regex": true, "context_lines": float64(1), "glob": "js/**"})
+	assert.Equal(t, "2 matches in 1 files:\n"+
+		"js/app.js-2- \n"+
+		"js/app.js:3: function loadOrders() {\n"+
+		"js/app.js-4-   return fetch(API);\n"+
+		"--\n"+
+		"js/app.js-6- \n"+
+		"js/app.js:7: function renderOrder(order) {\n"+
+		"js/app.js-8-   return `<div class=\"order\">${order.name}</div>`;\n", result.Result.Content)
+
+	result = runTool(s, projectID, "search_files", map[string]interface{}{"query": "ORDERS", "ignore_case": true, "glob": "*.html"})
+	assert.Equal(t, "1 matches in 1 files:\nindex.html:4:   <h1>Orders</h1>\n", result.Result.Content)
+
+	result = runTool(s, projectID, "search_files", map[string]interface{}{"query": "missing"})
+	assert.Equal(t, `No matches for "missing".`, result.Result.Content)
+
+	result = runTool(s, projectID, "search_files", map[string]interface{}{"query": "(", "regex": true})
+	assert.True(t, result.Result.IsError)
+	assert.Contains(t, result.Result.Content, "invalid regular expression")
+}
+
+func TestChatService_FileOutline(t *testing.T) {
+	s, projectID := newNavigationTestChatService(t)
+
+	result := runTool(s, projectID, "file_outline", map[string]interface{}{"path": "js/app.js"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, "js/app.js (javascript, 9 lines):\n"+
+		"    1  variable API\n"+
+		"    3  function loadOrders\n"+
+		"    7  function renderOrder\n", result.Result.Content)
+
+	result = runTool(s, projectID, "file_outline", map[string]interface{}{"path": "index.html"})
+	assert.Contains(t, result.Result.Content, "    3  element nav#top\n")
+	assert.Contains(t, result.Result.Content, "    4  h1 Orders\n")
+
+	result = runTool(s, projectID, "file_outline", map[string]interface{}{"path": "missing.js"})
+	assert.True(t, result.Result.IsError)
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		expected      bool
+	}{
+		{"*.css", "css/styles.css", true},
+		{"*.css", "styles.css", true},
+		{"css/*.css", "css/styles.css", true},
+		{"css/*.css", "css/vendor/reset.css", false},
+		{"css/**/*.css", "css/vendor/reset.css", true},
+		{"css/**/*.css", "css/styles.css", true},
+		{"**/*.js", "app.js", true},
+		{"src/**", "src/a/b/c.ts", true},
+		{"src/**", "lib/a.ts", false},
+		{"index.htm?", "index.html", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, matchGlob(tt.pattern, tt.path), "%s vs %s", tt.pattern, tt.path)
+	}
+}
@@ -0,0 +1,581 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+const (
+	defaultOpenAIBaseURL = "http://localhost:11434/v1" // Ollama's OpenAI-compatible endpoint
+	openAIAPIName        = "OpenAI-compatible API"
+)
+
+// OpenAIConfig holds configuration for an OpenAI-compatible chat completions backend.
+type OpenAIConfig struct {
+	APIKey                string // Optional; local servers usually don't need one
+	Model                 string
+	MaxTokens             int
+	BaseURL               string        // API root, e.g. "http://localhost:8080/v1"; /chat/completions is appended
+	ResponseHeaderTimeout time.Duration // How long to wait for response headers (local models can be slow to start)
+	Retry                 RetryConfig
+	Breaker               BreakerConfig
+	Routes                ModelRoutes // Optional per-purpose and per-agent generation parameters
+}
+
+// openAIRequest is the request body for the chat completions API.
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []openAIMessage      `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+	Tools         []openAITool         `json:"tools,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	Stop          []string             `json:"stop,omitempty"`
+}
+
+// openAIStreamOptions asks the server to report usage in the final stream chunk.
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIMessage is a chat message. Content is a string, a list of content parts (vision),
+// or nil for an assistant message that only calls tools.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is one part of a multimodal message.
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+// openAIImageURL references an image, here always as a data URI.
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAIToolCall is a function call made by the assistant.
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall holds the function name and its JSON-encoded arguments.
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// openAITool is a tool definition in the chat completions format.
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+// openAIFunction describes a callable function.
+type openAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// openAIUsage is the token usage reported by the API.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+// toTokenUsage converts API usage to the model type. Cached prompt tokens are reported as cache reads.
+func (u openAIUsage) toTokenUsage() model.TokenUsage {
+	usage := model.TokenUsage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CacheReadInputTokens = u.PromptTokensDetails.CachedTokens
+		usage.InputTokens -= u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
+// openAIStreamChunk is one streamed chat completion chunk.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"` // Some servers report failures mid-stream
+}
+
+// openAIResponse is a non-streaming chat completion.
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+}
+
+// openAIErrorResponse represents an error from the API.
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// OpenAIService is a Provider for OpenAI-compatible chat completions endpoints,
+// such as OpenAI itself or a local llama.cpp, Ollama or vLLM server.
+type OpenAIService struct {
+	config        OpenAIConfig
+	client        *http.Client
+	retrier       *retrier
+	usageRecorder UsageRecorder
+	logger        zerolog.Logger
+}
+
+// NewOpenAIService creates a new OpenAI-compatible provider.
+func NewOpenAIService(config OpenAIConfig, logger zerolog.Logger) *OpenAIService {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOpenAIBaseURL
+	}
+	if config.ResponseHeaderTimeout <= 0 {
+		config.ResponseHeaderTimeout = 120 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+
+	return &OpenAIService{
+		config:  config,
+		client:  &http.Client{Transport: transport},
+		retrier: newRetrier(openAIAPIName, config.Retry, config.Breaker, logger),
+		logger:  logger.With().Str("provider", ProviderOpenAI).Logger(),
+	}
+}
+
+// Name returns the provider name.
+func (s *OpenAIService) Name() string {
+	return ProviderOpenAI
+}
+
+// UseCassette routes API calls through a cassette, which records them or replays
+// earlier recordings without touching the network.
+func (s *OpenAIService) UseCassette(cassette *Cassette) {
+	s.client.Transport = cassette.Wrap(s.client.Transport)
+}
+
+// SetUsageRecorder sets the recorder that receives the token usage of every API call.
+// This is optional - if not set, usage is only logged.
+func (s *OpenAIService) SetUsageRecorder(recorder UsageRecorder) {
+	s.usageRecorder = recorder
+}
+
+// SendMessage sends messages to the model and returns a streaming response.
+func (s *OpenAIService) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	return s.SendMessageWithToolResults(ctx, systemPrompt, messages, nil, nil)
+}
+
+// SendMessageWithToolResults sends messages including the assistant's tool calls and their results.
+func (s *OpenAIService) SendMessageWithToolResults(
+	ctx context.Context,
+	systemPrompt string,
+	messages []ClaudeMessage,
+	assistantContent []ContentBlock,
+	toolResults []ToolResult,
+) (*ClaudeStream, error) {
+	for _, msg := range messages {
+		if err := msg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid message: %w", err)
+		}
+	}
+
+	ctx, params := s.route(ctx)
+
+	reqBody := openAIRequest{
+		Model:         params.Model,
+		Messages:      buildOpenAIMessages(systemPrompt, messages, assistantContent, toolResults),
+		MaxTokens:     params.MaxTokens,
+		Stream:        true,
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
+		Tools:         toOpenAITools(toolsFrom(ctx)),
+		Temperature:   params.Temperature,
+		Stop:          params.StopSequences,
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	s.logger.Debug().
+		Str("model", params.Model).
+		Int("messageCount", len(reqBody.Messages)).
+		Int("toolResults", len(toolResults)).
+		Msg("sending request to OpenAI-compatible API")
+
+	resp, err := s.post(ctx, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &ClaudeStream{
+		chunks: make(chan string, 100),
+		done:   make(chan struct{}),
+		resp:   resp,
+		model:  params.Model,
+	}
+
+	go s.processStream(ctx, resp, stream)
+
+	return stream, nil
+}
+
+// AnalyzeImage sends an image to a vision-capable model and returns the analysis as text.
+func (s *OpenAIService) AnalyzeImage(ctx context.Context, imageData []byte, mimeType, prompt string) (string, error) {
+	ctx, params := s.route(ctx)
+	dataURI := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData)
+
+	reqBody := openAIRequest{
+		Model: params.Model,
+		Messages: []openAIMessage{
+			{
+				Role: "user",
+				Content: []openAIContentPart{
+					{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURI}},
+					{Type: "text", Text: prompt},
+				},
+			},
+		},
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		Stop:        params.StopSequences,
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal vision request: %w", err)
+	}
+
+	s.logger.Debug().
+		Str("model", params.Model).
+		Str("mimeType", mimeType).
+		Int("imageSize", len(imageData)).
+		Msg("sending vision request to OpenAI-compatible API")
+
+	resp, err := s.post(ctx, jsonBody)
+	if err != nil {
+		return "", fmt.Errorf("vision request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vision response: %w", err)
+	}
+
+	var visionResp openAIResponse
+	if err := json.Unmarshal(body, &visionResp); err != nil {
+		return "", fmt.Errorf("failed to parse vision response: %w", err)
+	}
+
+	if visionResp.Usage != nil {
+		s.recordUsage(ctx, params.Model, visionResp.Usage.toTokenUsage())
+	}
+
+	if len(visionResp.Choices) == 0 {
+		return "", nil
+	}
+	return visionResp.Choices[0].Message.Content, nil
+}
+
+// post sends a request body to the chat completions endpoint, retrying transient failures.
+func (s *OpenAIService) post(ctx context.Context, body []byte) (*http.Response, error) {
+	return s.retrier.do(ctx, func() (*http.Response, error) {
+		return s.doRequest(ctx, body)
+	})
+}
+
+// doRequest makes a single attempt. Non-200 responses are returned as *ClaudeAPIError.
+func (s *OpenAIService) doRequest(ctx context.Context, body []byte) (*http.Response, error) {
+	url := strings.TrimSuffix(s.config.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	// Error types differ between servers, so errors are classified by status code only
+	apiErr := &ClaudeAPIError{
+		API:        openAIAPIName,
+		StatusCode: resp.StatusCode,
+		Message:    string(respBody),
+		RetryAfter: parseRetryAfter(resp.Header.Get("retry-after"), time.Now()),
+	}
+	var errResp openAIErrorResponse
+	if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Message = errResp.Error.Message
+	}
+	return nil, apiErr
+}
+
+// processStream reads streamed chunks, forwarding text and collecting tool calls.
+// The finish reason is normalized to the StopReason constants.
+func (s *OpenAIService) processStream(ctx context.Context, resp *http.Response, stream *ClaudeStream) {
+	defer close(stream.chunks)
+	defer close(stream.done)
+	defer func() { s.recordUsage(ctx, stream.model, stream.Usage()) }()
+
+	scanner := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 64*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	// Tool calls stream their arguments in pieces, keyed by index
+	type toolCallState struct {
+		id        string
+		name      string
+		arguments strings.Builder
+	}
+	var toolCalls []*toolCallState
+	byIndex := make(map[int]*toolCallState)
+	var finishReason string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			s.logger.Warn().Err(err).Str("data", data).Msg("failed to parse stream chunk")
+			continue
+		}
+
+		if chunk.Error != nil {
+			stream.err = &ClaudeAPIError{API: openAIAPIName, Type: "api_error", Message: chunk.Error.Message}
+			break
+		}
+
+		if chunk.Usage != nil {
+			stream.mu.Lock()
+			stream.usage = chunk.Usage.toTokenUsage()
+			stream.mu.Unlock()
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				stream.chunks <- choice.Delta.Content
+			}
+
+			for _, call := range choice.Delta.ToolCalls {
+				state, ok := byIndex[call.Index]
+				// Some servers send every complete call at index 0; a new ID starts a new call
+				if !ok || (call.ID != "" && state.id != "" && call.ID != state.id) {
+					state = &toolCallState{}
+					byIndex[call.Index] = state
+					toolCalls = append(toolCalls, state)
+				}
+				if call.ID != "" {
+					state.id = call.ID
+				}
+				if call.Function.Name != "" {
+					state.name = call.Function.Name
+				}
+				state.arguments.WriteString(call.Function.Arguments)
+			}
+
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				finishReason = *choice.FinishReason
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil && stream.err == nil {
+		stream.err = fmt.Errorf("stream read error: %w", err)
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	for i, call := range toolCalls {
+		var input map[string]interface{}
+		if args := call.arguments.String(); args != "" {
+			if err := json.Unmarshal([]byte(args), &input); err != nil {
+				s.logger.Warn().
+					Err(err).
+					Str("json", args).
+					Msg("failed to parse tool call arguments")
+			}
+		}
+		id := call.id
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i) // Some local servers omit IDs
+		}
+		stream.toolUses = append(stream.toolUses, ToolUseBlock{
+			Type:  "tool_use",
+			ID:    id,
+			Name:  call.name,
+			Input: input,
+		})
+	}
+	stream.stopReason = normalizeFinishReason(finishReason, len(toolCalls) > 0)
+}
+
+// recordUsage logs the usage of a call and passes it to the usage recorder.
+func (s *OpenAIService) recordUsage(ctx context.Context, modelName string, usage model.TokenUsage) {
+	s.logger.Debug().
+		Str("model", modelName).
+		Str("route", modelRouteFrom(ctx)).
+		Int("inputTokens", usage.InputTokens).
+		Int("outputTokens", usage.OutputTokens).
+		Int("cacheReadInputTokens", usage.CacheReadInputTokens).
+		Msg("OpenAI-compatible API usage")
+
+	if s.usageRecorder != nil && !usage.IsZero() {
+		s.usageRecorder.RecordUsage(context.WithoutCancel(ctx), modelName, usage)
+	}
+}
+
+// route resolves the generation parameters of a call from the routing table.
+// The returned context carries the matched route key for usage records.
+func (s *OpenAIService) route(ctx context.Context) (context.Context, GenerationParams) {
+	params, key := s.config.Routes.Resolve(ctx, GenerationParams{Model: s.config.Model, MaxTokens: s.config.MaxTokens})
+	return withModelRoute(ctx, key), params
+}
+
+// normalizeFinishReason maps a chat completions finish reason to a StopReason constant.
+// Some servers report "stop" even when the turn ends in tool calls.
+func normalizeFinishReason(reason string, hasToolCalls bool) string {
+	switch {
+	case reason == "tool_calls" || reason == "function_call" || hasToolCalls:
+		return StopReasonToolUse
+	case reason == "length":
+		return StopReasonMaxTokens
+	case reason == "stop" || reason == "":
+		return StopReasonEndTurn
+	default:
+		return reason
+	}
+}
+
+// buildOpenAIMessages converts a conversation, plus an optional tool-use continuation,
+// to chat completions messages.
+func buildOpenAIMessages(systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) []openAIMessage {
+	result := make([]openAIMessage, 0, len(messages)+2+len(toolResults))
+	if systemPrompt != "" {
+		result = append(result, openAIMessage{Role: "system", Content: systemPrompt})
+	}
+
+	for _, msg := range messages {
+		result = append(result, openAIMessage{Role: msg.Role, Content: msg.Content})
+	}
+
+	if len(assistantContent) > 0 {
+		assistant := openAIMessage{Role: "assistant"}
+		var text strings.Builder
+		for _, block := range assistantContent {
+			switch block.Type {
+			case "text":
+				text.WriteString(block.Text)
+			case "tool_use":
+				arguments, _ := json.Marshal(block.Input)
+				assistant.ToolCalls = append(assistant.ToolCalls, openAIToolCall{
+					ID:       block.ID,
+					Type:     "function",
+					Function: openAIFunctionCall{Name: block.Name, Arguments: string(arguments)},
+				})
+			}
+		}
+		if text.Len() > 0 {
+			assistant.Content = text.String()
+		}
+		result = append(result, assistant)
+	}
+
+	for _, toolResult := range toolResults {
+		content := toolResult.Content
+		if toolResult.IsError {
+			content = "Error: " + content
+		}
+		result = append(result, openAIMessage{
+			Role:       "tool",
+			Content:    content,
+			ToolCallID: toolResult.ToolUseID,
+		})
+	}
+
+	return result
+}
+
+// toOpenAITools converts tool definitions to the chat completions function format.
+func toOpenAITools(tools []ClaudeTool) []openAITool {
+	result := make([]openAITool, len(tools))
+	for i, tool := range tools {
+		result[i] = openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		}
+	}
+	return result
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// openAIChunk formats a chat completions stream chunk.
+func openAIChunk(data string) string {
+	return "data: " + data + "\n\n"
+}
+
+// openAITextTurn returns stream chunks for a turn that only streams text.
+func openAITextTurn(text string) []string {
+	textJSON, _ := json.Marshal(text)
+	return []string{
+		openAIChunk(`{"choices":[{"index":0,"delta":{"role":"assistant","content":` + string(textJSON) + `}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`),
+		openAIChunk(`{"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":8,"prompt_tokens_details":{"cached_tokens":20}}}`),
+		openAIChunk(`[DONE]`),
+	}
+}
+
+// openAIToolTurn returns stream chunks for a turn that calls write_file, with arguments split across chunks.
+func openAIToolTurn(path, content string) []string {
+	args, _ := json.Marshal(map[string]string{"path": path, "content": content})
+	half := len(args) / 2
+	part1, _ := json.Marshal(string(args[:half]))
+	part2, _ := json.Marshal(string(args[half:]))
+	return []string{
+		openAIChunk(`{"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"write_file","arguments":""}}]}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":` + string(part1) + `}}]}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":` + string(part2) + `}}]}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`),
+		openAIChunk(`[DONE]`),
+	}
+}
+
+// newScriptedOpenAIServer replays one scripted turn per request and captures request bodies.
+func newScriptedOpenAIServer(t *testing.T, turns ...[]string) (*httptest.Server, func() []openAIRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []openAIRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		body, _ := io.ReadAll(r.Body)
+		var req openAIRequest
+		json.Unmarshal(body, &req)
+
+		mu.Lock()
+		idx := len(requests)
+		requests = append(requests, req)
+		mu.Unlock()
+
+		if idx >= len(turns) {
+			idx = len(turns) - 1
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range turns[idx] {
+			w.Write([]byte(chunk))
+		}
+	}))
+
+	return server, func() []openAIRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]openAIRequest(nil), requests...)
+	}
+}
+
+func newTestOpenAIService(baseURL string) *OpenAIService {
+	return NewOpenAIService(OpenAIConfig{
+		Model:     "qwen2.5-coder",
+		MaxTokens: 1024,
+		BaseURL:   baseURL + "/v1",
+	}, zerolog.Nop())
+}
+
+func TestOpenAIService_SendMessage_StreamsText(t *testing.T) {
+	server, requests := newScriptedOpenAIServer(t, openAITextTurn("Hello there"))
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	stream, err := svc.SendMessage(WithTools(context.Background(), fileToolDefinitions()), "Be helpful.", []ClaudeMessage{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var content strings.Builder
+	for chunk := range stream.Chunks() {
+		content.WriteString(chunk)
+	}
+
+	assert.Equal(t, "Hello there", content.String())
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, StopReasonEndTurn, stream.StopReason())
+	assert.Empty(t, stream.ToolUses())
+	assert.Equal(t, model.TokenUsage{InputTokens: 100, OutputTokens: 8, CacheReadInputTokens: 20}, stream.Usage())
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "qwen2.5-coder", reqs[0].Model)
+	assert.True(t, reqs[0].Stream)
+	require.Len(t, reqs[0].Messages, 2)
+	assert.Equal(t, "system", reqs[0].Messages[0].Role)
+	assert.Equal(t, "Be helpful.", reqs[0].Messages[0].Content)
+	require.NotEmpty(t, reqs[0].Tools)
+	assert.Equal(t, "function", reqs[0].Tools[0].Type)
+	assert.Equal(t, "write_file", reqs[0].Tools[0].Function.Name)
+}
+
+func TestOpenAIService_SendMessage_CollectsToolCalls(t *testing.T) {
+	server, _ := newScriptedOpenAIServer(t, openAIToolTurn("index.html", "<h1>Hi</h1>"))
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	stream, err := svc.SendMessage(context.Background(), "", []ClaudeMessage{{Role: "user", Content: "Make a page"}})
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	assert.Equal(t, StopReasonToolUse, stream.StopReason())
+	toolUses := stream.ToolUses()
+	require.Len(t, toolUses, 1)
+	assert.Equal(t, "call_1", toolUses[0].ID)
+	assert.Equal(t, "write_file", toolUses[0].Name)
+	assert.Equal(t, "index.html", toolUses[0].Input["path"])
+	assert.Equal(t, "<h1>Hi</h1>", toolUses[0].Input["content"])
+}
+
+func TestOpenAIService_SendMessage_WholeToolCallsAtSameIndex(t *testing.T) {
+	// Ollama-style: complete calls in one chunk each, all at index 0, finish_reason "stop"
+	server, _ := newScriptedOpenAIServer(t, []string{
+		openAIChunk(`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"a","function":{"name":"read_file","arguments":"{\"path\":\"a.html\"}"}}]}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"b","function":{"name":"read_file","arguments":"{\"path\":\"b.html\"}"}}]}}]}`),
+		openAIChunk(`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`),
+		openAIChunk(`[DONE]`),
+	})
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	stream, err := svc.SendMessage(context.Background(), "", []ClaudeMessage{{Role: "user", Content: "Read both"}})
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	assert.Equal(t, StopReasonToolUse, stream.StopReason(), "tool calls imply tool_use even with finish_reason stop")
+	toolUses := stream.ToolUses()
+	require.Len(t, toolUses, 2)
+	assert.Equal(t, "a.html", toolUses[0].Input["path"])
+	assert.Equal(t, "b.html", toolUses[1].Input["path"])
+}
+
+func TestOpenAIService_SendMessageWithToolResults(t *testing.T) {
+	server, requests := newScriptedOpenAIServer(t, openAITextTurn("Done"))
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	stream, err := svc.SendMessageWithToolResults(context.Background(), "sys",
+		[]ClaudeMessage{{Role: "user", Content: "Make a page"}},
+		[]ContentBlock{
+			{Type: "text", Text: "Creating it."},
+			{Type: "tool_use", ID: "call_1", Name: "write_file", Input: map[string]interface{}{"path": "index.html"}},
+		},
+		[]ToolResult{{Type: "tool_result", ToolUseID: "call_1", Content: "disk full", IsError: true}},
+	)
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	messages := reqs[0].Messages
+	require.Len(t, messages, 4)
+
+	assistant := messages[2]
+	assert.Equal(t, "assistant", assistant.Role)
+	assert.Equal(t, "Creating it.", assistant.Content)
+	require.Len(t, assistant.ToolCalls, 1)
+	assert.Equal(t, "call_1", assistant.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"path":"index.html"}`, assistant.ToolCalls[0].Function.Arguments)
+
+	tool := messages[3]
+	assert.Equal(t, "tool", tool.Role)
+	assert.Equal(t, "call_1", tool.ToolCallID)
+	assert.Equal(t, "Error: disk full", tool.Content)
+}
+
+func TestOpenAIService_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"message":"model \"missing\" not found","type":"not_found"}}`))
+	}))
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	_, err := svc.SendMessage(context.Background(), "", []ClaudeMessage{{Role: "user", Content: "Hi"}})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "OpenAI-compatible API returned status 404")
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestOpenAIService_AnalyzeImage(t *testing.T) {
+	var captured openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &captured)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"A cat"}}],"usage":{"prompt_tokens":50,"completion_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	svc := newTestOpenAIService(server.URL)
+	usageRepo := repository.NewMockUsageRepository()
+	svc.SetUsageRecorder(NewUsageService(nil, usageRepo, zerolog.Nop()))
+
+	result, err := svc.AnalyzeImage(context.Background(), []byte("png-bytes"), "image/png", "Describe")
+	require.NoError(t, err)
+
+	assert.Equal(t, "A cat", result)
+	assert.False(t, captured.Stream)
+	parts, ok := captured.Messages[0].Content.([]interface{})
+	require.True(t, ok)
+	require.Len(t, parts, 2)
+	imageURL := parts[0].(map[string]interface{})["image_url"].(map[string]interface{})["url"]
+	assert.True(t, strings.HasPrefix(imageURL.(string), "data:image/png;base64,"))
+
+	records := usageRepo.All()
+	require.Len(t, records, 1)
+	assert.Equal(t, 50, records[0].InputTokens)
+}
+
+func TestNewProvider(t *testing.T) {
+	logger := zerolog.Nop()
+
+	provider, err := NewProvider(ProviderConfig{Anthropic: ClaudeConfig{APIKey: "key"}}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderAnthropic, provider.Name())
+
+	provider, err = NewProvider(ProviderConfig{Type: ProviderOpenAI, OpenAI: OpenAIConfig{Model: "llama3"}}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderOpenAI, provider.Name())
+
+	_, err = NewProvider(ProviderConfig{Type: ProviderAnthropic}, logger)
+	assert.Error(t, err, "anthropic requires an API key")
+
+	_, err = NewProvider(ProviderConfig{Type: ProviderOpenAI}, logger)
+	assert.Error(t, err, "openai requires a model")
+
+	_, err = NewProvider(ProviderConfig{Type: "gemini"}, logger)
+	assert.Error(t, err)
+}
+
+func TestChatService_ProcessMessage_WithOpenAIProvider(t *testing.T) {
+	server, _ := newScriptedOpenAIServer(t,
+		openAIToolTurn("index.html", "<h1>Offline</h1>"),
+		openAITextTurn("Created index.html."),
+	)
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	project, _ := repo.Create(ctx, "Offline Project")
+
+	chatService := NewChatService(ChatConfig{}, newTestOpenAIService(server.URL), nil, nil, repo, fileRepo, nil, logger)
+
+	var createdFiles []string
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Make a page", func(string) {}, func(path string) {
+		createdFiles = append(createdFiles, path)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Created index.html.", result.Content)
+	assert.Equal(t, []string{"index.html"}, createdFiles)
+
+	file, err := fileRepo.GetFileByPath(ctx, project.ID, "index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Offline</h1>", file.Content)
+}
@@ -20,9 +20,16 @@ type PRDService struct {
 	prdRepo       PRDRepository
 	discoveryRepo repository.DiscoveryRepository
 	claudeService ClaudeMessenger
+	compactor     ConversationCompactor
 	logger        zerolog.Logger
 }
 
+// ConversationCompactor defines the interface for summarizing a conversation when a PRD is completed.
+// This allows PRDService to trigger compaction without depending on the full SummaryService.
+type ConversationCompactor interface {
+	CompactForPRD(ctx context.Context, projectID, prdID uuid.UUID) error
+}
+
 // PRDRepository defines the interface for PRD data access.
 type PRDRepository interface {
 	// CRUD
@@ -81,6 +88,12 @@ func NewPRDService(
 	}
 }
 
+// SetConversationCompactor sets the compactor used to summarize the conversation when a PRD is completed.
+// This is optional - if not set, completing a PRD does not trigger a summary.
+func (s *PRDService) SetConversationCompactor(compactor ConversationCompactor) {
+	s.compactor = compactor
+}
+
 // GenerateAllPRDs creates PRDs for all features in a discovery.
 // MVP features are processed in parallel, future features sequentially.
 func (s *PRDService) GenerateAllPRDs(ctx context.Context, discoveryID uuid.UUID) error {
@@ -442,7 +455,7 @@ func (s *PRDService) generateFullPRD(ctx context.Context, prd *model.PRD, featur
 	}
 
 	// Call Claude for generation
-	content, err := s.callClaude(ctx, promptBuf.String())
+	content, err := s.callClaude(ctx, prd.ProjectID, promptBuf.String())
 	if err != nil {
 		return nil, err
 	}
@@ -479,7 +492,7 @@ func (s *PRDService) generateLightweightPRD(ctx context.Context, prd *model.PRD,
 	}
 
 	// Call Claude for generation
-	content, err := s.callClaude(ctx, promptBuf.String())
+	content, err := s.callClaude(ctx, prd.ProjectID, promptBuf.String())
 	if err != nil {
 		return nil, err
 	}
@@ -500,7 +513,11 @@ func (s *PRDService) getRelatedFeatures(summary *model.DiscoverySummary, exclude
 }
 
 // callClaude sends a message to Claude and collects the streaming response.
-func (s *PRDService) callClaude(ctx context.Context, prompt string) (string, error) {
+// Token usage is attributed to the project's product manager.
+func (s *PRDService) callClaude(ctx context.Context, projectID uuid.UUID, prompt string) (string, error) {
+	agentType := string(model.AgentProductManager)
+	ctx = WithUsageScope(ctx, projectID, &agentType, model.UsageSourcePRD)
+
 	messages := []ClaudeMessage{
 		{Role: "user", Content: prompt},
 	}
@@ -674,7 +691,25 @@ func (s *PRDService) CompleteImplementation(ctx context.Context, prdID uuid.UUID
 	if err := s.UpdateStatus(ctx, prdID, model.PRDStatusComplete); err != nil {
 		return err
 	}
-	return s.prdRepo.SetCompletedAt(ctx, prdID)
+	if err := s.prdRepo.SetCompletedAt(ctx, prdID); err != nil {
+		return err
+	}
+
+	// Trigger async conversation summary if a compactor is configured
+	if s.compactor != nil {
+		prd, err := s.prdRepo.GetByID(ctx, prdID)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("prdId", prdID.String()).Msg("failed to load completed PRD for summary")
+			return nil
+		}
+		go func() {
+			if err := s.compactor.CompactForPRD(context.Background(), prd.ProjectID, prdID); err != nil {
+				s.logger.Error().Err(err).Str("prdId", prdID.String()).Msg("failed to summarize conversation for completed PRD")
+			}
+		}()
+	}
+
+	return nil
 }
 
 // GetActivePRD retrieves the currently active PRD for a project.
@@ -873,6 +908,74 @@ func (s *PRDService) DeleteUserStory(ctx context.Context, prdID uuid.UUID, story
 	return err
 }
 
+// AddAcceptanceCriterion adds an acceptance criterion to a PRD.
+func (s *PRDService) AddAcceptanceCriterion(ctx context.Context, prdID uuid.UUID, criterion *model.AcceptanceCriterion) error {
+	prd, err := s.prdRepo.GetByID(ctx, prdID)
+	if err != nil {
+		return ErrPRDNotFound
+	}
+
+	if prd.Status != model.PRDStatusDraft {
+		return fmt.Errorf("%w: can only edit draft PRDs", ErrInvalidStatusChange)
+	}
+
+	criteria, err := prd.AcceptanceCriteria()
+	if err != nil {
+		return err
+	}
+
+	// Generate ID if not provided
+	if criterion.ID == "" {
+		criterion.ID = fmt.Sprintf("AC-%03d", len(criteria)+1)
+	}
+
+	criteria = append(criteria, *criterion)
+	if err := prd.SetAcceptanceCriteria(criteria); err != nil {
+		return err
+	}
+
+	_, err = s.prdRepo.Update(ctx, prd)
+	return err
+}
+
+// UpdateAcceptanceCriterion updates an acceptance criterion in a PRD.
+func (s *PRDService) UpdateAcceptanceCriterion(ctx context.Context, prdID uuid.UUID, criterionID string, criterion *model.AcceptanceCriterion) error {
+	prd, err := s.prdRepo.GetByID(ctx, prdID)
+	if err != nil {
+		return ErrPRDNotFound
+	}
+
+	if prd.Status != model.PRDStatusDraft {
+		return fmt.Errorf("%w: can only edit draft PRDs", ErrInvalidStatusChange)
+	}
+
+	criteria, err := prd.AcceptanceCriteria()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, c := range criteria {
+		if c.ID == criterionID {
+			criterion.ID = criterionID // Preserve ID
+			criteria[i] = *criterion
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("acceptance criterion %s not found", criterionID)
+	}
+
+	if err := prd.SetAcceptanceCriteria(criteria); err != nil {
+		return err
+	}
+
+	_, err = s.prdRepo.Update(ctx, prd)
+	return err
+}
+
 // min returns the smaller of two integers.
 func min(a, b int) int {
 	if a < b {
@@ -261,6 +261,10 @@ func (m *MockClaudeMessengerForPRD) SendMessage(ctx context.Context, systemPromp
 	return stream, nil
 }
 
+func (m *MockClaudeMessengerForPRD) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	return m.SendMessage(ctx, systemPrompt, messages)
+}
+
 func newTestPRDService() (*PRDService, *MockPRDRepository, *repository.MockDiscoveryRepository, *MockClaudeMessengerForPRD) {
 	prdRepo := NewMockPRDRepository()
 	discoveryRepo := repository.NewMockDiscoveryRepository()
@@ -904,6 +908,68 @@ func TestDeleteUserStory(t *testing.T) {
 	assert.Equal(t, "US-002", stories[0].ID)
 }
 
+func TestAddAcceptanceCriterion(t *testing.T) {
+	service, prdRepo, _, _ := newTestPRDService()
+	ctx := context.Background()
+
+	prd := &model.PRD{
+		DiscoveryID: uuid.New(),
+		FeatureID:   uuid.New(),
+		ProjectID:   uuid.New(),
+		Title:       "Test PRD",
+		Status:      model.PRDStatusDraft,
+	}
+	created, _ := prdRepo.Create(ctx, prd)
+
+	criterion := &model.AcceptanceCriterion{
+		Given:       "a test setup",
+		When:        "I run tests",
+		Then:        "they pass",
+		UserStoryID: "US-001",
+	}
+
+	err := service.AddAcceptanceCriterion(ctx, created.ID, criterion)
+	require.NoError(t, err)
+
+	updated, _ := prdRepo.GetByID(ctx, created.ID)
+	criteria, _ := updated.AcceptanceCriteria()
+	assert.Len(t, criteria, 1)
+	assert.Equal(t, "AC-001", criteria[0].ID)
+}
+
+func TestUpdateAcceptanceCriterion(t *testing.T) {
+	service, prdRepo, _, _ := newTestPRDService()
+	ctx := context.Background()
+
+	prd := &model.PRD{
+		DiscoveryID: uuid.New(),
+		FeatureID:   uuid.New(),
+		ProjectID:   uuid.New(),
+		Title:       "Test PRD",
+		Status:      model.PRDStatusDraft,
+	}
+	prd.SetAcceptanceCriteria([]model.AcceptanceCriterion{
+		{ID: "AC-001", Given: "a test setup", When: "I run tests", Then: "they pass", UserStoryID: "US-001"},
+	})
+	created, _ := prdRepo.Create(ctx, prd)
+
+	err := service.UpdateAcceptanceCriterion(ctx, created.ID, "AC-001", &model.AcceptanceCriterion{
+		Given: "a test setup",
+		When:  "I run tests",
+		Then:  "they pass quickly",
+	})
+	require.NoError(t, err)
+
+	updated, _ := prdRepo.GetByID(ctx, created.ID)
+	criteria, _ := updated.AcceptanceCriteria()
+	assert.Len(t, criteria, 1)
+	assert.Equal(t, "AC-001", criteria[0].ID) // ID preserved
+	assert.Equal(t, "they pass quickly", criteria[0].Then)
+
+	err = service.UpdateAcceptanceCriterion(ctx, created.ID, "AC-009", &model.AcceptanceCriterion{})
+	assert.EqualError(t, err, "acceptance criterion AC-009 not found")
+}
+
 func TestRetryGeneration(t *testing.T) {
 	service, prdRepo, discoveryRepo, _ := newTestPRDService()
 	ctx := context.Background()
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// prdIDProperty is the optional prd_id input shared by the PRD tools.
+var prdIDProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "ID of the PRD to change. Defaults to the PRD being discussed.",
+}
+
+// userStoryProperties returns the schema properties of a user story's fields.
+func userStoryProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"prd_id": prdIDProperty,
+		"as_a": map[string]interface{}{
+			"type":        "string",
+			"description": "User persona, e.g. 'returning customer'",
+		},
+		"i_want": map[string]interface{}{
+			"type":        "string",
+			"description": "What the user wants to do",
+		},
+		"so_that": map[string]interface{}{
+			"type":        "string",
+			"description": "The benefit to the user",
+		},
+		"priority": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"must", "should", "could"},
+		},
+		"complexity": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"low", "medium", "high"},
+		},
+	}
+}
+
+// acceptanceCriterionProperties returns the schema properties of an acceptance criterion's fields.
+func acceptanceCriterionProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"prd_id": prdIDProperty,
+		"given": map[string]interface{}{
+			"type":        "string",
+			"description": "Precondition",
+		},
+		"when": map[string]interface{}{
+			"type":        "string",
+			"description": "Action",
+		},
+		"then": map[string]interface{}{
+			"type":        "string",
+			"description": "Expected outcome",
+		},
+		"user_story_id": map[string]interface{}{
+			"type":        "string",
+			"description": "ID of the user story the criterion belongs to, e.g. 'US-001'",
+		},
+	}
+}
+
+// Tools returns the PRD-editing tools for the chat's tool registry. They are offered to
+// the product manager agent only, and change PRDs immediately rather than with the
+// turn's file changes. Edits other than status changes require a draft PRD.
+func (s *PRDService) Tools() []Tool {
+	productManager := []model.AgentType{model.AgentProductManager}
+
+	withID := func(properties map[string]interface{}, name, description string) map[string]interface{} {
+		properties[name] = map[string]interface{}{"type": "string", "description": description}
+		return properties
+	}
+
+	return []Tool{
+		{
+			Name:        "update_prd_overview",
+			Description: "Replace the overview of the PRD. Only draft PRDs can be edited.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prd_id": prdIDProperty,
+					"overview": map[string]interface{}{
+						"type":        "string",
+						"description": "The new overview",
+					},
+				},
+				"required": []string{"overview"},
+			},
+			NewInput: func() ToolInput { return &updatePRDOverviewInput{} },
+			Handler:  s.executeUpdateOverview,
+			Agents:   productManager,
+		},
+		{
+			Name:        "add_user_story",
+			Description: "Add a user story to the PRD. Its ID (US-nnn) is assigned automatically.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": userStoryProperties(),
+				"required":   []string{"as_a", "i_want", "so_that"},
+			},
+			NewInput: func() ToolInput { return &userStoryInput{} },
+			Handler:  s.executeAddUserStory,
+			Agents:   productManager,
+		},
+		{
+			Name:        "update_user_story",
+			Description: "Change a user story in the PRD. Fields you leave out keep their current value.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": withID(userStoryProperties(), "story_id", "ID of the user story, e.g. 'US-001'"),
+				"required":   []string{"story_id"},
+			},
+			NewInput: func() ToolInput { return &userStoryInput{requireID: true} },
+			Handler:  s.executeUpdateUserStory,
+			Agents:   productManager,
+		},
+		{
+			Name:        "delete_user_story",
+			Description: "Remove a user story from the PRD",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prd_id": prdIDProperty,
+					"story_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the user story, e.g. 'US-001'",
+					},
+				},
+				"required": []string{"story_id"},
+			},
+			NewInput: func() ToolInput { return &userStoryInput{requireID: true} },
+			Handler:  s.executeDeleteUserStory,
+			Agents:   productManager,
+		},
+		{
+			Name:        "add_acceptance_criterion",
+			Description: "Add a Given/When/Then acceptance criterion to the PRD. Its ID (AC-nnn) is assigned automatically.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": acceptanceCriterionProperties(),
+				"required":   []string{"given", "when", "then"},
+			},
+			NewInput: func() ToolInput { return &acceptanceCriterionInput{} },
+			Handler:  s.executeAddAcceptanceCriterion,
+			Agents:   productManager,
+		},
+		{
+			Name:        "update_acceptance_criterion",
+			Description: "Change an acceptance criterion in the PRD. Fields you leave out keep their current value.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": withID(acceptanceCriterionProperties(), "criterion_id", "ID of the acceptance criterion, e.g. 'AC-001'"),
+				"required":   []string{"criterion_id"},
+			},
+			NewInput: func() ToolInput { return &acceptanceCriterionInput{requireID: true} },
+			Handler:  s.executeUpdateAcceptanceCriterion,
+			Agents:   productManager,
+		},
+		{
+			Name: "set_prd_status",
+			Description: "Move the PRD through its lifecycle: 'ready' approves a draft for building (or pauses one in progress), " +
+				"'in_progress' starts implementing a ready PRD, 'complete' marks an implemented PRD as done. " +
+				"Only do this when the user agrees.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prd_id": prdIDProperty,
+					"status": map[string]interface{}{
+						"type": "string",
+						"enum": []string{string(model.PRDStatusReady), string(model.PRDStatusInProgress), string(model.PRDStatusComplete)},
+					},
+				},
+				"required": []string{"status"},
+			},
+			NewInput: func() ToolInput { return &setPRDStatusInput{} },
+			Handler:  s.executeSetStatus,
+			Agents:   productManager,
+		},
+	}
+}
+
+// updatePRDOverviewInput is the input of update_prd_overview.
+type updatePRDOverviewInput struct {
+	PRDID    string `json:"prd_id"`
+	Overview string `json:"overview"`
+}
+
+func (in *updatePRDOverviewInput) Validate() error {
+	if in.Overview == "" {
+		return errors.New("overview is required")
+	}
+	return nil
+}
+
+// userStoryInput is the input of add_user_story, update_user_story and delete_user_story.
+type userStoryInput struct {
+	PRDID      string `json:"prd_id"`
+	StoryID    string `json:"story_id"`
+	AsA        string `json:"as_a"`
+	IWant      string `json:"i_want"`
+	SoThat     string `json:"so_that"`
+	Priority   string `json:"priority"`
+	Complexity string `json:"complexity"`
+
+	requireID bool // Set for tools that change an existing story
+}
+
+func (in *userStoryInput) Validate() error {
+	if in.requireID && in.StoryID == "" {
+		return errors.New("story_id is required")
+	}
+	if !in.requireID && (in.AsA == "" || in.IWant == "" || in.SoThat == "") {
+		return errors.New("as_a, i_want and so_that are required")
+	}
+	if !oneOf(in.Priority, "", "must", "should", "could") {
+		return fmt.Errorf("priority must be must, should or could, not %q", in.Priority)
+	}
+	if !oneOf(in.Complexity, "", "low", "medium", "high") {
+		return fmt.Errorf("complexity must be low, medium or high, not %q", in.Complexity)
+	}
+	return nil
+}
+
+// acceptanceCriterionInput is the input of add_acceptance_criterion and update_acceptance_criterion.
+type acceptanceCriterionInput struct {
+	PRDID       string `json:"prd_id"`
+	CriterionID string `json:"criterion_id"`
+	Given       string `json:"given"`
+	When        string `json:"when"`
+	Then        string `json:"then"`
+	UserStoryID string `json:"user_story_id"`
+
+	requireID bool // Set for tools that change an existing criterion
+}
+
+func (in *acceptanceCriterionInput) Validate() error {
+	if in.requireID && in.CriterionID == "" {
+		return errors.New("criterion_id is required")
+	}
+	if !in.requireID && (in.Given == "" || in.When == "" || in.Then == "") {
+		return errors.New("given, when and then are required")
+	}
+	return nil
+}
+
+// setPRDStatusInput is the input of set_prd_status.
+type setPRDStatusInput struct {
+	PRDID  string `json:"prd_id"`
+	Status string `json:"status"`
+}
+
+func (in *setPRDStatusInput) Validate() error {
+	switch model.PRDStatus(in.Status) {
+	case model.PRDStatusReady, model.PRDStatusInProgress, model.PRDStatusComplete:
+		return nil
+	case "":
+		return errors.New("status is required")
+	default:
+		return fmt.Errorf("status must be ready, in_progress or complete, not %q", in.Status)
+	}
+}
+
+// toolPRD returns the PRD a tool call targets: the given ID, or the PRD the turn's
+// agent is working on. PRDs of other projects are reported as not found.
+func (s *PRDService) toolPRD(ctx context.Context, turn *chatTurn, prdID string) (*model.PRD, error) {
+	id := turn.prdID
+	if prdID != "" {
+		parsed, err := uuid.Parse(prdID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prd_id %q", prdID)
+		}
+		id = parsed
+	}
+	if id == uuid.Nil {
+		return nil, errors.New("no PRD is being discussed; pass prd_id")
+	}
+
+	prd, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if prd.ProjectID != turn.projectID {
+		return nil, ErrPRDNotFound
+	}
+	return prd, nil
+}
+
+// prdUpdated fills in the result of a tool call that changed a PRD.
+func prdUpdated(execResult ToolExecutionResult, prd *model.PRD, format string, args ...interface{}) ToolExecutionResult {
+	execResult.Result.Content = fmt.Sprintf(format, args...) + fmt.Sprintf(" (PRD %q)", prd.Title)
+	execResult.UpdatedPRD = prd.ID
+	return execResult
+}
+
+// executeUpdateOverview replaces a PRD's overview.
+func (s *PRDService) executeUpdateOverview(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*updatePRDOverviewInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	if err := s.UpdateOverview(ctx, prd.ID, in.Overview); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Updated the overview")
+}
+
+// executeAddUserStory adds a user story to a PRD.
+func (s *PRDService) executeAddUserStory(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*userStoryInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+
+	story := &model.UserStory{AsA: in.AsA, IWant: in.IWant, SoThat: in.SoThat, Priority: in.Priority, Complexity: in.Complexity}
+	if story.Priority == "" {
+		story.Priority = "should"
+	}
+	if story.Complexity == "" {
+		story.Complexity = "medium"
+	}
+	if err := s.AddUserStory(ctx, prd.ID, story); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Added user story %s", story.ID)
+}
+
+// executeUpdateUserStory changes the given fields of a user story.
+func (s *PRDService) executeUpdateUserStory(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*userStoryInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	stories, err := prd.UserStories()
+	if err != nil {
+		return toolError(execResult, "reading user stories: %v", err)
+	}
+
+	var story *model.UserStory
+	for i := range stories {
+		if stories[i].ID == in.StoryID {
+			story = &stories[i]
+			break
+		}
+	}
+	if story == nil {
+		return toolError(execResult, "user story %s not found", in.StoryID)
+	}
+
+	setIfGiven(&story.AsA, in.AsA)
+	setIfGiven(&story.IWant, in.IWant)
+	setIfGiven(&story.SoThat, in.SoThat)
+	setIfGiven(&story.Priority, in.Priority)
+	setIfGiven(&story.Complexity, in.Complexity)
+	if err := s.UpdateUserStory(ctx, prd.ID, in.StoryID, story); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Updated user story %s", in.StoryID)
+}
+
+// executeDeleteUserStory removes a user story from a PRD.
+func (s *PRDService) executeDeleteUserStory(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*userStoryInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	if err := s.DeleteUserStory(ctx, prd.ID, in.StoryID); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Deleted user story %s", in.StoryID)
+}
+
+// executeAddAcceptanceCriterion adds an acceptance criterion to a PRD.
+func (s *PRDService) executeAddAcceptanceCriterion(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*acceptanceCriterionInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+
+	criterion := &model.AcceptanceCriterion{Given: in.Given, When: in.When, Then: in.Then, UserStoryID: in.UserStoryID}
+	if err := s.AddAcceptanceCriterion(ctx, prd.ID, criterion); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Added acceptance criterion %s", criterion.ID)
+}
+
+// executeUpdateAcceptanceCriterion changes the given fields of an acceptance criterion.
+func (s *PRDService) executeUpdateAcceptanceCriterion(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*acceptanceCriterionInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	criteria, err := prd.AcceptanceCriteria()
+	if err != nil {
+		return toolError(execResult, "reading acceptance criteria: %v", err)
+	}
+
+	var criterion *model.AcceptanceCriterion
+	for i := range criteria {
+		if criteria[i].ID == in.CriterionID {
+			criterion = &criteria[i]
+			break
+		}
+	}
+	if criterion == nil {
+		return toolError(execResult, "acceptance criterion %s not found", in.CriterionID)
+	}
+
+	setIfGiven(&criterion.Given, in.Given)
+	setIfGiven(&criterion.When, in.When)
+	setIfGiven(&criterion.Then, in.Then)
+	setIfGiven(&criterion.UserStoryID, in.UserStoryID)
+	if err := s.UpdateAcceptanceCriterion(ctx, prd.ID, in.CriterionID, criterion); err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Updated acceptance criterion %s", in.CriterionID)
+}
+
+// executeSetStatus moves a PRD to a new lifecycle status, recording the matching timestamp.
+func (s *PRDService) executeSetStatus(ctx context.Context, turn *chatTurn, input ToolInput, execResult ToolExecutionResult) ToolExecutionResult {
+	in := input.(*setPRDStatusInput)
+
+	prd, err := s.toolPRD(ctx, turn, in.PRDID)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+
+	status := model.PRDStatus(in.Status)
+	switch {
+	case status == model.PRDStatusReady && prd.Status == model.PRDStatusDraft:
+		err = s.MarkAsReady(ctx, prd.ID)
+	case status == model.PRDStatusReady:
+		err = s.UpdateStatus(ctx, prd.ID, status) // Pausing an implementation
+	case status == model.PRDStatusInProgress:
+		err = s.StartImplementation(ctx, prd.ID)
+	default:
+		err = s.CompleteImplementation(ctx, prd.ID)
+	}
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+	return prdUpdated(execResult, prd, "Changed the status from %s to %s", prd.Status, status)
+}
+
+// setIfGiven overwrites field with value unless value is empty.
+func setIfGiven(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// oneOf reports whether value is one of the allowed values.
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
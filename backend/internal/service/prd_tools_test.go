@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// newPRDToolsTest returns a registry with the PRD tools and a product manager turn
+// working on a draft PRD with one user story and one acceptance criterion.
+func newPRDToolsTest(t *testing.T) (*ToolRegistry, *chatTurn, *MockPRDRepository, *model.PRD) {
+	t.Helper()
+	prdService, prdRepo, _, _ := newTestPRDService()
+	registry := NewToolRegistry()
+	for _, tool := range prdService.Tools() {
+		require.NoError(t, registry.Register(tool))
+	}
+
+	prd := &model.PRD{ProjectID: uuid.New(), Title: "Checkout", Overview: "Pay for orders", Status: model.PRDStatusDraft}
+	require.NoError(t, prd.SetUserStories([]model.UserStory{
+		{ID: "US-001", AsA: "shopper", IWant: "to pay by card", SoThat: "I get my order", Priority: "must", Complexity: "medium"},
+	}))
+	require.NoError(t, prd.SetAcceptanceCriteria([]model.AcceptanceCriterion{
+		{ID: "AC-001", Given: "a full cart", When: "I pay", Then: "the order is placed", UserStoryID: "US-001"},
+	}))
+	created, _ := prdRepo.Create(context.Background(), prd)
+
+	productManager := string(model.AgentProductManager)
+	turn := &chatTurn{projectID: created.ProjectID, agentType: &productManager, prdID: created.ID}
+	return registry, turn, prdRepo, created
+}
+
+func runPRDTool(registry *ToolRegistry, turn *chatTurn, name string, input map[string]interface{}) ToolExecutionResult {
+	return registry.Execute(context.Background(), turn, ToolUseBlock{ID: "toolu_1", Name: name, Input: input})
+}
+
+func TestPRDTools_EditDraftPRD(t *testing.T) {
+	registry, turn, prdRepo, prd := newPRDToolsTest(t)
+
+	result := runPRDTool(registry, turn, "update_prd_overview", map[string]interface{}{"overview": "Pay for orders by card or invoice"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, `Updated the overview (PRD "Checkout")`, result.Result.Content)
+	assert.Equal(t, prd.ID, result.UpdatedPRD)
+
+	result = runPRDTool(registry, turn, "add_user_story", map[string]interface{}{"as_a": "business", "i_want": "to pay by invoice", "so_that": "I can pay later"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, `Added user story US-002 (PRD "Checkout")`, result.Result.Content)
+
+	result = runPRDTool(registry, turn, "update_user_story", map[string]interface{}{"story_id": "US-001", "priority": "should"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+
+	result = runPRDTool(registry, turn, "update_acceptance_criterion", map[string]interface{}{"criterion_id": "AC-001", "then": "the order is placed and a receipt is emailed"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+
+	result = runPRDTool(registry, turn, "add_acceptance_criterion", map[string]interface{}{"given": "a business account", "when": "I choose invoice", "then": "no card is asked for", "user_story_id": "US-002"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, `Added acceptance criterion AC-002 (PRD "Checkout")`, result.Result.Content)
+
+	updated, _ := prdRepo.GetByID(context.Background(), prd.ID)
+	assert.Equal(t, "Pay for orders by card or invoice", updated.Overview)
+
+	stories, _ := updated.UserStories()
+	require.Len(t, stories, 2)
+	assert.Equal(t, model.UserStory{ID: "US-001", AsA: "shopper", IWant: "to pay by card", SoThat: "I get my order", Priority: "should", Complexity: "medium"}, stories[0], "fields left out keep their value")
+	assert.Equal(t, model.UserStory{ID: "US-002", AsA: "business", IWant: "to pay by invoice", SoThat: "I can pay later", Priority: "should", Complexity: "medium"}, stories[1])
+
+	criteria, _ := updated.AcceptanceCriteria()
+	require.Len(t, criteria, 2)
+	assert.Equal(t, model.AcceptanceCriterion{ID: "AC-001", Given: "a full cart", When: "I pay", Then: "the order is placed and a receipt is emailed", UserStoryID: "US-001"}, criteria[0])
+	assert.Equal(t, "US-002", criteria[1].UserStoryID)
+
+	result = runPRDTool(registry, turn, "delete_user_story", map[string]interface{}{"story_id": "US-002"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	updated, _ = prdRepo.GetByID(context.Background(), prd.ID)
+	stories, _ = updated.UserStories()
+	assert.Len(t, stories, 1)
+}
+
+func TestPRDTools_SetStatus(t *testing.T) {
+	registry, turn, prdRepo, prd := newPRDToolsTest(t)
+
+	result := runPRDTool(registry, turn, "set_prd_status", map[string]interface{}{"status": "ready"})
+	require.False(t, result.Result.IsError, result.Result.Content)
+	assert.Equal(t, `Changed the status from draft to ready (PRD "Checkout")`, result.Result.Content)
+
+	updated, _ := prdRepo.GetByID(context.Background(), prd.ID)
+	assert.Equal(t, model.PRDStatusReady, updated.Status)
+	assert.NotNil(t, updated.ApprovedAt)
+
+	// Only drafts can be edited, and the lifecycle can't skip ahead
+	result = runPRDTool(registry, turn, "update_prd_overview", map[string]interface{}{"overview": "Too late"})
+	assert.True(t, result.Result.IsError)
+	assert.Equal(t, "Error: invalid status change: can only edit draft PRDs", result.Result.Content)
+	assert.Equal(t, uuid.Nil, result.UpdatedPRD)
+
+	result = runPRDTool(registry, turn, "set_prd_status", map[string]interface{}{"status": "complete"})
+	assert.True(t, result.Result.IsError)
+
+	result = runPRDTool(registry, turn, "set_prd_status", map[string]interface{}{"status": "failed"})
+	assert.Equal(t, `Error: status must be ready, in_progress or complete, not "failed"`, result.Result.Content)
+}
+
+func TestPRDTools_TargetPRD(t *testing.T) {
+	registry, turn, prdRepo, prd := newPRDToolsTest(t)
+	other, _ := prdRepo.Create(context.Background(), &model.PRD{ProjectID: uuid.New(), Title: "Elsewhere", Status: model.PRDStatusDraft})
+
+	tests := []struct {
+		name     string
+		prdID    uuid.UUID
+		input    map[string]interface{}
+		expected string
+	}{
+		{"explicit PRD", uuid.Nil, map[string]interface{}{"prd_id": prd.ID.String(), "overview": "New"}, `Updated the overview (PRD "Checkout")`},
+		{"no PRD", uuid.Nil, map[string]interface{}{"overview": "New"}, "Error: no PRD is being discussed; pass prd_id"},
+		{"invalid ID", prd.ID, map[string]interface{}{"prd_id": "checkout", "overview": "New"}, `Error: invalid prd_id "checkout"`},
+		{"other project", prd.ID, map[string]interface{}{"prd_id": other.ID.String(), "overview": "New"}, "Error: prd not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			turn.prdID = tt.prdID
+			result := runPRDTool(registry, turn, "update_prd_overview", tt.input)
+			assert.Equal(t, tt.expected, result.Result.Content)
+		})
+	}
+
+	unchanged, _ := prdRepo.GetByID(context.Background(), other.ID)
+	assert.Empty(t, unchanged.Overview)
+}
+
+func TestPRDTools_OnlyForProductManager(t *testing.T) {
+	registry, turn, _, _ := newPRDToolsTest(t)
+	developer := string(model.AgentDeveloper)
+
+	assert.Len(t, registry.Definitions(turn.agentType, nil), 7)
+	assert.Empty(t, registry.Definitions(&developer, nil))
+	assert.Empty(t, registry.Definitions(nil, nil))
+
+	turn.agentType = &developer
+	result := runPRDTool(registry, turn, "update_prd_overview", map[string]interface{}{"overview": "New"})
+	assert.Equal(t, "Error: update_prd_overview is not available in this conversation", result.Result.Content)
+}
+
+func TestChatService_ProcessStream_ReportsPRDUpdates(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_overview", "update_prd_overview", map[string]interface{}{"overview": "Pay for orders by card"}),
+		textTurnEvents("Updated the overview."),
+	)
+	defer server.Close()
+
+	registry, turn, prdRepo, prd := newPRDToolsTest(t)
+	claude := NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, zerolog.Nop())
+	s := NewChatService(ChatConfig{}, claude, nil, nil, repository.NewMockProjectRepository(), nil, nil, zerolog.Nop())
+	tool, _ := registry.Get("update_prd_overview")
+	require.NoError(t, s.Tools().Register(*tool))
+
+	var updated []uuid.UUID
+	_, err := s.processStreamWithTools(context.Background(), turn, "", nil, ChatCallbacks{
+		OnPRDUpdated: func(prdID uuid.UUID) { updated = append(updated, prdID) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []uuid.UUID{prd.ID}, updated)
+	saved, _ := prdRepo.GetByID(context.Background(), prd.ID)
+	assert.Equal(t, "Pay for orders by card", saved.Overview)
+}
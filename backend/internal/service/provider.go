@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// Stop reasons reported by ClaudeStream.StopReason. Providers other than Anthropic
+// map their own finish reasons onto these.
+const (
+	StopReasonEndTurn   = "end_turn"   // The model finished its turn
+	StopReasonToolUse   = "tool_use"   // The model is waiting for tool results
+	StopReasonMaxTokens = "max_tokens" // The response hit the token limit
+)
+
+// Provider names accepted by NewProvider.
+const (
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+	ProviderOpenAI    = "openai"    // OpenAI-compatible chat completions (OpenAI, llama.cpp, Ollama, vLLM, ...)
+)
+
+// Provider is an LLM backend. Whatever its wire format, it streams text chunks, tool calls
+// (as ToolUseBlock) and a normalized stop reason through ClaudeStream, takes tool-use
+// continuations as ContentBlock and ToolResult, and analyzes images.
+type Provider interface {
+	ClaudeMessenger
+	ClaudeVision
+
+	// Name returns the provider name, e.g. "anthropic".
+	Name() string
+
+	// SetUsageRecorder sets the recorder that receives the token usage of every API call.
+	SetUsageRecorder(recorder UsageRecorder)
+
+	// UseCassette routes API calls through a record/replay cassette.
+	UseCassette(cassette *Cassette)
+}
+
+// ProviderConfig selects and configures an LLM provider.
+type ProviderConfig struct {
+	Type      string // ProviderAnthropic (default) or ProviderOpenAI
+	Anthropic ClaudeConfig
+	OpenAI    OpenAIConfig
+	Cassette  *Cassette // Optional; records or replays API calls
+}
+
+// NewProvider creates the provider selected by config.Type.
+// No API key is needed when replaying from a cassette.
+func NewProvider(config ProviderConfig, logger zerolog.Logger) (Provider, error) {
+	replaying := config.Cassette != nil && config.Cassette.Mode() == CassetteReplay
+
+	var provider Provider
+	switch config.Type {
+	case "", ProviderAnthropic:
+		if config.Anthropic.APIKey == "" && !replaying {
+			return nil, fmt.Errorf("an API key is required for the %s provider", ProviderAnthropic)
+		}
+		provider = NewClaudeService(config.Anthropic, logger)
+	case ProviderOpenAI:
+		if config.OpenAI.Model == "" {
+			return nil, fmt.Errorf("a model is required for the %s provider", ProviderOpenAI)
+		}
+		provider = NewOpenAIService(config.OpenAI, logger)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q (expected %q or %q)", config.Type, ProviderAnthropic, ProviderOpenAI)
+	}
+
+	if config.Cassette != nil {
+		provider.UseCassette(config.Cassette)
+	}
+	return provider, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/pkg/markdown"
+)
+
+// repairInstructions is appended to the developer prompt for repair turns.
+const repairInstructions = `
+
+## Build Repair
+The build check found problems with the project files after your last response. Fix exactly
+these problems: create missing files, correct syntax errors, fix the code that fails at
+runtime and fix the markup of accessibility and SEO problems. Don't make unrelated changes. Keep the explanation to a sentence or two.`
+
+// RepairStatus is the state of a repair iteration.
+type RepairStatus string
+
+const (
+	RepairStatusStarted   RepairStatus = "started"   // The repair turn is running
+	RepairStatusFinished  RepairStatus = "finished"  // The repair turn's changes were saved and the project checked again
+	RepairStatusFailed    RepairStatus = "failed"    // The repair turn failed; its changes were discarded and repairs stopped
+	RepairStatusCancelled RepairStatus = "cancelled" // The response was cancelled; repairs stopped, discarding the changes of an unfinished repair turn
+)
+
+// RepairIteration reports the progress of a repair turn, run after a chat turn leaves
+// critical completeness issues to let the developer agent fix them.
+type RepairIteration struct {
+	Iteration     int                       // 1-based
+	MaxIterations int                       // ChatConfig.MaxRepairIterations
+	Status        RepairStatus              // Started, finished or failed
+	Issues        []model.CompletenessIssue // Critical issues the iteration works on
+	Files         []string                  // Paths written or deleted by the iteration (finished only)
+	Report        *model.CompletenessReport // Report after the iteration (finished only)
+}
+
+// repair runs repair turns until the report has no critical issues or MaxRepairIterations
+// is reached, and returns the last report and the number of turns run. The turns' file
+// changes are added to the turn's versions and change set. Each repair turn has its own
+// TurnTimeout; cancelling ctx stops the repairs.
+func (s *ChatService) repair(ctx context.Context, turn *chatTurn, report *model.CompletenessReport, callbacks ChatCallbacks) (*model.CompletenessReport, int) {
+	systemPrompt := s.repairSystemPrompt(ctx, turn) + repairInstructions
+	notify := func(iteration RepairIteration) {
+		if callbacks.OnRepairIteration != nil {
+			callbacks.OnRepairIteration(iteration)
+		}
+	}
+
+	iterations := 0
+	for iterations < s.config.MaxRepairIterations && report.HasCriticalIssues() && !isCancelled(ctx) {
+		iterations++
+		iteration := RepairIteration{
+			Iteration:     iterations,
+			MaxIterations: s.config.MaxRepairIterations,
+			Status:        RepairStatusStarted,
+			Issues:        report.GetCriticalIssues(),
+		}
+		notify(iteration)
+
+		// Each repair turn has its own deadline rather than what is left of the turn's
+		repairCtx, cancelRepair := context.WithTimeout(ctx, s.config.TurnTimeout)
+		files, err := s.runRepairTurn(repairCtx, turn, systemPrompt, iteration.Issues, callbacks)
+		var next *model.CompletenessReport
+		if err == nil {
+			if next, err = s.completenessChecker.Check(repairCtx, turn.projectID); err != nil {
+				err = fmt.Errorf("completeness check after repair: %w", err)
+			}
+		}
+		cancelRepair()
+
+		if isCancelled(ctx) {
+			iteration.Status = RepairStatusCancelled
+			iteration.Files = files
+			notify(iteration)
+			break
+		}
+		if err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("projectId", turn.projectID.String()).
+				Int("iteration", iterations).
+				Msg("repair turn failed")
+			iteration.Status = RepairStatusFailed
+			notify(iteration)
+			break
+		}
+		report = next
+		s.recordSyntaxErrors(turn.projectID, report.GetSyntaxErrors())
+
+		iteration.Status = RepairStatusFinished
+		iteration.Files = files
+		iteration.Report = report
+		notify(iteration)
+	}
+
+	s.logger.Info().
+		Str("projectId", turn.projectID.String()).
+		Int("iterations", iterations).
+		Int("criticalIssues", len(report.GetCriticalIssues())).
+		Msg("repair loop completed")
+
+	return report, iterations
+}
+
+// runRepairTurn asks the developer agent to fix issues, with the conversation so far as
+// context, and applies its file changes. It returns the paths of the changed files. A turn
+// that is cancelled or stopped at the tool limit leaves the files as they were.
+func (s *ChatService) runRepairTurn(ctx context.Context, turn *chatTurn, systemPrompt string, issues []model.CompletenessIssue, callbacks ChatCallbacks) ([]string, error) {
+	messages, err := s.repo.GetMessages(ctx, turn.projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	summary, claudeMessages := s.conversationContext(ctx, turn.projectID, messages)
+	claudeMessages = append(claudeMessages, ClaudeMessage{Role: "user", Content: repairRequest(issues)})
+	if summary != nil {
+		systemPrompt = withConversationSummary(systemPrompt, summary)
+	}
+
+	agentType := string(model.AgentDeveloper)
+	repairTurn := s.newTurn(turn.projectID, &agentType)
+	repairTurn.prdID = turn.prdID
+
+	// The repair's text isn't shown or saved; only its file changes are kept
+	fileCallbacks := ChatCallbacks{
+		OnFileCreated: callbacks.OnFileCreated,
+		OnFileDeleted: callbacks.OnFileDeleted,
+		OnPRDUpdated:  callbacks.OnPRDUpdated,
+	}
+	response, err := s.processStreamWithTools(ctx, repairTurn, systemPrompt, claudeMessages, fileCallbacks)
+	if err != nil {
+		s.discardTurnFiles(repairTurn)
+		return nil, err
+	}
+
+	saved := s.stageCodeBlocks(ctx, repairTurn, markdown.ExtractCodeBlocksWithMetadata(response))
+	if err := s.commitTurnFiles(ctx, repairTurn, fileCallbacks); err != nil {
+		return nil, err
+	}
+	for _, block := range saved {
+		s.saveCodeBlockMetadata(ctx, turn.projectID, block)
+	}
+
+	turn.versions = append(turn.versions, repairTurn.versions...)
+	turn.changes.merge(repairTurn.changes)
+
+	var files []string
+	if repairTurn.files != nil {
+		for _, staged := range repairTurn.files.changedPaths() {
+			files = append(files, staged.Path)
+		}
+	}
+	return files, nil
+}
+
+// repairSystemPrompt returns the developer agent's prompt for the turn's project, or the
+// default prompt without an agent context service.
+func (s *ChatService) repairSystemPrompt(ctx context.Context, turn *chatTurn) string {
+	if s.agentContextService == nil {
+		return DefaultSystemPrompt()
+	}
+
+	agentContext, err := s.agentContextService.GetContextForMessage(ctx, turn.projectID, turn.userMessage)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("projectId", turn.projectID.String()).
+			Msg("failed to get agent context for repair, using default prompt")
+		return DefaultSystemPrompt()
+	}
+	agentContext.Agent = model.AgentDeveloper
+	return s.getSystemPrompt(ctx, turn.projectID, nil, agentContext)
+}
+
+// repairRequest is the message asking the developer agent to fix issues.
+func repairRequest(issues []model.CompletenessIssue) string {
+	var b strings.Builder
+	b.WriteString("The build check found these problems:\n\n")
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "- %s\n", describeIssue(issue))
+	}
+	b.WriteString("\nPlease fix them.")
+	return b.String()
+}
+
+// describeIssue describes a completeness issue in one line.
+func describeIssue(issue model.CompletenessIssue) string {
+	switch issue.Type {
+	case "missing_file":
+		return fmt.Sprintf("%s is missing; %s references it on line %d: %s", issue.MissingFile, issue.ReferencedBy, issue.LineNumber, issue.Context)
+	case "syntax_error":
+		position := fmt.Sprintf("line %d", issue.LineNumber)
+		if issue.Column > 0 {
+			position += fmt.Sprintf(", column %d", issue.Column)
+		}
+		return fmt.Sprintf("Syntax error in %s at %s: %s", issue.ReferencedBy, position, issue.Context)
+	case "runtime_error":
+		if issue.LineNumber > 0 {
+			return fmt.Sprintf("Runtime error in %s on line %d: %s", issue.ReferencedBy, issue.LineNumber, issue.Context)
+		}
+		return fmt.Sprintf("Runtime error in %s: %s", issue.ReferencedBy, issue.Context)
+	case "accessibility", "seo":
+		kind := "SEO problem"
+		if issue.Type == "accessibility" {
+			kind = fmt.Sprintf("Accessibility problem (WCAG %s)", issue.WCAG)
+		}
+		return fmt.Sprintf("%s in %s on line %d: %s. %s", kind, issue.ReferencedBy, issue.LineNumber, issue.Context, issue.Suggestion)
+	}
+	return fmt.Sprintf("%s in %s: %s", issue.Type, issue.ReferencedBy, issue.Context)
+}
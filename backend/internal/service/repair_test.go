@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+const repairTestPage = "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<script src=\"js/app.js\"></script>\n<title>Menu</title>\n" +
+	"<meta name=\"description\" content=\"Our menu.\">\n<meta name=\"viewport\" content=\"width=device-width\">\n</head>\n" +
+	"<body>\n<h1>Menu</h1>\n</body>\n</html>\n"
+
+// newRepairTestChatService returns a chat service with change sets, using the Claude server at url.
+func newRepairTestChatService(url string, maxRepairIterations int) (*ChatService, *repository.MockProjectRepository, *repository.MockFileRepository) {
+	logger := zerolog.Nop()
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	claudeService := NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: url}, logger)
+	chatService := NewChatService(ChatConfig{MaxRepairIterations: maxRepairIterations}, claudeService, nil, nil, repo, fileRepo, nil, logger)
+	chatService.SetChangeSets(NewChangeSetService(repository.NewMockFileChangeRepository(), repo, logger))
+	return chatService, repo, fileRepo
+}
+
+func TestChatService_ProcessMessage_RepairsCriticalIssues(t *testing.T) {
+	server, bodies := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_page", "write_file", map[string]interface{}{"path": "index.html", "content": repairTestPage}),
+		textTurnEvents("Here is your page."),
+		toolUseTurnEvents("toolu_script", "write_file", map[string]interface{}{"path": "js/app.js", "content": "console.log('ready');\n"}),
+		textTurnEvents("Added the missing script."),
+	)
+	defer server.Close()
+
+	chatService, repo, fileRepo := newRepairTestChatService(server.URL, 2)
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var iterations []RepairIteration
+	var created []string
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Make a page", ChatCallbacks{
+		OnFileCreated:     func(path string) { created = append(created, path) },
+		OnRepairIteration: func(iteration RepairIteration) { iterations = append(iterations, iteration) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.RepairIterations)
+	require.NotNil(t, result.CompletenessReport)
+	assert.Equal(t, model.StatusPass, result.CompletenessReport.Status)
+	assert.Equal(t, "Here is your page.", result.Content)
+
+	require.Len(t, iterations, 2)
+	assert.Equal(t, RepairStatusStarted, iterations[0].Status)
+	assert.Equal(t, 1, iterations[0].Iteration)
+	assert.Equal(t, 2, iterations[0].MaxIterations)
+	require.Len(t, iterations[0].Issues, 1)
+	assert.Equal(t, "js/app.js", iterations[0].Issues[0].MissingFile)
+	assert.Nil(t, iterations[0].Report)
+	assert.Equal(t, RepairStatusFinished, iterations[1].Status)
+	assert.Equal(t, []string{"js/app.js"}, iterations[1].Files)
+	assert.Equal(t, model.StatusPass, iterations[1].Report.Status)
+
+	// The repair request lists the issue after the conversation so far
+	requests := bodies()
+	require.Len(t, requests, 4)
+	assert.Contains(t, requests[2], "## Build Repair")
+	assert.Contains(t, requests[2], "js/app.js is missing; index.html references it on line 4")
+
+	// The repair's files are saved and part of the message's change set; its text is not saved
+	assert.Equal(t, []string{"index.html", "js/app.js"}, created)
+	_, err = fileRepo.GetFileByPath(ctx, project.ID, "js/app.js")
+	require.NoError(t, err)
+	require.NotNil(t, result.Changes)
+	assert.Len(t, result.Changes.Added, 2)
+	messages, _ := repo.GetMessages(ctx, project.ID)
+	assert.Len(t, messages, 2)
+}
+
+func TestChatService_ProcessMessage_RepairBudget(t *testing.T) {
+	server, bodies := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_page", "write_file", map[string]interface{}{"path": "index.html", "content": repairTestPage}),
+		textTurnEvents("I can't fix that."),
+	)
+	defer server.Close()
+
+	chatService, repo, _ := newRepairTestChatService(server.URL, 2)
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var statuses []RepairStatus
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Make a page", ChatCallbacks{
+		OnRepairIteration: func(iteration RepairIteration) { statuses = append(statuses, iteration.Status) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.RepairIterations)
+	assert.Equal(t, model.StatusCritical, result.CompletenessReport.Status)
+	assert.Equal(t, []RepairStatus{RepairStatusStarted, RepairStatusFinished, RepairStatusStarted, RepairStatusFinished}, statuses)
+	assert.Len(t, bodies(), 4)
+}
+
+func TestChatService_ProcessMessage_RepairDisabled(t *testing.T) {
+	server, bodies := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_page", "write_file", map[string]interface{}{"path": "index.html", "content": repairTestPage}),
+		textTurnEvents("Here is your page."),
+	)
+	defer server.Close()
+
+	chatService, repo, _ := newRepairTestChatService(server.URL, 0)
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Make a page", func(string) {}, nil)
+	require.NoError(t, err)
+
+	assert.Zero(t, result.RepairIterations)
+	assert.Equal(t, model.StatusCritical, result.CompletenessReport.Status)
+	assert.Len(t, bodies(), 2)
+}
+
+func TestChatService_ProcessMessage_RepairTurnFails(t *testing.T) {
+	server, bodies := newScriptedClaudeServer(t,
+		toolUseTurnEvents("toolu_page", "write_file", map[string]interface{}{"path": "index.html", "content": repairTestPage}),
+		textTurnEvents("Here is your page."),
+		toolUseTurnEvents("toolu_script", "write_file", map[string]interface{}{"path": "js/app.js", "content": "init();\n"}),
+	)
+	defer server.Close()
+
+	// The repair keeps calling tools and is stopped at the tool limit
+	chatService, repo, fileRepo := newRepairTestChatService(server.URL, 2)
+	chatService.config.MaxToolIterations = 1
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var statuses []RepairStatus
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Make a page", ChatCallbacks{
+		OnRepairIteration: func(iteration RepairIteration) { statuses = append(statuses, iteration.Status) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.RepairIterations)
+	assert.Equal(t, []RepairStatus{RepairStatusStarted, RepairStatusFailed}, statuses)
+	assert.Equal(t, model.StatusCritical, result.CompletenessReport.Status)
+	assert.Len(t, bodies(), 4)
+	_, err = fileRepo.GetFileByPath(ctx, project.ID, "js/app.js")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "the failed repair's files are discarded")
+}
+
+// hookedClaudeMessenger runs a hook before passing each request on, with the request's
+// 0-based index; an error from the hook fails the request.
+type hookedClaudeMessenger struct {
+	ClaudeMessenger
+	mu     sync.Mutex
+	calls  int
+	before func(ctx context.Context, call int) error
+}
+
+func (m *hookedClaudeMessenger) hook(ctx context.Context) error {
+	m.mu.Lock()
+	call := m.calls
+	m.calls++
+	m.mu.Unlock()
+	return m.before(ctx, call)
+}
+
+func (m *hookedClaudeMessenger) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	if err := m.hook(ctx); err != nil {
+		return nil, err
+	}
+	return m.ClaudeMessenger.SendMessage(ctx, systemPrompt, messages)
+}
+
+func (m *hookedClaudeMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	if err := m.hook(ctx); err != nil {
+		return nil, err
+	}
+	return m.ClaudeMessenger.SendMessageWithToolResults(ctx, systemPrompt, messages, assistantContent, toolResults)
+}
+
+// repairScript is a turn that writes a page missing its script, then a repair turn adding it.
+var repairScript = []scriptedResponse{
+	{toolUses: []ToolUseBlock{writeFileCall("index.html", repairTestPage)}},
+	{text: "Here is your page."},
+	{toolUses: []ToolUseBlock{writeFileCall("js/app.js", "console.log('ready');\n")}},
+	{text: "Added the missing script."},
+}
+
+func TestChatService_ProcessMessage_RepairTurnsHaveTheirOwnDeadline(t *testing.T) {
+	// The turn and the repair turn each take most of the timeout, together more than all of it
+	wait := func(ctx context.Context, call int) error {
+		if call%2 == 0 {
+			return nil
+		}
+		select {
+		case <-time.After(300 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	claude := &hookedClaudeMessenger{ClaudeMessenger: &scriptedClaudeMessenger{responses: repairScript}, before: wait}
+	repo := repository.NewMockProjectRepository()
+	chatService := NewChatService(ChatConfig{MaxRepairIterations: 1, TurnTimeout: 500 * time.Millisecond}, claude, nil, nil, repo, repository.NewMockFileRepository(), nil, zerolog.Nop())
+	chatService.SetChangeSets(NewChangeSetService(repository.NewMockFileChangeRepository(), repo, zerolog.Nop()))
+	ctx := context.Background()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var statuses []RepairStatus
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Make a page", ChatCallbacks{
+		OnRepairIteration: func(iteration RepairIteration) { statuses = append(statuses, iteration.Status) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []RepairStatus{RepairStatusStarted, RepairStatusFinished}, statuses)
+	assert.Equal(t, model.StatusPass, result.CompletenessReport.Status)
+	require.NotNil(t, result.Changes, "the change set is saved after the turn's deadline")
+	assert.Len(t, result.Changes.Added, 2)
+}
+
+func TestChatService_ProcessMessage_CancelDuringRepair(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnRepair := func(ctx context.Context, call int) error {
+		if call == 2 { // The repair turn's first request
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+	claude := &hookedClaudeMessenger{ClaudeMessenger: &scriptedClaudeMessenger{responses: repairScript}, before: cancelOnRepair}
+	repo := repository.NewMockProjectRepository()
+	fileRepo := repository.NewMockFileRepository()
+	chatService := NewChatService(ChatConfig{MaxRepairIterations: 2}, claude, nil, nil, repo, fileRepo, nil, zerolog.Nop())
+	chatService.SetChangeSets(NewChangeSetService(repository.NewMockFileChangeRepository(), repo, zerolog.Nop()))
+	project, _ := repo.Create(ctx, "Test Project")
+
+	var statuses []RepairStatus
+	result, err := chatService.ProcessMessageWithCallbacks(ctx, project.ID, "Make a page", ChatCallbacks{
+		OnRepairIteration: func(iteration RepairIteration) { statuses = append(statuses, iteration.Status) },
+	})
+	require.NoError(t, err)
+
+	// Repairs stop and the message is reported and saved as cancelled, with the turn's files
+	assert.Equal(t, []RepairStatus{RepairStatusStarted, RepairStatusCancelled}, statuses)
+	assert.True(t, result.Cancelled)
+	assert.Equal(t, 1, result.RepairIterations)
+	assert.Equal(t, "Here is your page.", result.Content)
+	require.NotNil(t, result.Changes)
+	assert.Len(t, result.Changes.Added, 1)
+
+	messages, _ := repo.GetMessages(context.Background(), project.ID)
+	require.Len(t, messages, 2)
+	assert.True(t, messages[1].Cancelled)
+	_, err = fileRepo.GetFileByPath(context.Background(), project.ID, "index.html")
+	require.NoError(t, err)
+}
+
+func TestDescribeIssue(t *testing.T) {
+	tests := []struct {
+		issue model.CompletenessIssue
+		want  string
+	}{
+		{
+			model.CompletenessIssue{Type: "missing_file", MissingFile: "css/site.css", ReferencedBy: "index.html", LineNumber: 5, Context: `<link href="css/site.css">`},
+			`css/site.css is missing; index.html references it on line 5: <link href="css/site.css">`,
+		},
+		{
+			model.CompletenessIssue{Type: "syntax_error", ReferencedBy: "js/app.js", LineNumber: 3, Column: 9, Context: "string is never closed"},
+			"Syntax error in js/app.js at line 3, column 9: string is never closed",
+		},
+		{
+			model.CompletenessIssue{Type: "syntax_error", ReferencedBy: "config.yaml", LineNumber: 2, Context: "did not find expected key"},
+			"Syntax error in config.yaml at line 2: did not find expected key",
+		},
+		{
+			model.CompletenessIssue{Type: "runtime_error", ReferencedBy: "js/app.js", LineNumber: 12, Context: "Uncaught TypeError: x is undefined"},
+			"Runtime error in js/app.js on line 12: Uncaught TypeError: x is undefined",
+		},
+		{
+			model.CompletenessIssue{Type: "runtime_error", ReferencedBy: "index.html", Context: "Uncaught ReferenceError: init is not defined"},
+			"Runtime error in index.html: Uncaught ReferenceError: init is not defined",
+		},
+		{
+			model.CompletenessIssue{Type: "accessibility", ReferencedBy: "index.html", LineNumber: 9, Context: `<img src="a.png"> has no alt attribute`,
+				WCAG: "1.1.1 Non-text Content", Suggestion: "Add an alt attribute describing the image."},
+			`Accessibility problem (WCAG 1.1.1 Non-text Content) in index.html on line 9: <img src="a.png"> has no alt attribute. Add an alt attribute describing the image.`,
+		},
+		{
+			model.CompletenessIssue{Type: "seo", ReferencedBy: "index.html", LineNumber: 3, Context: "the page has no meta description",
+				Suggestion: "Add a meta description."},
+			"SEO problem in index.html on line 3: the page has no meta description. Add a meta description.",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, describeIssue(tt.issue))
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// DefaultRoute is the route key applied to every call before a more specific route.
+const DefaultRoute = "default"
+
+// GenerationParams are the model and sampling settings of one LLM call.
+// Zero values mean "inherit": an empty Model or zero MaxTokens keeps the provider's
+// configured value, and a nil Temperature or empty StopSequences sends none.
+type GenerationParams struct {
+	Model         string   `json:"model,omitempty"`
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+// merge returns p with the non-zero fields of override applied.
+func (p GenerationParams) merge(override GenerationParams) GenerationParams {
+	if override.Model != "" {
+		p.Model = override.Model
+	}
+	if override.MaxTokens > 0 {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if len(override.StopSequences) > 0 {
+		p.StopSequences = override.StopSequences
+	}
+	return p
+}
+
+// ModelRoutes maps route keys to generation parameters. A key is a call purpose
+// (a model.UsageSource such as "chat", "discovery", "prd" or "vision"), an agent type
+// (a model.AgentType such as "developer"), both joined by a colon ("chat:developer"),
+// or DefaultRoute.
+type ModelRoutes map[string]GenerationParams
+
+// LoadModelRoutes reads routes from the JSON file at path and then from the inline JSON
+// in inline, with inline entries taking precedence. Both are optional, e.g.
+//
+//	{"discovery": {"model": "claude-3-5-haiku-latest"}, "chat:developer": {"maxTokens": 8192}}
+func LoadModelRoutes(path, inline string) (ModelRoutes, error) {
+	routes := make(ModelRoutes)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read model routes: %w", err)
+		}
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return nil, fmt.Errorf("failed to parse model routes: %w", err)
+		}
+	}
+
+	if inline != "" {
+		var overrides ModelRoutes
+		if err := json.Unmarshal([]byte(inline), &overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse inline model routes: %w", err)
+		}
+		for key, params := range overrides {
+			routes[key] = params
+		}
+	}
+
+	return routes, nil
+}
+
+// Resolve returns the parameters for a call and the key of the route that matched.
+// The purpose and agent type are read from the context's usage scope (see WithUsageScope).
+// Parameters start from base, then DefaultRoute, then the most specific matching route:
+// "purpose:agent", "purpose", "agent". The key is DefaultRoute when nothing more specific matched.
+func (r ModelRoutes) Resolve(ctx context.Context, base GenerationParams) (GenerationParams, string) {
+	params := base.merge(r[DefaultRoute])
+
+	purpose := string(model.UsageSourceOther)
+	agent := ""
+	if scope := usageScopeFrom(ctx); scope != nil {
+		purpose = string(scope.source)
+		if scope.agentType != nil {
+			agent = *scope.agentType
+		}
+	}
+
+	var keys []string
+	if agent != "" {
+		keys = append(keys, purpose+":"+agent)
+	}
+	keys = append(keys, purpose)
+	if agent != "" {
+		keys = append(keys, agent)
+	}
+
+	for _, key := range keys {
+		if route, ok := r[key]; ok {
+			return params.merge(route), key
+		}
+	}
+	return params, DefaultRoute
+}
+
+// Validate reports route keys that name no known purpose or agent type, which would never match.
+func (r ModelRoutes) Validate() error {
+	purposes := map[string]bool{}
+	for _, source := range []model.UsageSource{
+		model.UsageSourceChat, model.UsageSourceDiscovery, model.UsageSourcePRD, model.UsageSourceWelcome,
+		model.UsageSourceSummary, model.UsageSourceVision, model.UsageSourceFix, model.UsageSourceOther,
+	} {
+		purposes[string(source)] = true
+	}
+	agents := map[string]bool{
+		string(model.AgentProductManager): true,
+		string(model.AgentDesigner):       true,
+		string(model.AgentDeveloper):      true,
+	}
+
+	for key := range r {
+		if key == DefaultRoute || purposes[key] || agents[key] {
+			continue
+		}
+		purpose, agent, found := strings.Cut(key, ":")
+		if found && purposes[purpose] && agents[agent] {
+			continue
+		}
+		return fmt.Errorf("unknown model route %q", key)
+	}
+	return nil
+}
+
+// modelRouteKey is the context key for the route a call was made with.
+type modelRouteKey struct{}
+
+// withModelRoute returns a context carrying the route key, for usage records.
+func withModelRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, modelRouteKey{}, route)
+}
+
+// modelRouteFrom returns the route key of a context, or "" if there is none.
+func modelRouteFrom(ctx context.Context) string {
+	route, _ := ctx.Value(modelRouteKey{}).(string)
+	return route
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestModelRoutes_Resolve(t *testing.T) {
+	routes := ModelRoutes{
+		DefaultRoute:     {Temperature: floatPtr(1)},
+		"discovery":      {Model: "haiku", MaxTokens: 1024},
+		"chat":           {Model: "sonnet"},
+		"chat:developer": {MaxTokens: 16000, StopSequences: []string{"</done>"}},
+		"designer":       {Temperature: floatPtr(0.2)},
+	}
+	base := GenerationParams{Model: "base", MaxTokens: 4096}
+	projectID := uuid.New()
+	developer := string(model.AgentDeveloper)
+	designer := string(model.AgentDesigner)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected GenerationParams
+		route    string
+	}{
+		{
+			name:     "no scope uses defaults",
+			ctx:      context.Background(),
+			expected: GenerationParams{Model: "base", MaxTokens: 4096, Temperature: floatPtr(1)},
+			route:    DefaultRoute,
+		},
+		{
+			name:     "purpose",
+			ctx:      WithUsageScope(context.Background(), projectID, nil, model.UsageSourceDiscovery),
+			expected: GenerationParams{Model: "haiku", MaxTokens: 1024, Temperature: floatPtr(1)},
+			route:    "discovery",
+		},
+		{
+			name:     "purpose and agent is most specific",
+			ctx:      WithUsageScope(context.Background(), projectID, &developer, model.UsageSourceChat),
+			expected: GenerationParams{Model: "base", MaxTokens: 16000, Temperature: floatPtr(1), StopSequences: []string{"</done>"}},
+			route:    "chat:developer",
+		},
+		{
+			name:     "purpose before agent",
+			ctx:      WithUsageScope(context.Background(), projectID, &designer, model.UsageSourceChat),
+			expected: GenerationParams{Model: "sonnet", MaxTokens: 4096, Temperature: floatPtr(1)},
+			route:    "chat",
+		},
+		{
+			name:     "agent alone",
+			ctx:      WithUsageScope(context.Background(), projectID, &designer, model.UsageSourcePRD),
+			expected: GenerationParams{Model: "base", MaxTokens: 4096, Temperature: floatPtr(0.2)},
+			route:    "designer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, route := routes.Resolve(tt.ctx, base)
+			assert.Equal(t, tt.expected, params)
+			assert.Equal(t, tt.route, route)
+		})
+	}
+
+	var none ModelRoutes
+	params, route := none.Resolve(context.Background(), base)
+	assert.Equal(t, base, params, "a nil table keeps the provider's settings")
+	assert.Equal(t, DefaultRoute, route)
+}
+
+func TestModelRoutes_Validate(t *testing.T) {
+	valid := ModelRoutes{DefaultRoute: {}, "vision": {}, "developer": {}, "prd:product_manager": {}}
+	assert.NoError(t, valid.Validate())
+
+	assert.Error(t, ModelRoutes{"chatt": {}}.Validate())
+	assert.Error(t, ModelRoutes{"chat:tester": {}}.Validate())
+	assert.Error(t, ModelRoutes{"developer:chat": {}}.Validate())
+}
+
+func TestLoadModelRoutes(t *testing.T) {
+	routes, err := LoadModelRoutes("", "")
+	require.NoError(t, err)
+	assert.Empty(t, routes)
+
+	path := filepath.Join(t.TempDir(), "routes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"discovery": {"model": "haiku"}, "vision": {"maxTokens": 512}}`), 0o644))
+
+	routes, err = LoadModelRoutes(path, `{"discovery": {"model": "sonnet", "temperature": 0.5}}`)
+	require.NoError(t, err)
+	assert.Equal(t, GenerationParams{Model: "sonnet", Temperature: floatPtr(0.5)}, routes["discovery"], "inline routes replace file routes")
+	assert.Equal(t, 512, routes["vision"].MaxTokens)
+
+	_, err = LoadModelRoutes(filepath.Join(t.TempDir(), "missing.json"), "")
+	assert.Error(t, err)
+
+	_, err = LoadModelRoutes("", "{not json")
+	assert.Error(t, err)
+}
+
+// newCapturingClaudeServer streams a usage-reporting text turn and captures request bodies.
+func newCapturingClaudeServer(t *testing.T) (*httptest.Server, func() []map[string]interface{}) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range usageTurnEvents("OK") {
+			w.Write([]byte(event))
+		}
+	}))
+
+	return server, func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]interface{}(nil), bodies...)
+	}
+}
+
+func TestClaudeService_RoutesByScope(t *testing.T) {
+	server, bodies := newCapturingClaudeServer(t)
+	defer server.Close()
+
+	usageRepo := repository.NewMockUsageRepository()
+	svc := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+		Routes: ModelRoutes{
+			"discovery": {Model: "claude-3-5-haiku-latest", MaxTokens: 1024, Temperature: floatPtr(0.7), StopSequences: []string{"STOP"}},
+		},
+	}, zerolog.Nop())
+	svc.SetUsageRecorder(NewUsageService(nil, usageRepo, zerolog.Nop()))
+
+	send := func(source model.UsageSource) {
+		ctx := WithUsageScope(context.Background(), uuid.New(), nil, source)
+		stream, err := svc.SendMessage(ctx, "system", []ClaudeMessage{{Role: "user", Content: "Hello"}})
+		require.NoError(t, err)
+		for range stream.Chunks() {
+		}
+		stream.Close()
+	}
+	send(model.UsageSourceDiscovery)
+	send(model.UsageSourceChat)
+
+	requests := bodies()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "claude-3-5-haiku-latest", requests[0]["model"])
+	assert.Equal(t, float64(1024), requests[0]["max_tokens"])
+	assert.Equal(t, 0.7, requests[0]["temperature"])
+	assert.Equal(t, []interface{}{"STOP"}, requests[0]["stop_sequences"])
+
+	assert.Equal(t, "claude-sonnet-4-20250514", requests[1]["model"])
+	assert.Equal(t, float64(4096), requests[1]["max_tokens"])
+	assert.NotContains(t, requests[1], "temperature", "unset parameters are not sent")
+	assert.NotContains(t, requests[1], "stop_sequences")
+
+	records := usageRepo.All()
+	require.Len(t, records, 2)
+	assert.Equal(t, "claude-3-5-haiku-latest", records[0].Model, "usage is recorded under the routed model")
+	require.NotNil(t, records[0].Route)
+	assert.Equal(t, "discovery", *records[0].Route)
+	assert.Equal(t, "claude-sonnet-4-20250514", records[1].Model)
+	assert.Equal(t, DefaultRoute, *records[1].Route)
+}
+
+func TestOpenAIService_RoutesByScope(t *testing.T) {
+	server, requests := newScriptedOpenAIServer(t, openAITextTurn("OK"))
+	defer server.Close()
+
+	svc := NewOpenAIService(OpenAIConfig{
+		Model:   "qwen2.5-coder",
+		BaseURL: server.URL + "/v1",
+		Routes: ModelRoutes{
+			"chat:developer": {Model: "qwen2.5-coder:32b", Temperature: floatPtr(0), StopSequences: []string{"<|end|>"}},
+		},
+	}, zerolog.Nop())
+
+	developer := string(model.AgentDeveloper)
+	ctx := WithUsageScope(context.Background(), uuid.New(), &developer, model.UsageSourceChat)
+	stream, err := svc.SendMessage(ctx, "", []ClaudeMessage{{Role: "user", Content: "Build it"}})
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "qwen2.5-coder:32b", reqs[0].Model)
+	require.NotNil(t, reqs[0].Temperature)
+	assert.Equal(t, 0.0, *reqs[0].Temperature, "a zero temperature is sent, not dropped")
+	assert.Equal(t, []string{"<|end|>"}, reqs[0].Stop)
+}
+
+func TestBuildProjectUsage_ByModel(t *testing.T) {
+	projectID := uuid.New()
+	records := []model.UsageRecord{
+		{Model: "claude-sonnet-4-20250514", Source: model.UsageSourceChat, CostUSD: 2},
+		{Model: "claude-3-5-haiku-latest", Source: model.UsageSourceDiscovery, CostUSD: 0.1},
+		{Model: "claude-sonnet-4-20250514", Source: model.UsageSourceChat, CostUSD: 3},
+	}
+
+	usage := buildProjectUsage(projectID, records[0].CreatedAt, records)
+
+	require.Len(t, usage.ByModel, 2)
+	assert.Equal(t, "claude-3-5-haiku-latest", usage.ByModel[0].Model)
+	assert.Equal(t, "claude-sonnet-4-20250514", usage.ByModel[1].Model)
+	assert.Equal(t, 2, usage.ByModel[1].Calls)
+	assert.InDelta(t, 5.0, usage.ByModel[1].CostUSD, 1e-9)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// summarySystemPrompt instructs Claude to produce a rolling conversation summary.
+const summarySystemPrompt = `You summarize conversations between a user and an AI assistant that builds software projects.
+Write a concise recap in markdown that someone could read instead of the full conversation. Cover:
+- What the project is and who it is for
+- Key decisions and agreed requirements
+- Files that were created or changed, and why
+- Open questions or next steps
+If a previous summary is provided, merge it with the new messages into a single updated summary.
+Output only the summary.`
+
+// SummaryConfig holds configuration for conversation compaction.
+type SummaryConfig struct {
+	TokenBudget  int // Estimated tokens of unsummarized history that triggers compaction
+	MessageLimit int // Unsummarized messages that trigger compaction; at most ChatConfig.ContextMessageLimit
+	KeepRecent   int // Most recent messages kept verbatim when compacting
+}
+
+// SummaryService compacts older conversation history into rolling summaries.
+type SummaryService struct {
+	config        SummaryConfig
+	summaryRepo   repository.SummaryRepository
+	projectRepo   repository.ProjectRepository
+	claudeService ClaudeMessenger
+	locks         projectLocks // Serialize compaction per project so concurrent triggers don't summarize the same messages twice
+	logger        zerolog.Logger
+}
+
+// projectLocks holds a mutex per project, kept while it is held or waited for.
+type projectLocks struct {
+	mu    sync.Mutex
+	locks map[uuid.UUID]*projectLock
+}
+
+// projectLock is a project's mutex and the number of callers holding or waiting for it.
+type projectLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks a project's mutex and returns the function that unlocks it.
+func (l *projectLocks) lock(projectID uuid.UUID) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[uuid.UUID]*projectLock)
+	}
+	pl, ok := l.locks[projectID]
+	if !ok {
+		pl = &projectLock{}
+		l.locks[projectID] = pl
+	}
+	pl.refs++
+	l.mu.Unlock()
+
+	pl.mu.Lock()
+	return func() {
+		pl.mu.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if pl.refs--; pl.refs == 0 {
+			delete(l.locks, projectID)
+		}
+	}
+}
+
+// NewSummaryService creates a new SummaryService.
+func NewSummaryService(
+	config SummaryConfig,
+	summaryRepo repository.SummaryRepository,
+	projectRepo repository.ProjectRepository,
+	claudeService ClaudeMessenger,
+	logger zerolog.Logger,
+) *SummaryService {
+	if config.TokenBudget <= 0 {
+		config.TokenBudget = 12000
+	}
+	if config.MessageLimit <= 0 {
+		config.MessageLimit = 20
+	}
+	if config.KeepRecent <= 0 {
+		config.KeepRecent = 10
+	}
+	config.KeepRecent = min(config.KeepRecent, config.MessageLimit)
+
+	return &SummaryService{
+		config:        config,
+		summaryRepo:   summaryRepo,
+		projectRepo:   projectRepo,
+		claudeService: claudeService,
+		logger:        logger.With().Str("component", "summary").Logger(),
+	}
+}
+
+// PrepareContext returns the latest summary and the messages it does not cover.
+// If those messages exceed the token budget or the message limit, the older ones are
+// compacted into a new summary first, keeping the most recent messages verbatim, so
+// every message is either sent or summarized.
+func (s *SummaryService) PrepareContext(ctx context.Context, projectID uuid.UUID, messages []model.Message) (*model.ConversationSummary, []model.Message, error) {
+	defer s.locks.lock(projectID)()
+
+	latest, err := s.getLatest(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recent := messagesAfter(messages, latest)
+	var trigger model.SummaryTrigger
+	switch {
+	case len(recent) <= s.config.KeepRecent:
+		return latest, recent, nil
+	case estimateMessageTokens(recent) > s.config.TokenBudget:
+		trigger = model.SummaryTriggerTokenBudget
+	case len(recent) > s.config.MessageLimit:
+		trigger = model.SummaryTriggerMessageLimit
+	default:
+		return latest, recent, nil
+	}
+
+	split := len(recent) - s.config.KeepRecent
+	summary, err := s.compact(ctx, projectID, latest, recent[:split], trigger, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return summary, recent[split:], nil
+}
+
+// CompactForPRD records a summary when a PRD is completed, so the milestone is
+// captured even if the token budget has not been reached.
+func (s *SummaryService) CompactForPRD(ctx context.Context, projectID, prdID uuid.UUID) error {
+	defer s.locks.lock(projectID)()
+
+	messages, err := s.projectRepo.GetMessages(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	latest, err := s.getLatest(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	recent := messagesAfter(messages, latest)
+	if len(recent) <= s.config.KeepRecent {
+		return nil
+	}
+
+	_, err = s.compact(ctx, projectID, latest, recent[:len(recent)-s.config.KeepRecent], model.SummaryTriggerPRDComplete, &prdID)
+	return err
+}
+
+// ListSummaries returns all summaries for a project, oldest first.
+func (s *SummaryService) ListSummaries(ctx context.Context, projectID uuid.UUID) ([]model.ConversationSummary, error) {
+	summaries, err := s.summaryRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list summaries: %w", err)
+	}
+	if summaries == nil {
+		summaries = []model.ConversationSummary{}
+	}
+	return summaries, nil
+}
+
+// compact summarizes messages on top of the previous summary and stores the result.
+func (s *SummaryService) compact(
+	ctx context.Context,
+	projectID uuid.UUID,
+	previous *model.ConversationSummary,
+	messages []model.Message,
+	trigger model.SummaryTrigger,
+	prdID *uuid.UUID,
+) (*model.ConversationSummary, error) {
+	content, err := s.summarize(WithUsageScope(ctx, projectID, nil, model.UsageSourceSummary), previous, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	through := messages[len(messages)-1]
+	summary, err := s.summaryRepo.Create(ctx, &model.ConversationSummary{
+		ProjectID:        projectID,
+		Content:          content,
+		ThroughMessageID: &through.ID,
+		ThroughCreatedAt: &through.CreatedAt,
+		MessageCount:     len(messages),
+		Trigger:          trigger,
+		PRDID:            prdID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save summary: %w", err)
+	}
+
+	s.logger.Info().
+		Str("projectId", projectID.String()).
+		Str("trigger", string(trigger)).
+		Int("messages", len(messages)).
+		Msg("compacted conversation into summary")
+
+	return summary, nil
+}
+
+// summarize asks Claude for an updated summary covering the previous summary and the new messages.
+func (s *SummaryService) summarize(ctx context.Context, previous *model.ConversationSummary, messages []model.Message) (string, error) {
+	var transcript strings.Builder
+	if previous != nil {
+		transcript.WriteString("Previous summary:\n")
+		transcript.WriteString(previous.Content)
+		transcript.WriteString("\n\nNew messages:\n")
+	}
+	for _, msg := range messages {
+		if msg.Content == "" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "\n[%s]\n%s\n", msg.Role, msg.Content)
+	}
+
+	stream, err := s.claudeService.SendMessage(ctx, summarySystemPrompt, []ClaudeMessage{
+		{Role: "user", Content: transcript.String()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send summary request to Claude: %w", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for chunk := range stream.Chunks() {
+		content.WriteString(chunk)
+	}
+
+	if stream.Err() != nil {
+		return "", fmt.Errorf("stream error: %w", stream.Err())
+	}
+
+	result := strings.TrimSpace(content.String())
+	if result == "" {
+		return "", errors.New("Claude returned an empty summary")
+	}
+
+	return result, nil
+}
+
+// getLatest returns the latest summary for a project, or nil if there is none.
+func (s *SummaryService) getLatest(ctx context.Context, projectID uuid.UUID) (*model.ConversationSummary, error) {
+	latest, err := s.summaryRepo.GetLatest(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest summary: %w", err)
+	}
+	return latest, nil
+}
+
+// messagesAfter returns the messages not yet covered by a summary. If the summarized message
+// no longer exists, the messages created after it are returned, or all messages for a
+// summary without a cutoff time.
+func messagesAfter(messages []model.Message, summary *model.ConversationSummary) []model.Message {
+	if summary == nil {
+		return messages
+	}
+	if summary.ThroughMessageID != nil {
+		for i, msg := range messages {
+			if msg.ID == *summary.ThroughMessageID {
+				return messages[i+1:]
+			}
+		}
+	}
+	if summary.ThroughCreatedAt != nil {
+		for i, msg := range messages {
+			if msg.CreatedAt.After(*summary.ThroughCreatedAt) {
+				return messages[i:]
+			}
+		}
+		return messages[len(messages):]
+	}
+	return messages
+}
+
+// estimateMessageTokens roughly estimates the token count of messages (~4 characters per token).
+func estimateMessageTokens(messages []model.Message) int {
+	chars := 0
+	for _, msg := range messages {
+		chars += len(msg.Content)
+	}
+	return chars / 4
+}
+
+// withConversationSummary adds a summary of earlier messages to a system prompt.
+func withConversationSummary(systemPrompt string, summary *model.ConversationSummary) string {
+	return systemPrompt + "\n\n## Conversation So Far\n\n" +
+		"Earlier messages in this conversation have been summarized below. " +
+		"Treat the summary as established context.\n\n" + summary.Content
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func newTestSummaryService(config SummaryConfig) (*SummaryService, *repository.MockSummaryRepository, *repository.MockProjectRepository) {
+	summaryRepo := repository.NewMockSummaryRepository()
+	projectRepo := repository.NewMockProjectRepository()
+	claude := &MockClaudeMessengerForPRD{Response: "User is building a bakery site. Decided on a single index.html."}
+	return NewSummaryService(config, summaryRepo, projectRepo, claude, zerolog.Nop()), summaryRepo, projectRepo
+}
+
+// seedMessages creates n alternating user/assistant messages of ~40 characters each.
+func seedMessages(t *testing.T, repo *repository.MockProjectRepository, projectID uuid.UUID, n int) []model.Message {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		role := model.RoleUser
+		if i%2 == 1 {
+			role = model.RoleAssistant
+		}
+		_, err := repo.CreateMessage(ctx, projectID, role, strings.Repeat("m", 40))
+		require.NoError(t, err)
+	}
+	messages, err := repo.GetMessages(ctx, projectID)
+	require.NoError(t, err)
+	return messages
+}
+
+func TestSummaryService_PrepareContext_UnderBudget(t *testing.T) {
+	svc, summaryRepo, projectRepo := newTestSummaryService(SummaryConfig{TokenBudget: 1000, KeepRecent: 2})
+	project, _ := projectRepo.Create(context.Background(), "Test Project")
+	messages := seedMessages(t, projectRepo, project.ID, 6)
+
+	summary, recent, err := svc.PrepareContext(context.Background(), project.ID, messages)
+	require.NoError(t, err)
+
+	assert.Nil(t, summary)
+	assert.Len(t, recent, 6)
+
+	summaries, _ := summaryRepo.ListByProject(context.Background(), project.ID)
+	assert.Empty(t, summaries)
+}
+
+func TestSummaryService_PrepareContext_CompactsOverBudget(t *testing.T) {
+	svc, summaryRepo, projectRepo := newTestSummaryService(SummaryConfig{TokenBudget: 30, KeepRecent: 2})
+	ctx := context.Background()
+	project, _ := projectRepo.Create(ctx, "Test Project")
+	messages := seedMessages(t, projectRepo, project.ID, 6) // ~60 tokens
+
+	summary, recent, err := svc.PrepareContext(ctx, project.ID, messages)
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, model.SummaryTriggerTokenBudget, summary.Trigger)
+	assert.Equal(t, 4, summary.MessageCount)
+	assert.Equal(t, messages[3].ID, *summary.ThroughMessageID)
+	assert.Equal(t, messages[3].CreatedAt, *summary.ThroughCreatedAt)
+	assert.Equal(t, messages[4:], recent, "most recent messages are kept verbatim")
+
+	// The next turn reuses the stored summary without compacting again
+	summary2, recent2, err := svc.PrepareContext(ctx, project.ID, messages)
+	require.NoError(t, err)
+	assert.Equal(t, summary.ID, summary2.ID)
+	assert.Len(t, recent2, 2)
+
+	summaries, _ := summaryRepo.ListByProject(ctx, project.ID)
+	assert.Len(t, summaries, 1)
+}
+
+func TestSummaryService_PrepareContext_CompactsOverMessageLimit(t *testing.T) {
+	svc, _, projectRepo := newTestSummaryService(SummaryConfig{TokenBudget: 100000, MessageLimit: 20, KeepRecent: 10})
+	ctx := context.Background()
+	project, _ := projectRepo.Create(ctx, "Test Project")
+	messages := seedMessages(t, projectRepo, project.ID, 25) // ~250 tokens, well under budget
+
+	summary, recent, err := svc.PrepareContext(ctx, project.ID, messages)
+	require.NoError(t, err)
+	require.NotNil(t, summary)
+
+	assert.Equal(t, model.SummaryTriggerMessageLimit, summary.Trigger)
+	assert.Equal(t, 15, summary.MessageCount)
+	assert.Equal(t, messages[15:], recent)
+}
+
+// blockingMessenger is a Claude messenger whose summaries wait until release is closed.
+type blockingMessenger struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingMessenger) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	m.started <- struct{}{}
+	<-m.release
+	return (&MockClaudeMessengerForPRD{Response: "Summary."}).SendMessage(ctx, systemPrompt, messages)
+}
+
+func (m *blockingMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	return m.SendMessage(ctx, systemPrompt, messages)
+}
+
+func TestSummaryService_PrepareContext_CompactionDoesNotBlockOtherProjects(t *testing.T) {
+	projectRepo := repository.NewMockProjectRepository()
+	claude := &blockingMessenger{started: make(chan struct{}, 1), release: make(chan struct{})}
+	svc := NewSummaryService(SummaryConfig{TokenBudget: 30, KeepRecent: 2}, repository.NewMockSummaryRepository(), projectRepo, claude, zerolog.Nop())
+	ctx := context.Background()
+
+	compacting, _ := projectRepo.Create(ctx, "Compacting")
+	long := seedMessages(t, projectRepo, compacting.ID, 6)
+	other, _ := projectRepo.Create(ctx, "Other")
+	short := seedMessages(t, projectRepo, other.ID, 1)
+
+	compacted := make(chan error, 1)
+	go func() {
+		_, _, err := svc.PrepareContext(ctx, compacting.ID, long)
+		compacted <- err
+	}()
+	<-claude.started
+
+	done := make(chan struct{})
+	go func() {
+		_, recent, err := svc.PrepareContext(ctx, other.ID, short)
+		assert.NoError(t, err)
+		assert.Len(t, recent, 1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("another project's compaction blocked PrepareContext")
+	}
+
+	close(claude.release)
+	require.NoError(t, <-compacted)
+}
+
+func TestSummaryService_CompactForPRD(t *testing.T) {
+	svc, summaryRepo, projectRepo := newTestSummaryService(SummaryConfig{TokenBudget: 100000, KeepRecent: 2})
+	ctx := context.Background()
+	project, _ := projectRepo.Create(ctx, "Test Project")
+	seedMessages(t, projectRepo, project.ID, 5)
+	prdID := uuid.New()
+
+	require.NoError(t, svc.CompactForPRD(ctx, project.ID, prdID))
+
+	latest, err := summaryRepo.GetLatest(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SummaryTriggerPRDComplete, latest.Trigger)
+	assert.Equal(t, prdID, *latest.PRDID)
+	assert.Equal(t, 3, latest.MessageCount)
+
+	// Nothing new to summarize
+	require.NoError(t, svc.CompactForPRD(ctx, project.ID, uuid.New()))
+	summaries, _ := summaryRepo.ListByProject(ctx, project.ID)
+	assert.Len(t, summaries, 1)
+}
+
+func TestMessagesAfter(t *testing.T) {
+	start := time.Now().UTC()
+	messages := []model.Message{
+		{ID: uuid.New(), CreatedAt: start},
+		{ID: uuid.New(), CreatedAt: start.Add(time.Second)},
+		{ID: uuid.New(), CreatedAt: start.Add(2 * time.Second)},
+	}
+
+	assert.Len(t, messagesAfter(messages, nil), 3)
+
+	through := messages[1].ID
+	assert.Equal(t, messages[2:], messagesAfter(messages, &model.ConversationSummary{ThroughMessageID: &through, ThroughCreatedAt: &messages[1].CreatedAt}))
+
+	// The summarized message was deleted: its ID is gone, its cutoff time remains
+	deleted := start.Add(1500 * time.Millisecond)
+	assert.Equal(t, messages[2:], messagesAfter(messages, &model.ConversationSummary{ThroughCreatedAt: &deleted}))
+	latest := start.Add(3 * time.Second)
+	assert.Empty(t, messagesAfter(messages, &model.ConversationSummary{ThroughCreatedAt: &latest}))
+
+	assert.Len(t, messagesAfter(messages, &model.ConversationSummary{}), 3,
+		"all messages are sent for a summary without a cutoff")
+}
+
+// recordingCompactor records CompactForPRD calls.
+type recordingCompactor struct {
+	calls chan uuid.UUID
+}
+
+func (c *recordingCompactor) CompactForPRD(ctx context.Context, projectID, prdID uuid.UUID) error {
+	c.calls <- prdID
+	return nil
+}
+
+func TestPRDService_CompleteImplementation_TriggersCompaction(t *testing.T) {
+	svc, prdRepo, _, _ := newTestPRDService()
+	compactor := &recordingCompactor{calls: make(chan uuid.UUID, 1)}
+	svc.SetConversationCompactor(compactor)
+
+	prd, err := prdRepo.Create(context.Background(), &model.PRD{
+		ProjectID: uuid.New(),
+		Title:     "Checkout",
+		Status:    model.PRDStatusInProgress,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.CompleteImplementation(context.Background(), prd.ID))
+
+	select {
+	case prdID := <-compactor.calls:
+		assert.Equal(t, prd.ID, prdID)
+	case <-time.After(time.Second):
+		t.Fatal("expected conversation compaction to be triggered")
+	}
+}
+
+func TestChatService_ProcessMessage_IncludesSummaryInSystemPrompt(t *testing.T) {
+	var mu sync.Mutex
+	var systemPrompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			System string `json:"system"`
+		}
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		systemPrompts = append(systemPrompts, req.System)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range textTurnEvents("Sure.") {
+			w.Write([]byte(event))
+		}
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	summaries, summaryRepo, repo := newTestSummaryService(SummaryConfig{TokenBudget: 1000, KeepRecent: 2})
+	project, _ := repo.Create(ctx, "Test Project")
+	messages := seedMessages(t, repo, project.ID, 4)
+	through := messages[1].ID
+	_, err := summaryRepo.Create(ctx, &model.ConversationSummary{
+		ProjectID:        project.ID,
+		Content:          "The user wants a bakery website.",
+		ThroughMessageID: &through,
+		Trigger:          model.SummaryTriggerTokenBudget,
+	})
+	require.NoError(t, err)
+
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+	}, logger)
+	chatService := NewChatService(ChatConfig{}, claudeService, nil, nil, repo, repository.NewMockFileRepository(), nil, logger)
+	chatService.SetSummaries(summaries)
+
+	_, err = chatService.ProcessMessage(ctx, project.ID, "Add a menu page", func(string) {}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, systemPrompts, 1)
+	assert.Contains(t, systemPrompts[0], "## Conversation So Far")
+	assert.Contains(t, systemPrompts[0], "The user wants a bakery website.")
+}
+
+func TestChatService_ProcessMessage_SummarizesHistoryOverMessageLimit(t *testing.T) {
+	var mu sync.Mutex
+	var requests []struct {
+		System   string          `json:"system"`
+		Messages []ClaudeMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, struct {
+			System   string          `json:"system"`
+			Messages []ClaudeMessage `json:"messages"`
+		}{})
+		json.Unmarshal(body, &requests[len(requests)-1])
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range textTurnEvents("Sure.") {
+			w.Write([]byte(event))
+		}
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	summaries, summaryRepo, repo := newTestSummaryService(SummaryConfig{TokenBudget: 12000, MessageLimit: 20, KeepRecent: 10})
+	project, _ := repo.Create(ctx, "Test Project")
+	seedMessages(t, repo, project.ID, 24) // Short messages, far under the token budget
+
+	claudeService := NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, logger)
+	chatService := NewChatService(ChatConfig{ContextMessageLimit: 20}, claudeService, nil, nil, repo, repository.NewMockFileRepository(), nil, logger)
+	chatService.SetSummaries(summaries)
+
+	_, err := chatService.ProcessMessage(ctx, project.ID, "Add a menu page", func(string) {}, nil)
+	require.NoError(t, err)
+
+	// The 25 messages are either summarized or sent; none are dropped
+	latest, err := summaryRepo.GetLatest(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SummaryTriggerMessageLimit, latest.Trigger)
+	require.Len(t, requests, 1)
+	assert.Equal(t, 25, latest.MessageCount+len(requests[0].Messages))
+	assert.Len(t, requests[0].Messages, 10)
+	assert.Equal(t, "Add a menu page", requests[0].Messages[9].Content)
+	assert.Contains(t, requests[0].System, "## Conversation So Far")
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// maxSyntaxFeedback limits the syntax errors fed back to the developer agent in one prompt.
+const maxSyntaxFeedback = 20
+
+// recordSyntaxErrors keeps the syntax errors found after a turn, to feed back to the
+// developer agent on the project's next turn. A check without errors clears earlier ones.
+func (s *ChatService) recordSyntaxErrors(projectID uuid.UUID, issues []model.CompletenessIssue) {
+	s.syntaxMu.Lock()
+	defer s.syntaxMu.Unlock()
+	if len(issues) == 0 {
+		delete(s.syntaxErrors, projectID)
+		return
+	}
+	s.syntaxErrors[projectID] = issues
+}
+
+// syntaxErrorsFor returns the syntax errors recorded for a project. They are kept until a
+// turn that was shown them succeeds, so a turn that fails or is cancelled before fixing
+// them leaves them for the next.
+func (s *ChatService) syntaxErrorsFor(projectID uuid.UUID) []model.CompletenessIssue {
+	s.syntaxMu.Lock()
+	defer s.syntaxMu.Unlock()
+	return s.syntaxErrors[projectID]
+}
+
+// withSyntaxErrors adds the syntax errors left by the previous turn to a system prompt,
+// so the developer agent fixes them.
+func withSyntaxErrors(systemPrompt string, issues []model.CompletenessIssue) string {
+	var b strings.Builder
+	b.WriteString(systemPrompt)
+	b.WriteString("\n\n## Syntax Errors\n\n")
+	b.WriteString("The files written so far don't parse. Fix these errors before making other changes:\n\n")
+	for i, issue := range issues {
+		if i == maxSyntaxFeedback {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(issues)-i)
+			break
+		}
+		position := fmt.Sprintf("%s:%d", issue.ReferencedBy, issue.LineNumber)
+		if issue.Column > 0 {
+			position += fmt.Sprintf(":%d", issue.Column)
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", position, issue.Context)
+	}
+	return b.String()
+}
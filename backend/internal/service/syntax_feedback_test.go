@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+func TestWithSyntaxErrors(t *testing.T) {
+	prompt := withSyntaxErrors("You are a developer.", []model.CompletenessIssue{
+		{Type: "syntax_error", ReferencedBy: "js/app.js", LineNumber: 3, Column: 7, Context: "string is never closed"},
+		{Type: "syntax_error", ReferencedBy: "config.yaml", LineNumber: 2, Context: "did not find expected key"},
+	})
+
+	assert.Equal(t, "You are a developer.\n\n## Syntax Errors\n\n"+
+		"The files written so far don't parse. Fix these errors before making other changes:\n\n"+
+		"- js/app.js:3:7: string is never closed\n"+
+		"- config.yaml:2: did not find expected key\n", prompt)
+}
+
+func TestWithSyntaxErrors_Limit(t *testing.T) {
+	var issues []model.CompletenessIssue
+	for i := 1; i <= maxSyntaxFeedback+3; i++ {
+		issues = append(issues, model.CompletenessIssue{ReferencedBy: "a.css", LineNumber: i, Column: 1, Context: "unexpected '}'"})
+	}
+
+	prompt := withSyntaxErrors("", issues)
+	assert.Contains(t, prompt, fmt.Sprintf("- a.css:%d:1:", maxSyntaxFeedback))
+	assert.NotContains(t, prompt, fmt.Sprintf("- a.css:%d:1:", maxSyntaxFeedback+1))
+	assert.Contains(t, prompt, "- ... and 3 more\n")
+}
+
+func TestChatService_SyntaxErrors_Record(t *testing.T) {
+	s := NewChatService(ChatConfig{}, nil, nil, nil, nil, nil, nil, zerolog.Nop())
+	projectID := uuid.New()
+	issues := []model.CompletenessIssue{{Type: "syntax_error", ReferencedBy: "a.json"}}
+
+	s.recordSyntaxErrors(projectID, issues)
+	assert.Equal(t, issues, s.syntaxErrorsFor(projectID))
+	assert.Equal(t, issues, s.syntaxErrorsFor(projectID), "reading errors keeps them")
+
+	s.recordSyntaxErrors(projectID, nil)
+	assert.Empty(t, s.syntaxErrorsFor(projectID), "a clean check clears earlier errors")
+}
+
+func TestChatService_ProcessMessage_FeedsSyntaxErrorsToDeveloper(t *testing.T) {
+	turns := [][]string{
+		toolUseTurnEvents("toolu_write", "write_file", map[string]interface{}{"path": "js/app.js", "content": "function init() {\n  console.log('hi');\n"}),
+		textTurnEvents("Done."),
+		nil, // The API rejects the request
+		textTurnEvents("Fixed."),
+		textTurnEvents("Nothing to fix."),
+	}
+	var mu sync.Mutex
+	var systemPrompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			System string `json:"system"`
+		}
+		json.Unmarshal(body, &req)
+		mu.Lock()
+		idx := min(len(systemPrompts), len(turns)-1)
+		systemPrompts = append(systemPrompts, req.System)
+		mu.Unlock()
+
+		if turns[idx] == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"rejected"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range turns[idx] {
+			w.Write([]byte(event))
+		}
+	}))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	projectRepo := repository.NewMockProjectRepository()
+	discoveryRepo := repository.NewMockDiscoveryRepository()
+	project, _ := projectRepo.Create(ctx, "Test Project")
+	discovery, _ := discoveryRepo.Create(ctx, project.ID)
+	discoveryRepo.MarkComplete(ctx, discovery.ID)
+
+	claudeService := NewClaudeService(ClaudeConfig{APIKey: "test-key", Model: "claude-test", MaxTokens: 1024, BaseURL: server.URL}, logger)
+	chatService := NewChatService(ChatConfig{}, claudeService,
+		NewDiscoveryService(discoveryRepo, nil, logger),
+		NewAgentContextService(repository.NewMockPRDRepository(), projectRepo, discoveryRepo, logger),
+		projectRepo, repository.NewMockFileRepository(), nil, logger)
+
+	// The turn writing the file reports its syntax error
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Add a menu page", func(string) {}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, result.CompletenessReport)
+	syntaxErrors := result.CompletenessReport.GetSyntaxErrors()
+	require.Len(t, syntaxErrors, 1)
+	assert.Equal(t, model.SeverityCritical, syntaxErrors[0].Severity)
+	assert.Equal(t, "js/app.js", syntaxErrors[0].ReferencedBy)
+	assert.Equal(t, "javascript", syntaxErrors[0].ReferenceType)
+	assert.Equal(t, 3, syntaxErrors[0].LineNumber)
+	assert.Equal(t, 1, syntaxErrors[0].Column)
+	assert.Equal(t, "Unexpected end of file", syntaxErrors[0].Context)
+
+	// The developer's next turn is told about it until one completes
+	_, err = chatService.ProcessMessage(ctx, project.ID, "Add a contact form", func(string) {}, nil)
+	require.Error(t, err)
+	_, err = chatService.ProcessMessage(ctx, project.ID, "Add a contact form", func(string) {}, nil)
+	require.NoError(t, err)
+	_, err = chatService.ProcessMessage(ctx, project.ID, "Add a gallery", func(string) {}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, systemPrompts, 5)
+	assert.NotContains(t, systemPrompts[0], "## Syntax Errors")
+	assert.Contains(t, systemPrompts[2], "- js/app.js:3:1: Unexpected end of file")
+	assert.Contains(t, systemPrompts[3], "## Syntax Errors", "a failed turn leaves the errors for the next")
+	assert.Contains(t, systemPrompts[3], "- js/app.js:3:1: Unexpected end of file")
+	assert.NotContains(t, systemPrompts[4], "## Syntax Errors")
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+)
+
+// ToolPermission is a capability a tool needs from the chat turn it runs in.
+type ToolPermission string
+
+const (
+	ToolPermissionReadFiles  ToolPermission = "files:read"
+	ToolPermissionWriteFiles ToolPermission = "files:write"
+	ToolPermissionAskUser    ToolPermission = "user:ask" // The caller can pause the turn for the user's answer
+)
+
+// ToolInput is the typed input of a tool: a pointer to a struct the tool call's JSON
+// input is decoded into. Validate checks what the JSON types can't, e.g. required fields.
+type ToolInput interface {
+	Validate() error
+}
+
+// ToolHandler executes a tool call with its decoded and validated input, filling in result.
+type ToolHandler func(ctx context.Context, turn *chatTurn, input ToolInput, result ToolExecutionResult) ToolExecutionResult
+
+// Tool describes a tool Claude can call: its API definition, how to run it, and who may use it.
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema map[string]interface{}
+	NewInput    func() ToolInput // Returns a new, empty input to decode a call into
+	Handler     ToolHandler
+	Permissions []ToolPermission  // Capabilities the turn must grant
+	Agents      []model.AgentType // Agents that may use the tool; empty allows every agent, and turns without one
+	ReadOnly    bool              // Never changes project state; consecutive read-only calls run concurrently
+}
+
+// Definition returns the tool's definition for the Claude API.
+func (t *Tool) Definition() ClaudeTool {
+	return ClaudeTool{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema}
+}
+
+// allows reports whether a turn with the given agent and permissions may use the tool.
+func (t *Tool) allows(agentType *string, granted map[ToolPermission]bool) bool {
+	for _, permission := range t.Permissions {
+		if !granted[permission] {
+			return false
+		}
+	}
+	if len(t.Agents) == 0 {
+		return true
+	}
+	if agentType == nil {
+		return false
+	}
+	for _, agent := range t.Agents {
+		if string(agent) == *agentType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolRegistry holds the tools available to chat turns. Services contribute tools by
+// registering them; each turn is offered the tools its agent and permissions allow.
+// Safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]*Tool
+	order []string // Registration order, which is the order tools are offered in
+}
+
+// NewToolRegistry creates an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]*Tool)}
+}
+
+// Register adds a tool. It returns an error if the tool is incomplete or its name is taken.
+func (r *ToolRegistry) Register(tool Tool) error {
+	switch {
+	case tool.Name == "":
+		return errors.New("tool name is required")
+	case tool.Handler == nil || tool.NewInput == nil:
+		return fmt.Errorf("tool %s: handler and input are required", tool.Name)
+	case tool.InputSchema == nil:
+		return fmt.Errorf("tool %s: input schema is required", tool.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[tool.Name]; exists {
+		return fmt.Errorf("tool %s is already registered", tool.Name)
+	}
+	r.tools[tool.Name] = &tool
+	r.order = append(r.order, tool.Name)
+	return nil
+}
+
+// Get returns the tool registered under name.
+func (r *ToolRegistry) Get(name string) (*Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Available returns the tools a turn with the given agent and permissions may use,
+// in registration order.
+func (r *ToolRegistry) Available(agentType *string, granted map[ToolPermission]bool) []*Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tools []*Tool
+	for _, name := range r.order {
+		if tool := r.tools[name]; tool.allows(agentType, granted) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// Definitions returns the Claude API definitions of the tools a turn may use.
+func (r *ToolRegistry) Definitions(agentType *string, granted map[ToolPermission]bool) []ClaudeTool {
+	tools := r.Available(agentType, granted)
+	definitions := make([]ClaudeTool, len(tools))
+	for i, tool := range tools {
+		definitions[i] = tool.Definition()
+	}
+	return definitions
+}
+
+// Execute runs a tool call for a turn. Unknown or disallowed tools and invalid input
+// are reported to Claude as tool errors, so it can correct the call.
+func (r *ToolRegistry) Execute(ctx context.Context, turn *chatTurn, toolUse ToolUseBlock) ToolExecutionResult {
+	execResult := ToolExecutionResult{Result: ToolResult{Type: "tool_result", ToolUseID: toolUse.ID}}
+
+	tool, ok := r.Get(toolUse.Name)
+	if !ok {
+		execResult.Result.Content = fmt.Sprintf("Unknown tool: %s", toolUse.Name)
+		execResult.Result.IsError = true
+		return execResult
+	}
+	if !tool.allows(turn.agentType, turn.permissions) {
+		return toolError(execResult, "%s is not available in this conversation", tool.Name)
+	}
+
+	input, err := decodeToolInput(tool, toolUse.Input)
+	if err != nil {
+		return toolError(execResult, "%v", err)
+	}
+
+	return tool.Handler(ctx, turn, input, execResult)
+}
+
+// decodeToolInput decodes a tool call's JSON input into the tool's input type and validates it.
+func decodeToolInput(tool *Tool, raw map[string]interface{}) (ToolInput, error) {
+	input := tool.NewInput()
+	if raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input: %v", err)
+		}
+		if err := json.Unmarshal(data, input); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("invalid input: %s must be %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+			}
+			return nil, fmt.Errorf("invalid input: %v", err)
+		}
+	}
+
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// jsonTypeName names a Go type the way the JSON schema does.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// toolsKey is the context key for the tools offered with Claude calls.
+type toolsKey struct{}
+
+// WithTools returns a context whose Claude calls offer the given tools to the model.
+func WithTools(ctx context.Context, tools []ClaudeTool) context.Context {
+	return context.WithValue(ctx, toolsKey{}, tools)
+}
+
+// toolsFrom returns the tools to offer with a call, or nil if there are none.
+func toolsFrom(ctx context.Context) []ClaudeTool {
+	tools, _ := ctx.Value(toolsKey{}).([]ClaudeTool)
+	return tools
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// fileToolDefinitions returns the file tool definitions a chat turn offers to Claude.
+func fileToolDefinitions() []ClaudeTool {
+	s := NewChatService(ChatConfig{}, nil, nil, nil, nil, repository.NewMockFileRepository(), nil, zerolog.Nop())
+	turn := s.newTurn(uuid.New(), nil)
+	return s.Tools().Definitions(turn.agentType, turn.permissions)
+}
+
+// echoInput is the input of the test tool "echo".
+type echoInput struct {
+	Text  string `json:"text"`
+	Times int    `json:"times"`
+}
+
+func (in *echoInput) Validate() error {
+	if in.Text == "" {
+		return errors.New("text is required")
+	}
+	return nil
+}
+
+func echoTool(agents ...model.AgentType) Tool {
+	return Tool{
+		Name:        "echo",
+		Description: "Repeat text",
+		InputSchema: map[string]interface{}{"type": "object"},
+		NewInput:    func() ToolInput { return &echoInput{} },
+		Handler: func(ctx context.Context, turn *chatTurn, input ToolInput, result ToolExecutionResult) ToolExecutionResult {
+			in := input.(*echoInput)
+			for i := 0; i < in.Times; i++ {
+				result.Result.Content += in.Text
+			}
+			return result
+		},
+		Agents:   agents,
+		ReadOnly: true,
+	}
+}
+
+func TestToolRegistry_Register(t *testing.T) {
+	registry := NewToolRegistry()
+
+	require.NoError(t, registry.Register(echoTool()))
+	assert.EqualError(t, registry.Register(echoTool()), "tool echo is already registered")
+	assert.EqualError(t, registry.Register(Tool{Name: "broken", InputSchema: map[string]interface{}{}}), "tool broken: handler and input are required")
+	assert.Error(t, registry.Register(Tool{}))
+
+	tool, ok := registry.Get("echo")
+	require.True(t, ok)
+	assert.Equal(t, ClaudeTool{Name: "echo", Description: "Repeat text", InputSchema: map[string]interface{}{"type": "object"}}, tool.Definition())
+}
+
+func TestToolRegistry_Execute_DecodesAndValidatesInput(t *testing.T) {
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(echoTool()))
+	turn := &chatTurn{projectID: uuid.New()}
+
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+		isError  bool
+	}{
+		{"typed input", map[string]interface{}{"text": "hi", "times": float64(3)}, "hihihi", false},
+		{"missing field", map[string]interface{}{"times": float64(1)}, "Error: text is required", true},
+		{"wrong type", map[string]interface{}{"text": "hi", "times": "two"}, "Error: invalid input: times must be an integer, not string", true},
+		{"no input", nil, "Error: text is required", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := registry.Execute(context.Background(), turn, ToolUseBlock{ID: "toolu_1", Name: "echo", Input: tt.input})
+			assert.Equal(t, "toolu_1", result.Result.ToolUseID)
+			assert.Equal(t, tt.expected, result.Result.Content)
+			assert.Equal(t, tt.isError, result.Result.IsError)
+		})
+	}
+
+	result := registry.Execute(context.Background(), turn, ToolUseBlock{ID: "toolu_2", Name: "shout"})
+	assert.True(t, result.Result.IsError)
+	assert.Equal(t, "Unknown tool: shout", result.Result.Content)
+}
+
+func TestToolRegistry_RestrictsByAgentAndPermission(t *testing.T) {
+	registry := NewToolRegistry()
+	require.NoError(t, registry.Register(echoTool(model.AgentDesigner)))
+	writer := echoTool()
+	writer.Name = "save"
+	writer.Permissions = []ToolPermission{ToolPermissionWriteFiles}
+	require.NoError(t, registry.Register(writer))
+
+	designer := string(model.AgentDesigner)
+	developer := string(model.AgentDeveloper)
+	canWrite := map[ToolPermission]bool{ToolPermissionWriteFiles: true}
+
+	names := func(agentType *string, granted map[ToolPermission]bool) []string {
+		var names []string
+		for _, tool := range registry.Definitions(agentType, granted) {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"echo", "save"}, names(&designer, canWrite))
+	assert.Equal(t, []string{"save"}, names(&developer, canWrite))
+	assert.Equal(t, []string{"save"}, names(nil, canWrite))
+	assert.Empty(t, names(&developer, nil))
+
+	// A call to a tool the turn wasn't offered is refused
+	turn := &chatTurn{projectID: uuid.New(), agentType: &developer}
+	result := registry.Execute(context.Background(), turn, ToolUseBlock{ID: "toolu_1", Name: "echo", Input: map[string]interface{}{"text": "hi"}})
+	assert.True(t, result.Result.IsError)
+	assert.Equal(t, "Error: echo is not available in this conversation", result.Result.Content)
+}
+
+func TestChatService_Tools_WithoutFileRepository(t *testing.T) {
+	s := NewChatService(ChatConfig{}, nil, nil, nil, repository.NewMockProjectRepository(), nil, nil, zerolog.Nop())
+	turn := s.newTurn(uuid.New(), nil)
+
+	assert.Empty(t, s.Tools().Definitions(turn.agentType, turn.permissions))
+	result := s.executeTool(context.Background(), turn, ToolUseBlock{ID: "toolu_1", Name: "read_file", Input: map[string]interface{}{"path": "index.html"}})
+	assert.True(t, result.Result.IsError)
+}
+
+func TestChatService_ProcessMessage_OffersRegisteredTools(t *testing.T) {
+	var offered []string
+	claude := &recordingClaudeMessenger{onSend: func(ctx context.Context) {
+		for _, tool := range toolsFrom(ctx) {
+			offered = append(offered, tool.Name)
+		}
+	}}
+
+	repo := repository.NewMockProjectRepository()
+	s := NewChatService(ChatConfig{}, claude, nil, nil, repo, repository.NewMockFileRepository(), nil, zerolog.Nop())
+	require.NoError(t, s.Tools().Register(echoTool()))
+	project, _ := repo.Create(context.Background(), "Tools Project")
+
+	_, err := s.ProcessMessage(context.Background(), project.ID, "Hello", func(string) {}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"write_file", "edit_file", "read_file", "list_files", "search_files", "file_outline", "delete_file", "move_file", "echo"}, offered)
+}
+
+// recordingClaudeMessenger replies with a fixed text and reports the context of each call.
+type recordingClaudeMessenger struct {
+	onSend func(ctx context.Context)
+}
+
+func (m *recordingClaudeMessenger) SendMessage(ctx context.Context, systemPrompt string, messages []ClaudeMessage) (*ClaudeStream, error) {
+	m.onSend(ctx)
+	return NewMockClaudeServiceSimple().createMockStream("Hi!"), nil
+}
+
+func (m *recordingClaudeMessenger) SendMessageWithToolResults(ctx context.Context, systemPrompt string, messages []ClaudeMessage, assistantContent []ContentBlock, toolResults []ToolResult) (*ClaudeStream, error) {
+	return m.SendMessage(ctx, systemPrompt, messages)
+}
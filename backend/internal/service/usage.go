@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// DefaultModelPrices are Anthropic list prices in USD per million tokens, keyed by model name prefix.
+var DefaultModelPrices = map[string]model.ModelPrice{
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+}
+
+// LoadModelPrices returns the default price table, overridden by the JSON file at path if set.
+// The file maps model names (or name prefixes) to prices, e.g. {"claude-sonnet-4": {"input": 3, "output": 15}}.
+func LoadModelPrices(path string) (map[string]model.ModelPrice, error) {
+	prices := make(map[string]model.ModelPrice, len(DefaultModelPrices))
+	for name, price := range DefaultModelPrices {
+		prices[name] = price
+	}
+
+	if path == "" {
+		return prices, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model prices: %w", err)
+	}
+
+	var overrides map[string]model.ModelPrice
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse model prices: %w", err)
+	}
+	for name, price := range overrides {
+		prices[name] = price
+	}
+
+	return prices, nil
+}
+
+// usageScopeKey is the context key for the usage scope.
+type usageScopeKey struct{}
+
+// usageScope attributes the Claude calls made with a context and collects the records they produced.
+type usageScope struct {
+	projectID uuid.UUID
+	agentType *string
+	source    model.UsageSource
+
+	mu        sync.Mutex
+	recordIDs []uuid.UUID
+}
+
+// WithUsageScope returns a context whose Claude calls are attributed to a project, agent type and source.
+func WithUsageScope(ctx context.Context, projectID uuid.UUID, agentType *string, source model.UsageSource) context.Context {
+	return context.WithValue(ctx, usageScopeKey{}, &usageScope{
+		projectID: projectID,
+		agentType: agentType,
+		source:    source,
+	})
+}
+
+// usageScopeFrom returns the usage scope of a context, or nil if there is none.
+func usageScopeFrom(ctx context.Context) *usageScope {
+	scope, _ := ctx.Value(usageScopeKey{}).(*usageScope)
+	return scope
+}
+
+// UsageService prices and stores the token usage of Claude API calls and aggregates it per project.
+// It implements UsageRecorder.
+type UsageService struct {
+	prices    map[string]model.ModelPrice
+	usageRepo repository.UsageRepository
+	logger    zerolog.Logger
+}
+
+// NewUsageService creates a new UsageService. A nil price table uses DefaultModelPrices.
+func NewUsageService(prices map[string]model.ModelPrice, usageRepo repository.UsageRepository, logger zerolog.Logger) *UsageService {
+	if prices == nil {
+		prices = DefaultModelPrices
+	}
+
+	return &UsageService{
+		prices:    prices,
+		usageRepo: usageRepo,
+		logger:    logger.With().Str("component", "usage").Logger(),
+	}
+}
+
+// RecordUsage prices and stores the usage of one call, attributed by the context's usage scope.
+// Failures are logged rather than returned so accounting never breaks a response.
+func (s *UsageService) RecordUsage(ctx context.Context, modelName string, usage model.TokenUsage) {
+	cost, savings := s.price(modelName, usage)
+
+	record := &model.UsageRecord{
+		Source:          model.UsageSourceOther,
+		Model:           modelName,
+		TokenUsage:      usage,
+		CostUSD:         cost,
+		CacheSavingsUSD: savings,
+	}
+	if route := modelRouteFrom(ctx); route != "" {
+		record.Route = &route
+	}
+
+	scope := usageScopeFrom(ctx)
+	if scope != nil {
+		projectID := scope.projectID
+		record.ProjectID = &projectID
+		record.AgentType = scope.agentType
+		record.Source = scope.source
+	}
+
+	created, err := s.usageRepo.Create(ctx, record)
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("model", modelName).
+			Str("source", string(record.Source)).
+			Msg("failed to record token usage")
+		return
+	}
+
+	if scope != nil {
+		scope.mu.Lock()
+		scope.recordIDs = append(scope.recordIDs, created.ID)
+		scope.mu.Unlock()
+	}
+}
+
+// AttachMessage links the usage recorded under the context's scope to the assistant message it produced.
+func (s *UsageService) AttachMessage(ctx context.Context, messageID uuid.UUID) error {
+	scope := usageScopeFrom(ctx)
+	if scope == nil {
+		return nil
+	}
+
+	scope.mu.Lock()
+	ids := append([]uuid.UUID(nil), scope.recordIDs...)
+	scope.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.usageRepo.AttachMessage(ctx, ids, messageID); err != nil {
+		return fmt.Errorf("failed to attach message to token usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetProjectUsage returns a project's usage and cost over the last days days (including today, UTC),
+// with totals per agent type, per source, per model, and per day.
+func (s *UsageService) GetProjectUsage(ctx context.Context, projectID uuid.UUID, days int) (*model.ProjectUsageResponse, error) {
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	records, err := s.usageRepo.ListByProject(ctx, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token usage: %w", err)
+	}
+
+	return buildProjectUsage(projectID, since, records), nil
+}
+
+// price returns the cost of a call and the amount saved by cache reads, using the
+// longest price table key that matches the model name.
+func (s *UsageService) price(modelName string, usage model.TokenUsage) (cost, savings float64) {
+	price, ok := s.lookupPrice(modelName)
+	if !ok {
+		s.logger.Debug().Str("model", modelName).Msg("no price configured for model, recording zero cost")
+		return 0, 0
+	}
+
+	cost = (float64(usage.InputTokens)*price.Input +
+		float64(usage.OutputTokens)*price.Output +
+		float64(usage.CacheCreationInputTokens)*price.CacheWrite +
+		float64(usage.CacheReadInputTokens)*price.CacheRead) / 1e6
+	savings = float64(usage.CacheReadInputTokens) * (price.Input - price.CacheRead) / 1e6
+
+	return cost, savings
+}
+
+// lookupPrice finds the price of a model by exact name, then by longest matching prefix.
+func (s *UsageService) lookupPrice(modelName string) (model.ModelPrice, bool) {
+	if price, ok := s.prices[modelName]; ok {
+		return price, true
+	}
+
+	var best string
+	for name := range s.prices {
+		if strings.HasPrefix(modelName, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return model.ModelPrice{}, false
+	}
+	return s.prices[best], true
+}
+
+// buildProjectUsage aggregates usage records into totals per agent, source, model and day.
+func buildProjectUsage(projectID uuid.UUID, since time.Time, records []model.UsageRecord) *model.ProjectUsageResponse {
+	response := &model.ProjectUsageResponse{
+		ProjectID: projectID,
+		Since:     since,
+		ByAgent:   []model.AgentUsage{},
+		BySource:  []model.SourceUsage{},
+		ByModel:   []model.ModelUsage{},
+		Daily:     []model.DailyUsage{},
+	}
+
+	byAgent := make(map[string]*model.UsageTotals)
+	bySource := make(map[string]*model.UsageTotals)
+	byModel := make(map[string]*model.UsageTotals)
+	daily := make(map[string]*model.UsageTotals)
+
+	for _, record := range records {
+		response.Total.AddRecord(record)
+
+		agent := "none"
+		if record.AgentType != nil {
+			agent = *record.AgentType
+		}
+		addToGroup(byAgent, agent, record)
+		addToGroup(bySource, string(record.Source), record)
+		addToGroup(byModel, record.Model, record)
+		addToGroup(daily, record.CreatedAt.UTC().Format("2006-01-02"), record)
+	}
+
+	for agent, totals := range byAgent {
+		response.ByAgent = append(response.ByAgent, model.AgentUsage{AgentType: agent, UsageTotals: *totals})
+	}
+	sort.Slice(response.ByAgent, func(i, j int) bool { return response.ByAgent[i].AgentType < response.ByAgent[j].AgentType })
+
+	for source, totals := range bySource {
+		response.BySource = append(response.BySource, model.SourceUsage{Source: model.UsageSource(source), UsageTotals: *totals})
+	}
+	sort.Slice(response.BySource, func(i, j int) bool { return response.BySource[i].Source < response.BySource[j].Source })
+
+	for name, totals := range byModel {
+		response.ByModel = append(response.ByModel, model.ModelUsage{Model: name, UsageTotals: *totals})
+	}
+	sort.Slice(response.ByModel, func(i, j int) bool { return response.ByModel[i].Model < response.ByModel[j].Model })
+
+	for date, totals := range daily {
+		response.Daily = append(response.Daily, model.DailyUsage{Date: date, UsageTotals: *totals})
+	}
+	sort.Slice(response.Daily, func(i, j int) bool { return response.Daily[i].Date < response.Daily[j].Date })
+
+	return response
+}
+
+// addToGroup adds a record to the totals of its group, creating them if needed.
+func addToGroup(groups map[string]*model.UsageTotals, key string, record model.UsageRecord) {
+	totals, ok := groups[key]
+	if !ok {
+		totals = &model.UsageTotals{}
+		groups[key] = totals
+	}
+	totals.AddRecord(record)
+}
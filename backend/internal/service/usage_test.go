@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/model"
+	"gitlab.yuki.lan/goodies/gochat/backend/internal/repository"
+)
+
+// usageTurnEvents returns SSE events for a text-only turn that reports token usage.
+func usageTurnEvents(text string) []string {
+	return []string{
+		sseEvent("message_start", `{"type":"message_start","message":{"id":"msg_usage","role":"assistant","usage":{"input_tokens":1000,"output_tokens":1,"cache_creation_input_tokens":200,"cache_read_input_tokens":5000}}}`),
+		sseEvent("content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"`+text+`"}}`),
+		sseEvent("message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":300}}`),
+		sseEvent("message_stop", `{"type":"message_stop"}`),
+	}
+}
+
+func TestClaudeService_CapturesStreamUsage(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t, usageTurnEvents("Hi"))
+	defer server.Close()
+
+	usageRepo := repository.NewMockUsageRepository()
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+	}, zerolog.Nop())
+	claudeService.SetUsageRecorder(NewUsageService(nil, usageRepo, zerolog.Nop()))
+
+	projectID := uuid.New()
+	ctx := WithUsageScope(context.Background(), projectID, nil, model.UsageSourceWelcome)
+	stream, err := claudeService.SendMessage(ctx, "system", []ClaudeMessage{{Role: "user", Content: "Hello"}})
+	require.NoError(t, err)
+	for range stream.Chunks() {
+	}
+	stream.Close()
+
+	expected := model.TokenUsage{
+		InputTokens:              1000,
+		OutputTokens:             300,
+		CacheCreationInputTokens: 200,
+		CacheReadInputTokens:     5000,
+	}
+	assert.Equal(t, expected, stream.Usage())
+
+	records := usageRepo.All()
+	require.Len(t, records, 1, "usage is recorded before the stream's chunks are closed")
+	assert.Equal(t, expected, records[0].TokenUsage)
+	assert.Equal(t, projectID, *records[0].ProjectID)
+	assert.Equal(t, model.UsageSourceWelcome, records[0].Source)
+	assert.Equal(t, "claude-sonnet-4-20250514", records[0].Model)
+	// 1000*3 + 300*15 + 200*3.75 + 5000*0.30 = 9750 per million
+	assert.InDelta(t, 0.00975, records[0].CostUSD, 1e-9)
+	// 5000 * (3 - 0.30) per million
+	assert.InDelta(t, 0.0135, records[0].CacheSavingsUSD, 1e-9)
+}
+
+func TestUsageService_RecordUsage_WithoutScope(t *testing.T) {
+	usageRepo := repository.NewMockUsageRepository()
+	svc := NewUsageService(nil, usageRepo, zerolog.Nop())
+
+	svc.RecordUsage(context.Background(), "some-unknown-model", model.TokenUsage{InputTokens: 10, OutputTokens: 5})
+
+	records := usageRepo.All()
+	require.Len(t, records, 1)
+	assert.Nil(t, records[0].ProjectID)
+	assert.Equal(t, model.UsageSourceOther, records[0].Source)
+	assert.Zero(t, records[0].CostUSD, "unknown models are recorded at zero cost")
+}
+
+func TestUsageService_LookupPrice(t *testing.T) {
+	svc := NewUsageService(map[string]model.ModelPrice{
+		"claude":          {Input: 1},
+		"claude-sonnet-4": {Input: 3},
+		"claude-exact":    {Input: 9},
+	}, repository.NewMockUsageRepository(), zerolog.Nop())
+
+	price, ok := svc.lookupPrice("claude-sonnet-4-20250514")
+	require.True(t, ok)
+	assert.Equal(t, 3.0, price.Input, "longest matching prefix wins")
+
+	price, ok = svc.lookupPrice("claude-exact")
+	require.True(t, ok)
+	assert.Equal(t, 9.0, price.Input)
+
+	_, ok = svc.lookupPrice("gpt-4o")
+	assert.False(t, ok)
+}
+
+func TestUsageService_AttachMessage(t *testing.T) {
+	usageRepo := repository.NewMockUsageRepository()
+	svc := NewUsageService(nil, usageRepo, zerolog.Nop())
+	ctx := WithUsageScope(context.Background(), uuid.New(), nil, model.UsageSourceChat)
+
+	svc.RecordUsage(ctx, "claude-sonnet-4-20250514", model.TokenUsage{InputTokens: 10})
+	svc.RecordUsage(ctx, "claude-sonnet-4-20250514", model.TokenUsage{InputTokens: 20})
+	svc.RecordUsage(context.Background(), "claude-sonnet-4-20250514", model.TokenUsage{InputTokens: 30})
+
+	messageID := uuid.New()
+	require.NoError(t, svc.AttachMessage(ctx, messageID))
+
+	records := usageRepo.All()
+	require.Len(t, records, 3)
+	assert.Equal(t, messageID, *records[0].MessageID)
+	assert.Equal(t, messageID, *records[1].MessageID)
+	assert.Nil(t, records[2].MessageID, "calls outside the scope are not linked")
+}
+
+func TestUsageService_GetProjectUsage(t *testing.T) {
+	usageRepo := repository.NewMockUsageRepository()
+	svc := NewUsageService(nil, usageRepo, zerolog.Nop())
+	ctx := context.Background()
+	projectID := uuid.New()
+	developer := "developer"
+	today := time.Now().UTC()
+
+	records := []model.UsageRecord{
+		{ProjectID: &projectID, AgentType: &developer, Source: model.UsageSourceChat, TokenUsage: model.TokenUsage{InputTokens: 100}, CostUSD: 1, CreatedAt: today},
+		{ProjectID: &projectID, AgentType: &developer, Source: model.UsageSourceChat, TokenUsage: model.TokenUsage{InputTokens: 50}, CostUSD: 0.5, CreatedAt: today.AddDate(0, 0, -1)},
+		{ProjectID: &projectID, Source: model.UsageSourceWelcome, TokenUsage: model.TokenUsage{OutputTokens: 10}, CostUSD: 0.25, CreatedAt: today},
+		{ProjectID: &projectID, Source: model.UsageSourceChat, CostUSD: 100, CreatedAt: today.AddDate(0, 0, -40)}, // Outside the window
+	}
+	for i := range records {
+		_, err := usageRepo.Create(ctx, &records[i])
+		require.NoError(t, err)
+	}
+
+	usage, err := svc.GetProjectUsage(ctx, projectID, 30)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, usage.Total.Calls)
+	assert.Equal(t, 150, usage.Total.InputTokens)
+	assert.Equal(t, 10, usage.Total.OutputTokens)
+	assert.InDelta(t, 1.75, usage.Total.CostUSD, 1e-9)
+
+	require.Len(t, usage.ByAgent, 2)
+	assert.Equal(t, "developer", usage.ByAgent[0].AgentType)
+	assert.Equal(t, 2, usage.ByAgent[0].Calls)
+	assert.Equal(t, "none", usage.ByAgent[1].AgentType)
+
+	require.Len(t, usage.BySource, 2)
+	assert.Equal(t, model.UsageSourceChat, usage.BySource[0].Source)
+	assert.InDelta(t, 1.5, usage.BySource[0].CostUSD, 1e-9)
+
+	require.Len(t, usage.Daily, 2)
+	assert.Equal(t, today.AddDate(0, 0, -1).Format("2006-01-02"), usage.Daily[0].Date)
+	assert.Equal(t, today.Format("2006-01-02"), usage.Daily[1].Date)
+	assert.Equal(t, 2, usage.Daily[1].Calls)
+}
+
+func TestLoadModelPrices(t *testing.T) {
+	prices, err := LoadModelPrices("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultModelPrices["claude-sonnet-4"], prices["claude-sonnet-4"])
+
+	path := filepath.Join(t.TempDir(), "prices.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"claude-sonnet-4": {"input": 2, "output": 10}, "my-model": {"input": 1}}`), 0o644))
+
+	prices, err = LoadModelPrices(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, prices["claude-sonnet-4"].Input)
+	assert.Equal(t, 1.0, prices["my-model"].Input)
+	assert.Equal(t, DefaultModelPrices["claude-opus-4"], prices["claude-opus-4"], "unlisted models keep default prices")
+
+	_, err = LoadModelPrices(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestChatService_ProcessMessage_LinksUsageToMessage(t *testing.T) {
+	server, _ := newScriptedClaudeServer(t, usageTurnEvents("Sure"))
+	defer server.Close()
+
+	logger := zerolog.Nop()
+	ctx := context.Background()
+	repo := repository.NewMockProjectRepository()
+	project, _ := repo.Create(ctx, "Test Project")
+
+	usageRepo := repository.NewMockUsageRepository()
+	usageSvc := NewUsageService(nil, usageRepo, logger)
+	claudeService := NewClaudeService(ClaudeConfig{
+		APIKey:    "test-key",
+		Model:     "claude-sonnet-4-20250514",
+		MaxTokens: 4096,
+		BaseURL:   server.URL,
+	}, logger)
+	claudeService.SetUsageRecorder(usageSvc)
+
+	chatService := NewChatService(ChatConfig{}, claudeService, nil, nil, repo, repository.NewMockFileRepository(), nil, logger)
+	chatService.SetUsage(usageSvc)
+
+	result, err := chatService.ProcessMessage(ctx, project.ID, "Hello", func(string) {}, nil)
+	require.NoError(t, err)
+
+	records := usageRepo.All()
+	require.Len(t, records, 1)
+	assert.Equal(t, model.UsageSourceChat, records[0].Source)
+	assert.Equal(t, project.ID, *records[0].ProjectID)
+	require.NotNil(t, records[0].MessageID)
+	assert.Equal(t, result.Message.ID, *records[0].MessageID)
+}
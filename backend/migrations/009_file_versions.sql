@@ -0,0 +1,41 @@
+-- Migration 009: Add file_versions table for file history and rollback
+-- Every write to a project file records an immutable snapshot so earlier content can be restored
+
+CREATE TABLE IF NOT EXISTS file_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    path VARCHAR(500) NOT NULL,
+    language VARCHAR(50),
+    content TEXT NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'tool',
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    agent_type VARCHAR(50),
+    restored_from INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_file_version UNIQUE (file_id, version),
+    CONSTRAINT valid_version CHECK (version > 0)
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_file_versions_file_id ON file_versions(file_id);
+CREATE INDEX IF NOT EXISTS idx_file_versions_project_id ON file_versions(project_id);
+CREATE INDEX IF NOT EXISTS idx_file_versions_message_id ON file_versions(message_id) WHERE message_id IS NOT NULL;
+
+-- Backfill: existing files get their current content as version 1, so the first overwrite can be undone
+INSERT INTO file_versions (file_id, project_id, version, path, language, content, source, created_at)
+SELECT f.id, f.project_id, 1, f.path, f.language, f.content,
+       CASE WHEN EXISTS (SELECT 1 FROM file_sources fs WHERE fs.file_id = f.id) THEN 'upload' ELSE 'tool' END,
+       f.created_at
+FROM files f
+WHERE NOT EXISTS (SELECT 1 FROM file_versions v WHERE v.file_id = f.id);
+
+-- Comments
+COMMENT ON TABLE file_versions IS 'Immutable snapshots of file content, one row per write';
+COMMENT ON COLUMN file_versions.version IS 'Sequential version number per file, starting at 1';
+COMMENT ON COLUMN file_versions.source IS 'What caused the write: tool, code_block, upload, restore';
+COMMENT ON COLUMN file_versions.message_id IS 'Assistant message whose turn produced this version (NULL for uploads and restores)';
+COMMENT ON COLUMN file_versions.agent_type IS 'Agent active when the version was written: product_manager, designer, developer';
+COMMENT ON COLUMN file_versions.restored_from IS 'Version number this version was restored from, if source is restore';
@@ -0,0 +1,30 @@
+-- Migration 010: Add file_changes table for per-turn change sets
+-- Each assistant turn records the before/after content of every file it touched
+
+CREATE TABLE IF NOT EXISTS file_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    path VARCHAR(500) NOT NULL,
+    change_type VARCHAR(20) NOT NULL,
+    before_content TEXT,
+    after_content TEXT,
+    additions INTEGER NOT NULL DEFAULT 0,
+    deletions INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_message_file_change UNIQUE (message_id, path),
+    CONSTRAINT valid_change_type CHECK (change_type IN ('added', 'modified', 'deleted'))
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_file_changes_message_id ON file_changes(message_id);
+CREATE INDEX IF NOT EXISTS idx_file_changes_project_id ON file_changes(project_id);
+
+-- Comments
+COMMENT ON TABLE file_changes IS 'Net file changes made during one assistant turn';
+COMMENT ON COLUMN file_changes.change_type IS 'added, modified, or deleted';
+COMMENT ON COLUMN file_changes.before_content IS 'File content before the turn (NULL if the file was added)';
+COMMENT ON COLUMN file_changes.after_content IS 'File content after the turn (NULL if the file was deleted)';
+COMMENT ON COLUMN file_changes.additions IS 'Number of lines added';
+COMMENT ON COLUMN file_changes.deletions IS 'Number of lines deleted';
@@ -0,0 +1,7 @@
+-- Migration 011: Add cancelled flag to messages
+-- Marks assistant responses that were stopped by the user before they finished
+
+ALTER TABLE messages ADD COLUMN cancelled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Add comment for documentation
+COMMENT ON COLUMN messages.cancelled IS 'True if the user cancelled the response mid-generation; content holds the partial response.';
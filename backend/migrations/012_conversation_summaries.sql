@@ -0,0 +1,25 @@
+-- Migration 012: Add conversation_summaries table for context compaction
+-- Older messages are compacted into rolling summaries that are prepended to Claude's context
+
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    through_message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    message_count INTEGER NOT NULL DEFAULT 0,
+    trigger VARCHAR(20) NOT NULL,
+    prd_id UUID REFERENCES prds(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_summary_trigger CHECK (trigger IN ('token_budget', 'prd_complete'))
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_conversation_summaries_project_id ON conversation_summaries(project_id, created_at);
+
+-- Comments
+COMMENT ON TABLE conversation_summaries IS 'Rolling summaries of compacted conversation history; the latest covers everything up to through_message_id';
+COMMENT ON COLUMN conversation_summaries.through_message_id IS 'Last message included in the summary; later messages are sent verbatim';
+COMMENT ON COLUMN conversation_summaries.message_count IS 'Number of messages compacted into this summary (excluding earlier summaries)';
+COMMENT ON COLUMN conversation_summaries.trigger IS 'What caused compaction: token_budget or prd_complete';
+COMMENT ON COLUMN conversation_summaries.prd_id IS 'Completed PRD that triggered the summary, if trigger is prd_complete';
@@ -0,0 +1,32 @@
+-- Migration 013: Add token_usage table for cost accounting
+-- Every Claude API call records its token counts and priced cost
+
+CREATE TABLE IF NOT EXISTS token_usage (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    agent_type VARCHAR(50),
+    source VARCHAR(20) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_creation_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cache_read_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    cache_savings_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT valid_usage_source CHECK (source IN ('chat', 'prd', 'welcome', 'summary', 'vision', 'other'))
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_token_usage_project_id ON token_usage(project_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_token_usage_message_id ON token_usage(message_id);
+
+-- Comments
+COMMENT ON TABLE token_usage IS 'Token counts and cost of each Claude API call';
+COMMENT ON COLUMN token_usage.project_id IS 'Project the call was made for (NULL if unattributed)';
+COMMENT ON COLUMN token_usage.message_id IS 'Assistant message the call produced (chat calls only, set once the message is saved)';
+COMMENT ON COLUMN token_usage.source IS 'What made the call: chat, prd, welcome, summary, vision, or other';
+COMMENT ON COLUMN token_usage.cost_usd IS 'Cost priced with the model price table at the time of the call';
+COMMENT ON COLUMN token_usage.cache_savings_usd IS 'Amount saved by cache reads compared to uncached input tokens';
@@ -0,0 +1,13 @@
+-- Migration 014: Record model routing in token_usage
+-- Calls are routed to a model per purpose and agent; each record keeps the route it used
+
+ALTER TABLE token_usage ADD COLUMN IF NOT EXISTS route VARCHAR(100);
+
+-- Chat during discovery is its own source so it can be routed and priced separately
+ALTER TABLE token_usage DROP CONSTRAINT IF EXISTS valid_usage_source;
+ALTER TABLE token_usage ADD CONSTRAINT valid_usage_source
+    CHECK (source IN ('chat', 'discovery', 'prd', 'welcome', 'summary', 'vision', 'other'));
+
+-- Comments
+COMMENT ON COLUMN token_usage.route IS 'Model route key the call matched, e.g. chat:developer, discovery, or default';
+COMMENT ON COLUMN token_usage.source IS 'What made the call: chat, discovery, prd, welcome, summary, vision, or other';
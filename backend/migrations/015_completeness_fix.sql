@@ -0,0 +1,10 @@
+-- Migration 015: Completeness fixes
+-- Missing files can be generated by the developer agent; its calls are their own usage source
+
+ALTER TABLE token_usage DROP CONSTRAINT IF EXISTS valid_usage_source;
+ALTER TABLE token_usage ADD CONSTRAINT valid_usage_source
+    CHECK (source IN ('chat', 'discovery', 'prd', 'welcome', 'summary', 'vision', 'fix', 'other'));
+
+-- Comments
+COMMENT ON COLUMN token_usage.source IS 'What made the call: chat, discovery, prd, welcome, summary, vision, fix, or other';
+COMMENT ON COLUMN file_versions.source IS 'What caused the write: tool, code_block, upload, restore, fix';
@@ -0,0 +1,9 @@
+-- Migration 016: Compact conversations on message count
+-- History over the chat's message limit is summarized rather than dropped from the context
+
+ALTER TABLE conversation_summaries DROP CONSTRAINT IF EXISTS valid_summary_trigger;
+ALTER TABLE conversation_summaries ADD CONSTRAINT valid_summary_trigger
+    CHECK (trigger IN ('token_budget', 'message_limit', 'prd_complete'));
+
+-- Comments
+COMMENT ON COLUMN conversation_summaries.trigger IS 'What caused compaction: token_budget, message_limit or prd_complete';
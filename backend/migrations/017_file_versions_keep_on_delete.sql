@@ -0,0 +1,13 @@
+-- Migration 017: Keep file versions when a file is deleted
+-- Versions of a deleted file stay in the project's history so the file can be restored by path
+
+ALTER TABLE file_versions ALTER COLUMN file_id DROP NOT NULL;
+ALTER TABLE file_versions DROP CONSTRAINT IF EXISTS file_versions_file_id_fkey;
+ALTER TABLE file_versions ADD CONSTRAINT file_versions_file_id_fkey
+    FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_file_versions_deleted ON file_versions(project_id, path) WHERE file_id IS NULL;
+
+-- Comments
+COMMENT ON COLUMN file_versions.file_id IS 'File this version belongs to; NULL once the file is deleted, until a new file at the same path adopts the versions';
@@ -0,0 +1,13 @@
+-- Migration 018: Keep a summary's cutoff when its last message is deleted
+-- through_message_id is set NULL when the message is deleted; the cutoff time still says which messages the summary covers
+
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS through_created_at TIMESTAMP WITH TIME ZONE;
+
+-- Backfill from the summarized messages that still exist
+UPDATE conversation_summaries s
+SET through_created_at = m.created_at
+FROM messages m
+WHERE m.id = s.through_message_id AND s.through_created_at IS NULL;
+
+-- Comments
+COMMENT ON COLUMN conversation_summaries.through_created_at IS 'Creation time of the last message included in the summary; identifies later messages once that message is deleted';
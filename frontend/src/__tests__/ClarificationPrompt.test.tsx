@@ -0,0 +1,36 @@
+import { render, screen, fireEvent } from '@testing-library/react';
+import { ClarificationPrompt } from '@/components/chat/ClarificationPrompt';
+
+describe('ClarificationPrompt', () => {
+  const clarification = {
+    clarificationId: 'c1',
+    question: 'Which colour scheme?',
+    options: ['Blue', 'Green'],
+  };
+
+  it('shows the question and answers with an option', () => {
+    const onAnswer = jest.fn();
+    render(<ClarificationPrompt clarification={clarification} onAnswer={onAnswer} />);
+
+    expect(screen.getByText('Which colour scheme?')).toBeInTheDocument();
+    fireEvent.click(screen.getByRole('button', { name: 'Green' }));
+
+    expect(onAnswer).toHaveBeenCalledWith('Green');
+  });
+
+  it('asks for a typed answer when there are no options', () => {
+    render(<ClarificationPrompt clarification={{ ...clarification, options: [] }} onAnswer={jest.fn()} />);
+
+    expect(screen.queryByRole('button')).not.toBeInTheDocument();
+    expect(screen.getByText('Type your answer below.')).toBeInTheDocument();
+  });
+
+  it('disables the options while disconnected', () => {
+    const onAnswer = jest.fn();
+    render(<ClarificationPrompt clarification={clarification} onAnswer={onAnswer} disabled />);
+
+    fireEvent.click(screen.getByRole('button', { name: 'Blue' }));
+
+    expect(onAnswer).not.toHaveBeenCalled();
+  });
+});
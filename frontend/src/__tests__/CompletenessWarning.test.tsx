@@ -0,0 +1,91 @@
+import { render, screen } from '@testing-library/react';
+import { CompletenessWarning } from '@/components/projects/CompletenessWarning';
+import { CompletenessReport } from '@/types';
+
+describe('CompletenessWarning', () => {
+  const report: CompletenessReport = {
+    projectId: 'p1',
+    checkedAt: '2026-01-01T00:00:00Z',
+    status: 'critical',
+    filesChecked: 2,
+    autoFixable: 0,
+    issues: [
+      {
+        id: 'issue-1',
+        severity: 'critical',
+        type: 'runtime_error',
+        referencedBy: 'js/app.js',
+        referenceType: 'exception',
+        lineNumber: 12,
+        context: "Uncaught TypeError: Cannot read properties of null (reading 'addEventListener')",
+        autoFixable: false,
+        fixApplied: false,
+      },
+    ],
+  };
+
+  it('lists runtime errors with their location', () => {
+    render(<CompletenessWarning report={report} />);
+
+    expect(screen.getByText('Your app has errors')).toBeInTheDocument();
+    expect(screen.getByText("Uncaught TypeError: Cannot read properties of null (reading 'addEventListener')")).toBeInTheDocument();
+    expect(screen.getByText(/in js\/app\.js, line 12/)).toBeInTheDocument();
+  });
+
+  it('lists syntax errors with their position', () => {
+    render(<CompletenessWarning report={{
+      ...report,
+      issues: [{
+        id: 'issue-2',
+        severity: 'critical',
+        type: 'syntax_error',
+        referencedBy: 'data/menu.json',
+        referenceType: 'json',
+        lineNumber: 3,
+        column: 14,
+        context: "invalid character ']' looking for beginning of value",
+        autoFixable: false,
+        fixApplied: false,
+      }],
+    }} />);
+
+    expect(screen.getByText('Your app has errors')).toBeInTheDocument();
+    expect(screen.getByText(/Some files contain syntax errors/)).toBeInTheDocument();
+    expect(screen.getByText("invalid character ']' looking for beginning of value")).toBeInTheDocument();
+    expect(screen.getByText(/in data\/menu\.json, line 3, column 14/)).toBeInTheDocument();
+  });
+
+  it('lists accessibility and SEO issues with their fix and the scores', () => {
+    render(<CompletenessWarning report={{
+      ...report,
+      status: 'warning',
+      lintScore: { accessibility: 90, seo: 95, pagesChecked: 1 },
+      issues: [{
+        id: 'issue-3',
+        severity: 'warning',
+        type: 'accessibility',
+        referencedBy: 'index.html',
+        referenceType: 'img-alt',
+        lineNumber: 9,
+        context: '<img src="logo.png"> has no alt attribute',
+        wcag: '1.1.1 Non-text Content',
+        suggestion: 'Add an alt attribute describing the image, or alt="" if it is only decorative.',
+        autoFixable: false,
+        fixApplied: false,
+      }],
+    }} />);
+
+    expect(screen.getByText('Accessibility and SEO suggestions')).toBeInTheDocument();
+    expect(screen.getByText('<img src="logo.png"> has no alt attribute')).toBeInTheDocument();
+    expect(screen.getByText(/in index\.html, line 9/)).toBeInTheDocument();
+    expect(screen.getByText('WCAG 1.1.1 Non-text Content')).toBeInTheDocument();
+    expect(screen.getByText(/Add an alt attribute describing the image/)).toBeInTheDocument();
+    expect(screen.getByText(/Accessibility score 90\/100 · SEO score 95\/100/)).toBeInTheDocument();
+  });
+
+  it('renders nothing when the check passed', () => {
+    const { container } = render(<CompletenessWarning report={{ ...report, status: 'pass', issues: [] }} />);
+
+    expect(container).toBeEmptyDOMElement();
+  });
+});
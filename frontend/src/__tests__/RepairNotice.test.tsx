@@ -0,0 +1,45 @@
+import { render, screen } from '@testing-library/react';
+import { RepairNotice } from '@/components/chat/RepairNotice';
+import { CompletenessIssue, RepairProgress } from '@/types';
+
+describe('RepairNotice', () => {
+  const missing: CompletenessIssue = {
+    id: 'issue-1',
+    severity: 'critical',
+    type: 'missing_file',
+    missingFile: 'js/app.js',
+    referencedBy: 'index.html',
+    autoFixable: true,
+    fixApplied: false,
+  };
+  const syntax: CompletenessIssue = {
+    id: 'issue-2',
+    severity: 'critical',
+    type: 'syntax_error',
+    referencedBy: 'data/menu.json',
+    context: "invalid character ']' looking for beginning of value",
+    autoFixable: false,
+    fixApplied: false,
+  };
+  const repair: RepairProgress = { iteration: 1, maxIterations: 2, status: 'started', issues: [missing, syntax] };
+
+  it('lists the problems being fixed', () => {
+    render(<RepairNotice repair={repair} />);
+
+    expect(screen.getByText('Fixing 2 problems found by the build check (attempt 1 of 2)...')).toBeInTheDocument();
+    expect(screen.getByText('js/app.js is missing')).toBeInTheDocument();
+    expect(screen.getByText("data/menu.json: invalid character ']' looking for beginning of value")).toBeInTheDocument();
+  });
+
+  it('reports a failed repair', () => {
+    render(<RepairNotice repair={{ ...repair, iteration: 2, status: 'failed', issues: [missing] }} />);
+
+    expect(screen.getByText("Couldn't fix 1 problem automatically (attempt 2 of 2)")).toBeInTheDocument();
+  });
+
+  it('reports a cancelled repair', () => {
+    render(<RepairNotice repair={{ ...repair, status: 'cancelled' }} />);
+
+    expect(screen.getByText('Stopped fixing 2 problems (attempt 1 of 2)')).toBeInTheDocument();
+  });
+});
@@ -182,6 +182,35 @@ describe('API Client', () => {
     });
   });
 
+  describe('exportProjectGraph', () => {
+    it('returns the graph source in the requested format', async () => {
+      mockFetch.mockResolvedValueOnce({
+        ok: true,
+        text: async () => 'flowchart LR\n',
+      });
+
+      const result = await api.exportProjectGraph('1', 'mermaid');
+
+      expect(mockFetch).toHaveBeenCalledWith(
+        `${API_BASE_URL}/api/projects/1/graph?format=mermaid`,
+        expect.objectContaining({
+          method: 'GET',
+        })
+      );
+      expect(result).toBe('flowchart LR\n');
+    });
+
+    it('throws ApiError on failure', async () => {
+      mockFetch.mockResolvedValueOnce({
+        ok: false,
+        status: 400,
+        json: async () => ({ error: 'format must be json, dot or mermaid' }),
+      });
+
+      await expect(api.exportProjectGraph('1', 'dot')).rejects.toThrow(ApiError);
+    });
+  });
+
   describe('getWebSocketUrl', () => {
     it('returns correct WebSocket URL', () => {
       const url = getWebSocketUrl('project-123');
@@ -13,7 +13,7 @@ import { useProjects, useProject } from '@/hooks/useProjects';
 import { useFiles } from '@/hooks/useFiles';
 import { usePreviewFiles } from '@/hooks/usePreviewFiles';
 import { Project, CompletenessReport } from '@/types';
-import { API_BASE_URL } from '@/lib/api';
+import { api, API_BASE_URL } from '@/lib/api';
 
 type RightPanelView = 'files' | 'preview';
 
@@ -61,6 +61,17 @@ export function ProjectPageClient() {
     setCompletenessReport(report);
   }, []);
 
+  // Generate the missing files of auto-fixable issues and show the new report
+  const handleFixCompleteness = useCallback(async () => {
+    try {
+      const result = await api.fixCompleteness(projectId);
+      setCompletenessReport(result.newReport);
+      fetchFiles();
+    } catch (err) {
+      console.error('[ProjectPageClient] Failed to fix completeness issues:', err);
+    }
+  }, [projectId, fetchFiles]);
+
   // Load preview files when files change and preview tab is active
   useEffect(() => {
     if (rightPanelView === 'preview' && files.length > 0) {
@@ -376,6 +387,7 @@ export function ProjectPageClient() {
         isOpen={isPreviewModalOpen}
         onClose={() => setIsPreviewModalOpen(false)}
         completenessReport={completenessReport}
+        onFixClick={handleFixCompleteness}
       />
     </div>
   );
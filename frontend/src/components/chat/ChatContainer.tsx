@@ -7,6 +7,8 @@ import { useDiscoveryExperience } from '@/hooks/useDiscoveryExperience';
 import { useBuildPhase } from '@/hooks/useBuildPhase';
 import { MessageList, MessageListHandle } from './MessageList';
 import { ChatInput } from './ChatInput';
+import { ClarificationPrompt } from './ClarificationPrompt';
+import { RepairNotice } from './RepairNotice';
 import { BuildPhaseProgress } from './BuildPhaseProgress';
 import { MilestoneToast } from './MilestoneToast';
 import { ConnectionStatus } from '@/components/shared/ConnectionStatus';
@@ -48,7 +50,10 @@ export function ChatContainer({
     connectionStatus,
     reconnectAttempts,
     completenessReport,
+    clarification,
+    repair,
     sendMessage,
+    answerClarification,
     clearError,
     reconnect,
   } = useChat({
@@ -262,6 +267,7 @@ export function ChatContainer({
     if (isStartingDiscovery) return 'Root is joining...';
     if (isWaitingForDiscoveryStart) return 'Click the button above to start...';
     if (isLoading) return 'Waiting for response...';
+    if (clarification) return 'Type your answer...';
     if (!isDiscoveryMode) return 'Describe what you want to build...';
 
     switch (currentStage) {
@@ -429,10 +435,22 @@ export function ChatContainer({
         </button>
       )}
 
+      {/* Problems the developer agent is fixing after its response */}
+      {repair && <RepairNotice repair={repair} />}
+
+      {/* Question the assistant is waiting on */}
+      {clarification && (
+        <ClarificationPrompt
+          clarification={clarification}
+          onAnswer={answerClarification}
+          disabled={connectionStatus !== 'connected' || isLoading}
+        />
+      )}
+
       {/* Input */}
       <ChatInput
         projectId={projectId}
-        onSend={sendMessage}
+        onSend={clarification ? answerClarification : sendMessage}
         disabled={connectionStatus !== 'connected' || isLoading || isWaitingForDiscoveryStart || isStartingDiscovery}
         placeholder={getPlaceholder()}
       />
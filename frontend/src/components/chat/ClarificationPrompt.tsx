@@ -0,0 +1,44 @@
+'use client';
+
+import { Clarification } from '@/types';
+
+interface ClarificationPromptProps {
+  clarification: Clarification;
+  onAnswer: (answer: string) => void;
+  disabled?: boolean;
+}
+
+/**
+ * Shows a question the assistant is waiting on, with its suggested answers as buttons.
+ * The user can also type an answer in the chat input.
+ */
+export function ClarificationPrompt({ clarification, onAnswer, disabled = false }: ClarificationPromptProps) {
+  return (
+    <div
+      className="mx-4 mb-2 p-3 rounded-lg border border-amber-200 bg-amber-50"
+      role="group"
+      aria-label="Question from the assistant"
+      data-testid="clarification-prompt"
+    >
+      <p className="text-sm font-medium text-amber-900">{clarification.question}</p>
+      {clarification.options.length > 0 && (
+        <div className="mt-2 flex flex-wrap gap-2">
+          {clarification.options.map(option => (
+            <button
+              key={option}
+              type="button"
+              onClick={() => onAnswer(option)}
+              disabled={disabled}
+              className="px-3 py-1 text-sm rounded-full border border-amber-300 bg-white text-amber-900 hover:bg-amber-100 disabled:opacity-50 disabled:cursor-not-allowed transition-colors"
+            >
+              {option}
+            </button>
+          ))}
+        </div>
+      )}
+      <p className="mt-2 text-xs text-amber-700">
+        {clarification.options.length > 0 ? 'Pick an answer or type your own below.' : 'Type your answer below.'}
+      </p>
+    </div>
+  );
+}
@@ -0,0 +1,44 @@
+'use client';
+
+import { RepairProgress } from '@/types';
+
+interface RepairNoticeProps {
+  repair: RepairProgress;
+}
+
+/**
+ * Shows that the developer agent is fixing problems the build check found in its response.
+ */
+export function RepairNotice({ repair }: RepairNoticeProps) {
+  const count = repair.issues.length;
+  const problems = `${count} ${count === 1 ? 'problem' : 'problems'}`;
+  const attempt = repair.maxIterations > 1 ? ` (attempt ${repair.iteration} of ${repair.maxIterations})` : '';
+
+  return (
+    <div
+      className="mx-4 mb-2 p-3 rounded-lg border border-blue-200 bg-blue-50"
+      role="status"
+      data-testid="repair-notice"
+    >
+      <p className="text-sm font-medium text-blue-900">
+        {repair.status === 'failed'
+          ? `Couldn't fix ${problems} automatically${attempt}`
+          : repair.status === 'cancelled'
+            ? `Stopped fixing ${problems}${attempt}`
+            : `Fixing ${problems} found by the build check${attempt}...`}
+      </p>
+      {count > 0 && (
+        <ul className="mt-1 text-xs text-blue-700 list-disc list-inside">
+          {repair.issues.slice(0, 3).map(issue => (
+            <li key={issue.id}>
+              {issue.type === 'missing_file'
+                ? `${issue.missingFile} is missing`
+                : `${issue.referencedBy}: ${issue.context}`}
+            </li>
+          ))}
+          {count > 3 && <li>...and {count - 3} more</li>}
+        </ul>
+      )}
+    </div>
+  );
+}
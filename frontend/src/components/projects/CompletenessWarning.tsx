@@ -24,6 +24,59 @@ function IssueIcon({ severity }: { severity: string }) {
 }
 
 function IssueItem({ issue }: { issue: CompletenessIssue }) {
+  if (issue.type === 'syntax_error') {
+    const position = issue.lineNumber
+      ? `line ${issue.lineNumber}${issue.column ? `, column ${issue.column}` : ''}`
+      : '';
+    return (
+      <li className="flex items-start gap-2 text-sm">
+        <span className="text-gray-400 mt-0.5">🧩</span>
+        <div>
+          <span className="font-medium text-gray-700">{issue.context}</span>
+          <span className="text-gray-500">
+            {' '}(in {issue.referencedBy}{position ? `, ${position}` : ''})
+          </span>
+        </div>
+      </li>
+    );
+  }
+
+  if (issue.type === 'accessibility' || issue.type === 'seo') {
+    return (
+      <li className="flex items-start gap-2 text-sm">
+        <span className="text-gray-400 mt-0.5">{issue.type === 'accessibility' ? '♿' : '🔍'}</span>
+        <div>
+          <span className="font-medium text-gray-700">{issue.context}</span>
+          <span className="text-gray-500">
+            {' '}(in {issue.referencedBy}{issue.lineNumber ? `, line ${issue.lineNumber}` : ''})
+          </span>
+          {issue.wcag && (
+            <span className="block text-xs text-gray-500">WCAG {issue.wcag}</span>
+          )}
+          {issue.suggestion && (
+            <span className="block text-xs text-gray-500">{issue.suggestion}</span>
+          )}
+        </div>
+      </li>
+    );
+  }
+
+  if (issue.type === 'runtime_error') {
+    return (
+      <li className="flex items-start gap-2 text-sm">
+        <span className="text-gray-400 mt-0.5">⚠️</span>
+        <div>
+          <span className="font-medium text-gray-700">{issue.context}</span>
+          {issue.referencedBy && (
+            <span className="text-gray-500">
+              {' '}(in {issue.referencedBy}{issue.lineNumber ? `, line ${issue.lineNumber}` : ''})
+            </span>
+          )}
+        </div>
+      </li>
+    );
+  }
+
   return (
     <li className="flex items-start gap-2 text-sm">
       <span className="text-gray-400 mt-0.5">
@@ -31,6 +84,11 @@ function IssueItem({ issue }: { issue: CompletenessIssue }) {
         {issue.referenceType === 'stylesheet' && '🎨'}
         {issue.referenceType === 'import' && '📦'}
         {issue.referenceType === 'image' && '🖼️'}
+        {issue.referenceType === 'font' && '🔤'}
+        {issue.referenceType === 'media' && '🎬'}
+        {issue.referenceType === 'manifest' && '📋'}
+        {issue.referenceType === 'entry' && '🚪'}
+        {issue.referenceType === 'link' && '🔗'}
         {!issue.referenceType && '📄'}
       </span>
       <div>
@@ -52,6 +110,11 @@ export function CompletenessWarning({ report, onFixClick, onDismiss }: Completen
   const criticalIssues = report.issues.filter(i => i.severity === 'critical');
   const warningIssues = report.issues.filter(i => i.severity === 'warning');
   const missingFiles = Array.from(new Set(report.issues.filter(i => i.type === 'missing_file').map(i => i.missingFile)));
+  const hasRuntimeErrors = report.issues.some(i => i.type === 'runtime_error');
+  const hasSyntaxErrors = report.issues.some(i => i.type === 'syntax_error');
+  const hasLintIssues = report.issues.some(i => i.type === 'accessibility' || i.type === 'seo');
+  const onlyErrors = (hasRuntimeErrors || hasSyntaxErrors) && missingFiles.length === 0;
+  const onlyLint = hasLintIssues && !hasRuntimeErrors && !hasSyntaxErrors && missingFiles.length === 0;
 
   return (
     <div className={`rounded-lg border p-4 mb-4 ${
@@ -63,15 +126,30 @@ export function CompletenessWarning({ report, onFixClick, onDismiss }: Completen
         <IssueIcon severity={isCritical ? 'critical' : 'warning'} />
         <div className="flex-1">
           <h3 className={`font-medium ${isCritical ? 'text-red-800' : 'text-yellow-800'}`}>
-            {isCritical ? 'Some files are missing' : 'Minor issues detected'}
+            {isCritical
+              ? (onlyErrors ? 'Your app has errors' : onlyLint ? 'Your pages have accessibility or SEO problems' : 'Some files are missing')
+              : (onlyLint ? 'Accessibility and SEO suggestions' : 'Minor issues detected')}
           </h3>
           <p className={`text-sm mt-1 ${isCritical ? 'text-red-600' : 'text-yellow-600'}`}>
             {isCritical
-              ? 'Your app may not work correctly until these files are created.'
-              : 'Your app should work, but some resources are missing.'}
+              ? (onlyErrors
+                ? (hasSyntaxErrors
+                  ? 'Some files contain syntax errors. Your app may not work correctly until they are fixed.'
+                  : 'Your app ran into errors when it loaded and may not work correctly.')
+                : onlyLint
+                  ? 'Some visitors may not be able to use your pages, and search engines may rank them lower.'
+                  : 'Your app may not work correctly until these files are created.')
+              : (onlyLint
+                ? 'Your app works, but some pages could be easier to use and to find.'
+                : 'Your app should work, but some resources are missing or it logged errors.')}
           </p>
+          {report.lintScore && hasLintIssues && (
+            <p className="text-xs mt-1 text-gray-500">
+              Accessibility score {report.lintScore.accessibility}/100 · SEO score {report.lintScore.seo}/100
+            </p>
+          )}
 
-          {missingFiles.length > 0 && (
+          {(missingFiles.length > 0 || hasRuntimeErrors || hasSyntaxErrors || hasLintIssues) && (
             <ul className="mt-3 space-y-1">
               {criticalIssues.slice(0, 5).map((issue) => (
                 <IssueItem key={issue.id} issue={issue} />
@@ -1,13 +1,15 @@
 'use client';
 
 import { useState, useCallback, useRef, useEffect } from 'react';
-import { Message, ChatState, ServerMessage, ConnectionStatus, CompletenessReport } from '@/types';
+import { Message, ChatState, ServerMessage, ConnectionStatus, CompletenessReport, Clarification, RepairProgress } from '@/types';
 import { useWebSocket } from './useWebSocket';
 
 interface UseChatOptions {
   projectId: string;
   initialMessages?: Message[];
   onFilesUpdated?: () => void;
+  onPRDUpdated?: (prdId: string) => void;
+  onPreviewReload?: (filePaths: string[]) => void; // Files served at /preview changed
 }
 
 interface UseChatReturn {
@@ -17,7 +19,10 @@ interface UseChatReturn {
   connectionStatus: ConnectionStatus;
   reconnectAttempts: number;
   completenessReport: CompletenessReport | null;
+  clarification: Clarification | null;
+  repair: RepairProgress | null; // Latest repair turn of the response being generated
   sendMessage: (content: string) => void;
+  answerClarification: (answer: string) => void;
   clearError: () => void;
   reconnect: () => void;
 }
@@ -60,13 +65,15 @@ function getDisplayContent(content: string): string {
  * - Manages loading states during AI response
  * - Provides error handling and reconnection
  */
-export function useChat({ projectId, initialMessages = [], onFilesUpdated }: UseChatOptions): UseChatReturn {
+export function useChat({ projectId, initialMessages = [], onFilesUpdated, onPRDUpdated, onPreviewReload }: UseChatOptions): UseChatReturn {
   const [state, setState] = useState<ChatState>({
     messages: initialMessages,
     isLoading: false,
     error: null,
   });
   const [completenessReport, setCompletenessReport] = useState<CompletenessReport | null>(null);
+  const [clarification, setClarification] = useState<Clarification | null>(null);
+  const [repair, setRepair] = useState<RepairProgress | null>(null);
 
   // Sync initialMessages when they change (e.g., welcome message loaded after discovery)
   // Only update if we have no messages and initialMessages has content
@@ -79,6 +86,9 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
   // Track streaming message by ID
   const streamingMessageRef = useRef<Map<string, Message>>(new Map());
 
+  // Message paused on a clarification; the answer's stream continues it
+  const pausedMessageRef = useRef<Message | null>(null);
+
   // Track delayed error timeout
   const errorTimeoutRef = useRef<NodeJS.Timeout | null>(null);
 
@@ -88,6 +98,25 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
   const handleWebSocketMessage = useCallback((serverMessage: ServerMessage) => {
     switch (serverMessage.type) {
       case 'message_start': {
+        // Continue the message that paused for a clarification, under the new stream's ID
+        const pausedMessage = pausedMessageRef.current;
+        if (pausedMessage) {
+          pausedMessageRef.current = null;
+          const continuedMessage = { ...pausedMessage, id: serverMessage.messageId, isStreaming: true };
+          streamingMessageRef.current.set(serverMessage.messageId, continuedMessage);
+          setState(prev => ({
+            ...prev,
+            messages: prev.messages.map(msg =>
+              msg.id === pausedMessage.id
+                ? { ...msg, id: serverMessage.messageId, isStreaming: true }
+                : msg
+            ),
+            isLoading: true,
+            error: null,
+          }));
+          break;
+        }
+
         // Create new streaming message
         const newMessage: Message = {
           id: serverMessage.messageId,
@@ -157,11 +186,27 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
         if (serverMessage.completenessReport) {
           setCompletenessReport(serverMessage.completenessReport);
         }
+        setRepair(null);
+        break;
+      }
+
+      case 'repair_iteration': {
+        // The developer agent is fixing critical issues the response left
+        setRepair({
+          iteration: serverMessage.iteration || 1,
+          maxIterations: serverMessage.maxIterations || 1,
+          status: serverMessage.status || 'started',
+          issues: serverMessage.issues || [],
+        });
+        if (serverMessage.completenessReport) {
+          setCompletenessReport(serverMessage.completenessReport);
+        }
         break;
       }
 
       case 'error': {
         // Handle error and clean up any streaming message
+        setRepair(null);
         const errorMessageId = serverMessage.messageId;
         if (errorMessageId) {
           streamingMessageRef.current.delete(errorMessageId);
@@ -182,13 +227,52 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
         break;
       }
 
-      case 'files_updated': {
-        // Trigger file refresh callback when files are created/updated via tool use
+      case 'files_updated':
+      case 'files_deleted': {
+        // Trigger file refresh callback when files are created, updated, deleted or moved
         onFilesUpdated?.();
         break;
       }
+
+      case 'clarification_request': {
+        // The response pauses until the user answers the question
+        const streamingMessage = serverMessage.messageId
+          ? streamingMessageRef.current.get(serverMessage.messageId)
+          : undefined;
+        if (streamingMessage) {
+          streamingMessageRef.current.delete(serverMessage.messageId);
+          pausedMessageRef.current = streamingMessage;
+        }
+        setClarification({
+          clarificationId: serverMessage.clarificationId || '',
+          question: serverMessage.question || '',
+          options: serverMessage.options || [],
+        });
+        setState(prev => ({
+          ...prev,
+          messages: prev.messages.map(msg =>
+            msg.id === serverMessage.messageId ? { ...msg, isStreaming: false } : msg
+          ),
+          isLoading: false,
+        }));
+        break;
+      }
+
+      case 'prd_updated': {
+        // Trigger PRD refresh callback when the product manager agent changes a PRD
+        if (serverMessage.prdId) {
+          onPRDUpdated?.(serverMessage.prdId);
+        }
+        break;
+      }
+
+      case 'preview_reload': {
+        // Reload the live preview when a file it serves changes
+        onPreviewReload?.(serverMessage.filePaths || []);
+        break;
+      }
     }
-  }, [projectId, onFilesUpdated]);
+  }, [projectId, onFilesUpdated, onPRDUpdated, onPreviewReload]);
 
   /**
    * Clear any pending error timeout
@@ -277,6 +361,10 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
       isLoading: true,
     }));
 
+    // A new message instead of an answer leaves the question unanswered
+    setClarification(null);
+    pausedMessageRef.current = null;
+
     // Send via WebSocket
     wsSendMessage({
       type: 'chat_message',
@@ -286,6 +374,25 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
     });
   }, [projectId, wsSendMessage]);
 
+  /**
+   * Answer the question the assistant is waiting on; its response continues in the same message
+   */
+  const answerClarification = useCallback((answer: string) => {
+    const trimmedAnswer = answer.trim();
+    if (!trimmedAnswer || !clarification) return;
+
+    setClarification(null);
+    setState(prev => ({ ...prev, error: null, isLoading: true }));
+
+    wsSendMessage({
+      type: 'clarification_response',
+      projectId,
+      clarificationId: clarification.clarificationId,
+      content: trimmedAnswer,
+      timestamp: new Date().toISOString(),
+    });
+  }, [projectId, clarification, wsSendMessage]);
+
   /**
    * Clear error state
    */
@@ -307,7 +414,10 @@ export function useChat({ projectId, initialMessages = [], onFilesUpdated }: Use
     connectionStatus,
     reconnectAttempts,
     completenessReport,
+    clarification,
+    repair,
     sendMessage,
+    answerClarification,
     clearError,
     reconnect,
   };
@@ -2,7 +2,7 @@
  * REST API client for project CRUD operations
  */
 
-import { Project, Message, FileItem, FileWithContent } from '@/types';
+import { Project, Message, FileItem, FileWithContent, CompletenessFixResponse, ProjectGraph, GraphExportFormat } from '@/types';
 
 export const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8081';
 
@@ -188,6 +188,52 @@ export const api = {
 
     return handleResponse<FileWithContent>(response);
   },
+
+  /**
+   * Generate missing files for completeness issues (all auto-fixable issues if none are given)
+   * POST /api/projects/:id/completeness/fix
+   */
+  async fixCompleteness(projectId: string, issueIds?: string[]): Promise<CompletenessFixResponse> {
+    const response = await fetch(`${API_BASE_URL}/api/projects/${projectId}/completeness/fix`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ issueIds }),
+    });
+
+    return handleResponse<CompletenessFixResponse>(response);
+  },
+
+  /**
+   * Get the dependency graph of a project
+   * GET /api/projects/:id/graph
+   */
+  async getProjectGraph(projectId: string): Promise<ProjectGraph> {
+    const response = await fetch(`${API_BASE_URL}/api/projects/${projectId}/graph`, {
+      method: 'GET',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+    });
+
+    return handleResponse<ProjectGraph>(response);
+  },
+
+  /**
+   * Export the dependency graph of a project as Graphviz DOT or Mermaid source
+   * GET /api/projects/:id/graph?format=dot|mermaid
+   */
+  async exportProjectGraph(projectId: string, format: GraphExportFormat): Promise<string> {
+    const response = await fetch(`${API_BASE_URL}/api/projects/${projectId}/graph?format=${format}`, {
+      method: 'GET',
+    });
+
+    if (!response.ok) {
+      await handleResponse<never>(response);
+    }
+    return response.text();
+  },
 };
 
 /**
@@ -127,12 +127,15 @@ export type Severity = 'critical' | 'warning' | 'info';
 export interface CompletenessIssue {
   id: string;
   severity: Severity;
-  type: string; // "missing_file", "syntax_error", "broken_reference"
+  type: string; // "missing_file", "syntax_error", "broken_reference", "runtime_error", "accessibility", "seo"
   missingFile?: string;
   referencedBy?: string;
-  referenceType?: string; // "script", "stylesheet", "import", "image"
+  referenceType?: string; // "script", "stylesheet", "import", "image", "font", "media", "manifest", "entry", "link"; for runtime errors "exception", "console_error", "asset_load"; for syntax errors the file's language; for accessibility and SEO issues the lint rule's ID
   lineNumber?: number;
-  context?: string;
+  column?: number; // Column of a syntax error, if known
+  context?: string; // The referencing line, or a runtime or syntax error's or lint rule's message
+  wcag?: string; // WCAG success criterion an accessibility issue fails, e.g. "1.1.1 Non-text Content"
+  suggestion?: string; // How to fix an accessibility or SEO issue
   autoFixable: boolean;
   fixApplied: boolean;
 }
@@ -144,26 +147,97 @@ export interface CompletenessReport {
   issues: CompletenessIssue[];
   filesChecked: number;
   autoFixable: number;
+  lintScore?: LintScore; // Absent if the project has no HTML pages
+}
+
+// Accessibility and SEO scores of a project's HTML pages, from 0 to 100
+export interface LintScore {
+  accessibility: number;
+  seo: number;
+  pagesChecked: number;
+}
+
+export interface CompletenessFixResponse {
+  fixed: string[]; // IDs of the fixed issues
+  failed: string[]; // IDs of the issues that couldn't be fixed
+  files: string[]; // Paths of the files written by the fix
+  newReport: CompletenessReport;
+}
+
+// Dependency graph types
+export type GraphExportFormat = 'dot' | 'mermaid';
+
+export interface GraphNode {
+  id: string;
+  path: string;
+  filename: string;
+  language?: string;
+  functionalGroup?: string;
+  shortDescription?: string;
+}
+
+export interface GraphEdge {
+  from: string; // Path of the referencing file
+  to: string; // Path of the referenced file
+  type: string; // "script", "stylesheet", "import", "image", "font", "media", "manifest", "entry", "link"
+  line: number; // Line of the first reference in the referencing file
+}
+
+export interface ProjectGraph {
+  projectId: string;
+  nodes: GraphNode[];
+  edges: GraphEdge[];
+  orphans: string[]; // Paths of files that neither reference nor are referenced by another file
+  cycles: string[][]; // Paths of each cycle; the last file references the first
 }
 
 // WebSocket message types
 export interface ClientMessage {
-  type: 'chat_message';
+  type: 'chat_message' | 'clarification_response';
   projectId: string;
   content: string;
+  clarificationId?: string; // For clarification_response: the question being answered
   timestamp: string;
 }
 
 export interface ServerMessage {
-  type: 'message_start' | 'message_chunk' | 'message_complete' | 'error' | 'files_updated';
+  type: 'message_start' | 'message_chunk' | 'message_complete' | 'error' | 'files_updated' | 'files_deleted' | 'prd_updated' | 'clarification_request' | 'preview_reload' | 'repair_iteration';
   projectId: string;
   messageId: string;
   content?: string;
   fullContent?: string;
   agentType?: AgentType;
   error?: string;
-  filePaths?: string[]; // For files_updated event
-  completenessReport?: CompletenessReport; // For message_complete event
+  filePaths?: string[]; // For files_updated, files_deleted, preview_reload and finished repair_iteration events
+  prdId?: string; // For prd_updated event
+  clarificationId?: string; // For clarification_request event
+  question?: string; // For clarification_request event
+  options?: string[]; // For clarification_request event: suggested answers
+  completenessReport?: CompletenessReport; // For message_complete event, and repair_iteration once the iteration finished
+  toolLimitReached?: boolean; // For message_complete event: stopped at the tool call limit, file changes discarded
+  repairIterations?: number; // For message_complete event: repair turns run before the completeness report
+  iteration?: number; // For repair_iteration event: 1-based
+  maxIterations?: number; // For repair_iteration event
+  status?: RepairStatus; // For repair_iteration event
+  issues?: CompletenessIssue[]; // For repair_iteration event: critical issues the iteration works on
+}
+
+// State of a repair turn, in which the developer agent fixes critical completeness issues
+// a response left
+export type RepairStatus = 'started' | 'finished' | 'failed' | 'cancelled';
+
+export interface RepairProgress {
+  iteration: number;
+  maxIterations: number;
+  status: RepairStatus;
+  issues: CompletenessIssue[];
+}
+
+// A question the assistant asked; its response continues once the user answers
+export interface Clarification {
+  clarificationId: string;
+  question: string;
+  options: string[];
 }
 
 // Connection status